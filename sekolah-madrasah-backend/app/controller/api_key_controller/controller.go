@@ -0,0 +1,176 @@
+package api_key_controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"sekolah-madrasah/app/use_case/api_key_use_case"
+	"sekolah-madrasah/pkg/auth_utils"
+	"sekolah-madrasah/pkg/gin_utils"
+	"sekolah-madrasah/pkg/paginate_utils"
+)
+
+type apiKeyController struct {
+	apiKeyUseCase api_key_use_case.ApiKeyUseCase
+}
+
+func NewApiKeyController(apiKeyUseCase api_key_use_case.ApiKeyUseCase) ApiKeyController {
+	return &apiKeyController{apiKeyUseCase: apiKeyUseCase}
+}
+
+func (ctrl *apiKeyController) toApiKeyResponse(k api_key_use_case.ApiKey) ApiKey {
+	return ApiKey{
+		Id:             k.Id,
+		OrganizationId: k.OrganizationId,
+		Name:           k.Name,
+		Prefix:         k.Prefix,
+		Scopes:         k.Scopes,
+		CreatedBy:      k.CreatedBy,
+		ExpiresAt:      k.ExpiresAt,
+		LastUsedAt:     k.LastUsedAt,
+		LastUsedIp:     k.LastUsedIp,
+		RevokedAt:      k.RevokedAt,
+		CreatedAt:      k.CreatedAt,
+	}
+}
+
+// GetApiKeys godoc
+// @Summary List organization API keys
+// @Description Lists the organization's API keys, including revoked and expired ones. Secrets are never returned.
+// @Tags API Key
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID (UUID)"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} gin_utils.DataWithPaginateResponse{data=[]ApiKey}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 401 {object} gin_utils.MessageResponse
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 500 {object} gin_utils.MessageResponse
+// @Router /api/v1/organizations/{id}/api-keys [get]
+func (ctrl *apiKeyController) GetApiKeys(c *gin.Context) {
+	orgId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "invalid organization id"})
+		return
+	}
+
+	paginate := &paginate_utils.PaginateData{}
+	queryParams := make(map[string]interface{})
+	for k, v := range c.Request.URL.Query() {
+		if len(v) > 0 {
+			queryParams[k] = v[0]
+		}
+	}
+	paginate_utils.CheckPaginateFromMap(queryParams, paginate)
+
+	claims := auth_utils.GetAuthClaim(c.Request.Context())
+	keys, code, err := ctrl.apiKeyUseCase.GetApiKeys(c.Request.Context(), claims, orgId, paginate)
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	result := make([]ApiKey, len(keys))
+	for i, k := range keys {
+		result[i] = ctrl.toApiKeyResponse(k)
+	}
+
+	c.JSON(code, gin_utils.DataWithPaginateResponse{
+		DataResponse: gin_utils.DataResponse{
+			Message: "success",
+			Data:    result,
+		},
+		Paginate: paginate,
+	})
+}
+
+// CreateApiKey godoc
+// @Summary Create an organization API key
+// @Description Creates an API key for machine integrations such as attendance devices. Scopes are permission names the caller holds; the key only acts inside units of the organization. The plaintext key is returned once and cannot be retrieved again.
+// @Tags API Key
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID (UUID)"
+// @Param request body CreateApiKeyRequest true "Key name, scopes and optional expiry"
+// @Success 201 {object} gin_utils.DataResponse{data=CreatedApiKey}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 401 {object} gin_utils.MessageResponse
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Failure 500 {object} gin_utils.MessageResponse
+// @Router /api/v1/organizations/{id}/api-keys [post]
+func (ctrl *apiKeyController) CreateApiKey(c *gin.Context) {
+	orgId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "invalid organization id"})
+		return
+	}
+
+	var req CreateApiKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	claims := auth_utils.GetAuthClaim(c.Request.Context())
+	created, code, err := ctrl.apiKeyUseCase.CreateApiKey(c.Request.Context(), claims, orgId, api_key_use_case.CreateApiKeyRequest{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	}, c.ClientIP())
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(code, gin_utils.DataResponse{
+		Message: "API key created; store it now, it will not be shown again",
+		Data: CreatedApiKey{
+			ApiKey: ctrl.toApiKeyResponse(created.ApiKey),
+			Key:    created.Key,
+		},
+	})
+}
+
+// RevokeApiKey godoc
+// @Summary Revoke an organization API key
+// @Description Revokes an API key immediately. Revoked keys stay listed for auditing.
+// @Tags API Key
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID (UUID)"
+// @Param keyId path string true "API key ID (UUID)"
+// @Success 200 {object} gin_utils.MessageResponse
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 401 {object} gin_utils.MessageResponse
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Failure 500 {object} gin_utils.MessageResponse
+// @Router /api/v1/organizations/{id}/api-keys/{keyId} [delete]
+func (ctrl *apiKeyController) RevokeApiKey(c *gin.Context) {
+	orgId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "invalid organization id"})
+		return
+	}
+	keyId, err := uuid.Parse(c.Param("keyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "invalid API key id"})
+		return
+	}
+
+	claims := auth_utils.GetAuthClaim(c.Request.Context())
+	code, err := ctrl.apiKeyUseCase.RevokeApiKey(c.Request.Context(), claims, orgId, keyId, c.ClientIP())
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(code, gin_utils.MessageResponse{Message: "API key revoked"})
+}
@@ -0,0 +1,9 @@
+package api_key_controller
+
+import "github.com/gin-gonic/gin"
+
+type ApiKeyController interface {
+	GetApiKeys(c *gin.Context)
+	CreateApiKey(c *gin.Context)
+	RevokeApiKey(c *gin.Context)
+}
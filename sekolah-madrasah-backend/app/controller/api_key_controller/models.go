@@ -0,0 +1,33 @@
+package api_key_controller
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ApiKey struct {
+	Id             uuid.UUID  `json:"id"`
+	OrganizationId uuid.UUID  `json:"organization_id"`
+	Name           string     `json:"name"`
+	Prefix         string     `json:"prefix"`
+	Scopes         []string   `json:"scopes"`
+	CreatedBy      uuid.UUID  `json:"created_by"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	LastUsedAt     *time.Time `json:"last_used_at"`
+	LastUsedIp     string     `json:"last_used_ip"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type CreatedApiKey struct {
+	ApiKey
+	// Key is only returned when the key is created
+	Key string `json:"key"`
+}
+
+type CreateApiKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
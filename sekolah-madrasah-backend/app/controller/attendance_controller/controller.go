@@ -0,0 +1,495 @@
+package attendance_controller
+
+import (
+	"errors"
+	"net/http"
+	"sekolah-madrasah/app/use_case/attendance_use_case"
+	"sekolah-madrasah/database/schemas"
+	"sekolah-madrasah/pkg/auth_utils"
+	"sekolah-madrasah/pkg/gin_utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AttendanceController struct {
+	useCase attendance_use_case.AttendanceUseCase
+}
+
+func NewAttendanceController(useCase attendance_use_case.AttendanceUseCase) *AttendanceController {
+	return &AttendanceController{useCase: useCase}
+}
+
+type AttendanceRecordDTO struct {
+	ClassEnrollmentId string  `json:"class_enrollment_id" binding:"required"`
+	Status            string  `json:"status" binding:"required"` // hadir, sakit, izin, alpa, terlambat
+	Note              *string `json:"note"`
+}
+
+type SaveAttendanceDTO struct {
+	Date       string                `json:"date" binding:"required"` // Format: YYYY-MM-DD
+	Records    []AttendanceRecordDTO `json:"records" binding:"required,dive"`
+	RecordedBy *string               `json:"recorded_by"` // Staff member recording; required with an API key, ignored otherwise
+}
+
+type SaveLessonDTO struct {
+	Topic      string                `json:"topic" binding:"required,max=255"` // Materi yang diajarkan
+	Notes      *string               `json:"notes"`
+	Records    []AttendanceRecordDTO `json:"records" binding:"dive"`
+	RecordedBy *string               `json:"recorded_by"` // Teacher recording; required with an API key, ignored otherwise
+}
+
+// errorStatus maps use case errors to their HTTP status; anything else is a validation error.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, attendance_use_case.ErrClassNotFound), errors.Is(err, attendance_use_case.ErrLessonNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, attendance_use_case.ErrNotAllowed), errors.Is(err, attendance_use_case.ErrNotLessonTeacher),
+		errors.Is(err, attendance_use_case.ErrRecorderNotFound):
+		return http.StatusForbidden
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// GetClassAttendance godoc
+// @Summary Get a class's attendance for a day
+// @Description Lists the students enrolled in the class on the date with their recorded status; status is null for students not recorded yet.
+// @Tags Attendance
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param classId path string true "Class ID"
+// @Param date query string false "Date (YYYY-MM-DD), defaults to today"
+// @Success 200 {object} gin_utils.DataResponse{data=attendance_use_case.ClassAttendanceView}
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/classes/{classId}/attendance [get]
+func (c *AttendanceController) GetClassAttendance(ctx *gin.Context) {
+	unitId, classId, ok := parseIds(ctx)
+	if !ok {
+		return
+	}
+
+	date := time.Now()
+	if value := ctx.Query("date"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid date, use YYYY-MM-DD"})
+			return
+		}
+		date = parsed
+	}
+
+	attendance, err := c.useCase.GetClassAttendance(unitId, classId, date)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Attendance retrieved successfully", Data: attendance})
+}
+
+// SaveClassAttendance godoc
+// @Summary Record a class's attendance for a day
+// @Description Records the status of several students at once, replacing what was recorded for them that day. Only the class's homeroom teacher or unit staff may record attendance; an API key records on behalf of the member named by recorded_by.
+// @Tags Attendance
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param classId path string true "Class ID"
+// @Param body body SaveAttendanceDTO true "Attendance records"
+// @Success 200 {object} gin_utils.DataResponse{data=attendance_use_case.ClassAttendanceView}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/classes/{classId}/attendance [put]
+func (c *AttendanceController) SaveClassAttendance(ctx *gin.Context) {
+	unitId, classId, ok := parseIds(ctx)
+	if !ok {
+		return
+	}
+
+	var dto SaveAttendanceDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+	date, err := time.Parse("2006-01-02", dto.Date)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid date, use YYYY-MM-DD"})
+		return
+	}
+
+	records, ok := parseRecords(ctx, dto.Records)
+	if !ok {
+		return
+	}
+	recorder, ok := parseRecorder(ctx, dto.RecordedBy)
+	if !ok {
+		return
+	}
+
+	attendance, err := c.useCase.SaveClassAttendance(&attendance_use_case.SaveAttendanceRequest{
+		UnitId:   unitId,
+		ClassId:  classId,
+		Date:     date,
+		Records:  records,
+		Recorder: recorder,
+	})
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Attendance saved successfully", Data: attendance})
+}
+
+// GetClassRecap godoc
+// @Summary Get a class's monthly attendance recap
+// @Description Totals per status for every student of the class in the month, and for the class as a whole.
+// @Tags Attendance
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param classId path string true "Class ID"
+// @Param month query string false "Month (YYYY-MM), defaults to the current month"
+// @Success 200 {object} gin_utils.DataResponse{data=attendance_use_case.ClassRecap}
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/classes/{classId}/attendance/recap [get]
+func (c *AttendanceController) GetClassRecap(ctx *gin.Context) {
+	unitId, classId, ok := parseIds(ctx)
+	if !ok {
+		return
+	}
+	month, ok := parseMonth(ctx)
+	if !ok {
+		return
+	}
+
+	recap, err := c.useCase.GetClassRecap(unitId, classId, month)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Attendance recap retrieved successfully", Data: recap})
+}
+
+// GetUnitRecap godoc
+// @Summary Get the unit's monthly attendance recap
+// @Description Totals per status for every class of the unit in the month.
+// @Tags Attendance
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param month query string false "Month (YYYY-MM), defaults to the current month"
+// @Param academic_year query string false "Only classes of this academic year"
+// @Success 200 {object} gin_utils.DataResponse{data=attendance_use_case.UnitRecap}
+// @Router /api/v1/units/{id}/attendance/recap [get]
+func (c *AttendanceController) GetUnitRecap(ctx *gin.Context) {
+	unitId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid unit ID"})
+		return
+	}
+	month, ok := parseMonth(ctx)
+	if !ok {
+		return
+	}
+
+	recap, err := c.useCase.GetUnitRecap(unitId, ctx.Query("academic_year"), month)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Attendance recap retrieved successfully", Data: recap})
+}
+
+// GetLesson godoc
+// @Summary Get a lesson's attendance and journal
+// @Description Lists the students of the lesson's class on the date with their status in the lesson, together with the teaching journal entry; journal is null until the lesson is recorded.
+// @Tags Attendance
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param timetableId path string true "Timetable entry ID"
+// @Param date path string true "Date (YYYY-MM-DD)"
+// @Success 200 {object} gin_utils.DataResponse{data=attendance_use_case.LessonView}
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/timetable/{timetableId}/lessons/{date} [get]
+func (c *AttendanceController) GetLesson(ctx *gin.Context) {
+	unitId, entryId, date, ok := parseLesson(ctx)
+	if !ok {
+		return
+	}
+
+	lesson, err := c.useCase.GetLesson(unitId, entryId, date)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Lesson retrieved successfully", Data: lesson})
+}
+
+// SaveLesson godoc
+// @Summary Record a lesson's attendance and journal
+// @Description Records the topic covered in a lesson held from the timetable and the attendance of its students, replacing what was recorded for them. Only the lesson's teacher, or a unit owner or admin, may record it; an API key records on behalf of the member named by recorded_by.
+// @Tags Attendance
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param timetableId path string true "Timetable entry ID"
+// @Param date path string true "Date (YYYY-MM-DD)"
+// @Param body body SaveLessonDTO true "Journal and attendance"
+// @Success 200 {object} gin_utils.DataResponse{data=attendance_use_case.LessonView}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/timetable/{timetableId}/lessons/{date} [put]
+func (c *AttendanceController) SaveLesson(ctx *gin.Context) {
+	unitId, entryId, date, ok := parseLesson(ctx)
+	if !ok {
+		return
+	}
+
+	var dto SaveLessonDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+	records, ok := parseRecords(ctx, dto.Records)
+	if !ok {
+		return
+	}
+	recorder, ok := parseRecorder(ctx, dto.RecordedBy)
+	if !ok {
+		return
+	}
+
+	lesson, err := c.useCase.SaveLesson(&attendance_use_case.LessonRequest{
+		UnitId:           unitId,
+		TimetableEntryId: entryId,
+		Date:             date,
+		Topic:            dto.Topic,
+		Notes:            dto.Notes,
+		Records:          records,
+		Recorder:         recorder,
+	})
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Lesson saved successfully", Data: lesson})
+}
+
+// GetJournals godoc
+// @Summary List a class's teaching journal
+// @Description Lists the lessons recorded for the class between two dates, in order.
+// @Tags Attendance
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param classId path string true "Class ID"
+// @Param from query string false "First day (YYYY-MM-DD), defaults to the first of the month"
+// @Param to query string false "Last day (YYYY-MM-DD), defaults to the end of the month"
+// @Param subject_id query string false "Only lessons of this subject"
+// @Success 200 {object} gin_utils.DataResponse{data=[]schemas.LessonJournal}
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/classes/{classId}/lessons [get]
+func (c *AttendanceController) GetJournals(ctx *gin.Context) {
+	unitId, classId, ok := parseIds(ctx)
+	if !ok {
+		return
+	}
+	from, to, ok := parseRange(ctx)
+	if !ok {
+		return
+	}
+
+	var subjectId *uuid.UUID
+	if value := ctx.Query("subject_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid subject ID"})
+			return
+		}
+		subjectId = &id
+	}
+
+	journals, err := c.useCase.GetJournals(unitId, classId, subjectId, from, to)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Journal retrieved successfully", Data: journals})
+}
+
+// GetSubjectRecap godoc
+// @Summary Get a class's attendance per subject
+// @Description Totals lesson attendance per subject for every student of the class, with the attendance rate in each subject.
+// @Tags Attendance
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param classId path string true "Class ID"
+// @Param from query string false "First day (YYYY-MM-DD), defaults to the first of the month"
+// @Param to query string false "Last day (YYYY-MM-DD), defaults to the end of the month"
+// @Success 200 {object} gin_utils.DataResponse{data=attendance_use_case.SubjectRecap}
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/classes/{classId}/attendance/subjects [get]
+func (c *AttendanceController) GetSubjectRecap(ctx *gin.Context) {
+	unitId, classId, ok := parseIds(ctx)
+	if !ok {
+		return
+	}
+	from, to, ok := parseRange(ctx)
+	if !ok {
+		return
+	}
+
+	recap, err := c.useCase.GetSubjectRecap(unitId, classId, from, to)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Subject attendance retrieved successfully", Data: recap})
+}
+
+// GetReconciliation godoc
+// @Summary Compare daily and lesson attendance
+// @Description Flags students whose lesson attendance disagrees with their daily attendance: present in the morning but absent from a later lesson, or absent for the day but marked present in a lesson.
+// @Tags Attendance
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param classId path string true "Class ID"
+// @Param date query string false "Date (YYYY-MM-DD), defaults to today"
+// @Success 200 {object} gin_utils.DataResponse{data=attendance_use_case.Reconciliation}
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/classes/{classId}/attendance/reconciliation [get]
+func (c *AttendanceController) GetReconciliation(ctx *gin.Context) {
+	unitId, classId, ok := parseIds(ctx)
+	if !ok {
+		return
+	}
+
+	date := time.Now()
+	if value := ctx.Query("date"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid date, use YYYY-MM-DD"})
+			return
+		}
+		date = parsed
+	}
+
+	reconciliation, err := c.useCase.GetReconciliation(unitId, classId, date)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Attendance reconciled successfully", Data: reconciliation})
+}
+
+func parseRecorder(ctx *gin.Context, recordedBy *string) (attendance_use_case.Recorder, bool) {
+	access, _ := auth_utils.GetUnitAccess(ctx.Request.Context())
+	claims := auth_utils.GetAuthClaim(ctx.Request.Context())
+	recorder := attendance_use_case.Recorder{
+		UserId:    claims.UserID,
+		Role:      access.Role,
+		ViaApiKey: claims.IsApiKey(),
+	}
+	if recorder.ViaApiKey && recordedBy != nil && *recordedBy != "" {
+		staffUserId, err := uuid.Parse(*recordedBy)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid recorded_by user ID"})
+			return attendance_use_case.Recorder{}, false
+		}
+		recorder.StaffUserId = &staffUserId
+	}
+	return recorder, true
+}
+
+func parseRecords(ctx *gin.Context, dtos []AttendanceRecordDTO) ([]attendance_use_case.RecordRequest, bool) {
+	records := make([]attendance_use_case.RecordRequest, 0, len(dtos))
+	for _, record := range dtos {
+		enrollmentId, err := uuid.Parse(record.ClassEnrollmentId)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid class enrollment ID"})
+			return nil, false
+		}
+		records = append(records, attendance_use_case.RecordRequest{
+			ClassEnrollmentId: enrollmentId,
+			Status:            schemas.AttendanceStatus(record.Status),
+			Note:              record.Note,
+		})
+	}
+	return records, true
+}
+
+func parseLesson(ctx *gin.Context) (uuid.UUID, uuid.UUID, time.Time, bool) {
+	unitId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid unit ID"})
+		return uuid.Nil, uuid.Nil, time.Time{}, false
+	}
+	entryId, err := uuid.Parse(ctx.Param("timetableId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid timetable entry ID"})
+		return uuid.Nil, uuid.Nil, time.Time{}, false
+	}
+	date, err := time.Parse("2006-01-02", ctx.Param("date"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid date, use YYYY-MM-DD"})
+		return uuid.Nil, uuid.Nil, time.Time{}, false
+	}
+	return unitId, entryId, date, true
+}
+
+// parseRange reads the from and to query dates, defaulting to the current month.
+func parseRange(ctx *gin.Context) (time.Time, time.Time, bool) {
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, -1)
+	for _, bound := range []struct {
+		name  string
+		value *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		value := ctx.Query(bound.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid " + bound.name + " date, use YYYY-MM-DD"})
+			return time.Time{}, time.Time{}, false
+		}
+		*bound.value = parsed
+	}
+	return from, to, true
+}
+
+func parseIds(ctx *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	unitId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid unit ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	classId, err := uuid.Parse(ctx.Param("classId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid class ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return unitId, classId, true
+}
+
+func parseMonth(ctx *gin.Context) (time.Time, bool) {
+	value := ctx.Query("month")
+	if value == "" {
+		return time.Now(), true
+	}
+	month, err := time.Parse("2006-01", value)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid month, use YYYY-MM"})
+		return time.Time{}, false
+	}
+	return month, true
+}
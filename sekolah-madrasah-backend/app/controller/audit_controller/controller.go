@@ -0,0 +1,107 @@
+package audit_controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"sekolah-madrasah/app/use_case/audit_use_case"
+	"sekolah-madrasah/pkg/gin_utils"
+	"sekolah-madrasah/pkg/paginate_utils"
+)
+
+type auditController struct {
+	auditUseCase audit_use_case.AuditUseCase
+}
+
+func NewAuditController(auditUseCase audit_use_case.AuditUseCase) AuditController {
+	return &auditController{auditUseCase: auditUseCase}
+}
+
+func (ctrl *auditController) toAuditLogResponse(l audit_use_case.AuditLog) AuditLog {
+	details := json.RawMessage(l.Details)
+	if !json.Valid(details) {
+		details = json.RawMessage("{}")
+	}
+
+	return AuditLog{
+		Id:        l.Id,
+		ActorId:   l.ActorId,
+		UserId:    l.UserId,
+		Action:    l.Action,
+		IpAddress: l.IpAddress,
+		Details:   details,
+		CreatedAt: l.CreatedAt,
+	}
+}
+
+// GetAuditLogs godoc
+// @Summary List audit logs
+// @Description Retrieves security-relevant actions such as account lockouts and unlocks, newest first
+// @Tags Audit
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param user_id query string false "Filter by affected user (UUID)"
+// @Param actor_id query string false "Filter by acting user (UUID)"
+// @Param action query string false "Filter by action, e.g. auth.account_locked"
+// @Success 200 {object} gin_utils.DataWithPaginateResponse{data=[]AuditLog}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 401 {object} gin_utils.MessageResponse
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 500 {object} gin_utils.MessageResponse
+// @Router /api/v1/audit-logs [get]
+func (ctrl *auditController) GetAuditLogs(c *gin.Context) {
+	paginate := &paginate_utils.PaginateData{}
+	queryParams := make(map[string]interface{})
+	for k, v := range c.Request.URL.Query() {
+		if len(v) > 0 {
+			queryParams[k] = v[0]
+		}
+	}
+	paginate_utils.CheckPaginateFromMap(queryParams, paginate)
+
+	filter := audit_use_case.AuditLogFilter{}
+
+	if userIdParam := c.Query("user_id"); userIdParam != "" {
+		userId, err := uuid.Parse(userIdParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "invalid user_id"})
+			return
+		}
+		filter.UserId = &userId
+	}
+	if actorIdParam := c.Query("actor_id"); actorIdParam != "" {
+		actorId, err := uuid.Parse(actorIdParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "invalid actor_id"})
+			return
+		}
+		filter.ActorId = &actorId
+	}
+	if action := c.Query("action"); action != "" {
+		filter.Action = &action
+	}
+
+	logs, code, err := ctrl.auditUseCase.GetAuditLogs(c.Request.Context(), filter, paginate)
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	result := make([]AuditLog, len(logs))
+	for i, l := range logs {
+		result[i] = ctrl.toAuditLogResponse(l)
+	}
+
+	c.JSON(code, gin_utils.DataWithPaginateResponse{
+		DataResponse: gin_utils.DataResponse{
+			Message: "success",
+			Data:    result,
+		},
+		Paginate: paginate,
+	})
+}
@@ -0,0 +1,7 @@
+package audit_controller
+
+import "github.com/gin-gonic/gin"
+
+type AuditController interface {
+	GetAuditLogs(c *gin.Context)
+}
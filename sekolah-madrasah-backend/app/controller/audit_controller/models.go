@@ -0,0 +1,18 @@
+package audit_controller
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditLog struct {
+	Id        uuid.UUID       `json:"id"`
+	ActorId   *uuid.UUID      `json:"actor_id"`
+	UserId    *uuid.UUID      `json:"user_id"`
+	Action    string          `json:"action"`
+	IpAddress string          `json:"ip_address"`
+	Details   json.RawMessage `json:"details" swaggertype:"object"`
+	CreatedAt time.Time       `json:"created_at"`
+}
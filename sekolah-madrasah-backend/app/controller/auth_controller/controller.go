@@ -1,21 +1,29 @@
 package auth_controller
 
 import (
+	"errors"
+	"math"
 	"net/http"
 	"reflect"
+	"strconv"
+	"time"
 
 	"github.com/Rhyanz46/go-map-validator/map_validator"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"sekolah-madrasah/app/use_case/auth_use_case"
+	"sekolah-madrasah/app/use_case/sso_use_case"
+	"sekolah-madrasah/pkg/auth_utils"
 	"sekolah-madrasah/pkg/gin_utils"
 )
 
 type authController struct {
 	authUseCase auth_use_case.AuthUseCase
+	ssoUseCase  sso_use_case.SsoUseCase
 }
 
-func NewAuthController(authUseCase auth_use_case.AuthUseCase) AuthController {
-	return &authController{authUseCase: authUseCase}
+func NewAuthController(authUseCase auth_use_case.AuthUseCase, ssoUseCase sso_use_case.SsoUseCase) AuthController {
+	return &authController{authUseCase: authUseCase, ssoUseCase: ssoUseCase}
 }
 
 // Login godoc
@@ -28,6 +36,8 @@ func NewAuthController(authUseCase auth_use_case.AuthUseCase) AuthController {
 // @Success 200 {object} gin_utils.DataResponse{data=LoginResponse}
 // @Failure 400 {object} gin_utils.MessageResponse
 // @Failure 401 {object} gin_utils.MessageResponse
+// @Failure 423 {object} gin_utils.MessageResponse "Account locked, see Retry-After"
+// @Failure 429 {object} gin_utils.MessageResponse "Too many failed attempts, see Retry-After"
 // @Failure 500 {object} gin_utils.MessageResponse
 // @Router /api/v1/auth/login [post]
 func (ctrl *authController) Login(c *gin.Context) {
@@ -61,35 +71,34 @@ func (ctrl *authController) Login(c *gin.Context) {
 	}
 
 	result, code, err := ctrl.authUseCase.Login(c.Request.Context(), auth_use_case.LoginRequest{
-		Email:    req.Email,
-		Password: req.Password,
+		Email:     req.Email,
+		Password:  req.Password,
+		UserAgent: c.Request.UserAgent(),
+		IpAddress: c.ClientIP(),
 	})
 	if err != nil {
+		var throttled *auth_use_case.LoginThrottledError
+		if errors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		}
 		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
 		return
 	}
 
+	message := "login successful"
+	if result.TwoFactorRequired {
+		message = "two-factor verification required"
+	}
+
 	c.JSON(code, gin_utils.DataResponse{
-		Message: "login successful",
-		Data: LoginResponse{
-			AccessToken:  result.AccessToken,
-			RefreshToken: result.RefreshToken,
-			ExpiresAt:    result.ExpiresAt,
-			User: UserInfo{
-				Id:           result.User.Id,
-				Email:        result.User.Email,
-				FullName:     result.User.FullName,
-				IsSuperAdmin: result.User.IsSuperAdmin,
-				IsActive:     result.User.IsActive,
-				LastLoginAt:  result.User.LastLoginAt,
-			},
-		},
+		Message: message,
+		Data:    toLoginResponse(result),
 	})
 }
 
 // Register godoc
 // @Summary User registration
-// @Description Creates a new user account with email, password, and full name
+// @Description Creates a new user account with email, password, and full name. With unit_code and role the user also signs up for that school and can log in once its administrator approves.
 // @Tags Authentication
 // @Accept json
 // @Produce json
@@ -112,6 +121,16 @@ func (ctrl *authController) Register(c *gin.Context) {
 		SetRule("full_name", map_validator.Rules{
 			Type: reflect.String,
 			Max:  map_validator.SetTotal(100),
+		}).
+		SetRule("unit_code", map_validator.Rules{
+			Type: reflect.String,
+			Max:  map_validator.SetTotal(50),
+			Null: true,
+		}).
+		SetRule("role", map_validator.Rules{
+			Type: reflect.String,
+			Max:  map_validator.SetTotal(20),
+			Null: true,
 		})
 
 	jsonDataRoles := map_validator.NewValidateBuilder().SetRules(roles)
@@ -133,26 +152,33 @@ func (ctrl *authController) Register(c *gin.Context) {
 		return
 	}
 
-	result, code, err := ctrl.authUseCase.Register(c.Request.Context(), auth_use_case.RegisterRequest{
+	request := auth_use_case.RegisterRequest{
 		Email:    req.Email,
 		Password: req.Password,
 		FullName: req.FullName,
-	})
+	}
+	if req.UnitCode != nil {
+		request.UnitCode = *req.UnitCode
+	}
+	if req.Role != nil {
+		request.Role = *req.Role
+	}
+
+	result, code, err := ctrl.authUseCase.Register(c.Request.Context(), request)
 	if err != nil {
 		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
 		return
 	}
 
+	message := "registration successful"
+	if request.UnitCode != "" {
+		message = "registration successful, waiting for approval by the school administrator"
+	}
+
 	c.JSON(code, gin_utils.DataResponse{
-		Message: "registration successful",
+		Message: message,
 		Data: RegisterResponse{
-			User: UserInfo{
-				Id:           result.Id,
-				Email:        result.Email,
-				FullName:     result.FullName,
-				IsSuperAdmin: result.IsSuperAdmin,
-				IsActive:     result.IsActive,
-			},
+			User: toUserInfo(result),
 		},
 	})
 }
@@ -197,6 +223,8 @@ func (ctrl *authController) RefreshToken(c *gin.Context) {
 
 	result, code, err := ctrl.authUseCase.RefreshToken(c.Request.Context(), auth_use_case.RefreshTokenRequest{
 		RefreshToken: req.RefreshToken,
+		UserAgent:    c.Request.UserAgent(),
+		IpAddress:    c.ClientIP(),
 	})
 	if err != nil {
 		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
@@ -205,18 +233,608 @@ func (ctrl *authController) RefreshToken(c *gin.Context) {
 
 	c.JSON(code, gin_utils.DataResponse{
 		Message: "token refreshed",
-		Data: LoginResponse{
-			AccessToken:  result.AccessToken,
-			RefreshToken: result.RefreshToken,
-			ExpiresAt:    result.ExpiresAt,
-			User: UserInfo{
-				Id:           result.User.Id,
-				Email:        result.User.Email,
-				FullName:     result.User.FullName,
-				IsSuperAdmin: result.User.IsSuperAdmin,
-				IsActive:     result.User.IsActive,
-				LastLoginAt:  result.User.LastLoginAt,
-			},
+		Data:    toLoginResponse(result),
+	})
+}
+
+// Logout godoc
+// @Summary Log out the current session
+// @Description Revokes the refresh token family of the current session and denylists its access tokens
+// @Tags Authentication
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} gin_utils.MessageResponse
+// @Failure 401 {object} gin_utils.MessageResponse
+// @Failure 500 {object} gin_utils.MessageResponse
+// @Router /api/v1/auth/logout [post]
+func (ctrl *authController) Logout(c *gin.Context) {
+	claims := auth_utils.GetAuthClaim(c.Request.Context())
+	if claims.UserID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, gin_utils.MessageResponse{Message: "unauthorized"})
+		return
+	}
+
+	code, err := ctrl.authUseCase.Logout(c.Request.Context(), claims)
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin_utils.MessageResponse{Message: "logged out"})
+}
+
+// LogoutAll godoc
+// @Summary Log out every session
+// @Description Revokes all refresh tokens of the current user and denylists their access tokens
+// @Tags Authentication
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} gin_utils.MessageResponse
+// @Failure 401 {object} gin_utils.MessageResponse
+// @Failure 500 {object} gin_utils.MessageResponse
+// @Router /api/v1/auth/logout-all [post]
+func (ctrl *authController) LogoutAll(c *gin.Context) {
+	claims := auth_utils.GetAuthClaim(c.Request.Context())
+	if claims.UserID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, gin_utils.MessageResponse{Message: "unauthorized"})
+		return
+	}
+
+	code, err := ctrl.authUseCase.LogoutAll(c.Request.Context(), claims)
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin_utils.MessageResponse{Message: "logged out of all sessions"})
+}
+
+// ForgotPassword godoc
+// @Summary Request a password reset link
+// @Description Emails a single-use reset link when the address belongs to an active account. The response is the same either way.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body ForgotPasswordRequest true "Account email"
+// @Success 200 {object} gin_utils.MessageResponse
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 429 {object} gin_utils.MessageResponse
+// @Router /api/v1/auth/forgot-password [post]
+func (ctrl *authController) ForgotPassword(c *gin.Context) {
+	roles := map_validator.BuildRoles().
+		SetRule("email", map_validator.Rules{
+			Type: reflect.String,
+			Max:  map_validator.SetTotal(255),
+		})
+
+	jsonDataRoles := map_validator.NewValidateBuilder().SetRules(roles)
+	jsonDataValidate, err := jsonDataRoles.LoadJsonHttp(c.Request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	jsonData, err := jsonDataValidate.RunValidate()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	var req ForgotPasswordRequest
+	if err := jsonData.Bind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	code, err := ctrl.authUseCase.ForgotPassword(c.Request.Context(), auth_use_case.ForgotPasswordRequest{
+		Email:     req.Email,
+		IpAddress: c.ClientIP(),
+	})
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin_utils.MessageResponse{Message: "if the email is registered, a reset link has been sent"})
+}
+
+// ResetPassword godoc
+// @Summary Reset password with a reset token
+// @Description Sets a new password using the token from the reset email and signs the user out of every session
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} gin_utils.MessageResponse
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Router /api/v1/auth/reset-password [post]
+func (ctrl *authController) ResetPassword(c *gin.Context) {
+	roles := map_validator.BuildRoles().
+		SetRule("token", map_validator.Rules{
+			Type: reflect.String,
+			Min:  map_validator.SetTotal(1),
+			Max:  map_validator.SetTotal(255),
+		}).
+		SetRule("new_password", map_validator.Rules{
+			Type: reflect.String,
+			Min:  map_validator.SetTotal(8),
+		})
+
+	jsonDataRoles := map_validator.NewValidateBuilder().SetRules(roles)
+	jsonDataValidate, err := jsonDataRoles.LoadJsonHttp(c.Request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	jsonData, err := jsonDataValidate.RunValidate()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	var req ResetPasswordRequest
+	if err := jsonData.Bind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	code, err := ctrl.authUseCase.ResetPassword(c.Request.Context(), auth_use_case.ResetPasswordRequest{
+		Token:       req.Token,
+		NewPassword: req.NewPassword,
+	})
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin_utils.MessageResponse{Message: "password has been reset, please log in again"})
+}
+
+// VerifyEmail godoc
+// @Summary Verify an email address
+// @Description Marks the account's email as verified using the token from the verification email
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body VerifyEmailRequest true "Verification token"
+// @Success 200 {object} gin_utils.MessageResponse
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Router /api/v1/auth/verify-email [post]
+func (ctrl *authController) VerifyEmail(c *gin.Context) {
+	roles := map_validator.BuildRoles().
+		SetRule("token", map_validator.Rules{
+			Type: reflect.String,
+			Min:  map_validator.SetTotal(1),
+			Max:  map_validator.SetTotal(2048),
+		})
+
+	jsonDataRoles := map_validator.NewValidateBuilder().SetRules(roles)
+	jsonDataValidate, err := jsonDataRoles.LoadJsonHttp(c.Request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	jsonData, err := jsonDataValidate.RunValidate()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	var req VerifyEmailRequest
+	if err := jsonData.Bind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	code, err := ctrl.authUseCase.VerifyEmail(c.Request.Context(), auth_use_case.VerifyEmailRequest{Token: req.Token})
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin_utils.MessageResponse{Message: "email verified"})
+}
+
+// ResendEmailVerification godoc
+// @Summary Resend the verification email
+// @Description Mails a new verification link when the address belongs to an active, unverified account. The response is the same either way.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body ResendEmailVerificationRequest true "Account email"
+// @Success 200 {object} gin_utils.MessageResponse
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 429 {object} gin_utils.MessageResponse
+// @Router /api/v1/auth/resend-verification [post]
+func (ctrl *authController) ResendEmailVerification(c *gin.Context) {
+	roles := map_validator.BuildRoles().
+		SetRule("email", map_validator.Rules{
+			Type: reflect.String,
+			Max:  map_validator.SetTotal(255),
+		})
+
+	jsonDataRoles := map_validator.NewValidateBuilder().SetRules(roles)
+	jsonDataValidate, err := jsonDataRoles.LoadJsonHttp(c.Request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	jsonData, err := jsonDataValidate.RunValidate()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	var req ResendEmailVerificationRequest
+	if err := jsonData.Bind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	code, err := ctrl.authUseCase.ResendEmailVerification(c.Request.Context(), auth_use_case.ResendEmailVerificationRequest{Email: req.Email})
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin_utils.MessageResponse{Message: "if the email is registered and unverified, a verification link has been sent"})
+}
+
+// VerifyTwoFactor godoc
+// @Summary Complete a two-factor login
+// @Description Exchanges the challenge token returned by login, plus a TOTP code or a recovery code, for access and refresh tokens
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body VerifyTwoFactorRequest true "Challenge token and second factor"
+// @Success 200 {object} gin_utils.DataResponse{data=LoginResponse}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 401 {object} gin_utils.MessageResponse
+// @Failure 429 {object} gin_utils.MessageResponse
+// @Router /api/v1/auth/2fa/verify [post]
+func (ctrl *authController) VerifyTwoFactor(c *gin.Context) {
+	roles := map_validator.BuildRoles().
+		SetRule("challenge_token", map_validator.Rules{
+			Type: reflect.String,
+			Min:  map_validator.SetTotal(1),
+			Max:  map_validator.SetTotal(2048),
+		}).
+		SetRule("code", map_validator.Rules{
+			Type: reflect.String,
+			Max:  map_validator.SetTotal(10),
+			Null: true,
+		}).
+		SetRule("recovery_code", map_validator.Rules{
+			Type: reflect.String,
+			Max:  map_validator.SetTotal(20),
+			Null: true,
+		})
+
+	jsonDataRoles := map_validator.NewValidateBuilder().SetRules(roles)
+	jsonDataValidate, err := jsonDataRoles.LoadJsonHttp(c.Request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	jsonData, err := jsonDataValidate.RunValidate()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	var req VerifyTwoFactorRequest
+	if err := jsonData.Bind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	if req.Code == "" && req.RecoveryCode == "" {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "code or recovery_code is required"})
+		return
+	}
+
+	result, code, err := ctrl.authUseCase.VerifyTwoFactor(c.Request.Context(), auth_use_case.VerifyTwoFactorRequest{
+		ChallengeToken: req.ChallengeToken,
+		Code:           req.Code,
+		RecoveryCode:   req.RecoveryCode,
+		UserAgent:      c.Request.UserAgent(),
+		IpAddress:      c.ClientIP(),
+	})
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(code, gin_utils.DataResponse{
+		Message: "login successful",
+		Data:    toLoginResponse(result),
+	})
+}
+
+// ChangePassword godoc
+// @Summary Change the current user's password
+// @Description Verifies the current password, applies the strength rules to the new one, signs out every other session and returns a fresh token pair. This is the only endpoint available while must_change_password is set.
+// @Tags Users
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} gin_utils.DataResponse{data=LoginResponse}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 401 {object} gin_utils.MessageResponse
+// @Failure 500 {object} gin_utils.MessageResponse
+// @Router /api/v1/users/me/password [put]
+func (ctrl *authController) ChangePassword(c *gin.Context) {
+	claims := auth_utils.GetAuthClaim(c.Request.Context())
+	if claims.UserID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, gin_utils.MessageResponse{Message: "unauthorized"})
+		return
+	}
+
+	roles := map_validator.BuildRoles().
+		SetRule("old_password", map_validator.Rules{
+			Type: reflect.String,
+			Min:  map_validator.SetTotal(1),
+		}).
+		SetRule("new_password", map_validator.Rules{
+			Type: reflect.String,
+			Min:  map_validator.SetTotal(8),
+		})
+
+	jsonDataRoles := map_validator.NewValidateBuilder().SetRules(roles)
+	jsonDataValidate, err := jsonDataRoles.LoadJsonHttp(c.Request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	jsonData, err := jsonDataValidate.RunValidate()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := jsonData.Bind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	result, code, err := ctrl.authUseCase.ChangePassword(c.Request.Context(), claims, auth_use_case.ChangePasswordRequest{
+		OldPassword: req.OldPassword,
+		NewPassword: req.NewPassword,
+		UserAgent:   c.Request.UserAgent(),
+		IpAddress:   c.ClientIP(),
+	})
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(code, gin_utils.DataResponse{
+		Message: "password changed",
+		Data:    toLoginResponse(result),
+	})
+}
+
+// StartSsoLogin godoc
+// @Summary Start single sign-on
+// @Description Starts an OpenID Connect authorization code flow with PKCE against the organization's identity provider. Send the browser to authorization_url; the provider redirects back to the configured redirect URI with code and state.
+// @Tags Authentication
+// @Produce json
+// @Param organizationId path string true "Organization ID (UUID)"
+// @Success 200 {object} gin_utils.DataResponse{data=SsoAuthorizationResponse}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Failure 502 {object} gin_utils.MessageResponse
+// @Router /api/v1/auth/sso/{organizationId}/authorize [get]
+func (ctrl *authController) StartSsoLogin(c *gin.Context) {
+	orgId, err := uuid.Parse(c.Param("organizationId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "invalid organization id"})
+		return
+	}
+
+	result, code, err := ctrl.ssoUseCase.StartLogin(c.Request.Context(), orgId)
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(code, gin_utils.DataResponse{
+		Message: "success",
+		Data: SsoAuthorizationResponse{
+			AuthorizationUrl: result.AuthorizationUrl,
+			ExpiresAt:        result.ExpiresAt.Unix(),
+		},
+	})
+}
+
+// SsoCallback godoc
+// @Summary Finish single sign-on
+// @Description Exchanges the code and state the identity provider redirected back with for access and refresh tokens. Unknown users are linked by verified email or provisioned when the organization allows it, and IdP groups are mapped to unit roles.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body SsoCallbackRequest true "Code and state from the provider redirect"
+// @Success 200 {object} gin_utils.DataResponse{data=LoginResponse}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 401 {object} gin_utils.MessageResponse
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 502 {object} gin_utils.MessageResponse
+// @Router /api/v1/auth/sso/callback [post]
+func (ctrl *authController) SsoCallback(c *gin.Context) {
+	roles := map_validator.BuildRoles().
+		SetRule("state", map_validator.Rules{
+			Type: reflect.String,
+			Min:  map_validator.SetTotal(1),
+			Max:  map_validator.SetTotal(512),
+		}).
+		SetRule("code", map_validator.Rules{
+			Type: reflect.String,
+			Min:  map_validator.SetTotal(1),
+			Max:  map_validator.SetTotal(2048),
+		})
+
+	jsonDataRoles := map_validator.NewValidateBuilder().SetRules(roles)
+	jsonDataValidate, err := jsonDataRoles.LoadJsonHttp(c.Request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	jsonData, err := jsonDataValidate.RunValidate()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	var req SsoCallbackRequest
+	if err := jsonData.Bind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	result, code, err := ctrl.ssoUseCase.CompleteLogin(c.Request.Context(), sso_use_case.CallbackRequest{
+		State:     req.State,
+		Code:      req.Code,
+		UserAgent: c.Request.UserAgent(),
+		IpAddress: c.ClientIP(),
+	})
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	message := "login successful"
+	if result.TwoFactorRequired {
+		message = "two-factor verification required"
+	}
+
+	c.JSON(code, gin_utils.DataResponse{
+		Message: message,
+		Data:    toLoginResponse(result),
+	})
+}
+
+func toLoginResponse(result auth_use_case.LoginResponse) LoginResponse {
+	return LoginResponse{
+		AccessToken:       result.AccessToken,
+		RefreshToken:      result.RefreshToken,
+		ExpiresAt:         result.ExpiresAt,
+		User:              toUserInfo(result.User),
+		TwoFactorRequired: result.TwoFactorRequired,
+		ChallengeToken:    result.ChallengeToken,
+	}
+}
+
+func toUserInfo(user auth_use_case.UserInfo) UserInfo {
+	return UserInfo{
+		Id:                 user.Id,
+		Email:              user.Email,
+		FullName:           user.FullName,
+		IsSuperAdmin:       user.IsSuperAdmin,
+		IsActive:           user.IsActive,
+		MustChangePassword: user.MustChangePassword,
+		EmailVerifiedAt:    user.EmailVerifiedAt,
+		LastLoginAt:        user.LastLoginAt,
+	}
+}
+
+// UnlockUser godoc
+// @Summary Unlock a user account
+// @Description Lifts a lockout caused by failed login attempts and resets the failure count. The unlock is recorded in the audit log.
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID (UUID)"
+// @Success 200 {object} gin_utils.MessageResponse
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 401 {object} gin_utils.MessageResponse
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/users/{id}/unlock [post]
+func (ctrl *authController) UnlockUser(c *gin.Context) {
+	userId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "invalid user id"})
+		return
+	}
+
+	claims := auth_utils.GetAuthClaim(c.Request.Context())
+	code, err := ctrl.authUseCase.UnlockUser(c.Request.Context(), claims, userId, c.ClientIP())
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin_utils.MessageResponse{Message: "account unlocked"})
+}
+
+// Impersonate godoc
+// @Summary Impersonate a user
+// @Description Issues a short-lived access token that acts as the user on behalf of the calling super admin. The token is read-only unless allow_writes is set, can never change the user's credentials, and has no refresh token. The start and every request made with the token are recorded in the audit log with both identities.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID (UUID)"
+// @Param request body ImpersonateRequest true "Reason and options"
+// @Success 200 {object} gin_utils.DataResponse{data=ImpersonationResponse}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 401 {object} gin_utils.MessageResponse
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/users/{id}/impersonate [post]
+func (ctrl *authController) Impersonate(c *gin.Context) {
+	userId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "invalid user id"})
+		return
+	}
+
+	var req ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	claims := auth_utils.GetAuthClaim(c.Request.Context())
+	result, code, err := ctrl.authUseCase.Impersonate(c.Request.Context(), claims, userId, auth_use_case.ImpersonateRequest{
+		Reason:      req.Reason,
+		AllowWrites: req.AllowWrites,
+		Duration:    time.Duration(req.DurationMinutes) * time.Minute,
+		UserAgent:   c.Request.UserAgent(),
+		IpAddress:   c.ClientIP(),
+	})
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(code, gin_utils.DataResponse{
+		Message: "impersonation started",
+		Data: ImpersonationResponse{
+			AccessToken: result.AccessToken,
+			ExpiresAt:   result.ExpiresAt,
+			AllowWrites: result.AllowWrites,
+			User:        toUserInfo(result.User),
 		},
 	})
 }
+
+// Jwks godoc
+// @Summary Token verification keys
+// @Description Publishes the public keys access tokens are signed with as a JSON Web Key Set, so other services can verify tokens without the signing secret. Keys are matched by the kid header; retired keys stay listed until the tokens they signed expire.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} auth_utils.Jwks
+// @Router /.well-known/jwks.json [get]
+func (ctrl *authController) Jwks(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, auth_utils.PublicJwks())
+}
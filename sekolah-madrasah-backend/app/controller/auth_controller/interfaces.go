@@ -6,4 +6,17 @@ type AuthController interface {
 	Login(c *gin.Context)
 	Register(c *gin.Context)
 	RefreshToken(c *gin.Context)
+	Logout(c *gin.Context)
+	LogoutAll(c *gin.Context)
+	ForgotPassword(c *gin.Context)
+	ResetPassword(c *gin.Context)
+	ChangePassword(c *gin.Context)
+	VerifyEmail(c *gin.Context)
+	ResendEmailVerification(c *gin.Context)
+	VerifyTwoFactor(c *gin.Context)
+	StartSsoLogin(c *gin.Context)
+	SsoCallback(c *gin.Context)
+	UnlockUser(c *gin.Context)
+	Impersonate(c *gin.Context)
+	Jwks(c *gin.Context)
 }
@@ -15,28 +15,90 @@ type RegisterRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 	FullName string `json:"full_name"`
+	// UnitCode and Role sign the user up for a school, pending the school's approval
+	UnitCode *string `json:"unit_code"`
+	Role     *string `json:"role" enums:"teacher,parent,student"`
 }
 
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type ResendEmailVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+type VerifyTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+type SsoCallbackRequest struct {
+	State string `json:"state"`
+	Code  string `json:"code"`
+}
+
+type SsoAuthorizationResponse struct {
+	AuthorizationUrl string `json:"authorization_url"`
+	// ExpiresAt is when the sign-in must be finished, as a Unix timestamp
+	ExpiresAt int64 `json:"expires_at"`
+}
+
 type LoginResponse struct {
-	AccessToken  string   `json:"access_token"`
-	RefreshToken string   `json:"refresh_token"`
+	AccessToken  string   `json:"access_token,omitempty"`
+	RefreshToken string   `json:"refresh_token,omitempty"`
 	ExpiresAt    int64    `json:"expires_at"`
 	User         UserInfo `json:"user"`
+	// TwoFactorRequired means the login must be finished at /auth/2fa/verify with ChallengeToken
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
 }
 
 type UserInfo struct {
-	Id           uuid.UUID  `json:"id"`
-	Email        string     `json:"email"`
-	FullName     string     `json:"full_name"`
-	IsSuperAdmin bool       `json:"is_super_admin"`
-	IsActive     bool       `json:"is_active"`
-	LastLoginAt  *time.Time `json:"last_login_at,omitempty"`
+	Id                 uuid.UUID  `json:"id"`
+	Email              string     `json:"email"`
+	FullName           string     `json:"full_name"`
+	IsSuperAdmin       bool       `json:"is_super_admin"`
+	IsActive           bool       `json:"is_active"`
+	MustChangePassword bool       `json:"must_change_password"`
+	EmailVerifiedAt    *time.Time `json:"email_verified_at"`
+	LastLoginAt        *time.Time `json:"last_login_at,omitempty"`
 }
 
 type RegisterResponse struct {
 	User UserInfo `json:"user"`
 }
+
+type ImpersonateRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+	// AllowWrites lifts the default read-only restriction
+	AllowWrites bool `json:"allow_writes"`
+	// DurationMinutes defaults to 15 and may be at most 60
+	DurationMinutes int `json:"duration_minutes" binding:"min=0,max=60"`
+}
+
+type ImpersonationResponse struct {
+	AccessToken string   `json:"access_token"`
+	ExpiresAt   int64    `json:"expires_at"`
+	AllowWrites bool     `json:"allow_writes"`
+	User        UserInfo `json:"user"`
+}
@@ -0,0 +1,597 @@
+package gradebook_controller
+
+import (
+	"errors"
+	"net/http"
+	"sekolah-madrasah/app/use_case/gradebook_use_case"
+	"sekolah-madrasah/database/schemas"
+	"sekolah-madrasah/pkg/auth_utils"
+	"sekolah-madrasah/pkg/gin_utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type GradebookController struct {
+	useCase gradebook_use_case.GradebookUseCase
+}
+
+func NewGradebookController(useCase gradebook_use_case.GradebookUseCase) *GradebookController {
+	return &GradebookController{useCase: useCase}
+}
+
+type CreateAssessmentDTO struct {
+	AcademicYear string   `json:"academic_year"`                                      // Defaults to the unit's current one
+	Semester     int      `json:"semester"`                                           // Defaults to the unit's current one
+	Type         string   `json:"type" binding:"required,oneof=harian tugas pts pas"` // harian, tugas, pts, pas
+	Name         string   `json:"name" binding:"required,max=100"`
+	Competency   *string  `json:"competency" binding:"omitempty,max=255"` // Tujuan pembelajaran, used in rapor descriptions
+	Date         *string  `json:"date"`                                   // Format: YYYY-MM-DD
+	Weight       float64  `json:"weight" binding:"required"`
+	MaxScore     *float64 `json:"max_score"` // Defaults to 100
+}
+
+type UpdateAssessmentDTO struct {
+	Type       *string  `json:"type" binding:"omitempty,oneof=harian tugas pts pas"`
+	Name       *string  `json:"name" binding:"omitempty,max=100"`
+	Competency *string  `json:"competency" binding:"omitempty,max=255"`
+	Date       *string  `json:"date"`
+	Weight     *float64 `json:"weight"`
+	MaxScore   *float64 `json:"max_score"`
+}
+
+type ScoreDTO struct {
+	ClassEnrollmentId string   `json:"class_enrollment_id" binding:"required"`
+	Score             *float64 `json:"score" binding:"required"`
+	Note              *string  `json:"note"`
+	Version           int      `json:"version"` // As loaded, 0 for a new score
+}
+
+type SaveScoresDTO struct {
+	Scores []ScoreDTO `json:"scores" binding:"required,dive"`
+}
+
+type ThresholdDTO struct {
+	SubjectId  *string  `json:"subject_id"` // Leave out for every subject
+	Level      *int     `json:"level"`      // Leave out for every level
+	MinScore   *float64 `json:"min_score" binding:"required"`
+	PredicateA *float64 `json:"predicate_a" binding:"required"` // Lowest average for an A
+	PredicateB *float64 `json:"predicate_b" binding:"required"`
+	PredicateC *float64 `json:"predicate_c" binding:"required"` // Below this is a D
+}
+
+// errorStatus maps use case errors to their HTTP status; anything else is a validation error.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, gradebook_use_case.ErrClassNotFound),
+		errors.Is(err, gradebook_use_case.ErrSubjectNotFound),
+		errors.Is(err, gradebook_use_case.ErrAssessmentNotFound),
+		errors.Is(err, gradebook_use_case.ErrThresholdNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, gradebook_use_case.ErrNotSubjectTeacher):
+		return http.StatusForbidden
+	case errors.Is(err, gradebook_use_case.ErrGradesLocked),
+		errors.Is(err, gradebook_use_case.ErrThresholdExists):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// respondError answers with the error; stale scores are listed in data so the
+// sheet can mark the students to reload.
+func respondError(ctx *gin.Context, err error) {
+	var staleErr *gradebook_use_case.StaleScoresError
+	if errors.As(err, &staleErr) {
+		ctx.JSON(http.StatusConflict, gin_utils.DataResponse{Message: err.Error(), Data: staleErr.ClassEnrollmentIds})
+		return
+	}
+	ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+}
+
+// ListAssessments godoc
+// @Summary List a class's assessments in a subject
+// @Description academic_year and semester default to the unit's current ones.
+// @Tags Gradebook
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param classId path string true "Class ID"
+// @Param subjectId path string true "Subject ID"
+// @Param academic_year query string false "Academic Year"
+// @Param semester query int false "Semester"
+// @Success 200 {object} gin_utils.DataResponse{data=[]schemas.Assessment}
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/classes/{classId}/subjects/{subjectId}/assessments [get]
+func (c *GradebookController) ListAssessments(ctx *gin.Context) {
+	unitId, classId, subjectId, ok := parseClassSubject(ctx)
+	if !ok {
+		return
+	}
+	semester, ok := parseSemester(ctx)
+	if !ok {
+		return
+	}
+
+	assessments, err := c.useCase.ListAssessments(unitId, classId, subjectId, ctx.Query("academic_year"), semester)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Assessments retrieved successfully", Data: assessments})
+}
+
+// CreateAssessment godoc
+// @Summary Create an assessment
+// @Description Defines a daily test, assignment, PTS or PAS for a class in a subject, with its weight and maximum score.
+// @Tags Gradebook
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param classId path string true "Class ID"
+// @Param subjectId path string true "Subject ID"
+// @Param body body CreateAssessmentDTO true "Assessment"
+// @Success 201 {object} gin_utils.DataResponse{data=schemas.Assessment}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/classes/{classId}/subjects/{subjectId}/assessments [post]
+func (c *GradebookController) CreateAssessment(ctx *gin.Context) {
+	unitId, classId, subjectId, ok := parseClassSubject(ctx)
+	if !ok {
+		return
+	}
+
+	var dto CreateAssessmentDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+	date, ok := parseDate(ctx, dto.Date)
+	if !ok {
+		return
+	}
+
+	req := &gradebook_use_case.AssessmentRequest{
+		UnitId:       unitId,
+		ClassId:      classId,
+		SubjectId:    subjectId,
+		AcademicYear: dto.AcademicYear,
+		Semester:     dto.Semester,
+		Type:         schemas.AssessmentType(dto.Type),
+		Name:         dto.Name,
+		Competency:   dto.Competency,
+		Date:         date,
+		Weight:       dto.Weight,
+		Grader:       grader(ctx),
+	}
+	if dto.MaxScore != nil {
+		req.MaxScore = *dto.MaxScore
+	}
+
+	assessment, err := c.useCase.CreateAssessment(req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin_utils.DataResponse{Message: "Assessment created successfully", Data: assessment})
+}
+
+// UpdateAssessment godoc
+// @Summary Update an assessment
+// @Tags Gradebook
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param assessmentId path string true "Assessment ID"
+// @Param body body UpdateAssessmentDTO true "Assessment"
+// @Success 200 {object} gin_utils.DataResponse{data=schemas.Assessment}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/assessments/{assessmentId} [put]
+func (c *GradebookController) UpdateAssessment(ctx *gin.Context) {
+	unitId, assessmentId, ok := parseAssessment(ctx)
+	if !ok {
+		return
+	}
+
+	var dto UpdateAssessmentDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+	date, ok := parseDate(ctx, dto.Date)
+	if !ok {
+		return
+	}
+
+	req := &gradebook_use_case.UpdateAssessmentRequest{
+		Name:       dto.Name,
+		Competency: dto.Competency,
+		Date:       date,
+		Weight:     dto.Weight,
+		MaxScore:   dto.MaxScore,
+		Grader:     grader(ctx),
+	}
+	if dto.Type != nil {
+		assessmentType := schemas.AssessmentType(*dto.Type)
+		req.Type = &assessmentType
+	}
+
+	assessment, err := c.useCase.UpdateAssessment(unitId, assessmentId, req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Assessment updated successfully", Data: assessment})
+}
+
+// DeleteAssessment godoc
+// @Summary Delete an assessment
+// @Tags Gradebook
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param assessmentId path string true "Assessment ID"
+// @Success 200 {object} gin_utils.MessageResponse
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/assessments/{assessmentId} [delete]
+func (c *GradebookController) DeleteAssessment(ctx *gin.Context) {
+	unitId, assessmentId, ok := parseAssessment(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.useCase.DeleteAssessment(unitId, assessmentId, grader(ctx)); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.MessageResponse{Message: "Assessment deleted successfully"})
+}
+
+// GetScores godoc
+// @Summary Get an assessment's scores
+// @Description Lists the class's students with their score and its version; send the version back when saving.
+// @Tags Gradebook
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param assessmentId path string true "Assessment ID"
+// @Success 200 {object} gin_utils.DataResponse{data=gradebook_use_case.ScoreSheet}
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/assessments/{assessmentId}/scores [get]
+func (c *GradebookController) GetScores(ctx *gin.Context) {
+	unitId, assessmentId, ok := parseAssessment(ctx)
+	if !ok {
+		return
+	}
+
+	sheet, err := c.useCase.GetScores(unitId, assessmentId)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Scores retrieved successfully", Data: sheet})
+}
+
+// SaveScores godoc
+// @Summary Enter an assessment's scores
+// @Description Stores the scores of several students at once. Each score carries the version it was loaded at (0 for a new one); if any changed in the meantime nothing is saved and 409 lists the class enrollments to reload. Only the teacher assigned to the class and subject may enter scores.
+// @Tags Gradebook
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param assessmentId path string true "Assessment ID"
+// @Param body body SaveScoresDTO true "Scores"
+// @Success 200 {object} gin_utils.DataResponse{data=gradebook_use_case.ScoreSheet}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Failure 409 {object} gin_utils.DataResponse{data=[]string}
+// @Router /api/v1/units/{id}/assessments/{assessmentId}/scores [put]
+func (c *GradebookController) SaveScores(ctx *gin.Context) {
+	unitId, assessmentId, ok := parseAssessment(ctx)
+	if !ok {
+		return
+	}
+
+	var dto SaveScoresDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	scores := make([]gradebook_use_case.ScoreRequest, 0, len(dto.Scores))
+	for _, score := range dto.Scores {
+		enrollmentId, err := uuid.Parse(score.ClassEnrollmentId)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid class enrollment ID"})
+			return
+		}
+		scores = append(scores, gradebook_use_case.ScoreRequest{
+			ClassEnrollmentId: enrollmentId,
+			Score:             *score.Score,
+			Note:              score.Note,
+			Version:           score.Version,
+		})
+	}
+
+	sheet, err := c.useCase.SaveScores(&gradebook_use_case.SaveScoresRequest{
+		UnitId:       unitId,
+		AssessmentId: assessmentId,
+		Scores:       scores,
+		Grader:       grader(ctx),
+	})
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Scores saved successfully", Data: sheet})
+}
+
+// GetGradebook godoc
+// @Summary Get a class's gradebook in a subject
+// @Description Every assessment of the semester with each student's scores and weighted average, rounded with the unit's grade rounding policy. Averages get a predicate and are marked when below the passing score that applies to the subject and the class's level.
+// @Tags Gradebook
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param classId path string true "Class ID"
+// @Param subjectId path string true "Subject ID"
+// @Param academic_year query string false "Academic Year"
+// @Param semester query int false "Semester"
+// @Success 200 {object} gin_utils.DataResponse{data=gradebook_use_case.Gradebook}
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/classes/{classId}/subjects/{subjectId}/gradebook [get]
+func (c *GradebookController) GetGradebook(ctx *gin.Context) {
+	unitId, classId, subjectId, ok := parseClassSubject(ctx)
+	if !ok {
+		return
+	}
+	semester, ok := parseSemester(ctx)
+	if !ok {
+		return
+	}
+
+	book, err := c.useCase.GetGradebook(unitId, classId, subjectId, ctx.Query("academic_year"), semester)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Gradebook retrieved successfully", Data: book})
+}
+
+// GetPassRates godoc
+// @Summary Get a class's pass rates per subject
+// @Description For every subject assessed in the class during the semester, how many students' averages reached the passing score (KKM/KKTP) and which did not.
+// @Tags Gradebook
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param classId path string true "Class ID"
+// @Param academic_year query string false "Academic Year"
+// @Param semester query int false "Semester"
+// @Success 200 {object} gin_utils.DataResponse{data=gradebook_use_case.PassRates}
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/classes/{classId}/pass-rates [get]
+func (c *GradebookController) GetPassRates(ctx *gin.Context) {
+	unitId, classId, ok := parseIds(ctx, "classId", "class")
+	if !ok {
+		return
+	}
+	semester, ok := parseSemester(ctx)
+	if !ok {
+		return
+	}
+
+	rates, err := c.useCase.GetPassRates(unitId, classId, ctx.Query("academic_year"), semester)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Pass rates retrieved successfully", Data: rates})
+}
+
+// ListThresholds godoc
+// @Summary List passing thresholds
+// @Description The unit's passing scores (KKM/KKTP) and predicate scales. A rule may be set for a subject, a level, both or neither; the most specific one applies.
+// @Tags Gradebook
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Success 200 {object} gin_utils.DataResponse{data=[]schemas.GradeThreshold}
+// @Router /api/v1/units/{id}/grade-thresholds [get]
+func (c *GradebookController) ListThresholds(ctx *gin.Context) {
+	unitId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid unit ID"})
+		return
+	}
+
+	thresholds, err := c.useCase.ListThresholds(unitId)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Passing thresholds retrieved successfully", Data: thresholds})
+}
+
+// CreateThreshold godoc
+// @Summary Create a passing threshold
+// @Description Sets a passing score and the lowest averages earning an A, B and C, for every subject and level or narrowed to a subject and/or level.
+// @Tags Gradebook
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param body body ThresholdDTO true "Passing threshold"
+// @Success 201 {object} gin_utils.DataResponse{data=schemas.GradeThreshold}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 409 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/grade-thresholds [post]
+func (c *GradebookController) CreateThreshold(ctx *gin.Context) {
+	unitId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid unit ID"})
+		return
+	}
+	req, ok := bindThreshold(ctx, unitId)
+	if !ok {
+		return
+	}
+
+	threshold, err := c.useCase.CreateThreshold(req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin_utils.DataResponse{Message: "Passing threshold created successfully", Data: threshold})
+}
+
+// UpdateThreshold godoc
+// @Summary Update a passing threshold
+// @Tags Gradebook
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param thresholdId path string true "Threshold ID"
+// @Param body body ThresholdDTO true "Passing threshold"
+// @Success 200 {object} gin_utils.DataResponse{data=schemas.GradeThreshold}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Failure 409 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/grade-thresholds/{thresholdId} [put]
+func (c *GradebookController) UpdateThreshold(ctx *gin.Context) {
+	unitId, thresholdId, ok := parseIds(ctx, "thresholdId", "threshold")
+	if !ok {
+		return
+	}
+	req, ok := bindThreshold(ctx, unitId)
+	if !ok {
+		return
+	}
+
+	threshold, err := c.useCase.UpdateThreshold(unitId, thresholdId, req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Passing threshold updated successfully", Data: threshold})
+}
+
+// DeleteThreshold godoc
+// @Summary Delete a passing threshold
+// @Tags Gradebook
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param thresholdId path string true "Threshold ID"
+// @Success 200 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/grade-thresholds/{thresholdId} [delete]
+func (c *GradebookController) DeleteThreshold(ctx *gin.Context) {
+	unitId, thresholdId, ok := parseIds(ctx, "thresholdId", "threshold")
+	if !ok {
+		return
+	}
+
+	if err := c.useCase.DeleteThreshold(unitId, thresholdId); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.MessageResponse{Message: "Passing threshold deleted successfully"})
+}
+
+func bindThreshold(ctx *gin.Context, unitId uuid.UUID) (*gradebook_use_case.ThresholdRequest, bool) {
+	var dto ThresholdDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return nil, false
+	}
+
+	req := &gradebook_use_case.ThresholdRequest{
+		UnitId:     unitId,
+		Level:      dto.Level,
+		MinScore:   *dto.MinScore,
+		PredicateA: *dto.PredicateA,
+		PredicateB: *dto.PredicateB,
+		PredicateC: *dto.PredicateC,
+	}
+	if dto.SubjectId != nil && *dto.SubjectId != "" {
+		subjectId, err := uuid.Parse(*dto.SubjectId)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid subject ID"})
+			return nil, false
+		}
+		req.SubjectId = &subjectId
+	}
+	return req, true
+}
+
+func grader(ctx *gin.Context) gradebook_use_case.Grader {
+	access, _ := auth_utils.GetUnitAccess(ctx.Request.Context())
+	return gradebook_use_case.Grader{
+		UserId: auth_utils.GetAuthClaim(ctx.Request.Context()).UserID,
+		Role:   access.Role,
+	}
+}
+
+func parseClassSubject(ctx *gin.Context) (uuid.UUID, uuid.UUID, uuid.UUID, bool) {
+	ids := make([]uuid.UUID, 0, 3)
+	for _, param := range []struct{ name, label string }{{"id", "unit"}, {"classId", "class"}, {"subjectId", "subject"}} {
+		id, err := uuid.Parse(ctx.Param(param.name))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid " + param.label + " ID"})
+			return uuid.Nil, uuid.Nil, uuid.Nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids[0], ids[1], ids[2], true
+}
+
+func parseAssessment(ctx *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	return parseIds(ctx, "assessmentId", "assessment")
+}
+
+// parseIds reads the unit ID and the ID of the resource nested under it.
+func parseIds(ctx *gin.Context, param, label string) (uuid.UUID, uuid.UUID, bool) {
+	unitId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid unit ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	id, err := uuid.Parse(ctx.Param(param))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid " + label + " ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return unitId, id, true
+}
+
+func parseSemester(ctx *gin.Context) (int, bool) {
+	value := ctx.Query("semester")
+	if value == "" {
+		return 0, true
+	}
+	semester, err := strconv.Atoi(value)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid semester"})
+		return 0, false
+	}
+	return semester, true
+}
+
+func parseDate(ctx *gin.Context, value *string) (*time.Time, bool) {
+	if value == nil || *value == "" {
+		return nil, true
+	}
+	date, err := time.Parse("2006-01-02", *value)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid date, use YYYY-MM-DD"})
+		return nil, false
+	}
+	return &date, true
+}
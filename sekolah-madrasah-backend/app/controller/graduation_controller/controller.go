@@ -0,0 +1,269 @@
+package graduation_controller
+
+import (
+	"errors"
+	"net/http"
+	"sekolah-madrasah/app/repository/graduation_repository"
+	"sekolah-madrasah/app/use_case/graduation_use_case"
+	"sekolah-madrasah/pkg/gin_utils"
+	"sekolah-madrasah/pkg/paginate_utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type GraduationController struct {
+	useCase graduation_use_case.GraduationUseCase
+}
+
+func NewGraduationController(useCase graduation_use_case.GraduationUseCase) *GraduationController {
+	return &GraduationController{useCase: useCase}
+}
+
+type GraduateStudentDTO struct {
+	ClassEnrollmentId string   `json:"class_enrollment_id" binding:"required"`
+	CertificateNumber *string  `json:"certificate_number" binding:"omitempty,max=50"` // Nomor ijazah
+	FinalScore        *float64 `json:"final_score" binding:"omitempty,min=0,max=100"` // Nilai ujian akhir
+	Notes             *string  `json:"notes"`
+}
+
+type GraduateDTO struct {
+	Date         *string              `json:"date"`                                                              // Format: YYYY-MM-DD, defaults to today
+	MemberAction string               `json:"member_action" binding:"omitempty,oneof=keep downgrade deactivate"` // What happens to the students' unit membership, defaults to keep
+	Students     []GraduateStudentDTO `json:"students" binding:"omitempty,dive"`                                 // Empty graduates every active student of the class
+}
+
+type UpdateGraduationDTO struct {
+	GraduationDate    *string  `json:"graduation_date"`                               // Format: YYYY-MM-DD, kept when empty
+	CertificateNumber *string  `json:"certificate_number" binding:"omitempty,max=50"` // Kept when omitted, cleared when empty
+	FinalScore        *float64 `json:"final_score" binding:"omitempty,min=0,max=100"` // Kept when omitted
+	Notes             *string  `json:"notes"`                                         // Kept when omitted, cleared when empty
+}
+
+// errorStatus maps use case errors to their HTTP status; anything else is a validation error.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, graduation_use_case.ErrClassNotFound),
+		errors.Is(err, graduation_use_case.ErrGraduationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, graduation_use_case.ErrCertificateExists),
+		errors.Is(err, graduation_use_case.ErrAlreadyGraduated):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// Graduate godoc
+// @Summary Graduate a final-level class
+// @Description Records the graduation date, certificate number and final score of the class's students, closes their enrollments as graduated and marks them as alumni, in one transaction. Their student membership of the unit can be kept, downgraded to alumni or deactivated.
+// @Tags Graduation
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param classId path string true "Class ID"
+// @Param body body GraduateDTO true "Graduation"
+// @Success 200 {object} gin_utils.DataResponse{data=graduation_use_case.GraduationResult}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Failure 409 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/classes/{classId}/graduation [post]
+func (c *GraduationController) Graduate(ctx *gin.Context) {
+	unitId, classId, ok := parseIds(ctx, "classId", "class")
+	if !ok {
+		return
+	}
+
+	var dto GraduateDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	req := &graduation_use_case.GraduateRequest{
+		UnitId:       unitId,
+		ClassId:      classId,
+		MemberAction: graduation_use_case.MemberAction(dto.MemberAction),
+		Students:     make([]graduation_use_case.GraduateStudent, 0, len(dto.Students)),
+	}
+	if req.Date, ok = parseDate(ctx, dto.Date); !ok {
+		return
+	}
+	for _, student := range dto.Students {
+		enrollmentId, err := uuid.Parse(student.ClassEnrollmentId)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid class enrollment ID"})
+			return
+		}
+		req.Students = append(req.Students, graduation_use_case.GraduateStudent{
+			ClassEnrollmentId: enrollmentId,
+			CertificateNumber: student.CertificateNumber,
+			FinalScore:        student.FinalScore,
+			Notes:             student.Notes,
+		})
+	}
+
+	result, err := c.useCase.Graduate(req)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Class graduated successfully", Data: result})
+}
+
+// ListAlumni godoc
+// @Summary Get the alumni directory of a unit
+// @Tags Graduation
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param academic_year query string false "Year of graduation, e.g. 2025/2026"
+// @Param search query string false "Name, NIS, NISN or certificate number"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} gin_utils.DataWithPaginateResponse{data=[]graduation_use_case.Alumnus}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/alumni [get]
+func (c *GraduationController) ListAlumni(ctx *gin.Context) {
+	unitId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid unit ID"})
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	alumni, total, err := c.useCase.ListAlumni(unitId, graduation_repository.AlumniFilter{
+		AcademicYear: ctx.Query("academic_year"),
+		Search:       ctx.Query("search"),
+		Page:         page,
+		Limit:        limit,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	totalPages := int(total) / limit
+	if int(total)%limit > 0 {
+		totalPages++
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataWithPaginateResponse{
+		DataResponse: gin_utils.DataResponse{
+			Message: "Alumni retrieved successfully",
+			Data:    alumni,
+		},
+		Paginate: &paginate_utils.PaginateData{
+			Page:       page,
+			Limit:      limit,
+			TotalData:  total,
+			TotalPages: totalPages,
+		},
+	})
+}
+
+// ListAlumniYears godoc
+// @Summary Get the graduation years of a unit
+// @Description Lists every academic year the unit has graduates of, newest first, with the number of graduates.
+// @Tags Graduation
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Success 200 {object} gin_utils.DataResponse{data=[]graduation_repository.AlumniYear}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/alumni/years [get]
+func (c *GraduationController) ListAlumniYears(ctx *gin.Context) {
+	unitId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid unit ID"})
+		return
+	}
+
+	years, err := c.useCase.ListAlumniYears(unitId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Graduation years retrieved successfully", Data: years})
+}
+
+// UpdateGraduation godoc
+// @Summary Update a graduation
+// @Description Corrects the graduation date, or records the certificate number and final score once they are issued. Fields that are omitted are kept; an empty certificate number or notes clears it.
+// @Tags Graduation
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param graduationId path string true "Graduation ID"
+// @Param body body UpdateGraduationDTO true "Graduation"
+// @Success 200 {object} gin_utils.DataResponse{data=graduation_use_case.Alumnus}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Failure 409 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/alumni/{graduationId} [put]
+func (c *GraduationController) UpdateGraduation(ctx *gin.Context) {
+	unitId, graduationId, ok := parseIds(ctx, "graduationId", "graduation")
+	if !ok {
+		return
+	}
+
+	var dto UpdateGraduationDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	req := &graduation_use_case.UpdateGraduationRequest{
+		UnitId:            unitId,
+		GraduationId:      graduationId,
+		CertificateNumber: dto.CertificateNumber,
+		FinalScore:        dto.FinalScore,
+		Notes:             dto.Notes,
+	}
+	if req.GraduationDate, ok = parseDate(ctx, dto.GraduationDate); !ok {
+		return
+	}
+
+	alumnus, err := c.useCase.UpdateGraduation(req)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Graduation updated successfully", Data: alumnus})
+}
+
+func parseIds(ctx *gin.Context, param, label string) (uuid.UUID, uuid.UUID, bool) {
+	unitId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid unit ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	id, err := uuid.Parse(ctx.Param(param))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid " + label + " ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return unitId, id, true
+}
+
+// parseDate reads an optional YYYY-MM-DD date; nil or empty is no date.
+func parseDate(ctx *gin.Context, value *string) (*time.Time, bool) {
+	if value == nil || *value == "" {
+		return nil, true
+	}
+	date, err := time.Parse("2006-01-02", *value)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid date, use YYYY-MM-DD"})
+		return nil, false
+	}
+	return &date, true
+}
@@ -260,6 +260,12 @@ func (c *PostController) CreateComment(ctx *gin.Context) {
 // @Summary Delete a comment
 // @Tags posts
 func (c *PostController) DeleteComment(ctx *gin.Context) {
+	postId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "invalid post id"})
+		return
+	}
+
 	commentId, err := uuid.Parse(ctx.Param("commentId"))
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "invalid comment id"})
@@ -273,7 +279,7 @@ func (c *PostController) DeleteComment(ctx *gin.Context) {
 	}
 	userId := userIdVal.(uuid.UUID)
 
-	code, err := c.useCase.DeleteComment(ctx.Request.Context(), commentId, userId)
+	code, err := c.useCase.DeleteComment(ctx.Request.Context(), postId, commentId, userId)
 	if err != nil {
 		ctx.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
 		return
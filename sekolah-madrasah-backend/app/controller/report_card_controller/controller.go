@@ -0,0 +1,384 @@
+package report_card_controller
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"sekolah-madrasah/app/use_case/report_card_use_case"
+	"sekolah-madrasah/pkg/auth_utils"
+	"sekolah-madrasah/pkg/gin_utils"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ReportCardController struct {
+	useCase report_card_use_case.ReportCardUseCase
+}
+
+func NewReportCardController(useCase report_card_use_case.ReportCardUseCase) *ReportCardController {
+	return &ReportCardController{useCase: useCase}
+}
+
+type TemplateDTO struct {
+	Title           *string `json:"title" binding:"omitempty,max=100"`
+	SchoolName      *string `json:"school_name" binding:"omitempty,max=255"` // Defaults to the unit's name
+	HeaderLines     *string `json:"header_lines"`                            // Address and contacts, one per line
+	PaperSize       *string `json:"paper_size"`                              // A4 or F4
+	Place           *string `json:"place" binding:"omitempty,max=100"`
+	PrincipalName   *string `json:"principal_name" binding:"omitempty,max=100"`
+	PrincipalNip    *string `json:"principal_nip" binding:"omitempty,max=30"`
+	DescriptionHigh *string `json:"description_high"` // Must mention {kompetensi}; {nama} is the student's name
+	DescriptionLow  *string `json:"description_low"`  // Must mention {kompetensi}
+	Footer          *string `json:"footer"`
+}
+
+type ActivityNoteDTO struct {
+	ActivityId string  `json:"activity_id" binding:"required"`
+	Note       *string `json:"note"`
+}
+
+type DraftDTO struct {
+	HomeroomNote  *string           `json:"homeroom_note"`
+	ActivityNotes []ActivityNoteDTO `json:"activity_notes" binding:"dive"`
+}
+
+// errorStatus maps use case errors to their HTTP status; anything else is a validation error.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, report_card_use_case.ErrEnrollmentNotFound),
+		errors.Is(err, report_card_use_case.ErrClassNotFound),
+		errors.Is(err, report_card_use_case.ErrNotPublishedYet):
+		return http.StatusNotFound
+	case errors.Is(err, report_card_use_case.ErrNotHomeroom),
+		errors.Is(err, report_card_use_case.ErrNotPublisher):
+		return http.StatusForbidden
+	case errors.Is(err, report_card_use_case.ErrNotDraft),
+		errors.Is(err, report_card_use_case.ErrNotFinalized),
+		errors.Is(err, report_card_use_case.ErrPublished),
+		errors.Is(err, report_card_use_case.ErrChangedMeanwhile):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// GetTemplate godoc
+// @Summary Get the unit's report card template
+// @Description Returns the defaults until the unit saves its own.
+// @Tags Report Cards
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Success 200 {object} gin_utils.DataResponse{data=schemas.ReportCardTemplate}
+// @Router /api/v1/units/{id}/report-card-template [get]
+func (c *ReportCardController) GetTemplate(ctx *gin.Context) {
+	unitId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid unit ID"})
+		return
+	}
+
+	template, err := c.useCase.GetTemplate(unitId)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Report card template retrieved successfully", Data: template})
+}
+
+// UpdateTemplate godoc
+// @Summary Update the unit's report card template
+// @Description Sets the letterhead, paper size, signatories and the wording of competency descriptions.
+// @Tags Report Cards
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param body body TemplateDTO true "Template"
+// @Success 200 {object} gin_utils.DataResponse{data=schemas.ReportCardTemplate}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/report-card-template [put]
+func (c *ReportCardController) UpdateTemplate(ctx *gin.Context) {
+	unitId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid unit ID"})
+		return
+	}
+
+	var dto TemplateDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	template, err := c.useCase.UpdateTemplate(&report_card_use_case.TemplateRequest{
+		UnitId:          unitId,
+		Title:           dto.Title,
+		SchoolName:      dto.SchoolName,
+		HeaderLines:     dto.HeaderLines,
+		PaperSize:       dto.PaperSize,
+		Place:           dto.Place,
+		PrincipalName:   dto.PrincipalName,
+		PrincipalNip:    dto.PrincipalNip,
+		DescriptionHigh: dto.DescriptionHigh,
+		DescriptionLow:  dto.DescriptionLow,
+		Footer:          dto.Footer,
+	})
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Report card template updated successfully", Data: template})
+}
+
+// ListClass godoc
+// @Summary List a class's report cards
+// @Description The report card status of every student of the class; semester defaults to the unit's current one.
+// @Tags Report Cards
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param classId path string true "Class ID"
+// @Param semester query int false "Semester"
+// @Success 200 {object} gin_utils.DataResponse{data=report_card_use_case.ClassReportCards}
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/classes/{classId}/report-cards [get]
+func (c *ReportCardController) ListClass(ctx *gin.Context) {
+	unitId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid unit ID"})
+		return
+	}
+	classId, err := uuid.Parse(ctx.Param("classId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid class ID"})
+		return
+	}
+	semester, ok := parseSemester(ctx)
+	if !ok {
+		return
+	}
+
+	list, err := c.useCase.ListClass(unitId, classId, semester)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Report cards retrieved successfully", Data: list})
+}
+
+// Get godoc
+// @Summary Get a student's report card
+// @Description While a draft the results are computed from the gradebook, attendance and activities; once finalized they are as frozen then. Students and parents only get their own or their child's, once published.
+// @Tags Report Cards
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param enrollmentId path string true "Class Enrollment ID"
+// @Param semester query int false "Semester"
+// @Success 200 {object} gin_utils.DataResponse{data=report_card_use_case.ReportCardView}
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/enrollments/{enrollmentId}/report-card [get]
+func (c *ReportCardController) Get(ctx *gin.Context) {
+	unitId, enrollmentId, semester, ok := parseReportCard(ctx)
+	if !ok {
+		return
+	}
+
+	card, err := c.useCase.Get(unitId, enrollmentId, semester, editor(ctx))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Report card retrieved successfully", Data: card})
+}
+
+// UpdateDraft godoc
+// @Summary Write the homeroom notes on a draft report card
+// @Description Sets the homeroom teacher's note and the remarks on the student's extracurriculars.
+// @Tags Report Cards
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param enrollmentId path string true "Class Enrollment ID"
+// @Param semester query int false "Semester"
+// @Param body body DraftDTO true "Notes"
+// @Success 200 {object} gin_utils.DataResponse{data=report_card_use_case.ReportCardView}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 409 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/enrollments/{enrollmentId}/report-card [put]
+func (c *ReportCardController) UpdateDraft(ctx *gin.Context) {
+	unitId, enrollmentId, semester, ok := parseReportCard(ctx)
+	if !ok {
+		return
+	}
+
+	var dto DraftDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+	notes := make(map[uuid.UUID]*string, len(dto.ActivityNotes))
+	for _, note := range dto.ActivityNotes {
+		activityId, err := uuid.Parse(note.ActivityId)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid activity ID"})
+			return
+		}
+		notes[activityId] = note.Note
+	}
+
+	card, err := c.useCase.UpdateDraft(&report_card_use_case.DraftRequest{
+		UnitId:        unitId,
+		EnrollmentId:  enrollmentId,
+		Semester:      semester,
+		HomeroomNote:  dto.HomeroomNote,
+		ActivityNotes: notes,
+		Editor:        editor(ctx),
+	})
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Report card updated successfully", Data: card})
+}
+
+// Finalize godoc
+// @Summary Finalize a report card
+// @Description Freezes the results and locks the grades of the class for the semester until reopened.
+// @Tags Report Cards
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param enrollmentId path string true "Class Enrollment ID"
+// @Param semester query int false "Semester"
+// @Success 200 {object} gin_utils.DataResponse{data=report_card_use_case.ReportCardView}
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 409 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/enrollments/{enrollmentId}/report-card/finalize [post]
+func (c *ReportCardController) Finalize(ctx *gin.Context) {
+	c.transition(ctx, c.useCase.Finalize, "Report card finalized successfully")
+}
+
+// Reopen godoc
+// @Summary Reopen a finalized report card
+// @Description Turns it back into a draft; published report cards cannot be reopened.
+// @Tags Report Cards
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param enrollmentId path string true "Class Enrollment ID"
+// @Param semester query int false "Semester"
+// @Success 200 {object} gin_utils.DataResponse{data=report_card_use_case.ReportCardView}
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 409 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/enrollments/{enrollmentId}/report-card/reopen [post]
+func (c *ReportCardController) Reopen(ctx *gin.Context) {
+	c.transition(ctx, c.useCase.Reopen, "Report card reopened successfully")
+}
+
+// Publish godoc
+// @Summary Publish a finalized report card
+// @Description Only unit owners and admins may publish; a published report card cannot change.
+// @Tags Report Cards
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param enrollmentId path string true "Class Enrollment ID"
+// @Param semester query int false "Semester"
+// @Success 200 {object} gin_utils.DataResponse{data=report_card_use_case.ReportCardView}
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 409 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/enrollments/{enrollmentId}/report-card/publish [post]
+func (c *ReportCardController) Publish(ctx *gin.Context) {
+	c.transition(ctx, c.useCase.Publish, "Report card published successfully")
+}
+
+// DownloadPDF godoc
+// @Summary Download a report card as PDF
+// @Description Rendered with the unit's template; drafts are marked as such.
+// @Tags Report Cards
+// @Security BearerAuth
+// @Produce application/pdf
+// @Param id path string true "Unit ID"
+// @Param enrollmentId path string true "Class Enrollment ID"
+// @Param semester query int false "Semester"
+// @Success 200 {file} file
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/enrollments/{enrollmentId}/report-card/pdf [get]
+func (c *ReportCardController) DownloadPDF(ctx *gin.Context) {
+	unitId, enrollmentId, semester, ok := parseReportCard(ctx)
+	if !ok {
+		return
+	}
+
+	printable, err := c.useCase.GetPrintable(unitId, enrollmentId, semester, editor(ctx))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	var out bytes.Buffer
+	if err := report_card_use_case.WriteReportCardPDF(&out, printable); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	card := printable.Card
+	name := strings.ReplaceAll(strings.ToLower(card.StudentName), " ", "-")
+	filename := fmt.Sprintf("rapor-%s-%s-semester-%d.pdf", name, strings.ReplaceAll(card.AcademicYear, "/", "-"), card.Semester)
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	ctx.Data(http.StatusOK, "application/pdf", out.Bytes())
+}
+
+func (c *ReportCardController) transition(ctx *gin.Context, move func(unitId, enrollmentId uuid.UUID, semester int, editor report_card_use_case.Editor) (*report_card_use_case.ReportCardView, error), message string) {
+	unitId, enrollmentId, semester, ok := parseReportCard(ctx)
+	if !ok {
+		return
+	}
+
+	card, err := move(unitId, enrollmentId, semester, editor(ctx))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: message, Data: card})
+}
+
+func editor(ctx *gin.Context) report_card_use_case.Editor {
+	access, _ := auth_utils.GetUnitAccess(ctx.Request.Context())
+	return report_card_use_case.Editor{
+		UserId: auth_utils.GetAuthClaim(ctx.Request.Context()).UserID,
+		Role:   access.Role,
+	}
+}
+
+func parseReportCard(ctx *gin.Context) (uuid.UUID, uuid.UUID, int, bool) {
+	unitId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid unit ID"})
+		return uuid.Nil, uuid.Nil, 0, false
+	}
+	enrollmentId, err := uuid.Parse(ctx.Param("enrollmentId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid class enrollment ID"})
+		return uuid.Nil, uuid.Nil, 0, false
+	}
+	semester, ok := parseSemester(ctx)
+	return unitId, enrollmentId, semester, ok
+}
+
+func parseSemester(ctx *gin.Context) (int, bool) {
+	value := ctx.Query("semester")
+	if value == "" {
+		return 0, true
+	}
+	semester, err := strconv.Atoi(value)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid semester"})
+		return 0, false
+	}
+	return semester, true
+}
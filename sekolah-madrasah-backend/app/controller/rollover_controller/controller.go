@@ -0,0 +1,162 @@
+package rollover_controller
+
+import (
+	"errors"
+	"net/http"
+	"sekolah-madrasah/app/use_case/rollover_use_case"
+	"sekolah-madrasah/pkg/gin_utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type RolloverController struct {
+	useCase rollover_use_case.RolloverUseCase
+}
+
+func NewRolloverController(useCase rollover_use_case.RolloverUseCase) *RolloverController {
+	return &RolloverController{useCase: useCase}
+}
+
+type ClassNameDTO struct {
+	SourceClassId string `json:"source_class_id" binding:"required"` // Class of from_year
+	Name          string `json:"name" binding:"required,max=50"`
+}
+
+type OverrideDTO struct {
+	ClassEnrollmentId string  `json:"class_enrollment_id" binding:"required"`
+	Action            string  `json:"action" binding:"required,oneof=promote retain graduate skip"`
+	ToClassId         *string `json:"to_class_id"` // A from_year class to join its clone, or a class already created for to_year
+}
+
+type RolloverDTO struct {
+	FromYear   string         `json:"from_year"`   // Defaults to the unit's current academic year
+	ToYear     string         `json:"to_year"`     // Defaults to the year after from_year
+	FinalLevel int            `json:"final_level"` // Defaults to the highest level of from_year
+	Date       *string        `json:"date"`        // Format: YYYY-MM-DD, defaults to today
+	ClassNames []ClassNameDTO `json:"class_names" binding:"omitempty,dive"`
+	Overrides  []OverrideDTO  `json:"overrides" binding:"omitempty,dive"`
+}
+
+// errorStatus maps use case errors to their HTTP status; anything else is a validation error.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, rollover_use_case.ErrNoClasses):
+		return http.StatusNotFound
+	case errors.Is(err, rollover_use_case.ErrAlreadyRolledOver):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// Preview godoc
+// @Summary Preview the academic year rollover
+// @Description Proposes the next year's classes, each old class below the final level cloned one level up, and where each student goes, with the overrides applied. Nothing is saved.
+// @Tags Rollover
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param body body RolloverDTO true "Rollover"
+// @Success 200 {object} gin_utils.DataResponse{data=rollover_use_case.RolloverPlan}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/rollover/preview [post]
+func (c *RolloverController) Preview(ctx *gin.Context) {
+	req, ok := bindRollover(ctx)
+	if !ok {
+		return
+	}
+
+	plan, err := c.useCase.Preview(req)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Rollover previewed successfully", Data: plan})
+}
+
+// Apply godoc
+// @Summary Roll the unit over to the next academic year
+// @Description Creates the proposed classes, closes the old enrollments as promoted or retained and enrolls the students in their new classes, all in one transaction. Students of the final level are left for graduation.
+// @Tags Rollover
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param body body RolloverDTO true "Rollover"
+// @Success 200 {object} gin_utils.DataResponse{data=rollover_use_case.RolloverPlan}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Failure 409 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/rollover [post]
+func (c *RolloverController) Apply(ctx *gin.Context) {
+	req, ok := bindRollover(ctx)
+	if !ok {
+		return
+	}
+
+	plan, err := c.useCase.Apply(req)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Academic year rolled over successfully", Data: plan})
+}
+
+func bindRollover(ctx *gin.Context) (*rollover_use_case.RolloverRequest, bool) {
+	unitId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid unit ID"})
+		return nil, false
+	}
+
+	var dto RolloverDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return nil, false
+	}
+
+	req := &rollover_use_case.RolloverRequest{
+		UnitId:     unitId,
+		FromYear:   dto.FromYear,
+		ToYear:     dto.ToYear,
+		FinalLevel: dto.FinalLevel,
+		ClassNames: make(map[uuid.UUID]string, len(dto.ClassNames)),
+		Overrides:  make([]rollover_use_case.Override, 0, len(dto.Overrides)),
+	}
+	if dto.Date != nil && *dto.Date != "" {
+		date, err := time.Parse("2006-01-02", *dto.Date)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid date, use YYYY-MM-DD"})
+			return nil, false
+		}
+		req.Date = &date
+	}
+	for _, name := range dto.ClassNames {
+		classId, err := uuid.Parse(name.SourceClassId)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid source class ID"})
+			return nil, false
+		}
+		req.ClassNames[classId] = name.Name
+	}
+	for _, override := range dto.Overrides {
+		enrollmentId, err := uuid.Parse(override.ClassEnrollmentId)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid class enrollment ID"})
+			return nil, false
+		}
+		item := rollover_use_case.Override{ClassEnrollmentId: enrollmentId, Action: rollover_use_case.PromotionAction(override.Action)}
+		if override.ToClassId != nil && *override.ToClassId != "" {
+			toClassId, err := uuid.Parse(*override.ToClassId)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid target class ID"})
+				return nil, false
+			}
+			item.ToClassId = &toClassId
+		}
+		req.Overrides = append(req.Overrides, item)
+	}
+	return req, true
+}
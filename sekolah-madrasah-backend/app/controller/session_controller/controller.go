@@ -0,0 +1,148 @@
+package session_controller
+
+import (
+	"net/http"
+
+	"sekolah-madrasah/app/use_case/auth_use_case"
+	"sekolah-madrasah/pkg/auth_utils"
+	"sekolah-madrasah/pkg/gin_utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type sessionController struct {
+	authUseCase auth_use_case.AuthUseCase
+}
+
+func NewSessionController(authUseCase auth_use_case.AuthUseCase) SessionController {
+	return &sessionController{authUseCase: authUseCase}
+}
+
+func toSessions(sessions []auth_use_case.Session) []Session {
+	result := make([]Session, len(sessions))
+	for i, s := range sessions {
+		result[i] = Session{
+			Id:         s.Id,
+			Device:     s.Device,
+			UserAgent:  s.UserAgent,
+			IpAddress:  s.IpAddress,
+			LastSeenAt: s.LastSeenAt,
+			ExpiresAt:  s.ExpiresAt,
+			CreatedAt:  s.CreatedAt,
+			Current:    s.Current,
+		}
+	}
+	return result
+}
+
+func (ctrl *sessionController) listSessions(c *gin.Context, userId uuid.UUID) {
+	claims := auth_utils.GetAuthClaim(c.Request.Context())
+	sessions, code, err := ctrl.authUseCase.GetSessions(c.Request.Context(), userId, claims.SessionID)
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin_utils.DataResponse{
+		Message: "sessions retrieved",
+		Data:    toSessions(sessions),
+	})
+}
+
+func (ctrl *sessionController) revokeSession(c *gin.Context, userId uuid.UUID, param string) {
+	sessionId, err := uuid.Parse(c.Param(param))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "invalid session id"})
+		return
+	}
+
+	code, err := ctrl.authUseCase.RevokeSession(c.Request.Context(), userId, sessionId)
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin_utils.MessageResponse{Message: "session revoked"})
+}
+
+// GetMySessions godoc
+// @Summary List my active sessions
+// @Description Returns every device currently signed in to the caller's account
+// @Tags Sessions
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} gin_utils.DataResponse{data=[]Session}
+// @Failure 401 {object} gin_utils.MessageResponse
+// @Router /api/v1/users/me/sessions [get]
+func (ctrl *sessionController) GetMySessions(c *gin.Context) {
+	claims := auth_utils.GetAuthClaim(c.Request.Context())
+	if claims.UserID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, gin_utils.MessageResponse{Message: "unauthorized"})
+		return
+	}
+
+	ctrl.listSessions(c, claims.UserID)
+}
+
+// RevokeMySession godoc
+// @Summary Sign out one of my sessions
+// @Description Revokes the session and its tokens, signing that device out
+// @Tags Sessions
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/users/me/sessions/{id} [delete]
+func (ctrl *sessionController) RevokeMySession(c *gin.Context) {
+	claims := auth_utils.GetAuthClaim(c.Request.Context())
+	if claims.UserID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, gin_utils.MessageResponse{Message: "unauthorized"})
+		return
+	}
+
+	ctrl.revokeSession(c, claims.UserID, "id")
+}
+
+// GetUserSessions godoc
+// @Summary List a user's active sessions
+// @Description Super admin only
+// @Tags Sessions
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} gin_utils.DataResponse{data=[]Session}
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Router /api/v1/users/{id}/sessions [get]
+func (ctrl *sessionController) GetUserSessions(c *gin.Context) {
+	userId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "invalid user id"})
+		return
+	}
+
+	ctrl.listSessions(c, userId)
+}
+
+// RevokeUserSession godoc
+// @Summary Sign out a user's session
+// @Description Super admin only
+// @Tags Sessions
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Param sessionId path string true "Session ID"
+// @Success 200 {object} gin_utils.MessageResponse
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/users/{id}/sessions/{sessionId} [delete]
+func (ctrl *sessionController) RevokeUserSession(c *gin.Context) {
+	userId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "invalid user id"})
+		return
+	}
+
+	ctrl.revokeSession(c, userId, "sessionId")
+}
@@ -0,0 +1,10 @@
+package session_controller
+
+import "github.com/gin-gonic/gin"
+
+type SessionController interface {
+	GetMySessions(c *gin.Context)
+	RevokeMySession(c *gin.Context)
+	GetUserSessions(c *gin.Context)
+	RevokeUserSession(c *gin.Context)
+}
@@ -0,0 +1,166 @@
+package sso_controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"sekolah-madrasah/app/use_case/sso_use_case"
+	"sekolah-madrasah/pkg/auth_utils"
+	"sekolah-madrasah/pkg/gin_utils"
+)
+
+type ssoController struct {
+	ssoUseCase sso_use_case.SsoUseCase
+}
+
+func NewSsoController(ssoUseCase sso_use_case.SsoUseCase) SsoController {
+	return &ssoController{ssoUseCase: ssoUseCase}
+}
+
+func (ctrl *ssoController) toProviderResponse(p sso_use_case.SsoProvider) SsoProvider {
+	mappings := make([]GroupRoleMapping, len(p.GroupRoleMappings))
+	for i, m := range p.GroupRoleMappings {
+		mappings[i] = GroupRoleMapping{Group: m.Group, UnitId: m.UnitId, Role: m.Role}
+	}
+
+	return SsoProvider{
+		Id:                p.Id,
+		OrganizationId:    p.OrganizationId,
+		Issuer:            p.Issuer,
+		ClientId:          p.ClientId,
+		HasClientSecret:   p.HasClientSecret,
+		RedirectUri:       p.RedirectUri,
+		Scopes:            p.Scopes,
+		GroupsClaim:       p.GroupsClaim,
+		GroupRoleMappings: mappings,
+		AllowProvisioning: p.AllowProvisioning,
+		IsEnabled:         p.IsEnabled,
+		CreatedAt:         p.CreatedAt,
+		UpdatedAt:         p.UpdatedAt,
+	}
+}
+
+// GetProvider godoc
+// @Summary Get the organization's single sign-on provider
+// @Description Returns the OpenID Connect provider configuration. The client secret is never returned.
+// @Tags Organization
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID (UUID)"
+// @Success 200 {object} gin_utils.DataResponse{data=SsoProvider}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 401 {object} gin_utils.MessageResponse
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Failure 500 {object} gin_utils.MessageResponse
+// @Router /api/v1/organizations/{id}/sso [get]
+func (ctrl *ssoController) GetProvider(c *gin.Context) {
+	orgId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "invalid organization id"})
+		return
+	}
+
+	provider, code, err := ctrl.ssoUseCase.GetProvider(c.Request.Context(), auth_utils.GetAuthClaim(c.Request.Context()), orgId)
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(code, gin_utils.DataResponse{
+		Message: "success",
+		Data:    ctrl.toProviderResponse(provider),
+	})
+}
+
+// SaveProvider godoc
+// @Summary Configure single sign-on
+// @Description Creates or replaces the organization's OpenID Connect provider. The issuer must serve a discovery document. Group mappings grant a unit role to members of an IdP group; owner cannot be granted this way.
+// @Tags Organization
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID (UUID)"
+// @Param request body SaveProviderRequest true "Provider configuration"
+// @Success 200 {object} gin_utils.DataResponse{data=SsoProvider}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 401 {object} gin_utils.MessageResponse
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Failure 500 {object} gin_utils.MessageResponse
+// @Router /api/v1/organizations/{id}/sso [put]
+func (ctrl *ssoController) SaveProvider(c *gin.Context) {
+	orgId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "invalid organization id"})
+		return
+	}
+
+	var req SaveProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	mappings := make([]sso_use_case.GroupRoleMapping, len(req.GroupRoleMappings))
+	for i, m := range req.GroupRoleMappings {
+		mappings[i] = sso_use_case.GroupRoleMapping{Group: m.Group, UnitId: m.UnitId, Role: m.Role}
+	}
+
+	isEnabled := true
+	if req.IsEnabled != nil {
+		isEnabled = *req.IsEnabled
+	}
+
+	provider, code, err := ctrl.ssoUseCase.SaveProvider(c.Request.Context(), auth_utils.GetAuthClaim(c.Request.Context()), orgId, sso_use_case.SaveProviderRequest{
+		Issuer:            req.Issuer,
+		ClientId:          req.ClientId,
+		ClientSecret:      req.ClientSecret,
+		RedirectUri:       req.RedirectUri,
+		Scopes:            req.Scopes,
+		GroupsClaim:       req.GroupsClaim,
+		GroupRoleMappings: mappings,
+		AllowProvisioning: req.AllowProvisioning,
+		IsEnabled:         isEnabled,
+	})
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(code, gin_utils.DataResponse{
+		Message: "single sign-on provider saved",
+		Data:    ctrl.toProviderResponse(provider),
+	})
+}
+
+// DeleteProvider godoc
+// @Summary Remove single sign-on
+// @Description Removes the organization's provider. Linked identities are kept, so re-adding the same issuer restores them.
+// @Tags Organization
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID (UUID)"
+// @Success 200 {object} gin_utils.MessageResponse
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 401 {object} gin_utils.MessageResponse
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Failure 500 {object} gin_utils.MessageResponse
+// @Router /api/v1/organizations/{id}/sso [delete]
+func (ctrl *ssoController) DeleteProvider(c *gin.Context) {
+	orgId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "invalid organization id"})
+		return
+	}
+
+	code, err := ctrl.ssoUseCase.DeleteProvider(c.Request.Context(), auth_utils.GetAuthClaim(c.Request.Context()), orgId)
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(code, gin_utils.MessageResponse{Message: "single sign-on provider removed"})
+}
@@ -0,0 +1,9 @@
+package sso_controller
+
+import "github.com/gin-gonic/gin"
+
+type SsoController interface {
+	GetProvider(c *gin.Context)
+	SaveProvider(c *gin.Context)
+	DeleteProvider(c *gin.Context)
+}
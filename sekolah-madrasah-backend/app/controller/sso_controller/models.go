@@ -0,0 +1,44 @@
+package sso_controller
+
+import (
+	"time"
+
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+)
+
+type GroupRoleMapping struct {
+	Group  string                 `json:"group" binding:"required"`
+	UnitId uuid.UUID              `json:"unit_id" binding:"required"`
+	Role   schemas.UnitMemberRole `json:"role" binding:"required"`
+}
+
+type SsoProvider struct {
+	Id                uuid.UUID          `json:"id"`
+	OrganizationId    uuid.UUID          `json:"organization_id"`
+	Issuer            string             `json:"issuer"`
+	ClientId          string             `json:"client_id"`
+	HasClientSecret   bool               `json:"has_client_secret"`
+	RedirectUri       string             `json:"redirect_uri"`
+	Scopes            []string           `json:"scopes"`
+	GroupsClaim       string             `json:"groups_claim"`
+	GroupRoleMappings []GroupRoleMapping `json:"group_role_mappings"`
+	AllowProvisioning bool               `json:"allow_provisioning"`
+	IsEnabled         bool               `json:"is_enabled"`
+	CreatedAt         time.Time          `json:"created_at"`
+	UpdatedAt         time.Time          `json:"updated_at"`
+}
+
+type SaveProviderRequest struct {
+	Issuer   string `json:"issuer" binding:"required,max=500"`
+	ClientId string `json:"client_id" binding:"required,max=255"`
+	// ClientSecret is write-only; omit it to keep the stored secret
+	ClientSecret      string             `json:"client_secret" binding:"max=1000"`
+	RedirectUri       string             `json:"redirect_uri" binding:"required,max=500"`
+	Scopes            []string           `json:"scopes"`
+	GroupsClaim       string             `json:"groups_claim" binding:"max=100"`
+	GroupRoleMappings []GroupRoleMapping `json:"group_role_mappings" binding:"dive"`
+	AllowProvisioning bool               `json:"allow_provisioning"`
+	IsEnabled         *bool              `json:"is_enabled"`
+}
@@ -0,0 +1,285 @@
+package staff_attendance_controller
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"sekolah-madrasah/app/use_case/staff_attendance_use_case"
+	"sekolah-madrasah/pkg/auth_utils"
+	"sekolah-madrasah/pkg/gin_utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type StaffAttendanceController struct {
+	useCase staff_attendance_use_case.StaffAttendanceUseCase
+}
+
+func NewStaffAttendanceController(useCase staff_attendance_use_case.StaffAttendanceUseCase) *StaffAttendanceController {
+	return &StaffAttendanceController{useCase: useCase}
+}
+
+type CheckDTO struct {
+	UserId    *string  `json:"user_id"` // Staff member checking in; required with an API key, ignored otherwise
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	DeviceId  *string  `json:"device_id" binding:"omitempty,max=100"`
+	Note      *string  `json:"note"`
+}
+
+// errorStatus maps use case errors to their HTTP status; anything else is a validation error.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, staff_attendance_use_case.ErrNotStaff):
+		return http.StatusForbidden
+	case errors.Is(err, staff_attendance_use_case.ErrAlreadyCheckedIn), errors.Is(err, staff_attendance_use_case.ErrAlreadyCheckedOut):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// CheckIn godoc
+// @Summary Check in
+// @Description Records the caller's arrival today. The check-in counts as late once it is past the unit's start time plus its late tolerance. An API key, such as an attendance kiosk, checks in the staff member named by user_id.
+// @Tags Staff Attendance
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param body body CheckDTO false "Location and device"
+// @Success 201 {object} gin_utils.DataResponse{data=schemas.StaffAttendance}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 409 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/staff-attendance/check-in [post]
+func (c *StaffAttendanceController) CheckIn(ctx *gin.Context) {
+	req, ok := bindCheck(ctx)
+	if !ok {
+		return
+	}
+
+	record, err := c.useCase.CheckIn(req)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin_utils.DataResponse{Message: "Checked in successfully", Data: record})
+}
+
+// CheckOut godoc
+// @Summary Check out
+// @Description Records the caller's departure today. The check-out counts as leaving early before the unit's end time. An API key checks out the staff member named by user_id.
+// @Tags Staff Attendance
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param body body CheckDTO false "Location and device"
+// @Success 200 {object} gin_utils.DataResponse{data=schemas.StaffAttendance}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 409 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/staff-attendance/check-out [post]
+func (c *StaffAttendanceController) CheckOut(ctx *gin.Context) {
+	req, ok := bindCheck(ctx)
+	if !ok {
+		return
+	}
+
+	record, err := c.useCase.CheckOut(req)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Checked out successfully", Data: record})
+}
+
+// GetOwn godoc
+// @Summary Get my attendance
+// @Description Lists the caller's check-ins in the month.
+// @Tags Staff Attendance
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param month query string false "Month (YYYY-MM), defaults to the current month"
+// @Success 200 {object} gin_utils.DataResponse{data=[]schemas.StaffAttendance}
+// @Router /api/v1/units/{id}/staff-attendance/me [get]
+func (c *StaffAttendanceController) GetOwn(ctx *gin.Context) {
+	unitId, ok := parseUnit(ctx)
+	if !ok {
+		return
+	}
+	month, ok := parseMonth(ctx)
+	if !ok {
+		return
+	}
+
+	claims := auth_utils.GetAuthClaim(ctx.Request.Context())
+	if claims.IsApiKey() {
+		// The key would stand for its creator here
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "API keys have no attendance of their own, list the day instead"})
+		return
+	}
+	records, err := c.useCase.GetOwn(unitId, claims.UserID, month)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Attendance retrieved successfully", Data: records})
+}
+
+// GetDay godoc
+// @Summary List staff attendance for a day
+// @Description Lists who checked in on the date, with check-in and check-out times.
+// @Tags Staff Attendance
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param date query string false "Date (YYYY-MM-DD), defaults to today"
+// @Success 200 {object} gin_utils.DataResponse{data=[]schemas.StaffAttendance}
+// @Router /api/v1/units/{id}/staff-attendance [get]
+func (c *StaffAttendanceController) GetDay(ctx *gin.Context) {
+	unitId, ok := parseUnit(ctx)
+	if !ok {
+		return
+	}
+
+	date := time.Now()
+	if value := ctx.Query("date"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid date, use YYYY-MM-DD"})
+			return
+		}
+		date = parsed
+	}
+
+	records, err := c.useCase.GetDay(unitId, date)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Attendance retrieved successfully", Data: records})
+}
+
+// GetMonthlyRecap godoc
+// @Summary Get the monthly staff attendance recap
+// @Description Days present, late and leaving early for every teacher and staff member in the month.
+// @Tags Staff Attendance
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param month query string false "Month (YYYY-MM), defaults to the current month"
+// @Success 200 {object} gin_utils.DataResponse{data=staff_attendance_use_case.MonthlyRecap}
+// @Router /api/v1/units/{id}/staff-attendance/recap [get]
+func (c *StaffAttendanceController) GetMonthlyRecap(ctx *gin.Context) {
+	recap, ok := c.recap(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Attendance recap retrieved successfully", Data: recap})
+}
+
+// ExportMonthlyRecap godoc
+// @Summary Export the monthly staff attendance recap
+// @Description Downloads the monthly recap as CSV for payroll.
+// @Tags Staff Attendance
+// @Security BearerAuth
+// @Produce text/csv
+// @Param id path string true "Unit ID"
+// @Param month query string false "Month (YYYY-MM), defaults to the current month"
+// @Success 200 {file} file
+// @Router /api/v1/units/{id}/staff-attendance/recap/export [get]
+func (c *StaffAttendanceController) ExportMonthlyRecap(ctx *gin.Context) {
+	recap, ok := c.recap(ctx)
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := staff_attendance_use_case.WriteRecapCSV(&buf, recap); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="presensi-pegawai-%s.csv"`, recap.Month))
+	ctx.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+func (c *StaffAttendanceController) recap(ctx *gin.Context) (*staff_attendance_use_case.MonthlyRecap, bool) {
+	unitId, ok := parseUnit(ctx)
+	if !ok {
+		return nil, false
+	}
+	month, ok := parseMonth(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	recap, err := c.useCase.GetMonthlyRecap(unitId, month)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin_utils.MessageResponse{Message: err.Error()})
+		return nil, false
+	}
+	return recap, true
+}
+
+func bindCheck(ctx *gin.Context) (*staff_attendance_use_case.CheckRequest, bool) {
+	unitId, ok := parseUnit(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	var dto CheckDTO
+	// The body is optional
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&dto); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+			return nil, false
+		}
+	}
+
+	claims := auth_utils.GetAuthClaim(ctx.Request.Context())
+	req := &staff_attendance_use_case.CheckRequest{
+		UnitId:    unitId,
+		UserId:    claims.UserID,
+		ViaApiKey: claims.IsApiKey(),
+		Latitude:  dto.Latitude,
+		Longitude: dto.Longitude,
+		DeviceId:  dto.DeviceId,
+		Note:      dto.Note,
+	}
+	if req.ViaApiKey && dto.UserId != nil && *dto.UserId != "" {
+		staffUserId, err := uuid.Parse(*dto.UserId)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid user ID"})
+			return nil, false
+		}
+		req.StaffUserId = &staffUserId
+	}
+	return req, true
+}
+
+func parseUnit(ctx *gin.Context) (uuid.UUID, bool) {
+	unitId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid unit ID"})
+		return uuid.Nil, false
+	}
+	return unitId, true
+}
+
+func parseMonth(ctx *gin.Context) (time.Time, bool) {
+	value := ctx.Query("month")
+	if value == "" {
+		return time.Now(), true
+	}
+	month, err := time.Parse("2006-01", value)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid month, use YYYY-MM"})
+		return time.Time{}, false
+	}
+	return month, true
+}
@@ -6,6 +6,7 @@ import (
 	"sekolah-madrasah/app/use_case/user_use_case"
 	"sekolah-madrasah/pkg/gin_utils"
 	"sekolah-madrasah/pkg/paginate_utils"
+	"sekolah-madrasah/pkg/password_utils"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -34,6 +35,7 @@ type CreateStudentProfileDTO struct {
 	MotherName     *string `json:"mother_name"`
 	GuardianName   *string `json:"guardian_name"`
 	ParentPhone    *string `json:"parent_phone"`
+	ParentUserId   *string `json:"parent_user_id"` // Parent's account, may see the published report cards
 	EnrollmentDate *string `json:"enrollment_date"`
 }
 
@@ -49,6 +51,7 @@ type UpdateStudentProfileDTO struct {
 	MotherName     *string `json:"mother_name"`
 	GuardianName   *string `json:"guardian_name"`
 	ParentPhone    *string `json:"parent_phone"`
+	ParentUserId   *string `json:"parent_user_id"` // Parent's account, may see the published report cards
 	EnrollmentDate *string `json:"enrollment_date"`
 }
 
@@ -70,6 +73,7 @@ type CreateStudentWithUserDTO struct {
 	MotherName     *string `json:"mother_name"`
 	GuardianName   *string `json:"guardian_name"`
 	ParentPhone    *string `json:"parent_phone"`
+	ParentUserId   *string `json:"parent_user_id"` // Parent's account, may see the published report cards
 	EnrollmentDate *string `json:"enrollment_date"`
 }
 
@@ -163,6 +167,11 @@ func (c *StudentProfileController) Create(ctx *gin.Context) {
 		return
 	}
 
+	parentUserId, ok := parseParentUserId(ctx, dto.ParentUserId)
+	if !ok {
+		return
+	}
+
 	userId, err := uuid.Parse(dto.UserId)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid user ID"})
@@ -183,6 +192,7 @@ func (c *StudentProfileController) Create(ctx *gin.Context) {
 		MotherName:     dto.MotherName,
 		GuardianName:   dto.GuardianName,
 		ParentPhone:    dto.ParentPhone,
+		ParentUserId:   parentUserId,
 		EnrollmentDate: dto.EnrollmentDate,
 	}
 
@@ -217,6 +227,11 @@ func (c *StudentProfileController) Update(ctx *gin.Context) {
 		return
 	}
 
+	parentUserId, ok := parseParentUserId(ctx, dto.ParentUserId)
+	if !ok {
+		return
+	}
+
 	req := &student_profile_use_case.UpdateStudentProfileRequest{
 		NIS:            dto.NIS,
 		NISN:           dto.NISN,
@@ -229,6 +244,7 @@ func (c *StudentProfileController) Update(ctx *gin.Context) {
 		MotherName:     dto.MotherName,
 		GuardianName:   dto.GuardianName,
 		ParentPhone:    dto.ParentPhone,
+		ParentUserId:   parentUserId,
 		EnrollmentDate: dto.EnrollmentDate,
 	}
 
@@ -285,16 +301,14 @@ func (c *StudentProfileController) CreateWithUser(ctx *gin.Context) {
 		return
 	}
 
-	// Generate default password: NIS + DDMMYYYY
-	password := dto.NIS
-	if dto.BirthDate != "" {
-		// Parse date and format as DDMMYYYY
-		if len(dto.BirthDate) >= 10 {
-			// Assuming format YYYY-MM-DD
-			password += dto.BirthDate[8:10] + dto.BirthDate[5:7] + dto.BirthDate[0:4]
-		}
+	parentUserId, ok := parseParentUserId(ctx, dto.ParentUserId)
+	if !ok {
+		return
 	}
 
+	// Generate a random temporary password; the student must replace it on first login
+	password := password_utils.GeneratePassword(12)
+
 	// Generate email if not provided
 	email := ""
 	if dto.Email != nil && *dto.Email != "" {
@@ -311,10 +325,11 @@ func (c *StudentProfileController) CreateWithUser(ctx *gin.Context) {
 
 	// Create user first
 	user, code, err := c.userUseCase.CreateUser(ctx.Request.Context(), user_use_case.CreateUserRequest{
-		Email:    email,
-		Password: password,
-		FullName: dto.FullName,
-		Phone:    phone,
+		Email:              email,
+		Password:           password,
+		FullName:           dto.FullName,
+		Phone:              phone,
+		MustChangePassword: true,
 	})
 	if err != nil {
 		ctx.JSON(code, gin_utils.MessageResponse{Message: "Gagal membuat akun: " + err.Error()})
@@ -336,6 +351,7 @@ func (c *StudentProfileController) CreateWithUser(ctx *gin.Context) {
 		MotherName:     dto.MotherName,
 		GuardianName:   dto.GuardianName,
 		ParentPhone:    dto.ParentPhone,
+		ParentUserId:   parentUserId,
 		EnrollmentDate: dto.EnrollmentDate,
 	}
 
@@ -355,3 +371,16 @@ func (c *StudentProfileController) CreateWithUser(ctx *gin.Context) {
 		},
 	})
 }
+
+// parseParentUserId reads the optional parent account ID; nil or empty is none.
+func parseParentUserId(ctx *gin.Context, value *string) (*uuid.UUID, bool) {
+	if value == nil || *value == "" {
+		return nil, true
+	}
+	id, err := uuid.Parse(*value)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid parent user ID"})
+		return nil, false
+	}
+	return &id, true
+}
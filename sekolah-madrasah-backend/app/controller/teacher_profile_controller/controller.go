@@ -5,6 +5,7 @@ import (
 	"sekolah-madrasah/app/use_case/teacher_profile_use_case"
 	"sekolah-madrasah/app/use_case/user_use_case"
 	"sekolah-madrasah/pkg/gin_utils"
+	"sekolah-madrasah/pkg/password_utils"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -182,11 +183,8 @@ func (c *TeacherProfileController) CreateWithUser(ctx *gin.Context) {
 		return
 	}
 
-	// Generate default password: guru + random 6 chars or NIP if available
-	password := "guru123456"
-	if dto.NIP != nil && *dto.NIP != "" {
-		password = *dto.NIP
-	}
+	// Generate a random temporary password; the teacher must replace it on first login
+	password := password_utils.GeneratePassword(12)
 
 	// Generate email if not provided
 	email := ""
@@ -209,10 +207,11 @@ func (c *TeacherProfileController) CreateWithUser(ctx *gin.Context) {
 
 	// Create user first
 	user, code, err := c.userUseCase.CreateUser(ctx.Request.Context(), user_use_case.CreateUserRequest{
-		Email:    email,
-		Password: password,
-		FullName: dto.FullName,
-		Phone:    phone,
+		Email:              email,
+		Password:           password,
+		FullName:           dto.FullName,
+		Phone:              phone,
+		MustChangePassword: true,
 	})
 	if err != nil {
 		ctx.JSON(code, gin_utils.MessageResponse{Message: "Gagal membuat akun: " + err.Error()})
@@ -0,0 +1,719 @@
+package timetable_controller
+
+import (
+	"errors"
+	"net/http"
+	"sekolah-madrasah/app/use_case/timetable_use_case"
+	"sekolah-madrasah/pkg/auth_utils"
+	"sekolah-madrasah/pkg/gin_utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type TimetableController struct {
+	useCase timetable_use_case.TimetableUseCase
+}
+
+func NewTimetableController(useCase timetable_use_case.TimetableUseCase) *TimetableController {
+	return &TimetableController{useCase: useCase}
+}
+
+type CreateEntryDTO struct {
+	ClassId          string  `json:"class_id" binding:"required"`
+	SubjectId        string  `json:"subject_id" binding:"required"`
+	TeacherProfileId string  `json:"teacher_profile_id" binding:"required"`
+	Room             *string `json:"room" binding:"omitempty,max=50"`
+	Semester         int     `json:"semester"`
+	DayOfWeek        int     `json:"day_of_week" binding:"required"` // 1 = Monday ... 7 = Sunday
+	Period           int     `json:"period" binding:"required"`
+}
+
+type UpdateEntryDTO struct {
+	SubjectId        *string `json:"subject_id"`
+	TeacherProfileId *string `json:"teacher_profile_id"`
+	Room             *string `json:"room" binding:"omitempty,max=50"`
+	DayOfWeek        *int    `json:"day_of_week"`
+	Period           *int    `json:"period"`
+}
+
+type GenerationDTO struct {
+	AcademicYear string   `json:"academic_year"`
+	Semester     int      `json:"semester"`
+	ClassIds     []string `json:"class_ids"` // Defaults to every active class of the academic year
+	Days         []int    `json:"days"`      // Defaults to Monday to Friday
+}
+
+type ClassSubjectDTO struct {
+	SubjectId        string  `json:"subject_id" binding:"required"`
+	TeacherProfileId *string `json:"teacher_profile_id"`
+	HoursPerWeek     int     `json:"hours_per_week" binding:"required"`
+	Room             *string `json:"room" binding:"omitempty,max=50"`
+}
+
+type SetClassSubjectsDTO struct {
+	Subjects []ClassSubjectDTO `json:"subjects" binding:"dive"`
+}
+
+type UnavailabilityDTO struct {
+	DayOfWeek  int     `json:"day_of_week" binding:"required"`
+	PeriodFrom int     `json:"period_from" binding:"required"`
+	PeriodTo   int     `json:"period_to" binding:"required"`
+	Reason     *string `json:"reason" binding:"omitempty,max=255"`
+}
+
+type SetUnavailabilityDTO struct {
+	Periods []UnavailabilityDTO `json:"periods" binding:"dive"`
+}
+
+// errorStatus maps use case errors to their HTTP status; anything else is a validation error.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, timetable_use_case.ErrEntryNotFound), errors.Is(err, timetable_use_case.ErrGenerationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, timetable_use_case.ErrConflict):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// respondError answers with the error; conflicts are listed in data so the
+// editor can point at the lessons involved.
+func respondError(ctx *gin.Context, err error) {
+	var conflictErr *timetable_use_case.ConflictError
+	if errors.As(err, &conflictErr) {
+		ctx.JSON(http.StatusConflict, gin_utils.DataResponse{Message: err.Error(), Data: conflictErr.Conflicts})
+		return
+	}
+	ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+}
+
+// GetAll godoc
+// @Summary Get a unit's timetable
+// @Description Lists the lessons of one semester with their period times. academic_year and semester default to the unit's current ones.
+// @Tags Timetable
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param academic_year query string false "Academic Year"
+// @Param semester query int false "Semester (1 or 2)"
+// @Param class_id query string false "Class ID"
+// @Param teacher_id query string false "Teacher Profile ID"
+// @Param room query string false "Room"
+// @Param day query int false "Day of week (1 = Monday ... 7 = Sunday)"
+// @Success 200 {object} gin_utils.DataResponse{data=timetable_use_case.TimetableView}
+// @Router /api/v1/units/{id}/timetable [get]
+func (c *TimetableController) GetAll(ctx *gin.Context) {
+	query, ok := parseQuery(ctx)
+	if !ok {
+		return
+	}
+
+	if classId := ctx.Query("class_id"); classId != "" {
+		id, err := uuid.Parse(classId)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid class ID"})
+			return
+		}
+		query.ClassId = &id
+	}
+	if teacherId := ctx.Query("teacher_id"); teacherId != "" {
+		id, err := uuid.Parse(teacherId)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid teacher ID"})
+			return
+		}
+		query.TeacherProfileId = &id
+	}
+	if room := ctx.Query("room"); room != "" {
+		query.Room = &room
+	}
+	if day := ctx.Query("day"); day != "" {
+		dayOfWeek, err := strconv.Atoi(day)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid day"})
+			return
+		}
+		query.DayOfWeek = &dayOfWeek
+	}
+
+	c.respondTimetable(ctx, query)
+}
+
+// GetByClass godoc
+// @Summary Get a class's timetable
+// @Tags Timetable
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param classId path string true "Class ID"
+// @Param academic_year query string false "Academic Year"
+// @Param semester query int false "Semester (1 or 2)"
+// @Success 200 {object} gin_utils.DataResponse{data=timetable_use_case.TimetableView}
+// @Router /api/v1/units/{id}/timetable/classes/{classId} [get]
+func (c *TimetableController) GetByClass(ctx *gin.Context) {
+	classId, err := uuid.Parse(ctx.Param("classId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid class ID"})
+		return
+	}
+
+	query, ok := parseQuery(ctx)
+	if !ok {
+		return
+	}
+	query.ClassId = &classId
+	c.respondTimetable(ctx, query)
+}
+
+// GetByTeacher godoc
+// @Summary Get a teacher's timetable
+// @Tags Timetable
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param teacherId path string true "Teacher Profile ID"
+// @Param academic_year query string false "Academic Year"
+// @Param semester query int false "Semester (1 or 2)"
+// @Success 200 {object} gin_utils.DataResponse{data=timetable_use_case.TimetableView}
+// @Router /api/v1/units/{id}/timetable/teachers/{teacherId} [get]
+func (c *TimetableController) GetByTeacher(ctx *gin.Context) {
+	teacherId, err := uuid.Parse(ctx.Param("teacherId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid teacher ID"})
+		return
+	}
+
+	query, ok := parseQuery(ctx)
+	if !ok {
+		return
+	}
+	query.TeacherProfileId = &teacherId
+	c.respondTimetable(ctx, query)
+}
+
+// GetByRoom godoc
+// @Summary Get a room's timetable
+// @Tags Timetable
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param room path string true "Room"
+// @Param academic_year query string false "Academic Year"
+// @Param semester query int false "Semester (1 or 2)"
+// @Success 200 {object} gin_utils.DataResponse{data=timetable_use_case.TimetableView}
+// @Router /api/v1/units/{id}/timetable/rooms/{room} [get]
+func (c *TimetableController) GetByRoom(ctx *gin.Context) {
+	query, ok := parseQuery(ctx)
+	if !ok {
+		return
+	}
+	room := ctx.Param("room")
+	query.Room = &room
+	c.respondTimetable(ctx, query)
+}
+
+// GetPeriods godoc
+// @Summary Get the unit's period times
+// @Description Start and end time of every period, derived from the unit settings
+// @Tags Timetable
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Success 200 {object} gin_utils.DataResponse{data=[]timetable_use_case.PeriodTime}
+// @Router /api/v1/units/{id}/timetable/periods [get]
+func (c *TimetableController) GetPeriods(ctx *gin.Context) {
+	unitId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid unit ID"})
+		return
+	}
+
+	periods, err := c.useCase.GetPeriods(unitId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Periods retrieved successfully", Data: periods})
+}
+
+// GetById godoc
+// @Summary Get timetable entry by ID
+// @Tags Timetable
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param timetableId path string true "Timetable entry ID"
+// @Success 200 {object} gin_utils.DataResponse{data=timetable_use_case.EntryView}
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/timetable/{timetableId} [get]
+func (c *TimetableController) GetById(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("timetableId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid timetable entry ID"})
+		return
+	}
+
+	entry, err := c.useCase.GetById(id)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Timetable entry retrieved successfully", Data: entry})
+}
+
+// Create godoc
+// @Summary Create timetable entry
+// @Description Schedules a subject and its teacher in one class's weekly period. The academic year is the class's; semester defaults to the unit's current one.
+// @Tags Timetable
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param body body CreateEntryDTO true "Timetable entry data"
+// @Success 201 {object} gin_utils.DataResponse{data=timetable_use_case.EntryView}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 409 {object} gin_utils.DataResponse{data=[]timetable_use_case.Conflict}
+// @Router /api/v1/units/{id}/timetable [post]
+func (c *TimetableController) Create(ctx *gin.Context) {
+	unitId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid unit ID"})
+		return
+	}
+
+	var dto CreateEntryDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	classId, err := uuid.Parse(dto.ClassId)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid class ID"})
+		return
+	}
+	subjectId, err := uuid.Parse(dto.SubjectId)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid subject ID"})
+		return
+	}
+	teacherProfileId, err := uuid.Parse(dto.TeacherProfileId)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid teacher profile ID"})
+		return
+	}
+
+	req := &timetable_use_case.CreateEntryRequest{
+		UnitId:           unitId,
+		ClassId:          classId,
+		SubjectId:        subjectId,
+		TeacherProfileId: teacherProfileId,
+		Room:             dto.Room,
+		Semester:         dto.Semester,
+		DayOfWeek:        dto.DayOfWeek,
+		Period:           dto.Period,
+	}
+
+	entry, err := c.useCase.Create(req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin_utils.DataResponse{Message: "Timetable entry created successfully", Data: entry})
+}
+
+// Update godoc
+// @Summary Update timetable entry
+// @Tags Timetable
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param timetableId path string true "Timetable entry ID"
+// @Param body body UpdateEntryDTO true "Timetable entry data"
+// @Success 200 {object} gin_utils.DataResponse{data=timetable_use_case.EntryView}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Failure 409 {object} gin_utils.DataResponse{data=[]timetable_use_case.Conflict}
+// @Router /api/v1/units/{id}/timetable/{timetableId} [put]
+func (c *TimetableController) Update(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("timetableId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid timetable entry ID"})
+		return
+	}
+
+	var dto UpdateEntryDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	req := &timetable_use_case.UpdateEntryRequest{
+		Room:      dto.Room,
+		DayOfWeek: dto.DayOfWeek,
+		Period:    dto.Period,
+	}
+	if dto.SubjectId != nil {
+		subjectId, err := uuid.Parse(*dto.SubjectId)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid subject ID"})
+			return
+		}
+		req.SubjectId = &subjectId
+	}
+	if dto.TeacherProfileId != nil {
+		teacherProfileId, err := uuid.Parse(*dto.TeacherProfileId)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid teacher profile ID"})
+			return
+		}
+		req.TeacherProfileId = &teacherProfileId
+	}
+
+	entry, err := c.useCase.Update(id, req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Timetable entry updated successfully", Data: entry})
+}
+
+// Delete godoc
+// @Summary Delete timetable entry
+// @Tags Timetable
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param timetableId path string true "Timetable entry ID"
+// @Success 200 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/timetable/{timetableId} [delete]
+func (c *TimetableController) Delete(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("timetableId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid timetable entry ID"})
+		return
+	}
+
+	if err := c.useCase.Delete(id); err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.MessageResponse{Message: "Timetable entry deleted successfully"})
+}
+
+// GetConflicts godoc
+// @Summary Check the timetable for conflicts
+// @Description Lists double-booked teachers, classes and rooms, lessons in a teacher's unavailable periods and runs longer than the unit's max consecutive periods.
+// @Tags Timetable
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param academic_year query string false "Academic Year"
+// @Param semester query int false "Semester (1 or 2)"
+// @Success 200 {object} gin_utils.DataResponse{data=[]timetable_use_case.Conflict}
+// @Router /api/v1/units/{id}/timetable/conflicts [get]
+func (c *TimetableController) GetConflicts(ctx *gin.Context) {
+	query, ok := parseQuery(ctx)
+	if !ok {
+		return
+	}
+
+	conflicts, err := c.useCase.GetConflicts(query)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Timetable conflicts retrieved successfully", Data: conflicts})
+}
+
+// StartGeneration godoc
+// @Summary Generate a timetable
+// @Description Starts a background job that lays out the classes' subject hours over the school days. Poll the generation until it is completed, review what it could not satisfy, then apply it.
+// @Tags Timetable
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param body body GenerationDTO true "Generation options"
+// @Success 202 {object} gin_utils.DataResponse{data=timetable_use_case.GenerationView}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/timetable/generations [post]
+func (c *TimetableController) StartGeneration(ctx *gin.Context) {
+	unitId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid unit ID"})
+		return
+	}
+
+	var dto GenerationDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	req := &timetable_use_case.GenerationRequest{
+		UnitId:       unitId,
+		AcademicYear: dto.AcademicYear,
+		Semester:     dto.Semester,
+		Days:         dto.Days,
+		RequestedBy:  auth_utils.GetAuthClaim(ctx.Request.Context()).UserID,
+	}
+	for _, classId := range dto.ClassIds {
+		id, err := uuid.Parse(classId)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid class ID"})
+			return
+		}
+		req.ClassIds = append(req.ClassIds, id)
+	}
+
+	generation, err := c.useCase.StartGeneration(req)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin_utils.DataResponse{Message: "Timetable generation started", Data: generation})
+}
+
+// GetGeneration godoc
+// @Summary Get a timetable generation
+// @Tags Timetable
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param generationId path string true "Generation ID"
+// @Success 200 {object} gin_utils.DataResponse{data=timetable_use_case.GenerationView}
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/timetable/generations/{generationId} [get]
+func (c *TimetableController) GetGeneration(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("generationId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid generation ID"})
+		return
+	}
+
+	generation, err := c.useCase.GetGeneration(id)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Timetable generation retrieved successfully", Data: generation})
+}
+
+// ApplyGeneration godoc
+// @Summary Apply a timetable generation
+// @Description Replaces the semester's timetable of the generated classes with the proposal, after checking it against the current timetable again.
+// @Tags Timetable
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param generationId path string true "Generation ID"
+// @Success 200 {object} gin_utils.DataResponse{data=timetable_use_case.GenerationView}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Failure 409 {object} gin_utils.DataResponse{data=[]timetable_use_case.Conflict}
+// @Router /api/v1/units/{id}/timetable/generations/{generationId}/apply [post]
+func (c *TimetableController) ApplyGeneration(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("generationId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid generation ID"})
+		return
+	}
+
+	generation, err := c.useCase.ApplyGeneration(id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Timetable generation applied successfully", Data: generation})
+}
+
+// GetClassSubjects godoc
+// @Summary Get a class's subject hours
+// @Description The subjects on the class's curriculum with their weekly hours, which the timetable generator lays out.
+// @Tags Timetable
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param classId path string true "Class ID"
+// @Success 200 {object} gin_utils.DataResponse{data=[]schemas.ClassSubject}
+// @Router /api/v1/units/{id}/classes/{classId}/subject-hours [get]
+func (c *TimetableController) GetClassSubjects(ctx *gin.Context) {
+	unitId, classId, ok := parseUnitAnd(ctx, "classId", "Invalid class ID")
+	if !ok {
+		return
+	}
+
+	subjects, err := c.useCase.GetClassSubjects(unitId, classId)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Class subject hours retrieved successfully", Data: subjects})
+}
+
+// SetClassSubjects godoc
+// @Summary Set a class's subject hours
+// @Description Replaces the class's subjects and weekly hours. teacher_profile_id pins the subject's teacher for this class; without it the generator picks one of the subject's teachers.
+// @Tags Timetable
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param classId path string true "Class ID"
+// @Param body body SetClassSubjectsDTO true "Subject hours"
+// @Success 200 {object} gin_utils.DataResponse{data=[]schemas.ClassSubject}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/classes/{classId}/subject-hours [put]
+func (c *TimetableController) SetClassSubjects(ctx *gin.Context) {
+	unitId, classId, ok := parseUnitAnd(ctx, "classId", "Invalid class ID")
+	if !ok {
+		return
+	}
+
+	var dto SetClassSubjectsDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	subjects := make([]timetable_use_case.ClassSubjectRequest, 0, len(dto.Subjects))
+	for _, item := range dto.Subjects {
+		subjectId, err := uuid.Parse(item.SubjectId)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid subject ID"})
+			return
+		}
+		req := timetable_use_case.ClassSubjectRequest{
+			SubjectId:    subjectId,
+			HoursPerWeek: item.HoursPerWeek,
+			Room:         item.Room,
+		}
+		if item.TeacherProfileId != nil {
+			teacherProfileId, err := uuid.Parse(*item.TeacherProfileId)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid teacher profile ID"})
+				return
+			}
+			req.TeacherProfileId = &teacherProfileId
+		}
+		subjects = append(subjects, req)
+	}
+
+	result, err := c.useCase.SetClassSubjects(unitId, classId, subjects)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Class subject hours updated successfully", Data: result})
+}
+
+// GetUnavailability godoc
+// @Summary Get a teacher's unavailable periods
+// @Tags Timetable
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param teacherId path string true "Teacher Profile ID"
+// @Success 200 {object} gin_utils.DataResponse{data=[]schemas.TeacherUnavailability}
+// @Router /api/v1/units/{id}/teachers/{teacherId}/unavailability [get]
+func (c *TimetableController) GetUnavailability(ctx *gin.Context) {
+	unitId, teacherId, ok := parseUnitAnd(ctx, "teacherId", "Invalid teacher ID")
+	if !ok {
+		return
+	}
+
+	periods, err := c.useCase.GetUnavailability(unitId, teacherId)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Teacher unavailability retrieved successfully", Data: periods})
+}
+
+// SetUnavailability godoc
+// @Summary Set a teacher's unavailable periods
+// @Description Replaces the periods in which the teacher cannot be scheduled. Ranges are inclusive.
+// @Tags Timetable
+// @Security BearerAuth
+// @Param id path string true "Unit ID"
+// @Param teacherId path string true "Teacher Profile ID"
+// @Param body body SetUnavailabilityDTO true "Unavailable periods"
+// @Success 200 {object} gin_utils.DataResponse{data=[]schemas.TeacherUnavailability}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/teachers/{teacherId}/unavailability [put]
+func (c *TimetableController) SetUnavailability(ctx *gin.Context) {
+	unitId, teacherId, ok := parseUnitAnd(ctx, "teacherId", "Invalid teacher ID")
+	if !ok {
+		return
+	}
+
+	var dto SetUnavailabilityDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	periods := make([]timetable_use_case.UnavailabilityRequest, 0, len(dto.Periods))
+	for _, item := range dto.Periods {
+		periods = append(periods, timetable_use_case.UnavailabilityRequest{
+			DayOfWeek:  item.DayOfWeek,
+			PeriodFrom: item.PeriodFrom,
+			PeriodTo:   item.PeriodTo,
+			Reason:     item.Reason,
+		})
+	}
+
+	result, err := c.useCase.SetUnavailability(unitId, teacherId, periods)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Teacher unavailability updated successfully", Data: result})
+}
+
+// parseUnitAnd reads the unit and one more id from the path.
+func parseUnitAnd(ctx *gin.Context, param, invalidMessage string) (uuid.UUID, uuid.UUID, bool) {
+	unitId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid unit ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	id, err := uuid.Parse(ctx.Param(param))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: invalidMessage})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return unitId, id, true
+}
+
+// parseQuery reads the unit and the optional academic_year and semester shared by
+// every timetable view, answering the request itself when they are invalid.
+func parseQuery(ctx *gin.Context) (*timetable_use_case.TimetableQuery, bool) {
+	unitId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Invalid unit ID"})
+		return nil, false
+	}
+
+	query := &timetable_use_case.TimetableQuery{
+		UnitId:       unitId,
+		AcademicYear: ctx.Query("academic_year"),
+	}
+	if semester := ctx.Query("semester"); semester != "" {
+		query.Semester, err = strconv.Atoi(semester)
+		if err != nil || (query.Semester != 1 && query.Semester != 2) {
+			ctx.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "Semester must be 1 or 2"})
+			return nil, false
+		}
+	}
+	return query, true
+}
+
+func (c *TimetableController) respondTimetable(ctx *gin.Context, query *timetable_use_case.TimetableQuery) {
+	timetable, err := c.useCase.GetTimetable(query)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin_utils.DataResponse{Message: "Timetable retrieved successfully", Data: timetable})
+}
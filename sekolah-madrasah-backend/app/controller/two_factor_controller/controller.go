@@ -0,0 +1,291 @@
+package two_factor_controller
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/Rhyanz46/go-map-validator/map_validator"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"sekolah-madrasah/app/use_case/auth_use_case"
+	"sekolah-madrasah/pkg/auth_utils"
+	"sekolah-madrasah/pkg/gin_utils"
+)
+
+type twoFactorController struct {
+	authUseCase auth_use_case.AuthUseCase
+}
+
+func NewTwoFactorController(authUseCase auth_use_case.AuthUseCase) TwoFactorController {
+	return &twoFactorController{authUseCase: authUseCase}
+}
+
+func currentUserId(c *gin.Context) (uuid.UUID, bool) {
+	claims := auth_utils.GetAuthClaim(c.Request.Context())
+	if claims.UserID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, gin_utils.MessageResponse{Message: "unauthorized"})
+		return uuid.Nil, false
+	}
+	return claims.UserID, true
+}
+
+// bindCode reads a {"code": "123456"} body.
+func bindCode(c *gin.Context) (string, bool) {
+	roles := map_validator.BuildRoles().
+		SetRule("code", map_validator.Rules{
+			Type: reflect.String,
+			Min:  map_validator.SetTotal(6),
+			Max:  map_validator.SetTotal(10),
+		})
+
+	jsonDataRoles := map_validator.NewValidateBuilder().SetRules(roles)
+	jsonDataValidate, err := jsonDataRoles.LoadJsonHttp(c.Request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return "", false
+	}
+
+	jsonData, err := jsonDataValidate.RunValidate()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return "", false
+	}
+
+	var req CodeRequest
+	if err := jsonData.Bind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return "", false
+	}
+	return req.Code, true
+}
+
+// GetStatus godoc
+// @Summary Get my two-factor status
+// @Description Reports whether two-factor authentication is enabled, whether policy requires it and how many recovery codes are left
+// @Tags Two-Factor
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} gin_utils.DataResponse{data=TwoFactorStatus}
+// @Failure 401 {object} gin_utils.MessageResponse
+// @Router /api/v1/users/me/2fa [get]
+func (ctrl *twoFactorController) GetStatus(c *gin.Context) {
+	userId, ok := currentUserId(c)
+	if !ok {
+		return
+	}
+
+	status, code, err := ctrl.authUseCase.GetTwoFactorStatus(c.Request.Context(), userId)
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin_utils.DataResponse{
+		Message: "two-factor status retrieved",
+		Data: TwoFactorStatus{
+			Enabled:           status.Enabled,
+			Required:          status.Required,
+			RecoveryCodesLeft: status.RecoveryCodesLeft,
+		},
+	})
+}
+
+// Setup godoc
+// @Summary Start two-factor enrollment
+// @Description Generates a TOTP secret and otpauth URI for an authenticator app. Two-factor stays off until it is enabled with a code.
+// @Tags Two-Factor
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} gin_utils.DataResponse{data=TwoFactorSetup}
+// @Failure 401 {object} gin_utils.MessageResponse
+// @Failure 409 {object} gin_utils.MessageResponse
+// @Router /api/v1/users/me/2fa/setup [post]
+func (ctrl *twoFactorController) Setup(c *gin.Context) {
+	userId, ok := currentUserId(c)
+	if !ok {
+		return
+	}
+
+	setup, code, err := ctrl.authUseCase.SetupTwoFactor(c.Request.Context(), userId)
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin_utils.DataResponse{
+		Message: "scan the secret with an authenticator app, then enable two-factor with a code",
+		Data: TwoFactorSetup{
+			Secret:     setup.Secret,
+			OtpauthURI: setup.OtpauthURI,
+		},
+	})
+}
+
+// Enable godoc
+// @Summary Enable two-factor authentication
+// @Description Confirms enrollment with a code from the authenticator app and returns recovery codes, which are shown only once. Refresh the token pair afterwards to drop an enrollment-only restriction.
+// @Tags Two-Factor
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CodeRequest true "TOTP code"
+// @Success 200 {object} gin_utils.DataResponse{data=RecoveryCodesResponse}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 401 {object} gin_utils.MessageResponse
+// @Router /api/v1/users/me/2fa/enable [post]
+func (ctrl *twoFactorController) Enable(c *gin.Context) {
+	userId, ok := currentUserId(c)
+	if !ok {
+		return
+	}
+
+	totpCode, ok := bindCode(c)
+	if !ok {
+		return
+	}
+
+	recoveryCodes, code, err := ctrl.authUseCase.EnableTwoFactor(c.Request.Context(), userId, totpCode)
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin_utils.DataResponse{
+		Message: "two-factor authentication enabled",
+		Data:    RecoveryCodesResponse{RecoveryCodes: recoveryCodes},
+	})
+}
+
+// Disable godoc
+// @Summary Disable two-factor authentication
+// @Description Requires the current password and a TOTP or recovery code. Refused when policy makes two-factor mandatory.
+// @Tags Two-Factor
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body DisableRequest true "Password and second factor"
+// @Success 200 {object} gin_utils.MessageResponse
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 401 {object} gin_utils.MessageResponse
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Router /api/v1/users/me/2fa/disable [post]
+func (ctrl *twoFactorController) Disable(c *gin.Context) {
+	userId, ok := currentUserId(c)
+	if !ok {
+		return
+	}
+
+	roles := map_validator.BuildRoles().
+		SetRule("password", map_validator.Rules{
+			Type: reflect.String,
+			Min:  map_validator.SetTotal(1),
+		}).
+		SetRule("code", map_validator.Rules{
+			Type: reflect.String,
+			Max:  map_validator.SetTotal(10),
+			Null: true,
+		}).
+		SetRule("recovery_code", map_validator.Rules{
+			Type: reflect.String,
+			Max:  map_validator.SetTotal(20),
+			Null: true,
+		})
+
+	jsonDataRoles := map_validator.NewValidateBuilder().SetRules(roles)
+	jsonDataValidate, err := jsonDataRoles.LoadJsonHttp(c.Request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	jsonData, err := jsonDataValidate.RunValidate()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	var req DisableRequest
+	if err := jsonData.Bind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	if req.Code == "" && req.RecoveryCode == "" {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "code or recovery_code is required"})
+		return
+	}
+
+	code, err := ctrl.authUseCase.DisableTwoFactor(c.Request.Context(), userId, auth_use_case.DisableTwoFactorRequest{
+		Password:     req.Password,
+		Code:         req.Code,
+		RecoveryCode: req.RecoveryCode,
+	})
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin_utils.MessageResponse{Message: "two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replaces every recovery code after checking a TOTP code. The new codes are shown only once.
+// @Tags Two-Factor
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CodeRequest true "TOTP code"
+// @Success 200 {object} gin_utils.DataResponse{data=RecoveryCodesResponse}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 401 {object} gin_utils.MessageResponse
+// @Router /api/v1/users/me/2fa/recovery-codes [post]
+func (ctrl *twoFactorController) RegenerateRecoveryCodes(c *gin.Context) {
+	userId, ok := currentUserId(c)
+	if !ok {
+		return
+	}
+
+	totpCode, ok := bindCode(c)
+	if !ok {
+		return
+	}
+
+	recoveryCodes, code, err := ctrl.authUseCase.RegenerateRecoveryCodes(c.Request.Context(), userId, totpCode)
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin_utils.DataResponse{
+		Message: "recovery codes regenerated",
+		Data:    RecoveryCodesResponse{RecoveryCodes: recoveryCodes},
+	})
+}
+
+// ResetUserTwoFactor godoc
+// @Summary Reset a user's two-factor authentication
+// @Description Super admin only. Removes the user's authenticator and recovery codes so they can sign in and enroll again.
+// @Tags Two-Factor
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} gin_utils.MessageResponse
+// @Failure 403 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Router /api/v1/users/{id}/2fa [delete]
+func (ctrl *twoFactorController) ResetUserTwoFactor(c *gin.Context) {
+	userId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "invalid user id"})
+		return
+	}
+
+	code, err := ctrl.authUseCase.ResetTwoFactor(c.Request.Context(), userId)
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin_utils.MessageResponse{Message: "two-factor authentication reset"})
+}
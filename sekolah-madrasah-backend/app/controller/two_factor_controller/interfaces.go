@@ -0,0 +1,12 @@
+package two_factor_controller
+
+import "github.com/gin-gonic/gin"
+
+type TwoFactorController interface {
+	GetStatus(c *gin.Context)
+	Setup(c *gin.Context)
+	Enable(c *gin.Context)
+	Disable(c *gin.Context)
+	RegenerateRecoveryCodes(c *gin.Context)
+	ResetUserTwoFactor(c *gin.Context)
+}
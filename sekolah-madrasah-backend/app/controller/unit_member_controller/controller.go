@@ -5,6 +5,7 @@ import (
 
 	"sekolah-madrasah/app/use_case/unit_member_use_case"
 	"sekolah-madrasah/database/schemas"
+	"sekolah-madrasah/pkg/auth_utils"
 	"sekolah-madrasah/pkg/gin_utils"
 	"sekolah-madrasah/pkg/paginate_utils"
 
@@ -31,6 +32,7 @@ func NewUnitMemberController(memberUseCase unit_member_use_case.UnitMemberUseCas
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
 // @Param role query string false "Filter by role (admin, pengurus, warga, parent, staff)"
+// @Param approval_status query string false "Filter by approval status (pending, approved, rejected)"
 // @Success 200 {object} gin_utils.DataWithPaginateResponse{data=[]UnitMember}
 // @Router /api/v1/units/{id}/members [get]
 func (ctrl *unitMemberController) GetMembers(c *gin.Context) {
@@ -55,6 +57,10 @@ func (ctrl *unitMemberController) GetMembers(c *gin.Context) {
 		role := schemas.UnitMemberRole(roleStr)
 		filter.Role = &role
 	}
+	if statusStr := c.Query("approval_status"); statusStr != "" {
+		status := schemas.UnitMemberApprovalStatus(statusStr)
+		filter.ApprovalStatus = &status
+	}
 
 	members, code, err := ctrl.memberUseCase.GetMembers(c.Request.Context(), unitId, filter, paginate)
 	if err != nil {
@@ -264,3 +270,90 @@ func (ctrl *unitMemberController) RemoveMember(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin_utils.MessageResponse{Message: "member removed successfully"})
 }
+
+// ApproveMember godoc
+// @Summary Approve a pending sign-up
+// @Description Approves a teacher, parent or student who signed up for the unit, letting them log in and use it
+// @Tags UnitMember
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param unit_id path string true "Unit ID (UUID)"
+// @Param member_id path string true "Member ID (UUID)"
+// @Success 200 {object} gin_utils.DataResponse{data=UnitMember}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Failure 409 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/members/{memberId}/approve [post]
+func (ctrl *unitMemberController) ApproveMember(c *gin.Context) {
+	unitId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "invalid unit id"})
+		return
+	}
+
+	memberId, err := uuid.Parse(c.Param("memberId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "invalid member id"})
+		return
+	}
+
+	claims := auth_utils.GetAuthClaim(c.Request.Context())
+	member, code, err := ctrl.memberUseCase.ApproveMember(c.Request.Context(), unitId, memberId, claims.UserID)
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin_utils.DataResponse{
+		Message: "sign-up approved",
+		Data:    toMemberResponse(member),
+	})
+}
+
+// RejectMember godoc
+// @Summary Reject a pending sign-up
+// @Description Rejects a teacher, parent or student who signed up for the unit. The reason is shown to them when they try to log in.
+// @Tags UnitMember
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param unit_id path string true "Unit ID (UUID)"
+// @Param member_id path string true "Member ID (UUID)"
+// @Param body body RejectMemberRequest true "Rejection reason"
+// @Success 200 {object} gin_utils.DataResponse{data=UnitMember}
+// @Failure 400 {object} gin_utils.MessageResponse
+// @Failure 404 {object} gin_utils.MessageResponse
+// @Failure 409 {object} gin_utils.MessageResponse
+// @Router /api/v1/units/{id}/members/{memberId}/reject [post]
+func (ctrl *unitMemberController) RejectMember(c *gin.Context) {
+	unitId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "invalid unit id"})
+		return
+	}
+
+	memberId, err := uuid.Parse(c.Param("memberId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "invalid member id"})
+		return
+	}
+
+	var body RejectMemberRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "a reason is required to reject a sign-up"})
+		return
+	}
+
+	claims := auth_utils.GetAuthClaim(c.Request.Context())
+	member, code, err := ctrl.memberUseCase.RejectMember(c.Request.Context(), unitId, memberId, claims.UserID, body.Reason)
+	if err != nil {
+		c.JSON(code, gin_utils.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin_utils.DataResponse{
+		Message: "sign-up rejected",
+		Data:    toMemberResponse(member),
+	})
+}
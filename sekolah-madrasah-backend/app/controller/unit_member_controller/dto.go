@@ -20,8 +20,15 @@ type UnitMember struct {
 	InvitedBy *uuid.UUID             `json:"invited_by,omitempty"`
 	CreatedAt time.Time              `json:"created_at"`
 	UpdatedAt time.Time              `json:"updated_at"`
-	User      *UserInfo              `json:"user,omitempty"`
-	Unit      *UnitInfo              `json:"unit,omitempty"`
+
+	// ApprovalStatus is pending until an admin reviews a self sign-up
+	ApprovalStatus  schemas.UnitMemberApprovalStatus `json:"approval_status"`
+	ReviewedBy      *uuid.UUID                       `json:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time                       `json:"reviewed_at,omitempty"`
+	RejectionReason string                           `json:"rejection_reason,omitempty"`
+
+	User *UserInfo `json:"user,omitempty"`
+	Unit *UnitInfo `json:"unit,omitempty"`
 }
 
 type UserInfo struct {
@@ -47,6 +54,10 @@ type UpdateMemberRequest struct {
 	IsActive *bool                  `json:"is_active,omitempty"`
 }
 
+type RejectMemberRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
 func toMemberResponse(m unit_member_use_case.UnitMember) UnitMember {
 	member := UnitMember{
 		Id:        m.Id,
@@ -58,6 +69,11 @@ func toMemberResponse(m unit_member_use_case.UnitMember) UnitMember {
 		InvitedBy: m.InvitedBy,
 		CreatedAt: m.CreatedAt,
 		UpdatedAt: m.UpdatedAt,
+
+		ApprovalStatus:  m.ApprovalStatus,
+		ReviewedBy:      m.ReviewedBy,
+		ReviewedAt:      m.ReviewedAt,
+		RejectionReason: m.RejectionReason,
 	}
 
 	if m.User != nil {
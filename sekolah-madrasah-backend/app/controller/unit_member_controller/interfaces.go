@@ -8,4 +8,6 @@ type GinUnitMemberController interface {
 	AddMember(c *gin.Context)
 	UpdateMember(c *gin.Context)
 	RemoveMember(c *gin.Context)
+	ApproveMember(c *gin.Context)
+	RejectMember(c *gin.Context)
 }
@@ -45,6 +45,8 @@ func (ctrl *controller) GetSettings(c *gin.Context) {
 			TotalPeriods:     9,
 			BreakAfterPeriod: 3,
 			BreakDuration:    15,
+			Timezone:         "Asia/Jakarta",
+			GradeRounding:    schemas.GradeRoundHalfUp,
 			CreatedAt:        time.Now(),
 			UpdatedAt:        time.Now(),
 		}
@@ -64,6 +66,12 @@ func (ctrl *controller) GetSettings(c *gin.Context) {
 			"total_periods":      settings.TotalPeriods,
 			"break_after_period": settings.BreakAfterPeriod,
 			"break_duration":     settings.BreakDuration,
+			"max_consecutive":    settings.MaxConsecutive,
+			"end_time":           settings.EndTime,
+			"late_tolerance":     settings.LateTolerance,
+			"timezone":           settings.Timezone,
+			"grade_rounding":     settings.GradeRounding,
+			"grade_decimals":     settings.GradeDecimals,
 			"academic_year":      settings.AcademicYear,
 			"current_semester":   settings.CurrentSemester,
 			"semester_1_start":   settings.Semester1Start,
@@ -80,6 +88,12 @@ type UpdateSettingsRequest struct {
 	TotalPeriods     *int    `json:"total_periods"`
 	BreakAfterPeriod *int    `json:"break_after_period"`
 	BreakDuration    *int    `json:"break_duration"`
+	MaxConsecutive   *int    `json:"max_consecutive"`
+	EndTime          *string `json:"end_time"`       // HH:MM, empty to use the end of the last period
+	LateTolerance    *int    `json:"late_tolerance"` // Minutes
+	Timezone         *string `json:"timezone"`       // IANA name, e.g. Asia/Jakarta
+	GradeRounding    *string `json:"grade_rounding"` // half_up, down or up
+	GradeDecimals    *int    `json:"grade_decimals"`
 	AcademicYear     *string `json:"academic_year"`
 	CurrentSemester  *int    `json:"current_semester"`
 	Semester1Start   *string `json:"semester_1_start"`
@@ -106,8 +120,10 @@ func (ctrl *controller) UpdateSettings(c *gin.Context) {
 	var settings schemas.UnitSettings
 	if err := ctrl.db.Where("unit_id = ?", unitId).First(&settings).Error; err != nil {
 		settings = schemas.UnitSettings{
-			Id:     uuid.New(),
-			UnitId: unitId,
+			Id:            uuid.New(),
+			UnitId:        unitId,
+			Timezone:      "Asia/Jakarta",
+			GradeRounding: schemas.GradeRoundHalfUp,
 		}
 	}
 
@@ -126,6 +142,52 @@ func (ctrl *controller) UpdateSettings(c *gin.Context) {
 	if req.BreakDuration != nil {
 		settings.BreakDuration = *req.BreakDuration
 	}
+	if req.MaxConsecutive != nil {
+		if *req.MaxConsecutive < 0 {
+			c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "max_consecutive cannot be negative"})
+			return
+		}
+		settings.MaxConsecutive = *req.MaxConsecutive
+	}
+	if req.EndTime != nil {
+		if *req.EndTime != "" {
+			if _, err := time.Parse("15:04", *req.EndTime); err != nil {
+				c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "end_time must be HH:MM"})
+				return
+			}
+		}
+		settings.EndTime = *req.EndTime
+	}
+	if req.LateTolerance != nil {
+		if *req.LateTolerance < 0 {
+			c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "late_tolerance cannot be negative"})
+			return
+		}
+		settings.LateTolerance = *req.LateTolerance
+	}
+	if req.Timezone != nil {
+		if _, err := time.LoadLocation(*req.Timezone); err != nil || *req.Timezone == "" {
+			c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "unknown timezone"})
+			return
+		}
+		settings.Timezone = *req.Timezone
+	}
+	if req.GradeRounding != nil {
+		switch *req.GradeRounding {
+		case schemas.GradeRoundHalfUp, schemas.GradeRoundDown, schemas.GradeRoundUp:
+			settings.GradeRounding = *req.GradeRounding
+		default:
+			c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "grade_rounding must be half_up, down or up"})
+			return
+		}
+	}
+	if req.GradeDecimals != nil {
+		if *req.GradeDecimals < 0 || *req.GradeDecimals > 2 {
+			c.JSON(http.StatusBadRequest, gin_utils.MessageResponse{Message: "grade_decimals must be between 0 and 2"})
+			return
+		}
+		settings.GradeDecimals = *req.GradeDecimals
+	}
 	if req.AcademicYear != nil {
 		settings.AcademicYear = *req.AcademicYear
 	}
@@ -28,17 +28,19 @@ func NewUserController(userUseCase user_use_case.UserUseCase, membershipService
 
 func (ctrl *userController) toUserResponse(u user_use_case.User) User {
 	return User{
-		Id:              u.Id,
-		Email:           u.Email,
-		FullName:        u.FullName,
-		Phone:           u.Phone,
-		Avatar:          u.Avatar,
-		IsSuperAdmin:    u.IsSuperAdmin,
-		IsActive:        u.IsActive,
-		EmailVerifiedAt: u.EmailVerifiedAt,
-		LastLoginAt:     u.LastLoginAt,
-		CreatedAt:       u.CreatedAt,
-		UpdatedAt:       u.UpdatedAt,
+		Id:                 u.Id,
+		Email:              u.Email,
+		FullName:           u.FullName,
+		Phone:              u.Phone,
+		Avatar:             u.Avatar,
+		IsSuperAdmin:       u.IsSuperAdmin,
+		IsActive:           u.IsActive,
+		MustChangePassword: u.MustChangePassword,
+		EmailVerifiedAt:    u.EmailVerifiedAt,
+		LastLoginAt:        u.LastLoginAt,
+		LockedUntil:        u.LockedUntil,
+		CreatedAt:          u.CreatedAt,
+		UpdatedAt:          u.UpdatedAt,
 	}
 }
 
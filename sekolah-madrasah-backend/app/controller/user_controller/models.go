@@ -7,17 +7,19 @@ import (
 )
 
 type User struct {
-	Id              uuid.UUID  `json:"id"`
-	Email           string     `json:"email"`
-	FullName        string     `json:"full_name"`
-	Phone           string     `json:"phone,omitempty"`
-	Avatar          string     `json:"avatar,omitempty"`
-	IsSuperAdmin    bool       `json:"is_super_admin"`
-	IsActive        bool       `json:"is_active"`
-	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
-	LastLoginAt     *time.Time `json:"last_login_at,omitempty"`
-	CreatedAt       time.Time  `json:"created_at"`
-	UpdatedAt       time.Time  `json:"updated_at"`
+	Id                 uuid.UUID  `json:"id"`
+	Email              string     `json:"email"`
+	FullName           string     `json:"full_name"`
+	Phone              string     `json:"phone,omitempty"`
+	Avatar             string     `json:"avatar,omitempty"`
+	IsSuperAdmin       bool       `json:"is_super_admin"`
+	IsActive           bool       `json:"is_active"`
+	MustChangePassword bool       `json:"must_change_password"`
+	EmailVerifiedAt    *time.Time `json:"email_verified_at,omitempty"`
+	LastLoginAt        *time.Time `json:"last_login_at,omitempty"`
+	LockedUntil        *time.Time `json:"locked_until,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
 }
 
 type CreateUserRequest struct {
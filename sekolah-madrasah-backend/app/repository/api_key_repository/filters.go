@@ -0,0 +1,11 @@
+package api_key_repository
+
+import "github.com/google/uuid"
+
+type ApiKeyFilter struct {
+	Id             *uuid.UUID
+	OrganizationId *uuid.UUID
+	KeyHash        *string
+	// Active limits results to keys that are unrevoked and unexpired
+	Active *bool
+}
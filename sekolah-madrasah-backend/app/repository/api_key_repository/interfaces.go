@@ -0,0 +1,19 @@
+package api_key_repository
+
+import (
+	"context"
+	"time"
+
+	"sekolah-madrasah/pkg/paginate_utils"
+
+	"github.com/google/uuid"
+)
+
+type ApiKeyRepository interface {
+	GetApiKey(ctx context.Context, filter ApiKeyFilter) (ApiKey, int, error)
+	GetApiKeys(ctx context.Context, filter ApiKeyFilter, paginate *paginate_utils.PaginateData) ([]ApiKey, int, error)
+	CreateApiKey(ctx context.Context, key ApiKey) (ApiKey, int, error)
+	// RevokeApiKey returns 404 when no unrevoked key matches filter.
+	RevokeApiKey(ctx context.Context, filter ApiKeyFilter) (int, error)
+	TouchApiKey(ctx context.Context, id uuid.UUID, ipAddress string, usedAt time.Time) (int, error)
+}
@@ -0,0 +1,23 @@
+package api_key_repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ApiKey struct {
+	Id             uuid.UUID
+	OrganizationId uuid.UUID
+	Name           string
+	Prefix         string
+	KeyHash        string
+	Scopes         []string
+	CreatedBy      uuid.UUID
+	ExpiresAt      *time.Time
+	LastUsedAt     *time.Time
+	LastUsedIp     string
+	RevokedAt      *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
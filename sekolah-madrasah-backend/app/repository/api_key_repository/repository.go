@@ -0,0 +1,156 @@
+package api_key_repository
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"sekolah-madrasah/app/repository/common"
+	"sekolah-madrasah/database/schemas"
+	"sekolah-madrasah/pkg/paginate_utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewApiKeyRepository(db *gorm.DB) ApiKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) applyFilter(query *gorm.DB, filter ApiKeyFilter) *gorm.DB {
+	if filter.Id != nil {
+		query = query.Where("id = ?", *filter.Id)
+	}
+	if filter.OrganizationId != nil {
+		query = query.Where("organization_id = ?", *filter.OrganizationId)
+	}
+	if filter.KeyHash != nil {
+		query = query.Where("key_hash = ?", *filter.KeyHash)
+	}
+	if filter.Active != nil {
+		if *filter.Active {
+			query = query.Where("revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", time.Now())
+		} else {
+			query = query.Where("revoked_at IS NOT NULL OR expires_at <= ?", time.Now())
+		}
+	}
+	return query
+}
+
+func (r *apiKeyRepository) toModel(schema schemas.ApiKey) ApiKey {
+	var scopes []string
+	if err := json.Unmarshal([]byte(schema.Scopes), &scopes); err != nil || scopes == nil {
+		scopes = []string{}
+	}
+
+	return ApiKey{
+		Id:             schema.Id,
+		OrganizationId: schema.OrganizationId,
+		Name:           schema.Name,
+		Prefix:         schema.Prefix,
+		KeyHash:        schema.KeyHash,
+		Scopes:         scopes,
+		CreatedBy:      schema.CreatedBy,
+		ExpiresAt:      schema.ExpiresAt,
+		LastUsedAt:     schema.LastUsedAt,
+		LastUsedIp:     schema.LastUsedIp,
+		RevokedAt:      schema.RevokedAt,
+		CreatedAt:      schema.CreatedAt,
+		UpdatedAt:      schema.UpdatedAt,
+	}
+}
+
+func (r *apiKeyRepository) GetApiKey(ctx context.Context, filter ApiKeyFilter) (ApiKey, int, error) {
+	var schema schemas.ApiKey
+	query := r.db.WithContext(ctx).Model(&schemas.ApiKey{})
+	query = r.applyFilter(query, filter)
+
+	if err := query.First(&schema).Error; err != nil {
+		code, err := common.HandleGORMError(err)
+		return ApiKey{}, code, err
+	}
+
+	return r.toModel(schema), http.StatusOK, nil
+}
+
+func (r *apiKeyRepository) GetApiKeys(ctx context.Context, filter ApiKeyFilter, paginate *paginate_utils.PaginateData) ([]ApiKey, int, error) {
+	var schemaList []schemas.ApiKey
+	query := r.db.WithContext(ctx).Model(&schemas.ApiKey{})
+	query = r.applyFilter(query, filter)
+
+	if err := common.CountTotal(query, paginate); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	query = common.ApplyPagination(query, paginate)
+	query = common.ApplyOrderBy(query, "created_at DESC")
+
+	if err := query.Find(&schemaList).Error; err != nil {
+		code, err := common.HandleGORMError(err)
+		return nil, code, err
+	}
+
+	keys := make([]ApiKey, len(schemaList))
+	for i, s := range schemaList {
+		keys[i] = r.toModel(s)
+	}
+
+	return keys, http.StatusOK, nil
+}
+
+func (r *apiKeyRepository) CreateApiKey(ctx context.Context, key ApiKey) (ApiKey, int, error) {
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return ApiKey{}, http.StatusInternalServerError, err
+	}
+
+	schema := schemas.ApiKey{
+		Id:             key.Id,
+		OrganizationId: key.OrganizationId,
+		Name:           key.Name,
+		Prefix:         key.Prefix,
+		KeyHash:        key.KeyHash,
+		Scopes:         string(scopes),
+		CreatedBy:      key.CreatedBy,
+		ExpiresAt:      key.ExpiresAt,
+	}
+
+	if err := r.db.WithContext(ctx).Create(&schema).Error; err != nil {
+		return ApiKey{}, http.StatusInternalServerError, err
+	}
+
+	return r.toModel(schema), http.StatusCreated, nil
+}
+
+func (r *apiKeyRepository) RevokeApiKey(ctx context.Context, filter ApiKeyFilter) (int, error) {
+	now := time.Now()
+	query := r.db.WithContext(ctx).Model(&schemas.ApiKey{})
+	query = r.applyFilter(query, filter)
+
+	result := query.Where("revoked_at IS NULL").
+		Updates(map[string]interface{}{"revoked_at": now, "updated_at": now})
+	if result.Error != nil {
+		return http.StatusInternalServerError, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return http.StatusNotFound, gorm.ErrRecordNotFound
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *apiKeyRepository) TouchApiKey(ctx context.Context, id uuid.UUID, ipAddress string, usedAt time.Time) (int, error) {
+	result := r.db.WithContext(ctx).Model(&schemas.ApiKey{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"last_used_at": usedAt, "last_used_ip": ipAddress})
+	if result.Error != nil {
+		return http.StatusInternalServerError, result.Error
+	}
+
+	return http.StatusOK, nil
+}
@@ -0,0 +1,281 @@
+package attendance_repository
+
+import (
+	"time"
+
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StatusCount is how many days of one status were recorded for a student or a
+// class, depending on the query.
+type StatusCount struct {
+	Id     uuid.UUID // Class enrollment or class
+	Status schemas.AttendanceStatus
+	Days   int
+}
+
+// JournalFilter selects teaching journal entries from From to To, inclusive.
+type JournalFilter struct {
+	UnitId           uuid.UUID
+	ClassId          *uuid.UUID
+	TeacherProfileId *uuid.UUID
+	SubjectId        *uuid.UUID
+	From             time.Time
+	To               time.Time
+}
+
+// LessonStatusCount is how many lessons of a subject a student attended with a status.
+type LessonStatusCount struct {
+	ClassEnrollmentId uuid.UUID
+	SubjectId         uuid.UUID
+	Status            schemas.AttendanceStatus
+	Lessons           int
+}
+
+// LessonMark is a student's status in one lesson of a day.
+type LessonMark struct {
+	LessonJournalId   uuid.UUID
+	ClassEnrollmentId uuid.UUID
+	SubjectId         uuid.UUID
+	SubjectName       string
+	Period            int
+	Status            schemas.AttendanceStatus
+}
+
+type AttendanceRepository interface {
+	FindByClassAndDate(classId uuid.UUID, date time.Time) ([]schemas.DailyAttendance, error)
+	// Save records a class's attendance for a day, replacing what was recorded
+	// before for the same students.
+	Save(records []schemas.DailyAttendance) error
+	CountByEnrollment(classId uuid.UUID, from, to time.Time) ([]StatusCount, error)
+	CountByClass(unitId uuid.UUID, from, to time.Time) ([]StatusCount, error)
+	// CountRecordedDays counts the days on which a class's attendance was recorded
+	CountRecordedDays(classIds []uuid.UUID, from, to time.Time) (map[uuid.UUID]int, error)
+	// Lessons
+	FindJournal(timetableEntryId uuid.UUID, date time.Time) (*schemas.LessonJournal, error)
+	FindJournals(filter JournalFilter) ([]schemas.LessonJournal, error)
+	FindLessonAttendance(lessonJournalId uuid.UUID) ([]schemas.LessonAttendance, error)
+	// SaveLesson stores the journal entry together with the attendance of the
+	// listed students, replacing what was recorded before for them.
+	SaveLesson(journal *schemas.LessonJournal, records []schemas.LessonAttendance) error
+	CountLessonAttendance(classId uuid.UUID, from, to time.Time) ([]LessonStatusCount, error)
+	FindLessonMarks(classId uuid.UUID, date time.Time) ([]LessonMark, error)
+	// Lookups
+	FindClass(id uuid.UUID) (*schemas.Class, error)
+	FindTimetableEntry(id uuid.UUID) (*schemas.TimetableEntry, error)
+	FindClasses(unitId uuid.UUID, academicYear string) ([]schemas.Class, error)
+	FindEnrollments(classId uuid.UUID) ([]schemas.ClassEnrollment, error)
+	FindTeacherByUser(unitId, userId uuid.UUID) (*schemas.TeacherProfile, error)
+	// FindMember returns the user's active, approved membership of the unit
+	FindMember(unitId, userId uuid.UUID) (*schemas.UnitMember, error)
+}
+
+type attendanceRepository struct {
+	db *gorm.DB
+}
+
+func NewAttendanceRepository(db *gorm.DB) AttendanceRepository {
+	return &attendanceRepository{db: db}
+}
+
+func (r *attendanceRepository) FindByClassAndDate(classId uuid.UUID, date time.Time) ([]schemas.DailyAttendance, error) {
+	var records []schemas.DailyAttendance
+	err := r.db.Where("class_id = ? AND date = ?", classId, date).Find(&records).Error
+	return records, err
+}
+
+func (r *attendanceRepository) Save(records []schemas.DailyAttendance) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "class_enrollment_id"}, {Name: "date"}},
+			DoUpdates: clause.AssignmentColumns([]string{"status", "note", "recorded_by", "updated_at"}),
+		}).Create(&records).Error
+	})
+}
+
+func (r *attendanceRepository) CountByEnrollment(classId uuid.UUID, from, to time.Time) ([]StatusCount, error) {
+	var counts []StatusCount
+	err := r.db.Model(&schemas.DailyAttendance{}).
+		Select("class_enrollment_id AS id, status, COUNT(*) AS days").
+		Where("class_id = ? AND date BETWEEN ? AND ?", classId, from, to).
+		Group("class_enrollment_id, status").
+		Scan(&counts).Error
+	return counts, err
+}
+
+func (r *attendanceRepository) CountByClass(unitId uuid.UUID, from, to time.Time) ([]StatusCount, error) {
+	var counts []StatusCount
+	err := r.db.Model(&schemas.DailyAttendance{}).
+		Select("daily_attendances.class_id AS id, daily_attendances.status, COUNT(*) AS days").
+		Joins("JOIN classes ON classes.id = daily_attendances.class_id").
+		Where("classes.unit_id = ? AND daily_attendances.date BETWEEN ? AND ?", unitId, from, to).
+		Group("daily_attendances.class_id, daily_attendances.status").
+		Scan(&counts).Error
+	return counts, err
+}
+
+func (r *attendanceRepository) CountRecordedDays(classIds []uuid.UUID, from, to time.Time) (map[uuid.UUID]int, error) {
+	var rows []struct {
+		ClassId uuid.UUID
+		Days    int
+	}
+	err := r.db.Model(&schemas.DailyAttendance{}).
+		Select("class_id, COUNT(DISTINCT date) AS days").
+		Where("class_id IN ? AND date BETWEEN ? AND ?", classIds, from, to).
+		Group("class_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	days := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		days[row.ClassId] = row.Days
+	}
+	return days, nil
+}
+
+func (r *attendanceRepository) FindJournal(timetableEntryId uuid.UUID, date time.Time) (*schemas.LessonJournal, error) {
+	var journal schemas.LessonJournal
+	err := r.db.First(&journal, "timetable_entry_id = ? AND date = ?", timetableEntryId, date).Error
+	if err != nil {
+		return nil, err
+	}
+	return &journal, nil
+}
+
+func (r *attendanceRepository) FindJournals(filter JournalFilter) ([]schemas.LessonJournal, error) {
+	var journals []schemas.LessonJournal
+
+	query := r.db.Where("unit_id = ? AND date BETWEEN ? AND ?", filter.UnitId, filter.From, filter.To)
+	if filter.ClassId != nil {
+		query = query.Where("class_id = ?", *filter.ClassId)
+	}
+	if filter.TeacherProfileId != nil {
+		query = query.Where("teacher_profile_id = ?", *filter.TeacherProfileId)
+	}
+	if filter.SubjectId != nil {
+		query = query.Where("subject_id = ?", *filter.SubjectId)
+	}
+
+	err := query.Preload("Subject").Preload("TeacherProfile.User").
+		Order("date ASC, period ASC").
+		Find(&journals).Error
+	return journals, err
+}
+
+func (r *attendanceRepository) FindLessonAttendance(lessonJournalId uuid.UUID) ([]schemas.LessonAttendance, error) {
+	var records []schemas.LessonAttendance
+	err := r.db.Where("lesson_journal_id = ?", lessonJournalId).Find(&records).Error
+	return records, err
+}
+
+func (r *attendanceRepository) SaveLesson(journal *schemas.LessonJournal, records []schemas.LessonAttendance) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if journal.Id == uuid.Nil {
+			if err := tx.Create(journal).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Omit("Subject", "TeacherProfile").Save(journal).Error; err != nil {
+			return err
+		}
+
+		if len(records) == 0 {
+			return nil
+		}
+		for i := range records {
+			records[i].LessonJournalId = journal.Id
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "lesson_journal_id"}, {Name: "class_enrollment_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"status", "note"}),
+		}).Create(&records).Error
+	})
+}
+
+func (r *attendanceRepository) CountLessonAttendance(classId uuid.UUID, from, to time.Time) ([]LessonStatusCount, error) {
+	var counts []LessonStatusCount
+	err := r.db.Model(&schemas.LessonAttendance{}).
+		Select("lesson_attendances.class_enrollment_id, lesson_journals.subject_id, lesson_attendances.status, COUNT(*) AS lessons").
+		Joins("JOIN lesson_journals ON lesson_journals.id = lesson_attendances.lesson_journal_id").
+		Where("lesson_journals.class_id = ? AND lesson_journals.date BETWEEN ? AND ?", classId, from, to).
+		Group("lesson_attendances.class_enrollment_id, lesson_journals.subject_id, lesson_attendances.status").
+		Scan(&counts).Error
+	return counts, err
+}
+
+func (r *attendanceRepository) FindLessonMarks(classId uuid.UUID, date time.Time) ([]LessonMark, error) {
+	var marks []LessonMark
+	err := r.db.Model(&schemas.LessonAttendance{}).
+		Select("lesson_journals.id AS lesson_journal_id, lesson_attendances.class_enrollment_id, lesson_journals.subject_id, subjects.name AS subject_name, lesson_journals.period, lesson_attendances.status").
+		Joins("JOIN lesson_journals ON lesson_journals.id = lesson_attendances.lesson_journal_id").
+		Joins("JOIN subjects ON subjects.id = lesson_journals.subject_id").
+		Where("lesson_journals.class_id = ? AND lesson_journals.date = ?", classId, date).
+		Order("lesson_journals.period ASC").
+		Scan(&marks).Error
+	return marks, err
+}
+
+func (r *attendanceRepository) FindClass(id uuid.UUID) (*schemas.Class, error) {
+	var class schemas.Class
+	err := r.db.First(&class, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &class, nil
+}
+
+func (r *attendanceRepository) FindTimetableEntry(id uuid.UUID) (*schemas.TimetableEntry, error) {
+	var entry schemas.TimetableEntry
+	err := r.db.Preload("Subject").First(&entry, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *attendanceRepository) FindClasses(unitId uuid.UUID, academicYear string) ([]schemas.Class, error) {
+	var classes []schemas.Class
+	query := r.db.Where("unit_id = ?", unitId)
+	if academicYear != "" {
+		query = query.Where("academic_year = ?", academicYear)
+	}
+	err := query.Order("level ASC, name ASC").Find(&classes).Error
+	return classes, err
+}
+
+// FindEnrollments returns every enrollment of the class, including students who
+// have left it, so past days keep their students.
+func (r *attendanceRepository) FindEnrollments(classId uuid.UUID) ([]schemas.ClassEnrollment, error) {
+	var enrollments []schemas.ClassEnrollment
+	err := r.db.Preload("StudentProfile").Preload("StudentProfile.User").
+		Where("class_id = ?", classId).
+		Find(&enrollments).Error
+	return enrollments, err
+}
+
+func (r *attendanceRepository) FindTeacherByUser(unitId, userId uuid.UUID) (*schemas.TeacherProfile, error) {
+	var teacher schemas.TeacherProfile
+	err := r.db.First(&teacher, "unit_id = ? AND user_id = ?", unitId, userId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &teacher, nil
+}
+
+func (r *attendanceRepository) FindMember(unitId, userId uuid.UUID) (*schemas.UnitMember, error) {
+	var member schemas.UnitMember
+	err := r.db.First(&member, "unit_id = ? AND user_id = ? AND is_active = ? AND approval_status = ?",
+		unitId, userId, true, schemas.UnitMemberApprovalApproved).Error
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
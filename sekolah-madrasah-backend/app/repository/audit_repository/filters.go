@@ -0,0 +1,9 @@
+package audit_repository
+
+import "github.com/google/uuid"
+
+type AuditLogFilter struct {
+	ActorId *uuid.UUID
+	UserId  *uuid.UUID
+	Action  *string
+}
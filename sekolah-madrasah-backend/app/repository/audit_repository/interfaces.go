@@ -0,0 +1,12 @@
+package audit_repository
+
+import (
+	"context"
+
+	"sekolah-madrasah/pkg/paginate_utils"
+)
+
+type AuditRepository interface {
+	CreateAuditLog(ctx context.Context, log AuditLog) (int, error)
+	GetAuditLogs(ctx context.Context, filter AuditLogFilter, paginate *paginate_utils.PaginateData) ([]AuditLog, int, error)
+}
@@ -0,0 +1,30 @@
+package audit_repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditLog struct {
+	Id        uuid.UUID
+	ActorId   *uuid.UUID
+	UserId    *uuid.UUID
+	Action    string
+	IpAddress string
+	// Details is a JSON object describing the action
+	Details   string
+	CreatedAt time.Time
+}
+
+// Actions written to the audit log
+const (
+	ActionAccountLocked   = "auth.account_locked"
+	ActionAccountUnlocked = "auth.account_unlocked"
+	ActionApiKeyCreated   = "api_key.created"
+	ActionApiKeyRevoked   = "api_key.revoked"
+
+	ActionImpersonationStarted = "impersonation.started"
+	// ActionImpersonationRequest is written for every request made while impersonating
+	ActionImpersonationRequest = "impersonation.request"
+)
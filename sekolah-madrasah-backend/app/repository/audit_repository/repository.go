@@ -0,0 +1,92 @@
+package audit_repository
+
+import (
+	"context"
+	"net/http"
+
+	"sekolah-madrasah/app/repository/common"
+	"sekolah-madrasah/database/schemas"
+	"sekolah-madrasah/pkg/paginate_utils"
+
+	"gorm.io/gorm"
+)
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) applyFilter(query *gorm.DB, filter AuditLogFilter) *gorm.DB {
+	if filter.ActorId != nil {
+		query = query.Where("actor_id = ?", *filter.ActorId)
+	}
+	if filter.UserId != nil {
+		query = query.Where("user_id = ?", *filter.UserId)
+	}
+	if filter.Action != nil {
+		query = query.Where("action = ?", *filter.Action)
+	}
+	return query
+}
+
+func (r *auditRepository) toModel(schema schemas.AuditLog) AuditLog {
+	return AuditLog{
+		Id:        schema.Id,
+		ActorId:   schema.ActorId,
+		UserId:    schema.UserId,
+		Action:    schema.Action,
+		IpAddress: schema.IpAddress,
+		Details:   schema.Details,
+		CreatedAt: schema.CreatedAt,
+	}
+}
+
+func (r *auditRepository) CreateAuditLog(ctx context.Context, log AuditLog) (int, error) {
+	details := log.Details
+	if details == "" {
+		details = "{}"
+	}
+
+	schema := schemas.AuditLog{
+		Id:        log.Id,
+		ActorId:   log.ActorId,
+		UserId:    log.UserId,
+		Action:    log.Action,
+		IpAddress: log.IpAddress,
+		Details:   details,
+	}
+
+	if err := r.db.WithContext(ctx).Create(&schema).Error; err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return http.StatusCreated, nil
+}
+
+func (r *auditRepository) GetAuditLogs(ctx context.Context, filter AuditLogFilter, paginate *paginate_utils.PaginateData) ([]AuditLog, int, error) {
+	var schemaList []schemas.AuditLog
+	query := r.db.WithContext(ctx).Model(&schemas.AuditLog{})
+	query = r.applyFilter(query, filter)
+
+	if err := common.CountTotal(query, paginate); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	query = common.ApplyPagination(query, paginate)
+	query = common.ApplyOrderBy(query, "created_at DESC")
+
+	if err := query.Find(&schemaList).Error; err != nil {
+		code, err := common.HandleGORMError(err)
+		return nil, code, err
+	}
+
+	logs := make([]AuditLog, len(schemaList))
+	for i, s := range schemaList {
+		logs[i] = r.toModel(s)
+	}
+
+	return logs, http.StatusOK, nil
+}
@@ -0,0 +1,257 @@
+package gradebook_repository
+
+import (
+	"errors"
+	"time"
+
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errStale rolls back a batch of scores once one of them turns out stale.
+var errStale = errors.New("stale scores")
+
+type GradebookRepository interface {
+	FindAssessment(id uuid.UUID) (*schemas.Assessment, error)
+	FindAssessments(classId, subjectId uuid.UUID, academicYear string, semester int) ([]schemas.Assessment, error)
+	// FindClassAssessments lists a class's assessments in every subject for the
+	// term, with their subject.
+	FindClassAssessments(classId uuid.UUID, academicYear string, semester int) ([]schemas.Assessment, error)
+	CreateAssessment(assessment *schemas.Assessment) (*schemas.Assessment, error)
+	UpdateAssessment(assessment *schemas.Assessment) (*schemas.Assessment, error)
+	DeleteAssessment(id uuid.UUID) error
+	FindScores(assessmentIds []uuid.UUID) ([]schemas.AssessmentScore, error)
+	// SaveScores stores the scores in one transaction. Each score's Version is
+	// the version it was read at, 0 for a new score; when any of them was
+	// changed in the meantime nothing is saved and the enrollments of the stale
+	// scores are returned.
+	SaveScores(scores []schemas.AssessmentScore) ([]uuid.UUID, error)
+	// Passing thresholds
+	FindThresholds(unitId uuid.UUID) ([]schemas.GradeThreshold, error)
+	FindThreshold(id uuid.UUID) (*schemas.GradeThreshold, error)
+	CreateThreshold(threshold *schemas.GradeThreshold) (*schemas.GradeThreshold, error)
+	UpdateThreshold(threshold *schemas.GradeThreshold) (*schemas.GradeThreshold, error)
+	DeleteThreshold(id uuid.UUID) error
+	// Lookups
+	FindSettings(unitId uuid.UUID) (*schemas.UnitSettings, error)
+	FindClass(id uuid.UUID) (*schemas.Class, error)
+	FindSubject(id uuid.UUID) (*schemas.Subject, error)
+	FindClassSubject(classId, subjectId uuid.UUID) (*schemas.ClassSubject, error)
+	FindTeacherByUser(unitId, userId uuid.UUID) (*schemas.TeacherProfile, error)
+	TeachesSubject(teacherId, subjectId uuid.UUID) (bool, error)
+	FindEnrollments(classId uuid.UUID) ([]schemas.ClassEnrollment, error)
+	// TermLocked reports whether any report card of the class for the term has
+	// been finalized or published, which freezes its grades.
+	TermLocked(classId uuid.UUID, academicYear string, semester int) (bool, error)
+}
+
+type gradebookRepository struct {
+	db *gorm.DB
+}
+
+func NewGradebookRepository(db *gorm.DB) GradebookRepository {
+	return &gradebookRepository{db: db}
+}
+
+func (r *gradebookRepository) FindAssessment(id uuid.UUID) (*schemas.Assessment, error) {
+	var assessment schemas.Assessment
+	err := r.db.First(&assessment, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &assessment, nil
+}
+
+func (r *gradebookRepository) FindAssessments(classId, subjectId uuid.UUID, academicYear string, semester int) ([]schemas.Assessment, error) {
+	var assessments []schemas.Assessment
+	err := r.db.Where("class_id = ? AND subject_id = ? AND academic_year = ? AND semester = ?", classId, subjectId, academicYear, semester).
+		Order("date ASC NULLS LAST, created_at ASC").
+		Find(&assessments).Error
+	return assessments, err
+}
+
+func (r *gradebookRepository) FindClassAssessments(classId uuid.UUID, academicYear string, semester int) ([]schemas.Assessment, error) {
+	var assessments []schemas.Assessment
+	err := r.db.Preload("Subject").
+		Where("class_id = ? AND academic_year = ? AND semester = ?", classId, academicYear, semester).
+		Order("date ASC NULLS LAST, created_at ASC").
+		Find(&assessments).Error
+	return assessments, err
+}
+
+func (r *gradebookRepository) CreateAssessment(assessment *schemas.Assessment) (*schemas.Assessment, error) {
+	err := r.db.Create(assessment).Error
+	if err != nil {
+		return nil, err
+	}
+	return assessment, nil
+}
+
+func (r *gradebookRepository) UpdateAssessment(assessment *schemas.Assessment) (*schemas.Assessment, error) {
+	err := r.db.Omit("Class", "Subject").Save(assessment).Error
+	if err != nil {
+		return nil, err
+	}
+	return assessment, nil
+}
+
+func (r *gradebookRepository) DeleteAssessment(id uuid.UUID) error {
+	return r.db.Delete(&schemas.Assessment{}, "id = ?", id).Error
+}
+
+func (r *gradebookRepository) FindScores(assessmentIds []uuid.UUID) ([]schemas.AssessmentScore, error) {
+	var scores []schemas.AssessmentScore
+	if len(assessmentIds) == 0 {
+		return scores, nil
+	}
+	err := r.db.Where("assessment_id IN ?", assessmentIds).Find(&scores).Error
+	return scores, err
+}
+
+func (r *gradebookRepository) SaveScores(scores []schemas.AssessmentScore) ([]uuid.UUID, error) {
+	var stale []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for i := range scores {
+			score := scores[i]
+			var result *gorm.DB
+			if score.Version == 0 {
+				score.Version = 1
+				result = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&score)
+			} else {
+				result = tx.Model(&schemas.AssessmentScore{}).
+					Where("assessment_id = ? AND class_enrollment_id = ? AND version = ?", score.AssessmentId, score.ClassEnrollmentId, score.Version).
+					Updates(map[string]interface{}{
+						"score":       score.Score,
+						"note":        score.Note,
+						"recorded_by": score.RecordedBy,
+						"version":     gorm.Expr("version + 1"),
+						"updated_at":  time.Now(),
+					})
+			}
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				stale = append(stale, score.ClassEnrollmentId)
+			}
+		}
+		if len(stale) > 0 {
+			return errStale
+		}
+		return nil
+	})
+	if errors.Is(err, errStale) {
+		return stale, nil
+	}
+	return nil, err
+}
+
+func (r *gradebookRepository) FindThresholds(unitId uuid.UUID) ([]schemas.GradeThreshold, error) {
+	var thresholds []schemas.GradeThreshold
+	err := r.db.Preload("Subject").
+		Where("unit_id = ?", unitId).
+		Order("subject_id NULLS FIRST, level NULLS FIRST").
+		Find(&thresholds).Error
+	return thresholds, err
+}
+
+func (r *gradebookRepository) FindThreshold(id uuid.UUID) (*schemas.GradeThreshold, error) {
+	var threshold schemas.GradeThreshold
+	err := r.db.First(&threshold, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &threshold, nil
+}
+
+func (r *gradebookRepository) CreateThreshold(threshold *schemas.GradeThreshold) (*schemas.GradeThreshold, error) {
+	err := r.db.Create(threshold).Error
+	if err != nil {
+		return nil, err
+	}
+	return threshold, nil
+}
+
+func (r *gradebookRepository) UpdateThreshold(threshold *schemas.GradeThreshold) (*schemas.GradeThreshold, error) {
+	err := r.db.Omit("Subject").Save(threshold).Error
+	if err != nil {
+		return nil, err
+	}
+	return threshold, nil
+}
+
+func (r *gradebookRepository) DeleteThreshold(id uuid.UUID) error {
+	return r.db.Delete(&schemas.GradeThreshold{}, "id = ?", id).Error
+}
+
+func (r *gradebookRepository) FindSettings(unitId uuid.UUID) (*schemas.UnitSettings, error) {
+	var settings schemas.UnitSettings
+	err := r.db.First(&settings, "unit_id = ?", unitId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (r *gradebookRepository) FindClass(id uuid.UUID) (*schemas.Class, error) {
+	var class schemas.Class
+	err := r.db.First(&class, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &class, nil
+}
+
+func (r *gradebookRepository) FindSubject(id uuid.UUID) (*schemas.Subject, error) {
+	var subject schemas.Subject
+	err := r.db.First(&subject, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &subject, nil
+}
+
+func (r *gradebookRepository) FindClassSubject(classId, subjectId uuid.UUID) (*schemas.ClassSubject, error) {
+	var classSubject schemas.ClassSubject
+	err := r.db.First(&classSubject, "class_id = ? AND subject_id = ?", classId, subjectId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &classSubject, nil
+}
+
+func (r *gradebookRepository) FindTeacherByUser(unitId, userId uuid.UUID) (*schemas.TeacherProfile, error) {
+	var teacher schemas.TeacherProfile
+	err := r.db.First(&teacher, "unit_id = ? AND user_id = ?", unitId, userId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &teacher, nil
+}
+
+func (r *gradebookRepository) TeachesSubject(teacherId, subjectId uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Model(&schemas.TeacherSubject{}).
+		Where("teacher_profile_id = ? AND subject_id = ?", teacherId, subjectId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *gradebookRepository) FindEnrollments(classId uuid.UUID) ([]schemas.ClassEnrollment, error) {
+	var enrollments []schemas.ClassEnrollment
+	err := r.db.Preload("StudentProfile").Preload("StudentProfile.User").
+		Where("class_id = ?", classId).
+		Find(&enrollments).Error
+	return enrollments, err
+}
+
+func (r *gradebookRepository) TermLocked(classId uuid.UUID, academicYear string, semester int) (bool, error) {
+	var count int64
+	err := r.db.Model(&schemas.ReportCard{}).
+		Where("class_id = ? AND academic_year = ? AND semester = ? AND status <> ?", classId, academicYear, semester, schemas.ReportCardDraft).
+		Count(&count).Error
+	return count > 0, err
+}
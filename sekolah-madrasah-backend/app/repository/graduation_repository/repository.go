@@ -0,0 +1,231 @@
+package graduation_repository
+
+import (
+	"errors"
+	"time"
+
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrAlreadyGraduated is returned by Graduate when one of the enrollments is no
+// longer active or one of the students already has a graduation, both of which
+// happen when the class was graduated by another request meanwhile.
+var ErrAlreadyGraduated = errors.New("student has already graduated")
+
+// AlumniFilter narrows the alumni directory of a unit.
+type AlumniFilter struct {
+	AcademicYear string // Year of graduation, empty = every year
+	Search       string // Matches the name, NIS, NISN or certificate number
+	Page         int
+	Limit        int
+}
+
+// AlumniYear counts the graduates of one academic year.
+type AlumniYear struct {
+	AcademicYear string `json:"academic_year"`
+	Graduates    int64  `json:"graduates"`
+}
+
+type GraduationRepository interface {
+	FindClass(unitId, classId uuid.UUID) (*schemas.Class, error)
+	// FindFinalLevel returns the highest level among the unit's classes of the academic year.
+	FindFinalLevel(unitId uuid.UUID, academicYear string) (int, error)
+	// FindActiveEnrollments lists the students still active in the class, with their profile.
+	FindActiveEnrollments(classId uuid.UUID) ([]schemas.ClassEnrollment, error)
+	// FindMembers returns the student memberships (role anggota) of the users in the unit.
+	FindMembers(unitId uuid.UUID, userIds []uuid.UUID) ([]schemas.UnitMember, error)
+	// FindCertificates returns which of the certificate numbers the unit has
+	// already issued, leaving out the graduation being edited.
+	FindCertificates(unitId uuid.UUID, numbers []string, exceptId *uuid.UUID) ([]string, error)
+	// Graduate records the graduations, closes the enrollments as graduated,
+	// marks the students as alumni and updates their memberships in one
+	// transaction; nothing is saved if any of it fails. It fails with
+	// ErrAlreadyGraduated when an enrollment was closed or a student graduated
+	// meanwhile.
+	Graduate(graduations []schemas.Graduation, closed []schemas.ClassEnrollment, members []schemas.UnitMember) error
+
+	FindGraduation(id uuid.UUID) (*schemas.Graduation, error)
+	UpdateGraduation(graduation *schemas.Graduation) error
+	FindAlumni(unitId uuid.UUID, filter AlumniFilter) ([]schemas.Graduation, int64, error)
+	FindAlumniYears(unitId uuid.UUID) ([]AlumniYear, error)
+}
+
+type graduationRepository struct {
+	db *gorm.DB
+}
+
+func NewGraduationRepository(db *gorm.DB) GraduationRepository {
+	return &graduationRepository{db: db}
+}
+
+func (r *graduationRepository) FindClass(unitId, classId uuid.UUID) (*schemas.Class, error) {
+	var class schemas.Class
+	err := r.db.Where("id = ? AND unit_id = ?", classId, unitId).First(&class).Error
+	if err != nil {
+		return nil, err
+	}
+	return &class, nil
+}
+
+func (r *graduationRepository) FindFinalLevel(unitId uuid.UUID, academicYear string) (int, error) {
+	var level int
+	err := r.db.Model(&schemas.Class{}).
+		Where("unit_id = ? AND academic_year = ?", unitId, academicYear).
+		Select("COALESCE(MAX(level), 0)").
+		Scan(&level).Error
+	return level, err
+}
+
+func (r *graduationRepository) FindActiveEnrollments(classId uuid.UUID) ([]schemas.ClassEnrollment, error) {
+	var enrollments []schemas.ClassEnrollment
+	err := r.db.Preload("StudentProfile").Preload("StudentProfile.User").
+		Where("class_id = ? AND status = ? AND left_at IS NULL", classId, schemas.EnrollmentStatusActive).
+		Find(&enrollments).Error
+	return enrollments, err
+}
+
+func (r *graduationRepository) FindMembers(unitId uuid.UUID, userIds []uuid.UUID) ([]schemas.UnitMember, error) {
+	var members []schemas.UnitMember
+	if len(userIds) == 0 {
+		return members, nil
+	}
+	err := r.db.Where("unit_id = ? AND user_id IN ? AND role = ?", unitId, userIds, schemas.UnitMemberRoleAnggota).
+		Find(&members).Error
+	return members, err
+}
+
+func (r *graduationRepository) FindCertificates(unitId uuid.UUID, numbers []string, exceptId *uuid.UUID) ([]string, error) {
+	var issued []string
+	if len(numbers) == 0 {
+		return issued, nil
+	}
+	query := r.db.Model(&schemas.Graduation{}).
+		Where("unit_id = ? AND certificate_number IN ?", unitId, numbers)
+	if exceptId != nil {
+		query = query.Where("id <> ?", *exceptId)
+	}
+	err := query.Pluck("certificate_number", &issued).Error
+	return issued, err
+}
+
+func (r *graduationRepository) Graduate(graduations []schemas.Graduation, closed []schemas.ClassEnrollment, members []schemas.UnitMember) error {
+	profileIds := make([]uuid.UUID, 0, len(graduations))
+	for _, graduation := range graduations {
+		profileIds = append(profileIds, graduation.StudentProfileId)
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, enrollment := range closed {
+			result := tx.Model(&schemas.ClassEnrollment{}).
+				Where("id = ? AND status = ? AND left_at IS NULL", enrollment.Id, schemas.EnrollmentStatusActive).
+				Updates(map[string]interface{}{
+					"status":     enrollment.Status,
+					"left_at":    enrollment.LeftAt,
+					"notes":      enrollment.Notes,
+					"updated_at": time.Now(),
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrAlreadyGraduated
+			}
+		}
+		if len(graduations) > 0 {
+			if err := tx.Omit("StudentProfile", "Class").Create(&graduations).Error; err != nil {
+				return err
+			}
+		}
+		if len(profileIds) > 0 {
+			err := tx.Model(&schemas.StudentProfile{}).
+				Where("id IN ?", profileIds).
+				Updates(map[string]interface{}{
+					"status":     schemas.StudentStatusAlumni,
+					"updated_at": time.Now(),
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		for _, member := range members {
+			err := tx.Model(&schemas.UnitMember{}).
+				Where("id = ?", member.Id).
+				Updates(map[string]interface{}{
+					"role":       member.Role,
+					"is_active":  member.IsActive,
+					"updated_at": time.Now(),
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// Either a student graduated before or a certificate number was
+		// issued meanwhile; only the former is told apart here
+		var graduated int64
+		if r.db.Model(&schemas.Graduation{}).Where("student_profile_id IN ?", profileIds).Count(&graduated).Error == nil && graduated > 0 {
+			return ErrAlreadyGraduated
+		}
+	}
+	return err
+}
+
+func (r *graduationRepository) FindGraduation(id uuid.UUID) (*schemas.Graduation, error) {
+	var graduation schemas.Graduation
+	err := r.db.Preload("StudentProfile").Preload("StudentProfile.User").Preload("Class").
+		Where("id = ?", id).First(&graduation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &graduation, nil
+}
+
+func (r *graduationRepository) UpdateGraduation(graduation *schemas.Graduation) error {
+	return r.db.Model(graduation).
+		Select("graduation_date", "certificate_number", "final_score", "notes", "updated_at").
+		Updates(graduation).Error
+}
+
+func (r *graduationRepository) FindAlumni(unitId uuid.UUID, filter AlumniFilter) ([]schemas.Graduation, int64, error) {
+	var graduations []schemas.Graduation
+	var total int64
+
+	query := r.db.Model(&schemas.Graduation{}).
+		Joins("JOIN student_profiles ON student_profiles.id = graduations.student_profile_id").
+		Joins("JOIN users ON users.id = student_profiles.user_id").
+		Where("graduations.unit_id = ?", unitId)
+	if filter.AcademicYear != "" {
+		query = query.Where("graduations.academic_year = ?", filter.AcademicYear)
+	}
+	if filter.Search != "" {
+		like := "%" + filter.Search + "%"
+		query = query.Where("users.full_name ILIKE ? OR student_profiles.nis ILIKE ? OR student_profiles.nisn ILIKE ? OR graduations.certificate_number ILIKE ?",
+			like, like, like, like)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.Limit
+	err := query.Preload("StudentProfile").Preload("StudentProfile.User").Preload("Class").
+		Order("graduations.academic_year DESC, users.full_name ASC").
+		Offset(offset).Limit(filter.Limit).
+		Find(&graduations).Error
+	return graduations, total, err
+}
+
+func (r *graduationRepository) FindAlumniYears(unitId uuid.UUID) ([]AlumniYear, error) {
+	var years []AlumniYear
+	err := r.db.Model(&schemas.Graduation{}).
+		Select("academic_year, COUNT(*) AS graduates").
+		Where("unit_id = ?", unitId).
+		Group("academic_year").
+		Order("academic_year DESC").
+		Scan(&years).Error
+	return years, err
+}
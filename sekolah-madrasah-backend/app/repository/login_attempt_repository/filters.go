@@ -0,0 +1,11 @@
+package login_attempt_repository
+
+import "time"
+
+type LoginAttemptFilter struct {
+	Email     *string
+	IpAddress *string
+	Success   *bool
+	// Since limits results to attempts made at or after this time
+	Since *time.Time
+}
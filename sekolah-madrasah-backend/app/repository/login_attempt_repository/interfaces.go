@@ -0,0 +1,8 @@
+package login_attempt_repository
+
+import "context"
+
+type LoginAttemptRepository interface {
+	CreateLoginAttempt(ctx context.Context, attempt LoginAttempt) (int, error)
+	GetLoginAttemptStats(ctx context.Context, filter LoginAttemptFilter) (LoginAttemptStats, int, error)
+}
@@ -0,0 +1,24 @@
+package login_attempt_repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type LoginAttempt struct {
+	Id        uuid.UUID
+	Email     string
+	IpAddress string
+	UserId    *uuid.UUID
+	Success   bool
+	CreatedAt time.Time
+}
+
+// LoginAttemptStats summarizes the attempts matching a filter. First and Last are
+// nil when Count is zero.
+type LoginAttemptStats struct {
+	Count int64
+	First *time.Time
+	Last  *time.Time
+}
@@ -0,0 +1,64 @@
+package login_attempt_repository
+
+import (
+	"context"
+	"net/http"
+
+	"sekolah-madrasah/database/schemas"
+
+	"gorm.io/gorm"
+)
+
+type loginAttemptRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+func (r *loginAttemptRepository) applyFilter(query *gorm.DB, filter LoginAttemptFilter) *gorm.DB {
+	if filter.Email != nil {
+		query = query.Where("email = ?", *filter.Email)
+	}
+	if filter.IpAddress != nil {
+		query = query.Where("ip_address = ?", *filter.IpAddress)
+	}
+	if filter.Success != nil {
+		query = query.Where("success = ?", *filter.Success)
+	}
+	if filter.Since != nil {
+		query = query.Where("created_at >= ?", *filter.Since)
+	}
+	return query
+}
+
+func (r *loginAttemptRepository) CreateLoginAttempt(ctx context.Context, attempt LoginAttempt) (int, error) {
+	schema := schemas.LoginAttempt{
+		Id:        attempt.Id,
+		Email:     attempt.Email,
+		IpAddress: attempt.IpAddress,
+		UserId:    attempt.UserId,
+		Success:   attempt.Success,
+	}
+
+	if err := r.db.WithContext(ctx).Create(&schema).Error; err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return http.StatusCreated, nil
+}
+
+func (r *loginAttemptRepository) GetLoginAttemptStats(ctx context.Context, filter LoginAttemptFilter) (LoginAttemptStats, int, error) {
+	var stats LoginAttemptStats
+	query := r.db.WithContext(ctx).Model(&schemas.LoginAttempt{})
+	query = r.applyFilter(query, filter)
+
+	err := query.Select("COUNT(*) AS count, MIN(created_at) AS first, MAX(created_at) AS last").
+		Scan(&stats).Error
+	if err != nil {
+		return LoginAttemptStats{}, http.StatusInternalServerError, err
+	}
+
+	return stats, http.StatusOK, nil
+}
@@ -353,6 +353,9 @@ func (r *postRepository) GetPollOptions(ctx context.Context, filter PostPollOpti
 	var schemaList []schemas.PostPollOption
 	query := r.db.WithContext(ctx).Model(&schemas.PostPollOption{})
 
+	if filter.Id != nil {
+		query = query.Where("id = ?", *filter.Id)
+	}
 	if filter.PostId != nil {
 		query = query.Where("post_id = ?", *filter.PostId)
 	}
@@ -0,0 +1,253 @@
+package report_card_repository
+
+import (
+	"errors"
+	"time"
+
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrStatusChanged is returned when a report card was created, or moved out
+// of the status it was saved from, by another request meanwhile.
+var ErrStatusChanged = errors.New("report card status changed meanwhile")
+
+// AttendanceCount is how many school days a student spent in one status.
+type AttendanceCount struct {
+	Status schemas.AttendanceStatus
+	Count  int
+}
+
+type ReportCardRepository interface {
+	FindReportCard(enrollmentId uuid.UUID, semester int) (*schemas.ReportCard, error)
+	FindClassReportCards(classId uuid.UUID, academicYear string, semester int) ([]schemas.ReportCard, error)
+	// SaveReportCard creates the report card, or updates it while its stored
+	// status is still from, replacing its stored subjects and activities with
+	// the ones it carries. It fails with ErrStatusChanged otherwise.
+	SaveReportCard(card *schemas.ReportCard, from schemas.ReportCardStatus) (*schemas.ReportCard, error)
+	FindTemplate(unitId uuid.UUID) (*schemas.ReportCardTemplate, error)
+	SaveTemplate(template *schemas.ReportCardTemplate) (*schemas.ReportCardTemplate, error)
+	// Lookups
+	FindUnit(id uuid.UUID) (*schemas.Unit, error)
+	FindSettings(unitId uuid.UUID) (*schemas.UnitSettings, error)
+	FindClass(id uuid.UUID) (*schemas.Class, error)
+	FindEnrollment(id uuid.UUID) (*schemas.ClassEnrollment, error)
+	FindEnrollments(classId uuid.UUID) ([]schemas.ClassEnrollment, error)
+	FindTeacherByUser(unitId, userId uuid.UUID) (*schemas.TeacherProfile, error)
+	FindClassSubjects(classId uuid.UUID) ([]schemas.ClassSubject, error)
+	FindAssessments(classId uuid.UUID, academicYear string, semester int) ([]schemas.Assessment, error)
+	FindScores(enrollmentId uuid.UUID, assessmentIds []uuid.UUID) ([]schemas.AssessmentScore, error)
+	FindThresholds(unitId uuid.UUID) ([]schemas.GradeThreshold, error)
+	// CountAttendance counts the enrollment's school days per status, within
+	// the dates when given.
+	CountAttendance(enrollmentId uuid.UUID, from, to *time.Time) ([]AttendanceCount, error)
+	FindActivities(unitId, studentProfileId uuid.UUID) ([]schemas.ActivityStudent, error)
+}
+
+type reportCardRepository struct {
+	db *gorm.DB
+}
+
+func NewReportCardRepository(db *gorm.DB) ReportCardRepository {
+	return &reportCardRepository{db: db}
+}
+
+func (r *reportCardRepository) FindReportCard(enrollmentId uuid.UUID, semester int) (*schemas.ReportCard, error) {
+	var card schemas.ReportCard
+	err := r.db.Preload("Subjects", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") }).
+		Preload("Activities", func(db *gorm.DB) *gorm.DB { return db.Order("activity_name ASC") }).
+		First(&card, "class_enrollment_id = ? AND semester = ?", enrollmentId, semester).Error
+	if err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+func (r *reportCardRepository) FindClassReportCards(classId uuid.UUID, academicYear string, semester int) ([]schemas.ReportCard, error) {
+	var cards []schemas.ReportCard
+	err := r.db.Where("class_id = ? AND academic_year = ? AND semester = ?", classId, academicYear, semester).
+		Find(&cards).Error
+	return cards, err
+}
+
+func (r *reportCardRepository) SaveReportCard(card *schemas.ReportCard, from schemas.ReportCardStatus) (*schemas.ReportCard, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if card.Id == uuid.Nil {
+			if err := tx.Omit("Subjects", "Activities", "ClassEnrollment").Create(card).Error; err != nil {
+				if errors.Is(err, gorm.ErrDuplicatedKey) {
+					return ErrStatusChanged
+				}
+				return err
+			}
+		} else {
+			result := tx.Model(card).Where("status = ?", from).
+				Select("*").Omit("id", "created_at", "Subjects", "Activities", "ClassEnrollment").
+				Updates(card)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrStatusChanged
+			}
+		}
+		if err := tx.Where("report_card_id = ?", card.Id).Delete(&schemas.ReportCardSubject{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("report_card_id = ?", card.Id).Delete(&schemas.ReportCardActivity{}).Error; err != nil {
+			return err
+		}
+		for i := range card.Subjects {
+			card.Subjects[i].Id = uuid.Nil
+			card.Subjects[i].ReportCardId = card.Id
+		}
+		for i := range card.Activities {
+			card.Activities[i].Id = uuid.Nil
+			card.Activities[i].ReportCardId = card.Id
+		}
+		if len(card.Subjects) > 0 {
+			if err := tx.Create(&card.Subjects).Error; err != nil {
+				return err
+			}
+		}
+		if len(card.Activities) > 0 {
+			if err := tx.Create(&card.Activities).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+func (r *reportCardRepository) FindTemplate(unitId uuid.UUID) (*schemas.ReportCardTemplate, error) {
+	var template schemas.ReportCardTemplate
+	err := r.db.First(&template, "unit_id = ?", unitId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *reportCardRepository) SaveTemplate(template *schemas.ReportCardTemplate) (*schemas.ReportCardTemplate, error) {
+	err := r.db.Save(template).Error
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func (r *reportCardRepository) FindUnit(id uuid.UUID) (*schemas.Unit, error) {
+	var unit schemas.Unit
+	err := r.db.First(&unit, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &unit, nil
+}
+
+func (r *reportCardRepository) FindSettings(unitId uuid.UUID) (*schemas.UnitSettings, error) {
+	var settings schemas.UnitSettings
+	err := r.db.First(&settings, "unit_id = ?", unitId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (r *reportCardRepository) FindClass(id uuid.UUID) (*schemas.Class, error) {
+	var class schemas.Class
+	err := r.db.Preload("HomeroomTeacher").Preload("HomeroomTeacher.User").
+		First(&class, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &class, nil
+}
+
+func (r *reportCardRepository) FindEnrollment(id uuid.UUID) (*schemas.ClassEnrollment, error) {
+	var enrollment schemas.ClassEnrollment
+	err := r.db.Preload("StudentProfile").Preload("StudentProfile.User").
+		First(&enrollment, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &enrollment, nil
+}
+
+func (r *reportCardRepository) FindEnrollments(classId uuid.UUID) ([]schemas.ClassEnrollment, error) {
+	var enrollments []schemas.ClassEnrollment
+	err := r.db.Preload("StudentProfile").Preload("StudentProfile.User").
+		Where("class_id = ?", classId).
+		Find(&enrollments).Error
+	return enrollments, err
+}
+
+func (r *reportCardRepository) FindTeacherByUser(unitId, userId uuid.UUID) (*schemas.TeacherProfile, error) {
+	var teacher schemas.TeacherProfile
+	err := r.db.First(&teacher, "unit_id = ? AND user_id = ?", unitId, userId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &teacher, nil
+}
+
+func (r *reportCardRepository) FindClassSubjects(classId uuid.UUID) ([]schemas.ClassSubject, error) {
+	var classSubjects []schemas.ClassSubject
+	err := r.db.Preload("Subject").Where("class_id = ?", classId).Find(&classSubjects).Error
+	return classSubjects, err
+}
+
+func (r *reportCardRepository) FindAssessments(classId uuid.UUID, academicYear string, semester int) ([]schemas.Assessment, error) {
+	var assessments []schemas.Assessment
+	err := r.db.Preload("Subject").
+		Where("class_id = ? AND academic_year = ? AND semester = ?", classId, academicYear, semester).
+		Order("date ASC NULLS LAST, created_at ASC").
+		Find(&assessments).Error
+	return assessments, err
+}
+
+func (r *reportCardRepository) FindScores(enrollmentId uuid.UUID, assessmentIds []uuid.UUID) ([]schemas.AssessmentScore, error) {
+	var scores []schemas.AssessmentScore
+	if len(assessmentIds) == 0 {
+		return scores, nil
+	}
+	err := r.db.Where("class_enrollment_id = ? AND assessment_id IN ?", enrollmentId, assessmentIds).
+		Find(&scores).Error
+	return scores, err
+}
+
+func (r *reportCardRepository) FindThresholds(unitId uuid.UUID) ([]schemas.GradeThreshold, error) {
+	var thresholds []schemas.GradeThreshold
+	err := r.db.Where("unit_id = ?", unitId).Find(&thresholds).Error
+	return thresholds, err
+}
+
+func (r *reportCardRepository) CountAttendance(enrollmentId uuid.UUID, from, to *time.Time) ([]AttendanceCount, error) {
+	var counts []AttendanceCount
+	query := r.db.Model(&schemas.DailyAttendance{}).
+		Select("status, COUNT(*) AS count").
+		Where("class_enrollment_id = ?", enrollmentId)
+	if from != nil {
+		query = query.Where("date >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("date <= ?", *to)
+	}
+	err := query.Group("status").Scan(&counts).Error
+	return counts, err
+}
+
+func (r *reportCardRepository) FindActivities(unitId, studentProfileId uuid.UUID) ([]schemas.ActivityStudent, error) {
+	var participations []schemas.ActivityStudent
+	err := r.db.Preload("Activity").
+		Joins("JOIN activities ON activities.id = activity_students.activity_id AND activities.deleted_at IS NULL").
+		Where("activity_students.student_profile_id = ? AND activities.unit_id = ?", studentProfileId, unitId).
+		Order("activities.name ASC").
+		Find(&participations).Error
+	return participations, err
+}
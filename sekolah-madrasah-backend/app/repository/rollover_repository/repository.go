@@ -0,0 +1,94 @@
+package rollover_repository
+
+import (
+	"errors"
+	"time"
+
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrEnrollmentClosed means an enrollment to close was no longer active, for
+// instance because a concurrent rollover already closed it.
+var ErrEnrollmentClosed = errors.New("enrollment is no longer active")
+
+type RolloverRepository interface {
+	FindSettings(unitId uuid.UUID) (*schemas.UnitSettings, error)
+	FindClasses(unitId uuid.UUID, academicYear string) ([]schemas.Class, error)
+	// FindActiveEnrollments lists the students still active in the unit's
+	// classes of the academic year, with their profile and class.
+	FindActiveEnrollments(unitId uuid.UUID, academicYear string) ([]schemas.ClassEnrollment, error)
+	// Apply creates the new classes, closes the old enrollments and opens the
+	// new ones in one transaction; nothing is saved if any of it fails,
+	// including an enrollment that is no longer active (ErrEnrollmentClosed).
+	Apply(classes []schemas.Class, closed []schemas.ClassEnrollment, opened []schemas.ClassEnrollment) error
+}
+
+type rolloverRepository struct {
+	db *gorm.DB
+}
+
+func NewRolloverRepository(db *gorm.DB) RolloverRepository {
+	return &rolloverRepository{db: db}
+}
+
+func (r *rolloverRepository) FindSettings(unitId uuid.UUID) (*schemas.UnitSettings, error) {
+	var settings schemas.UnitSettings
+	err := r.db.First(&settings, "unit_id = ?", unitId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (r *rolloverRepository) FindClasses(unitId uuid.UUID, academicYear string) ([]schemas.Class, error) {
+	var classes []schemas.Class
+	err := r.db.Where("unit_id = ? AND academic_year = ?", unitId, academicYear).
+		Order("level ASC, name ASC").
+		Find(&classes).Error
+	return classes, err
+}
+
+func (r *rolloverRepository) FindActiveEnrollments(unitId uuid.UUID, academicYear string) ([]schemas.ClassEnrollment, error) {
+	var enrollments []schemas.ClassEnrollment
+	err := r.db.Preload("StudentProfile").Preload("StudentProfile.User").Preload("Class").
+		Joins("JOIN classes ON classes.id = class_enrollments.class_id AND classes.deleted_at IS NULL").
+		Where("classes.unit_id = ? AND classes.academic_year = ?", unitId, academicYear).
+		Where("class_enrollments.status = ? AND class_enrollments.left_at IS NULL", schemas.EnrollmentStatusActive).
+		Find(&enrollments).Error
+	return enrollments, err
+}
+
+func (r *rolloverRepository) Apply(classes []schemas.Class, closed []schemas.ClassEnrollment, opened []schemas.ClassEnrollment) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if len(classes) > 0 {
+			if err := tx.Omit("Unit", "HomeroomTeacher").Create(&classes).Error; err != nil {
+				return err
+			}
+		}
+		for _, enrollment := range closed {
+			result := tx.Model(&schemas.ClassEnrollment{}).
+				Where("id = ? AND status = ? AND left_at IS NULL", enrollment.Id, schemas.EnrollmentStatusActive).
+				Updates(map[string]interface{}{
+					"status":     enrollment.Status,
+					"left_at":    enrollment.LeftAt,
+					"notes":      enrollment.Notes,
+					"updated_at": time.Now(),
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrEnrollmentClosed
+			}
+		}
+		if len(opened) > 0 {
+			if err := tx.Omit("StudentProfile", "Class").Create(&opened).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
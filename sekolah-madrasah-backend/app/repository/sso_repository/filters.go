@@ -0,0 +1,16 @@
+package sso_repository
+
+import "github.com/google/uuid"
+
+type SsoProviderFilter struct {
+	Id             *uuid.UUID
+	OrganizationId *uuid.UUID
+	IsEnabled      *bool
+}
+
+type UserIdentityFilter struct {
+	Id      *uuid.UUID
+	UserId  *uuid.UUID
+	Issuer  *string
+	Subject *string
+}
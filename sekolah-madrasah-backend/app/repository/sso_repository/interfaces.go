@@ -0,0 +1,25 @@
+package sso_repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SsoRepository interface {
+	GetProvider(ctx context.Context, filter SsoProviderFilter) (SsoProvider, int, error)
+	// SaveProvider creates or replaces the organization's provider. An empty
+	// ClientSecret keeps the stored one.
+	SaveProvider(ctx context.Context, provider SsoProvider) (SsoProvider, int, error)
+	DeleteProvider(ctx context.Context, filter SsoProviderFilter) (int, error)
+
+	CreateLoginState(ctx context.Context, state SsoLoginState) (int, error)
+	// ConsumeLoginState marks a pending state used. Unknown, used or expired
+	// states return 400.
+	ConsumeLoginState(ctx context.Context, stateHash string) (SsoLoginState, int, error)
+
+	GetIdentity(ctx context.Context, filter UserIdentityFilter) (UserIdentity, int, error)
+	CreateIdentity(ctx context.Context, identity UserIdentity) (UserIdentity, int, error)
+	TouchIdentity(ctx context.Context, id uuid.UUID, loginAt time.Time) (int, error)
+}
@@ -0,0 +1,54 @@
+package sso_repository
+
+import (
+	"time"
+
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+)
+
+type SsoProvider struct {
+	Id             uuid.UUID
+	OrganizationId uuid.UUID
+	Issuer         string
+	ClientId       string
+	// ClientSecret is the encrypted secret as stored
+	ClientSecret      string
+	RedirectUri       string
+	Scopes            []string
+	GroupsClaim       string
+	GroupRoleMappings []GroupRoleMapping
+	AllowProvisioning bool
+	IsEnabled         bool
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+type GroupRoleMapping struct {
+	Group  string                 `json:"group"`
+	UnitId uuid.UUID              `json:"unit_id"`
+	Role   schemas.UnitMemberRole `json:"role"`
+}
+
+type SsoLoginState struct {
+	Id             uuid.UUID
+	StateHash      string
+	OrganizationId uuid.UUID
+	CodeVerifier   string
+	Nonce          string
+	ExpiresAt      time.Time
+	UsedAt         *time.Time
+	CreatedAt      time.Time
+}
+
+type UserIdentity struct {
+	Id             uuid.UUID
+	UserId         uuid.UUID
+	OrganizationId uuid.UUID
+	Issuer         string
+	Subject        string
+	Email          string
+	LastLoginAt    *time.Time
+	CreatedAt      time.Time
+}
@@ -0,0 +1,249 @@
+package sso_repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"sekolah-madrasah/app/repository/common"
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// expiredStateRetention is how long used or expired login states are kept
+const expiredStateRetention = 24 * time.Hour
+
+type ssoRepository struct {
+	db *gorm.DB
+}
+
+func NewSsoRepository(db *gorm.DB) SsoRepository {
+	return &ssoRepository{db: db}
+}
+
+func (r *ssoRepository) applyProviderFilter(query *gorm.DB, filter SsoProviderFilter) *gorm.DB {
+	if filter.Id != nil {
+		query = query.Where("id = ?", *filter.Id)
+	}
+	if filter.OrganizationId != nil {
+		query = query.Where("organization_id = ?", *filter.OrganizationId)
+	}
+	if filter.IsEnabled != nil {
+		query = query.Where("is_enabled = ?", *filter.IsEnabled)
+	}
+	return query
+}
+
+func (r *ssoRepository) applyIdentityFilter(query *gorm.DB, filter UserIdentityFilter) *gorm.DB {
+	if filter.Id != nil {
+		query = query.Where("id = ?", *filter.Id)
+	}
+	if filter.UserId != nil {
+		query = query.Where("user_id = ?", *filter.UserId)
+	}
+	if filter.Issuer != nil {
+		query = query.Where("issuer = ?", *filter.Issuer)
+	}
+	if filter.Subject != nil {
+		query = query.Where("subject = ?", *filter.Subject)
+	}
+	return query
+}
+
+func (r *ssoRepository) toProvider(schema schemas.SsoProvider) SsoProvider {
+	var mappings []GroupRoleMapping
+	if err := json.Unmarshal([]byte(schema.GroupRoleMappings), &mappings); err != nil || mappings == nil {
+		mappings = []GroupRoleMapping{}
+	}
+
+	return SsoProvider{
+		Id:                schema.Id,
+		OrganizationId:    schema.OrganizationId,
+		Issuer:            schema.Issuer,
+		ClientId:          schema.ClientId,
+		ClientSecret:      schema.ClientSecret,
+		RedirectUri:       schema.RedirectUri,
+		Scopes:            strings.Fields(schema.Scopes),
+		GroupsClaim:       schema.GroupsClaim,
+		GroupRoleMappings: mappings,
+		AllowProvisioning: schema.AllowProvisioning,
+		IsEnabled:         schema.IsEnabled,
+		CreatedAt:         schema.CreatedAt,
+		UpdatedAt:         schema.UpdatedAt,
+	}
+}
+
+func (r *ssoRepository) toIdentity(schema schemas.UserIdentity) UserIdentity {
+	return UserIdentity{
+		Id:             schema.Id,
+		UserId:         schema.UserId,
+		OrganizationId: schema.OrganizationId,
+		Issuer:         schema.Issuer,
+		Subject:        schema.Subject,
+		Email:          schema.Email,
+		LastLoginAt:    schema.LastLoginAt,
+		CreatedAt:      schema.CreatedAt,
+	}
+}
+
+func (r *ssoRepository) GetProvider(ctx context.Context, filter SsoProviderFilter) (SsoProvider, int, error) {
+	var schema schemas.SsoProvider
+	query := r.db.WithContext(ctx).Model(&schemas.SsoProvider{})
+	query = r.applyProviderFilter(query, filter)
+
+	if err := query.First(&schema).Error; err != nil {
+		code, err := common.HandleGORMError(err)
+		return SsoProvider{}, code, err
+	}
+
+	return r.toProvider(schema), http.StatusOK, nil
+}
+
+func (r *ssoRepository) SaveProvider(ctx context.Context, provider SsoProvider) (SsoProvider, int, error) {
+	mappings, err := json.Marshal(provider.GroupRoleMappings)
+	if err != nil {
+		return SsoProvider{}, http.StatusInternalServerError, err
+	}
+
+	schema := schemas.SsoProvider{
+		OrganizationId:    provider.OrganizationId,
+		Issuer:            provider.Issuer,
+		ClientId:          provider.ClientId,
+		ClientSecret:      provider.ClientSecret,
+		RedirectUri:       provider.RedirectUri,
+		Scopes:            strings.Join(provider.Scopes, " "),
+		GroupsClaim:       provider.GroupsClaim,
+		GroupRoleMappings: string(mappings),
+		AllowProvisioning: provider.AllowProvisioning,
+		IsEnabled:         provider.IsEnabled,
+	}
+
+	columns := []string{"issuer", "client_id", "redirect_uri", "scopes", "groups_claim",
+		"group_role_mappings", "allow_provisioning", "is_enabled", "updated_at"}
+	if provider.ClientSecret != "" {
+		columns = append(columns, "client_secret")
+	}
+
+	err = r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "organization_id"}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).Create(&schema).Error
+	if err != nil {
+		return SsoProvider{}, http.StatusInternalServerError, err
+	}
+
+	return r.GetProvider(ctx, SsoProviderFilter{OrganizationId: &provider.OrganizationId})
+}
+
+func (r *ssoRepository) DeleteProvider(ctx context.Context, filter SsoProviderFilter) (int, error) {
+	query := r.db.WithContext(ctx)
+	query = r.applyProviderFilter(query, filter)
+
+	result := query.Delete(&schemas.SsoProvider{})
+	if result.Error != nil {
+		return http.StatusInternalServerError, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return http.StatusNotFound, gorm.ErrRecordNotFound
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *ssoRepository) CreateLoginState(ctx context.Context, state SsoLoginState) (int, error) {
+	schema := schemas.SsoLoginState{
+		StateHash:      state.StateHash,
+		OrganizationId: state.OrganizationId,
+		CodeVerifier:   state.CodeVerifier,
+		Nonce:          state.Nonce,
+		ExpiresAt:      state.ExpiresAt,
+	}
+
+	if err := r.db.WithContext(ctx).Create(&schema).Error; err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// Abandoned sign-ins leave states behind; prune them as new ones arrive
+	r.db.WithContext(ctx).
+		Where("expires_at < ?", time.Now().Add(-expiredStateRetention)).
+		Delete(&schemas.SsoLoginState{})
+
+	return http.StatusCreated, nil
+}
+
+func (r *ssoRepository) ConsumeLoginState(ctx context.Context, stateHash string) (SsoLoginState, int, error) {
+	var schema schemas.SsoLoginState
+	now := time.Now()
+
+	result := r.db.WithContext(ctx).Model(&schema).
+		Clauses(clause.Returning{}).
+		Where("state_hash = ? AND used_at IS NULL AND expires_at > ?", stateHash, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return SsoLoginState{}, http.StatusInternalServerError, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return SsoLoginState{}, http.StatusBadRequest, errors.New("invalid or expired sign-in state")
+	}
+
+	return SsoLoginState{
+		Id:             schema.Id,
+		StateHash:      schema.StateHash,
+		OrganizationId: schema.OrganizationId,
+		CodeVerifier:   schema.CodeVerifier,
+		Nonce:          schema.Nonce,
+		ExpiresAt:      schema.ExpiresAt,
+		UsedAt:         schema.UsedAt,
+		CreatedAt:      schema.CreatedAt,
+	}, http.StatusOK, nil
+}
+
+func (r *ssoRepository) GetIdentity(ctx context.Context, filter UserIdentityFilter) (UserIdentity, int, error) {
+	var schema schemas.UserIdentity
+	query := r.db.WithContext(ctx).Model(&schemas.UserIdentity{})
+	query = r.applyIdentityFilter(query, filter)
+
+	if err := query.First(&schema).Error; err != nil {
+		code, err := common.HandleGORMError(err)
+		return UserIdentity{}, code, err
+	}
+
+	return r.toIdentity(schema), http.StatusOK, nil
+}
+
+func (r *ssoRepository) CreateIdentity(ctx context.Context, identity UserIdentity) (UserIdentity, int, error) {
+	schema := schemas.UserIdentity{
+		UserId:         identity.UserId,
+		OrganizationId: identity.OrganizationId,
+		Issuer:         identity.Issuer,
+		Subject:        identity.Subject,
+		Email:          identity.Email,
+		LastLoginAt:    identity.LastLoginAt,
+	}
+
+	if err := r.db.WithContext(ctx).Create(&schema).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return UserIdentity{}, http.StatusConflict, errors.New("identity is already linked to a user")
+		}
+		return UserIdentity{}, http.StatusInternalServerError, err
+	}
+
+	return r.toIdentity(schema), http.StatusCreated, nil
+}
+
+func (r *ssoRepository) TouchIdentity(ctx context.Context, id uuid.UUID, loginAt time.Time) (int, error) {
+	result := r.db.WithContext(ctx).Model(&schemas.UserIdentity{}).
+		Where("id = ?", id).
+		UpdateColumn("last_login_at", loginAt)
+	if result.Error != nil {
+		return http.StatusInternalServerError, result.Error
+	}
+
+	return http.StatusOK, nil
+}
@@ -0,0 +1,112 @@
+package staff_attendance_repository
+
+import (
+	"time"
+
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type StaffAttendanceRepository interface {
+	FindByUserAndDate(unitId, userId uuid.UUID, date time.Time) (*schemas.StaffAttendance, error)
+	Create(record *schemas.StaffAttendance) (*schemas.StaffAttendance, error)
+	Update(record *schemas.StaffAttendance) (*schemas.StaffAttendance, error)
+	// FindByDates lists the unit's records from from to to, inclusive, of one
+	// user when userId is set.
+	FindByDates(unitId uuid.UUID, userId *uuid.UUID, from, to time.Time) ([]schemas.StaffAttendance, error)
+	// Lookups
+	FindSettings(unitId uuid.UUID) (*schemas.UnitSettings, error)
+	FindMember(unitId, userId uuid.UUID) (*schemas.UnitMember, error)
+	// FindStaff lists the unit's active owners, admins and staff
+	FindStaff(unitId uuid.UUID) ([]schemas.UnitMember, error)
+	FindTeacherByUser(unitId, userId uuid.UUID) (*schemas.TeacherProfile, error)
+	FindTeachers(unitId uuid.UUID) ([]schemas.TeacherProfile, error)
+}
+
+type staffAttendanceRepository struct {
+	db *gorm.DB
+}
+
+func NewStaffAttendanceRepository(db *gorm.DB) StaffAttendanceRepository {
+	return &staffAttendanceRepository{db: db}
+}
+
+func (r *staffAttendanceRepository) FindByUserAndDate(unitId, userId uuid.UUID, date time.Time) (*schemas.StaffAttendance, error) {
+	var record schemas.StaffAttendance
+	err := r.db.First(&record, "unit_id = ? AND user_id = ? AND date = ?", unitId, userId, date).Error
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *staffAttendanceRepository) Create(record *schemas.StaffAttendance) (*schemas.StaffAttendance, error) {
+	err := r.db.Create(record).Error
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (r *staffAttendanceRepository) Update(record *schemas.StaffAttendance) (*schemas.StaffAttendance, error) {
+	err := r.db.Omit("User", "TeacherProfile").Save(record).Error
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (r *staffAttendanceRepository) FindByDates(unitId uuid.UUID, userId *uuid.UUID, from, to time.Time) ([]schemas.StaffAttendance, error) {
+	var records []schemas.StaffAttendance
+	query := r.db.Where("unit_id = ? AND date BETWEEN ? AND ?", unitId, from, to)
+	if userId != nil {
+		query = query.Where("user_id = ?", *userId)
+	}
+	err := query.Preload("User").Order("date ASC, check_in_at ASC").Find(&records).Error
+	return records, err
+}
+
+func (r *staffAttendanceRepository) FindSettings(unitId uuid.UUID) (*schemas.UnitSettings, error) {
+	var settings schemas.UnitSettings
+	err := r.db.First(&settings, "unit_id = ?", unitId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (r *staffAttendanceRepository) FindMember(unitId, userId uuid.UUID) (*schemas.UnitMember, error) {
+	var member schemas.UnitMember
+	err := r.db.First(&member, "unit_id = ? AND user_id = ? AND is_active = ? AND approval_status = ?",
+		unitId, userId, true, schemas.UnitMemberApprovalApproved).Error
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *staffAttendanceRepository) FindStaff(unitId uuid.UUID) ([]schemas.UnitMember, error) {
+	var members []schemas.UnitMember
+	err := r.db.Preload("User").
+		Where("unit_id = ? AND is_active = ? AND approval_status = ?", unitId, true, schemas.UnitMemberApprovalApproved).
+		Where("role IN ?", []schemas.UnitMemberRole{schemas.UnitMemberRoleOwner, schemas.UnitMemberRoleAdmin, schemas.UnitMemberRoleStaff}).
+		Find(&members).Error
+	return members, err
+}
+
+func (r *staffAttendanceRepository) FindTeacherByUser(unitId, userId uuid.UUID) (*schemas.TeacherProfile, error) {
+	var teacher schemas.TeacherProfile
+	err := r.db.First(&teacher, "unit_id = ? AND user_id = ?", unitId, userId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &teacher, nil
+}
+
+func (r *staffAttendanceRepository) FindTeachers(unitId uuid.UUID) ([]schemas.TeacherProfile, error) {
+	var teachers []schemas.TeacherProfile
+	err := r.db.Preload("User").Where("unit_id = ?", unitId).Find(&teachers).Error
+	return teachers, err
+}
@@ -0,0 +1,326 @@
+package timetable_repository
+
+import (
+	"errors"
+	"time"
+
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrGenerationChanged is returned when a generation is no longer in the status
+// a write expects, e.g. because another request applied it meanwhile or it was
+// failed as abandoned.
+var ErrGenerationChanged = errors.New("timetable generation changed meanwhile")
+
+// TimetableFilter selects the entries of one unit's timetable for a semester.
+// The optional fields narrow it down to a class, a teacher, a room or a day.
+type TimetableFilter struct {
+	UnitId           uuid.UUID
+	AcademicYear     string
+	Semester         int
+	ClassId          *uuid.UUID
+	TeacherProfileId *uuid.UUID
+	Room             *string
+	DayOfWeek        *int
+}
+
+type TimetableRepository interface {
+	Create(entry *schemas.TimetableEntry) error
+	FindById(id uuid.UUID) (*schemas.TimetableEntry, error)
+	Find(filter TimetableFilter) ([]schemas.TimetableEntry, error)
+	Update(entry *schemas.TimetableEntry) error
+	Delete(id uuid.UUID) error
+	// Lookups used to validate entries
+	FindSettings(unitId uuid.UUID) (*schemas.UnitSettings, error)
+	FindClass(id uuid.UUID) (*schemas.Class, error)
+	FindSubject(id uuid.UUID) (*schemas.Subject, error)
+	FindTeacher(id uuid.UUID) (*schemas.TeacherProfile, error)
+	IsTeacherAssigned(teacherProfileId, subjectId uuid.UUID) (bool, error)
+	// Generator inputs
+	FindUnavailability(unitId uuid.UUID) ([]schemas.TeacherUnavailability, error)
+	FindTeacherUnavailability(teacherProfileId uuid.UUID) ([]schemas.TeacherUnavailability, error)
+	ReplaceTeacherUnavailability(teacherProfileId uuid.UUID, periods []schemas.TeacherUnavailability) error
+	FindClassSubjects(classIds []uuid.UUID) ([]schemas.ClassSubject, error)
+	ReplaceClassSubjects(classId uuid.UUID, subjects []schemas.ClassSubject) error
+	FindActiveClasses(unitId uuid.UUID, academicYear string) ([]schemas.Class, error)
+	FindSubjectTeachers(unitId uuid.UUID) ([]schemas.TeacherSubject, error)
+	// Generation jobs
+	CreateGeneration(generation *schemas.TimetableGeneration) error
+	FindGeneration(id uuid.UUID) (*schemas.TimetableGeneration, error)
+	// StartGeneration moves a pending generation to running, HeartbeatGeneration
+	// records that a running one is still being worked on and FinishGeneration
+	// stores the outcome of a running one. They fail with ErrGenerationChanged
+	// when the stored generation is not in the expected status.
+	StartGeneration(id uuid.UUID, startedAt time.Time) error
+	HeartbeatGeneration(id uuid.UUID) error
+	FinishGeneration(generation *schemas.TimetableGeneration) error
+	// FailStaleGenerations marks the pending and running generations without a
+	// heartbeat since aliveAfter as failed with the message, returning how many
+	// there were.
+	FailStaleGenerations(message string, aliveAfter time.Time) (int64, error)
+	// ApplyGeneration replaces the semester's timetable of the generation's classes
+	// with entries and marks the generation applied, in one transaction. It fails
+	// with ErrGenerationChanged unless the stored generation is still completed.
+	ApplyGeneration(generation *schemas.TimetableGeneration, classIds []uuid.UUID, entries []schemas.TimetableEntry) error
+}
+
+type timetableRepository struct {
+	db *gorm.DB
+}
+
+func NewTimetableRepository(db *gorm.DB) TimetableRepository {
+	return &timetableRepository{db: db}
+}
+
+func (r *timetableRepository) Create(entry *schemas.TimetableEntry) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *timetableRepository) FindById(id uuid.UUID) (*schemas.TimetableEntry, error) {
+	var entry schemas.TimetableEntry
+	err := r.db.Preload("Class").Preload("Subject").Preload("TeacherProfile.User").
+		First(&entry, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *timetableRepository) Find(filter TimetableFilter) ([]schemas.TimetableEntry, error) {
+	var entries []schemas.TimetableEntry
+
+	query := r.db.Model(&schemas.TimetableEntry{}).
+		Where("unit_id = ? AND academic_year = ? AND semester = ?", filter.UnitId, filter.AcademicYear, filter.Semester)
+	if filter.ClassId != nil {
+		query = query.Where("class_id = ?", *filter.ClassId)
+	}
+	if filter.TeacherProfileId != nil {
+		query = query.Where("teacher_profile_id = ?", *filter.TeacherProfileId)
+	}
+	if filter.Room != nil {
+		query = query.Where("room = ?", *filter.Room)
+	}
+	if filter.DayOfWeek != nil {
+		query = query.Where("day_of_week = ?", *filter.DayOfWeek)
+	}
+
+	err := query.Preload("Class").Preload("Subject").Preload("TeacherProfile.User").
+		Order("day_of_week ASC, period ASC").
+		Find(&entries).Error
+	return entries, err
+}
+
+func (r *timetableRepository) Update(entry *schemas.TimetableEntry) error {
+	return r.db.Omit("Class", "Subject", "TeacherProfile").Save(entry).Error
+}
+
+func (r *timetableRepository) Delete(id uuid.UUID) error {
+	return r.db.Delete(&schemas.TimetableEntry{}, "id = ?", id).Error
+}
+
+func (r *timetableRepository) FindSettings(unitId uuid.UUID) (*schemas.UnitSettings, error) {
+	var settings schemas.UnitSettings
+	err := r.db.First(&settings, "unit_id = ?", unitId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (r *timetableRepository) FindClass(id uuid.UUID) (*schemas.Class, error) {
+	var class schemas.Class
+	err := r.db.First(&class, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &class, nil
+}
+
+func (r *timetableRepository) FindSubject(id uuid.UUID) (*schemas.Subject, error) {
+	var subject schemas.Subject
+	err := r.db.First(&subject, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &subject, nil
+}
+
+func (r *timetableRepository) FindTeacher(id uuid.UUID) (*schemas.TeacherProfile, error) {
+	var teacher schemas.TeacherProfile
+	err := r.db.First(&teacher, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &teacher, nil
+}
+
+func (r *timetableRepository) IsTeacherAssigned(teacherProfileId, subjectId uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Model(&schemas.TeacherSubject{}).
+		Where("teacher_profile_id = ? AND subject_id = ?", teacherProfileId, subjectId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *timetableRepository) FindUnavailability(unitId uuid.UUID) ([]schemas.TeacherUnavailability, error) {
+	var periods []schemas.TeacherUnavailability
+	err := r.db.Where("unit_id = ?", unitId).Find(&periods).Error
+	return periods, err
+}
+
+func (r *timetableRepository) FindTeacherUnavailability(teacherProfileId uuid.UUID) ([]schemas.TeacherUnavailability, error) {
+	var periods []schemas.TeacherUnavailability
+	err := r.db.Where("teacher_profile_id = ?", teacherProfileId).
+		Order("day_of_week ASC, period_from ASC").
+		Find(&periods).Error
+	return periods, err
+}
+
+func (r *timetableRepository) ReplaceTeacherUnavailability(teacherProfileId uuid.UUID, periods []schemas.TeacherUnavailability) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("teacher_profile_id = ?", teacherProfileId).Delete(&schemas.TeacherUnavailability{}).Error; err != nil {
+			return err
+		}
+		if len(periods) == 0 {
+			return nil
+		}
+		return tx.Create(&periods).Error
+	})
+}
+
+func (r *timetableRepository) FindClassSubjects(classIds []uuid.UUID) ([]schemas.ClassSubject, error) {
+	var subjects []schemas.ClassSubject
+	err := r.db.Preload("Subject").
+		Where("class_id IN ?", classIds).
+		Find(&subjects).Error
+	return subjects, err
+}
+
+func (r *timetableRepository) ReplaceClassSubjects(classId uuid.UUID, subjects []schemas.ClassSubject) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("class_id = ?", classId).Delete(&schemas.ClassSubject{}).Error; err != nil {
+			return err
+		}
+		if len(subjects) == 0 {
+			return nil
+		}
+		return tx.Create(&subjects).Error
+	})
+}
+
+func (r *timetableRepository) FindActiveClasses(unitId uuid.UUID, academicYear string) ([]schemas.Class, error) {
+	var classes []schemas.Class
+	err := r.db.Where("unit_id = ? AND academic_year = ? AND is_active = ?", unitId, academicYear, true).
+		Order("level ASC, name ASC").
+		Find(&classes).Error
+	return classes, err
+}
+
+func (r *timetableRepository) FindSubjectTeachers(unitId uuid.UUID) ([]schemas.TeacherSubject, error) {
+	var assignments []schemas.TeacherSubject
+	err := r.db.
+		Joins("JOIN teacher_profiles ON teacher_profiles.id = teacher_subjects.teacher_profile_id").
+		Where("teacher_profiles.unit_id = ? AND teacher_profiles.deleted_at IS NULL", unitId).
+		Find(&assignments).Error
+	return assignments, err
+}
+
+func (r *timetableRepository) CreateGeneration(generation *schemas.TimetableGeneration) error {
+	return r.db.Create(generation).Error
+}
+
+func (r *timetableRepository) FindGeneration(id uuid.UUID) (*schemas.TimetableGeneration, error) {
+	var generation schemas.TimetableGeneration
+	err := r.db.First(&generation, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &generation, nil
+}
+
+func (r *timetableRepository) StartGeneration(id uuid.UUID, startedAt time.Time) error {
+	return r.updateGeneration(id, schemas.TimetableGenerationPending, map[string]interface{}{
+		"status":       schemas.TimetableGenerationRunning,
+		"started_at":   startedAt,
+		"heartbeat_at": startedAt,
+		"updated_at":   startedAt,
+	})
+}
+
+func (r *timetableRepository) HeartbeatGeneration(id uuid.UUID) error {
+	now := time.Now()
+	return r.updateGeneration(id, schemas.TimetableGenerationRunning, map[string]interface{}{
+		"heartbeat_at": now,
+		"updated_at":   now,
+	})
+}
+
+func (r *timetableRepository) FinishGeneration(generation *schemas.TimetableGeneration) error {
+	return r.updateGeneration(generation.Id, schemas.TimetableGenerationRunning, map[string]interface{}{
+		"status":      generation.Status,
+		"entries":     generation.Entries,
+		"unsatisfied": generation.Unsatisfied,
+		"error":       generation.Error,
+		"finished_at": generation.FinishedAt,
+		"updated_at":  time.Now(),
+	})
+}
+
+func (r *timetableRepository) updateGeneration(id uuid.UUID, from schemas.TimetableGenerationStatus, values map[string]interface{}) error {
+	result := r.db.Model(&schemas.TimetableGeneration{}).
+		Where("id = ? AND status = ?", id, from).
+		Updates(values)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrGenerationChanged
+	}
+	return nil
+}
+
+func (r *timetableRepository) FailStaleGenerations(message string, aliveAfter time.Time) (int64, error) {
+	now := time.Now()
+	result := r.db.Model(&schemas.TimetableGeneration{}).
+		Where("status IN ?", []schemas.TimetableGenerationStatus{schemas.TimetableGenerationPending, schemas.TimetableGenerationRunning}).
+		Where("COALESCE(heartbeat_at, created_at) < ?", aliveAfter).
+		Updates(map[string]interface{}{
+			"status":      schemas.TimetableGenerationFailed,
+			"error":       message,
+			"finished_at": now,
+			"updated_at":  now,
+		})
+	return result.RowsAffected, result.Error
+}
+
+func (r *timetableRepository) ApplyGeneration(generation *schemas.TimetableGeneration, classIds []uuid.UUID, entries []schemas.TimetableEntry) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Claiming the generation first also makes a concurrent apply wait here
+		result := tx.Model(&schemas.TimetableGeneration{}).
+			Where("id = ? AND status = ?", generation.Id, schemas.TimetableGenerationCompleted).
+			Updates(map[string]interface{}{
+				"status":     generation.Status,
+				"applied_at": generation.AppliedAt,
+				"updated_at": time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrGenerationChanged
+		}
+
+		err := tx.Where("class_id IN ? AND academic_year = ? AND semester = ?", classIds, generation.AcademicYear, generation.Semester).
+			Delete(&schemas.TimetableEntry{}).Error
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			return tx.Create(&entries).Error
+		}
+		return nil
+	})
+}
@@ -0,0 +1,18 @@
+package token_repository
+
+import "github.com/google/uuid"
+
+type RefreshTokenFilter struct {
+	Id       *uuid.UUID
+	UserId   *uuid.UUID
+	FamilyId *uuid.UUID
+	// Active limits results to tokens that are unused, unrevoked and unexpired
+	Active *bool
+}
+
+type SessionFilter struct {
+	Id     *uuid.UUID
+	UserId *uuid.UUID
+	// Active limits results to sessions that are unrevoked and unexpired
+	Active *bool
+}
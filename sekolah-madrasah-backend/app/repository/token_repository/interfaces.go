@@ -0,0 +1,32 @@
+package token_repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TokenRepository interface {
+	GetRefreshToken(ctx context.Context, filter RefreshTokenFilter) (RefreshToken, int, error)
+	GetRefreshTokens(ctx context.Context, filter RefreshTokenFilter) ([]RefreshToken, int, error)
+	CreateRefreshToken(ctx context.Context, token RefreshToken) (RefreshToken, int, error)
+	// MarkRefreshTokenUsed flags a token as rotated. It returns 409 when the token
+	// was already used or revoked, which means it is being replayed.
+	MarkRefreshTokenUsed(ctx context.Context, id uuid.UUID) (int, error)
+	RevokeRefreshTokens(ctx context.Context, filter RefreshTokenFilter) (int, error)
+	RevokeAccessTokens(ctx context.Context, tokens []RevokedAccessToken) (int, error)
+	IsAccessTokenRevoked(ctx context.Context, jti uuid.UUID) (bool, error)
+
+	GetSession(ctx context.Context, filter SessionFilter) (Session, int, error)
+	GetSessions(ctx context.Context, filter SessionFilter) ([]Session, int, error)
+	// SaveSession creates the session or refreshes its device details and expiry.
+	SaveSession(ctx context.Context, session Session) (int, error)
+	TouchSession(ctx context.Context, id uuid.UUID, ipAddress string, seenAt time.Time) (int, error)
+	RevokeSessions(ctx context.Context, filter SessionFilter) (int, error)
+
+	CreatePasswordResetToken(ctx context.Context, token PasswordResetToken) (int, error)
+	// ConsumePasswordResetToken marks the token and every other pending reset token
+	// of the same user as used. Unknown, used or expired tokens return 400.
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string) (PasswordResetToken, int, error)
+}
@@ -0,0 +1,49 @@
+package token_repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RefreshToken struct {
+	Id              uuid.UUID
+	UserId          uuid.UUID
+	FamilyId        uuid.UUID
+	AccessTokenId   uuid.UUID
+	AccessExpiresAt time.Time
+	ExpiresAt       time.Time
+	UsedAt          *time.Time
+	RevokedAt       *time.Time
+	UserAgent       string
+	IpAddress       string
+	CreatedAt       time.Time
+}
+
+type RevokedAccessToken struct {
+	Jti       uuid.UUID
+	UserId    uuid.UUID
+	ExpiresAt time.Time
+}
+
+type Session struct {
+	Id         uuid.UUID
+	UserId     uuid.UUID
+	Device     string
+	UserAgent  string
+	IpAddress  string
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+type PasswordResetToken struct {
+	Id        uuid.UUID
+	UserId    uuid.UUID
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	IpAddress string
+	CreatedAt time.Time
+}
@@ -0,0 +1,354 @@
+package token_repository
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"sekolah-madrasah/app/repository/common"
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type tokenRepository struct {
+	db *gorm.DB
+}
+
+func NewTokenRepository(db *gorm.DB) TokenRepository {
+	return &tokenRepository{db: db}
+}
+
+func (r *tokenRepository) applyFilter(query *gorm.DB, filter RefreshTokenFilter) *gorm.DB {
+	if filter.Id != nil {
+		query = query.Where("refresh_tokens.id = ?", *filter.Id)
+	}
+	if filter.UserId != nil {
+		query = query.Where("refresh_tokens.user_id = ?", *filter.UserId)
+	}
+	if filter.FamilyId != nil {
+		query = query.Where("refresh_tokens.family_id = ?", *filter.FamilyId)
+	}
+	if filter.Active != nil {
+		if *filter.Active {
+			query = query.Where("refresh_tokens.used_at IS NULL AND refresh_tokens.revoked_at IS NULL AND refresh_tokens.expires_at > ?", time.Now())
+		} else {
+			query = query.Where("refresh_tokens.used_at IS NOT NULL OR refresh_tokens.revoked_at IS NOT NULL OR refresh_tokens.expires_at <= ?", time.Now())
+		}
+	}
+	return query
+}
+
+func (r *tokenRepository) toModel(schema schemas.RefreshToken) RefreshToken {
+	return RefreshToken{
+		Id:              schema.Id,
+		UserId:          schema.UserId,
+		FamilyId:        schema.FamilyId,
+		AccessTokenId:   schema.AccessTokenId,
+		AccessExpiresAt: schema.AccessExpiresAt,
+		ExpiresAt:       schema.ExpiresAt,
+		UsedAt:          schema.UsedAt,
+		RevokedAt:       schema.RevokedAt,
+		UserAgent:       schema.UserAgent,
+		IpAddress:       schema.IpAddress,
+		CreatedAt:       schema.CreatedAt,
+	}
+}
+
+func (r *tokenRepository) GetRefreshToken(ctx context.Context, filter RefreshTokenFilter) (RefreshToken, int, error) {
+	var schema schemas.RefreshToken
+	query := r.db.WithContext(ctx).Model(&schemas.RefreshToken{})
+	query = r.applyFilter(query, filter)
+
+	if err := query.First(&schema).Error; err != nil {
+		code, err := common.HandleGORMError(err)
+		return RefreshToken{}, code, err
+	}
+
+	return r.toModel(schema), http.StatusOK, nil
+}
+
+func (r *tokenRepository) GetRefreshTokens(ctx context.Context, filter RefreshTokenFilter) ([]RefreshToken, int, error) {
+	var schemaList []schemas.RefreshToken
+	query := r.db.WithContext(ctx).Model(&schemas.RefreshToken{})
+	query = r.applyFilter(query, filter)
+	query = common.ApplyOrderBy(query, "created_at DESC")
+
+	if err := query.Find(&schemaList).Error; err != nil {
+		code, err := common.HandleGORMError(err)
+		return nil, code, err
+	}
+
+	tokens := make([]RefreshToken, len(schemaList))
+	for i, s := range schemaList {
+		tokens[i] = r.toModel(s)
+	}
+
+	return tokens, http.StatusOK, nil
+}
+
+func (r *tokenRepository) CreateRefreshToken(ctx context.Context, token RefreshToken) (RefreshToken, int, error) {
+	schema := schemas.RefreshToken{
+		Id:              token.Id,
+		UserId:          token.UserId,
+		FamilyId:        token.FamilyId,
+		AccessTokenId:   token.AccessTokenId,
+		AccessExpiresAt: token.AccessExpiresAt,
+		ExpiresAt:       token.ExpiresAt,
+		UserAgent:       token.UserAgent,
+		IpAddress:       token.IpAddress,
+	}
+
+	if err := r.db.WithContext(ctx).Create(&schema).Error; err != nil {
+		return RefreshToken{}, http.StatusInternalServerError, err
+	}
+
+	return r.toModel(schema), http.StatusCreated, nil
+}
+
+func (r *tokenRepository) MarkRefreshTokenUsed(ctx context.Context, id uuid.UUID) (int, error) {
+	now := time.Now()
+	// The conditional update makes rotation atomic: only one caller can consume a token
+	result := r.db.WithContext(ctx).Model(&schemas.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"used_at": now, "updated_at": now})
+	if result.Error != nil {
+		return http.StatusInternalServerError, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return http.StatusConflict, errors.New("refresh token already used")
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *tokenRepository) RevokeRefreshTokens(ctx context.Context, filter RefreshTokenFilter) (int, error) {
+	now := time.Now()
+	query := r.db.WithContext(ctx).Model(&schemas.RefreshToken{})
+	query = r.applyFilter(query, filter)
+
+	result := query.Where("refresh_tokens.revoked_at IS NULL").
+		Updates(map[string]interface{}{"revoked_at": now, "updated_at": now})
+	if result.Error != nil {
+		return http.StatusInternalServerError, result.Error
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *tokenRepository) RevokeAccessTokens(ctx context.Context, tokens []RevokedAccessToken) (int, error) {
+	if len(tokens) == 0 {
+		return http.StatusOK, nil
+	}
+
+	schemaList := make([]schemas.RevokedAccessToken, len(tokens))
+	for i, t := range tokens {
+		schemaList[i] = schemas.RevokedAccessToken{
+			Jti:       t.Jti,
+			UserId:    t.UserId,
+			ExpiresAt: t.ExpiresAt,
+		}
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&schemaList).Error; err != nil {
+			return err
+		}
+		// Entries past their expiry are useless, since the token itself is no longer accepted
+		return tx.Where("expires_at <= ?", time.Now()).Delete(&schemas.RevokedAccessToken{}).Error
+	})
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *tokenRepository) IsAccessTokenRevoked(ctx context.Context, jti uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&schemas.RevokedAccessToken{}).
+		Where("jti = ?", jti).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *tokenRepository) applySessionFilter(query *gorm.DB, filter SessionFilter) *gorm.DB {
+	if filter.Id != nil {
+		query = query.Where("user_sessions.id = ?", *filter.Id)
+	}
+	if filter.UserId != nil {
+		query = query.Where("user_sessions.user_id = ?", *filter.UserId)
+	}
+	if filter.Active != nil {
+		if *filter.Active {
+			query = query.Where("user_sessions.revoked_at IS NULL AND user_sessions.expires_at > ?", time.Now())
+		} else {
+			query = query.Where("user_sessions.revoked_at IS NOT NULL OR user_sessions.expires_at <= ?", time.Now())
+		}
+	}
+	return query
+}
+
+func (r *tokenRepository) toSessionModel(schema schemas.UserSession) Session {
+	return Session{
+		Id:         schema.Id,
+		UserId:     schema.UserId,
+		Device:     schema.Device,
+		UserAgent:  schema.UserAgent,
+		IpAddress:  schema.IpAddress,
+		LastSeenAt: schema.LastSeenAt,
+		ExpiresAt:  schema.ExpiresAt,
+		RevokedAt:  schema.RevokedAt,
+		CreatedAt:  schema.CreatedAt,
+	}
+}
+
+func (r *tokenRepository) GetSession(ctx context.Context, filter SessionFilter) (Session, int, error) {
+	var schema schemas.UserSession
+	query := r.db.WithContext(ctx).Model(&schemas.UserSession{})
+	query = r.applySessionFilter(query, filter)
+
+	if err := query.First(&schema).Error; err != nil {
+		code, err := common.HandleGORMError(err)
+		return Session{}, code, err
+	}
+
+	return r.toSessionModel(schema), http.StatusOK, nil
+}
+
+func (r *tokenRepository) GetSessions(ctx context.Context, filter SessionFilter) ([]Session, int, error) {
+	var schemaList []schemas.UserSession
+	query := r.db.WithContext(ctx).Model(&schemas.UserSession{})
+	query = r.applySessionFilter(query, filter)
+	query = common.ApplyOrderBy(query, "last_seen_at DESC")
+
+	if err := query.Find(&schemaList).Error; err != nil {
+		code, err := common.HandleGORMError(err)
+		return nil, code, err
+	}
+
+	sessions := make([]Session, len(schemaList))
+	for i, s := range schemaList {
+		sessions[i] = r.toSessionModel(s)
+	}
+
+	return sessions, http.StatusOK, nil
+}
+
+func (r *tokenRepository) SaveSession(ctx context.Context, session Session) (int, error) {
+	schema := schemas.UserSession{
+		Id:         session.Id,
+		UserId:     session.UserId,
+		Device:     session.Device,
+		UserAgent:  session.UserAgent,
+		IpAddress:  session.IpAddress,
+		LastSeenAt: session.LastSeenAt,
+		ExpiresAt:  session.ExpiresAt,
+	}
+
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"device", "user_agent", "ip_address", "last_seen_at", "expires_at", "updated_at"}),
+	}).Create(&schema).Error
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *tokenRepository) TouchSession(ctx context.Context, id uuid.UUID, ipAddress string, seenAt time.Time) (int, error) {
+	updates := map[string]interface{}{
+		"last_seen_at": seenAt,
+		"updated_at":   time.Now(),
+	}
+	if ipAddress != "" {
+		updates["ip_address"] = ipAddress
+	}
+
+	result := r.db.WithContext(ctx).Model(&schemas.UserSession{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(updates)
+	if result.Error != nil {
+		return http.StatusInternalServerError, result.Error
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *tokenRepository) RevokeSessions(ctx context.Context, filter SessionFilter) (int, error) {
+	now := time.Now()
+	query := r.db.WithContext(ctx).Model(&schemas.UserSession{})
+	query = r.applySessionFilter(query, filter)
+
+	result := query.Where("user_sessions.revoked_at IS NULL").
+		Updates(map[string]interface{}{"revoked_at": now, "updated_at": now})
+	if result.Error != nil {
+		return http.StatusInternalServerError, result.Error
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *tokenRepository) CreatePasswordResetToken(ctx context.Context, token PasswordResetToken) (int, error) {
+	schema := schemas.PasswordResetToken{
+		Id:        token.Id,
+		UserId:    token.UserId,
+		TokenHash: token.TokenHash,
+		ExpiresAt: token.ExpiresAt,
+		IpAddress: token.IpAddress,
+	}
+
+	if err := r.db.WithContext(ctx).Create(&schema).Error; err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return http.StatusCreated, nil
+}
+
+func (r *tokenRepository) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (PasswordResetToken, int, error) {
+	var schema schemas.PasswordResetToken
+	code := http.StatusOK
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).
+			First(&schema).Error
+		if err != nil {
+			code, err = common.HandleGORMError(err)
+			return err
+		}
+
+		if err := tx.Model(&schemas.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", schema.UserId).
+			Update("used_at", now).Error; err != nil {
+			code = http.StatusInternalServerError
+			return err
+		}
+		schema.UsedAt = &now
+		return nil
+	})
+	if err != nil {
+		if code == http.StatusNotFound {
+			return PasswordResetToken{}, http.StatusBadRequest, errors.New("invalid or expired reset token")
+		}
+		return PasswordResetToken{}, code, err
+	}
+
+	return PasswordResetToken{
+		Id:        schema.Id,
+		UserId:    schema.UserId,
+		TokenHash: schema.TokenHash,
+		ExpiresAt: schema.ExpiresAt,
+		UsedAt:    schema.UsedAt,
+		IpAddress: schema.IpAddress,
+		CreatedAt: schema.CreatedAt,
+	}, http.StatusOK, nil
+}
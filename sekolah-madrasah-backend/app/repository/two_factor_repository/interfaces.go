@@ -0,0 +1,24 @@
+package two_factor_repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type TwoFactorRepository interface {
+	GetTwoFactor(ctx context.Context, userId uuid.UUID) (TwoFactor, int, error)
+	// SavePendingTwoFactor stores a new, unconfirmed secret. It returns 409 when the
+	// user already has confirmed two-factor authentication.
+	SavePendingTwoFactor(ctx context.Context, userId uuid.UUID, secret string) (int, error)
+	// ConfirmTwoFactor activates the pending secret and replaces the recovery codes.
+	ConfirmTwoFactor(ctx context.Context, userId uuid.UUID, step int64, codeHashes []string) (int, error)
+	// UseStep records an accepted TOTP time step. It returns 409 when the step, or
+	// a later one, was already used.
+	UseStep(ctx context.Context, userId uuid.UUID, step int64) (int, error)
+	// UseRecoveryCode marks an unused recovery code as used. Unknown or used codes return 400.
+	UseRecoveryCode(ctx context.Context, userId uuid.UUID, codeHash string) (int, error)
+	ReplaceRecoveryCodes(ctx context.Context, userId uuid.UUID, codeHashes []string) (int, error)
+	CountRecoveryCodes(ctx context.Context, userId uuid.UUID) (int64, int, error)
+	DeleteTwoFactor(ctx context.Context, userId uuid.UUID) (int, error)
+}
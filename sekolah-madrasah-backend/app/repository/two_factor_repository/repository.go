@@ -0,0 +1,182 @@
+package two_factor_repository
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"sekolah-madrasah/app/repository/common"
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type twoFactorRepository struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorRepository(db *gorm.DB) TwoFactorRepository {
+	return &twoFactorRepository{db: db}
+}
+
+func (r *twoFactorRepository) toModel(schema schemas.UserTwoFactor) TwoFactor {
+	return TwoFactor{
+		UserId:       schema.UserId,
+		Secret:       schema.Secret,
+		ConfirmedAt:  schema.ConfirmedAt,
+		LastUsedStep: schema.LastUsedStep,
+		CreatedAt:    schema.CreatedAt,
+		UpdatedAt:    schema.UpdatedAt,
+	}
+}
+
+func (r *twoFactorRepository) GetTwoFactor(ctx context.Context, userId uuid.UUID) (TwoFactor, int, error) {
+	var schema schemas.UserTwoFactor
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userId).First(&schema).Error; err != nil {
+		code, err := common.HandleGORMError(err)
+		return TwoFactor{}, code, err
+	}
+
+	return r.toModel(schema), http.StatusOK, nil
+}
+
+func (r *twoFactorRepository) SavePendingTwoFactor(ctx context.Context, userId uuid.UUID, secret string) (int, error) {
+	schema := schemas.UserTwoFactor{
+		UserId: userId,
+		Secret: secret,
+	}
+
+	// Only an unconfirmed enrollment may be overwritten
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"secret", "last_used_step", "updated_at"}),
+		Where:     clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "user_two_factors.confirmed_at IS NULL"}}},
+	}).Create(&schema)
+	if result.Error != nil {
+		return http.StatusInternalServerError, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return http.StatusConflict, errors.New("two-factor authentication is already enabled")
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *twoFactorRepository) ConfirmTwoFactor(ctx context.Context, userId uuid.UUID, step int64, codeHashes []string) (int, error) {
+	code := http.StatusOK
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&schemas.UserTwoFactor{}).
+			Where("user_id = ? AND confirmed_at IS NULL", userId).
+			Updates(map[string]interface{}{
+				"confirmed_at":   now,
+				"last_used_step": step,
+				"updated_at":     now,
+			})
+		if result.Error != nil {
+			code = http.StatusInternalServerError
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			code = http.StatusConflict
+			return errors.New("no pending two-factor enrollment")
+		}
+
+		return replaceRecoveryCodes(tx, userId, codeHashes)
+	})
+	if err != nil {
+		if code == http.StatusOK {
+			code = http.StatusInternalServerError
+		}
+		return code, err
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *twoFactorRepository) UseStep(ctx context.Context, userId uuid.UUID, step int64) (int, error) {
+	result := r.db.WithContext(ctx).Model(&schemas.UserTwoFactor{}).
+		Where("user_id = ? AND confirmed_at IS NOT NULL AND last_used_step < ?", userId, step).
+		Updates(map[string]interface{}{
+			"last_used_step": step,
+			"updated_at":     time.Now(),
+		})
+	if result.Error != nil {
+		return http.StatusInternalServerError, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return http.StatusConflict, errors.New("verification code was already used")
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *twoFactorRepository) UseRecoveryCode(ctx context.Context, userId uuid.UUID, codeHash string) (int, error) {
+	result := r.db.WithContext(ctx).Model(&schemas.TwoFactorRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return http.StatusInternalServerError, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return http.StatusBadRequest, errors.New("invalid recovery code")
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *twoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userId uuid.UUID, codeHashes []string) (int, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userId, codeHashes)
+	})
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *twoFactorRepository) CountRecoveryCodes(ctx context.Context, userId uuid.UUID) (int64, int, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&schemas.TwoFactorRecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userId).
+		Count(&count).Error
+	if err != nil {
+		return 0, http.StatusInternalServerError, err
+	}
+
+	return count, http.StatusOK, nil
+}
+
+func (r *twoFactorRepository) DeleteTwoFactor(ctx context.Context, userId uuid.UUID) (int, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userId).Delete(&schemas.TwoFactorRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userId).Delete(&schemas.UserTwoFactor{}).Error
+	})
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return http.StatusOK, nil
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userId uuid.UUID, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userId).Delete(&schemas.TwoFactorRecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if len(codeHashes) == 0 {
+		return nil
+	}
+
+	codes := make([]schemas.TwoFactorRecoveryCode, len(codeHashes))
+	for i, hash := range codeHashes {
+		codes[i] = schemas.TwoFactorRecoveryCode{UserId: userId, CodeHash: hash}
+	}
+	return tx.Create(&codes).Error
+}
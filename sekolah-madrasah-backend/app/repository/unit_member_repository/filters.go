@@ -12,4 +12,6 @@ type UnitMemberFilter struct {
 	UnitId *uuid.UUID
 	Role     *schemas.UnitMemberRole
 	IsActive *bool
+
+	ApprovalStatus *schemas.UnitMemberApprovalStatus
 }
@@ -11,5 +11,7 @@ type UnitMemberRepository interface {
 	GetMembers(ctx context.Context, filter UnitMemberFilter, paginate *paginate_utils.PaginateData) ([]UnitMember, int, error)
 	AddMember(ctx context.Context, member UnitMember) (UnitMember, int, error)
 	UpdateMember(ctx context.Context, filter UnitMemberFilter, member UnitMember) (int, error)
+	// ReviewMember records the decision on members still awaiting review.
+	ReviewMember(ctx context.Context, filter UnitMemberFilter, review MemberReview) (int, error)
 	RemoveMember(ctx context.Context, filter UnitMemberFilter) (int, error)
 }
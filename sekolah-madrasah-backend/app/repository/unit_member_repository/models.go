@@ -16,6 +16,12 @@ type UnitMember struct {
 	IsActive  bool
 	JoinedAt  time.Time
 	InvitedBy *uuid.UUID
+
+	ApprovalStatus  schemas.UnitMemberApprovalStatus
+	ReviewedBy      *uuid.UUID
+	ReviewedAt      *time.Time
+	RejectionReason string
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 
@@ -24,6 +30,13 @@ type UnitMember struct {
 	Unit *UnitInfo
 }
 
+// MemberReview is an admin's decision on a pending sign-up
+type MemberReview struct {
+	Status          schemas.UnitMemberApprovalStatus
+	ReviewedBy      uuid.UUID
+	RejectionReason string
+}
+
 type UserInfo struct {
 	Id       uuid.UUID
 	FullName string
@@ -37,6 +37,9 @@ func (r *unitMemberRepository) applyFilter(query *gorm.DB, filter UnitMemberFilt
 	if filter.IsActive != nil {
 		query = query.Where("unit_members.is_active = ?", *filter.IsActive)
 	}
+	if filter.ApprovalStatus != nil {
+		query = query.Where("unit_members.approval_status = ?", *filter.ApprovalStatus)
+	}
 	return query
 }
 
@@ -51,6 +54,11 @@ func (r *unitMemberRepository) toModel(schema schemas.UnitMember) UnitMember {
 		InvitedBy: schema.InvitedBy,
 		CreatedAt: schema.CreatedAt,
 		UpdatedAt: schema.UpdatedAt,
+
+		ApprovalStatus:  schema.ApprovalStatus,
+		ReviewedBy:      schema.ReviewedBy,
+		ReviewedAt:      schema.ReviewedAt,
+		RejectionReason: schema.RejectionReason,
 	}
 
 	if schema.User != nil {
@@ -121,11 +129,18 @@ func (r *unitMemberRepository) AddMember(ctx context.Context, member UnitMember)
 		Role:      member.Role,
 		IsActive:  member.IsActive,
 		InvitedBy: member.InvitedBy,
+
+		ApprovalStatus: member.ApprovalStatus,
+		ReviewedBy:     member.ReviewedBy,
+		ReviewedAt:     member.ReviewedAt,
 	}
 
 	if schema.Id == uuid.Nil {
 		schema.Id = uuid.New()
 	}
+	if schema.ApprovalStatus == "" {
+		schema.ApprovalStatus = schemas.UnitMemberApprovalApproved
+	}
 	schema.JoinedAt = time.Now()
 	schema.CreatedAt = time.Now()
 	schema.UpdatedAt = time.Now()
@@ -162,6 +177,30 @@ func (r *unitMemberRepository) UpdateMember(ctx context.Context, filter UnitMemb
 	return http.StatusOK, nil
 }
 
+func (r *unitMemberRepository) ReviewMember(ctx context.Context, filter UnitMemberFilter, review MemberReview) (int, error) {
+	query := r.db.WithContext(ctx).Model(&schemas.UnitMember{})
+	query = r.applyFilter(query, filter)
+	// Only pending sign-ups can be decided, so two reviewers cannot both win
+	query = query.Where("unit_members.approval_status = ?", schemas.UnitMemberApprovalPending)
+
+	now := time.Now()
+	result := query.Updates(map[string]interface{}{
+		"approval_status":  review.Status,
+		"reviewed_by":      review.ReviewedBy,
+		"reviewed_at":      now,
+		"rejection_reason": review.RejectionReason,
+		"updated_at":       now,
+	})
+	if result.Error != nil {
+		return http.StatusInternalServerError, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return http.StatusNotFound, gorm.ErrRecordNotFound
+	}
+
+	return http.StatusOK, nil
+}
+
 func (r *unitMemberRepository) RemoveMember(ctx context.Context, filter UnitMemberFilter) (int, error) {
 	query := r.db.WithContext(ctx).Model(&schemas.UnitMember{})
 	query = r.applyFilter(query, filter)
@@ -2,6 +2,7 @@ package user_repository
 
 import (
 	"context"
+	"time"
 
 	"sekolah-madrasah/pkg/paginate_utils"
 
@@ -12,9 +13,32 @@ type UserRepository interface {
 	GetUser(ctx context.Context, filter UserFilter) (User, int, error)
 	GetUsers(ctx context.Context, filter UserFilter, paginate *paginate_utils.PaginateData) ([]User, int, error)
 	CreateUser(ctx context.Context, user User) (User, int, error)
+	// CreateSignUpUser creates the user together with their pending membership
+	// of the unit they signed up for, so neither is stored without the other.
+	CreateSignUpUser(ctx context.Context, user User, signUp SignUp) (User, int, error)
+	// UpdateUser applies non-empty fields. Changing the email clears EmailVerifiedAt.
 	UpdateUser(ctx context.Context, filter UserFilter, user User) (int, error)
+	// UpdatePassword stores a new password hash and sets the must-change flag.
+	UpdatePassword(ctx context.Context, filter UserFilter, hashedPassword string, mustChange bool) (int, error)
+	// MarkEmailVerified sets EmailVerifiedAt on the users matching filter.
+	MarkEmailVerified(ctx context.Context, filter UserFilter) (int, error)
 	DeleteUser(ctx context.Context, filter UserFilter) (int, error)
 	UpdateLastLogin(ctx context.Context, filter UserFilter) (int, error)
-	HasPendingApproval(ctx context.Context, userId uuid.UUID) (bool, error)
-	GetApprovalStatus(ctx context.Context, userId uuid.UUID) (string, error)
+	// RecordFailedLogin counts a wrong password and, once the count reaches lockAfter,
+	// locks the account until lockUntil. It returns the updated user.
+	RecordFailedLogin(ctx context.Context, userId uuid.UUID, lockAfter int, lockUntil time.Time) (User, int, error)
+	// ResetFailedLogins clears the failure count and any lock.
+	ResetFailedLogins(ctx context.Context, filter UserFilter) (int, error)
+	// GetApprovalStatus summarizes the review of the user's unit sign-ups. See
+	// the implementation for how several memberships combine.
+	GetApprovalStatus(ctx context.Context, userId uuid.UUID) (ApprovalStatus, error)
+	// RequiresEmailVerification reports whether any organization the user belongs to
+	// only lets verified users log in.
+	RequiresEmailVerification(ctx context.Context, userId uuid.UUID) (bool, error)
+	// RequiresTwoFactor reports whether the user administers an organization that
+	// has made two-factor authentication mandatory for its admins.
+	RequiresTwoFactor(ctx context.Context, userId uuid.UUID) (bool, error)
+	// IsOrganizationMember reports whether the user belongs to the organization
+	// directly or through an approved membership in one of its units.
+	IsOrganizationMember(ctx context.Context, userId, organizationId uuid.UUID) (bool, error)
 }
@@ -3,20 +3,42 @@ package user_repository
 import (
 	"time"
 
+	"sekolah-madrasah/database/schemas"
+
 	"github.com/google/uuid"
 )
 
 type User struct {
-	Id              uuid.UUID
-	Email           string
-	Password        string
-	FullName        string
-	Phone           string
-	Avatar          string
-	IsSuperAdmin    bool
-	IsActive        bool
-	EmailVerifiedAt *time.Time
-	LastLoginAt     *time.Time
-	CreatedAt       time.Time
-	UpdatedAt       time.Time
+	Id                  uuid.UUID
+	Email               string
+	Password            string
+	FullName            string
+	Phone               string
+	Avatar              string
+	IsSuperAdmin        bool
+	IsActive            bool
+	MustChangePassword  bool
+	PasswordChangedAt   *time.Time
+	EmailVerifiedAt     *time.Time
+	LastLoginAt         *time.Time
+	FailedLoginAttempts int
+	LastFailedLoginAt   *time.Time
+	LockedUntil         *time.Time
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+// SignUp is the unit membership a new user asks for when registering, pending
+// until the unit's admins approve it.
+type SignUp struct {
+	UnitId uuid.UUID
+	Role   schemas.UnitMemberRole
+}
+
+// ApprovalStatus is the outcome of a user's unit sign-ups. Status is empty when
+// the user has no unit memberships at all.
+type ApprovalStatus struct {
+	Status schemas.UnitMemberApprovalStatus
+	// RejectionReason is the latest reason given when every sign-up was rejected
+	RejectionReason string
 }
@@ -47,33 +47,40 @@ func (r *userRepository) applyFilter(query *gorm.DB, filter UserFilter) *gorm.DB
 
 func (r *userRepository) toModel(schema schemas.User) User {
 	return User{
-		Id:              schema.Id,
-		Email:           schema.Email,
-		Password:        schema.Password,
-		FullName:        schema.FullName,
-		Phone:           schema.Phone,
-		Avatar:          schema.Avatar,
-		IsSuperAdmin:    schema.IsSuperAdmin,
-		IsActive:        schema.IsActive,
-		EmailVerifiedAt: schema.EmailVerifiedAt,
-		LastLoginAt:     schema.LastLoginAt,
-		CreatedAt:       schema.CreatedAt,
-		UpdatedAt:       schema.UpdatedAt,
+		Id:                  schema.Id,
+		Email:               schema.Email,
+		Password:            schema.Password,
+		FullName:            schema.FullName,
+		Phone:               schema.Phone,
+		Avatar:              schema.Avatar,
+		IsSuperAdmin:        schema.IsSuperAdmin,
+		IsActive:            schema.IsActive,
+		MustChangePassword:  schema.MustChangePassword,
+		PasswordChangedAt:   schema.PasswordChangedAt,
+		EmailVerifiedAt:     schema.EmailVerifiedAt,
+		LastLoginAt:         schema.LastLoginAt,
+		FailedLoginAttempts: schema.FailedLoginAttempts,
+		LastFailedLoginAt:   schema.LastFailedLoginAt,
+		LockedUntil:         schema.LockedUntil,
+		CreatedAt:           schema.CreatedAt,
+		UpdatedAt:           schema.UpdatedAt,
 	}
 }
 
 func (r *userRepository) toSchema(model User) schemas.User {
 	return schemas.User{
-		Id:              model.Id,
-		Email:           model.Email,
-		Password:        model.Password,
-		FullName:        model.FullName,
-		Phone:           model.Phone,
-		Avatar:          model.Avatar,
-		IsSuperAdmin:    model.IsSuperAdmin,
-		IsActive:        model.IsActive,
-		EmailVerifiedAt: model.EmailVerifiedAt,
-		LastLoginAt:     model.LastLoginAt,
+		Id:                 model.Id,
+		Email:              model.Email,
+		Password:           model.Password,
+		FullName:           model.FullName,
+		Phone:              model.Phone,
+		Avatar:             model.Avatar,
+		IsSuperAdmin:       model.IsSuperAdmin,
+		IsActive:           model.IsActive,
+		MustChangePassword: model.MustChangePassword,
+		PasswordChangedAt:  model.PasswordChangedAt,
+		EmailVerifiedAt:    model.EmailVerifiedAt,
+		LastLoginAt:        model.LastLoginAt,
 	}
 }
 
@@ -130,6 +137,38 @@ func (r *userRepository) CreateUser(ctx context.Context, user User) (User, int,
 	return r.toModel(schema), http.StatusCreated, nil
 }
 
+func (r *userRepository) CreateSignUpUser(ctx context.Context, user User, signUp SignUp) (User, int, error) {
+	schema := r.toSchema(user)
+	if schema.Id == uuid.Nil {
+		schema.Id = uuid.New()
+	}
+	now := time.Now()
+	schema.CreatedAt = now
+	schema.UpdatedAt = now
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&schema).Error; err != nil {
+			return err
+		}
+		return tx.Create(&schemas.UnitMember{
+			Id:             uuid.New(),
+			UserId:         schema.Id,
+			UnitId:         signUp.UnitId,
+			Role:           signUp.Role,
+			IsActive:       true,
+			ApprovalStatus: schemas.UnitMemberApprovalPending,
+			JoinedAt:       now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}).Error
+	})
+	if err != nil {
+		return User{}, http.StatusInternalServerError, err
+	}
+
+	return r.toModel(schema), http.StatusCreated, nil
+}
+
 func (r *userRepository) UpdateUser(ctx context.Context, filter UserFilter, user User) (int, error) {
 	query := r.db.WithContext(ctx).Model(&schemas.User{})
 	query = r.applyFilter(query, filter)
@@ -140,6 +179,7 @@ func (r *userRepository) UpdateUser(ctx context.Context, filter UserFilter, user
 
 	if user.Email != "" {
 		updates["email"] = user.Email
+		updates["email_verified_at"] = nil
 	}
 	if user.Password != "" {
 		updates["password"] = user.Password
@@ -165,6 +205,46 @@ func (r *userRepository) UpdateUser(ctx context.Context, filter UserFilter, user
 	return http.StatusOK, nil
 }
 
+func (r *userRepository) UpdatePassword(ctx context.Context, filter UserFilter, hashedPassword string, mustChange bool) (int, error) {
+	query := r.db.WithContext(ctx).Model(&schemas.User{})
+	query = r.applyFilter(query, filter)
+
+	now := time.Now()
+	result := query.Updates(map[string]interface{}{
+		"password":             hashedPassword,
+		"must_change_password": mustChange,
+		"password_changed_at":  now,
+		"updated_at":           now,
+	})
+	if result.Error != nil {
+		return http.StatusInternalServerError, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return http.StatusNotFound, gorm.ErrRecordNotFound
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *userRepository) MarkEmailVerified(ctx context.Context, filter UserFilter) (int, error) {
+	query := r.db.WithContext(ctx).Model(&schemas.User{})
+	query = r.applyFilter(query, filter)
+
+	now := time.Now()
+	result := query.Updates(map[string]interface{}{
+		"email_verified_at": now,
+		"updated_at":        now,
+	})
+	if result.Error != nil {
+		return http.StatusInternalServerError, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return http.StatusNotFound, gorm.ErrRecordNotFound
+	}
+
+	return http.StatusOK, nil
+}
+
 func (r *userRepository) DeleteUser(ctx context.Context, filter UserFilter) (int, error) {
 	query := r.db.WithContext(ctx).Model(&schemas.User{})
 	query = r.applyFilter(query, filter)
@@ -197,29 +277,147 @@ func (r *userRepository) UpdateLastLogin(ctx context.Context, filter UserFilter)
 	return http.StatusOK, nil
 }
 
-// HasPendingApproval checks if user has any warga profile with pending approval status
-func (r *userRepository) HasPendingApproval(ctx context.Context, userId uuid.UUID) (bool, error) {
-	status, err := r.GetApprovalStatus(ctx, userId)
+func (r *userRepository) RecordFailedLogin(ctx context.Context, userId uuid.UUID, lockAfter int, lockUntil time.Time) (User, int, error) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"failed_login_attempts": gorm.Expr("failed_login_attempts + 1"),
+		"last_failed_login_at":  now,
+		"updated_at":            now,
+	}
+	if lockAfter > 0 {
+		// Postgres evaluates SET expressions against the old row, so the count is +1 here too
+		updates["locked_until"] = gorm.Expr("CASE WHEN failed_login_attempts + 1 >= ? THEN ?::timestamptz ELSE locked_until END", lockAfter, lockUntil)
+	}
+
+	result := r.db.WithContext(ctx).Model(&schemas.User{}).Where("id = ?", userId).Updates(updates)
+	if result.Error != nil {
+		return User{}, http.StatusInternalServerError, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return User{}, http.StatusNotFound, gorm.ErrRecordNotFound
+	}
+
+	return r.GetUser(ctx, UserFilter{Id: &userId})
+}
+
+func (r *userRepository) ResetFailedLogins(ctx context.Context, filter UserFilter) (int, error) {
+	query := r.db.WithContext(ctx).Model(&schemas.User{})
+	query = r.applyFilter(query, filter)
+
+	result := query.Updates(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"last_failed_login_at":  nil,
+		"locked_until":          nil,
+		"updated_at":            time.Now(),
+	})
+	if result.Error != nil {
+		return http.StatusInternalServerError, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return http.StatusNotFound, gorm.ErrRecordNotFound
+	}
+
+	return http.StatusOK, nil
+}
+
+// GetApprovalStatus is approved as soon as one unit sign-up was approved or the
+// user belongs to an organization directly. Otherwise it is pending while any
+// sign-up awaits review, and rejected once all of them were turned down.
+func (r *userRepository) GetApprovalStatus(ctx context.Context, userId uuid.UUID) (ApprovalStatus, error) {
+	var orgMemberships int64
+	err := r.db.WithContext(ctx).Model(&schemas.OrganizationMember{}).
+		Where("user_id = ?", userId).
+		Count(&orgMemberships).Error
+	if err != nil {
+		return ApprovalStatus{}, err
+	}
+	if orgMemberships > 0 {
+		return ApprovalStatus{Status: schemas.UnitMemberApprovalApproved}, nil
+	}
+
+	var members []schemas.UnitMember
+	err = r.db.WithContext(ctx).
+		Select("approval_status", "rejection_reason").
+		Where("user_id = ?", userId).
+		Order("reviewed_at DESC NULLS LAST").
+		Find(&members).Error
+	if err != nil {
+		return ApprovalStatus{}, err
+	}
+
+	var result ApprovalStatus
+	for _, member := range members {
+		switch member.ApprovalStatus {
+		case schemas.UnitMemberApprovalApproved:
+			return ApprovalStatus{Status: schemas.UnitMemberApprovalApproved}, nil
+		case schemas.UnitMemberApprovalPending:
+			result = ApprovalStatus{Status: schemas.UnitMemberApprovalPending}
+		case schemas.UnitMemberApprovalRejected:
+			if result.Status == "" {
+				result = ApprovalStatus{Status: schemas.UnitMemberApprovalRejected, RejectionReason: member.RejectionReason}
+			}
+		}
+	}
+	return result, nil
+}
+
+// RequiresEmailVerification checks the require_email_verification setting of every
+// organization the user belongs to, directly or through a unit membership.
+func (r *userRepository) RequiresEmailVerification(ctx context.Context, userId uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Table("organizations").
+		Where("organizations.deleted_at IS NULL").
+		Where("organizations.settings->>'require_email_verification' = ?", "true").
+		Where(`organizations.id IN (
+			SELECT organization_id FROM organization_members WHERE user_id = ? AND deleted_at IS NULL
+			UNION
+			SELECT units.organization_id FROM unit_members
+			JOIN units ON units.id = unit_members.unit_id
+			WHERE unit_members.user_id = ? AND unit_members.deleted_at IS NULL
+		)`, userId, userId).
+		Count(&count).Error
 	if err != nil {
 		return false, err
 	}
-	return status == "pending", nil
+	return count > 0, nil
 }
 
-// GetApprovalStatus returns the approval status of a user's warga profile
-// Returns: "pending", "rejected", "approved", or "" if no warga profile
-func (r *userRepository) GetApprovalStatus(ctx context.Context, userId uuid.UUID) (string, error) {
-	var status string
+// RequiresTwoFactor treats a member as an organization admin when their role grants
+// organizations.update, and checks that organization's require_two_factor setting.
+func (r *userRepository) RequiresTwoFactor(ctx context.Context, userId uuid.UUID) (bool, error) {
+	var count int64
 	err := r.db.WithContext(ctx).
-		Table("warga_profiles").
-		Select("warga_profiles.approval_status").
-		Joins("JOIN unit_members ON unit_members.id = warga_profiles.unit_member_id").
-		Where("unit_members.user_id = ?", userId).
-		Where("warga_profiles.deleted_at IS NULL").
-		Limit(1).
-		Scan(&status).Error
+		Table("organization_members").
+		Joins("JOIN organizations ON organizations.id = organization_members.organization_id AND organizations.deleted_at IS NULL").
+		Joins("JOIN role_permissions ON role_permissions.role_id = organization_members.role_id").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Where("organization_members.user_id = ?", userId).
+		Where("organization_members.is_active = ?", true).
+		Where("organization_members.deleted_at IS NULL").
+		Where("permissions.name = ?", "organizations.update").
+		Where("organizations.settings->>'require_two_factor' = ?", "true").
+		Count(&count).Error
 	if err != nil {
-		return "", err
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *userRepository) IsOrganizationMember(ctx context.Context, userId, organizationId uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Raw(`SELECT COUNT(*) FROM (
+			SELECT 1 FROM organization_members
+			WHERE user_id = ? AND organization_id = ? AND deleted_at IS NULL
+			UNION ALL
+			SELECT 1 FROM unit_members
+			JOIN units ON units.id = unit_members.unit_id
+			WHERE unit_members.user_id = ? AND units.organization_id = ? AND unit_members.deleted_at IS NULL
+				AND unit_members.approval_status = 'approved'
+		) AS memberships`, userId, organizationId, userId, organizationId).
+		Scan(&count).Error
+	if err != nil {
+		return false, err
 	}
-	return status, nil
+	return count > 0, nil
 }
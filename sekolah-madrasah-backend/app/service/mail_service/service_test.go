@@ -0,0 +1,42 @@
+package mail_service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMailer_WritesMessage(t *testing.T) {
+	dir := t.TempDir()
+	mailer := NewFileMailer(dir, "no-reply@example.com")
+
+	err := mailer.Send(context.Background(), Message{
+		To:      []string{"guru@example.com"},
+		Subject: "Atur ulang kata sandi",
+		Body:    "line one\nline two",
+	})
+	require.NoError(t, err)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.Len(t, files, 1)
+
+	content, _ := os.ReadFile(files[0])
+	assert.Contains(t, string(content), "To: guru@example.com\r\n")
+	assert.Contains(t, string(content), "line one\r\nline two")
+}
+
+func TestBuildMessage_RejectsHeaderInjection(t *testing.T) {
+	_, err := buildMessage("no-reply@example.com", Message{To: []string{"a@example.com\r\nBcc: b@example.com"}})
+
+	assert.Error(t, err)
+}
+
+func TestBuildMessage_RequiresRecipient(t *testing.T) {
+	_, err := buildMessage("no-reply@example.com", Message{Subject: "hi"})
+
+	assert.Error(t, err)
+}
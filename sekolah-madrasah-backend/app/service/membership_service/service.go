@@ -38,6 +38,8 @@ type UnitMembership struct {
 	OrgName      string    `json:"org_name"`
 	Role         string    `json:"role"` // pengurus, warga, admin, staff, parent
 	IsActive     bool      `json:"is_active"`
+	// ApprovalStatus shows sign-ups that are still waiting for the unit's admins
+	ApprovalStatus string `json:"approval_status"`
 }
 
 type UserMemberships struct {
@@ -102,6 +104,8 @@ func (s *membershipService) GetUserMemberships(ctx context.Context, userId uuid.
 			UnitId:       sm.UnitId,
 			Role:         string(sm.Role),
 			IsActive:     sm.IsActive,
+
+			ApprovalStatus: string(sm.ApprovalStatus),
 		}
 		if sm.Unit != nil {
 			membership.UnitName = sm.Unit.Name
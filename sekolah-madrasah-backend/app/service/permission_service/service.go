@@ -0,0 +1,197 @@
+package permission_service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DefaultCacheTTL keeps resolved permissions short-lived so role changes
+// take effect quickly without hitting the database on every request.
+const DefaultCacheTTL = time.Minute
+
+// PermissionService resolves a user's permissions from role_permissions
+// through organization_members.role_id. It satisfies
+// http_middleware.PermissionChecker.
+type PermissionService interface {
+	HasPermission(userID string, permission string) bool
+	GetUserPermissions(userID string) []string
+	IsSuperAdmin(userID string) bool
+	// HasOrganizationPermission reports whether the user's role in that
+	// organization grants the permission; roles held elsewhere do not count.
+	HasOrganizationPermission(userID string, organizationId uuid.UUID, permission string) bool
+	Invalidate(userID string)
+	InvalidateAll()
+}
+
+type userPermissions struct {
+	isSuperAdmin   bool
+	names          map[string]struct{}
+	byOrganization map[uuid.UUID]map[string]struct{}
+	expiresAt      time.Time
+}
+
+type permissionService struct {
+	db    *gorm.DB
+	ttl   time.Duration
+	now   func() time.Time
+	load  func(ctx context.Context, userId uuid.UUID) (userPermissions, error)
+	mu    sync.RWMutex
+	cache map[string]userPermissions
+}
+
+func NewPermissionService(db *gorm.DB, ttl time.Duration) PermissionService {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	s := &permissionService{
+		db:    db,
+		ttl:   ttl,
+		now:   time.Now,
+		cache: make(map[string]userPermissions),
+	}
+	s.load = s.loadFromDB
+	return s
+}
+
+func (s *permissionService) HasPermission(userID string, permission string) bool {
+	perms, ok := s.resolve(userID)
+	if !ok {
+		return false
+	}
+	if perms.isSuperAdmin {
+		return true
+	}
+	_, granted := perms.names[permission]
+	return granted
+}
+
+func (s *permissionService) GetUserPermissions(userID string) []string {
+	perms, ok := s.resolve(userID)
+	if !ok {
+		return []string{}
+	}
+
+	result := make([]string, 0, len(perms.names))
+	for name := range perms.names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (s *permissionService) IsSuperAdmin(userID string) bool {
+	perms, ok := s.resolve(userID)
+	return ok && perms.isSuperAdmin
+}
+
+func (s *permissionService) HasOrganizationPermission(userID string, organizationId uuid.UUID, permission string) bool {
+	perms, ok := s.resolve(userID)
+	if !ok {
+		return false
+	}
+	if perms.isSuperAdmin {
+		return true
+	}
+	_, granted := perms.byOrganization[organizationId][permission]
+	return granted
+}
+
+func (s *permissionService) Invalidate(userID string) {
+	s.mu.Lock()
+	delete(s.cache, userID)
+	s.mu.Unlock()
+}
+
+func (s *permissionService) InvalidateAll() {
+	s.mu.Lock()
+	s.cache = make(map[string]userPermissions)
+	s.mu.Unlock()
+}
+
+func (s *permissionService) resolve(userID string) (userPermissions, bool) {
+	s.mu.RLock()
+	cached, found := s.cache[userID]
+	s.mu.RUnlock()
+	if found && s.now().Before(cached.expiresAt) {
+		return cached, true
+	}
+
+	userId, err := uuid.Parse(userID)
+	if err != nil {
+		return userPermissions{}, false
+	}
+
+	perms, err := s.load(context.Background(), userId)
+	if err != nil {
+		// Do not cache failures, the next request retries the lookup
+		return userPermissions{}, false
+	}
+	perms.expiresAt = s.now().Add(s.ttl)
+
+	s.mu.Lock()
+	s.cache[userID] = perms
+	s.mu.Unlock()
+
+	return perms, true
+}
+
+func (s *permissionService) loadFromDB(ctx context.Context, userId uuid.UUID) (userPermissions, error) {
+	result := userPermissions{
+		names:          make(map[string]struct{}),
+		byOrganization: make(map[uuid.UUID]map[string]struct{}),
+	}
+
+	var user schemas.User
+	if err := s.db.WithContext(ctx).Select("id", "is_super_admin", "is_active").
+		Where("id = ?", userId).First(&user).Error; err != nil {
+		return result, err
+	}
+	if !user.IsActive {
+		return result, nil
+	}
+
+	if user.IsSuperAdmin {
+		var names []string
+		result.isSuperAdmin = true
+		if err := s.db.WithContext(ctx).Model(&schemas.Permission{}).Pluck("name", &names).Error; err != nil {
+			return result, err
+		}
+		for _, name := range names {
+			result.names[name] = struct{}{}
+		}
+		return result, nil
+	}
+
+	var grants []struct {
+		OrganizationId uuid.UUID
+		Name           string
+	}
+	err := s.db.WithContext(ctx).
+		Table("permissions").
+		Distinct("organization_members.organization_id", "permissions.name").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN organization_members ON organization_members.role_id = role_permissions.role_id").
+		Where("organization_members.user_id = ?", userId).
+		Where("organization_members.is_active = ?", true).
+		Where("organization_members.deleted_at IS NULL").
+		Scan(&grants).Error
+	if err != nil {
+		return result, err
+	}
+
+	for _, grant := range grants {
+		result.names[grant.Name] = struct{}{}
+		if result.byOrganization[grant.OrganizationId] == nil {
+			result.byOrganization[grant.OrganizationId] = make(map[string]struct{})
+		}
+		result.byOrganization[grant.OrganizationId][grant.Name] = struct{}{}
+	}
+	return result, nil
+}
@@ -0,0 +1,121 @@
+package permission_service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubLoader struct {
+	calls int
+	perms userPermissions
+	err   error
+}
+
+func (l *stubLoader) load(ctx context.Context, userId uuid.UUID) (userPermissions, error) {
+	l.calls++
+	return l.perms, l.err
+}
+
+func newTestService(loader *stubLoader, now *time.Time) *permissionService {
+	s := NewPermissionService(nil, time.Minute).(*permissionService)
+	s.load = loader.load
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+func namesOf(names ...string) map[string]struct{} {
+	set := make(map[string]struct{}, len(names))
+	for _, n := range names {
+		set[n] = struct{}{}
+	}
+	return set
+}
+
+func TestHasPermission_GrantedAndDenied(t *testing.T) {
+	now := time.Now()
+	loader := &stubLoader{perms: userPermissions{names: namesOf("units.read", "units.update")}}
+	s := newTestService(loader, &now)
+	userID := uuid.New().String()
+
+	assert.True(t, s.HasPermission(userID, "units.update"))
+	assert.False(t, s.HasPermission(userID, "units.delete"))
+	assert.Equal(t, []string{"units.read", "units.update"}, s.GetUserPermissions(userID))
+}
+
+func TestHasPermission_SuperAdminBypass(t *testing.T) {
+	now := time.Now()
+	loader := &stubLoader{perms: userPermissions{isSuperAdmin: true, names: namesOf()}}
+	s := newTestService(loader, &now)
+
+	assert.True(t, s.HasPermission(uuid.New().String(), "organizations.delete"))
+	assert.True(t, s.IsSuperAdmin(uuid.New().String()))
+}
+
+func TestHasPermission_CachesUntilExpiry(t *testing.T) {
+	now := time.Now()
+	loader := &stubLoader{perms: userPermissions{names: namesOf("posts.delete")}}
+	s := newTestService(loader, &now)
+	userID := uuid.New().String()
+
+	s.HasPermission(userID, "posts.delete")
+	s.HasPermission(userID, "posts.delete")
+	assert.Equal(t, 1, loader.calls)
+
+	now = now.Add(2 * time.Minute)
+	s.HasPermission(userID, "posts.delete")
+	assert.Equal(t, 2, loader.calls)
+}
+
+func TestInvalidate_ForcesReload(t *testing.T) {
+	now := time.Now()
+	loader := &stubLoader{perms: userPermissions{names: namesOf("posts.delete")}}
+	s := newTestService(loader, &now)
+	userID := uuid.New().String()
+
+	assert.True(t, s.HasPermission(userID, "posts.delete"))
+
+	loader.perms = userPermissions{names: namesOf()}
+	s.Invalidate(userID)
+	assert.False(t, s.HasPermission(userID, "posts.delete"))
+	assert.Equal(t, 2, loader.calls)
+}
+
+func TestHasPermission_LoadErrorIsDeniedAndNotCached(t *testing.T) {
+	now := time.Now()
+	loader := &stubLoader{err: errors.New("db down")}
+	s := newTestService(loader, &now)
+	userID := uuid.New().String()
+
+	assert.False(t, s.HasPermission(userID, "units.read"))
+	assert.False(t, s.HasPermission(userID, "units.read"))
+	assert.Equal(t, 2, loader.calls)
+}
+
+func TestHasPermission_InvalidUserID(t *testing.T) {
+	now := time.Now()
+	loader := &stubLoader{}
+	s := newTestService(loader, &now)
+
+	assert.False(t, s.HasPermission("not-a-uuid", "units.read"))
+	assert.Equal(t, 0, loader.calls)
+	assert.Empty(t, s.GetUserPermissions("not-a-uuid"))
+}
+
+func TestHasOrganizationPermission_OnlyInThatOrganization(t *testing.T) {
+	now := time.Now()
+	orgA, orgB := uuid.New(), uuid.New()
+	loader := &stubLoader{perms: userPermissions{
+		names:          namesOf("api_keys.create"),
+		byOrganization: map[uuid.UUID]map[string]struct{}{orgA: namesOf("api_keys.create")},
+	}}
+	s := newTestService(loader, &now)
+	userID := uuid.New().String()
+
+	assert.True(t, s.HasOrganizationPermission(userID, orgA, "api_keys.create"))
+	assert.False(t, s.HasOrganizationPermission(userID, orgB, "api_keys.create"))
+}
@@ -0,0 +1,82 @@
+package rate_limit_service
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"sekolah-madrasah/database/schemas"
+	"sekolah-madrasah/pkg/http_middleware"
+
+	"github.com/labstack/gommon/log"
+	"gorm.io/gorm"
+)
+
+// CleanupInterval is how often expired counters are deleted
+const CleanupInterval = 5 * time.Minute
+
+type postgresStore struct {
+	db *gorm.DB
+}
+
+// NewPostgresStore returns a http_middleware.RateLimitStore that keeps its counters
+// in rate_limit_counters, so every replica using the same database shares them.
+func NewPostgresStore(db *gorm.DB) http_middleware.RateLimitStore {
+	s := &postgresStore{db: db}
+
+	go s.cleanup(CleanupInterval)
+
+	return s
+}
+
+func (s *postgresStore) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	for range ticker.C {
+		err := s.db.Where("expires_at < ?", time.Now()).Delete(&schemas.RateLimitCounter{}).Error
+		if err != nil {
+			log.Errorf("failed to delete expired rate limit counters: %v", err)
+		}
+	}
+}
+
+// Take uses a sliding window counter: the count of the current fixed window plus
+// the previous window's count weighted by how much of it still overlaps the
+// sliding window. Rejected requests are counted too, so a client that keeps
+// retrying stays limited.
+func (s *postgresStore) Take(ctx context.Context, key string, limit int, window time.Duration) (http_middleware.RateLimitResult, error) {
+	now := time.Now()
+	windowStart := now.Truncate(window)
+	previousStart := windowStart.Add(-window)
+
+	var counts struct {
+		Current  int64
+		Previous int64
+	}
+	err := s.db.WithContext(ctx).Raw(`
+		WITH current AS (
+			INSERT INTO rate_limit_counters (key, window_start, count, expires_at)
+			VALUES (?, ?, 1, ?)
+			ON CONFLICT (key, window_start) DO UPDATE SET count = rate_limit_counters.count + 1
+			RETURNING count
+		)
+		SELECT current.count AS current,
+			COALESCE((SELECT count FROM rate_limit_counters WHERE key = ? AND window_start = ?), 0) AS previous
+		FROM current`,
+		key, windowStart, windowStart.Add(2*window), key, previousStart,
+	).Scan(&counts).Error
+	if err != nil {
+		return http_middleware.RateLimitResult{}, err
+	}
+
+	overlap := 1 - float64(now.Sub(windowStart))/float64(window)
+	estimated := float64(counts.Previous)*overlap + float64(counts.Current)
+
+	result := http_middleware.RateLimitResult{
+		Allowed: estimated <= float64(limit),
+		Limit:   limit,
+		Reset:   windowStart.Add(window).Sub(now),
+	}
+	result.Remaining = max(limit-int(math.Ceil(estimated)), 0)
+
+	return result, nil
+}
@@ -0,0 +1,170 @@
+package unit_access_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UnitAccessService decides whether a user may act inside a unit and with
+// which effective UnitMemberRole. It satisfies http_middleware.UnitAccessResolver
+// and http_middleware.OrganizationAccessResolver.
+type UnitAccessService interface {
+	ResolveUnitRole(ctx context.Context, userId, unitId uuid.UUID) (schemas.UnitMemberRole, int, error)
+	ResolveOwningUnit(ctx context.Context, resource string, id uuid.UUID) (uuid.UUID, int, error)
+	ResolveUnitOrganization(ctx context.Context, unitId uuid.UUID) (uuid.UUID, int, error)
+	ResolveOrganizationOwner(ctx context.Context, organizationId uuid.UUID) (uuid.UUID, int, error)
+	ResolveUserOrganizations(ctx context.Context, userId uuid.UUID) ([]uuid.UUID, int, error)
+}
+
+type unitAccessService struct {
+	db *gorm.DB
+}
+
+func NewUnitAccessService(db *gorm.DB) UnitAccessService {
+	return &unitAccessService{db: db}
+}
+
+// ResolveUnitRole returns the caller's effective role in the unit.
+// Super admins and the owner of the unit's organization act as owner,
+// everyone else needs an active, approved unit_members row.
+func (s *unitAccessService) ResolveUnitRole(ctx context.Context, userId, unitId uuid.UUID) (schemas.UnitMemberRole, int, error) {
+	var unit schemas.Unit
+	if err := s.db.WithContext(ctx).Preload("Organization").
+		Where("id = ?", unitId).First(&unit).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", http.StatusNotFound, errors.New("unit not found")
+		}
+		return "", http.StatusInternalServerError, err
+	}
+
+	var user schemas.User
+	if err := s.db.WithContext(ctx).Select("id", "is_super_admin").
+		Where("id = ?", userId).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", http.StatusForbidden, errors.New("you do not have access to this unit")
+		}
+		return "", http.StatusInternalServerError, err
+	}
+	if user.IsSuperAdmin {
+		return schemas.UnitMemberRoleOwner, http.StatusOK, nil
+	}
+	if unit.Organization != nil && unit.Organization.OwnerId == userId {
+		return schemas.UnitMemberRoleOwner, http.StatusOK, nil
+	}
+
+	var member schemas.UnitMember
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND unit_id = ? AND is_active = ?", userId, unitId, true).
+		Where("approval_status = ?", schemas.UnitMemberApprovalApproved).
+		First(&member).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", http.StatusForbidden, errors.New("you do not have access to this unit")
+		}
+		return "", http.StatusInternalServerError, err
+	}
+
+	return member.Role, http.StatusOK, nil
+}
+
+// ResolveOwningUnit returns the unit a nested resource belongs to.
+// The resource is identified by its table name.
+func (s *unitAccessService) ResolveOwningUnit(ctx context.Context, resource string, id uuid.UUID) (uuid.UUID, int, error) {
+	var unitIds []uuid.UUID
+	query := s.db.WithContext(ctx)
+
+	switch resource {
+	case schemas.UnitMember{}.TableName(), schemas.TeacherProfile{}.TableName(),
+		schemas.StudentProfile{}.TableName(), schemas.Class{}.TableName(),
+		schemas.Subject{}.TableName(), schemas.Activity{}.TableName(),
+		schemas.TimetableEntry{}.TableName(), schemas.Assessment{}.TableName():
+		query = query.Table(resource).
+			Where(resource+".id = ?", id).
+			Where(resource+".deleted_at IS NULL").
+			Limit(1).
+			Pluck(resource+".unit_id", &unitIds)
+	case schemas.TimetableGeneration{}.TableName(), schemas.GradeThreshold{}.TableName(),
+		schemas.Graduation{}.TableName():
+		query = query.Table(resource).
+			Where("id = ?", id).
+			Limit(1).
+			Pluck("unit_id", &unitIds)
+	case schemas.ClassEnrollment{}.TableName():
+		query = query.Table("class_enrollments").
+			Joins("JOIN classes ON classes.id = class_enrollments.class_id").
+			Where("class_enrollments.id = ?", id).
+			Where("classes.deleted_at IS NULL").
+			Limit(1).
+			Pluck("classes.unit_id", &unitIds)
+	default:
+		return uuid.Nil, http.StatusInternalServerError, fmt.Errorf("unknown unit resource %q", resource)
+	}
+
+	if query.Error != nil {
+		return uuid.Nil, http.StatusInternalServerError, query.Error
+	}
+	if len(unitIds) == 0 {
+		return uuid.Nil, http.StatusNotFound, errors.New("resource not found")
+	}
+	return unitIds[0], http.StatusOK, nil
+}
+
+// ResolveUnitOrganization returns the organization a unit belongs to.
+func (s *unitAccessService) ResolveUnitOrganization(ctx context.Context, unitId uuid.UUID) (uuid.UUID, int, error) {
+	var orgIds []uuid.UUID
+	err := s.db.WithContext(ctx).Model(&schemas.Unit{}).
+		Where("id = ?", unitId).
+		Limit(1).
+		Pluck("organization_id", &orgIds).Error
+	if err != nil {
+		return uuid.Nil, http.StatusInternalServerError, err
+	}
+	if len(orgIds) == 0 {
+		return uuid.Nil, http.StatusNotFound, errors.New("unit not found")
+	}
+	return orgIds[0], http.StatusOK, nil
+}
+
+// ResolveOrganizationOwner returns the owner of an organization.
+func (s *unitAccessService) ResolveOrganizationOwner(ctx context.Context, organizationId uuid.UUID) (uuid.UUID, int, error) {
+	var ownerIds []uuid.UUID
+	err := s.db.WithContext(ctx).Model(&schemas.Organization{}).
+		Where("id = ?", organizationId).
+		Limit(1).
+		Pluck("owner_id", &ownerIds).Error
+	if err != nil {
+		return uuid.Nil, http.StatusInternalServerError, err
+	}
+	if len(ownerIds) == 0 {
+		return uuid.Nil, http.StatusNotFound, errors.New("organization not found")
+	}
+	return ownerIds[0], http.StatusOK, nil
+}
+
+// ResolveUserOrganizations returns the organizations the user is an active
+// member or the owner of.
+func (s *unitAccessService) ResolveUserOrganizations(ctx context.Context, userId uuid.UUID) ([]uuid.UUID, int, error) {
+	var memberOf []uuid.UUID
+	err := s.db.WithContext(ctx).Model(&schemas.OrganizationMember{}).
+		Where("user_id = ? AND is_active = ?", userId, true).
+		Pluck("organization_id", &memberOf).Error
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	var owned []uuid.UUID
+	err = s.db.WithContext(ctx).Model(&schemas.Organization{}).
+		Where("owner_id = ?", userId).
+		Pluck("id", &owned).Error
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return append(memberOf, owned...), http.StatusOK, nil
+}
@@ -0,0 +1,26 @@
+package api_key_use_case
+
+import (
+	"context"
+
+	"sekolah-madrasah/pkg/auth_utils"
+	"sekolah-madrasah/pkg/paginate_utils"
+
+	"github.com/google/uuid"
+)
+
+type ApiKeyUseCase interface {
+	// CreateApiKey returns the plaintext key once; only its hash is stored.
+	CreateApiKey(ctx context.Context, actor auth_utils.AuthClaim, organizationId uuid.UUID, req CreateApiKeyRequest, ipAddress string) (CreatedApiKey, int, error)
+	GetApiKeys(ctx context.Context, actor auth_utils.AuthClaim, organizationId uuid.UUID, paginate *paginate_utils.PaginateData) ([]ApiKey, int, error)
+	RevokeApiKey(ctx context.Context, actor auth_utils.AuthClaim, organizationId, keyId uuid.UUID, ipAddress string) (int, error)
+
+	AuthenticateApiKey(ctx context.Context, rawKey, ipAddress string) (*auth_utils.AuthClaim, int, error)
+}
+
+// PermissionChecker reports what the creator of a key holds in the key's
+// organization, so a key can never carry more than the person who issued it.
+type PermissionChecker interface {
+	HasOrganizationPermission(userID string, organizationId uuid.UUID, permission string) bool
+	IsSuperAdmin(userID string) bool
+}
@@ -0,0 +1,33 @@
+package api_key_use_case
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ApiKey struct {
+	Id             uuid.UUID
+	OrganizationId uuid.UUID
+	Name           string
+	Prefix         string
+	Scopes         []string
+	CreatedBy      uuid.UUID
+	ExpiresAt      *time.Time
+	LastUsedAt     *time.Time
+	LastUsedIp     string
+	RevokedAt      *time.Time
+	CreatedAt      time.Time
+}
+
+type CreateApiKeyRequest struct {
+	Name      string
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+type CreatedApiKey struct {
+	ApiKey
+	// Key is the plaintext key. It cannot be retrieved again.
+	Key string
+}
@@ -0,0 +1,341 @@
+package api_key_use_case
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"sekolah-madrasah/app/repository/api_key_repository"
+	"sekolah-madrasah/app/repository/audit_repository"
+	"sekolah-madrasah/app/repository/org_member_repository"
+	"sekolah-madrasah/app/repository/organization_repository"
+	"sekolah-madrasah/app/repository/permission_repository"
+	"sekolah-madrasah/pkg/auth_utils"
+	"sekolah-madrasah/pkg/paginate_utils"
+
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	// Keys look like smk_<prefix>_<secret>. The prefix is shown in listings so
+	// administrators can tell keys apart without seeing the secret.
+	keyPrefix       = "smk_"
+	prefixLength    = 8
+	secretBytes     = 32
+	prefixAlphabet  = "abcdefghijklmnopqrstuvwxyz0123456789"
+	maxScopes       = 50
+	touchInterval   = time.Minute
+	errInvalidKey   = "invalid API key"
+	errKeyExpired   = "API key has expired"
+	errKeyForbidden = "API keys cannot manage API keys"
+	// Only organization admins, whose role in the organization may update it,
+	// manage its keys.
+	managerPermission = "organizations.update"
+)
+
+type apiKeyUseCase struct {
+	apiKeyRepo        api_key_repository.ApiKeyRepository
+	orgRepo           organization_repository.OrganizationRepository
+	orgMemberRepo     org_member_repository.OrgMemberRepository
+	permissionRepo    permission_repository.PermissionRepository
+	auditRepo         audit_repository.AuditRepository
+	permissionChecker PermissionChecker
+}
+
+func NewApiKeyUseCase(
+	apiKeyRepo api_key_repository.ApiKeyRepository,
+	orgRepo organization_repository.OrganizationRepository,
+	orgMemberRepo org_member_repository.OrgMemberRepository,
+	permissionRepo permission_repository.PermissionRepository,
+	auditRepo audit_repository.AuditRepository,
+	permissionChecker PermissionChecker,
+) ApiKeyUseCase {
+	return &apiKeyUseCase{
+		apiKeyRepo:        apiKeyRepo,
+		orgRepo:           orgRepo,
+		orgMemberRepo:     orgMemberRepo,
+		permissionRepo:    permissionRepo,
+		auditRepo:         auditRepo,
+		permissionChecker: permissionChecker,
+	}
+}
+
+func (u *apiKeyUseCase) toApiKey(k api_key_repository.ApiKey) ApiKey {
+	return ApiKey{
+		Id:             k.Id,
+		OrganizationId: k.OrganizationId,
+		Name:           k.Name,
+		Prefix:         k.Prefix,
+		Scopes:         k.Scopes,
+		CreatedBy:      k.CreatedBy,
+		ExpiresAt:      k.ExpiresAt,
+		LastUsedAt:     k.LastUsedAt,
+		LastUsedIp:     k.LastUsedIp,
+		RevokedAt:      k.RevokedAt,
+		CreatedAt:      k.CreatedAt,
+	}
+}
+
+func hashKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateKey() (prefix, rawKey string, err error) {
+	prefixChars := make([]byte, prefixLength)
+	for i := range prefixChars {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(prefixAlphabet))))
+		if err != nil {
+			return "", "", err
+		}
+		prefixChars[i] = prefixAlphabet[n.Int64()]
+	}
+
+	secret := make([]byte, secretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+
+	prefix = keyPrefix + string(prefixChars)
+	return prefix, prefix + "_" + hex.EncodeToString(secret), nil
+}
+
+// authorizeManager allows super admins, the organization owner and the
+// organization's admins to manage its keys. It reports whether the actor holds
+// every permission in the organization, as super admins and owners do.
+func (u *apiKeyUseCase) authorizeManager(ctx context.Context, actor auth_utils.AuthClaim, organizationId uuid.UUID) (bool, int, error) {
+	if actor.IsApiKey() {
+		return false, http.StatusForbidden, errors.New(errKeyForbidden)
+	}
+
+	org, code, err := u.orgRepo.GetOrganization(ctx, organization_repository.OrganizationFilter{Id: &organizationId})
+	if err != nil {
+		return false, code, err
+	}
+
+	if u.permissionChecker.IsSuperAdmin(actor.UserID.String()) || org.OwnerId == actor.UserID {
+		return true, http.StatusOK, nil
+	}
+
+	code, err = u.activeMember(ctx, organizationId, actor.UserID)
+	if err != nil {
+		return false, code, err
+	}
+	if !u.permissionChecker.HasOrganizationPermission(actor.UserID.String(), organizationId, managerPermission) {
+		return false, http.StatusForbidden, errors.New("only the organization's admins can manage its API keys")
+	}
+
+	return false, http.StatusOK, nil
+}
+
+// activeMember refuses users who are not, or no longer, active members of the organization.
+func (u *apiKeyUseCase) activeMember(ctx context.Context, organizationId, userId uuid.UUID) (int, error) {
+	isActive := true
+	_, code, err := u.orgMemberRepo.GetMember(ctx, org_member_repository.OrgMemberFilter{
+		OrganizationId: &organizationId,
+		UserId:         &userId,
+		IsActive:       &isActive,
+	})
+	if err != nil {
+		if code == http.StatusNotFound {
+			return http.StatusForbidden, errors.New("you are not a member of this organization")
+		}
+		return code, err
+	}
+	return http.StatusOK, nil
+}
+
+func (u *apiKeyUseCase) CreateApiKey(ctx context.Context, actor auth_utils.AuthClaim, organizationId uuid.UUID, req CreateApiKeyRequest, ipAddress string) (CreatedApiKey, int, error) {
+	holdsAll, code, err := u.authorizeManager(ctx, actor, organizationId)
+	if err != nil {
+		return CreatedApiKey{}, code, err
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return CreatedApiKey{}, http.StatusBadRequest, errors.New("name is required")
+	}
+	if len(req.Scopes) == 0 {
+		return CreatedApiKey{}, http.StatusBadRequest, errors.New("at least one scope is required")
+	}
+	if len(req.Scopes) > maxScopes {
+		return CreatedApiKey{}, http.StatusBadRequest, fmt.Errorf("at most %d scopes are allowed", maxScopes)
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return CreatedApiKey{}, http.StatusBadRequest, errors.New("expires_at must be in the future")
+	}
+
+	scopes := make([]string, 0, len(req.Scopes))
+	seen := make(map[string]struct{}, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		scope = strings.TrimSpace(scope)
+		if _, ok := seen[scope]; ok {
+			continue
+		}
+		seen[scope] = struct{}{}
+
+		if _, code, err := u.permissionRepo.GetPermission(ctx, permission_repository.PermissionFilter{Name: &scope}); err != nil {
+			if code == http.StatusNotFound {
+				return CreatedApiKey{}, http.StatusBadRequest, fmt.Errorf("unknown scope %q", scope)
+			}
+			return CreatedApiKey{}, code, err
+		}
+		if !holdsAll && !u.permissionChecker.HasOrganizationPermission(actor.UserID.String(), organizationId, scope) {
+			return CreatedApiKey{}, http.StatusForbidden, fmt.Errorf("you cannot grant scope %q", scope)
+		}
+		scopes = append(scopes, scope)
+	}
+
+	prefix, rawKey, err := generateKey()
+	if err != nil {
+		return CreatedApiKey{}, http.StatusInternalServerError, err
+	}
+
+	key, code, err := u.apiKeyRepo.CreateApiKey(ctx, api_key_repository.ApiKey{
+		OrganizationId: organizationId,
+		Name:           req.Name,
+		Prefix:         prefix,
+		KeyHash:        hashKey(rawKey),
+		Scopes:         scopes,
+		CreatedBy:      actor.UserID,
+		ExpiresAt:      req.ExpiresAt,
+	})
+	if err != nil {
+		return CreatedApiKey{}, code, err
+	}
+
+	u.audit(ctx, actor, audit_repository.ActionApiKeyCreated, ipAddress, map[string]interface{}{
+		"api_key_id":      key.Id,
+		"organization_id": organizationId,
+		"prefix":          prefix,
+		"scopes":          scopes,
+	})
+
+	return CreatedApiKey{ApiKey: u.toApiKey(key), Key: rawKey}, http.StatusCreated, nil
+}
+
+func (u *apiKeyUseCase) GetApiKeys(ctx context.Context, actor auth_utils.AuthClaim, organizationId uuid.UUID, paginate *paginate_utils.PaginateData) ([]ApiKey, int, error) {
+	if _, code, err := u.authorizeManager(ctx, actor, organizationId); err != nil {
+		return nil, code, err
+	}
+
+	keys, code, err := u.apiKeyRepo.GetApiKeys(ctx, api_key_repository.ApiKeyFilter{OrganizationId: &organizationId}, paginate)
+	if err != nil {
+		return nil, code, err
+	}
+
+	result := make([]ApiKey, len(keys))
+	for i, k := range keys {
+		result[i] = u.toApiKey(k)
+	}
+
+	return result, http.StatusOK, nil
+}
+
+func (u *apiKeyUseCase) RevokeApiKey(ctx context.Context, actor auth_utils.AuthClaim, organizationId, keyId uuid.UUID, ipAddress string) (int, error) {
+	_, code, err := u.authorizeManager(ctx, actor, organizationId)
+	if err != nil {
+		return code, err
+	}
+
+	code, err = u.apiKeyRepo.RevokeApiKey(ctx, api_key_repository.ApiKeyFilter{
+		Id:             &keyId,
+		OrganizationId: &organizationId,
+	})
+	if err != nil {
+		if code == http.StatusNotFound {
+			return code, errors.New("API key not found or already revoked")
+		}
+		return code, err
+	}
+
+	u.audit(ctx, actor, audit_repository.ActionApiKeyRevoked, ipAddress, map[string]interface{}{
+		"api_key_id":      keyId,
+		"organization_id": organizationId,
+	})
+
+	return http.StatusOK, nil
+}
+
+func (u *apiKeyUseCase) AuthenticateApiKey(ctx context.Context, rawKey, ipAddress string) (*auth_utils.AuthClaim, int, error) {
+	if !strings.HasPrefix(rawKey, keyPrefix) {
+		return nil, http.StatusUnauthorized, errors.New(errInvalidKey)
+	}
+
+	keyHash := hashKey(rawKey)
+	key, code, err := u.apiKeyRepo.GetApiKey(ctx, api_key_repository.ApiKeyFilter{KeyHash: &keyHash})
+	if err != nil {
+		if code == http.StatusNotFound {
+			return nil, http.StatusUnauthorized, errors.New(errInvalidKey)
+		}
+		return nil, code, err
+	}
+
+	now := time.Now()
+	if key.RevokedAt != nil {
+		return nil, http.StatusUnauthorized, errors.New(errInvalidKey)
+	}
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(now) {
+		return nil, http.StatusUnauthorized, errors.New(errKeyExpired)
+	}
+	// A key acts as its creator, so it stops working once they leave the organization
+	if code, err := u.creatorStillMember(ctx, key); err != nil {
+		if code == http.StatusForbidden {
+			return nil, http.StatusUnauthorized, errors.New(errInvalidKey)
+		}
+		return nil, code, err
+	}
+
+	// Devices may call every few seconds; writing each use would be wasted I/O
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= touchInterval || key.LastUsedIp != ipAddress {
+		if _, err := u.apiKeyRepo.TouchApiKey(ctx, key.Id, ipAddress, now); err != nil {
+			log.Errorf("failed to record use of API key %s: %v", key.Id, err)
+		}
+	}
+
+	return &auth_utils.AuthClaim{
+		UserID:         key.CreatedBy,
+		TokenType:      auth_utils.TokenTypeApiKey,
+		ApiKeyID:       key.Id,
+		OrganizationID: key.OrganizationId,
+		Scopes:         key.Scopes,
+	}, http.StatusOK, nil
+}
+
+func (u *apiKeyUseCase) creatorStillMember(ctx context.Context, key api_key_repository.ApiKey) (int, error) {
+	org, code, err := u.orgRepo.GetOrganization(ctx, organization_repository.OrganizationFilter{Id: &key.OrganizationId})
+	if err != nil {
+		if code == http.StatusNotFound {
+			return http.StatusForbidden, err
+		}
+		return code, err
+	}
+	if org.OwnerId == key.CreatedBy || u.permissionChecker.IsSuperAdmin(key.CreatedBy.String()) {
+		return http.StatusOK, nil
+	}
+	return u.activeMember(ctx, key.OrganizationId, key.CreatedBy)
+}
+
+func (u *apiKeyUseCase) audit(ctx context.Context, actor auth_utils.AuthClaim, action, ipAddress string, details map[string]interface{}) {
+	entry := audit_repository.AuditLog{
+		ActorId:   &actor.UserID,
+		Action:    action,
+		IpAddress: ipAddress,
+	}
+	if encoded, err := json.Marshal(details); err == nil {
+		entry.Details = string(encoded)
+	}
+
+	if _, err := u.auditRepo.CreateAuditLog(ctx, entry); err != nil {
+		log.Errorf("failed to write audit log %s: %v", action, err)
+	}
+}
@@ -0,0 +1,355 @@
+package api_key_use_case
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"sekolah-madrasah/app/repository/api_key_repository"
+	"sekolah-madrasah/app/repository/audit_repository"
+	"sekolah-madrasah/app/repository/org_member_repository"
+	"sekolah-madrasah/app/repository/organization_repository"
+	"sekolah-madrasah/app/repository/permission_repository"
+	"sekolah-madrasah/pkg/auth_utils"
+	"sekolah-madrasah/pkg/paginate_utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type MockApiKeyRepository struct {
+	keys    []api_key_repository.ApiKey
+	touches int
+}
+
+func (m *MockApiKeyRepository) find(filter api_key_repository.ApiKeyFilter) int {
+	for i, k := range m.keys {
+		if filter.Id != nil && k.Id != *filter.Id {
+			continue
+		}
+		if filter.OrganizationId != nil && k.OrganizationId != *filter.OrganizationId {
+			continue
+		}
+		if filter.KeyHash != nil && k.KeyHash != *filter.KeyHash {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+func (m *MockApiKeyRepository) GetApiKey(ctx context.Context, filter api_key_repository.ApiKeyFilter) (api_key_repository.ApiKey, int, error) {
+	if i := m.find(filter); i >= 0 {
+		return m.keys[i], http.StatusOK, nil
+	}
+	return api_key_repository.ApiKey{}, http.StatusNotFound, gorm.ErrRecordNotFound
+}
+
+func (m *MockApiKeyRepository) GetApiKeys(ctx context.Context, filter api_key_repository.ApiKeyFilter, paginate *paginate_utils.PaginateData) ([]api_key_repository.ApiKey, int, error) {
+	return m.keys, http.StatusOK, nil
+}
+
+func (m *MockApiKeyRepository) CreateApiKey(ctx context.Context, key api_key_repository.ApiKey) (api_key_repository.ApiKey, int, error) {
+	key.Id = uuid.New()
+	key.CreatedAt = time.Now()
+	m.keys = append(m.keys, key)
+	return key, http.StatusCreated, nil
+}
+
+func (m *MockApiKeyRepository) RevokeApiKey(ctx context.Context, filter api_key_repository.ApiKeyFilter) (int, error) {
+	i := m.find(filter)
+	if i < 0 || m.keys[i].RevokedAt != nil {
+		return http.StatusNotFound, gorm.ErrRecordNotFound
+	}
+	now := time.Now()
+	m.keys[i].RevokedAt = &now
+	return http.StatusOK, nil
+}
+
+func (m *MockApiKeyRepository) TouchApiKey(ctx context.Context, id uuid.UUID, ipAddress string, usedAt time.Time) (int, error) {
+	for i := range m.keys {
+		if m.keys[i].Id == id {
+			m.keys[i].LastUsedAt = &usedAt
+			m.keys[i].LastUsedIp = ipAddress
+			m.touches++
+		}
+	}
+	return http.StatusOK, nil
+}
+
+type MockOrganizationRepository struct {
+	organization_repository.OrganizationRepository
+	orgs map[uuid.UUID]organization_repository.Organization
+}
+
+func (m *MockOrganizationRepository) GetOrganization(ctx context.Context, filter organization_repository.OrganizationFilter) (organization_repository.Organization, int, error) {
+	if org, ok := m.orgs[*filter.Id]; ok {
+		return org, http.StatusOK, nil
+	}
+	return organization_repository.Organization{}, http.StatusNotFound, gorm.ErrRecordNotFound
+}
+
+type MockOrgMemberRepository struct {
+	org_member_repository.OrgMemberRepository
+	members []org_member_repository.OrganizationMember
+}
+
+func (m *MockOrgMemberRepository) GetMember(ctx context.Context, filter org_member_repository.OrgMemberFilter) (org_member_repository.OrganizationMember, int, error) {
+	for _, member := range m.members {
+		if member.OrganizationId == *filter.OrganizationId && member.UserId == *filter.UserId && member.IsActive {
+			return member, http.StatusOK, nil
+		}
+	}
+	return org_member_repository.OrganizationMember{}, http.StatusNotFound, gorm.ErrRecordNotFound
+}
+
+type MockPermissionRepository struct {
+	permission_repository.PermissionRepository
+	names map[string]bool
+}
+
+func (m *MockPermissionRepository) GetPermission(ctx context.Context, filter permission_repository.PermissionFilter) (permission_repository.Permission, int, error) {
+	if m.names[*filter.Name] {
+		return permission_repository.Permission{Id: uuid.New(), Name: *filter.Name}, http.StatusOK, nil
+	}
+	return permission_repository.Permission{}, http.StatusNotFound, gorm.ErrRecordNotFound
+}
+
+type MockAuditRepository struct {
+	logs []audit_repository.AuditLog
+}
+
+func (m *MockAuditRepository) CreateAuditLog(ctx context.Context, log audit_repository.AuditLog) (int, error) {
+	m.logs = append(m.logs, log)
+	return http.StatusCreated, nil
+}
+
+func (m *MockAuditRepository) GetAuditLogs(ctx context.Context, filter audit_repository.AuditLogFilter, paginate *paginate_utils.PaginateData) ([]audit_repository.AuditLog, int, error) {
+	return m.logs, http.StatusOK, nil
+}
+
+type MockPermissionChecker struct {
+	granted    map[uuid.UUID]map[string]map[string]bool // Organization, user, permission
+	superAdmin map[string]bool
+}
+
+func (m *MockPermissionChecker) HasOrganizationPermission(userID string, organizationId uuid.UUID, permission string) bool {
+	return m.superAdmin[userID] || m.granted[organizationId][userID][permission]
+}
+
+func (m *MockPermissionChecker) IsSuperAdmin(userID string) bool {
+	return m.superAdmin[userID]
+}
+
+type fixture struct {
+	useCase  ApiKeyUseCase
+	keys     *MockApiKeyRepository
+	members  *MockOrgMemberRepository
+	audit    *MockAuditRepository
+	orgId    uuid.UUID
+	admin    auth_utils.AuthClaim
+	member   auth_utils.AuthClaim
+	outsider auth_utils.AuthClaim
+}
+
+// newFixture sets up an organization with an admin and a plain member. The
+// admin also holds users.delete, but only as admin of another organization.
+func newFixture() fixture {
+	orgId := uuid.New()
+	otherOrgId := uuid.New()
+	adminId := uuid.New()
+	memberId := uuid.New()
+	outsiderId := uuid.New()
+
+	keys := &MockApiKeyRepository{}
+	audit := &MockAuditRepository{}
+	orgs := &MockOrganizationRepository{orgs: map[uuid.UUID]organization_repository.Organization{
+		orgId: {Id: orgId, OwnerId: uuid.New()},
+	}}
+	members := &MockOrgMemberRepository{members: []org_member_repository.OrganizationMember{
+		{Id: uuid.New(), OrganizationId: orgId, UserId: adminId, IsActive: true},
+		{Id: uuid.New(), OrganizationId: orgId, UserId: memberId, IsActive: true},
+	}}
+	permissions := &MockPermissionRepository{names: map[string]bool{
+		"class_enrollments.list": true,
+		"activities.create":      true,
+		"users.delete":           true,
+	}}
+	checker := &MockPermissionChecker{
+		granted: map[uuid.UUID]map[string]map[string]bool{
+			orgId: {
+				adminId.String():  {"organizations.update": true, "class_enrollments.list": true, "activities.create": true},
+				memberId.String(): {"class_enrollments.list": true},
+			},
+			otherOrgId: {
+				adminId.String():    {"organizations.update": true, "users.delete": true},
+				outsiderId.String(): {"organizations.update": true, "class_enrollments.list": true},
+			},
+		},
+		superAdmin: map[string]bool{},
+	}
+
+	return fixture{
+		useCase:  NewApiKeyUseCase(keys, orgs, members, permissions, audit, checker),
+		keys:     keys,
+		members:  members,
+		audit:    audit,
+		orgId:    orgId,
+		admin:    auth_utils.AuthClaim{UserID: adminId, TokenType: auth_utils.TokenTypeAccess},
+		member:   auth_utils.AuthClaim{UserID: memberId, TokenType: auth_utils.TokenTypeAccess},
+		outsider: auth_utils.AuthClaim{UserID: outsiderId, TokenType: auth_utils.TokenTypeAccess},
+	}
+}
+
+func TestCreateApiKey_StoresOnlyHash(t *testing.T) {
+	f := newFixture()
+	ctx := context.Background()
+
+	created, code, err := f.useCase.CreateApiKey(ctx, f.admin, f.orgId, CreateApiKeyRequest{
+		Name:   "Gate device",
+		Scopes: []string{"class_enrollments.list", "class_enrollments.list"},
+	}, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("CreateApiKey failed: %d %v", code, err)
+	}
+	if code != http.StatusCreated {
+		t.Errorf("expected 201, got %d", code)
+	}
+	if !strings.HasPrefix(created.Key, created.Prefix+"_") {
+		t.Errorf("key %q should start with prefix %q", created.Key, created.Prefix)
+	}
+	if len(created.Scopes) != 1 {
+		t.Errorf("expected duplicate scopes to collapse, got %v", created.Scopes)
+	}
+
+	stored := f.keys.keys[0]
+	if stored.KeyHash == created.Key || stored.KeyHash != hashKey(created.Key) {
+		t.Error("expected only the SHA-256 of the key to be stored")
+	}
+	if len(f.audit.logs) != 1 || f.audit.logs[0].Action != audit_repository.ActionApiKeyCreated {
+		t.Errorf("expected an api_key.created audit entry, got %+v", f.audit.logs)
+	}
+}
+
+func TestCreateApiKey_Rejections(t *testing.T) {
+	f := newFixture()
+	ctx := context.Background()
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name  string
+		actor auth_utils.AuthClaim
+		req   CreateApiKeyRequest
+		code  int
+	}{
+		{"member but not admin", f.member, CreateApiKeyRequest{Name: "x", Scopes: []string{"class_enrollments.list"}}, http.StatusForbidden},
+		{"non member", f.outsider, CreateApiKeyRequest{Name: "x", Scopes: []string{"class_enrollments.list"}}, http.StatusForbidden},
+		{"api key caller", auth_utils.AuthClaim{UserID: f.admin.UserID, TokenType: auth_utils.TokenTypeApiKey}, CreateApiKeyRequest{Name: "x", Scopes: []string{"class_enrollments.list"}}, http.StatusForbidden},
+		{"unknown scope", f.admin, CreateApiKeyRequest{Name: "x", Scopes: []string{"nope.read"}}, http.StatusBadRequest},
+		{"scope held in another organization only", f.admin, CreateApiKeyRequest{Name: "x", Scopes: []string{"users.delete"}}, http.StatusForbidden},
+		{"no scopes", f.admin, CreateApiKeyRequest{Name: "x"}, http.StatusBadRequest},
+		{"expired", f.admin, CreateApiKeyRequest{Name: "x", Scopes: []string{"activities.create"}, ExpiresAt: &past}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, code, err := f.useCase.CreateApiKey(ctx, tt.actor, f.orgId, tt.req, "")
+			if err == nil || code != tt.code {
+				t.Errorf("expected %d error, got %d %v", tt.code, code, err)
+			}
+		})
+	}
+
+	if len(f.keys.keys) != 0 {
+		t.Errorf("expected no keys to be created, got %d", len(f.keys.keys))
+	}
+}
+
+func TestAuthenticateApiKey(t *testing.T) {
+	f := newFixture()
+	ctx := context.Background()
+
+	created, _, err := f.useCase.CreateApiKey(ctx, f.admin, f.orgId, CreateApiKeyRequest{
+		Name:   "Finance",
+		Scopes: []string{"activities.create"},
+	}, "")
+	if err != nil {
+		t.Fatalf("CreateApiKey failed: %v", err)
+	}
+
+	claims, code, err := f.useCase.AuthenticateApiKey(ctx, created.Key, "10.0.0.2")
+	if err != nil {
+		t.Fatalf("AuthenticateApiKey failed: %d %v", code, err)
+	}
+	if !claims.IsApiKey() || claims.OrganizationID != f.orgId || claims.UserID != f.admin.UserID {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if len(claims.Scopes) != 1 || claims.Scopes[0] != "activities.create" {
+		t.Errorf("unexpected scopes %v", claims.Scopes)
+	}
+
+	// A second call within the touch interval from the same address is not written
+	if _, _, err := f.useCase.AuthenticateApiKey(ctx, created.Key, "10.0.0.2"); err != nil {
+		t.Fatalf("second AuthenticateApiKey failed: %v", err)
+	}
+	if f.keys.touches != 1 {
+		t.Errorf("expected last use to be recorded once, got %d", f.keys.touches)
+	}
+
+	if _, code, err := f.useCase.AuthenticateApiKey(ctx, created.Key+"x", ""); err == nil || code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a wrong key, got %d %v", code, err)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	f.keys.keys[0].ExpiresAt = &past
+	if _, code, err := f.useCase.AuthenticateApiKey(ctx, created.Key, ""); err == nil || code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for an expired key, got %d %v", code, err)
+	}
+}
+
+func TestRevokeApiKey(t *testing.T) {
+	f := newFixture()
+	ctx := context.Background()
+
+	created, _, err := f.useCase.CreateApiKey(ctx, f.admin, f.orgId, CreateApiKeyRequest{
+		Name:   "Gate device",
+		Scopes: []string{"class_enrollments.list"},
+	}, "")
+	if err != nil {
+		t.Fatalf("CreateApiKey failed: %v", err)
+	}
+
+	if code, err := f.useCase.RevokeApiKey(ctx, f.outsider, f.orgId, created.Id, ""); err == nil || code != http.StatusForbidden {
+		t.Errorf("expected 403 for a non member, got %d %v", code, err)
+	}
+	if code, err := f.useCase.RevokeApiKey(ctx, f.admin, f.orgId, created.Id, ""); err != nil {
+		t.Fatalf("RevokeApiKey failed: %d %v", code, err)
+	}
+	if code, err := f.useCase.RevokeApiKey(ctx, f.admin, f.orgId, created.Id, ""); err == nil || code != http.StatusNotFound {
+		t.Errorf("expected 404 when revoking twice, got %d %v", code, err)
+	}
+
+	if _, code, err := f.useCase.AuthenticateApiKey(ctx, created.Key, ""); err == nil || code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a revoked key, got %d %v", code, err)
+	}
+}
+
+func TestAuthenticateApiKey_CreatorLeftOrganization(t *testing.T) {
+	f := newFixture()
+	ctx := context.Background()
+
+	created, _, err := f.useCase.CreateApiKey(ctx, f.admin, f.orgId, CreateApiKeyRequest{
+		Name:   "Gate device",
+		Scopes: []string{"class_enrollments.list"},
+	}, "")
+	if err != nil {
+		t.Fatalf("CreateApiKey failed: %v", err)
+	}
+
+	f.members.members[0].IsActive = false
+	if _, code, err := f.useCase.AuthenticateApiKey(ctx, created.Key, ""); err == nil || code != http.StatusUnauthorized {
+		t.Errorf("expected 401 once the creator left, got %d %v", code, err)
+	}
+}
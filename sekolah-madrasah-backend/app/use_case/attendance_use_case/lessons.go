@@ -0,0 +1,464 @@
+package attendance_use_case
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"sekolah-madrasah/app/repository/attendance_repository"
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrLessonNotFound   = errors.New("timetable entry not found in this unit")
+	ErrNotLessonTeacher = errors.New("only the lesson's teacher may record its attendance and journal")
+)
+
+// Reconciliation flags
+const (
+	// FlagAbsentLater marks a student present at school in the morning who
+	// missed a lesson later that day.
+	FlagAbsentLater = "absent_later"
+	// FlagPresentWhileAbsent marks a student recorded absent for the day who was
+	// nevertheless marked present in a lesson.
+	FlagPresentWhileAbsent = "present_while_absent"
+)
+
+var dayNames = []string{"", "Senin", "Selasa", "Rabu", "Kamis", "Jumat", "Sabtu", "Minggu"}
+
+type LessonRequest struct {
+	UnitId           uuid.UUID
+	TimetableEntryId uuid.UUID
+	Date             time.Time
+	Topic            string
+	Notes            *string
+	Records          []RecordRequest
+	Recorder         Recorder
+}
+
+// LessonView is the attendance sheet of one lesson with its journal entry.
+type LessonView struct {
+	TimetableEntryId uuid.UUID              `json:"timetable_entry_id"`
+	ClassId          uuid.UUID              `json:"class_id"`
+	SubjectId        uuid.UUID              `json:"subject_id"`
+	TeacherProfileId uuid.UUID              `json:"teacher_profile_id"`
+	Date             string                 `json:"date"`
+	Period           int                    `json:"period"`
+	Journal          *schemas.LessonJournal `json:"journal"` // Null until recorded
+	Students         []StudentAttendance    `json:"students"`
+}
+
+// SubjectAttendance is attendance in the lessons of one subject; its totals
+// count lessons rather than days.
+type SubjectAttendance struct {
+	SubjectId   uuid.UUID `json:"subject_id"`
+	SubjectName string    `json:"subject_name"`
+	Lessons     int       `json:"lessons"` // Lessons held in the period
+	Totals      Totals    `json:"totals"`
+}
+
+type StudentSubjectRecap struct {
+	ClassEnrollmentId uuid.UUID           `json:"class_enrollment_id"`
+	StudentProfileId  uuid.UUID           `json:"student_profile_id"`
+	StudentName       string              `json:"student_name"`
+	NIS               *string             `json:"nis"`
+	Subjects          []SubjectAttendance `json:"subjects"`
+}
+
+type SubjectRecap struct {
+	ClassId   uuid.UUID             `json:"class_id"`
+	ClassName string                `json:"class_name"`
+	From      string                `json:"from"`
+	To        string                `json:"to"`
+	Subjects  []SubjectAttendance   `json:"subjects"` // Whole class per subject
+	Students  []StudentSubjectRecap `json:"students"`
+}
+
+type LessonMarkView struct {
+	Period      int                      `json:"period"`
+	SubjectId   uuid.UUID                `json:"subject_id"`
+	SubjectName string                   `json:"subject_name"`
+	Status      schemas.AttendanceStatus `json:"status"`
+}
+
+type ReconciliationFlag struct {
+	ClassEnrollmentId uuid.UUID                `json:"class_enrollment_id"`
+	StudentProfileId  uuid.UUID                `json:"student_profile_id"`
+	StudentName       string                   `json:"student_name"`
+	NIS               *string                  `json:"nis"`
+	Flag              string                   `json:"flag"` // absent_later, present_while_absent
+	DailyStatus       schemas.AttendanceStatus `json:"daily_status"`
+	Lessons           []LessonMarkView         `json:"lessons"` // The lessons that disagree with the daily status
+}
+
+type Reconciliation struct {
+	ClassId uuid.UUID            `json:"class_id"`
+	Date    string               `json:"date"`
+	Flags   []ReconciliationFlag `json:"flags"`
+}
+
+func (uc *attendanceUseCase) GetLesson(unitId, timetableEntryId uuid.UUID, date time.Time) (*LessonView, error) {
+	entry, err := uc.lesson(unitId, timetableEntryId)
+	if err != nil {
+		return nil, err
+	}
+	return uc.lessonSheet(entry, dateOnly(date))
+}
+
+func (uc *attendanceUseCase) SaveLesson(req *LessonRequest) (*LessonView, error) {
+	entry, err := uc.lesson(req.UnitId, req.TimetableEntryId)
+	if err != nil {
+		return nil, err
+	}
+	recorder, err := uc.recorder(req.UnitId, req.Recorder)
+	if err != nil {
+		return nil, err
+	}
+	if !uc.isLessonTeacher(entry, recorder) {
+		return nil, ErrNotLessonTeacher
+	}
+
+	date := dateOnly(req.Date)
+	if date.After(dateOnly(uc.now())) {
+		return nil, errors.New("a lesson cannot be recorded for a future date")
+	}
+	if isoWeekday(date) != entry.DayOfWeek {
+		return nil, fmt.Errorf("this lesson is held on %s, not on %s", dayName(entry.DayOfWeek), date.Format(dateFormat))
+	}
+	topic := strings.TrimSpace(req.Topic)
+	if topic == "" {
+		return nil, errors.New("topic is required")
+	}
+
+	enrollments, err := uc.repo.FindEnrollments(entry.ClassId)
+	if err != nil {
+		return nil, err
+	}
+	enrolled := map[uuid.UUID]bool{}
+	for _, enrollment := range enrollments {
+		if enrolledOn(enrollment, date) {
+			enrolled[enrollment.Id] = true
+		}
+	}
+
+	records := make([]schemas.LessonAttendance, 0, len(req.Records))
+	seen := map[uuid.UUID]bool{}
+	for _, record := range req.Records {
+		if !enrolled[record.ClassEnrollmentId] {
+			return nil, fmt.Errorf("enrollment %s is not in this class on %s", record.ClassEnrollmentId, date.Format(dateFormat))
+		}
+		if seen[record.ClassEnrollmentId] {
+			return nil, fmt.Errorf("enrollment %s is listed twice", record.ClassEnrollmentId)
+		}
+		seen[record.ClassEnrollmentId] = true
+		if !record.Status.IsValid() {
+			return nil, fmt.Errorf("invalid attendance status %q", record.Status)
+		}
+
+		records = append(records, schemas.LessonAttendance{
+			ClassEnrollmentId: record.ClassEnrollmentId,
+			Status:            record.Status,
+			Note:              record.Note,
+		})
+	}
+
+	journal, err := uc.repo.FindJournal(entry.Id, date)
+	if err != nil {
+		journal = &schemas.LessonJournal{
+			UnitId:           entry.UnitId,
+			TimetableEntryId: entry.Id,
+			Date:             date,
+		}
+	}
+	journal.ClassId = entry.ClassId
+	journal.SubjectId = entry.SubjectId
+	journal.TeacherProfileId = entry.TeacherProfileId
+	journal.Period = entry.Period
+	journal.Topic = topic
+	journal.Notes = req.Notes
+	journal.RecordedBy = recorder.UserId
+
+	if err := uc.repo.SaveLesson(journal, records); err != nil {
+		return nil, err
+	}
+	return uc.lessonSheet(entry, date)
+}
+
+func (uc *attendanceUseCase) GetJournals(unitId, classId uuid.UUID, subjectId *uuid.UUID, from, to time.Time) ([]schemas.LessonJournal, error) {
+	class, err := uc.class(unitId, classId)
+	if err != nil {
+		return nil, err
+	}
+	return uc.repo.FindJournals(attendance_repository.JournalFilter{
+		UnitId:    unitId,
+		ClassId:   &class.Id,
+		SubjectId: subjectId,
+		From:      dateOnly(from),
+		To:        dateOnly(to),
+	})
+}
+
+func (uc *attendanceUseCase) GetSubjectRecap(unitId, classId uuid.UUID, from, to time.Time) (*SubjectRecap, error) {
+	class, err := uc.class(unitId, classId)
+	if err != nil {
+		return nil, err
+	}
+	from, to = dateOnly(from), dateOnly(to)
+	if to.Before(from) {
+		return nil, errors.New("from must not be after to")
+	}
+
+	journals, err := uc.repo.FindJournals(attendance_repository.JournalFilter{
+		UnitId: unitId, ClassId: &class.Id, From: from, To: to,
+	})
+	if err != nil {
+		return nil, err
+	}
+	counts, err := uc.repo.CountLessonAttendance(class.Id, from, to)
+	if err != nil {
+		return nil, err
+	}
+	enrollments, err := uc.repo.FindEnrollments(class.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Subjects in the order they are first held
+	var subjects []SubjectAttendance
+	index := map[uuid.UUID]int{}
+	for _, journal := range journals {
+		i, ok := index[journal.SubjectId]
+		if !ok {
+			i = len(subjects)
+			index[journal.SubjectId] = i
+			subject := SubjectAttendance{SubjectId: journal.SubjectId}
+			if journal.Subject != nil {
+				subject.SubjectName = journal.Subject.Name
+			}
+			subjects = append(subjects, subject)
+		}
+		subjects[i].Lessons++
+	}
+
+	perStudent := map[uuid.UUID][]Totals{}
+	for _, count := range counts {
+		i, ok := index[count.SubjectId]
+		if !ok {
+			continue
+		}
+		if perStudent[count.ClassEnrollmentId] == nil {
+			perStudent[count.ClassEnrollmentId] = make([]Totals, len(subjects))
+		}
+		perStudent[count.ClassEnrollmentId][i].add(count.Status, count.Lessons)
+	}
+
+	recap := &SubjectRecap{
+		ClassId:   class.Id,
+		ClassName: class.Name,
+		From:      from.Format(dateFormat),
+		To:        to.Format(dateFormat),
+		Subjects:  append([]SubjectAttendance{}, subjects...),
+		Students:  []StudentSubjectRecap{},
+	}
+	for _, enrollment := range enrollments {
+		totals := perStudent[enrollment.Id]
+		if totals == nil && !enrolledDuring(enrollment, from, to) {
+			continue
+		}
+		if totals == nil {
+			totals = make([]Totals, len(subjects))
+		}
+
+		line := StudentSubjectRecap{
+			ClassEnrollmentId: enrollment.Id,
+			StudentProfileId:  enrollment.StudentProfileId,
+			Subjects:          make([]SubjectAttendance, len(subjects)),
+		}
+		line.StudentName, line.NIS = student(enrollment)
+		for i, subject := range subjects {
+			totals[i].rate()
+			subject.Totals = totals[i]
+			line.Subjects[i] = subject
+			recap.Subjects[i].Totals.merge(totals[i])
+		}
+		recap.Students = append(recap.Students, line)
+	}
+	for i := range recap.Subjects {
+		recap.Subjects[i].Totals.rate()
+	}
+
+	sort.SliceStable(recap.Students, func(i, j int) bool {
+		return recap.Students[i].StudentName < recap.Students[j].StudentName
+	})
+	return recap, nil
+}
+
+// GetReconciliation compares a class's daily attendance with its lesson
+// attendance on the date. Students missing from either side are not flagged.
+func (uc *attendanceUseCase) GetReconciliation(unitId, classId uuid.UUID, date time.Time) (*Reconciliation, error) {
+	class, err := uc.class(unitId, classId)
+	if err != nil {
+		return nil, err
+	}
+	date = dateOnly(date)
+
+	daily, err := uc.repo.FindByClassAndDate(class.Id, date)
+	if err != nil {
+		return nil, err
+	}
+	marks, err := uc.repo.FindLessonMarks(class.Id, date)
+	if err != nil {
+		return nil, err
+	}
+	enrollments, err := uc.repo.FindEnrollments(class.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	lessons := map[uuid.UUID][]attendance_repository.LessonMark{}
+	for _, mark := range marks {
+		lessons[mark.ClassEnrollmentId] = append(lessons[mark.ClassEnrollmentId], mark)
+	}
+	students := make(map[uuid.UUID]schemas.ClassEnrollment, len(enrollments))
+	for _, enrollment := range enrollments {
+		students[enrollment.Id] = enrollment
+	}
+
+	view := &Reconciliation{ClassId: class.Id, Date: date.Format(dateFormat), Flags: []ReconciliationFlag{}}
+	for _, record := range daily {
+		atSchool := isPresent(record.Status)
+
+		var disagree []LessonMarkView
+		for _, mark := range lessons[record.ClassEnrollmentId] {
+			if isPresent(mark.Status) != atSchool {
+				disagree = append(disagree, LessonMarkView{
+					Period:      mark.Period,
+					SubjectId:   mark.SubjectId,
+					SubjectName: mark.SubjectName,
+					Status:      mark.Status,
+				})
+			}
+		}
+		if len(disagree) == 0 {
+			continue
+		}
+
+		flag := ReconciliationFlag{
+			ClassEnrollmentId: record.ClassEnrollmentId,
+			Flag:              FlagAbsentLater,
+			DailyStatus:       record.Status,
+			Lessons:           disagree,
+		}
+		if !atSchool {
+			flag.Flag = FlagPresentWhileAbsent
+		}
+		if enrollment, ok := students[record.ClassEnrollmentId]; ok {
+			flag.StudentProfileId = enrollment.StudentProfileId
+			flag.StudentName, flag.NIS = student(enrollment)
+		}
+		view.Flags = append(view.Flags, flag)
+	}
+
+	sort.SliceStable(view.Flags, func(i, j int) bool {
+		return view.Flags[i].StudentName < view.Flags[j].StudentName
+	})
+	return view, nil
+}
+
+func (uc *attendanceUseCase) lesson(unitId, timetableEntryId uuid.UUID) (*schemas.TimetableEntry, error) {
+	entry, err := uc.repo.FindTimetableEntry(timetableEntryId)
+	if err != nil || entry.UnitId != unitId {
+		return nil, ErrLessonNotFound
+	}
+	return entry, nil
+}
+
+// isLessonTeacher allows the teacher the lesson is scheduled for, and unit
+// owners and admins who fill in for them.
+func (uc *attendanceUseCase) isLessonTeacher(entry *schemas.TimetableEntry, recorder Recorder) bool {
+	switch recorder.Role {
+	case schemas.UnitMemberRoleOwner, schemas.UnitMemberRoleAdmin:
+		return true
+	}
+	teacher, err := uc.repo.FindTeacherByUser(entry.UnitId, recorder.UserId)
+	return err == nil && teacher.Id == entry.TeacherProfileId
+}
+
+func (uc *attendanceUseCase) lessonSheet(entry *schemas.TimetableEntry, date time.Time) (*LessonView, error) {
+	enrollments, err := uc.repo.FindEnrollments(entry.ClassId)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &LessonView{
+		TimetableEntryId: entry.Id,
+		ClassId:          entry.ClassId,
+		SubjectId:        entry.SubjectId,
+		TeacherProfileId: entry.TeacherProfileId,
+		Date:             date.Format(dateFormat),
+		Period:           entry.Period,
+		Students:         []StudentAttendance{},
+	}
+
+	recorded := map[uuid.UUID]schemas.LessonAttendance{}
+	if journal, err := uc.repo.FindJournal(entry.Id, date); err == nil {
+		view.Journal = journal
+		records, err := uc.repo.FindLessonAttendance(journal.Id)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			recorded[record.ClassEnrollmentId] = record
+		}
+	}
+
+	for _, enrollment := range enrollments {
+		record, ok := recorded[enrollment.Id]
+		if !ok && !enrolledOn(enrollment, date) {
+			continue
+		}
+
+		line := StudentAttendance{
+			ClassEnrollmentId: enrollment.Id,
+			StudentProfileId:  enrollment.StudentProfileId,
+		}
+		line.StudentName, line.NIS = student(enrollment)
+		if ok {
+			status := record.Status
+			recordedBy := view.Journal.RecordedBy
+			line.Status = &status
+			line.Note = record.Note
+			line.RecordedBy = &recordedBy
+		}
+		view.Students = append(view.Students, line)
+	}
+
+	sort.SliceStable(view.Students, func(i, j int) bool {
+		return view.Students[i].StudentName < view.Students[j].StudentName
+	})
+	return view, nil
+}
+
+// isPresent reports whether the status means the student was there.
+func isPresent(status schemas.AttendanceStatus) bool {
+	return status == schemas.AttendancePresent || status == schemas.AttendanceLate
+}
+
+// isoWeekday numbers the days like timetable entries do, 1 = Senin ... 7 = Minggu.
+func isoWeekday(date time.Time) int {
+	if date.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(date.Weekday())
+}
+
+func dayName(day int) string {
+	if day < 1 || day >= len(dayNames) {
+		return fmt.Sprintf("day %d", day)
+	}
+	return dayNames[day]
+}
@@ -0,0 +1,479 @@
+package attendance_use_case
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"sekolah-madrasah/app/repository/attendance_repository"
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+)
+
+const (
+	dateFormat  = "2006-01-02"
+	monthFormat = "2006-01"
+)
+
+var (
+	ErrClassNotFound    = errors.New("class not found in this unit")
+	ErrNotAllowed       = errors.New("only the class's homeroom teacher or unit staff may record its attendance")
+	ErrRecorderRequired = errors.New("recorded_by of the staff member is required when recording with an API key")
+	ErrRecorderNotFound = errors.New("recorded_by is not an active member of this unit")
+)
+
+type AttendanceUseCase interface {
+	// GetClassAttendance lists the students enrolled in the class on the date
+	// with what was recorded for them, if anything.
+	GetClassAttendance(unitId, classId uuid.UUID, date time.Time) (*ClassAttendanceView, error)
+	// SaveClassAttendance records the attendance of several students of a class
+	// for one day, overwriting what was recorded for them before.
+	SaveClassAttendance(req *SaveAttendanceRequest) (*ClassAttendanceView, error)
+	GetClassRecap(unitId, classId uuid.UUID, month time.Time) (*ClassRecap, error)
+	GetUnitRecap(unitId uuid.UUID, academicYear string, month time.Time) (*UnitRecap, error)
+	// GetLesson returns the attendance sheet and journal of a timetable slot on a date
+	GetLesson(unitId, timetableEntryId uuid.UUID, date time.Time) (*LessonView, error)
+	// SaveLesson records the journal entry and student attendance of a lesson
+	// held from the timetable. Only the lesson's teacher may record it.
+	SaveLesson(req *LessonRequest) (*LessonView, error)
+	GetJournals(unitId, classId uuid.UUID, subjectId *uuid.UUID, from, to time.Time) ([]schemas.LessonJournal, error)
+	// GetSubjectRecap totals lesson attendance per subject for every student
+	GetSubjectRecap(unitId, classId uuid.UUID, from, to time.Time) (*SubjectRecap, error)
+	GetReconciliation(unitId, classId uuid.UUID, date time.Time) (*Reconciliation, error)
+}
+
+// Recorder is the user entering attendance and their role in the unit. An API
+// key acts for the key's creator and carries no unit role, so it has to name
+// the staff member recording in StaffUserId instead; their role is looked up.
+type Recorder struct {
+	UserId      uuid.UUID
+	Role        schemas.UnitMemberRole
+	ViaApiKey   bool
+	StaffUserId *uuid.UUID
+}
+
+type RecordRequest struct {
+	ClassEnrollmentId uuid.UUID
+	Status            schemas.AttendanceStatus
+	Note              *string
+}
+
+type SaveAttendanceRequest struct {
+	UnitId   uuid.UUID
+	ClassId  uuid.UUID
+	Date     time.Time
+	Records  []RecordRequest
+	Recorder Recorder
+}
+
+// StudentAttendance is one student's line on a class's attendance sheet.
+type StudentAttendance struct {
+	ClassEnrollmentId uuid.UUID                 `json:"class_enrollment_id"`
+	StudentProfileId  uuid.UUID                 `json:"student_profile_id"`
+	StudentName       string                    `json:"student_name"`
+	NIS               *string                   `json:"nis"`
+	Status            *schemas.AttendanceStatus `json:"status"` // Null until recorded
+	Note              *string                   `json:"note"`
+	RecordedBy        *uuid.UUID                `json:"recorded_by"`
+}
+
+type ClassAttendanceView struct {
+	ClassId  uuid.UUID           `json:"class_id"`
+	Date     string              `json:"date"`
+	Students []StudentAttendance `json:"students"`
+}
+
+// Totals counts recorded days (or lessons, in subject recaps) per status.
+// AttendanceRate is the share of them the student was there (hadir or
+// terlambat), in percent.
+type Totals struct {
+	Hadir          int     `json:"hadir"`
+	Sakit          int     `json:"sakit"`
+	Izin           int     `json:"izin"`
+	Alpa           int     `json:"alpa"`
+	Terlambat      int     `json:"terlambat"`
+	Recorded       int     `json:"recorded"`
+	AttendanceRate float64 `json:"attendance_rate"`
+}
+
+type StudentRecap struct {
+	ClassEnrollmentId uuid.UUID `json:"class_enrollment_id"`
+	StudentProfileId  uuid.UUID `json:"student_profile_id"`
+	StudentName       string    `json:"student_name"`
+	NIS               *string   `json:"nis"`
+	Totals            Totals    `json:"totals"`
+}
+
+type ClassRecap struct {
+	ClassId      uuid.UUID      `json:"class_id"`
+	ClassName    string         `json:"class_name"`
+	Month        string         `json:"month"`
+	DaysRecorded int            `json:"days_recorded"` // School days with attendance entered
+	Students     []StudentRecap `json:"students"`
+	Totals       Totals         `json:"totals"`
+}
+
+type ClassSummary struct {
+	ClassId      uuid.UUID `json:"class_id"`
+	ClassName    string    `json:"class_name"`
+	Level        int       `json:"level"`
+	DaysRecorded int       `json:"days_recorded"`
+	Totals       Totals    `json:"totals"`
+}
+
+type UnitRecap struct {
+	Month   string         `json:"month"`
+	Classes []ClassSummary `json:"classes"`
+	Totals  Totals         `json:"totals"`
+}
+
+type attendanceUseCase struct {
+	repo attendance_repository.AttendanceRepository
+	now  func() time.Time
+}
+
+func NewAttendanceUseCase(repo attendance_repository.AttendanceRepository) AttendanceUseCase {
+	return &attendanceUseCase{repo: repo, now: time.Now}
+}
+
+func (uc *attendanceUseCase) GetClassAttendance(unitId, classId uuid.UUID, date time.Time) (*ClassAttendanceView, error) {
+	if _, err := uc.class(unitId, classId); err != nil {
+		return nil, err
+	}
+	return uc.sheet(classId, dateOnly(date))
+}
+
+func (uc *attendanceUseCase) SaveClassAttendance(req *SaveAttendanceRequest) (*ClassAttendanceView, error) {
+	class, err := uc.class(req.UnitId, req.ClassId)
+	if err != nil {
+		return nil, err
+	}
+	recorder, err := uc.recorder(req.UnitId, req.Recorder)
+	if err != nil {
+		return nil, err
+	}
+	if !uc.canRecord(class, recorder) {
+		return nil, ErrNotAllowed
+	}
+
+	date := dateOnly(req.Date)
+	if date.After(dateOnly(uc.now())) {
+		return nil, errors.New("attendance cannot be recorded for a future date")
+	}
+	if len(req.Records) == 0 {
+		return nil, errors.New("records must not be empty")
+	}
+
+	enrollments, err := uc.repo.FindEnrollments(class.Id)
+	if err != nil {
+		return nil, err
+	}
+	enrolled := map[uuid.UUID]bool{}
+	for _, enrollment := range enrollments {
+		if enrolledOn(enrollment, date) {
+			enrolled[enrollment.Id] = true
+		}
+	}
+
+	records := make([]schemas.DailyAttendance, 0, len(req.Records))
+	seen := map[uuid.UUID]bool{}
+	for _, record := range req.Records {
+		if !enrolled[record.ClassEnrollmentId] {
+			return nil, fmt.Errorf("enrollment %s is not in this class on %s", record.ClassEnrollmentId, date.Format(dateFormat))
+		}
+		if seen[record.ClassEnrollmentId] {
+			return nil, fmt.Errorf("enrollment %s is listed twice", record.ClassEnrollmentId)
+		}
+		seen[record.ClassEnrollmentId] = true
+		if !record.Status.IsValid() {
+			return nil, fmt.Errorf("invalid attendance status %q", record.Status)
+		}
+
+		records = append(records, schemas.DailyAttendance{
+			ClassEnrollmentId: record.ClassEnrollmentId,
+			ClassId:           class.Id,
+			Date:              date,
+			Status:            record.Status,
+			Note:              record.Note,
+			RecordedBy:        recorder.UserId,
+		})
+	}
+
+	if err := uc.repo.Save(records); err != nil {
+		return nil, err
+	}
+	return uc.sheet(class.Id, date)
+}
+
+func (uc *attendanceUseCase) GetClassRecap(unitId, classId uuid.UUID, month time.Time) (*ClassRecap, error) {
+	class, err := uc.class(unitId, classId)
+	if err != nil {
+		return nil, err
+	}
+	from, to := monthRange(month)
+
+	counts, err := uc.repo.CountByEnrollment(class.Id, from, to)
+	if err != nil {
+		return nil, err
+	}
+	days, err := uc.repo.CountRecordedDays([]uuid.UUID{class.Id}, from, to)
+	if err != nil {
+		return nil, err
+	}
+	enrollments, err := uc.repo.FindEnrollments(class.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	perEnrollment := map[uuid.UUID]*Totals{}
+	for _, count := range counts {
+		if perEnrollment[count.Id] == nil {
+			perEnrollment[count.Id] = &Totals{}
+		}
+		perEnrollment[count.Id].add(count.Status, count.Days)
+	}
+
+	recap := &ClassRecap{
+		ClassId:      class.Id,
+		ClassName:    class.Name,
+		Month:        from.Format(monthFormat),
+		DaysRecorded: days[class.Id],
+		Students:     []StudentRecap{},
+	}
+	for _, enrollment := range enrollments {
+		totals := perEnrollment[enrollment.Id]
+		// Students who were not in the class that month are left out
+		if totals == nil && !enrolledDuring(enrollment, from, to) {
+			continue
+		}
+		if totals == nil {
+			totals = &Totals{}
+		}
+		totals.rate()
+
+		line := StudentRecap{
+			ClassEnrollmentId: enrollment.Id,
+			StudentProfileId:  enrollment.StudentProfileId,
+			Totals:            *totals,
+		}
+		line.StudentName, line.NIS = student(enrollment)
+		recap.Students = append(recap.Students, line)
+		recap.Totals.merge(*totals)
+	}
+	recap.Totals.rate()
+
+	sort.SliceStable(recap.Students, func(i, j int) bool {
+		return recap.Students[i].StudentName < recap.Students[j].StudentName
+	})
+	return recap, nil
+}
+
+func (uc *attendanceUseCase) GetUnitRecap(unitId uuid.UUID, academicYear string, month time.Time) (*UnitRecap, error) {
+	from, to := monthRange(month)
+
+	classes, err := uc.repo.FindClasses(unitId, academicYear)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := uc.repo.CountByClass(unitId, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	perClass := map[uuid.UUID]*Totals{}
+	for _, count := range counts {
+		if perClass[count.Id] == nil {
+			perClass[count.Id] = &Totals{}
+		}
+		perClass[count.Id].add(count.Status, count.Days)
+	}
+
+	classIds := make([]uuid.UUID, 0, len(classes))
+	for _, class := range classes {
+		classIds = append(classIds, class.Id)
+	}
+	days := map[uuid.UUID]int{}
+	if len(classIds) > 0 {
+		if days, err = uc.repo.CountRecordedDays(classIds, from, to); err != nil {
+			return nil, err
+		}
+	}
+
+	recap := &UnitRecap{Month: from.Format(monthFormat), Classes: []ClassSummary{}}
+	for _, class := range classes {
+		totals := Totals{}
+		if perClass[class.Id] != nil {
+			totals = *perClass[class.Id]
+		}
+		totals.rate()
+		recap.Classes = append(recap.Classes, ClassSummary{
+			ClassId:      class.Id,
+			ClassName:    class.Name,
+			Level:        class.Level,
+			DaysRecorded: days[class.Id],
+			Totals:       totals,
+		})
+		recap.Totals.merge(totals)
+	}
+	recap.Totals.rate()
+	return recap, nil
+}
+
+func (uc *attendanceUseCase) class(unitId, classId uuid.UUID) (*schemas.Class, error) {
+	class, err := uc.repo.FindClass(classId)
+	if err != nil || class.UnitId != unitId {
+		return nil, ErrClassNotFound
+	}
+	return class, nil
+}
+
+// recorder is who the attendance is recorded by: the caller, or the active
+// member of the unit an API key names, never the key's creator.
+func (uc *attendanceUseCase) recorder(unitId uuid.UUID, recorder Recorder) (Recorder, error) {
+	if !recorder.ViaApiKey {
+		return recorder, nil
+	}
+	if recorder.StaffUserId == nil || *recorder.StaffUserId == uuid.Nil {
+		return Recorder{}, ErrRecorderRequired
+	}
+	member, err := uc.repo.FindMember(unitId, *recorder.StaffUserId)
+	if err != nil {
+		return Recorder{}, ErrRecorderNotFound
+	}
+	return Recorder{UserId: member.UserId, Role: member.Role}, nil
+}
+
+// canRecord allows unit owners and admins, the class's homeroom teacher, and
+// staff members without a teacher profile (tata usaha). Other teachers may not
+// record attendance for classes they are not homeroom teacher of.
+func (uc *attendanceUseCase) canRecord(class *schemas.Class, recorder Recorder) bool {
+	switch recorder.Role {
+	case schemas.UnitMemberRoleOwner, schemas.UnitMemberRoleAdmin:
+		return true
+	}
+
+	teacher, err := uc.repo.FindTeacherByUser(class.UnitId, recorder.UserId)
+	if err == nil {
+		return class.HomeroomTeacherId != nil && *class.HomeroomTeacherId == teacher.Id
+	}
+	return recorder.Role == schemas.UnitMemberRoleStaff
+}
+
+func (uc *attendanceUseCase) sheet(classId uuid.UUID, date time.Time) (*ClassAttendanceView, error) {
+	enrollments, err := uc.repo.FindEnrollments(classId)
+	if err != nil {
+		return nil, err
+	}
+	records, err := uc.repo.FindByClassAndDate(classId, date)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded := make(map[uuid.UUID]schemas.DailyAttendance, len(records))
+	for _, record := range records {
+		recorded[record.ClassEnrollmentId] = record
+	}
+
+	view := &ClassAttendanceView{ClassId: classId, Date: date.Format(dateFormat), Students: []StudentAttendance{}}
+	for _, enrollment := range enrollments {
+		record, ok := recorded[enrollment.Id]
+		if !ok && !enrolledOn(enrollment, date) {
+			continue
+		}
+
+		line := StudentAttendance{
+			ClassEnrollmentId: enrollment.Id,
+			StudentProfileId:  enrollment.StudentProfileId,
+		}
+		line.StudentName, line.NIS = student(enrollment)
+		if ok {
+			status := record.Status
+			recordedBy := record.RecordedBy
+			line.Status = &status
+			line.Note = record.Note
+			line.RecordedBy = &recordedBy
+		}
+		view.Students = append(view.Students, line)
+	}
+
+	sort.SliceStable(view.Students, func(i, j int) bool {
+		return view.Students[i].StudentName < view.Students[j].StudentName
+	})
+	return view, nil
+}
+
+func (t *Totals) add(status schemas.AttendanceStatus, days int) {
+	switch status {
+	case schemas.AttendancePresent:
+		t.Hadir += days
+	case schemas.AttendanceSick:
+		t.Sakit += days
+	case schemas.AttendanceExcused:
+		t.Izin += days
+	case schemas.AttendanceAbsent:
+		t.Alpa += days
+	case schemas.AttendanceLate:
+		t.Terlambat += days
+	default:
+		return
+	}
+	t.Recorded += days
+}
+
+func (t *Totals) merge(other Totals) {
+	t.Hadir += other.Hadir
+	t.Sakit += other.Sakit
+	t.Izin += other.Izin
+	t.Alpa += other.Alpa
+	t.Terlambat += other.Terlambat
+	t.Recorded += other.Recorded
+}
+
+func (t *Totals) rate() {
+	if t.Recorded == 0 {
+		t.AttendanceRate = 0
+		return
+	}
+	t.AttendanceRate = math.Round(float64(t.Hadir+t.Terlambat)/float64(t.Recorded)*10000) / 100
+}
+
+func student(enrollment schemas.ClassEnrollment) (string, *string) {
+	if enrollment.StudentProfile == nil {
+		return "", nil
+	}
+	name := ""
+	if enrollment.StudentProfile.User != nil {
+		name = enrollment.StudentProfile.User.FullName
+	}
+	return name, enrollment.StudentProfile.NIS
+}
+
+// enrolledOn reports whether the student was in the class on the date.
+func enrolledOn(enrollment schemas.ClassEnrollment, date time.Time) bool {
+	return enrolledDuring(enrollment, date, date)
+}
+
+// enrolledDuring reports whether the student was in the class on any day from
+// from to to. Enrollments without a leave date are ongoing.
+func enrolledDuring(enrollment schemas.ClassEnrollment, from, to time.Time) bool {
+	if dateOnly(enrollment.EnrolledAt).After(to) {
+		return false
+	}
+	if enrollment.LeftAt != nil {
+		return dateOnly(*enrollment.LeftAt).After(from)
+	}
+	return true
+}
+
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// monthRange returns the first and last day of the month.
+func monthRange(month time.Time) (time.Time, time.Time) {
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return from, from.AddDate(0, 1, -1)
+}
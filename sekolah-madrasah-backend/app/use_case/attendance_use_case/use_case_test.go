@@ -0,0 +1,501 @@
+package attendance_use_case
+
+import (
+	"testing"
+	"time"
+
+	"sekolah-madrasah/app/repository/attendance_repository"
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockRepository is a mock implementation of AttendanceRepository
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) FindByClassAndDate(classId uuid.UUID, date time.Time) ([]schemas.DailyAttendance, error) {
+	args := m.Called(classId, date)
+	return args.Get(0).([]schemas.DailyAttendance), args.Error(1)
+}
+
+func (m *MockRepository) Save(records []schemas.DailyAttendance) error {
+	args := m.Called(records)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CountByEnrollment(classId uuid.UUID, from, to time.Time) ([]attendance_repository.StatusCount, error) {
+	args := m.Called(classId, from, to)
+	return args.Get(0).([]attendance_repository.StatusCount), args.Error(1)
+}
+
+func (m *MockRepository) CountByClass(unitId uuid.UUID, from, to time.Time) ([]attendance_repository.StatusCount, error) {
+	args := m.Called(unitId, from, to)
+	return args.Get(0).([]attendance_repository.StatusCount), args.Error(1)
+}
+
+func (m *MockRepository) CountRecordedDays(classIds []uuid.UUID, from, to time.Time) (map[uuid.UUID]int, error) {
+	args := m.Called(classIds, from, to)
+	return args.Get(0).(map[uuid.UUID]int), args.Error(1)
+}
+
+func (m *MockRepository) FindJournal(timetableEntryId uuid.UUID, date time.Time) (*schemas.LessonJournal, error) {
+	args := m.Called(timetableEntryId, date)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.LessonJournal), args.Error(1)
+}
+
+func (m *MockRepository) FindJournals(filter attendance_repository.JournalFilter) ([]schemas.LessonJournal, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]schemas.LessonJournal), args.Error(1)
+}
+
+func (m *MockRepository) FindLessonAttendance(lessonJournalId uuid.UUID) ([]schemas.LessonAttendance, error) {
+	args := m.Called(lessonJournalId)
+	return args.Get(0).([]schemas.LessonAttendance), args.Error(1)
+}
+
+func (m *MockRepository) SaveLesson(journal *schemas.LessonJournal, records []schemas.LessonAttendance) error {
+	args := m.Called(journal, records)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CountLessonAttendance(classId uuid.UUID, from, to time.Time) ([]attendance_repository.LessonStatusCount, error) {
+	args := m.Called(classId, from, to)
+	return args.Get(0).([]attendance_repository.LessonStatusCount), args.Error(1)
+}
+
+func (m *MockRepository) FindLessonMarks(classId uuid.UUID, date time.Time) ([]attendance_repository.LessonMark, error) {
+	args := m.Called(classId, date)
+	return args.Get(0).([]attendance_repository.LessonMark), args.Error(1)
+}
+
+func (m *MockRepository) FindClass(id uuid.UUID) (*schemas.Class, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.Class), args.Error(1)
+}
+
+func (m *MockRepository) FindTimetableEntry(id uuid.UUID) (*schemas.TimetableEntry, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.TimetableEntry), args.Error(1)
+}
+
+func (m *MockRepository) FindClasses(unitId uuid.UUID, academicYear string) ([]schemas.Class, error) {
+	args := m.Called(unitId, academicYear)
+	return args.Get(0).([]schemas.Class), args.Error(1)
+}
+
+func (m *MockRepository) FindEnrollments(classId uuid.UUID) ([]schemas.ClassEnrollment, error) {
+	args := m.Called(classId)
+	return args.Get(0).([]schemas.ClassEnrollment), args.Error(1)
+}
+
+func (m *MockRepository) FindTeacherByUser(unitId, userId uuid.UUID) (*schemas.TeacherProfile, error) {
+	args := m.Called(unitId, userId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.TeacherProfile), args.Error(1)
+}
+
+func (m *MockRepository) FindMember(unitId, userId uuid.UUID) (*schemas.UnitMember, error) {
+	args := m.Called(unitId, userId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.UnitMember), args.Error(1)
+}
+
+func day(value string) time.Time {
+	date, _ := time.Parse(dateFormat, value)
+	return date
+}
+
+type fixture struct {
+	repo       *MockRepository
+	uc         *attendanceUseCase
+	unitId     uuid.UUID
+	homeroom   *schemas.TeacherProfile
+	class      *schemas.Class
+	budi, siti schemas.ClassEnrollment
+	left       schemas.ClassEnrollment
+}
+
+func newFixture() fixture {
+	unitId := uuid.New()
+	homeroom := &schemas.TeacherProfile{Id: uuid.New(), UnitId: unitId, UserId: uuid.New()}
+	class := &schemas.Class{Id: uuid.New(), UnitId: unitId, Name: "VII A", HomeroomTeacherId: &homeroom.Id}
+	enrollment := func(name string, enrolledAt string) schemas.ClassEnrollment {
+		return schemas.ClassEnrollment{
+			Id: uuid.New(), ClassId: class.Id, StudentProfileId: uuid.New(), EnrolledAt: day(enrolledAt),
+			Status:         schemas.EnrollmentStatusActive,
+			StudentProfile: &schemas.StudentProfile{User: &schemas.User{FullName: name}},
+		}
+	}
+	left := enrollment("Andi", "2025-07-14")
+	leftAt := day("2025-09-01")
+	left.LeftAt = &leftAt
+	left.Status = schemas.EnrollmentStatusTransferred
+
+	f := fixture{
+		repo:     new(MockRepository),
+		unitId:   unitId,
+		homeroom: homeroom,
+		class:    class,
+		budi:     enrollment("Budi", "2025-07-14"),
+		siti:     enrollment("Siti", "2025-07-14"),
+		left:     left,
+	}
+	f.uc = NewAttendanceUseCase(f.repo).(*attendanceUseCase)
+	f.uc.now = func() time.Time { return day("2025-09-15") }
+
+	f.repo.On("FindClass", class.Id).Return(class, nil)
+	f.repo.On("FindEnrollments", class.Id).Return([]schemas.ClassEnrollment{f.siti, f.budi, f.left}, nil)
+	return f
+}
+
+func (f fixture) save(recorder Recorder, records ...RecordRequest) (*ClassAttendanceView, error) {
+	return f.uc.SaveClassAttendance(&SaveAttendanceRequest{
+		UnitId:   f.unitId,
+		ClassId:  f.class.Id,
+		Date:     day("2025-09-15"),
+		Records:  records,
+		Recorder: recorder,
+	})
+}
+
+// Tests
+
+func TestSaveClassAttendance_HomeroomTeacher(t *testing.T) {
+	f := newFixture()
+	recorder := Recorder{UserId: f.homeroom.UserId, Role: schemas.UnitMemberRoleStaff}
+	f.repo.On("FindTeacherByUser", f.unitId, f.homeroom.UserId).Return(f.homeroom, nil)
+	var saved []schemas.DailyAttendance
+	f.repo.On("Save", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		saved = args.Get(0).([]schemas.DailyAttendance)
+	})
+	note := "demam"
+	f.repo.On("FindByClassAndDate", f.class.Id, day("2025-09-15")).Return([]schemas.DailyAttendance{
+		{ClassEnrollmentId: f.budi.Id, Status: schemas.AttendanceSick, Note: &note, RecordedBy: f.homeroom.UserId},
+	}, nil)
+
+	view, err := f.save(recorder,
+		RecordRequest{ClassEnrollmentId: f.budi.Id, Status: schemas.AttendanceSick, Note: &note},
+		RecordRequest{ClassEnrollmentId: f.siti.Id, Status: schemas.AttendancePresent},
+	)
+
+	assert.NoError(t, err)
+	assert.Len(t, saved, 2)
+	assert.Equal(t, f.class.Id, saved[0].ClassId)
+	assert.Equal(t, f.homeroom.UserId, saved[1].RecordedBy)
+
+	assert.Len(t, view.Students, 2, "Andi left the class before the date")
+	assert.Equal(t, "Budi", view.Students[0].StudentName)
+	assert.Equal(t, schemas.AttendanceSick, *view.Students[0].Status)
+	assert.Nil(t, view.Students[1].Status)
+}
+
+func TestSaveClassAttendance_EditRights(t *testing.T) {
+	f := newFixture()
+	otherTeacher := &schemas.TeacherProfile{Id: uuid.New(), UnitId: f.unitId, UserId: uuid.New()}
+	tataUsaha := uuid.New()
+	f.repo.On("FindTeacherByUser", f.unitId, otherTeacher.UserId).Return(otherTeacher, nil)
+	f.repo.On("FindTeacherByUser", f.unitId, tataUsaha).Return(nil, gorm.ErrRecordNotFound)
+	f.repo.On("Save", mock.Anything).Return(nil)
+	f.repo.On("FindByClassAndDate", f.class.Id, mock.Anything).Return([]schemas.DailyAttendance{}, nil)
+	record := RecordRequest{ClassEnrollmentId: f.siti.Id, Status: schemas.AttendancePresent}
+
+	_, err := f.save(Recorder{UserId: otherTeacher.UserId, Role: schemas.UnitMemberRoleStaff}, record)
+	assert.ErrorIs(t, err, ErrNotAllowed, "a teacher who is not the homeroom teacher")
+
+	_, err = f.save(Recorder{UserId: tataUsaha, Role: schemas.UnitMemberRolePengurus}, record)
+	assert.ErrorIs(t, err, ErrNotAllowed)
+
+	_, err = f.save(Recorder{UserId: tataUsaha, Role: schemas.UnitMemberRoleStaff}, record)
+	assert.NoError(t, err, "staff without a teacher profile")
+
+	_, err = f.save(Recorder{UserId: uuid.New(), Role: schemas.UnitMemberRoleAdmin}, record)
+	assert.NoError(t, err)
+}
+
+func TestSaveClassAttendance_ViaApiKey(t *testing.T) {
+	f := newFixture()
+	keyCreator, stranger := uuid.New(), uuid.New()
+	f.repo.On("FindMember", f.unitId, f.homeroom.UserId).Return(&schemas.UnitMember{UserId: f.homeroom.UserId, Role: schemas.UnitMemberRoleStaff}, nil)
+	f.repo.On("FindMember", f.unitId, stranger).Return(nil, gorm.ErrRecordNotFound)
+	f.repo.On("FindTeacherByUser", f.unitId, f.homeroom.UserId).Return(f.homeroom, nil)
+	var saved []schemas.DailyAttendance
+	f.repo.On("Save", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		saved = args.Get(0).([]schemas.DailyAttendance)
+	})
+	f.repo.On("FindByClassAndDate", f.class.Id, mock.Anything).Return([]schemas.DailyAttendance{}, nil)
+	record := RecordRequest{ClassEnrollmentId: f.siti.Id, Status: schemas.AttendancePresent}
+
+	_, err := f.save(Recorder{UserId: keyCreator, ViaApiKey: true}, record)
+	assert.ErrorIs(t, err, ErrRecorderRequired)
+
+	_, err = f.save(Recorder{UserId: keyCreator, ViaApiKey: true, StaffUserId: &stranger}, record)
+	assert.ErrorIs(t, err, ErrRecorderNotFound)
+
+	_, err = f.save(Recorder{UserId: keyCreator, ViaApiKey: true, StaffUserId: &f.homeroom.UserId}, record)
+	assert.NoError(t, err)
+	assert.Equal(t, f.homeroom.UserId, saved[0].RecordedBy, "credited to the named teacher, not the key's creator")
+}
+
+func TestSaveClassAttendance_Refusals(t *testing.T) {
+	f := newFixture()
+	admin := Recorder{UserId: uuid.New(), Role: schemas.UnitMemberRoleAdmin}
+
+	_, err := f.save(admin, RecordRequest{ClassEnrollmentId: f.left.Id, Status: schemas.AttendancePresent})
+	assert.ErrorContains(t, err, "is not in this class")
+
+	_, err = f.save(admin, RecordRequest{ClassEnrollmentId: f.siti.Id, Status: "bolos"})
+	assert.EqualError(t, err, `invalid attendance status "bolos"`)
+
+	_, err = f.save(admin,
+		RecordRequest{ClassEnrollmentId: f.siti.Id, Status: schemas.AttendancePresent},
+		RecordRequest{ClassEnrollmentId: f.siti.Id, Status: schemas.AttendanceLate},
+	)
+	assert.ErrorContains(t, err, "listed twice")
+
+	_, err = f.uc.SaveClassAttendance(&SaveAttendanceRequest{
+		UnitId: f.unitId, ClassId: f.class.Id, Date: day("2025-09-16"), Recorder: admin,
+		Records: []RecordRequest{{ClassEnrollmentId: f.siti.Id, Status: schemas.AttendancePresent}},
+	})
+	assert.EqualError(t, err, "attendance cannot be recorded for a future date")
+
+	_, err = f.uc.SaveClassAttendance(&SaveAttendanceRequest{UnitId: uuid.New(), ClassId: f.class.Id, Recorder: admin})
+	assert.ErrorIs(t, err, ErrClassNotFound)
+
+	f.repo.AssertNotCalled(t, "Save", mock.Anything)
+}
+
+func TestGetClassRecap_TotalsPerStudent(t *testing.T) {
+	f := newFixture()
+	from, to := day("2025-08-01"), day("2025-08-31")
+	f.repo.On("CountByEnrollment", f.class.Id, from, to).Return([]attendance_repository.StatusCount{
+		{Id: f.budi.Id, Status: schemas.AttendancePresent, Days: 15},
+		{Id: f.budi.Id, Status: schemas.AttendanceLate, Days: 2},
+		{Id: f.budi.Id, Status: schemas.AttendanceAbsent, Days: 3},
+		{Id: f.left.Id, Status: schemas.AttendancePresent, Days: 20},
+	}, nil)
+	f.repo.On("CountRecordedDays", []uuid.UUID{f.class.Id}, from, to).Return(map[uuid.UUID]int{f.class.Id: 20}, nil)
+
+	recap, err := f.uc.GetClassRecap(f.unitId, f.class.Id, day("2025-08-20"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "2025-08", recap.Month)
+	assert.Equal(t, 20, recap.DaysRecorded)
+	assert.Equal(t, []string{"Andi", "Budi", "Siti"}, []string{recap.Students[0].StudentName, recap.Students[1].StudentName, recap.Students[2].StudentName})
+
+	budi := recap.Students[1].Totals
+	assert.Equal(t, Totals{Hadir: 15, Terlambat: 2, Alpa: 3, Recorded: 20, AttendanceRate: 85}, budi)
+	assert.Equal(t, Totals{}, recap.Students[2].Totals, "nothing recorded for Siti")
+	assert.Equal(t, 40, recap.Totals.Recorded)
+	assert.Equal(t, 92.5, recap.Totals.AttendanceRate)
+}
+
+func TestGetUnitRecap_PerClass(t *testing.T) {
+	repo := new(MockRepository)
+	uc := NewAttendanceUseCase(repo)
+	unitId := uuid.New()
+	classA := schemas.Class{Id: uuid.New(), Name: "VII A", Level: 7}
+	classB := schemas.Class{Id: uuid.New(), Name: "VII B", Level: 7}
+	from, to := day("2025-09-01"), day("2025-09-30")
+
+	repo.On("FindClasses", unitId, "2025/2026").Return([]schemas.Class{classA, classB}, nil)
+	repo.On("CountByClass", unitId, from, to).Return([]attendance_repository.StatusCount{
+		{Id: classA.Id, Status: schemas.AttendancePresent, Days: 90},
+		{Id: classA.Id, Status: schemas.AttendanceSick, Days: 10},
+	}, nil)
+	repo.On("CountRecordedDays", []uuid.UUID{classA.Id, classB.Id}, from, to).Return(map[uuid.UUID]int{classA.Id: 5}, nil)
+
+	recap, err := uc.GetUnitRecap(unitId, "2025/2026", day("2025-09-03"))
+
+	assert.NoError(t, err)
+	assert.Len(t, recap.Classes, 2)
+	assert.Equal(t, 5, recap.Classes[0].DaysRecorded)
+	assert.Equal(t, 90.0, recap.Classes[0].Totals.AttendanceRate)
+	assert.Equal(t, 0, recap.Classes[1].Totals.Recorded)
+	assert.Equal(t, 100, recap.Totals.Recorded)
+}
+
+func (f fixture) lesson(teacher *schemas.TeacherProfile) *schemas.TimetableEntry {
+	entry := &schemas.TimetableEntry{
+		Id: uuid.New(), UnitId: f.unitId, ClassId: f.class.Id, SubjectId: uuid.New(),
+		TeacherProfileId: teacher.Id, DayOfWeek: 1, Period: 3, // Senin, jam ke-3
+	}
+	f.repo.On("FindTimetableEntry", entry.Id).Return(entry, nil)
+	return entry
+}
+
+func TestSaveLesson_SubjectTeacher(t *testing.T) {
+	f := newFixture()
+	teacher := &schemas.TeacherProfile{Id: uuid.New(), UnitId: f.unitId, UserId: uuid.New()}
+	entry := f.lesson(teacher)
+	f.repo.On("FindTeacherByUser", f.unitId, teacher.UserId).Return(teacher, nil)
+	f.repo.On("FindTeacherByUser", f.unitId, f.homeroom.UserId).Return(f.homeroom, nil)
+
+	var journal *schemas.LessonJournal
+	var saved []schemas.LessonAttendance
+	f.repo.On("FindJournal", entry.Id, day("2025-09-15")).Return(nil, gorm.ErrRecordNotFound).Once()
+	f.repo.On("SaveLesson", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		journal = args.Get(0).(*schemas.LessonJournal)
+		saved = args.Get(1).([]schemas.LessonAttendance)
+	})
+
+	request := &LessonRequest{
+		UnitId:           f.unitId,
+		TimetableEntryId: entry.Id,
+		Date:             day("2025-09-15"),
+		Topic:            "  Bilangan bulat ",
+		Records: []RecordRequest{
+			{ClassEnrollmentId: f.budi.Id, Status: schemas.AttendancePresent},
+			{ClassEnrollmentId: f.siti.Id, Status: schemas.AttendanceAbsent},
+		},
+		Recorder: Recorder{UserId: teacher.UserId, Role: schemas.UnitMemberRoleStaff},
+	}
+
+	// Even the homeroom teacher may not record someone else's lesson
+	other := *request
+	other.Recorder = Recorder{UserId: f.homeroom.UserId, Role: schemas.UnitMemberRoleStaff}
+	_, err := f.uc.SaveLesson(&other)
+	assert.ErrorIs(t, err, ErrNotLessonTeacher)
+
+	stored := &schemas.LessonJournal{Id: uuid.New(), RecordedBy: teacher.UserId}
+	f.repo.On("FindJournal", entry.Id, day("2025-09-15")).Return(stored, nil)
+	f.repo.On("FindLessonAttendance", stored.Id).Return([]schemas.LessonAttendance{
+		{ClassEnrollmentId: f.siti.Id, Status: schemas.AttendanceAbsent},
+	}, nil)
+	view, err := f.uc.SaveLesson(request)
+	assert.NoError(t, err)
+	if assert.Len(t, view.Students, 2) {
+		assert.Nil(t, view.Students[0].Status)
+		assert.Equal(t, schemas.AttendanceAbsent, *view.Students[1].Status)
+		assert.Equal(t, stored, view.Journal)
+	}
+	if assert.NotNil(t, journal) {
+		assert.Equal(t, "Bilangan bulat", journal.Topic)
+		assert.Equal(t, entry.SubjectId, journal.SubjectId)
+		assert.Equal(t, teacher.Id, journal.TeacherProfileId)
+		assert.Equal(t, 3, journal.Period)
+		assert.Equal(t, teacher.UserId, journal.RecordedBy)
+	}
+	assert.Len(t, saved, 2)
+}
+
+func TestSaveLesson_Refusals(t *testing.T) {
+	f := newFixture()
+	teacher := &schemas.TeacherProfile{Id: uuid.New(), UnitId: f.unitId, UserId: uuid.New()}
+	entry := f.lesson(teacher)
+	admin := Recorder{UserId: uuid.New(), Role: schemas.UnitMemberRoleAdmin}
+
+	tests := []struct {
+		name    string
+		date    string
+		topic   string
+		records []RecordRequest
+		err     string
+	}{
+		{"wrong weekday", "2025-09-09", "Pecahan", nil, "this lesson is held on Senin"},
+		{"future date", "2025-09-22", "Pecahan", nil, "future date"},
+		{"no topic", "2025-09-15", " ", nil, "topic is required"},
+		{"student who left", "2025-09-15", "Pecahan", []RecordRequest{{ClassEnrollmentId: f.left.Id, Status: schemas.AttendancePresent}}, "is not in this class"},
+		{"bad status", "2025-09-15", "Pecahan", []RecordRequest{{ClassEnrollmentId: f.budi.Id, Status: "bolos"}}, "invalid attendance status"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.uc.SaveLesson(&LessonRequest{
+				UnitId: f.unitId, TimetableEntryId: entry.Id, Date: day(tt.date),
+				Topic: tt.topic, Records: tt.records, Recorder: admin,
+			})
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+
+	_, err := f.uc.SaveLesson(&LessonRequest{UnitId: uuid.New(), TimetableEntryId: entry.Id, Date: day("2025-09-15"), Topic: "Pecahan", Recorder: admin})
+	assert.ErrorIs(t, err, ErrLessonNotFound)
+	f.repo.AssertNotCalled(t, "SaveLesson", mock.Anything, mock.Anything)
+}
+
+func TestGetSubjectRecap_RatePerSubject(t *testing.T) {
+	f := newFixture()
+	math := &schemas.Subject{Id: uuid.New(), Name: "Matematika"}
+	ipa := &schemas.Subject{Id: uuid.New(), Name: "IPA"}
+	from, to := day("2025-09-01"), day("2025-09-30")
+	f.repo.On("FindJournals", mock.Anything).Return([]schemas.LessonJournal{
+		{SubjectId: math.Id, Subject: math},
+		{SubjectId: ipa.Id, Subject: ipa},
+		{SubjectId: math.Id, Subject: math},
+		{SubjectId: math.Id, Subject: math},
+		{SubjectId: math.Id, Subject: math},
+	}, nil)
+	f.repo.On("CountLessonAttendance", f.class.Id, from, to).Return([]attendance_repository.LessonStatusCount{
+		{ClassEnrollmentId: f.budi.Id, SubjectId: math.Id, Status: schemas.AttendancePresent, Lessons: 3},
+		{ClassEnrollmentId: f.budi.Id, SubjectId: math.Id, Status: schemas.AttendanceAbsent, Lessons: 1},
+		{ClassEnrollmentId: f.budi.Id, SubjectId: ipa.Id, Status: schemas.AttendancePresent, Lessons: 1},
+		{ClassEnrollmentId: f.siti.Id, SubjectId: math.Id, Status: schemas.AttendanceLate, Lessons: 4},
+	}, nil)
+
+	recap, err := f.uc.GetSubjectRecap(f.unitId, f.class.Id, from, to)
+	assert.NoError(t, err)
+	if !assert.Len(t, recap.Subjects, 2) {
+		return
+	}
+	assert.Equal(t, "Matematika", recap.Subjects[0].SubjectName)
+	assert.Equal(t, 4, recap.Subjects[0].Lessons)
+	assert.Equal(t, 87.5, recap.Subjects[0].Totals.AttendanceRate)
+
+	// Andi left on 1 September and is not listed
+	if assert.Len(t, recap.Students, 2) {
+		budi := recap.Students[0]
+		assert.Equal(t, "Budi", budi.StudentName)
+		assert.Equal(t, 75.0, budi.Subjects[0].Totals.AttendanceRate)
+		assert.Equal(t, 100.0, budi.Subjects[1].Totals.AttendanceRate)
+		assert.Equal(t, 0, recap.Students[1].Subjects[1].Totals.Recorded)
+	}
+}
+
+func TestGetReconciliation_FlagsDisagreements(t *testing.T) {
+	f := newFixture()
+	date := day("2025-09-15")
+	math, ipa := uuid.New(), uuid.New()
+	f.repo.On("FindByClassAndDate", f.class.Id, date).Return([]schemas.DailyAttendance{
+		{ClassEnrollmentId: f.budi.Id, Status: schemas.AttendancePresent},
+		{ClassEnrollmentId: f.siti.Id, Status: schemas.AttendanceSick},
+	}, nil)
+	f.repo.On("FindLessonMarks", f.class.Id, date).Return([]attendance_repository.LessonMark{
+		{ClassEnrollmentId: f.budi.Id, SubjectId: math, SubjectName: "Matematika", Period: 1, Status: schemas.AttendancePresent},
+		{ClassEnrollmentId: f.budi.Id, SubjectId: ipa, SubjectName: "IPA", Period: 5, Status: schemas.AttendanceAbsent},
+		{ClassEnrollmentId: f.siti.Id, SubjectId: math, SubjectName: "Matematika", Period: 1, Status: schemas.AttendanceSick},
+		{ClassEnrollmentId: f.siti.Id, SubjectId: ipa, SubjectName: "IPA", Period: 5, Status: schemas.AttendancePresent},
+	}, nil)
+
+	view, err := f.uc.GetReconciliation(f.unitId, f.class.Id, date)
+	assert.NoError(t, err)
+	if !assert.Len(t, view.Flags, 2) {
+		return
+	}
+
+	budi := view.Flags[0]
+	assert.Equal(t, "Budi", budi.StudentName)
+	assert.Equal(t, FlagAbsentLater, budi.Flag)
+	assert.Equal(t, []LessonMarkView{{Period: 5, SubjectId: ipa, SubjectName: "IPA", Status: schemas.AttendanceAbsent}}, budi.Lessons)
+
+	siti := view.Flags[1]
+	assert.Equal(t, FlagPresentWhileAbsent, siti.Flag)
+	assert.Equal(t, schemas.AttendanceSick, siti.DailyStatus)
+	assert.Len(t, siti.Lessons, 1)
+}
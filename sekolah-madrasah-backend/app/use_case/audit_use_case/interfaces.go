@@ -0,0 +1,11 @@
+package audit_use_case
+
+import (
+	"context"
+
+	"sekolah-madrasah/pkg/paginate_utils"
+)
+
+type AuditUseCase interface {
+	GetAuditLogs(ctx context.Context, filter AuditLogFilter, paginate *paginate_utils.PaginateData) ([]AuditLog, int, error)
+}
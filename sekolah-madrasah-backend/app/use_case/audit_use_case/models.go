@@ -0,0 +1,23 @@
+package audit_use_case
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditLog struct {
+	Id        uuid.UUID
+	ActorId   *uuid.UUID
+	UserId    *uuid.UUID
+	Action    string
+	IpAddress string
+	Details   string
+	CreatedAt time.Time
+}
+
+type AuditLogFilter struct {
+	ActorId *uuid.UUID
+	UserId  *uuid.UUID
+	Action  *string
+}
@@ -0,0 +1,43 @@
+package audit_use_case
+
+import (
+	"context"
+	"net/http"
+
+	"sekolah-madrasah/app/repository/audit_repository"
+	"sekolah-madrasah/pkg/paginate_utils"
+)
+
+type auditUseCase struct {
+	auditRepo audit_repository.AuditRepository
+}
+
+func NewAuditUseCase(auditRepo audit_repository.AuditRepository) AuditUseCase {
+	return &auditUseCase{auditRepo: auditRepo}
+}
+
+func (u *auditUseCase) GetAuditLogs(ctx context.Context, filter AuditLogFilter, paginate *paginate_utils.PaginateData) ([]AuditLog, int, error) {
+	logs, code, err := u.auditRepo.GetAuditLogs(ctx, audit_repository.AuditLogFilter{
+		ActorId: filter.ActorId,
+		UserId:  filter.UserId,
+		Action:  filter.Action,
+	}, paginate)
+	if err != nil {
+		return nil, code, err
+	}
+
+	result := make([]AuditLog, len(logs))
+	for i, l := range logs {
+		result[i] = AuditLog{
+			Id:        l.Id,
+			ActorId:   l.ActorId,
+			UserId:    l.UserId,
+			Action:    l.Action,
+			IpAddress: l.IpAddress,
+			Details:   l.Details,
+			CreatedAt: l.CreatedAt,
+		}
+	}
+
+	return result, http.StatusOK, nil
+}
@@ -0,0 +1,119 @@
+package auth_use_case
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"sekolah-madrasah/app/repository/audit_repository"
+	"sekolah-madrasah/app/repository/user_repository"
+	"sekolah-madrasah/pkg/auth_utils"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ImpersonationTokenDuration = 15 * time.Minute
+	MaxImpersonationDuration   = time.Hour
+)
+
+// Impersonate signs an access token for userId that carries the super admin as
+// its actor. The token belongs to no session and has no refresh token, so it
+// simply expires; logging out with it revokes it early.
+func (u *authUseCase) Impersonate(ctx context.Context, actor auth_utils.AuthClaim, userId uuid.UUID, req ImpersonateRequest) (ImpersonationResponse, int, error) {
+	if actor.IsApiKey() || actor.IsImpersonated() {
+		return ImpersonationResponse{}, http.StatusForbidden, errors.New("impersonation must be started from a super admin's own session")
+	}
+	if actor.UserID == userId {
+		return ImpersonationResponse{}, http.StatusBadRequest, errors.New("cannot impersonate yourself")
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return ImpersonationResponse{}, http.StatusBadRequest, errors.New("reason is required")
+	}
+
+	duration := req.Duration
+	if duration == 0 {
+		duration = ImpersonationTokenDuration
+	}
+	if duration < 0 || duration > MaxImpersonationDuration {
+		return ImpersonationResponse{}, http.StatusBadRequest, errors.New("impersonation may last at most 60 minutes")
+	}
+
+	admin, code, err := u.userRepo.GetUser(ctx, user_repository.UserFilter{Id: &actor.UserID})
+	if err != nil {
+		return ImpersonationResponse{}, code, err
+	}
+	if !admin.IsSuperAdmin {
+		return ImpersonationResponse{}, http.StatusForbidden, errors.New("super admin access required")
+	}
+
+	user, code, err := u.userRepo.GetUser(ctx, user_repository.UserFilter{Id: &userId})
+	if err != nil {
+		return ImpersonationResponse{}, code, err
+	}
+	if user.IsSuperAdmin {
+		return ImpersonationResponse{}, http.StatusForbidden, errors.New("super admins cannot be impersonated")
+	}
+	if !user.IsActive {
+		return ImpersonationResponse{}, http.StatusBadRequest, errors.New("user is inactive")
+	}
+
+	tokenId := uuid.New()
+	expiresAt := time.Now().Add(duration)
+	accessToken, err := auth_utils.GenerateTokenWithExpTimestamp(auth_utils.TokenParams{
+		UserID:    userId,
+		TokenType: auth_utils.TokenTypeAccess,
+		TokenID:   tokenId,
+
+		ImpersonatorID:      &admin.Id,
+		ImpersonationWrites: req.AllowWrites,
+	}, expiresAt.Unix())
+	if err != nil {
+		return ImpersonationResponse{}, http.StatusInternalServerError, err
+	}
+
+	u.audit(ctx, audit_repository.AuditLog{
+		ActorId:   &admin.Id,
+		UserId:    &userId,
+		Action:    audit_repository.ActionImpersonationStarted,
+		IpAddress: req.IpAddress,
+	}, map[string]interface{}{
+		"reason":       reason,
+		"allow_writes": req.AllowWrites,
+		"token_id":     tokenId,
+		"expires_at":   expiresAt,
+		"user_agent":   truncate(req.UserAgent, 255),
+	})
+
+	return ImpersonationResponse{
+		AccessToken: accessToken,
+		ExpiresAt:   expiresAt.Unix(),
+		AllowWrites: req.AllowWrites,
+		User:        toUserInfo(user),
+	}, http.StatusOK, nil
+}
+
+// RecordImpersonatedRequest writes one audit entry naming both the super admin
+// and the impersonated user. The token id ties the entry to its
+// ActionImpersonationStarted record.
+func (u *authUseCase) RecordImpersonatedRequest(ctx context.Context, claims auth_utils.AuthClaim, method, path string, status int, ipAddress string) {
+	if !claims.IsImpersonated() {
+		return
+	}
+
+	u.audit(ctx, audit_repository.AuditLog{
+		ActorId:   claims.ImpersonatorID,
+		UserId:    &claims.UserID,
+		Action:    audit_repository.ActionImpersonationRequest,
+		IpAddress: ipAddress,
+	}, map[string]interface{}{
+		"method":   method,
+		"path":     path,
+		"status":   status,
+		"token_id": claims.Id,
+	})
+}
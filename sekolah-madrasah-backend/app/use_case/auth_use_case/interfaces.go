@@ -1,9 +1,46 @@
 package auth_use_case
 
-import "context"
+import (
+	"context"
+
+	"sekolah-madrasah/pkg/auth_utils"
+
+	"github.com/google/uuid"
+)
 
 type AuthUseCase interface {
 	Login(ctx context.Context, req LoginRequest) (LoginResponse, int, error)
+	CompleteExternalLogin(ctx context.Context, req ExternalLoginRequest) (LoginResponse, int, error)
 	Register(ctx context.Context, req RegisterRequest) (UserInfo, int, error)
 	RefreshToken(ctx context.Context, req RefreshTokenRequest) (LoginResponse, int, error)
+	Logout(ctx context.Context, claims auth_utils.AuthClaim) (int, error)
+	LogoutAll(ctx context.Context, claims auth_utils.AuthClaim) (int, error)
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+
+	ForgotPassword(ctx context.Context, req ForgotPasswordRequest) (int, error)
+	ResetPassword(ctx context.Context, req ResetPasswordRequest) (int, error)
+	ChangePassword(ctx context.Context, claims auth_utils.AuthClaim, req ChangePasswordRequest) (LoginResponse, int, error)
+
+	VerifyEmail(ctx context.Context, req VerifyEmailRequest) (int, error)
+	ResendEmailVerification(ctx context.Context, req ResendEmailVerificationRequest) (int, error)
+	SendEmailVerification(ctx context.Context, userId uuid.UUID) (int, error)
+
+	VerifyTwoFactor(ctx context.Context, req VerifyTwoFactorRequest) (LoginResponse, int, error)
+	GetTwoFactorStatus(ctx context.Context, userId uuid.UUID) (TwoFactorStatus, int, error)
+	SetupTwoFactor(ctx context.Context, userId uuid.UUID) (TwoFactorSetup, int, error)
+	EnableTwoFactor(ctx context.Context, userId uuid.UUID, code string) ([]string, int, error)
+	DisableTwoFactor(ctx context.Context, userId uuid.UUID, req DisableTwoFactorRequest) (int, error)
+	RegenerateRecoveryCodes(ctx context.Context, userId uuid.UUID, code string) ([]string, int, error)
+	ResetTwoFactor(ctx context.Context, userId uuid.UUID) (int, error)
+
+	// UnlockUser clears a login lockout on behalf of an administrator.
+	UnlockUser(ctx context.Context, actor auth_utils.AuthClaim, userId uuid.UUID, ipAddress string) (int, error)
+
+	// Impersonate issues a short-lived access token that lets a super admin act as userId.
+	Impersonate(ctx context.Context, actor auth_utils.AuthClaim, userId uuid.UUID, req ImpersonateRequest) (ImpersonationResponse, int, error)
+	RecordImpersonatedRequest(ctx context.Context, claims auth_utils.AuthClaim, method, path string, status int, ipAddress string)
+
+	GetSessions(ctx context.Context, userId, currentSessionId uuid.UUID) ([]Session, int, error)
+	RevokeSession(ctx context.Context, userId, sessionId uuid.UUID) (int, error)
+	TouchSession(ctx context.Context, sessionId uuid.UUID, ipAddress string)
 }
@@ -0,0 +1,230 @@
+package auth_use_case
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"sekolah-madrasah/app/repository/audit_repository"
+	"sekolah-madrasah/app/repository/login_attempt_repository"
+	"sekolah-madrasah/app/repository/user_repository"
+	"sekolah-madrasah/config"
+	"sekolah-madrasah/pkg/auth_utils"
+
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+)
+
+// maxLoginDelay caps the progressive wait between failed attempts
+const maxLoginDelay = time.Minute
+
+// LoginThrottledError refuses a login before the password is checked. It comes
+// with 423 for a locked account and 429 while attempts are being slowed down.
+type LoginThrottledError struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string { return e.Message }
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func lockoutDuration() time.Duration {
+	return time.Duration(config.APP.Security.LoginLockoutMinutes) * time.Minute
+}
+
+// loginDelay is the wait required after the given number of consecutive failures:
+// none until LoginDelayAfter, then 2s, 4s, 8s and so on up to maxLoginDelay.
+func loginDelay(failures int) time.Duration {
+	after := config.APP.Security.LoginDelayAfter
+	if after <= 0 || failures < after {
+		return 0
+	}
+
+	shift := failures - after
+	if shift >= 5 {
+		return maxLoginDelay
+	}
+	return min((2*time.Second)<<shift, maxLoginDelay)
+}
+
+func lockedError(retryAfter time.Duration) (int, error) {
+	minutes := int(math.Ceil(retryAfter.Minutes()))
+	return http.StatusLocked, &LoginThrottledError{
+		Message:    fmt.Sprintf("account is temporarily locked after too many failed login attempts, try again in %d minute(s)", minutes),
+		RetryAfter: retryAfter,
+	}
+}
+
+func tooManyAttemptsError(retryAfter time.Duration) (int, error) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	return http.StatusTooManyRequests, &LoginThrottledError{
+		Message:    fmt.Sprintf("too many failed login attempts, try again in %d second(s)", seconds),
+		RetryAfter: retryAfter,
+	}
+}
+
+// loginThrottle decides whether another attempt is allowed after failures wrong
+// passwords, the latest at lastFailure.
+func loginThrottle(failures int, lastFailure, lockedUntil *time.Time) (int, error) {
+	now := time.Now()
+	if lockedUntil != nil && lockedUntil.After(now) {
+		return lockedError(lockedUntil.Sub(now))
+	}
+
+	if lastFailure != nil {
+		if wait := lastFailure.Add(loginDelay(failures)).Sub(now); wait > 0 {
+			return tooManyAttemptsError(wait)
+		}
+	}
+
+	return http.StatusOK, nil
+}
+
+// checkIpThrottle blocks an address once it has produced too many failures within
+// the attempt window, whichever accounts it tried.
+func (u *authUseCase) checkIpThrottle(ctx context.Context, ipAddress string) (int, error) {
+	maxAttempts := config.APP.Security.LoginMaxAttemptsPerIP
+	if maxAttempts <= 0 || ipAddress == "" {
+		return http.StatusOK, nil
+	}
+
+	window := time.Duration(config.APP.Security.LoginAttemptWindowMinutes) * time.Minute
+	since := time.Now().Add(-window)
+	success := false
+	stats, code, err := u.loginAttemptRepo.GetLoginAttemptStats(ctx, login_attempt_repository.LoginAttemptFilter{
+		IpAddress: &ipAddress,
+		Success:   &success,
+		Since:     &since,
+	})
+	if err != nil {
+		return code, err
+	}
+
+	if stats.Count >= int64(maxAttempts) && stats.First != nil {
+		return tooManyAttemptsError(time.Until(stats.First.Add(window)))
+	}
+
+	return http.StatusOK, nil
+}
+
+// checkUnknownEmailThrottle applies the account rules to an email without an
+// account, so responses do not reveal which emails are registered.
+func (u *authUseCase) checkUnknownEmailThrottle(ctx context.Context, email string) (int, error) {
+	window := time.Duration(config.APP.Security.LoginAttemptWindowMinutes) * time.Minute
+	since := time.Now().Add(-window)
+	success := false
+	stats, code, err := u.loginAttemptRepo.GetLoginAttemptStats(ctx, login_attempt_repository.LoginAttemptFilter{
+		Email:   &email,
+		Success: &success,
+		Since:   &since,
+	})
+	if err != nil {
+		return code, err
+	}
+
+	var lockedUntil *time.Time
+	if maxAttempts := config.APP.Security.LoginMaxAttempts; maxAttempts > 0 && stats.Count >= int64(maxAttempts) && stats.Last != nil {
+		until := stats.Last.Add(lockoutDuration())
+		lockedUntil = &until
+	}
+
+	return loginThrottle(int(stats.Count), stats.Last, lockedUntil)
+}
+
+func (u *authUseCase) recordLoginAttempt(ctx context.Context, email, ipAddress string, userId *uuid.UUID, success bool) {
+	if _, err := u.loginAttemptRepo.CreateLoginAttempt(ctx, login_attempt_repository.LoginAttempt{
+		Email:     email,
+		IpAddress: truncate(ipAddress, 45),
+		UserId:    userId,
+		Success:   success,
+	}); err != nil {
+		log.Errorf("failed to record login attempt for %s: %v", email, err)
+	}
+}
+
+// recordFailedLogin counts a wrong password or second factor for user and locks
+// the account when it reaches LoginMaxAttempts, in which case the lock is
+// returned as the error; otherwise invalid is.
+func (u *authUseCase) recordFailedLogin(ctx context.Context, user user_repository.User, ipAddress string, invalid error) (int, error) {
+	u.recordLoginAttempt(ctx, normalizeEmail(user.Email), ipAddress, &user.Id, false)
+
+	now := time.Now()
+	updated, code, err := u.userRepo.RecordFailedLogin(ctx, user.Id, config.APP.Security.LoginMaxAttempts, now.Add(lockoutDuration()))
+	if err != nil {
+		return code, err
+	}
+
+	if updated.LockedUntil != nil && updated.LockedUntil.After(now) {
+		u.audit(ctx, audit_repository.AuditLog{
+			UserId:    &user.Id,
+			Action:    audit_repository.ActionAccountLocked,
+			IpAddress: ipAddress,
+		}, map[string]interface{}{
+			"failed_attempts": updated.FailedLoginAttempts,
+			"locked_until":    updated.LockedUntil,
+		})
+		return lockedError(updated.LockedUntil.Sub(now))
+	}
+
+	return http.StatusUnauthorized, invalid
+}
+
+// resetFailedLogins clears the failure count once the user has fully signed in.
+func (u *authUseCase) resetFailedLogins(ctx context.Context, user user_repository.User) {
+	if user.FailedLoginAttempts == 0 && user.LockedUntil == nil {
+		return
+	}
+	if _, err := u.userRepo.ResetFailedLogins(ctx, user_repository.UserFilter{Id: &user.Id}); err != nil {
+		log.Errorf("failed to reset login failures for user %s: %v", user.Id, err)
+	}
+}
+
+// audit writes an audit log entry. Failing to write one never fails the action itself.
+func (u *authUseCase) audit(ctx context.Context, entry audit_repository.AuditLog, details map[string]interface{}) {
+	if details != nil {
+		encoded, err := json.Marshal(details)
+		if err != nil {
+			log.Errorf("failed to encode audit details for %s: %v", entry.Action, err)
+		} else {
+			entry.Details = string(encoded)
+		}
+	}
+	entry.IpAddress = truncate(entry.IpAddress, 45)
+
+	if _, err := u.auditRepo.CreateAuditLog(ctx, entry); err != nil {
+		log.Errorf("failed to write audit log %s: %v", entry.Action, err)
+	}
+}
+
+// UnlockUser lifts a lockout and resets the failure count so the user gets the
+// full number of attempts again.
+func (u *authUseCase) UnlockUser(ctx context.Context, actor auth_utils.AuthClaim, userId uuid.UUID, ipAddress string) (int, error) {
+	user, code, err := u.userRepo.GetUser(ctx, user_repository.UserFilter{Id: &userId})
+	if err != nil {
+		return code, err
+	}
+
+	code, err = u.userRepo.ResetFailedLogins(ctx, user_repository.UserFilter{Id: &userId})
+	if err != nil {
+		return code, err
+	}
+
+	u.audit(ctx, audit_repository.AuditLog{
+		ActorId:   &actor.UserID,
+		UserId:    &userId,
+		Action:    audit_repository.ActionAccountUnlocked,
+		IpAddress: ipAddress,
+	}, map[string]interface{}{
+		"failed_attempts": user.FailedLoginAttempts,
+		"locked_until":    user.LockedUntil,
+	})
+
+	return http.StatusOK, nil
+}
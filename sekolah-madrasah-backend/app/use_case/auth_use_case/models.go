@@ -7,8 +7,18 @@ import (
 )
 
 type LoginRequest struct {
-	Email    string
-	Password string
+	Email     string
+	Password  string
+	UserAgent string
+	IpAddress string
+}
+
+// ExternalLoginRequest identifies a user who authenticated elsewhere, e.g. at
+// an organization's identity provider.
+type ExternalLoginRequest struct {
+	UserId    uuid.UUID
+	UserAgent string
+	IpAddress string
 }
 
 type LoginResponse struct {
@@ -16,16 +26,79 @@ type LoginResponse struct {
 	RefreshToken string
 	ExpiresAt    int64
 	User         UserInfo
+
+	// TwoFactorRequired means no tokens were issued yet; ChallengeToken must be
+	// exchanged together with a TOTP or recovery code.
+	TwoFactorRequired bool
+	ChallengeToken    string
 }
 
 type RegisterRequest struct {
 	Email    string
 	Password string
 	FullName string
+
+	// UnitCode optionally signs the user up for a school as Role (teacher, parent
+	// or student). They cannot log in until the school's admins approve it.
+	UnitCode string
+	Role     string
 }
 
 type RefreshTokenRequest struct {
 	RefreshToken string
+	UserAgent    string
+	IpAddress    string
+}
+
+type ForgotPasswordRequest struct {
+	Email     string
+	IpAddress string
+}
+
+type ResetPasswordRequest struct {
+	Token       string
+	NewPassword string
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string
+	NewPassword string
+	UserAgent   string
+	IpAddress   string
+}
+
+type VerifyEmailRequest struct {
+	Token string
+}
+
+type ResendEmailVerificationRequest struct {
+	Email string
+}
+
+type VerifyTwoFactorRequest struct {
+	ChallengeToken string
+	Code           string
+	RecoveryCode   string
+	UserAgent      string
+	IpAddress      string
+}
+
+type TwoFactorStatus struct {
+	Enabled  bool
+	Required bool
+	// RecoveryCodesLeft counts unused recovery codes
+	RecoveryCodesLeft int64
+}
+
+type TwoFactorSetup struct {
+	Secret     string
+	OtpauthURI string
+}
+
+type DisableTwoFactorRequest struct {
+	Password     string
+	Code         string
+	RecoveryCode string
 }
 
 type UserInfo struct {
@@ -34,5 +107,37 @@ type UserInfo struct {
 	FullName     string
 	IsSuperAdmin bool
 	IsActive     bool
-	LastLoginAt  *time.Time
+	// MustChangePassword means the issued access token only works for changing the password
+	MustChangePassword bool
+	EmailVerifiedAt    *time.Time
+	LastLoginAt        *time.Time
+}
+
+type Session struct {
+	Id         uuid.UUID
+	Device     string
+	UserAgent  string
+	IpAddress  string
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	CreatedAt  time.Time
+	Current    bool
+}
+
+type ImpersonateRequest struct {
+	// Reason is kept in the audit log with the start of the impersonation
+	Reason string
+	// AllowWrites lets the token call state-changing endpoints; it is read-only otherwise
+	AllowWrites bool
+	// Duration defaults to ImpersonationTokenDuration and may not exceed MaxImpersonationDuration
+	Duration  time.Duration
+	UserAgent string
+	IpAddress string
+}
+
+type ImpersonationResponse struct {
+	AccessToken string
+	ExpiresAt   int64
+	AllowWrites bool
+	User        UserInfo
 }
@@ -0,0 +1,169 @@
+package auth_use_case
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"sekolah-madrasah/app/repository/token_repository"
+	"sekolah-madrasah/app/repository/user_repository"
+	"sekolah-madrasah/app/service/mail_service"
+	"sekolah-madrasah/config"
+	"sekolah-madrasah/pkg/auth_utils"
+	"sekolah-madrasah/pkg/password_utils"
+
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const PasswordResetTokenDuration = time.Hour
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newResetToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// ForgotPassword mails a reset link when the email belongs to an active account.
+// It reports success either way so the endpoint cannot be used to probe for accounts.
+func (u *authUseCase) ForgotPassword(ctx context.Context, req ForgotPasswordRequest) (int, error) {
+	email := strings.TrimSpace(req.Email)
+	user, code, err := u.userRepo.GetUser(ctx, user_repository.UserFilter{Email: &email})
+	if err != nil {
+		if code == http.StatusNotFound {
+			return http.StatusOK, nil
+		}
+		return code, err
+	}
+	if !user.IsActive {
+		return http.StatusOK, nil
+	}
+
+	token, err := newResetToken()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	code, err = u.tokenRepo.CreatePasswordResetToken(ctx, token_repository.PasswordResetToken{
+		UserId:    user.Id,
+		TokenHash: hashResetToken(token),
+		ExpiresAt: time.Now().Add(PasswordResetTokenDuration),
+		IpAddress: truncate(req.IpAddress, 45),
+	})
+	if err != nil {
+		return code, err
+	}
+
+	link := strings.TrimRight(config.APP.Rest.FrontendURL, "/") + "/reset-password?token=" + url.QueryEscape(token)
+	err = u.mailer.Send(ctx, mail_service.Message{
+		To:      []string{user.Email},
+		Subject: "Atur ulang kata sandi",
+		Body: fmt.Sprintf(
+			"Halo %s,\n\nKami menerima permintaan untuk mengatur ulang kata sandi akun Anda. "+
+				"Buka tautan berikut dalam %d menit untuk membuat kata sandi baru:\n\n%s\n\n"+
+				"Abaikan email ini jika Anda tidak merasa memintanya.\n",
+			user.FullName, int(PasswordResetTokenDuration.Minutes()), link,
+		),
+	})
+	if err != nil {
+		// Keep the response identical to the unknown-email case
+		log.Errorf("failed to send password reset email to user %s: %v", user.Id, err)
+	}
+
+	return http.StatusOK, nil
+}
+
+// ResetPassword sets a new password with a mailed reset token and signs the user
+// out everywhere, since whoever held the old password may still have sessions.
+func (u *authUseCase) ResetPassword(ctx context.Context, req ResetPasswordRequest) (int, error) {
+	if err := password_utils.ValidateStrength(req.NewPassword); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	resetToken, code, err := u.tokenRepo.ConsumePasswordResetToken(ctx, hashResetToken(req.Token))
+	if err != nil {
+		return code, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	code, err = u.userRepo.UpdatePassword(ctx, user_repository.UserFilter{Id: &resetToken.UserId}, string(hashedPassword), false)
+	if err != nil {
+		return code, err
+	}
+
+	code, err = u.revokeTokens(ctx, token_repository.RefreshTokenFilter{UserId: &resetToken.UserId})
+	if err != nil {
+		return code, err
+	}
+
+	return http.StatusOK, nil
+}
+
+// ChangePassword replaces the caller's password after checking the current one.
+// Every session is signed out and a fresh token pair is returned for this device.
+func (u *authUseCase) ChangePassword(ctx context.Context, claims auth_utils.AuthClaim, req ChangePasswordRequest) (LoginResponse, int, error) {
+	user, code, err := u.userRepo.GetUser(ctx, user_repository.UserFilter{Id: &claims.UserID})
+	if err != nil {
+		return LoginResponse{}, code, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.OldPassword)); err != nil {
+		return LoginResponse{}, http.StatusBadRequest, errors.New("current password is incorrect")
+	}
+
+	if req.OldPassword == req.NewPassword {
+		return LoginResponse{}, http.StatusBadRequest, errors.New("new password must differ from the current password")
+	}
+
+	if err := password_utils.ValidateStrength(req.NewPassword, user.Email, user.FullName); err != nil {
+		return LoginResponse{}, http.StatusBadRequest, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return LoginResponse{}, http.StatusInternalServerError, err
+	}
+
+	code, err = u.userRepo.UpdatePassword(ctx, user_repository.UserFilter{Id: &user.Id}, string(hashedPassword), false)
+	if err != nil {
+		return LoginResponse{}, code, err
+	}
+	user.MustChangePassword = false
+
+	code, err = u.revokeTokens(ctx, token_repository.RefreshTokenFilter{UserId: &user.Id}, currentAccessToken(claims)...)
+	if err != nil {
+		return LoginResponse{}, code, err
+	}
+
+	tokens, code, err := u.issueTokens(ctx, user, uuid.New(), req.UserAgent, req.IpAddress)
+	if err != nil {
+		return LoginResponse{}, code, err
+	}
+
+	return LoginResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt,
+		User:         toUserInfo(user),
+	}, http.StatusOK, nil
+}
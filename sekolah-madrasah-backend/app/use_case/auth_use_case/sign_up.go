@@ -0,0 +1,67 @@
+package auth_use_case
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"sekolah-madrasah/app/repository/unit_repository"
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+)
+
+// signUpRoles are the roles someone may ask for when signing up for a school,
+// keyed by the name used in the registration request.
+var signUpRoles = map[string]schemas.UnitMemberRole{
+	"teacher": schemas.UnitMemberRoleStaff,
+	"parent":  schemas.UnitMemberRoleParent,
+	"student": schemas.UnitMemberRoleAnggota,
+}
+
+// signUpUnit finds the school a registration asks to join, before the account
+// is created so a wrong code does not leave an orphaned user behind.
+func (u *authUseCase) signUpUnit(ctx context.Context, req RegisterRequest) (unit_repository.Unit, schemas.UnitMemberRole, int, error) {
+	role, ok := signUpRoles[req.Role]
+	if !ok {
+		return unit_repository.Unit{}, "", http.StatusBadRequest, errors.New("role must be teacher, parent or student")
+	}
+
+	code := strings.TrimSpace(req.UnitCode)
+	if code == "" {
+		return unit_repository.Unit{}, "", http.StatusBadRequest, errors.New("unit_code is required to sign up for a school")
+	}
+
+	active := true
+	unit, status, err := u.unitRepo.GetUnit(ctx, unit_repository.UnitFilter{Code: &code, IsActive: &active})
+	if err != nil {
+		if status == http.StatusNotFound {
+			return unit_repository.Unit{}, "", http.StatusBadRequest, errors.New("no school found with this code")
+		}
+		return unit_repository.Unit{}, "", status, err
+	}
+
+	return unit, role, http.StatusOK, nil
+}
+
+// checkSignUpApproval refuses a login until the school has approved the user's sign-up.
+func (u *authUseCase) checkSignUpApproval(ctx context.Context, userId uuid.UUID) (int, error) {
+	approval, err := u.userRepo.GetApprovalStatus(ctx, userId)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	switch approval.Status {
+	case schemas.UnitMemberApprovalPending:
+		return http.StatusForbidden, errors.New("your registration is waiting for approval by the school administrator")
+	case schemas.UnitMemberApprovalRejected:
+		message := "your registration was not approved by the school, please contact the school administrator"
+		if approval.RejectionReason != "" {
+			message += ": " + approval.RejectionReason
+		}
+		return http.StatusForbidden, errors.New(message)
+	}
+
+	return http.StatusOK, nil
+}
@@ -0,0 +1,341 @@
+package auth_use_case
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"sekolah-madrasah/app/repository/two_factor_repository"
+	"sekolah-madrasah/app/repository/user_repository"
+	"sekolah-madrasah/config"
+	"sekolah-madrasah/pkg/auth_utils"
+	"sekolah-madrasah/pkg/crypto_utils"
+	"sekolah-madrasah/pkg/totp_utils"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	TwoFactorChallengeDuration = 5 * time.Minute
+	RecoveryCodeCount          = 10
+
+	// recoveryCodeAlphabet has 32 symbols, without i, l, o and 1, so bytes map onto it evenly
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz023456789"
+)
+
+var errInvalidTwoFactorCode = errors.New("invalid verification code")
+
+// twoFactorRequired reports whether policy forces the user to use two-factor authentication.
+func (u *authUseCase) twoFactorRequired(ctx context.Context, user user_repository.User) (bool, error) {
+	if user.IsSuperAdmin && config.APP.Security.RequireSuperAdminTwoFactor {
+		return true, nil
+	}
+	return u.userRepo.RequiresTwoFactor(ctx, user.Id)
+}
+
+// twoFactorSetupRequired reports whether the user must enroll before the API opens up.
+func (u *authUseCase) twoFactorSetupRequired(ctx context.Context, user user_repository.User) (bool, error) {
+	twoFactor, code, err := u.twoFactorRepo.GetTwoFactor(ctx, user.Id)
+	if err != nil && code != http.StatusNotFound {
+		return false, err
+	}
+	if err == nil && twoFactor.ConfirmedAt != nil {
+		return false, nil
+	}
+	return u.twoFactorRequired(ctx, user)
+}
+
+func (u *authUseCase) twoFactorChallenge(user user_repository.User) (LoginResponse, int, error) {
+	expiresAt := time.Now().Add(TwoFactorChallengeDuration).Unix()
+	token, err := auth_utils.GenerateTokenWithExpTimestamp(auth_utils.TokenParams{
+		UserID:    user.Id,
+		TokenType: auth_utils.TokenTypeTwoFactorChallenge,
+	}, expiresAt)
+	if err != nil {
+		return LoginResponse{}, http.StatusInternalServerError, err
+	}
+
+	return LoginResponse{
+		ExpiresAt:         expiresAt,
+		User:              toUserInfo(user),
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+	}, http.StatusOK, nil
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused recovery code.
+// Each TOTP time step and each recovery code only works once.
+func (u *authUseCase) verifySecondFactor(ctx context.Context, twoFactor two_factor_repository.TwoFactor, code, recoveryCode string) (int, error) {
+	if recoveryCode != "" {
+		code, err := u.twoFactorRepo.UseRecoveryCode(ctx, twoFactor.UserId, hashRecoveryCode(recoveryCode))
+		if err != nil && code == http.StatusBadRequest {
+			return http.StatusUnauthorized, errInvalidTwoFactorCode
+		}
+		return code, err
+	}
+
+	secret, err := crypto_utils.Decrypt(twoFactor.Secret, config.APP.Security.AesKey)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("failed to read two-factor secret")
+	}
+
+	step, ok := totp_utils.Validate(string(secret), code, time.Now())
+	if !ok {
+		return http.StatusUnauthorized, errInvalidTwoFactorCode
+	}
+
+	if code, err := u.twoFactorRepo.UseStep(ctx, twoFactor.UserId, step); err != nil {
+		if code == http.StatusConflict {
+			return http.StatusUnauthorized, errInvalidTwoFactorCode
+		}
+		return code, err
+	}
+
+	return http.StatusOK, nil
+}
+
+// confirmedTwoFactor loads the user's active two-factor enrollment.
+func (u *authUseCase) confirmedTwoFactor(ctx context.Context, userId uuid.UUID) (two_factor_repository.TwoFactor, int, error) {
+	twoFactor, code, err := u.twoFactorRepo.GetTwoFactor(ctx, userId)
+	if err != nil {
+		if code == http.StatusNotFound {
+			return two_factor_repository.TwoFactor{}, http.StatusBadRequest, errors.New("two-factor authentication is not enabled")
+		}
+		return two_factor_repository.TwoFactor{}, code, err
+	}
+	if twoFactor.ConfirmedAt == nil {
+		return two_factor_repository.TwoFactor{}, http.StatusBadRequest, errors.New("two-factor authentication is not enabled")
+	}
+	return twoFactor, http.StatusOK, nil
+}
+
+func newRecoveryCodes() (codes []string, hashes []string, err error) {
+	raw := make([]byte, 10)
+	for i := 0; i < RecoveryCodeCount; i++ {
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		code := make([]byte, len(raw))
+		for j, b := range raw {
+			code[j] = recoveryCodeAlphabet[b&31]
+		}
+		formatted := string(code[:5]) + "-" + string(code[5:])
+		codes = append(codes, formatted)
+		hashes = append(hashes, hashRecoveryCode(formatted))
+	}
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyTwoFactor finishes a two-factor login by exchanging the challenge token
+// from Login and a second factor for a regular token pair.
+func (u *authUseCase) VerifyTwoFactor(ctx context.Context, req VerifyTwoFactorRequest) (LoginResponse, int, error) {
+	claims, err := auth_utils.ValidateToken(req.ChallengeToken)
+	if err != nil || claims.TokenType != auth_utils.TokenTypeTwoFactorChallenge {
+		return LoginResponse{}, http.StatusUnauthorized, errors.New("invalid or expired challenge token")
+	}
+
+	user, code, err := u.userRepo.GetUser(ctx, user_repository.UserFilter{Id: &claims.UserID})
+	if err != nil {
+		if code == http.StatusNotFound {
+			return LoginResponse{}, http.StatusUnauthorized, errors.New("invalid or expired challenge token")
+		}
+		return LoginResponse{}, code, err
+	}
+
+	if code, err := loginThrottle(user.FailedLoginAttempts, user.LastFailedLoginAt, user.LockedUntil); err != nil {
+		return LoginResponse{}, code, err
+	}
+
+	if !user.IsActive {
+		return LoginResponse{}, http.StatusForbidden, errors.New("account is not active")
+	}
+
+	twoFactor, code, err := u.confirmedTwoFactor(ctx, user.Id)
+	if err != nil {
+		return LoginResponse{}, code, err
+	}
+
+	if code, err := u.verifySecondFactor(ctx, twoFactor, req.Code, req.RecoveryCode); err != nil {
+		if code == http.StatusUnauthorized {
+			code, err = u.recordFailedLogin(ctx, user, req.IpAddress, err)
+		}
+		return LoginResponse{}, code, err
+	}
+	u.resetFailedLogins(ctx, user)
+
+	tokens, code, err := u.issueTokens(ctx, user, uuid.New(), req.UserAgent, req.IpAddress)
+	if err != nil {
+		return LoginResponse{}, code, err
+	}
+
+	u.userRepo.UpdateLastLogin(ctx, user_repository.UserFilter{Id: &user.Id})
+
+	return LoginResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt,
+		User:         toUserInfo(user),
+	}, http.StatusOK, nil
+}
+
+func (u *authUseCase) GetTwoFactorStatus(ctx context.Context, userId uuid.UUID) (TwoFactorStatus, int, error) {
+	user, code, err := u.userRepo.GetUser(ctx, user_repository.UserFilter{Id: &userId})
+	if err != nil {
+		return TwoFactorStatus{}, code, err
+	}
+
+	required, err := u.twoFactorRequired(ctx, user)
+	if err != nil {
+		return TwoFactorStatus{}, http.StatusInternalServerError, err
+	}
+
+	status := TwoFactorStatus{Required: required}
+	twoFactor, code, err := u.twoFactorRepo.GetTwoFactor(ctx, userId)
+	if err != nil && code != http.StatusNotFound {
+		return TwoFactorStatus{}, code, err
+	}
+	if err == nil && twoFactor.ConfirmedAt != nil {
+		status.Enabled = true
+		status.RecoveryCodesLeft, code, err = u.twoFactorRepo.CountRecoveryCodes(ctx, userId)
+		if err != nil {
+			return TwoFactorStatus{}, code, err
+		}
+	}
+
+	return status, http.StatusOK, nil
+}
+
+// SetupTwoFactor starts enrollment with a fresh secret. Nothing changes for the
+// user until EnableTwoFactor confirms a code from the authenticator app.
+func (u *authUseCase) SetupTwoFactor(ctx context.Context, userId uuid.UUID) (TwoFactorSetup, int, error) {
+	user, code, err := u.userRepo.GetUser(ctx, user_repository.UserFilter{Id: &userId})
+	if err != nil {
+		return TwoFactorSetup{}, code, err
+	}
+
+	secret, err := totp_utils.GenerateSecret()
+	if err != nil {
+		return TwoFactorSetup{}, http.StatusInternalServerError, err
+	}
+
+	encrypted, err := crypto_utils.Encrypt([]byte(secret), config.APP.Security.AesKey)
+	if err != nil {
+		return TwoFactorSetup{}, http.StatusInternalServerError, err
+	}
+
+	if code, err := u.twoFactorRepo.SavePendingTwoFactor(ctx, user.Id, encrypted); err != nil {
+		return TwoFactorSetup{}, code, err
+	}
+
+	return TwoFactorSetup{
+		Secret:     secret,
+		OtpauthURI: totp_utils.KeyURI(config.APP.Security.TwoFactorIssuer, user.Email, secret),
+	}, http.StatusOK, nil
+}
+
+// EnableTwoFactor confirms the pending secret with a code and returns the recovery
+// codes. They are only ever shown here.
+func (u *authUseCase) EnableTwoFactor(ctx context.Context, userId uuid.UUID, code string) ([]string, int, error) {
+	twoFactor, status, err := u.twoFactorRepo.GetTwoFactor(ctx, userId)
+	if err != nil || twoFactor.ConfirmedAt != nil {
+		if err != nil && status != http.StatusNotFound {
+			return nil, status, err
+		}
+		return nil, http.StatusBadRequest, errors.New("start two-factor setup first")
+	}
+
+	secret, err := crypto_utils.Decrypt(twoFactor.Secret, config.APP.Security.AesKey)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.New("failed to read two-factor secret")
+	}
+
+	step, ok := totp_utils.Validate(string(secret), code, time.Now())
+	if !ok {
+		return nil, http.StatusBadRequest, errInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	if status, err := u.twoFactorRepo.ConfirmTwoFactor(ctx, userId, step, hashes); err != nil {
+		return nil, status, err
+	}
+
+	return codes, http.StatusOK, nil
+}
+
+func (u *authUseCase) DisableTwoFactor(ctx context.Context, userId uuid.UUID, req DisableTwoFactorRequest) (int, error) {
+	user, code, err := u.userRepo.GetUser(ctx, user_repository.UserFilter{Id: &userId})
+	if err != nil {
+		return code, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		return http.StatusBadRequest, errors.New("current password is incorrect")
+	}
+
+	required, err := u.twoFactorRequired(ctx, user)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if required {
+		return http.StatusForbidden, errors.New("two-factor authentication is mandatory for this account")
+	}
+
+	twoFactor, code, err := u.confirmedTwoFactor(ctx, userId)
+	if err != nil {
+		return code, err
+	}
+
+	if code, err := u.verifySecondFactor(ctx, twoFactor, req.Code, req.RecoveryCode); err != nil {
+		return code, err
+	}
+
+	return u.twoFactorRepo.DeleteTwoFactor(ctx, userId)
+}
+
+// RegenerateRecoveryCodes replaces every recovery code after checking a TOTP code.
+func (u *authUseCase) RegenerateRecoveryCodes(ctx context.Context, userId uuid.UUID, code string) ([]string, int, error) {
+	twoFactor, status, err := u.confirmedTwoFactor(ctx, userId)
+	if err != nil {
+		return nil, status, err
+	}
+
+	if status, err := u.verifySecondFactor(ctx, twoFactor, code, ""); err != nil {
+		return nil, status, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	if status, err := u.twoFactorRepo.ReplaceRecoveryCodes(ctx, userId, hashes); err != nil {
+		return nil, status, err
+	}
+
+	return codes, http.StatusOK, nil
+}
+
+// ResetTwoFactor removes a user's enrollment so they can sign in with their password
+// again, for example after losing their authenticator and recovery codes.
+func (u *authUseCase) ResetTwoFactor(ctx context.Context, userId uuid.UUID) (int, error) {
+	if _, code, err := u.userRepo.GetUser(ctx, user_repository.UserFilter{Id: &userId}); err != nil {
+		return code, err
+	}
+	return u.twoFactorRepo.DeleteTwoFactor(ctx, userId)
+}
@@ -4,85 +4,318 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"sync"
 	"time"
 
+	"sekolah-madrasah/app/repository/audit_repository"
+	"sekolah-madrasah/app/repository/login_attempt_repository"
+	"sekolah-madrasah/app/repository/token_repository"
+	"sekolah-madrasah/app/repository/two_factor_repository"
+	"sekolah-madrasah/app/repository/unit_repository"
 	"sekolah-madrasah/app/repository/user_repository"
+	"sekolah-madrasah/app/service/mail_service"
+	"sekolah-madrasah/database/schemas"
 	"sekolah-madrasah/pkg/auth_utils"
+	"sekolah-madrasah/pkg/request_utils"
 
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
 	"golang.org/x/crypto/bcrypt"
 )
 
 const (
 	AccessTokenDuration  = 24 * time.Hour
 	RefreshTokenDuration = 7 * 24 * time.Hour
+
+	// SessionTouchInterval limits how often authenticated requests update a session's last-seen time
+	SessionTouchInterval = 5 * time.Minute
+
+	maxTrackedSessions = 10000
 )
 
+var errInvalidCredentials = errors.New("invalid email or password")
+
 type authUseCase struct {
-	userRepo user_repository.UserRepository
+	userRepo         user_repository.UserRepository
+	tokenRepo        token_repository.TokenRepository
+	twoFactorRepo    two_factor_repository.TwoFactorRepository
+	loginAttemptRepo login_attempt_repository.LoginAttemptRepository
+	auditRepo        audit_repository.AuditRepository
+	unitRepo         unit_repository.UnitRepository
+	mailer           mail_service.Mailer
+
+	touchMu     sync.Mutex
+	lastTouched map[uuid.UUID]time.Time
+}
+
+func NewAuthUseCase(
+	userRepo user_repository.UserRepository,
+	tokenRepo token_repository.TokenRepository,
+	twoFactorRepo two_factor_repository.TwoFactorRepository,
+	loginAttemptRepo login_attempt_repository.LoginAttemptRepository,
+	auditRepo audit_repository.AuditRepository,
+	unitRepo unit_repository.UnitRepository,
+	mailer mail_service.Mailer,
+) AuthUseCase {
+	return &authUseCase{
+		userRepo:         userRepo,
+		tokenRepo:        tokenRepo,
+		twoFactorRepo:    twoFactorRepo,
+		loginAttemptRepo: loginAttemptRepo,
+		auditRepo:        auditRepo,
+		unitRepo:         unitRepo,
+		mailer:           mailer,
+		lastTouched:      make(map[uuid.UUID]time.Time),
+	}
+}
+
+type issuedTokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    int64
+}
+
+// issueTokens signs an access/refresh pair for the session identified by familyId
+// and stores the refresh token so it can later be rotated or revoked.
+func (u *authUseCase) issueTokens(ctx context.Context, user user_repository.User, familyId uuid.UUID, userAgent, ipAddress string) (issuedTokens, int, error) {
+	userId := user.Id
+	now := time.Now()
+	accessId, refreshId := uuid.New(), uuid.New()
+	accessExpiresAt := now.Add(AccessTokenDuration)
+	refreshExpiresAt := now.Add(RefreshTokenDuration)
+
+	setupRequired, err := u.twoFactorSetupRequired(ctx, user)
+	if err != nil {
+		return issuedTokens{}, http.StatusInternalServerError, err
+	}
+
+	accessToken, err := auth_utils.GenerateTokenWithExpTimestamp(auth_utils.TokenParams{
+		UserID:    userId,
+		TokenType: auth_utils.TokenTypeAccess,
+		TokenID:   accessId,
+		SessionID: familyId,
+
+		MustChangePassword: user.MustChangePassword,
+		TwoFactorSetup:     setupRequired,
+	}, accessExpiresAt.Unix())
+	if err != nil {
+		return issuedTokens{}, http.StatusInternalServerError, err
+	}
+
+	refreshToken, err := auth_utils.GenerateTokenWithExpTimestamp(auth_utils.TokenParams{
+		UserID:    userId,
+		TokenType: auth_utils.TokenTypeRefresh,
+		TokenID:   refreshId,
+		SessionID: familyId,
+	}, refreshExpiresAt.Unix())
+	if err != nil {
+		return issuedTokens{}, http.StatusInternalServerError, err
+	}
+
+	userAgent, ipAddress = truncate(userAgent, 255), truncate(ipAddress, 45)
+
+	_, code, err := u.tokenRepo.CreateRefreshToken(ctx, token_repository.RefreshToken{
+		Id:              refreshId,
+		UserId:          userId,
+		FamilyId:        familyId,
+		AccessTokenId:   accessId,
+		AccessExpiresAt: accessExpiresAt,
+		ExpiresAt:       refreshExpiresAt,
+		UserAgent:       userAgent,
+		IpAddress:       ipAddress,
+	})
+	if err != nil {
+		return issuedTokens{}, code, err
+	}
+
+	code, err = u.tokenRepo.SaveSession(ctx, token_repository.Session{
+		Id:         familyId,
+		UserId:     userId,
+		Device:     request_utils.DeviceFromUserAgent(userAgent),
+		UserAgent:  userAgent,
+		IpAddress:  ipAddress,
+		LastSeenAt: now,
+		ExpiresAt:  refreshExpiresAt,
+	})
+	if err != nil {
+		return issuedTokens{}, code, err
+	}
+
+	return issuedTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    accessExpiresAt.Unix(),
+	}, http.StatusOK, nil
+}
+
+// revokeTokens revokes every refresh token matching the filter and denylists the
+// access tokens issued alongside them that have not expired yet.
+func (u *authUseCase) revokeTokens(ctx context.Context, filter token_repository.RefreshTokenFilter, extra ...token_repository.RevokedAccessToken) (int, error) {
+	tokens, code, err := u.tokenRepo.GetRefreshTokens(ctx, filter)
+	if err != nil {
+		return code, err
+	}
+
+	now := time.Now()
+	denied := append([]token_repository.RevokedAccessToken{}, extra...)
+	for _, t := range tokens {
+		if t.AccessExpiresAt.After(now) {
+			denied = append(denied, token_repository.RevokedAccessToken{
+				Jti:       t.AccessTokenId,
+				UserId:    t.UserId,
+				ExpiresAt: t.AccessExpiresAt,
+			})
+		}
+	}
+
+	if code, err := u.tokenRepo.RevokeAccessTokens(ctx, denied); err != nil {
+		return code, err
+	}
+
+	if code, err := u.tokenRepo.RevokeRefreshTokens(ctx, filter); err != nil {
+		return code, err
+	}
+
+	return u.tokenRepo.RevokeSessions(ctx, token_repository.SessionFilter{
+		Id:     filter.FamilyId,
+		UserId: filter.UserId,
+	})
+}
+
+// currentAccessToken describes the access token in claims as a denylist entry.
+func currentAccessToken(claims auth_utils.AuthClaim) []token_repository.RevokedAccessToken {
+	jti, err := uuid.Parse(claims.Id)
+	if err != nil {
+		return nil
+	}
+	return []token_repository.RevokedAccessToken{{
+		Jti:       jti,
+		UserId:    claims.UserID,
+		ExpiresAt: time.Unix(claims.Exp, 0),
+	}}
+}
+
+func toUserInfo(user user_repository.User) UserInfo {
+	return UserInfo{
+		Id:                 user.Id,
+		Email:              user.Email,
+		FullName:           user.FullName,
+		IsSuperAdmin:       user.IsSuperAdmin,
+		IsActive:           user.IsActive,
+		MustChangePassword: user.MustChangePassword,
+		EmailVerifiedAt:    user.EmailVerifiedAt,
+		LastLoginAt:        user.LastLoginAt,
+	}
 }
 
-func NewAuthUseCase(userRepo user_repository.UserRepository) AuthUseCase {
-	return &authUseCase{userRepo: userRepo}
+func truncate(value string, max int) string {
+	if len(value) > max {
+		return value[:max]
+	}
+	return value
 }
 
+// Login checks the password after the IP and account throttles. Refusals from a
+// throttle are *LoginThrottledError values carrying how long to wait.
 func (u *authUseCase) Login(ctx context.Context, req LoginRequest) (LoginResponse, int, error) {
+	if code, err := u.checkIpThrottle(ctx, req.IpAddress); err != nil {
+		return LoginResponse{}, code, err
+	}
+
 	user, code, err := u.userRepo.GetUser(ctx, user_repository.UserFilter{
 		Email: &req.Email,
 	})
 	if err != nil {
 		if code == http.StatusNotFound {
-			return LoginResponse{}, http.StatusUnauthorized, errors.New("invalid email or password")
+			email := normalizeEmail(req.Email)
+			if code, err := u.checkUnknownEmailThrottle(ctx, email); err != nil {
+				return LoginResponse{}, code, err
+			}
+			u.recordLoginAttempt(ctx, email, req.IpAddress, nil, false)
+			return LoginResponse{}, http.StatusUnauthorized, errInvalidCredentials
 		}
 		return LoginResponse{}, code, err
 	}
 
+	if code, err := loginThrottle(user.FailedLoginAttempts, user.LastFailedLoginAt, user.LockedUntil); err != nil {
+		return LoginResponse{}, code, err
+	}
+
 	if !user.IsActive {
 		return LoginResponse{}, http.StatusForbidden, errors.New("account is not active")
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return LoginResponse{}, http.StatusUnauthorized, errors.New("invalid email or password")
+		code, err := u.recordFailedLogin(ctx, user, req.IpAddress, errInvalidCredentials)
+		return LoginResponse{}, code, err
+	}
+
+	u.recordLoginAttempt(ctx, normalizeEmail(user.Email), req.IpAddress, &user.Id, true)
+
+	resp, code, err := u.completeLogin(ctx, user, req.UserAgent, req.IpAddress)
+	// Failed second factors count until VerifyTwoFactor succeeds, so the
+	// password alone must not clear them
+	if err == nil && !resp.TwoFactorRequired {
+		u.resetFailedLogins(ctx, user)
+	}
+	return resp, code, err
+}
+
+// CompleteExternalLogin signs in a user another authenticator, such as an
+// OpenID provider, has already identified. The same account checks as Login
+// apply, including the two-factor challenge.
+func (u *authUseCase) CompleteExternalLogin(ctx context.Context, req ExternalLoginRequest) (LoginResponse, int, error) {
+	user, code, err := u.userRepo.GetUser(ctx, user_repository.UserFilter{Id: &req.UserId})
+	if err != nil {
+		return LoginResponse{}, code, err
+	}
+
+	if !user.IsActive {
+		return LoginResponse{}, http.StatusForbidden, errors.New("account is not active")
 	}
 
-	// Check if user is a warga with pending or rejected approval
-	approvalStatus, err := u.userRepo.GetApprovalStatus(ctx, user.Id)
-	if err == nil {
-		if approvalStatus == "pending" {
-			return LoginResponse{}, http.StatusForbidden, errors.New("pendaftaran Anda masih menunggu verifikasi dari pengurus")
+	return u.completeLogin(ctx, user, req.UserAgent, req.IpAddress)
+}
+
+// completeLogin runs the checks that follow a successful authentication and
+// then issues tokens, or a challenge when the user has two-factor enabled.
+func (u *authUseCase) completeLogin(ctx context.Context, user user_repository.User, userAgent, ipAddress string) (LoginResponse, int, error) {
+	if user.EmailVerifiedAt == nil {
+		required, err := u.userRepo.RequiresEmailVerification(ctx, user.Id)
+		if err != nil {
+			return LoginResponse{}, http.StatusInternalServerError, err
 		}
-		if approvalStatus == "rejected" {
-			return LoginResponse{}, http.StatusForbidden, errors.New("pendaftaran Anda telah ditolak, silakan hubungi pengurus RT")
+		if required {
+			return LoginResponse{}, http.StatusForbidden, errors.New("email address has not been verified")
 		}
 	}
 
-	accessToken, err := auth_utils.GenerateToken(auth_utils.TokenParams{
-		UserID: user.Id,
-	}, AccessTokenDuration)
-	if err != nil {
-		return LoginResponse{}, http.StatusInternalServerError, err
+	if !user.IsSuperAdmin {
+		if code, err := u.checkSignUpApproval(ctx, user.Id); err != nil {
+			return LoginResponse{}, code, err
+		}
+	}
+
+	twoFactor, code, err := u.twoFactorRepo.GetTwoFactor(ctx, user.Id)
+	if err != nil && code != http.StatusNotFound {
+		return LoginResponse{}, code, err
+	}
+	if err == nil && twoFactor.ConfirmedAt != nil {
+		return u.twoFactorChallenge(user)
 	}
 
-	refreshToken, err := auth_utils.GenerateToken(auth_utils.TokenParams{
-		UserID: user.Id,
-	}, RefreshTokenDuration)
+	tokens, code, err := u.issueTokens(ctx, user, uuid.New(), userAgent, ipAddress)
 	if err != nil {
-		return LoginResponse{}, http.StatusInternalServerError, err
+		return LoginResponse{}, code, err
 	}
 
 	u.userRepo.UpdateLastLogin(ctx, user_repository.UserFilter{Id: &user.Id})
 
 	return LoginResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresAt:    time.Now().Add(AccessTokenDuration).Unix(),
-		User: UserInfo{
-			Id:           user.Id,
-			Email:        user.Email,
-			FullName:     user.FullName,
-			IsSuperAdmin: user.IsSuperAdmin,
-			IsActive:     user.IsActive,
-			LastLoginAt:  user.LastLoginAt,
-		},
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt,
+		User:         toUserInfo(user),
 	}, http.StatusOK, nil
 }
 
@@ -94,6 +327,16 @@ func (u *authUseCase) Register(ctx context.Context, req RegisterRequest) (UserIn
 		return UserInfo{}, http.StatusConflict, errors.New("email already registered")
 	}
 
+	var signUp unit_repository.Unit
+	var signUpRole schemas.UnitMemberRole
+	if req.UnitCode != "" || req.Role != "" {
+		var err error
+		signUp, signUpRole, code, err = u.signUpUnit(ctx, req)
+		if err != nil {
+			return UserInfo{}, code, err
+		}
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return UserInfo{}, http.StatusInternalServerError, err
@@ -106,26 +349,63 @@ func (u *authUseCase) Register(ctx context.Context, req RegisterRequest) (UserIn
 		IsActive: true,
 	}
 
-	createdUser, code, err := u.userRepo.CreateUser(ctx, newUser)
+	var createdUser user_repository.User
+	if signUp.Id != uuid.Nil {
+		// The school's admins still have to approve the membership
+		createdUser, code, err = u.userRepo.CreateSignUpUser(ctx, newUser, user_repository.SignUp{UnitId: signUp.Id, Role: signUpRole})
+	} else {
+		createdUser, code, err = u.userRepo.CreateUser(ctx, newUser)
+	}
 	if err != nil {
 		return UserInfo{}, code, err
 	}
 
-	return UserInfo{
-		Id:           createdUser.Id,
-		Email:        createdUser.Email,
-		FullName:     createdUser.FullName,
-		IsSuperAdmin: createdUser.IsSuperAdmin,
-		IsActive:     createdUser.IsActive,
-	}, http.StatusCreated, nil
+	if err := u.sendVerificationEmail(ctx, createdUser); err != nil {
+		// The account exists already; the user can ask for a new link
+		log.Errorf("failed to send verification email to user %s: %v", createdUser.Id, err)
+	}
+
+	return toUserInfo(createdUser), http.StatusCreated, nil
 }
 
 func (u *authUseCase) RefreshToken(ctx context.Context, req RefreshTokenRequest) (LoginResponse, int, error) {
 	claims, err := auth_utils.ValidateToken(req.RefreshToken)
+	if err != nil || claims.TokenType != auth_utils.TokenTypeRefresh {
+		return LoginResponse{}, http.StatusUnauthorized, errors.New("invalid refresh token")
+	}
+
+	tokenId, err := uuid.Parse(claims.Id)
+	if err != nil {
+		return LoginResponse{}, http.StatusUnauthorized, errors.New("invalid refresh token")
+	}
+
+	stored, code, err := u.tokenRepo.GetRefreshToken(ctx, token_repository.RefreshTokenFilter{Id: &tokenId})
 	if err != nil {
+		if code == http.StatusNotFound {
+			return LoginResponse{}, http.StatusUnauthorized, errors.New("invalid refresh token")
+		}
+		return LoginResponse{}, code, err
+	}
+
+	if stored.UserId != claims.UserID {
 		return LoginResponse{}, http.StatusUnauthorized, errors.New("invalid refresh token")
 	}
 
+	if stored.RevokedAt != nil {
+		return LoginResponse{}, http.StatusUnauthorized, errors.New("refresh token has been revoked")
+	}
+
+	if code, err := u.tokenRepo.MarkRefreshTokenUsed(ctx, stored.Id); err != nil {
+		if code != http.StatusConflict {
+			return LoginResponse{}, code, err
+		}
+		// A rotated token came back: assume it was stolen and end the whole session
+		if code, err := u.revokeTokens(ctx, token_repository.RefreshTokenFilter{FamilyId: &stored.FamilyId}); err != nil {
+			return LoginResponse{}, code, err
+		}
+		return LoginResponse{}, http.StatusUnauthorized, errors.New("refresh token reuse detected, please log in again")
+	}
+
 	user, code, err := u.userRepo.GetUser(ctx, user_repository.UserFilter{
 		Id: &claims.UserID,
 	})
@@ -137,31 +417,129 @@ func (u *authUseCase) RefreshToken(ctx context.Context, req RefreshTokenRequest)
 		return LoginResponse{}, http.StatusForbidden, errors.New("account is not active")
 	}
 
-	accessToken, err := auth_utils.GenerateToken(auth_utils.TokenParams{
-		UserID: user.Id,
-	}, AccessTokenDuration)
+	tokens, code, err := u.issueTokens(ctx, user, stored.FamilyId, req.UserAgent, req.IpAddress)
 	if err != nil {
-		return LoginResponse{}, http.StatusInternalServerError, err
+		return LoginResponse{}, code, err
+	}
+
+	return LoginResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt,
+		User:         toUserInfo(user),
+	}, http.StatusOK, nil
+}
+
+func (u *authUseCase) Logout(ctx context.Context, claims auth_utils.AuthClaim) (int, error) {
+	if claims.SessionID == uuid.Nil {
+		return u.tokenRepo.RevokeAccessTokens(ctx, currentAccessToken(claims))
 	}
 
-	refreshToken, err := auth_utils.GenerateToken(auth_utils.TokenParams{
-		UserID: user.Id,
-	}, RefreshTokenDuration)
+	code, err := u.revokeTokens(ctx, token_repository.RefreshTokenFilter{
+		UserId:   &claims.UserID,
+		FamilyId: &claims.SessionID,
+	}, currentAccessToken(claims)...)
 	if err != nil {
-		return LoginResponse{}, http.StatusInternalServerError, err
+		return code, err
 	}
 
-	return LoginResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresAt:    time.Now().Add(AccessTokenDuration).Unix(),
-		User: UserInfo{
-			Id:           user.Id,
-			Email:        user.Email,
-			FullName:     user.FullName,
-			IsSuperAdmin: user.IsSuperAdmin,
-			IsActive:     user.IsActive,
-			LastLoginAt:  user.LastLoginAt,
-		},
-	}, http.StatusOK, nil
+	return http.StatusOK, nil
+}
+
+func (u *authUseCase) LogoutAll(ctx context.Context, claims auth_utils.AuthClaim) (int, error) {
+	code, err := u.revokeTokens(ctx, token_repository.RefreshTokenFilter{
+		UserId: &claims.UserID,
+	}, currentAccessToken(claims)...)
+	if err != nil {
+		return code, err
+	}
+
+	return http.StatusOK, nil
+}
+
+func (u *authUseCase) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	id, err := uuid.Parse(jti)
+	if err != nil {
+		// Every access token we issue carries a UUID jti
+		return true, nil
+	}
+	return u.tokenRepo.IsAccessTokenRevoked(ctx, id)
+}
+
+func (u *authUseCase) GetSessions(ctx context.Context, userId, currentSessionId uuid.UUID) ([]Session, int, error) {
+	active := true
+	sessions, code, err := u.tokenRepo.GetSessions(ctx, token_repository.SessionFilter{
+		UserId: &userId,
+		Active: &active,
+	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	result := make([]Session, len(sessions))
+	for i, s := range sessions {
+		result[i] = Session{
+			Id:         s.Id,
+			Device:     s.Device,
+			UserAgent:  s.UserAgent,
+			IpAddress:  s.IpAddress,
+			LastSeenAt: s.LastSeenAt,
+			ExpiresAt:  s.ExpiresAt,
+			CreatedAt:  s.CreatedAt,
+			Current:    s.Id == currentSessionId,
+		}
+	}
+
+	return result, http.StatusOK, nil
+}
+
+func (u *authUseCase) RevokeSession(ctx context.Context, userId, sessionId uuid.UUID) (int, error) {
+	active := true
+	_, code, err := u.tokenRepo.GetSession(ctx, token_repository.SessionFilter{
+		Id:     &sessionId,
+		UserId: &userId,
+		Active: &active,
+	})
+	if err != nil {
+		if code == http.StatusNotFound {
+			return code, errors.New("session not found")
+		}
+		return code, err
+	}
+
+	code, err = u.revokeTokens(ctx, token_repository.RefreshTokenFilter{
+		UserId:   &userId,
+		FamilyId: &sessionId,
+	})
+	if err != nil {
+		return code, err
+	}
+
+	return http.StatusOK, nil
+}
+
+// TouchSession records activity on a session. It is best effort and throttled
+// to SessionTouchInterval per session so authenticated requests stay cheap.
+func (u *authUseCase) TouchSession(ctx context.Context, sessionId uuid.UUID, ipAddress string) {
+	if sessionId == uuid.Nil {
+		return
+	}
+
+	now := time.Now()
+	u.touchMu.Lock()
+	if last, ok := u.lastTouched[sessionId]; ok && now.Sub(last) < SessionTouchInterval {
+		u.touchMu.Unlock()
+		return
+	}
+	u.lastTouched[sessionId] = now
+	if len(u.lastTouched) > maxTrackedSessions {
+		for id, last := range u.lastTouched {
+			if now.Sub(last) >= SessionTouchInterval {
+				delete(u.lastTouched, id)
+			}
+		}
+	}
+	u.touchMu.Unlock()
+
+	u.tokenRepo.TouchSession(ctx, sessionId, truncate(ipAddress, 45), now)
 }
@@ -3,11 +3,25 @@ package auth_use_case
 import (
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
+	"sekolah-madrasah/app/repository/audit_repository"
+	"sekolah-madrasah/app/repository/login_attempt_repository"
+	"sekolah-madrasah/app/repository/token_repository"
+	"sekolah-madrasah/app/repository/two_factor_repository"
+	"sekolah-madrasah/app/repository/unit_repository"
 	"sekolah-madrasah/app/repository/user_repository"
+	"sekolah-madrasah/app/service/mail_service"
+	"sekolah-madrasah/config"
+	"sekolah-madrasah/database/schemas"
+	"sekolah-madrasah/pkg/auth_utils"
 	"sekolah-madrasah/pkg/paginate_utils"
+	"sekolah-madrasah/pkg/totp_utils"
 
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
@@ -15,6 +29,14 @@ import (
 
 type MockUserRepository struct {
 	users []user_repository.User
+	// requireVerification mimics an organization with require_email_verification set
+	requireVerification bool
+	// requireTwoFactor mimics an organization that makes 2FA mandatory for admins
+	requireTwoFactor bool
+	// approvals holds the sign-up review outcome per user, approved when missing
+	approvals map[uuid.UUID]user_repository.ApprovalStatus
+	// signUps holds the memberships requested along with new users, by user
+	signUps map[uuid.UUID]user_repository.SignUp
 }
 
 func NewMockUserRepository() *MockUserRepository {
@@ -47,10 +69,31 @@ func (m *MockUserRepository) CreateUser(ctx context.Context, user user_repositor
 	return user, 201, nil
 }
 
+func (m *MockUserRepository) CreateSignUpUser(ctx context.Context, user user_repository.User, signUp user_repository.SignUp) (user_repository.User, int, error) {
+	created, code, err := m.CreateUser(ctx, user)
+	if err != nil {
+		return created, code, err
+	}
+	if m.signUps == nil {
+		m.signUps = map[uuid.UUID]user_repository.SignUp{}
+	}
+	m.signUps[created.Id] = signUp
+	return created, code, nil
+}
+
 func (m *MockUserRepository) UpdateUser(ctx context.Context, filter user_repository.UserFilter, user user_repository.User) (int, error) {
 	for i, u := range m.users {
 		if filter.Id != nil && u.Id == *filter.Id {
-			m.users[i].FullName = user.FullName
+			if user.FullName != "" {
+				m.users[i].FullName = user.FullName
+			}
+			if user.Password != "" {
+				m.users[i].Password = user.Password
+			}
+			if user.Email != "" {
+				m.users[i].Email = user.Email
+				m.users[i].EmailVerifiedAt = nil
+			}
 			m.users[i].UpdatedAt = time.Now()
 			return 200, nil
 		}
@@ -72,17 +115,410 @@ func (m *MockUserRepository) UpdateLastLogin(ctx context.Context, filter user_re
 	return 200, nil
 }
 
-func (m *MockUserRepository) HasPendingApproval(ctx context.Context, userId uuid.UUID) (bool, error) {
-	return false, nil // Mock always returns not pending
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, filter user_repository.UserFilter, hashedPassword string, mustChange bool) (int, error) {
+	for i, u := range m.users {
+		if filter.Id != nil && u.Id == *filter.Id {
+			m.users[i].Password = hashedPassword
+			m.users[i].MustChangePassword = mustChange
+			return 200, nil
+		}
+	}
+	return 404, errors.New("user not found")
+}
+
+func (m *MockUserRepository) MarkEmailVerified(ctx context.Context, filter user_repository.UserFilter) (int, error) {
+	for i, u := range m.users {
+		if filter.Id != nil && u.Id == *filter.Id && (filter.Email == nil || u.Email == *filter.Email) {
+			now := time.Now()
+			m.users[i].EmailVerifiedAt = &now
+			return 200, nil
+		}
+	}
+	return 404, errors.New("user not found")
+}
+
+func (m *MockUserRepository) RequiresEmailVerification(ctx context.Context, userId uuid.UUID) (bool, error) {
+	return m.requireVerification, nil
+}
+
+func (m *MockUserRepository) RequiresTwoFactor(ctx context.Context, userId uuid.UUID) (bool, error) {
+	return m.requireTwoFactor, nil
+}
+
+func (m *MockUserRepository) IsOrganizationMember(ctx context.Context, userId, organizationId uuid.UUID) (bool, error) {
+	return false, nil
+}
+
+func (m *MockUserRepository) RecordFailedLogin(ctx context.Context, userId uuid.UUID, lockAfter int, lockUntil time.Time) (user_repository.User, int, error) {
+	for i, u := range m.users {
+		if u.Id == userId {
+			now := time.Now()
+			m.users[i].FailedLoginAttempts++
+			m.users[i].LastFailedLoginAt = &now
+			if lockAfter > 0 && m.users[i].FailedLoginAttempts >= lockAfter {
+				m.users[i].LockedUntil = &lockUntil
+			}
+			return m.users[i], 200, nil
+		}
+	}
+	return user_repository.User{}, 404, errors.New("user not found")
+}
+
+func (m *MockUserRepository) ResetFailedLogins(ctx context.Context, filter user_repository.UserFilter) (int, error) {
+	for i, u := range m.users {
+		if filter.Id != nil && u.Id == *filter.Id {
+			m.users[i].FailedLoginAttempts = 0
+			m.users[i].LastFailedLoginAt = nil
+			m.users[i].LockedUntil = nil
+			return 200, nil
+		}
+	}
+	return 404, errors.New("user not found")
+}
+
+func (m *MockUserRepository) GetApprovalStatus(ctx context.Context, userId uuid.UUID) (user_repository.ApprovalStatus, error) {
+	if approval, ok := m.approvals[userId]; ok {
+		return approval, nil
+	}
+	return user_repository.ApprovalStatus{}, nil
+}
+
+type MockTokenRepository struct {
+	refreshTokens map[uuid.UUID]token_repository.RefreshToken
+	revoked       map[uuid.UUID]bool
+	sessions      map[uuid.UUID]token_repository.Session
+	resetTokens   []token_repository.PasswordResetToken
+}
+
+func NewMockTokenRepository() *MockTokenRepository {
+	return &MockTokenRepository{
+		refreshTokens: map[uuid.UUID]token_repository.RefreshToken{},
+		revoked:       map[uuid.UUID]bool{},
+		sessions:      map[uuid.UUID]token_repository.Session{},
+	}
+}
+
+func (m *MockTokenRepository) matches(t token_repository.RefreshToken, filter token_repository.RefreshTokenFilter) bool {
+	if filter.Id != nil && t.Id != *filter.Id {
+		return false
+	}
+	if filter.UserId != nil && t.UserId != *filter.UserId {
+		return false
+	}
+	if filter.FamilyId != nil && t.FamilyId != *filter.FamilyId {
+		return false
+	}
+	if filter.Active != nil {
+		active := t.UsedAt == nil && t.RevokedAt == nil && t.ExpiresAt.After(time.Now())
+		if active != *filter.Active {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *MockTokenRepository) GetRefreshToken(ctx context.Context, filter token_repository.RefreshTokenFilter) (token_repository.RefreshToken, int, error) {
+	for _, t := range m.refreshTokens {
+		if m.matches(t, filter) {
+			return t, 200, nil
+		}
+	}
+	return token_repository.RefreshToken{}, 404, errors.New("record not found")
+}
+
+func (m *MockTokenRepository) GetRefreshTokens(ctx context.Context, filter token_repository.RefreshTokenFilter) ([]token_repository.RefreshToken, int, error) {
+	var result []token_repository.RefreshToken
+	for _, t := range m.refreshTokens {
+		if m.matches(t, filter) {
+			result = append(result, t)
+		}
+	}
+	return result, 200, nil
+}
+
+func (m *MockTokenRepository) CreateRefreshToken(ctx context.Context, token token_repository.RefreshToken) (token_repository.RefreshToken, int, error) {
+	token.CreatedAt = time.Now()
+	m.refreshTokens[token.Id] = token
+	return token, 201, nil
+}
+
+func (m *MockTokenRepository) MarkRefreshTokenUsed(ctx context.Context, id uuid.UUID) (int, error) {
+	t, ok := m.refreshTokens[id]
+	if !ok || t.UsedAt != nil || t.RevokedAt != nil {
+		return 409, errors.New("refresh token already used")
+	}
+	now := time.Now()
+	t.UsedAt = &now
+	m.refreshTokens[id] = t
+	return 200, nil
+}
+
+func (m *MockTokenRepository) RevokeRefreshTokens(ctx context.Context, filter token_repository.RefreshTokenFilter) (int, error) {
+	now := time.Now()
+	for id, t := range m.refreshTokens {
+		if m.matches(t, filter) && t.RevokedAt == nil {
+			t.RevokedAt = &now
+			m.refreshTokens[id] = t
+		}
+	}
+	return 200, nil
+}
+
+func (m *MockTokenRepository) RevokeAccessTokens(ctx context.Context, tokens []token_repository.RevokedAccessToken) (int, error) {
+	for _, t := range tokens {
+		m.revoked[t.Jti] = true
+	}
+	return 200, nil
+}
+
+func (m *MockTokenRepository) IsAccessTokenRevoked(ctx context.Context, jti uuid.UUID) (bool, error) {
+	return m.revoked[jti], nil
+}
+
+func (m *MockTokenRepository) matchesSession(s token_repository.Session, filter token_repository.SessionFilter) bool {
+	if filter.Id != nil && s.Id != *filter.Id {
+		return false
+	}
+	if filter.UserId != nil && s.UserId != *filter.UserId {
+		return false
+	}
+	if filter.Active != nil {
+		active := s.RevokedAt == nil && s.ExpiresAt.After(time.Now())
+		if active != *filter.Active {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *MockTokenRepository) GetSession(ctx context.Context, filter token_repository.SessionFilter) (token_repository.Session, int, error) {
+	for _, s := range m.sessions {
+		if m.matchesSession(s, filter) {
+			return s, 200, nil
+		}
+	}
+	return token_repository.Session{}, 404, errors.New("record not found")
+}
+
+func (m *MockTokenRepository) GetSessions(ctx context.Context, filter token_repository.SessionFilter) ([]token_repository.Session, int, error) {
+	var result []token_repository.Session
+	for _, s := range m.sessions {
+		if m.matchesSession(s, filter) {
+			result = append(result, s)
+		}
+	}
+	return result, 200, nil
+}
+
+func (m *MockTokenRepository) SaveSession(ctx context.Context, session token_repository.Session) (int, error) {
+	if existing, ok := m.sessions[session.Id]; ok {
+		session.CreatedAt = existing.CreatedAt
+	} else {
+		session.CreatedAt = time.Now()
+	}
+	m.sessions[session.Id] = session
+	return 200, nil
+}
+
+func (m *MockTokenRepository) TouchSession(ctx context.Context, id uuid.UUID, ipAddress string, seenAt time.Time) (int, error) {
+	if s, ok := m.sessions[id]; ok {
+		s.LastSeenAt = seenAt
+		m.sessions[id] = s
+	}
+	return 200, nil
+}
+
+func (m *MockTokenRepository) RevokeSessions(ctx context.Context, filter token_repository.SessionFilter) (int, error) {
+	now := time.Now()
+	for id, s := range m.sessions {
+		if m.matchesSession(s, filter) && s.RevokedAt == nil {
+			s.RevokedAt = &now
+			m.sessions[id] = s
+		}
+	}
+	return 200, nil
+}
+
+func (m *MockTokenRepository) CreatePasswordResetToken(ctx context.Context, token token_repository.PasswordResetToken) (int, error) {
+	m.resetTokens = append(m.resetTokens, token)
+	return 201, nil
+}
+
+func (m *MockTokenRepository) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (token_repository.PasswordResetToken, int, error) {
+	now := time.Now()
+	for i, t := range m.resetTokens {
+		if t.TokenHash != tokenHash || t.UsedAt != nil || !t.ExpiresAt.After(now) {
+			continue
+		}
+		for j := range m.resetTokens {
+			if m.resetTokens[j].UserId == t.UserId && m.resetTokens[j].UsedAt == nil {
+				m.resetTokens[j].UsedAt = &now
+			}
+		}
+		return m.resetTokens[i], 200, nil
+	}
+	return token_repository.PasswordResetToken{}, 400, errors.New("invalid or expired reset token")
+}
+
+type MockTwoFactorRepository struct {
+	twoFactors    map[uuid.UUID]two_factor_repository.TwoFactor
+	recoveryCodes map[uuid.UUID]map[string]bool
+}
+
+func NewMockTwoFactorRepository() *MockTwoFactorRepository {
+	return &MockTwoFactorRepository{
+		twoFactors:    map[uuid.UUID]two_factor_repository.TwoFactor{},
+		recoveryCodes: map[uuid.UUID]map[string]bool{},
+	}
+}
+
+func (m *MockTwoFactorRepository) GetTwoFactor(ctx context.Context, userId uuid.UUID) (two_factor_repository.TwoFactor, int, error) {
+	if tf, ok := m.twoFactors[userId]; ok {
+		return tf, 200, nil
+	}
+	return two_factor_repository.TwoFactor{}, 404, errors.New("record not found")
+}
+
+func (m *MockTwoFactorRepository) SavePendingTwoFactor(ctx context.Context, userId uuid.UUID, secret string) (int, error) {
+	if tf, ok := m.twoFactors[userId]; ok && tf.ConfirmedAt != nil {
+		return 409, errors.New("two-factor authentication is already enabled")
+	}
+	m.twoFactors[userId] = two_factor_repository.TwoFactor{UserId: userId, Secret: secret}
+	return 200, nil
+}
+
+func (m *MockTwoFactorRepository) ConfirmTwoFactor(ctx context.Context, userId uuid.UUID, step int64, codeHashes []string) (int, error) {
+	tf, ok := m.twoFactors[userId]
+	if !ok || tf.ConfirmedAt != nil {
+		return 409, errors.New("no pending two-factor enrollment")
+	}
+	now := time.Now()
+	tf.ConfirmedAt = &now
+	tf.LastUsedStep = step
+	m.twoFactors[userId] = tf
+	return m.ReplaceRecoveryCodes(ctx, userId, codeHashes)
+}
+
+func (m *MockTwoFactorRepository) UseStep(ctx context.Context, userId uuid.UUID, step int64) (int, error) {
+	tf, ok := m.twoFactors[userId]
+	if !ok || tf.ConfirmedAt == nil || tf.LastUsedStep >= step {
+		return 409, errors.New("verification code was already used")
+	}
+	tf.LastUsedStep = step
+	m.twoFactors[userId] = tf
+	return 200, nil
+}
+
+func (m *MockTwoFactorRepository) UseRecoveryCode(ctx context.Context, userId uuid.UUID, codeHash string) (int, error) {
+	if !m.recoveryCodes[userId][codeHash] {
+		return 400, errors.New("invalid recovery code")
+	}
+	m.recoveryCodes[userId][codeHash] = false
+	return 200, nil
+}
+
+func (m *MockTwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userId uuid.UUID, codeHashes []string) (int, error) {
+	m.recoveryCodes[userId] = map[string]bool{}
+	for _, hash := range codeHashes {
+		m.recoveryCodes[userId][hash] = true
+	}
+	return 200, nil
+}
+
+func (m *MockTwoFactorRepository) CountRecoveryCodes(ctx context.Context, userId uuid.UUID) (int64, int, error) {
+	var count int64
+	for _, unused := range m.recoveryCodes[userId] {
+		if unused {
+			count++
+		}
+	}
+	return count, 200, nil
+}
+
+func (m *MockTwoFactorRepository) DeleteTwoFactor(ctx context.Context, userId uuid.UUID) (int, error) {
+	delete(m.twoFactors, userId)
+	delete(m.recoveryCodes, userId)
+	return 200, nil
+}
+
+type MockLoginAttemptRepository struct {
+	attempts []login_attempt_repository.LoginAttempt
+}
+
+func NewMockLoginAttemptRepository() *MockLoginAttemptRepository {
+	return &MockLoginAttemptRepository{}
+}
+
+func (m *MockLoginAttemptRepository) CreateLoginAttempt(ctx context.Context, attempt login_attempt_repository.LoginAttempt) (int, error) {
+	attempt.Id = uuid.New()
+	attempt.CreatedAt = time.Now()
+	m.attempts = append(m.attempts, attempt)
+	return 201, nil
+}
+
+func (m *MockLoginAttemptRepository) GetLoginAttemptStats(ctx context.Context, filter login_attempt_repository.LoginAttemptFilter) (login_attempt_repository.LoginAttemptStats, int, error) {
+	var stats login_attempt_repository.LoginAttemptStats
+	for _, a := range m.attempts {
+		if filter.Email != nil && a.Email != *filter.Email ||
+			filter.IpAddress != nil && a.IpAddress != *filter.IpAddress ||
+			filter.Success != nil && a.Success != *filter.Success ||
+			filter.Since != nil && a.CreatedAt.Before(*filter.Since) {
+			continue
+		}
+		createdAt := a.CreatedAt
+		if stats.First == nil {
+			stats.First = &createdAt
+		}
+		stats.Last = &createdAt
+		stats.Count++
+	}
+	return stats, 200, nil
+}
+
+type MockAuditRepository struct {
+	logs []audit_repository.AuditLog
+}
+
+func NewMockAuditRepository() *MockAuditRepository {
+	return &MockAuditRepository{}
+}
+
+func (m *MockAuditRepository) CreateAuditLog(ctx context.Context, log audit_repository.AuditLog) (int, error) {
+	log.Id = uuid.New()
+	log.CreatedAt = time.Now()
+	m.logs = append(m.logs, log)
+	return 201, nil
 }
 
-func (m *MockUserRepository) GetApprovalStatus(ctx context.Context, userId uuid.UUID) (string, error) {
-	return "approved", nil // Mock always returns approved
+func (m *MockAuditRepository) GetAuditLogs(ctx context.Context, filter audit_repository.AuditLogFilter, paginate *paginate_utils.PaginateData) ([]audit_repository.AuditLog, int, error) {
+	return m.logs, 200, nil
+}
+
+type MockUnitRepository struct {
+	unit_repository.UnitRepository
+	units []unit_repository.Unit
+}
+
+func NewMockUnitRepository() *MockUnitRepository {
+	return &MockUnitRepository{}
+}
+
+func (m *MockUnitRepository) GetUnit(ctx context.Context, filter unit_repository.UnitFilter) (unit_repository.Unit, int, error) {
+	for _, unit := range m.units {
+		if filter.Code != nil && unit.Code == *filter.Code && (filter.IsActive == nil || unit.IsActive == *filter.IsActive) {
+			return unit, 200, nil
+		}
+	}
+	return unit_repository.Unit{}, 404, errors.New("unit not found")
+}
+
+func newTestMailer(t *testing.T) mail_service.Mailer {
+	return mail_service.NewFileMailer(t.TempDir(), "no-reply@example.com")
 }
 
 func TestAuthUseCase_Register(t *testing.T) {
 	mockRepo := NewMockUserRepository()
-	useCase := NewAuthUseCase(mockRepo)
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), newTestMailer(t))
 	ctx := context.Background()
 
 	req := RegisterRequest{
@@ -115,7 +551,7 @@ func TestAuthUseCase_Register(t *testing.T) {
 
 func TestAuthUseCase_RegisterDuplicateEmail(t *testing.T) {
 	mockRepo := NewMockUserRepository()
-	useCase := NewAuthUseCase(mockRepo)
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), newTestMailer(t))
 	ctx := context.Background()
 
 	req := RegisterRequest{
@@ -136,9 +572,111 @@ func TestAuthUseCase_RegisterDuplicateEmail(t *testing.T) {
 	}
 }
 
+func TestAuthUseCase_RegisterWithUnitCode(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	unitRepo := NewMockUnitRepository()
+	unit := unit_repository.Unit{Id: uuid.New(), Code: "MI-01", IsActive: true}
+	unitRepo.units = append(unitRepo.units, unit)
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), unitRepo, newTestMailer(t))
+	ctx := context.Background()
+
+	userInfo, code, err := useCase.Register(ctx, RegisterRequest{
+		Email:    "teacher@example.com",
+		Password: "password123",
+		FullName: "Teacher",
+		UnitCode: "MI-01",
+		Role:     "teacher",
+	})
+	if err != nil || code != 201 {
+		t.Fatalf("Expected 201, got %d: %v", code, err)
+	}
+
+	signUp, ok := mockRepo.signUps[userInfo.Id]
+	if !ok {
+		t.Fatal("Expected the user to be created together with a membership request")
+	}
+	if signUp.UnitId != unit.Id {
+		t.Error("Expected the membership to link the new user to the unit")
+	}
+	if signUp.Role != schemas.UnitMemberRoleStaff {
+		t.Errorf("Expected teacher to map to staff, got %s", signUp.Role)
+	}
+}
+
+func TestAuthUseCase_RegisterWithUnitCodeRefusals(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	unitRepo := NewMockUnitRepository()
+	unitRepo.units = append(unitRepo.units,
+		unit_repository.Unit{Id: uuid.New(), Code: "MI-01", IsActive: true},
+		unit_repository.Unit{Id: uuid.New(), Code: "MI-02", IsActive: false},
+	)
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), unitRepo, newTestMailer(t))
+	ctx := context.Background()
+
+	cases := map[string]RegisterRequest{
+		"unknown role":  {UnitCode: "MI-01", Role: "owner"},
+		"unknown code":  {UnitCode: "MI-99", Role: "parent"},
+		"missing code":  {Role: "student"},
+		"inactive unit": {UnitCode: "MI-02", Role: "parent"},
+	}
+	for name, req := range cases {
+		req.Email = strings.ReplaceAll(name, " ", "-") + "@example.com"
+		req.Password = "password123"
+		req.FullName = "Someone"
+
+		_, code, err := useCase.Register(ctx, req)
+		if code != 400 || err == nil {
+			t.Errorf("%s: expected 400, got %d", name, code)
+		}
+	}
+
+	if len(mockRepo.users) != 0 {
+		t.Errorf("Expected no user to be created, got %d", len(mockRepo.users))
+	}
+	if len(mockRepo.signUps) != 0 {
+		t.Errorf("Expected no membership to be created, got %d", len(mockRepo.signUps))
+	}
+}
+
+func TestAuthUseCase_LoginAwaitingApproval(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), newTestMailer(t))
+	ctx := context.Background()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	userId := uuid.New()
+	mockRepo.users = append(mockRepo.users, user_repository.User{
+		Id:       userId,
+		Email:    "parent@example.com",
+		Password: string(hashedPassword),
+		IsActive: true,
+	})
+	req := LoginRequest{Email: "parent@example.com", Password: "password123"}
+
+	mockRepo.approvals = map[uuid.UUID]user_repository.ApprovalStatus{
+		userId: {Status: schemas.UnitMemberApprovalPending},
+	}
+	_, code, err := useCase.Login(ctx, req)
+	if code != 403 || err == nil || !strings.Contains(err.Error(), "waiting for approval") {
+		t.Errorf("Expected 403 while pending, got %d: %v", code, err)
+	}
+
+	mockRepo.approvals[userId] = user_repository.ApprovalStatus{Status: schemas.UnitMemberApprovalRejected, RejectionReason: "unknown student"}
+	_, code, err = useCase.Login(ctx, req)
+	if code != 403 || err == nil || !strings.Contains(err.Error(), "unknown student") {
+		t.Errorf("Expected 403 with the rejection reason, got %d: %v", code, err)
+	}
+
+	mockRepo.approvals[userId] = user_repository.ApprovalStatus{Status: schemas.UnitMemberApprovalApproved}
+	_, code, err = useCase.Login(ctx, req)
+	if code != 200 || err != nil {
+		t.Errorf("Expected 200 once approved, got %d: %v", code, err)
+	}
+}
+
 func TestAuthUseCase_Login(t *testing.T) {
 	mockRepo := NewMockUserRepository()
-	useCase := NewAuthUseCase(mockRepo)
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), newTestMailer(t))
 	ctx := context.Background()
 
 	password := "password123"
@@ -181,7 +719,7 @@ func TestAuthUseCase_Login(t *testing.T) {
 
 func TestAuthUseCase_LoginWrongPassword(t *testing.T) {
 	mockRepo := NewMockUserRepository()
-	useCase := NewAuthUseCase(mockRepo)
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), newTestMailer(t))
 	ctx := context.Background()
 
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("correctpassword"), bcrypt.DefaultCost)
@@ -212,7 +750,7 @@ func TestAuthUseCase_LoginWrongPassword(t *testing.T) {
 
 func TestAuthUseCase_LoginUserNotFound(t *testing.T) {
 	mockRepo := NewMockUserRepository()
-	useCase := NewAuthUseCase(mockRepo)
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), newTestMailer(t))
 	ctx := context.Background()
 
 	req := LoginRequest{
@@ -234,7 +772,7 @@ func TestAuthUseCase_LoginUserNotFound(t *testing.T) {
 
 func TestAuthUseCase_LoginInactiveUser(t *testing.T) {
 	mockRepo := NewMockUserRepository()
-	useCase := NewAuthUseCase(mockRepo)
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), newTestMailer(t))
 	ctx := context.Background()
 
 	password := "password123"
@@ -263,3 +801,753 @@ func TestAuthUseCase_LoginInactiveUser(t *testing.T) {
 		t.Error("Expected error for inactive user")
 	}
 }
+
+func loginTestUser(t *testing.T, useCase AuthUseCase, mockRepo *MockUserRepository) LoginResponse {
+	t.Helper()
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	mockRepo.users = append(mockRepo.users, user_repository.User{
+		Id:       uuid.New(),
+		Email:    "session@example.com",
+		Password: string(hashedPassword),
+		FullName: "Session User",
+		IsActive: true,
+	})
+
+	resp, _, err := useCase.Login(context.Background(), LoginRequest{
+		Email:    "session@example.com",
+		Password: "password123",
+	})
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	return resp
+}
+
+func accessClaims(t *testing.T, token string) auth_utils.AuthClaim {
+	t.Helper()
+	claims, err := auth_utils.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("invalid access token: %v", err)
+	}
+	return *claims
+}
+
+func TestAuthUseCase_RefreshTokenRotates(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	tokenRepo := NewMockTokenRepository()
+	useCase := NewAuthUseCase(mockRepo, tokenRepo, NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), newTestMailer(t))
+	ctx := context.Background()
+
+	login := loginTestUser(t, useCase, mockRepo)
+
+	refreshed, code, err := useCase.RefreshToken(ctx, RefreshTokenRequest{RefreshToken: login.RefreshToken})
+	if err != nil || code != 200 {
+		t.Fatalf("Expected refresh to succeed, got %d: %v", code, err)
+	}
+
+	if refreshed.RefreshToken == login.RefreshToken {
+		t.Error("Expected a new refresh token after rotation")
+	}
+
+	if accessClaims(t, refreshed.AccessToken).SessionID != accessClaims(t, login.AccessToken).SessionID {
+		t.Error("Expected rotated tokens to stay in the same session")
+	}
+}
+
+func TestAuthUseCase_RefreshTokenRejectsAccessToken(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), newTestMailer(t))
+
+	login := loginTestUser(t, useCase, mockRepo)
+
+	_, code, err := useCase.RefreshToken(context.Background(), RefreshTokenRequest{RefreshToken: login.AccessToken})
+	if err == nil || code != 401 {
+		t.Errorf("Expected 401 when refreshing with an access token, got %d", code)
+	}
+}
+
+func TestAuthUseCase_RefreshTokenReuseRevokesFamily(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	tokenRepo := NewMockTokenRepository()
+	useCase := NewAuthUseCase(mockRepo, tokenRepo, NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), newTestMailer(t))
+	ctx := context.Background()
+
+	login := loginTestUser(t, useCase, mockRepo)
+
+	refreshed, _, err := useCase.RefreshToken(ctx, RefreshTokenRequest{RefreshToken: login.RefreshToken})
+	if err != nil {
+		t.Fatalf("Expected first refresh to succeed: %v", err)
+	}
+
+	_, code, err := useCase.RefreshToken(ctx, RefreshTokenRequest{RefreshToken: login.RefreshToken})
+	if err == nil || code != 401 {
+		t.Fatalf("Expected 401 on refresh token reuse, got %d", code)
+	}
+
+	if _, code, _ := useCase.RefreshToken(ctx, RefreshTokenRequest{RefreshToken: refreshed.RefreshToken}); code != 401 {
+		t.Errorf("Expected the newest refresh token to be revoked after reuse, got %d", code)
+	}
+
+	revoked, _ := useCase.IsAccessTokenRevoked(ctx, accessClaims(t, refreshed.AccessToken).Id)
+	if !revoked {
+		t.Error("Expected access tokens of the family to be denylisted after reuse")
+	}
+}
+
+func TestAuthUseCase_Logout(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), newTestMailer(t))
+	ctx := context.Background()
+
+	first := loginTestUser(t, useCase, mockRepo)
+	second, _, err := useCase.Login(ctx, LoginRequest{Email: "session@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("second login failed: %v", err)
+	}
+
+	if code, err := useCase.Logout(ctx, accessClaims(t, first.AccessToken)); err != nil || code != 200 {
+		t.Fatalf("Expected logout to succeed, got %d: %v", code, err)
+	}
+
+	if revoked, _ := useCase.IsAccessTokenRevoked(ctx, accessClaims(t, first.AccessToken).Id); !revoked {
+		t.Error("Expected the logged out access token to be denylisted")
+	}
+	if _, code, _ := useCase.RefreshToken(ctx, RefreshTokenRequest{RefreshToken: first.RefreshToken}); code != 401 {
+		t.Errorf("Expected the logged out refresh token to be rejected, got %d", code)
+	}
+	if revoked, _ := useCase.IsAccessTokenRevoked(ctx, accessClaims(t, second.AccessToken).Id); revoked {
+		t.Error("Expected other sessions to stay signed in")
+	}
+}
+
+func TestAuthUseCase_LogoutAll(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), newTestMailer(t))
+	ctx := context.Background()
+
+	first := loginTestUser(t, useCase, mockRepo)
+	second, _, err := useCase.Login(ctx, LoginRequest{Email: "session@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("second login failed: %v", err)
+	}
+
+	if code, err := useCase.LogoutAll(ctx, accessClaims(t, first.AccessToken)); err != nil || code != 200 {
+		t.Fatalf("Expected logout-all to succeed, got %d: %v", code, err)
+	}
+
+	if revoked, _ := useCase.IsAccessTokenRevoked(ctx, accessClaims(t, second.AccessToken).Id); !revoked {
+		t.Error("Expected every session's access token to be denylisted")
+	}
+	if _, code, _ := useCase.RefreshToken(ctx, RefreshTokenRequest{RefreshToken: second.RefreshToken}); code != 401 {
+		t.Errorf("Expected every refresh token to be revoked, got %d", code)
+	}
+}
+
+func TestAuthUseCase_SessionsListAndRevoke(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), newTestMailer(t))
+	ctx := context.Background()
+
+	first := loginTestUser(t, useCase, mockRepo)
+	second, _, err := useCase.Login(ctx, LoginRequest{
+		Email:     "session@example.com",
+		Password:  "password123",
+		UserAgent: "Mozilla/5.0 (Linux; Android 14) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36",
+		IpAddress: "10.0.0.2",
+	})
+	if err != nil {
+		t.Fatalf("second login failed: %v", err)
+	}
+
+	current := accessClaims(t, first.AccessToken)
+	sessions, _, err := useCase.GetSessions(ctx, current.UserID, current.SessionID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions, got %d", len(sessions))
+	}
+
+	other := accessClaims(t, second.AccessToken)
+	for _, s := range sessions {
+		if s.Current != (s.Id == current.SessionID) {
+			t.Errorf("Expected only the caller's session to be marked current")
+		}
+		if s.Id == other.SessionID && s.Device != "Chrome on Android" {
+			t.Errorf("Expected device Chrome on Android, got %q", s.Device)
+		}
+	}
+
+	if code, err := useCase.RevokeSession(ctx, current.UserID, other.SessionID); err != nil || code != 200 {
+		t.Fatalf("Expected revoke to succeed, got %d: %v", code, err)
+	}
+
+	if revoked, _ := useCase.IsAccessTokenRevoked(ctx, other.Id); !revoked {
+		t.Error("Expected the revoked session's access token to be denylisted")
+	}
+
+	sessions, _, _ = useCase.GetSessions(ctx, current.UserID, current.SessionID)
+	if len(sessions) != 1 {
+		t.Errorf("Expected 1 remaining session, got %d", len(sessions))
+	}
+
+	if code, _ := useCase.RevokeSession(ctx, uuid.New(), current.SessionID); code != 404 {
+		t.Errorf("Expected 404 when revoking another user's session, got %d", code)
+	}
+}
+
+func TestAuthUseCase_ForgotAndResetPassword(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	tokenRepo := NewMockTokenRepository()
+	mailDir := t.TempDir()
+	useCase := NewAuthUseCase(mockRepo, tokenRepo, NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), mail_service.NewFileMailer(mailDir, "no-reply@example.com"))
+	ctx := context.Background()
+
+	login := loginTestUser(t, useCase, mockRepo)
+
+	if code, err := useCase.ForgotPassword(ctx, ForgotPasswordRequest{Email: "session@example.com"}); err != nil || code != 200 {
+		t.Fatalf("Expected forgot-password to succeed, got %d: %v", code, err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(mailDir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("Expected one reset email, got %d", len(files))
+	}
+	content, _ := os.ReadFile(files[0])
+	match := regexp.MustCompile(`token=([A-Za-z0-9_-]+)`).FindStringSubmatch(string(content))
+	if match == nil {
+		t.Fatal("Expected the email to contain a reset link")
+	}
+	token := match[1]
+
+	if tokenRepo.resetTokens[0].TokenHash == token {
+		t.Error("Expected the reset token to be stored hashed")
+	}
+
+	if code, err := useCase.ResetPassword(ctx, ResetPasswordRequest{Token: token, NewPassword: "n3w-Passw0rd!"}); err != nil || code != 200 {
+		t.Fatalf("Expected reset to succeed, got %d: %v", code, err)
+	}
+
+	if _, code, _ := useCase.Login(ctx, LoginRequest{Email: "session@example.com", Password: "n3w-Passw0rd!"}); code != 200 {
+		t.Errorf("Expected login with the new password, got %d", code)
+	}
+
+	if revoked, _ := useCase.IsAccessTokenRevoked(ctx, accessClaims(t, login.AccessToken).Id); !revoked {
+		t.Error("Expected existing sessions to be revoked after a reset")
+	}
+
+	if code, _ := useCase.ResetPassword(ctx, ResetPasswordRequest{Token: token, NewPassword: "another-Passw0rd!"}); code != 400 {
+		t.Errorf("Expected a used reset token to be rejected, got %d", code)
+	}
+}
+
+func TestAuthUseCase_ForgotPasswordUnknownEmail(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	tokenRepo := NewMockTokenRepository()
+	useCase := NewAuthUseCase(mockRepo, tokenRepo, NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), newTestMailer(t))
+
+	code, err := useCase.ForgotPassword(context.Background(), ForgotPasswordRequest{Email: "nobody@example.com"})
+	if err != nil || code != 200 {
+		t.Errorf("Expected unknown emails to look like a success, got %d: %v", code, err)
+	}
+	if len(tokenRepo.resetTokens) != 0 {
+		t.Error("Expected no reset token for an unknown email")
+	}
+}
+
+func TestAuthUseCase_LoginReportsMustChangePassword(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), newTestMailer(t))
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	mockRepo.users = append(mockRepo.users, user_repository.User{
+		Id:                 uuid.New(),
+		Email:              "generated@example.com",
+		Password:           string(hashedPassword),
+		FullName:           "Generated User",
+		IsActive:           true,
+		MustChangePassword: true,
+	})
+
+	resp, _, err := useCase.Login(context.Background(), LoginRequest{Email: "generated@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if !resp.User.MustChangePassword {
+		t.Error("Expected login to report must_change_password")
+	}
+	if !accessClaims(t, resp.AccessToken).MustChangePassword {
+		t.Error("Expected the access token to carry the must-change-password flag")
+	}
+}
+
+func TestAuthUseCase_ChangePassword(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), newTestMailer(t))
+	ctx := context.Background()
+
+	login := loginTestUser(t, useCase, mockRepo)
+	mockRepo.users[0].MustChangePassword = true
+	claims := accessClaims(t, login.AccessToken)
+
+	if _, code, _ := useCase.ChangePassword(ctx, claims, ChangePasswordRequest{OldPassword: "wrong", NewPassword: "n3w-Passw0rd!"}); code != 400 {
+		t.Errorf("Expected 400 for a wrong current password, got %d", code)
+	}
+
+	if _, code, _ := useCase.ChangePassword(ctx, claims, ChangePasswordRequest{OldPassword: "password123", NewPassword: "password"}); code != 400 {
+		t.Errorf("Expected 400 for a weak new password, got %d", code)
+	}
+
+	resp, code, err := useCase.ChangePassword(ctx, claims, ChangePasswordRequest{OldPassword: "password123", NewPassword: "n3w-Passw0rd!"})
+	if err != nil || code != 200 {
+		t.Fatalf("Expected change to succeed, got %d: %v", code, err)
+	}
+
+	if mockRepo.users[0].MustChangePassword || resp.User.MustChangePassword {
+		t.Error("Expected the must-change-password flag to be cleared")
+	}
+	if accessClaims(t, resp.AccessToken).MustChangePassword {
+		t.Error("Expected the new access token to be unrestricted")
+	}
+
+	if revoked, _ := useCase.IsAccessTokenRevoked(ctx, claims.Id); !revoked {
+		t.Error("Expected the old access token to be revoked")
+	}
+	if _, code, _ := useCase.RefreshToken(ctx, RefreshTokenRequest{RefreshToken: login.RefreshToken}); code != 401 {
+		t.Errorf("Expected the old refresh token to be revoked, got %d", code)
+	}
+}
+
+func verificationTokenFromMail(t *testing.T, mailDir string) string {
+	t.Helper()
+	files, _ := filepath.Glob(filepath.Join(mailDir, "*.eml"))
+	for _, file := range files {
+		content, _ := os.ReadFile(file)
+		if match := regexp.MustCompile(`verify-email\?token=([A-Za-z0-9_.-]+)`).FindStringSubmatch(string(content)); match != nil {
+			return match[1]
+		}
+	}
+	t.Fatal("Expected a verification email")
+	return ""
+}
+
+func TestAuthUseCase_RegisterAndVerifyEmail(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	mockRepo.requireVerification = true
+	mailDir := t.TempDir()
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), mail_service.NewFileMailer(mailDir, "no-reply@example.com"))
+	ctx := context.Background()
+
+	if _, _, err := useCase.Register(ctx, RegisterRequest{Email: "new@example.com", Password: "password123", FullName: "New User"}); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	if _, code, _ := useCase.Login(ctx, LoginRequest{Email: "new@example.com", Password: "password123"}); code != 403 {
+		t.Errorf("Expected unverified login to be refused, got %d", code)
+	}
+
+	token := verificationTokenFromMail(t, mailDir)
+	if code, err := useCase.VerifyEmail(ctx, VerifyEmailRequest{Token: token}); err != nil || code != 200 {
+		t.Fatalf("Expected verification to succeed, got %d: %v", code, err)
+	}
+
+	resp, code, err := useCase.Login(ctx, LoginRequest{Email: "new@example.com", Password: "password123"})
+	if err != nil || code != 200 {
+		t.Fatalf("Expected verified login to succeed, got %d: %v", code, err)
+	}
+	if resp.User.EmailVerifiedAt == nil {
+		t.Error("Expected login to report the verification time")
+	}
+}
+
+func TestAuthUseCase_LoginUnverifiedAllowedWithoutSetting(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), newTestMailer(t))
+
+	loginTestUser(t, useCase, mockRepo)
+}
+
+func TestAuthUseCase_VerifyEmailRejectsStaleAndForeignTokens(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	mailDir := t.TempDir()
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), mail_service.NewFileMailer(mailDir, "no-reply@example.com"))
+	ctx := context.Background()
+
+	login := loginTestUser(t, useCase, mockRepo)
+	if code, _ := useCase.VerifyEmail(ctx, VerifyEmailRequest{Token: login.AccessToken}); code != 400 {
+		t.Errorf("Expected an access token to be rejected, got %d", code)
+	}
+
+	if code, _ := useCase.ResendEmailVerification(ctx, ResendEmailVerificationRequest{Email: "session@example.com"}); code != 200 {
+		t.Fatalf("Expected resend to succeed, got %d", code)
+	}
+	token := verificationTokenFromMail(t, mailDir)
+
+	newEmail := "changed@example.com"
+	mockRepo.UpdateUser(ctx, user_repository.UserFilter{Id: &mockRepo.users[0].Id}, user_repository.User{Email: newEmail})
+
+	if code, _ := useCase.VerifyEmail(ctx, VerifyEmailRequest{Token: token}); code != 400 {
+		t.Errorf("Expected a link for the previous address to be rejected, got %d", code)
+	}
+	if mockRepo.users[0].EmailVerifiedAt != nil {
+		t.Error("Expected the new address to stay unverified")
+	}
+}
+
+func TestAuthUseCase_ResendEmailVerificationUnknownEmail(t *testing.T) {
+	mailDir := t.TempDir()
+	useCase := NewAuthUseCase(NewMockUserRepository(), NewMockTokenRepository(), NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), mail_service.NewFileMailer(mailDir, "no-reply@example.com"))
+
+	code, err := useCase.ResendEmailVerification(context.Background(), ResendEmailVerificationRequest{Email: "nobody@example.com"})
+	if err != nil || code != 200 {
+		t.Errorf("Expected unknown emails to look like a success, got %d: %v", code, err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(mailDir, "*.eml")); len(files) != 0 {
+		t.Error("Expected no email for an unknown address")
+	}
+}
+
+func withAesKey(t *testing.T) {
+	previous := config.APP.Security.AesKey
+	config.APP.Security.AesKey = "test-aes-key"
+	t.Cleanup(func() { config.APP.Security.AesKey = previous })
+}
+
+func currentTOTP(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+	code, err := totp_utils.CodeAt(secret, totp_utils.Step(time.Now())+offset)
+	if err != nil {
+		t.Fatalf("failed to compute TOTP: %v", err)
+	}
+	return code
+}
+
+func TestAuthUseCase_TwoFactorEnrollmentAndLogin(t *testing.T) {
+	withAesKey(t)
+	mockRepo := NewMockUserRepository()
+	twoFactorRepo := NewMockTwoFactorRepository()
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), twoFactorRepo, NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), newTestMailer(t))
+	ctx := context.Background()
+
+	login := loginTestUser(t, useCase, mockRepo)
+	userId := accessClaims(t, login.AccessToken).UserID
+
+	setup, code, err := useCase.SetupTwoFactor(ctx, userId)
+	if err != nil || code != 200 {
+		t.Fatalf("Expected setup to succeed, got %d: %v", code, err)
+	}
+	if twoFactorRepo.twoFactors[userId].Secret == setup.Secret {
+		t.Error("Expected the TOTP secret to be stored encrypted")
+	}
+
+	if _, code, _ := useCase.EnableTwoFactor(ctx, userId, "000000"); code != 400 {
+		t.Errorf("Expected a wrong code to be rejected, got %d", code)
+	}
+
+	recoveryCodes, code, err := useCase.EnableTwoFactor(ctx, userId, currentTOTP(t, setup.Secret, -1))
+	if err != nil || code != 200 {
+		t.Fatalf("Expected enable to succeed, got %d: %v", code, err)
+	}
+	if len(recoveryCodes) != RecoveryCodeCount {
+		t.Fatalf("Expected %d recovery codes, got %d", RecoveryCodeCount, len(recoveryCodes))
+	}
+
+	challenge, code, err := useCase.Login(ctx, LoginRequest{Email: "session@example.com", Password: "password123"})
+	if err != nil || code != 200 {
+		t.Fatalf("Expected password step to succeed, got %d: %v", code, err)
+	}
+	if !challenge.TwoFactorRequired || challenge.AccessToken != "" || challenge.ChallengeToken == "" {
+		t.Fatal("Expected a challenge instead of tokens")
+	}
+
+	if _, code, _ := useCase.VerifyTwoFactor(ctx, VerifyTwoFactorRequest{ChallengeToken: challenge.ChallengeToken, Code: currentTOTP(t, setup.Secret, -1)}); code != 401 {
+		t.Errorf("Expected the code used during enrollment to be refused, got %d", code)
+	}
+
+	resp, code, err := useCase.VerifyTwoFactor(ctx, VerifyTwoFactorRequest{ChallengeToken: challenge.ChallengeToken, Code: currentTOTP(t, setup.Secret, 0)})
+	if err != nil || code != 200 || resp.AccessToken == "" {
+		t.Fatalf("Expected verification to issue tokens, got %d: %v", code, err)
+	}
+
+	if _, code, _ := useCase.VerifyTwoFactor(ctx, VerifyTwoFactorRequest{ChallengeToken: challenge.ChallengeToken, Code: currentTOTP(t, setup.Secret, 0)}); code != 401 {
+		t.Errorf("Expected a replayed code to be refused, got %d", code)
+	}
+
+	if _, code, err := useCase.VerifyTwoFactor(ctx, VerifyTwoFactorRequest{ChallengeToken: challenge.ChallengeToken, RecoveryCode: strings.ToUpper(recoveryCodes[0])}); err != nil || code != 200 {
+		t.Errorf("Expected a recovery code to work, got %d: %v", code, err)
+	}
+	if _, code, _ := useCase.VerifyTwoFactor(ctx, VerifyTwoFactorRequest{ChallengeToken: challenge.ChallengeToken, RecoveryCode: recoveryCodes[0]}); code != 401 {
+		t.Errorf("Expected a used recovery code to be refused, got %d", code)
+	}
+
+	if _, code, _ := useCase.VerifyTwoFactor(ctx, VerifyTwoFactorRequest{ChallengeToken: resp.AccessToken, Code: currentTOTP(t, setup.Secret, 1)}); code != 401 {
+		t.Errorf("Expected an access token to be refused as a challenge, got %d", code)
+	}
+}
+
+func TestAuthUseCase_MandatoryTwoFactorRestrictsTokens(t *testing.T) {
+	withAesKey(t)
+	mockRepo := NewMockUserRepository()
+	mockRepo.requireTwoFactor = true
+	twoFactorRepo := NewMockTwoFactorRepository()
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), twoFactorRepo, NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), newTestMailer(t))
+	ctx := context.Background()
+
+	login := loginTestUser(t, useCase, mockRepo)
+	claims := accessClaims(t, login.AccessToken)
+	if !claims.TwoFactorSetup {
+		t.Fatal("Expected an unenrolled admin to get an enrollment-only token")
+	}
+
+	setup, _, _ := useCase.SetupTwoFactor(ctx, claims.UserID)
+	if _, _, err := useCase.EnableTwoFactor(ctx, claims.UserID, currentTOTP(t, setup.Secret, 0)); err != nil {
+		t.Fatalf("enable failed: %v", err)
+	}
+
+	refreshed, _, err := useCase.RefreshToken(ctx, RefreshTokenRequest{RefreshToken: login.RefreshToken})
+	if err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if accessClaims(t, refreshed.AccessToken).TwoFactorSetup {
+		t.Error("Expected the restriction to lift after enrollment")
+	}
+
+	code, _ := useCase.DisableTwoFactor(ctx, claims.UserID, DisableTwoFactorRequest{Password: "password123", Code: currentTOTP(t, setup.Secret, 1)})
+	if code != 403 {
+		t.Errorf("Expected mandatory 2FA to stay enabled, got %d", code)
+	}
+}
+
+func withLoginPolicy(t *testing.T, maxAttempts, delayAfter, maxPerIP int) {
+	previous := config.APP.Security
+	config.APP.Security.LoginMaxAttempts = maxAttempts
+	config.APP.Security.LoginLockoutMinutes = 15
+	config.APP.Security.LoginDelayAfter = delayAfter
+	config.APP.Security.LoginMaxAttemptsPerIP = maxPerIP
+	config.APP.Security.LoginAttemptWindowMinutes = 15
+	t.Cleanup(func() { config.APP.Security = previous })
+}
+
+func TestAuthUseCase_LoginLockoutAndUnlock(t *testing.T) {
+	withLoginPolicy(t, 3, 0, 0)
+	mockRepo := NewMockUserRepository()
+	auditRepo := NewMockAuditRepository()
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), auditRepo, NewMockUnitRepository(), newTestMailer(t))
+	ctx := context.Background()
+	loginTestUser(t, useCase, mockRepo)
+	userId := mockRepo.users[0].Id
+
+	wrong := LoginRequest{Email: "session@example.com", Password: "wrongpassword", IpAddress: "10.0.0.1"}
+	for i := 0; i < 2; i++ {
+		if _, code, _ := useCase.Login(ctx, wrong); code != 401 {
+			t.Fatalf("Expected 401 for failure %d, got %d", i+1, code)
+		}
+	}
+
+	_, code, err := useCase.Login(ctx, wrong)
+	var throttled *LoginThrottledError
+	if code != 423 || !errors.As(err, &throttled) {
+		t.Fatalf("Expected the third failure to lock the account, got %d %v", code, err)
+	}
+	if throttled.RetryAfter <= 14*time.Minute {
+		t.Errorf("Expected a 15 minute lock, got %v", throttled.RetryAfter)
+	}
+
+	correct := LoginRequest{Email: "session@example.com", Password: "password123", IpAddress: "10.0.0.1"}
+	if _, code, _ := useCase.Login(ctx, correct); code != 423 {
+		t.Errorf("Expected a locked account to refuse the right password, got %d", code)
+	}
+
+	if len(auditRepo.logs) != 1 || auditRepo.logs[0].Action != audit_repository.ActionAccountLocked {
+		t.Fatalf("Expected the lockout to be audited, got %+v", auditRepo.logs)
+	}
+
+	adminId := uuid.New()
+	if code, err := useCase.UnlockUser(ctx, auth_utils.AuthClaim{UserID: adminId}, userId, "10.0.0.2"); err != nil {
+		t.Fatalf("Expected unlock to succeed, got %d %v", code, err)
+	}
+	unlocked := auditRepo.logs[len(auditRepo.logs)-1]
+	if unlocked.Action != audit_repository.ActionAccountUnlocked || unlocked.ActorId == nil || *unlocked.ActorId != adminId {
+		t.Errorf("Expected the unlock to be audited with the admin as actor, got %+v", unlocked)
+	}
+
+	if _, code, err := useCase.Login(ctx, correct); code != 200 {
+		t.Fatalf("Expected login after unlock, got %d %v", code, err)
+	}
+	if mockRepo.users[0].FailedLoginAttempts != 0 {
+		t.Errorf("Expected failures to be reset, got %d", mockRepo.users[0].FailedLoginAttempts)
+	}
+}
+
+func TestAuthUseCase_TwoFactorFailuresLockAccount(t *testing.T) {
+	withAesKey(t)
+	withLoginPolicy(t, 3, 0, 0)
+	mockRepo := NewMockUserRepository()
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), newTestMailer(t))
+	ctx := context.Background()
+
+	login := loginTestUser(t, useCase, mockRepo)
+	userId := accessClaims(t, login.AccessToken).UserID
+	setup, _, _ := useCase.SetupTwoFactor(ctx, userId)
+	if _, _, err := useCase.EnableTwoFactor(ctx, userId, currentTOTP(t, setup.Secret, -1)); err != nil {
+		t.Fatalf("enable failed: %v", err)
+	}
+
+	password := LoginRequest{Email: "session@example.com", Password: "password123"}
+	challenge, _, err := useCase.Login(ctx, password)
+	if err != nil {
+		t.Fatalf("password step failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, code, _ := useCase.VerifyTwoFactor(ctx, VerifyTwoFactorRequest{ChallengeToken: challenge.ChallengeToken, Code: "000000"}); code != 401 {
+			t.Fatalf("Expected 401 for wrong code %d, got %d", i+1, code)
+		}
+	}
+
+	// Entering the password again must not clear the failed codes
+	challenge, _, err = useCase.Login(ctx, password)
+	if err != nil {
+		t.Fatalf("password step failed: %v", err)
+	}
+	if _, code, _ := useCase.VerifyTwoFactor(ctx, VerifyTwoFactorRequest{ChallengeToken: challenge.ChallengeToken, Code: "000000"}); code != 423 {
+		t.Fatalf("Expected the third wrong code to lock the account, got %d", code)
+	}
+
+	if _, code, _ := useCase.VerifyTwoFactor(ctx, VerifyTwoFactorRequest{ChallengeToken: challenge.ChallengeToken, Code: currentTOTP(t, setup.Secret, 0)}); code != 423 {
+		t.Errorf("Expected a locked account to refuse the right code, got %d", code)
+	}
+}
+
+func TestAuthUseCase_LoginProgressiveDelay(t *testing.T) {
+	withLoginPolicy(t, 0, 1, 0)
+	mockRepo := NewMockUserRepository()
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), NewMockAuditRepository(), NewMockUnitRepository(), newTestMailer(t))
+	ctx := context.Background()
+	loginTestUser(t, useCase, mockRepo)
+
+	wrong := LoginRequest{Email: "session@example.com", Password: "wrongpassword"}
+	if _, code, _ := useCase.Login(ctx, wrong); code != 401 {
+		t.Fatalf("Expected 401 for the first failure, got %d", code)
+	}
+
+	_, code, err := useCase.Login(ctx, wrong)
+	var throttled *LoginThrottledError
+	if code != 429 || !errors.As(err, &throttled) {
+		t.Fatalf("Expected an immediate retry to be delayed, got %d %v", code, err)
+	}
+	if throttled.RetryAfter <= 0 || throttled.RetryAfter > 2*time.Second {
+		t.Errorf("Expected a delay of up to 2s, got %v", throttled.RetryAfter)
+	}
+}
+
+func TestAuthUseCase_LoginThrottlesIpAddress(t *testing.T) {
+	withLoginPolicy(t, 0, 0, 2)
+	mockRepo := NewMockUserRepository()
+	attemptRepo := NewMockLoginAttemptRepository()
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), NewMockTwoFactorRepository(), attemptRepo, NewMockAuditRepository(), NewMockUnitRepository(), newTestMailer(t))
+	ctx := context.Background()
+	loginTestUser(t, useCase, mockRepo)
+
+	for _, email := range []string{"a@example.com", "B@Example.com"} {
+		if _, code, _ := useCase.Login(ctx, LoginRequest{Email: email, Password: "x", IpAddress: "10.0.0.9"}); code != 401 {
+			t.Fatalf("Expected 401 for unknown email %s, got %d", email, code)
+		}
+	}
+	if attemptRepo.attempts[2].Email != "b@example.com" {
+		t.Errorf("Expected attempts to be stored with a normalized email, got %q", attemptRepo.attempts[2].Email)
+	}
+
+	if _, code, _ := useCase.Login(ctx, LoginRequest{Email: "session@example.com", Password: "password123", IpAddress: "10.0.0.9"}); code != 429 {
+		t.Errorf("Expected the address to be throttled, got %d", code)
+	}
+	if _, code, _ := useCase.Login(ctx, LoginRequest{Email: "session@example.com", Password: "password123", IpAddress: "10.0.0.10"}); code != 200 {
+		t.Errorf("Expected another address to log in, got %d", code)
+	}
+}
+
+func impersonationFixture(t *testing.T) (AuthUseCase, *MockUserRepository, *MockAuditRepository, user_repository.User, user_repository.User) {
+	t.Helper()
+	mockRepo := NewMockUserRepository()
+	auditRepo := NewMockAuditRepository()
+	useCase := NewAuthUseCase(mockRepo, NewMockTokenRepository(), NewMockTwoFactorRepository(), NewMockLoginAttemptRepository(), auditRepo, NewMockUnitRepository(), newTestMailer(t))
+
+	admin := user_repository.User{Id: uuid.New(), Email: "root@example.com", IsSuperAdmin: true, IsActive: true}
+	user := user_repository.User{Id: uuid.New(), Email: "teacher@example.com", IsActive: true}
+	mockRepo.users = append(mockRepo.users, admin, user)
+	return useCase, mockRepo, auditRepo, admin, user
+}
+
+func TestAuthUseCase_Impersonate(t *testing.T) {
+	useCase, _, auditRepo, admin, user := impersonationFixture(t)
+	ctx := context.Background()
+
+	resp, code, err := useCase.Impersonate(ctx, auth_utils.AuthClaim{UserID: admin.Id}, user.Id, ImpersonateRequest{Reason: "support ticket #42"})
+	if err != nil || code != 200 {
+		t.Fatalf("Expected impersonation to start, got %d: %v", code, err)
+	}
+
+	claims := accessClaims(t, resp.AccessToken)
+	if claims.UserID != user.Id || !claims.IsImpersonated() || *claims.ImpersonatorID != admin.Id {
+		t.Errorf("Expected the token to act as the user on behalf of the admin, got %+v", claims)
+	}
+	if claims.ImpersonationWrites {
+		t.Error("Expected the token to be read-only by default")
+	}
+	if claims.SessionID != uuid.Nil {
+		t.Error("Expected the token not to belong to a session")
+	}
+	if lifetime := time.Until(time.Unix(resp.ExpiresAt, 0)); lifetime > ImpersonationTokenDuration {
+		t.Errorf("Expected a short-lived token, got %s", lifetime)
+	}
+
+	if len(auditRepo.logs) != 1 || auditRepo.logs[0].Action != audit_repository.ActionImpersonationStarted {
+		t.Fatalf("Expected the start to be audited, got %+v", auditRepo.logs)
+	}
+	if *auditRepo.logs[0].ActorId != admin.Id || *auditRepo.logs[0].UserId != user.Id {
+		t.Error("Expected the audit entry to name both identities")
+	}
+
+	useCase.RecordImpersonatedRequest(ctx, claims, "GET", "/api/v1/users/me", 200, "10.0.0.1")
+	if len(auditRepo.logs) != 2 || auditRepo.logs[1].Action != audit_repository.ActionImpersonationRequest {
+		t.Fatalf("Expected the request to be audited, got %+v", auditRepo.logs)
+	}
+	if *auditRepo.logs[1].ActorId != admin.Id || *auditRepo.logs[1].UserId != user.Id {
+		t.Error("Expected the request entry to name both identities")
+	}
+}
+
+func TestAuthUseCase_ImpersonateRefusals(t *testing.T) {
+	useCase, mockRepo, auditRepo, admin, user := impersonationFixture(t)
+	ctx := context.Background()
+
+	otherAdmin := user_repository.User{Id: uuid.New(), IsSuperAdmin: true, IsActive: true}
+	inactive := user_repository.User{Id: uuid.New(), IsActive: false}
+	mockRepo.users = append(mockRepo.users, otherAdmin, inactive)
+	actorId := admin.Id
+
+	cases := []struct {
+		name   string
+		actor  auth_utils.AuthClaim
+		target uuid.UUID
+		req    ImpersonateRequest
+		code   int
+	}{
+		{"self", auth_utils.AuthClaim{UserID: admin.Id}, admin.Id, ImpersonateRequest{Reason: "x"}, 400},
+		{"missing reason", auth_utils.AuthClaim{UserID: admin.Id}, user.Id, ImpersonateRequest{Reason: "  "}, 400},
+		{"too long", auth_utils.AuthClaim{UserID: admin.Id}, user.Id, ImpersonateRequest{Reason: "x", Duration: 2 * time.Hour}, 400},
+		{"not a super admin", auth_utils.AuthClaim{UserID: user.Id}, inactive.Id, ImpersonateRequest{Reason: "x"}, 403},
+		{"super admin target", auth_utils.AuthClaim{UserID: admin.Id}, otherAdmin.Id, ImpersonateRequest{Reason: "x"}, 403},
+		{"inactive target", auth_utils.AuthClaim{UserID: admin.Id}, inactive.Id, ImpersonateRequest{Reason: "x"}, 400},
+		{"unknown target", auth_utils.AuthClaim{UserID: admin.Id}, uuid.New(), ImpersonateRequest{Reason: "x"}, 404},
+		{"nested", auth_utils.AuthClaim{UserID: user.Id, ImpersonatorID: &actorId}, inactive.Id, ImpersonateRequest{Reason: "x"}, 403},
+	}
+	for _, tc := range cases {
+		if _, code, err := useCase.Impersonate(ctx, tc.actor, tc.target, tc.req); err == nil || code != tc.code {
+			t.Errorf("%s: expected %d, got %d (%v)", tc.name, tc.code, code, err)
+		}
+	}
+
+	if len(auditRepo.logs) != 0 {
+		t.Errorf("Expected refused impersonations not to be audited as started, got %d entries", len(auditRepo.logs))
+	}
+}
@@ -0,0 +1,65 @@
+package gradebook_use_case
+
+import (
+	"math"
+
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+)
+
+// Rounding is how a unit rounds computed grades.
+type Rounding struct {
+	Policy   string `json:"policy"` // half_up, down or up
+	Decimals int    `json:"decimals"`
+}
+
+// RoundingFrom reads the rounding policy from the unit settings, rounding half
+// up to whole numbers unless set otherwise.
+func RoundingFrom(settings *schemas.UnitSettings) Rounding {
+	rounding := Rounding{Policy: settings.GradeRounding, Decimals: settings.GradeDecimals}
+	switch rounding.Policy {
+	case schemas.GradeRoundHalfUp, schemas.GradeRoundDown, schemas.GradeRoundUp:
+	default:
+		rounding.Policy = schemas.GradeRoundHalfUp
+	}
+	if rounding.Decimals < 0 {
+		rounding.Decimals = 0
+	}
+	return rounding
+}
+
+// Round applies the policy to the value.
+func (r Rounding) Round(value float64) float64 {
+	scale := math.Pow(10, float64(r.Decimals))
+	// The epsilon keeps values such as 82.3 * 10 = 822.9999... on the right side
+	const epsilon = 1e-9
+	switch r.Policy {
+	case schemas.GradeRoundDown:
+		return math.Floor(value*scale+epsilon) / scale
+	case schemas.GradeRoundUp:
+		return math.Ceil(value*scale-epsilon) / scale
+	default:
+		return math.Floor(value*scale+0.5+epsilon) / scale
+	}
+}
+
+// WeightedAverage averages a student's scores out of 100, each assessment
+// counting by its weight. Assessments without a score are left out rather
+// than counted as zero; the result is nil when there is no score at all.
+func WeightedAverage(assessments []schemas.Assessment, scores map[uuid.UUID]float64, rounding Rounding) *float64 {
+	var total, weights float64
+	for _, assessment := range assessments {
+		score, ok := scores[assessment.Id]
+		if !ok || assessment.MaxScore <= 0 || assessment.Weight <= 0 {
+			continue
+		}
+		total += score / assessment.MaxScore * 100 * assessment.Weight
+		weights += assessment.Weight
+	}
+	if weights == 0 {
+		return nil
+	}
+	average := rounding.Round(total / weights)
+	return &average
+}
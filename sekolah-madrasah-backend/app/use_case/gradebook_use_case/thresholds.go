@@ -0,0 +1,284 @@
+package gradebook_use_case
+
+import (
+	"errors"
+	"sort"
+
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ThresholdRequest sets a passing score and predicate scale for the unit,
+// narrowed to a subject and/or a level when given.
+type ThresholdRequest struct {
+	UnitId     uuid.UUID
+	SubjectId  *uuid.UUID // Nil for every subject
+	Level      *int       // Nil for every level
+	MinScore   float64
+	PredicateA float64
+	PredicateB float64
+	PredicateC float64
+}
+
+// StudentBelow is a student whose average fell short of the passing score.
+type StudentBelow struct {
+	ClassEnrollmentId uuid.UUID `json:"class_enrollment_id"`
+	StudentName       string    `json:"student_name"`
+	NIS               *string   `json:"nis"`
+	Average           float64   `json:"average"`
+}
+
+// SubjectPassRate is how a class did in a subject against its passing score.
+type SubjectPassRate struct {
+	SubjectId      uuid.UUID      `json:"subject_id"`
+	SubjectName    string         `json:"subject_name"`
+	MinScore       *float64       `json:"min_score"` // Null when no threshold applies
+	Graded         int            `json:"graded"`    // Students with an average
+	Passed         int            `json:"passed"`    // 0 when no threshold applies
+	PassRate       *float64       `json:"pass_rate"` // Percentage of graded students passing; null without a threshold or grades
+	BelowThreshold []StudentBelow `json:"below_threshold"`
+}
+
+type PassRates struct {
+	ClassId      uuid.UUID         `json:"class_id"`
+	AcademicYear string            `json:"academic_year"`
+	Semester     int               `json:"semester"`
+	Subjects     []SubjectPassRate `json:"subjects"`
+}
+
+// passRateRounding rounds pass rates to hundredths of a percent.
+var passRateRounding = Rounding{Policy: schemas.GradeRoundHalfUp, Decimals: 2}
+
+// ThresholdFor picks the rule applying to a subject at a level: one set for
+// both wins over one set for the subject only, then for the level only, then
+// the unit-wide rule. It returns nil when none applies.
+func ThresholdFor(rules []schemas.GradeThreshold, subjectId uuid.UUID, level int) *schemas.GradeThreshold {
+	var best *schemas.GradeThreshold
+	bestRank := -1
+	for i := range rules {
+		rule := &rules[i]
+		rank := 0
+		if rule.SubjectId != nil {
+			if *rule.SubjectId != subjectId {
+				continue
+			}
+			rank += 2
+		}
+		if rule.Level != nil {
+			if *rule.Level != level {
+				continue
+			}
+			rank++
+		}
+		if rank > bestRank {
+			best, bestRank = rule, rank
+		}
+	}
+	return best
+}
+
+// Mark gives an average its predicate and tells whether it falls below the
+// passing score. Without a rule or an average there is nothing to mark.
+func Mark(rule *schemas.GradeThreshold, average *float64) (*string, bool) {
+	if rule == nil || average == nil {
+		return nil, false
+	}
+	predicate := rule.Predicate(*average)
+	return &predicate, *average < rule.MinScore
+}
+
+func (uc *gradebookUseCase) ListThresholds(unitId uuid.UUID) ([]schemas.GradeThreshold, error) {
+	return uc.repo.FindThresholds(unitId)
+}
+
+func (uc *gradebookUseCase) CreateThreshold(req *ThresholdRequest) (*schemas.GradeThreshold, error) {
+	threshold := &schemas.GradeThreshold{UnitId: req.UnitId}
+	if err := uc.applyThreshold(threshold, req); err != nil {
+		return nil, err
+	}
+	return thresholdSaved(uc.repo.CreateThreshold(threshold))
+}
+
+func (uc *gradebookUseCase) UpdateThreshold(unitId, thresholdId uuid.UUID, req *ThresholdRequest) (*schemas.GradeThreshold, error) {
+	threshold, err := uc.repo.FindThreshold(thresholdId)
+	if err != nil || threshold.UnitId != unitId {
+		return nil, ErrThresholdNotFound
+	}
+	if err := uc.applyThreshold(threshold, req); err != nil {
+		return nil, err
+	}
+	return thresholdSaved(uc.repo.UpdateThreshold(threshold))
+}
+
+// thresholdSaved reports a rule saved concurrently for the same subject and
+// level, caught by the unique index, like the check in applyThreshold does.
+func thresholdSaved(threshold *schemas.GradeThreshold, err error) (*schemas.GradeThreshold, error) {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, ErrThresholdExists
+	}
+	return threshold, err
+}
+
+func (uc *gradebookUseCase) DeleteThreshold(unitId, thresholdId uuid.UUID) error {
+	threshold, err := uc.repo.FindThreshold(thresholdId)
+	if err != nil || threshold.UnitId != unitId {
+		return ErrThresholdNotFound
+	}
+	return uc.repo.DeleteThreshold(threshold.Id)
+}
+
+// applyThreshold validates the request and copies it onto the rule, refusing
+// a second rule for the same subject and level.
+func (uc *gradebookUseCase) applyThreshold(threshold *schemas.GradeThreshold, req *ThresholdRequest) error {
+	if req.MinScore < 0 || req.MinScore > 100 {
+		return errors.New("min_score must be between 0 and 100")
+	}
+	if req.PredicateC < 0 || req.PredicateA > 100 || req.PredicateC >= req.PredicateB || req.PredicateB >= req.PredicateA {
+		return errors.New("predicate minimums must rise from C to B to A within 0 to 100")
+	}
+	if req.Level != nil && (*req.Level < 1 || *req.Level > 12) {
+		return errors.New("level must be between 1 and 12")
+	}
+	if req.SubjectId != nil {
+		subject, err := uc.repo.FindSubject(*req.SubjectId)
+		if err != nil || subject.UnitId != threshold.UnitId {
+			return ErrSubjectNotFound
+		}
+	}
+
+	existing, err := uc.repo.FindThresholds(threshold.UnitId)
+	if err != nil {
+		return err
+	}
+	for _, other := range existing {
+		if other.Id != threshold.Id && sameUUID(other.SubjectId, req.SubjectId) && sameInt(other.Level, req.Level) {
+			return ErrThresholdExists
+		}
+	}
+
+	threshold.SubjectId = req.SubjectId
+	threshold.Level = req.Level
+	threshold.MinScore = req.MinScore
+	threshold.PredicateA = req.PredicateA
+	threshold.PredicateB = req.PredicateB
+	threshold.PredicateC = req.PredicateC
+	return nil
+}
+
+func (uc *gradebookUseCase) GetPassRates(unitId, classId uuid.UUID, academicYear string, semester int) (*PassRates, error) {
+	class, err := uc.repo.FindClass(classId)
+	if err != nil || class.UnitId != unitId {
+		return nil, ErrClassNotFound
+	}
+	settings, err := uc.settings(unitId)
+	if err != nil {
+		return nil, err
+	}
+	academicYear, semester, err = term(academicYear, semester, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	assessments, err := uc.repo.FindClassAssessments(class.Id, academicYear, semester)
+	if err != nil {
+		return nil, err
+	}
+	perSubject := map[uuid.UUID][]schemas.Assessment{}
+	names := map[uuid.UUID]string{}
+	ids := make([]uuid.UUID, 0, len(assessments))
+	for _, assessment := range assessments {
+		perSubject[assessment.SubjectId] = append(perSubject[assessment.SubjectId], assessment)
+		if assessment.Subject != nil {
+			names[assessment.SubjectId] = assessment.Subject.Name
+		}
+		ids = append(ids, assessment.Id)
+	}
+	scores, err := uc.repo.FindScores(ids)
+	if err != nil {
+		return nil, err
+	}
+	enrollments, err := uc.repo.FindEnrollments(class.Id)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := uc.repo.FindThresholds(unitId)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := &PassRates{ClassId: class.Id, AcademicYear: academicYear, Semester: semester, Subjects: []SubjectPassRate{}}
+	entered := scoresByStudent(scores)
+	rounding := RoundingFrom(settings)
+	for subjectId, subjectAssessments := range perSubject {
+		rule := ThresholdFor(rules, subjectId, class.Level)
+		rate := SubjectPassRate{SubjectId: subjectId, SubjectName: names[subjectId], BelowThreshold: []StudentBelow{}}
+		if rule != nil {
+			minScore := rule.MinScore
+			rate.MinScore = &minScore
+		}
+		lines := grades(subjectAssessments, entered, enrollments, rounding, rule)
+		for _, line := range lines {
+			if line.Average == nil {
+				continue
+			}
+			rate.Graded++
+			if line.BelowThreshold {
+				rate.BelowThreshold = append(rate.BelowThreshold, StudentBelow{
+					ClassEnrollmentId: line.ClassEnrollmentId,
+					StudentName:       line.StudentName,
+					NIS:               line.NIS,
+					Average:           *line.Average,
+				})
+			}
+		}
+		if rule != nil {
+			rate.Passed = rate.Graded - len(rate.BelowThreshold)
+		}
+		rate.PassRate = passRate(rule, lines)
+		rates.Subjects = append(rates.Subjects, rate)
+	}
+
+	sort.Slice(rates.Subjects, func(i, j int) bool {
+		return rates.Subjects[i].SubjectName < rates.Subjects[j].SubjectName
+	})
+	return rates, nil
+}
+
+// passRate is the percentage of graded students at or above the passing
+// score, nil without a rule or anyone graded.
+func passRate(rule *schemas.GradeThreshold, lines []StudentGrades) *float64 {
+	if rule == nil {
+		return nil
+	}
+	var graded, passed int
+	for _, line := range lines {
+		if line.Average == nil {
+			continue
+		}
+		graded++
+		if !line.BelowThreshold {
+			passed++
+		}
+	}
+	if graded == 0 {
+		return nil
+	}
+	rate := passRateRounding.Round(float64(passed) / float64(graded) * 100)
+	return &rate
+}
+
+func sameUUID(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func sameInt(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
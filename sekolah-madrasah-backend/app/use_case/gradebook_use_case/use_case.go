@@ -0,0 +1,609 @@
+package gradebook_use_case
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"sekolah-madrasah/app/repository/gradebook_repository"
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrClassNotFound      = errors.New("class not found in this unit")
+	ErrSubjectNotFound    = errors.New("subject not found in this unit")
+	ErrAssessmentNotFound = errors.New("assessment not found")
+	ErrNotSubjectTeacher  = errors.New("only the teacher assigned to this class and subject may enter its scores")
+	ErrStaleScores        = errors.New("some scores were changed by someone else since they were loaded")
+	ErrGradesLocked       = errors.New("grades are locked: report cards of this class and semester have been finalized")
+	ErrThresholdNotFound  = errors.New("passing threshold not found")
+	ErrThresholdExists    = errors.New("a passing threshold for this subject and level already exists")
+)
+
+// StaleScoresError lists the students whose scores changed since the sheet
+// was loaded; none of the batch was saved.
+type StaleScoresError struct {
+	ClassEnrollmentIds []uuid.UUID
+}
+
+func (e *StaleScoresError) Error() string {
+	return fmt.Sprintf("%s (%d students), reload and try again", ErrStaleScores.Error(), len(e.ClassEnrollmentIds))
+}
+
+func (e *StaleScoresError) Unwrap() error {
+	return ErrStaleScores
+}
+
+type GradebookUseCase interface {
+	ListAssessments(unitId, classId, subjectId uuid.UUID, academicYear string, semester int) ([]schemas.Assessment, error)
+	CreateAssessment(req *AssessmentRequest) (*schemas.Assessment, error)
+	UpdateAssessment(unitId, assessmentId uuid.UUID, req *UpdateAssessmentRequest) (*schemas.Assessment, error)
+	DeleteAssessment(unitId, assessmentId uuid.UUID, grader Grader) error
+	// GetScores returns an assessment's score sheet with the version of every
+	// score, to be sent back when saving.
+	GetScores(unitId, assessmentId uuid.UUID) (*ScoreSheet, error)
+	// SaveScores stores the scores of several students at once. Only the
+	// teacher assigned to the class and subject may enter them.
+	SaveScores(req *SaveScoresRequest) (*ScoreSheet, error)
+	// GetGradebook lists every assessment and score of a class in a subject for
+	// a semester, with each student's weighted average marked against the
+	// passing threshold.
+	GetGradebook(unitId, classId, subjectId uuid.UUID, academicYear string, semester int) (*Gradebook, error)
+	// Passing thresholds (KKM/KKTP) and predicate scales
+	ListThresholds(unitId uuid.UUID) ([]schemas.GradeThreshold, error)
+	CreateThreshold(req *ThresholdRequest) (*schemas.GradeThreshold, error)
+	UpdateThreshold(unitId, thresholdId uuid.UUID, req *ThresholdRequest) (*schemas.GradeThreshold, error)
+	DeleteThreshold(unitId, thresholdId uuid.UUID) error
+	// GetPassRates reports, for every subject assessed in a class during the
+	// semester, how many students reached the passing score and who did not.
+	GetPassRates(unitId, classId uuid.UUID, academicYear string, semester int) (*PassRates, error)
+}
+
+// Grader is the user managing assessments or entering scores and their role
+// in the unit.
+type Grader struct {
+	UserId uuid.UUID
+	Role   schemas.UnitMemberRole
+}
+
+type AssessmentRequest struct {
+	UnitId       uuid.UUID
+	ClassId      uuid.UUID
+	SubjectId    uuid.UUID
+	AcademicYear string // Defaults to the unit's current academic year
+	Semester     int    // Defaults to the unit's current semester
+	Type         schemas.AssessmentType
+	Name         string
+	Competency   *string
+	Date         *time.Time
+	Weight       float64
+	MaxScore     float64 // Defaults to 100
+	Grader       Grader
+}
+
+type UpdateAssessmentRequest struct {
+	Type       *schemas.AssessmentType
+	Name       *string
+	Competency *string
+	Date       *time.Time
+	Weight     *float64
+	MaxScore   *float64
+	Grader     Grader
+}
+
+type ScoreRequest struct {
+	ClassEnrollmentId uuid.UUID
+	Score             float64
+	Note              *string
+	Version           int // Version the score was loaded at, 0 for a new score
+}
+
+type SaveScoresRequest struct {
+	UnitId       uuid.UUID
+	AssessmentId uuid.UUID
+	Scores       []ScoreRequest
+	Grader       Grader
+}
+
+// ScoreLine is one student's line on an assessment's score sheet.
+type ScoreLine struct {
+	ClassEnrollmentId uuid.UUID  `json:"class_enrollment_id"`
+	StudentProfileId  uuid.UUID  `json:"student_profile_id"`
+	StudentName       string     `json:"student_name"`
+	NIS               *string    `json:"nis"`
+	Score             *float64   `json:"score"` // Null until entered
+	Note              *string    `json:"note"`
+	Version           int        `json:"version"` // 0 until entered
+	RecordedBy        *uuid.UUID `json:"recorded_by"`
+}
+
+type ScoreSheet struct {
+	Assessment schemas.Assessment `json:"assessment"`
+	Students   []ScoreLine        `json:"students"`
+}
+
+type StudentGrades struct {
+	ClassEnrollmentId uuid.UUID  `json:"class_enrollment_id"`
+	StudentProfileId  uuid.UUID  `json:"student_profile_id"`
+	StudentName       string     `json:"student_name"`
+	NIS               *string    `json:"nis"`
+	Scores            []*float64 `json:"scores"`          // In the order of the assessments, null when missing
+	Average           *float64   `json:"average"`         // Weighted, out of 100; null without any score
+	Predicate         *string    `json:"predicate"`       // A to D; null without a threshold or an average
+	BelowThreshold    bool       `json:"below_threshold"` // Average under the passing score
+	Missing           int        `json:"missing"`         // Assessments without a score
+}
+
+type Gradebook struct {
+	ClassId      uuid.UUID               `json:"class_id"`
+	SubjectId    uuid.UUID               `json:"subject_id"`
+	AcademicYear string                  `json:"academic_year"`
+	Semester     int                     `json:"semester"`
+	Rounding     Rounding                `json:"rounding"`
+	Threshold    *schemas.GradeThreshold `json:"threshold"` // Null when the unit set none for the subject and level
+	PassRate     *float64                `json:"pass_rate"` // Percentage of graded students passing
+	Assessments  []schemas.Assessment    `json:"assessments"`
+	Students     []StudentGrades         `json:"students"`
+}
+
+type gradebookUseCase struct {
+	repo gradebook_repository.GradebookRepository
+}
+
+func NewGradebookUseCase(repo gradebook_repository.GradebookRepository) GradebookUseCase {
+	return &gradebookUseCase{repo: repo}
+}
+
+func (uc *gradebookUseCase) ListAssessments(unitId, classId, subjectId uuid.UUID, academicYear string, semester int) ([]schemas.Assessment, error) {
+	class, subject, err := uc.classSubject(unitId, classId, subjectId)
+	if err != nil {
+		return nil, err
+	}
+	academicYear, semester, err = uc.term(unitId, academicYear, semester)
+	if err != nil {
+		return nil, err
+	}
+	return uc.repo.FindAssessments(class.Id, subject.Id, academicYear, semester)
+}
+
+func (uc *gradebookUseCase) CreateAssessment(req *AssessmentRequest) (*schemas.Assessment, error) {
+	class, subject, err := uc.classSubject(req.UnitId, req.ClassId, req.SubjectId)
+	if err != nil {
+		return nil, err
+	}
+	if !uc.canManage(class, subject.Id, req.Grader) {
+		return nil, ErrNotSubjectTeacher
+	}
+	academicYear, semester, err := uc.term(req.UnitId, req.AcademicYear, req.Semester)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.unlocked(class.Id, academicYear, semester); err != nil {
+		return nil, err
+	}
+
+	assessment := &schemas.Assessment{
+		UnitId:       req.UnitId,
+		ClassId:      class.Id,
+		SubjectId:    subject.Id,
+		AcademicYear: academicYear,
+		Semester:     semester,
+		Type:         req.Type,
+		Name:         strings.TrimSpace(req.Name),
+		Competency:   req.Competency,
+		Date:         req.Date,
+		Weight:       req.Weight,
+		MaxScore:     req.MaxScore,
+		CreatedBy:    req.Grader.UserId,
+	}
+	if assessment.MaxScore == 0 {
+		assessment.MaxScore = 100
+	}
+	if err := validateAssessment(assessment); err != nil {
+		return nil, err
+	}
+	return uc.repo.CreateAssessment(assessment)
+}
+
+func (uc *gradebookUseCase) UpdateAssessment(unitId, assessmentId uuid.UUID, req *UpdateAssessmentRequest) (*schemas.Assessment, error) {
+	assessment, class, err := uc.assessment(unitId, assessmentId)
+	if err != nil {
+		return nil, err
+	}
+	if !uc.canManage(class, assessment.SubjectId, req.Grader) {
+		return nil, ErrNotSubjectTeacher
+	}
+	if err := uc.unlocked(class.Id, assessment.AcademicYear, assessment.Semester); err != nil {
+		return nil, err
+	}
+
+	if req.Type != nil {
+		assessment.Type = *req.Type
+	}
+	if req.Name != nil {
+		assessment.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Competency != nil {
+		assessment.Competency = req.Competency
+	}
+	if req.Date != nil {
+		assessment.Date = req.Date
+	}
+	if req.Weight != nil {
+		assessment.Weight = *req.Weight
+	}
+	if req.MaxScore != nil {
+		scores, err := uc.repo.FindScores([]uuid.UUID{assessment.Id})
+		if err != nil {
+			return nil, err
+		}
+		for _, score := range scores {
+			if score.Score > *req.MaxScore {
+				return nil, fmt.Errorf("max_score cannot go below a score already entered (%g)", score.Score)
+			}
+		}
+		assessment.MaxScore = *req.MaxScore
+	}
+	if err := validateAssessment(assessment); err != nil {
+		return nil, err
+	}
+	return uc.repo.UpdateAssessment(assessment)
+}
+
+func (uc *gradebookUseCase) DeleteAssessment(unitId, assessmentId uuid.UUID, grader Grader) error {
+	assessment, class, err := uc.assessment(unitId, assessmentId)
+	if err != nil {
+		return err
+	}
+	if !uc.canManage(class, assessment.SubjectId, grader) {
+		return ErrNotSubjectTeacher
+	}
+	if err := uc.unlocked(class.Id, assessment.AcademicYear, assessment.Semester); err != nil {
+		return err
+	}
+	return uc.repo.DeleteAssessment(assessment.Id)
+}
+
+func (uc *gradebookUseCase) GetScores(unitId, assessmentId uuid.UUID) (*ScoreSheet, error) {
+	assessment, _, err := uc.assessment(unitId, assessmentId)
+	if err != nil {
+		return nil, err
+	}
+	return uc.sheet(assessment)
+}
+
+func (uc *gradebookUseCase) SaveScores(req *SaveScoresRequest) (*ScoreSheet, error) {
+	assessment, class, err := uc.assessment(req.UnitId, req.AssessmentId)
+	if err != nil {
+		return nil, err
+	}
+	if !uc.canGrade(class, assessment.SubjectId, req.Grader) {
+		return nil, ErrNotSubjectTeacher
+	}
+	if err := uc.unlocked(class.Id, assessment.AcademicYear, assessment.Semester); err != nil {
+		return nil, err
+	}
+	if len(req.Scores) == 0 {
+		return nil, errors.New("scores must not be empty")
+	}
+
+	enrollments, err := uc.repo.FindEnrollments(class.Id)
+	if err != nil {
+		return nil, err
+	}
+	current := map[uuid.UUID]bool{}
+	for _, enrollment := range enrollments {
+		if enrollment.LeftAt == nil {
+			current[enrollment.Id] = true
+		}
+	}
+
+	scores := make([]schemas.AssessmentScore, 0, len(req.Scores))
+	seen := map[uuid.UUID]bool{}
+	for _, score := range req.Scores {
+		if !current[score.ClassEnrollmentId] {
+			return nil, fmt.Errorf("enrollment %s is not a current student of this class", score.ClassEnrollmentId)
+		}
+		if seen[score.ClassEnrollmentId] {
+			return nil, fmt.Errorf("enrollment %s is listed twice", score.ClassEnrollmentId)
+		}
+		seen[score.ClassEnrollmentId] = true
+		if score.Score < 0 || score.Score > assessment.MaxScore {
+			return nil, fmt.Errorf("score %g is outside 0 to %g", score.Score, assessment.MaxScore)
+		}
+		if score.Version < 0 {
+			return nil, errors.New("version cannot be negative")
+		}
+
+		scores = append(scores, schemas.AssessmentScore{
+			AssessmentId:      assessment.Id,
+			ClassEnrollmentId: score.ClassEnrollmentId,
+			Score:             score.Score,
+			Note:              score.Note,
+			Version:           score.Version,
+			RecordedBy:        req.Grader.UserId,
+		})
+	}
+
+	stale, err := uc.repo.SaveScores(scores)
+	if err != nil {
+		return nil, err
+	}
+	if len(stale) > 0 {
+		return nil, &StaleScoresError{ClassEnrollmentIds: stale}
+	}
+	return uc.sheet(assessment)
+}
+
+func (uc *gradebookUseCase) GetGradebook(unitId, classId, subjectId uuid.UUID, academicYear string, semester int) (*Gradebook, error) {
+	class, subject, err := uc.classSubject(unitId, classId, subjectId)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := uc.settings(unitId)
+	if err != nil {
+		return nil, err
+	}
+	academicYear, semester, err = term(academicYear, semester, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	assessments, err := uc.repo.FindAssessments(class.Id, subject.Id, academicYear, semester)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, 0, len(assessments))
+	for _, assessment := range assessments {
+		ids = append(ids, assessment.Id)
+	}
+	scores, err := uc.repo.FindScores(ids)
+	if err != nil {
+		return nil, err
+	}
+	enrollments, err := uc.repo.FindEnrollments(class.Id)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := uc.repo.FindThresholds(unitId)
+	if err != nil {
+		return nil, err
+	}
+
+	rounding := RoundingFrom(settings)
+	rule := ThresholdFor(rules, subject.Id, class.Level)
+	students := grades(assessments, scoresByStudent(scores), enrollments, rounding, rule)
+	return &Gradebook{
+		ClassId:      class.Id,
+		SubjectId:    subject.Id,
+		AcademicYear: academicYear,
+		Semester:     semester,
+		Rounding:     rounding,
+		Threshold:    rule,
+		PassRate:     passRate(rule, students),
+		Assessments:  assessments,
+		Students:     students,
+	}, nil
+}
+
+// grades lines up each student's scores in the assessments with their
+// weighted average, marked against the rule, sorted by name. Students who
+// left without a score in these assessments are left out.
+func grades(assessments []schemas.Assessment, perStudent map[uuid.UUID]map[uuid.UUID]float64, enrollments []schemas.ClassEnrollment, rounding Rounding, rule *schemas.GradeThreshold) []StudentGrades {
+	students := []StudentGrades{}
+	for _, enrollment := range enrollments {
+		entered := perStudent[enrollment.Id]
+		line := StudentGrades{
+			ClassEnrollmentId: enrollment.Id,
+			StudentProfileId:  enrollment.StudentProfileId,
+			Scores:            make([]*float64, len(assessments)),
+		}
+		line.StudentName, line.NIS = student(enrollment)
+		for i, assessment := range assessments {
+			if score, ok := entered[assessment.Id]; ok {
+				value := score
+				line.Scores[i] = &value
+			} else {
+				line.Missing++
+			}
+		}
+		if enrollment.LeftAt != nil && line.Missing == len(assessments) {
+			continue
+		}
+		line.Average = WeightedAverage(assessments, entered, rounding)
+		line.Predicate, line.BelowThreshold = Mark(rule, line.Average)
+		students = append(students, line)
+	}
+
+	sort.SliceStable(students, func(i, j int) bool {
+		return students[i].StudentName < students[j].StudentName
+	})
+	return students
+}
+
+// scoresByStudent indexes scores by class enrollment, then assessment.
+func scoresByStudent(scores []schemas.AssessmentScore) map[uuid.UUID]map[uuid.UUID]float64 {
+	perStudent := map[uuid.UUID]map[uuid.UUID]float64{}
+	for _, score := range scores {
+		if perStudent[score.ClassEnrollmentId] == nil {
+			perStudent[score.ClassEnrollmentId] = map[uuid.UUID]float64{}
+		}
+		perStudent[score.ClassEnrollmentId][score.AssessmentId] = score.Score
+	}
+	return perStudent
+}
+
+func (uc *gradebookUseCase) classSubject(unitId, classId, subjectId uuid.UUID) (*schemas.Class, *schemas.Subject, error) {
+	class, err := uc.repo.FindClass(classId)
+	if err != nil || class.UnitId != unitId {
+		return nil, nil, ErrClassNotFound
+	}
+	subject, err := uc.repo.FindSubject(subjectId)
+	if err != nil || subject.UnitId != unitId {
+		return nil, nil, ErrSubjectNotFound
+	}
+	return class, subject, nil
+}
+
+func (uc *gradebookUseCase) assessment(unitId, assessmentId uuid.UUID) (*schemas.Assessment, *schemas.Class, error) {
+	assessment, err := uc.repo.FindAssessment(assessmentId)
+	if err != nil || assessment.UnitId != unitId {
+		return nil, nil, ErrAssessmentNotFound
+	}
+	class, err := uc.repo.FindClass(assessment.ClassId)
+	if err != nil {
+		return nil, nil, ErrClassNotFound
+	}
+	return assessment, class, nil
+}
+
+// canGrade allows only the teacher assigned to the class for the subject: the
+// class's pengampu when one is set, otherwise any teacher of the subject.
+func (uc *gradebookUseCase) canGrade(class *schemas.Class, subjectId uuid.UUID, grader Grader) bool {
+	teacher, err := uc.repo.FindTeacherByUser(class.UnitId, grader.UserId)
+	if err != nil {
+		return false
+	}
+	if classSubject, err := uc.repo.FindClassSubject(class.Id, subjectId); err == nil && classSubject.TeacherProfileId != nil {
+		return *classSubject.TeacherProfileId == teacher.Id
+	}
+	teaches, err := uc.repo.TeachesSubject(teacher.Id, subjectId)
+	return err == nil && teaches
+}
+
+// canManage lets unit owners and admins set up assessments as well as the
+// teacher who grades them.
+func (uc *gradebookUseCase) canManage(class *schemas.Class, subjectId uuid.UUID, grader Grader) bool {
+	switch grader.Role {
+	case schemas.UnitMemberRoleOwner, schemas.UnitMemberRoleAdmin, "":
+		return true
+	}
+	return uc.canGrade(class, subjectId, grader)
+}
+
+// unlocked refuses changes to a term whose report cards have been finalized.
+func (uc *gradebookUseCase) unlocked(classId uuid.UUID, academicYear string, semester int) error {
+	locked, err := uc.repo.TermLocked(classId, academicYear, semester)
+	if err != nil {
+		return err
+	}
+	if locked {
+		return ErrGradesLocked
+	}
+	return nil
+}
+
+func (uc *gradebookUseCase) sheet(assessment *schemas.Assessment) (*ScoreSheet, error) {
+	enrollments, err := uc.repo.FindEnrollments(assessment.ClassId)
+	if err != nil {
+		return nil, err
+	}
+	scores, err := uc.repo.FindScores([]uuid.UUID{assessment.Id})
+	if err != nil {
+		return nil, err
+	}
+
+	entered := make(map[uuid.UUID]schemas.AssessmentScore, len(scores))
+	for _, score := range scores {
+		entered[score.ClassEnrollmentId] = score
+	}
+
+	view := &ScoreSheet{Assessment: *assessment, Students: []ScoreLine{}}
+	for _, enrollment := range enrollments {
+		score, ok := entered[enrollment.Id]
+		if !ok && enrollment.LeftAt != nil {
+			continue
+		}
+
+		line := ScoreLine{
+			ClassEnrollmentId: enrollment.Id,
+			StudentProfileId:  enrollment.StudentProfileId,
+		}
+		line.StudentName, line.NIS = student(enrollment)
+		if ok {
+			value := score.Score
+			recordedBy := score.RecordedBy
+			line.Score = &value
+			line.Note = score.Note
+			line.Version = score.Version
+			line.RecordedBy = &recordedBy
+		}
+		view.Students = append(view.Students, line)
+	}
+
+	sort.SliceStable(view.Students, func(i, j int) bool {
+		return view.Students[i].StudentName < view.Students[j].StudentName
+	})
+	return view, nil
+}
+
+// settings returns the unit's settings, or the defaults if they were never saved.
+func (uc *gradebookUseCase) settings(unitId uuid.UUID) (*schemas.UnitSettings, error) {
+	settings, err := uc.repo.FindSettings(unitId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &schemas.UnitSettings{UnitId: unitId, CurrentSemester: 1, GradeRounding: schemas.GradeRoundHalfUp}, nil
+	}
+	return settings, err
+}
+
+func (uc *gradebookUseCase) term(unitId uuid.UUID, academicYear string, semester int) (string, int, error) {
+	settings, err := uc.settings(unitId)
+	if err != nil {
+		return "", 0, err
+	}
+	return term(academicYear, semester, settings)
+}
+
+func term(academicYear string, semester int, settings *schemas.UnitSettings) (string, int, error) {
+	if academicYear == "" {
+		academicYear = settings.AcademicYear
+	}
+	if academicYear == "" {
+		return "", 0, errors.New("academic_year is required until the unit's current academic year is set")
+	}
+	if semester == 0 {
+		semester = settings.CurrentSemester
+	}
+	if semester == 0 {
+		semester = 1
+	}
+	if semester != 1 && semester != 2 {
+		return "", 0, errors.New("semester must be 1 or 2")
+	}
+	return academicYear, semester, nil
+}
+
+func validateAssessment(assessment *schemas.Assessment) error {
+	if !assessment.Type.IsValid() {
+		return fmt.Errorf("invalid assessment type %q", assessment.Type)
+	}
+	if assessment.Name == "" {
+		return errors.New("name is required")
+	}
+	if assessment.Weight <= 0 {
+		return errors.New("weight must be greater than 0")
+	}
+	if assessment.MaxScore <= 0 {
+		return errors.New("max_score must be greater than 0")
+	}
+	if assessment.Semester != 1 && assessment.Semester != 2 {
+		return errors.New("semester must be 1 or 2")
+	}
+	return nil
+}
+
+func student(enrollment schemas.ClassEnrollment) (string, *string) {
+	if enrollment.StudentProfile == nil {
+		return "", nil
+	}
+	name := ""
+	if enrollment.StudentProfile.User != nil {
+		name = enrollment.StudentProfile.User.FullName
+	}
+	return name, enrollment.StudentProfile.NIS
+}
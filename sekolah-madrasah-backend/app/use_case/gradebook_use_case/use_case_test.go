@@ -0,0 +1,572 @@
+package gradebook_use_case
+
+import (
+	"testing"
+	"time"
+
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockRepository is a mock implementation of GradebookRepository
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) FindAssessment(id uuid.UUID) (*schemas.Assessment, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.Assessment), args.Error(1)
+}
+
+func (m *MockRepository) FindAssessments(classId, subjectId uuid.UUID, academicYear string, semester int) ([]schemas.Assessment, error) {
+	args := m.Called(classId, subjectId, academicYear, semester)
+	return args.Get(0).([]schemas.Assessment), args.Error(1)
+}
+
+func (m *MockRepository) FindClassAssessments(classId uuid.UUID, academicYear string, semester int) ([]schemas.Assessment, error) {
+	args := m.Called(classId, academicYear, semester)
+	return args.Get(0).([]schemas.Assessment), args.Error(1)
+}
+
+func (m *MockRepository) CreateAssessment(assessment *schemas.Assessment) (*schemas.Assessment, error) {
+	args := m.Called(assessment)
+	return assessment, args.Error(0)
+}
+
+func (m *MockRepository) UpdateAssessment(assessment *schemas.Assessment) (*schemas.Assessment, error) {
+	args := m.Called(assessment)
+	return assessment, args.Error(0)
+}
+
+func (m *MockRepository) DeleteAssessment(id uuid.UUID) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindScores(assessmentIds []uuid.UUID) ([]schemas.AssessmentScore, error) {
+	args := m.Called(assessmentIds)
+	return args.Get(0).([]schemas.AssessmentScore), args.Error(1)
+}
+
+func (m *MockRepository) SaveScores(scores []schemas.AssessmentScore) ([]uuid.UUID, error) {
+	args := m.Called(scores)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockRepository) FindThresholds(unitId uuid.UUID) ([]schemas.GradeThreshold, error) {
+	args := m.Called(unitId)
+	return args.Get(0).([]schemas.GradeThreshold), args.Error(1)
+}
+
+func (m *MockRepository) FindThreshold(id uuid.UUID) (*schemas.GradeThreshold, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.GradeThreshold), args.Error(1)
+}
+
+func (m *MockRepository) CreateThreshold(threshold *schemas.GradeThreshold) (*schemas.GradeThreshold, error) {
+	args := m.Called(threshold)
+	return threshold, args.Error(0)
+}
+
+func (m *MockRepository) UpdateThreshold(threshold *schemas.GradeThreshold) (*schemas.GradeThreshold, error) {
+	args := m.Called(threshold)
+	return threshold, args.Error(0)
+}
+
+func (m *MockRepository) DeleteThreshold(id uuid.UUID) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindSettings(unitId uuid.UUID) (*schemas.UnitSettings, error) {
+	args := m.Called(unitId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.UnitSettings), args.Error(1)
+}
+
+func (m *MockRepository) FindClass(id uuid.UUID) (*schemas.Class, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.Class), args.Error(1)
+}
+
+func (m *MockRepository) FindSubject(id uuid.UUID) (*schemas.Subject, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.Subject), args.Error(1)
+}
+
+func (m *MockRepository) FindClassSubject(classId, subjectId uuid.UUID) (*schemas.ClassSubject, error) {
+	args := m.Called(classId, subjectId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.ClassSubject), args.Error(1)
+}
+
+func (m *MockRepository) FindTeacherByUser(unitId, userId uuid.UUID) (*schemas.TeacherProfile, error) {
+	args := m.Called(unitId, userId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.TeacherProfile), args.Error(1)
+}
+
+func (m *MockRepository) TeachesSubject(teacherId, subjectId uuid.UUID) (bool, error) {
+	args := m.Called(teacherId, subjectId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) TermLocked(classId uuid.UUID, academicYear string, semester int) (bool, error) {
+	args := m.Called(classId, academicYear, semester)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) FindEnrollments(classId uuid.UUID) ([]schemas.ClassEnrollment, error) {
+	args := m.Called(classId)
+	return args.Get(0).([]schemas.ClassEnrollment), args.Error(1)
+}
+
+type fixture struct {
+	repo       *MockRepository
+	uc         *gradebookUseCase
+	unitId     uuid.UUID
+	class      *schemas.Class
+	subject    *schemas.Subject
+	pengampu   *schemas.TeacherProfile // Assigned to the class for the subject
+	other      *schemas.TeacherProfile // Teaches the subject, but not to this class
+	assignment *schemas.ClassSubject
+	assessment *schemas.Assessment
+	ani, budi  schemas.ClassEnrollment
+	left       schemas.ClassEnrollment
+	locked     *mock.Call // Whether report cards froze the term, unlocked by default
+	thresholds *mock.Call // The unit's passing thresholds, none by default
+}
+
+// newFixture sets up a class with two current students and one who left, a
+// subject assigned to one of two teachers of it, and a daily test out of 50.
+func newFixture() *fixture {
+	unitId := uuid.New()
+	f := &fixture{
+		repo:     new(MockRepository),
+		unitId:   unitId,
+		class:    &schemas.Class{Id: uuid.New(), UnitId: unitId, Level: 7},
+		subject:  &schemas.Subject{Id: uuid.New(), UnitId: unitId, Name: "Matematika"},
+		pengampu: &schemas.TeacherProfile{Id: uuid.New(), UnitId: unitId, UserId: uuid.New()},
+		other:    &schemas.TeacherProfile{Id: uuid.New(), UnitId: unitId, UserId: uuid.New()},
+	}
+	f.uc = NewGradebookUseCase(f.repo).(*gradebookUseCase)
+	f.assessment = &schemas.Assessment{
+		Id: uuid.New(), UnitId: unitId, ClassId: f.class.Id, SubjectId: f.subject.Id,
+		AcademicYear: "2025/2026", Semester: 1, Type: schemas.AssessmentDaily, Name: "UH 1", Weight: 1, MaxScore: 50,
+	}
+	f.ani = enrollment(f.class.Id, "Ani", nil)
+	f.budi = enrollment(f.class.Id, "Budi", nil)
+	leftAt := day("2025-10-01")
+	f.left = enrollment(f.class.Id, "Citra", &leftAt)
+
+	f.repo.On("FindSettings", unitId).Return(&schemas.UnitSettings{
+		UnitId: unitId, AcademicYear: "2025/2026", CurrentSemester: 1, GradeRounding: schemas.GradeRoundHalfUp,
+	}, nil)
+	f.repo.On("FindClass", f.class.Id).Return(f.class, nil)
+	f.repo.On("FindSubject", f.subject.Id).Return(f.subject, nil)
+	f.repo.On("FindAssessment", f.assessment.Id).Return(f.assessment, nil)
+	f.assignment = &schemas.ClassSubject{ClassId: f.class.Id, SubjectId: f.subject.Id, TeacherProfileId: &f.pengampu.Id}
+	f.repo.On("FindClassSubject", f.class.Id, f.subject.Id).Return(f.assignment, nil)
+	f.repo.On("FindTeacherByUser", unitId, f.pengampu.UserId).Return(f.pengampu, nil)
+	f.repo.On("FindTeacherByUser", unitId, f.other.UserId).Return(f.other, nil)
+	f.repo.On("TeachesSubject", mock.Anything, f.subject.Id).Return(true, nil)
+	f.repo.On("FindEnrollments", f.class.Id).Return([]schemas.ClassEnrollment{f.budi, f.ani, f.left}, nil)
+	f.locked = f.repo.On("TermLocked", f.class.Id, "2025/2026", 1).Return(false, nil)
+	f.thresholds = f.repo.On("FindThresholds", unitId).Return([]schemas.GradeThreshold{}, nil)
+	return f
+}
+
+func enrollment(classId uuid.UUID, name string, leftAt *time.Time) schemas.ClassEnrollment {
+	return schemas.ClassEnrollment{
+		Id:               uuid.New(),
+		ClassId:          classId,
+		StudentProfileId: uuid.New(),
+		LeftAt:           leftAt,
+		StudentProfile:   &schemas.StudentProfile{User: &schemas.User{FullName: name}},
+	}
+}
+
+func day(value string) time.Time {
+	date, _ := time.Parse("2006-01-02", value)
+	return date
+}
+
+func grader(teacher *schemas.TeacherProfile) Grader {
+	return Grader{UserId: teacher.UserId, Role: schemas.UnitMemberRoleStaff}
+}
+
+// Tests
+
+func TestSaveScores_OnlyAssignedTeacher(t *testing.T) {
+	f := newFixture()
+	f.repo.On("SaveScores", mock.Anything).Return(nil, nil)
+	f.repo.On("FindScores", []uuid.UUID{f.assessment.Id}).Return([]schemas.AssessmentScore{}, nil)
+	scores := []ScoreRequest{{ClassEnrollmentId: f.ani.Id, Score: 42}}
+
+	_, err := f.uc.SaveScores(&SaveScoresRequest{UnitId: f.unitId, AssessmentId: f.assessment.Id, Scores: scores, Grader: grader(f.other)})
+	assert.ErrorIs(t, err, ErrNotSubjectTeacher)
+
+	admin := Grader{UserId: uuid.New(), Role: schemas.UnitMemberRoleAdmin}
+	f.repo.On("FindTeacherByUser", f.unitId, admin.UserId).Return(nil, gorm.ErrRecordNotFound)
+	_, err = f.uc.SaveScores(&SaveScoresRequest{UnitId: f.unitId, AssessmentId: f.assessment.Id, Scores: scores, Grader: admin})
+	assert.ErrorIs(t, err, ErrNotSubjectTeacher)
+	f.repo.AssertNotCalled(t, "SaveScores", mock.Anything)
+
+	_, err = f.uc.SaveScores(&SaveScoresRequest{UnitId: f.unitId, AssessmentId: f.assessment.Id, Scores: scores, Grader: grader(f.pengampu)})
+	assert.NoError(t, err)
+	f.repo.AssertCalled(t, "SaveScores", []schemas.AssessmentScore{{
+		AssessmentId: f.assessment.Id, ClassEnrollmentId: f.ani.Id, Score: 42, RecordedBy: f.pengampu.UserId,
+	}})
+}
+
+func TestSaveScores_AnySubjectTeacherWithoutPengampu(t *testing.T) {
+	f := newFixture()
+	f.assignment.TeacherProfileId = nil
+	f.repo.On("SaveScores", mock.Anything).Return(nil, nil)
+	f.repo.On("FindScores", []uuid.UUID{f.assessment.Id}).Return([]schemas.AssessmentScore{}, nil)
+
+	_, err := f.uc.SaveScores(&SaveScoresRequest{
+		UnitId: f.unitId, AssessmentId: f.assessment.Id, Grader: grader(f.other),
+		Scores: []ScoreRequest{{ClassEnrollmentId: f.budi.Id, Score: 30}},
+	})
+	assert.NoError(t, err)
+}
+
+func TestSaveScores_Validation(t *testing.T) {
+	f := newFixture()
+	save := func(scores ...ScoreRequest) error {
+		_, err := f.uc.SaveScores(&SaveScoresRequest{UnitId: f.unitId, AssessmentId: f.assessment.Id, Scores: scores, Grader: grader(f.pengampu)})
+		return err
+	}
+
+	assert.Error(t, save())
+	assert.Error(t, save(ScoreRequest{ClassEnrollmentId: f.ani.Id, Score: 51}))
+	assert.Error(t, save(ScoreRequest{ClassEnrollmentId: f.ani.Id, Score: -1}))
+	assert.Error(t, save(ScoreRequest{ClassEnrollmentId: f.left.Id, Score: 40}))
+	assert.Error(t, save(ScoreRequest{ClassEnrollmentId: uuid.New(), Score: 40}))
+	assert.Error(t, save(ScoreRequest{ClassEnrollmentId: f.ani.Id, Score: 40}, ScoreRequest{ClassEnrollmentId: f.ani.Id, Score: 45}))
+	f.repo.AssertNotCalled(t, "SaveScores", mock.Anything)
+}
+
+func TestSaveScores_StaleVersion(t *testing.T) {
+	f := newFixture()
+	f.repo.On("SaveScores", mock.Anything).Return([]uuid.UUID{f.budi.Id}, nil)
+
+	_, err := f.uc.SaveScores(&SaveScoresRequest{
+		UnitId: f.unitId, AssessmentId: f.assessment.Id, Grader: grader(f.pengampu),
+		Scores: []ScoreRequest{
+			{ClassEnrollmentId: f.ani.Id, Score: 40},
+			{ClassEnrollmentId: f.budi.Id, Score: 35, Version: 2},
+		},
+	})
+	assert.ErrorIs(t, err, ErrStaleScores)
+	staleErr, ok := err.(*StaleScoresError)
+	assert.True(t, ok)
+	assert.Equal(t, []uuid.UUID{f.budi.Id}, staleErr.ClassEnrollmentIds)
+}
+
+func TestSaveScores_LockedByReportCards(t *testing.T) {
+	f := newFixture()
+	f.locked.Return(true, nil)
+
+	_, err := f.uc.SaveScores(&SaveScoresRequest{
+		UnitId: f.unitId, AssessmentId: f.assessment.Id, Grader: grader(f.pengampu),
+		Scores: []ScoreRequest{{ClassEnrollmentId: f.ani.Id, Score: 40}},
+	})
+	assert.ErrorIs(t, err, ErrGradesLocked)
+	err = f.uc.DeleteAssessment(f.unitId, f.assessment.Id, Grader{Role: schemas.UnitMemberRoleAdmin})
+	assert.ErrorIs(t, err, ErrGradesLocked)
+	f.repo.AssertNotCalled(t, "SaveScores", mock.Anything)
+	f.repo.AssertNotCalled(t, "DeleteAssessment", mock.Anything)
+}
+
+func TestCreateAssessment_TermDefaultsAndValidation(t *testing.T) {
+	f := newFixture()
+	f.repo.On("CreateAssessment", mock.Anything).Return(nil)
+
+	assessment, err := f.uc.CreateAssessment(&AssessmentRequest{
+		UnitId: f.unitId, ClassId: f.class.Id, SubjectId: f.subject.Id,
+		Type: schemas.AssessmentMidterm, Name: " PTS Ganjil ", Weight: 2, Grader: grader(f.pengampu),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "2025/2026", assessment.AcademicYear)
+	assert.Equal(t, 1, assessment.Semester)
+	assert.Equal(t, "PTS Ganjil", assessment.Name)
+	assert.Equal(t, 100.0, assessment.MaxScore)
+
+	_, err = f.uc.CreateAssessment(&AssessmentRequest{
+		UnitId: f.unitId, ClassId: f.class.Id, SubjectId: f.subject.Id,
+		Type: schemas.AssessmentMidterm, Name: "PTS", Weight: 0, Grader: grader(f.pengampu),
+	})
+	assert.Error(t, err)
+
+	_, err = f.uc.CreateAssessment(&AssessmentRequest{
+		UnitId: f.unitId, ClassId: f.class.Id, SubjectId: f.subject.Id,
+		Type: schemas.AssessmentMidterm, Name: "PTS", Weight: 1, Grader: grader(f.other),
+	})
+	assert.ErrorIs(t, err, ErrNotSubjectTeacher)
+}
+
+func TestUpdateAssessment_MaxScoreBelowEnteredScore(t *testing.T) {
+	f := newFixture()
+	f.repo.On("FindScores", []uuid.UUID{f.assessment.Id}).Return([]schemas.AssessmentScore{{ClassEnrollmentId: f.ani.Id, Score: 45}}, nil)
+
+	maxScore := 40.0
+	_, err := f.uc.UpdateAssessment(f.unitId, f.assessment.Id, &UpdateAssessmentRequest{MaxScore: &maxScore, Grader: Grader{Role: schemas.UnitMemberRoleAdmin}})
+	assert.Error(t, err)
+	f.repo.AssertNotCalled(t, "UpdateAssessment", mock.Anything)
+}
+
+func TestRounding_Policies(t *testing.T) {
+	tests := []struct {
+		rounding Rounding
+		value    float64
+		want     float64
+	}{
+		{Rounding{schemas.GradeRoundHalfUp, 0}, 82.5, 83},
+		{Rounding{schemas.GradeRoundHalfUp, 0}, 82.49, 82},
+		{Rounding{schemas.GradeRoundDown, 0}, 82.9, 82},
+		{Rounding{schemas.GradeRoundUp, 0}, 82.1, 83},
+		{Rounding{schemas.GradeRoundUp, 0}, 82, 82},
+		{Rounding{schemas.GradeRoundHalfUp, 1}, 82.35, 82.4},
+		{Rounding{schemas.GradeRoundDown, 2}, 82.3, 82.3},
+	}
+	for _, tt := range tests {
+		assert.InDelta(t, tt.want, tt.rounding.Round(tt.value), 1e-9, "%s/%d %g", tt.rounding.Policy, tt.rounding.Decimals, tt.value)
+	}
+
+	assert.Equal(t, Rounding{schemas.GradeRoundHalfUp, 0}, RoundingFrom(&schemas.UnitSettings{}))
+}
+
+func TestWeightedAverage(t *testing.T) {
+	daily := schemas.Assessment{Id: uuid.New(), Weight: 1, MaxScore: 50}
+	midterm := schemas.Assessment{Id: uuid.New(), Weight: 2, MaxScore: 100}
+	final := schemas.Assessment{Id: uuid.New(), Weight: 3, MaxScore: 100}
+	assessments := []schemas.Assessment{daily, midterm, final}
+
+	// (45/50*100*1 + 70*2) / 3 = 76.67, the missing final is left out
+	average := WeightedAverage(assessments, map[uuid.UUID]float64{daily.Id: 45, midterm.Id: 70}, Rounding{schemas.GradeRoundHalfUp, 0})
+	assert.Equal(t, 77.0, *average)
+	average = WeightedAverage(assessments, map[uuid.UUID]float64{daily.Id: 45, midterm.Id: 70}, Rounding{schemas.GradeRoundDown, 1})
+	assert.Equal(t, 76.6, *average)
+
+	assert.Nil(t, WeightedAverage(assessments, map[uuid.UUID]float64{}, Rounding{}))
+}
+
+func TestGetGradebook_Matrix(t *testing.T) {
+	f := newFixture()
+	midterm := schemas.Assessment{Id: uuid.New(), ClassId: f.class.Id, SubjectId: f.subject.Id, Type: schemas.AssessmentMidterm, Weight: 2, MaxScore: 100}
+	f.repo.On("FindAssessments", f.class.Id, f.subject.Id, "2025/2026", 1).Return([]schemas.Assessment{*f.assessment, midterm}, nil)
+	f.repo.On("FindScores", []uuid.UUID{f.assessment.Id, midterm.Id}).Return([]schemas.AssessmentScore{
+		{AssessmentId: f.assessment.Id, ClassEnrollmentId: f.ani.Id, Score: 40},
+		{AssessmentId: midterm.Id, ClassEnrollmentId: f.ani.Id, Score: 85},
+		{AssessmentId: midterm.Id, ClassEnrollmentId: f.budi.Id, Score: 60},
+	}, nil)
+
+	book, err := f.uc.GetGradebook(f.unitId, f.class.Id, f.subject.Id, "", 0)
+	assert.NoError(t, err)
+	assert.Len(t, book.Assessments, 2)
+	// Citra left without a score and is not listed; the rest are sorted by name
+	assert.Len(t, book.Students, 2)
+
+	ani := book.Students[0]
+	assert.Equal(t, "Ani", ani.StudentName)
+	assert.Equal(t, 0, ani.Missing)
+	assert.Equal(t, 83.0, *ani.Average) // (80*1 + 85*2) / 3 = 83.33
+
+	budi := book.Students[1]
+	assert.Nil(t, budi.Scores[0])
+	assert.Equal(t, 60.0, *budi.Scores[1])
+	assert.Equal(t, 1, budi.Missing)
+	assert.Equal(t, 60.0, *budi.Average)
+}
+
+func threshold(unitId uuid.UUID, subjectId *uuid.UUID, level *int, minScore float64) schemas.GradeThreshold {
+	return schemas.GradeThreshold{
+		Id: uuid.New(), UnitId: unitId, SubjectId: subjectId, Level: level,
+		MinScore: minScore, PredicateA: 90, PredicateB: 80, PredicateC: minScore,
+	}
+}
+
+func TestThresholdFor_MostSpecificWins(t *testing.T) {
+	unitId, math, arabic := uuid.New(), uuid.New(), uuid.New()
+	seven := 7
+	rules := []schemas.GradeThreshold{
+		threshold(unitId, nil, nil, 70),
+		threshold(unitId, nil, &seven, 72),
+		threshold(unitId, &math, nil, 75),
+		threshold(unitId, &math, &seven, 78),
+	}
+
+	assert.Equal(t, 78.0, ThresholdFor(rules, math, 7).MinScore)
+	assert.Equal(t, 75.0, ThresholdFor(rules, math, 8).MinScore)
+	assert.Equal(t, 72.0, ThresholdFor(rules, arabic, 7).MinScore)
+	assert.Equal(t, 70.0, ThresholdFor(rules, arabic, 8).MinScore)
+	assert.Nil(t, ThresholdFor(rules[2:], arabic, 7))
+}
+
+func TestPredicate_Scale(t *testing.T) {
+	rule := threshold(uuid.New(), nil, nil, 75)
+	for score, want := range map[float64]string{100: "A", 90: "A", 89.9: "B", 80: "B", 75: "C", 74.99: "D", 0: "D"} {
+		assert.Equal(t, want, rule.Predicate(score), "%g", score)
+	}
+
+	average := 74.0
+	predicate, below := Mark(&rule, &average)
+	assert.Equal(t, "D", *predicate)
+	assert.True(t, below)
+	predicate, below = Mark(nil, &average)
+	assert.Nil(t, predicate)
+	assert.False(t, below)
+}
+
+func TestGetGradebook_MarksBelowThreshold(t *testing.T) {
+	f := newFixture()
+	f.thresholds.Return([]schemas.GradeThreshold{threshold(f.unitId, &f.subject.Id, nil, 75)}, nil)
+	f.repo.On("FindAssessments", f.class.Id, f.subject.Id, "2025/2026", 1).Return([]schemas.Assessment{*f.assessment}, nil)
+	f.repo.On("FindScores", []uuid.UUID{f.assessment.Id}).Return([]schemas.AssessmentScore{
+		{AssessmentId: f.assessment.Id, ClassEnrollmentId: f.ani.Id, Score: 46},
+		{AssessmentId: f.assessment.Id, ClassEnrollmentId: f.budi.Id, Score: 35},
+	}, nil)
+
+	book, err := f.uc.GetGradebook(f.unitId, f.class.Id, f.subject.Id, "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 75.0, book.Threshold.MinScore)
+	assert.Equal(t, 50.0, *book.PassRate)
+
+	ani, budi := book.Students[0], book.Students[1]
+	assert.Equal(t, "A", *ani.Predicate) // 46/50 = 92
+	assert.False(t, ani.BelowThreshold)
+	assert.Equal(t, "D", *budi.Predicate) // 35/50 = 70
+	assert.True(t, budi.BelowThreshold)
+}
+
+func TestGetPassRates_PerSubject(t *testing.T) {
+	f := newFixture()
+	arabic := &schemas.Subject{Id: uuid.New(), UnitId: f.unitId, Name: "Bahasa Arab"}
+	f.assessment.Subject = f.subject
+	quiz := schemas.Assessment{Id: uuid.New(), ClassId: f.class.Id, SubjectId: arabic.Id, Subject: arabic, Weight: 1, MaxScore: 100}
+	seven := 7
+	f.thresholds.Return([]schemas.GradeThreshold{threshold(f.unitId, nil, &seven, 70)}, nil)
+	f.repo.On("FindClassAssessments", f.class.Id, "2025/2026", 1).Return([]schemas.Assessment{*f.assessment, quiz}, nil)
+	f.repo.On("FindScores", []uuid.UUID{f.assessment.Id, quiz.Id}).Return([]schemas.AssessmentScore{
+		{AssessmentId: f.assessment.Id, ClassEnrollmentId: f.ani.Id, Score: 40},
+		{AssessmentId: f.assessment.Id, ClassEnrollmentId: f.budi.Id, Score: 30},
+		{AssessmentId: f.assessment.Id, ClassEnrollmentId: f.left.Id, Score: 20},
+		{AssessmentId: quiz.Id, ClassEnrollmentId: f.ani.Id, Score: 70},
+	}, nil)
+
+	rates, err := f.uc.GetPassRates(f.unitId, f.class.Id, "", 0)
+	assert.NoError(t, err)
+	assert.Len(t, rates.Subjects, 2)
+
+	// Sorted by name; Budi has no Arabic score and is not graded in it
+	arabicRate := rates.Subjects[0]
+	assert.Equal(t, "Bahasa Arab", arabicRate.SubjectName)
+	assert.Equal(t, 1, arabicRate.Graded)
+	assert.Equal(t, 100.0, *arabicRate.PassRate)
+	assert.Empty(t, arabicRate.BelowThreshold)
+
+	// Ani 80 passes; Budi 60 and Citra 40, who left after her score, do not
+	mathRate := rates.Subjects[1]
+	assert.Equal(t, 70.0, *mathRate.MinScore)
+	assert.Equal(t, 3, mathRate.Graded)
+	assert.Equal(t, 1, mathRate.Passed)
+	assert.Equal(t, 33.33, *mathRate.PassRate)
+	assert.Len(t, mathRate.BelowThreshold, 2)
+	assert.Equal(t, "Budi", mathRate.BelowThreshold[0].StudentName)
+	assert.Equal(t, 60.0, mathRate.BelowThreshold[0].Average)
+}
+
+func TestCreateThreshold_Validation(t *testing.T) {
+	f := newFixture()
+	f.repo.On("CreateThreshold", mock.Anything).Return(nil)
+	valid := func() *ThresholdRequest {
+		return &ThresholdRequest{UnitId: f.unitId, SubjectId: &f.subject.Id, MinScore: 75, PredicateA: 90, PredicateB: 80, PredicateC: 75}
+	}
+
+	created, err := f.uc.CreateThreshold(valid())
+	assert.NoError(t, err)
+	assert.Equal(t, f.subject.Id, *created.SubjectId)
+	assert.Nil(t, created.Level)
+
+	req := valid()
+	req.MinScore = 101
+	_, err = f.uc.CreateThreshold(req)
+	assert.Error(t, err)
+
+	req = valid()
+	req.PredicateB = 95
+	_, err = f.uc.CreateThreshold(req)
+	assert.Error(t, err)
+
+	req = valid()
+	level := 13
+	req.Level = &level
+	_, err = f.uc.CreateThreshold(req)
+	assert.Error(t, err)
+
+	otherSubject := uuid.New()
+	f.repo.On("FindSubject", otherSubject).Return(&schemas.Subject{Id: otherSubject, UnitId: uuid.New()}, nil)
+	req = valid()
+	req.SubjectId = &otherSubject
+	_, err = f.uc.CreateThreshold(req)
+	assert.ErrorIs(t, err, ErrSubjectNotFound)
+
+	f.thresholds.Return([]schemas.GradeThreshold{threshold(f.unitId, &f.subject.Id, nil, 70)}, nil)
+	_, err = f.uc.CreateThreshold(valid())
+	assert.ErrorIs(t, err, ErrThresholdExists)
+	f.repo.AssertNumberOfCalls(t, "CreateThreshold", 1)
+}
+
+func TestCreateThreshold_SavedConcurrently(t *testing.T) {
+	f := newFixture()
+	// Another rule for every subject and level was saved after the check
+	f.repo.On("CreateThreshold", mock.Anything).Return(gorm.ErrDuplicatedKey)
+
+	_, err := f.uc.CreateThreshold(&ThresholdRequest{UnitId: f.unitId, MinScore: 75, PredicateA: 90, PredicateB: 80, PredicateC: 75})
+
+	assert.ErrorIs(t, err, ErrThresholdExists)
+}
+
+func TestUpdateThreshold_KeepsItsOwnScope(t *testing.T) {
+	f := newFixture()
+	existing := threshold(f.unitId, &f.subject.Id, nil, 70)
+	f.thresholds.Return([]schemas.GradeThreshold{existing}, nil)
+	f.repo.On("FindThreshold", existing.Id).Return(&existing, nil)
+	f.repo.On("UpdateThreshold", mock.Anything).Return(nil)
+
+	updated, err := f.uc.UpdateThreshold(f.unitId, existing.Id, &ThresholdRequest{
+		SubjectId: &f.subject.Id, MinScore: 78, PredicateA: 92, PredicateB: 85, PredicateC: 78,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 78.0, updated.MinScore)
+
+	_, err = f.uc.UpdateThreshold(uuid.New(), existing.Id, &ThresholdRequest{MinScore: 78, PredicateA: 92, PredicateB: 85, PredicateC: 78})
+	assert.ErrorIs(t, err, ErrThresholdNotFound)
+}
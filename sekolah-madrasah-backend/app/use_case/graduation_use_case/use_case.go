@@ -0,0 +1,402 @@
+package graduation_use_case
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"sekolah-madrasah/app/repository/graduation_repository"
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrClassNotFound      = errors.New("class not found")
+	ErrNotFinalLevel      = errors.New("only classes of the final level can graduate")
+	ErrNoStudents         = errors.New("the class has no active students to graduate")
+	ErrEnrollmentNotFound = errors.New("enrollment is not an active student of this class")
+	ErrCertificateExists  = errors.New("certificate number is already issued")
+	ErrGraduationNotFound = errors.New("graduation not found")
+	ErrAlreadyGraduated   = errors.New("some of the students have already graduated meanwhile, reload the class and try again")
+)
+
+// MemberAction is what happens to a graduate's unit membership.
+type MemberAction string
+
+const (
+	MemberKeep       MemberAction = "keep"       // Stays a student member
+	MemberDowngrade  MemberAction = "downgrade"  // Becomes an alumni member
+	MemberDeactivate MemberAction = "deactivate" // Loses access to the unit
+)
+
+func (a MemberAction) IsValid() bool {
+	switch a {
+	case MemberKeep, MemberDowngrade, MemberDeactivate:
+		return true
+	}
+	return false
+}
+
+type GraduationUseCase interface {
+	// Graduate closes the enrollments of a final-level class as graduated,
+	// records each student's graduation, marks them as alumni and updates
+	// their memberships, in one transaction.
+	Graduate(req *GraduateRequest) (*GraduationResult, error)
+	// UpdateGraduation corrects a graduation, e.g. once the certificates are issued.
+	UpdateGraduation(req *UpdateGraduationRequest) (*Alumnus, error)
+	ListAlumni(unitId uuid.UUID, filter graduation_repository.AlumniFilter) ([]Alumnus, int64, error)
+	ListAlumniYears(unitId uuid.UUID) ([]graduation_repository.AlumniYear, error)
+}
+
+type GraduateRequest struct {
+	UnitId       uuid.UUID
+	ClassId      uuid.UUID
+	Date         *time.Time   // Defaults to today
+	MemberAction MemberAction // Defaults to keep
+	// Students who graduate, with their certificate; empty graduates every
+	// active student of the class.
+	Students []GraduateStudent
+}
+
+type GraduateStudent struct {
+	ClassEnrollmentId uuid.UUID
+	CertificateNumber *string
+	FinalScore        *float64
+	Notes             *string
+}
+
+type UpdateGraduationRequest struct {
+	UnitId            uuid.UUID
+	GraduationId      uuid.UUID
+	// Each field is kept when nil; an empty certificate number or notes clears it
+	GraduationDate    *time.Time
+	CertificateNumber *string
+	FinalScore        *float64
+	Notes             *string
+}
+
+// Alumnus is an entry of the alumni directory.
+type Alumnus struct {
+	GraduationId      uuid.UUID `json:"graduation_id"`
+	StudentProfileId  uuid.UUID `json:"student_profile_id"`
+	StudentName       string    `json:"student_name"`
+	NIS               *string   `json:"nis"`
+	NISN              *string   `json:"nisn"`
+	ClassId           uuid.UUID `json:"class_id"`
+	ClassName         string    `json:"class_name"`
+	AcademicYear      string    `json:"academic_year"`
+	GraduationDate    time.Time `json:"graduation_date"`
+	CertificateNumber *string   `json:"certificate_number"`
+	FinalScore        *float64  `json:"final_score"`
+	Notes             *string   `json:"notes"`
+}
+
+type GraduationResult struct {
+	ClassId        uuid.UUID    `json:"class_id"`
+	ClassName      string       `json:"class_name"`
+	AcademicYear   string       `json:"academic_year"`
+	GraduationDate time.Time    `json:"graduation_date"`
+	MemberAction   MemberAction `json:"member_action"`
+	Graduates      []Alumnus    `json:"graduates"`
+	MembersUpdated int          `json:"members_updated"`
+	Remaining      int          `json:"remaining"` // Active students of the class left out
+}
+
+type graduationUseCase struct {
+	repo graduation_repository.GraduationRepository
+	now  func() time.Time
+}
+
+func NewGraduationUseCase(repo graduation_repository.GraduationRepository) GraduationUseCase {
+	return &graduationUseCase{repo: repo, now: time.Now}
+}
+
+func (uc *graduationUseCase) Graduate(req *GraduateRequest) (*GraduationResult, error) {
+	action := req.MemberAction
+	if action == "" {
+		action = MemberKeep
+	}
+	if !action.IsValid() {
+		return nil, fmt.Errorf("invalid member action %q", action)
+	}
+
+	class, err := uc.repo.FindClass(req.UnitId, req.ClassId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrClassNotFound
+		}
+		return nil, err
+	}
+	finalLevel, err := uc.repo.FindFinalLevel(req.UnitId, class.AcademicYear)
+	if err != nil {
+		return nil, err
+	}
+	if class.Level < finalLevel {
+		return nil, ErrNotFinalLevel
+	}
+
+	date := uc.now()
+	if req.Date != nil {
+		date = *req.Date
+	}
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+
+	enrollments, err := uc.repo.FindActiveEnrollments(class.Id)
+	if err != nil {
+		return nil, err
+	}
+	if len(enrollments) == 0 {
+		return nil, ErrNoStudents
+	}
+	active := make(map[uuid.UUID]schemas.ClassEnrollment, len(enrollments))
+	for _, enrollment := range enrollments {
+		active[enrollment.Id] = enrollment
+	}
+
+	students := req.Students
+	if len(students) == 0 {
+		students = make([]GraduateStudent, 0, len(enrollments))
+		for _, enrollment := range enrollments {
+			students = append(students, GraduateStudent{ClassEnrollmentId: enrollment.Id})
+		}
+	}
+
+	seen := make(map[uuid.UUID]bool, len(students))
+	numbers := []string{}
+	byNumber := map[string]bool{}
+	graduations := make([]schemas.Graduation, 0, len(students))
+	closed := make([]schemas.ClassEnrollment, 0, len(students))
+	userIds := make([]uuid.UUID, 0, len(students))
+	for _, student := range students {
+		enrollment, ok := active[student.ClassEnrollmentId]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrEnrollmentNotFound, student.ClassEnrollmentId)
+		}
+		if seen[enrollment.Id] {
+			return nil, fmt.Errorf("enrollment %s is listed more than once", enrollment.Id)
+		}
+		seen[enrollment.Id] = true
+
+		certificate, err := certificateNumber(student.CertificateNumber)
+		if err != nil {
+			return nil, err
+		}
+		if certificate != nil {
+			if byNumber[*certificate] {
+				return nil, fmt.Errorf("%w: %s is given twice", ErrCertificateExists, *certificate)
+			}
+			byNumber[*certificate] = true
+			numbers = append(numbers, *certificate)
+		}
+		if err := validateScore(student.FinalScore); err != nil {
+			return nil, err
+		}
+
+		graduations = append(graduations, schemas.Graduation{
+			Id:                uuid.New(),
+			UnitId:            req.UnitId,
+			StudentProfileId:  enrollment.StudentProfileId,
+			ClassEnrollmentId: enrollment.Id,
+			ClassId:           class.Id,
+			AcademicYear:      class.AcademicYear,
+			GraduationDate:    date,
+			CertificateNumber: certificate,
+			FinalScore:        student.FinalScore,
+			Notes:             trimmed(student.Notes),
+			StudentProfile:    enrollment.StudentProfile,
+			Class:             class,
+		})
+
+		leftAt := date
+		note := fmt.Sprintf("Graduated from %s in %s", class.Name, class.AcademicYear)
+		enrollment.Status = schemas.EnrollmentStatusGraduated
+		enrollment.LeftAt = &leftAt
+		enrollment.Notes = &note
+		closed = append(closed, enrollment)
+
+		if enrollment.StudentProfile != nil {
+			userIds = append(userIds, enrollment.StudentProfile.UserId)
+		}
+	}
+
+	issued, err := uc.repo.FindCertificates(req.UnitId, numbers, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(issued) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrCertificateExists, strings.Join(issued, ", "))
+	}
+
+	var members []schemas.UnitMember
+	if action != MemberKeep {
+		members, err = uc.repo.FindMembers(req.UnitId, userIds)
+		if err != nil {
+			return nil, err
+		}
+		for i := range members {
+			if action == MemberDowngrade {
+				members[i].Role = schemas.UnitMemberRoleAlumni
+			} else {
+				members[i].IsActive = false
+			}
+		}
+	}
+
+	if err := uc.repo.Graduate(graduations, closed, members); err != nil {
+		switch {
+		case errors.Is(err, graduation_repository.ErrAlreadyGraduated):
+			return nil, ErrAlreadyGraduated
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			return nil, fmt.Errorf("%w: it was issued meanwhile", ErrCertificateExists)
+		}
+		return nil, err
+	}
+
+	result := &GraduationResult{
+		ClassId:        class.Id,
+		ClassName:      class.Name,
+		AcademicYear:   class.AcademicYear,
+		GraduationDate: date,
+		MemberAction:   action,
+		Graduates:      make([]Alumnus, 0, len(graduations)),
+		MembersUpdated: len(members),
+		Remaining:      len(enrollments) - len(graduations),
+	}
+	for _, graduation := range graduations {
+		result.Graduates = append(result.Graduates, alumnus(graduation))
+	}
+	sort.SliceStable(result.Graduates, func(i, j int) bool {
+		return result.Graduates[i].StudentName < result.Graduates[j].StudentName
+	})
+	return result, nil
+}
+
+func (uc *graduationUseCase) UpdateGraduation(req *UpdateGraduationRequest) (*Alumnus, error) {
+	graduation, err := uc.repo.FindGraduation(req.GraduationId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGraduationNotFound
+		}
+		return nil, err
+	}
+	if graduation.UnitId != req.UnitId {
+		return nil, ErrGraduationNotFound
+	}
+
+	certificate, err := certificateNumber(req.CertificateNumber)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateScore(req.FinalScore); err != nil {
+		return nil, err
+	}
+	if certificate != nil {
+		issued, err := uc.repo.FindCertificates(req.UnitId, []string{*certificate}, &graduation.Id)
+		if err != nil {
+			return nil, err
+		}
+		if len(issued) > 0 {
+			return nil, fmt.Errorf("%w: %s", ErrCertificateExists, *certificate)
+		}
+	}
+
+	if req.GraduationDate != nil {
+		date := *req.GraduationDate
+		graduation.GraduationDate = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	}
+	if req.CertificateNumber != nil {
+		graduation.CertificateNumber = certificate
+	}
+	if req.FinalScore != nil {
+		graduation.FinalScore = req.FinalScore
+	}
+	if req.Notes != nil {
+		graduation.Notes = trimmed(req.Notes)
+	}
+	if err := uc.repo.UpdateGraduation(graduation); err != nil {
+		return nil, err
+	}
+
+	entry := alumnus(*graduation)
+	return &entry, nil
+}
+
+func (uc *graduationUseCase) ListAlumni(unitId uuid.UUID, filter graduation_repository.AlumniFilter) ([]Alumnus, int64, error) {
+	filter.AcademicYear = strings.TrimSpace(filter.AcademicYear)
+	filter.Search = strings.TrimSpace(filter.Search)
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 {
+		filter.Limit = 10
+	}
+
+	graduations, total, err := uc.repo.FindAlumni(unitId, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	alumni := make([]Alumnus, 0, len(graduations))
+	for _, graduation := range graduations {
+		alumni = append(alumni, alumnus(graduation))
+	}
+	return alumni, total, nil
+}
+
+func (uc *graduationUseCase) ListAlumniYears(unitId uuid.UUID) ([]graduation_repository.AlumniYear, error) {
+	return uc.repo.FindAlumniYears(unitId)
+}
+
+func alumnus(graduation schemas.Graduation) Alumnus {
+	entry := Alumnus{
+		GraduationId:      graduation.Id,
+		StudentProfileId:  graduation.StudentProfileId,
+		ClassId:           graduation.ClassId,
+		AcademicYear:      graduation.AcademicYear,
+		GraduationDate:    graduation.GraduationDate,
+		CertificateNumber: graduation.CertificateNumber,
+		FinalScore:        graduation.FinalScore,
+		Notes:             graduation.Notes,
+	}
+	if profile := graduation.StudentProfile; profile != nil {
+		entry.NIS = profile.NIS
+		entry.NISN = profile.NISN
+		if profile.User != nil {
+			entry.StudentName = profile.User.FullName
+		}
+	}
+	if graduation.Class != nil {
+		entry.ClassName = graduation.Class.Name
+	}
+	return entry
+}
+
+// certificateNumber trims the certificate number; a blank one is no number yet.
+func certificateNumber(number *string) (*string, error) {
+	number = trimmed(number)
+	if number != nil && len(*number) > 50 {
+		return nil, errors.New("certificate_number must be at most 50 characters")
+	}
+	return number, nil
+}
+
+func validateScore(score *float64) error {
+	if score != nil && (*score < 0 || *score > 100) {
+		return errors.New("final_score must be between 0 and 100")
+	}
+	return nil
+}
+
+func trimmed(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	text := strings.TrimSpace(*value)
+	if text == "" {
+		return nil
+	}
+	return &text
+}
@@ -0,0 +1,298 @@
+package graduation_use_case
+
+import (
+	"testing"
+	"time"
+
+	"sekolah-madrasah/app/repository/graduation_repository"
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockRepository is a mock implementation of GraduationRepository
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) FindClass(unitId, classId uuid.UUID) (*schemas.Class, error) {
+	args := m.Called(unitId, classId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.Class), args.Error(1)
+}
+
+func (m *MockRepository) FindFinalLevel(unitId uuid.UUID, academicYear string) (int, error) {
+	args := m.Called(unitId, academicYear)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRepository) FindActiveEnrollments(classId uuid.UUID) ([]schemas.ClassEnrollment, error) {
+	args := m.Called(classId)
+	return args.Get(0).([]schemas.ClassEnrollment), args.Error(1)
+}
+
+func (m *MockRepository) FindMembers(unitId uuid.UUID, userIds []uuid.UUID) ([]schemas.UnitMember, error) {
+	args := m.Called(unitId, userIds)
+	return args.Get(0).([]schemas.UnitMember), args.Error(1)
+}
+
+func (m *MockRepository) FindCertificates(unitId uuid.UUID, numbers []string, exceptId *uuid.UUID) ([]string, error) {
+	args := m.Called(unitId, numbers, exceptId)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockRepository) Graduate(graduations []schemas.Graduation, closed []schemas.ClassEnrollment, members []schemas.UnitMember) error {
+	args := m.Called(graduations, closed, members)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindGraduation(id uuid.UUID) (*schemas.Graduation, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.Graduation), args.Error(1)
+}
+
+func (m *MockRepository) UpdateGraduation(graduation *schemas.Graduation) error {
+	args := m.Called(graduation)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindAlumni(unitId uuid.UUID, filter graduation_repository.AlumniFilter) ([]schemas.Graduation, int64, error) {
+	args := m.Called(unitId, filter)
+	return args.Get(0).([]schemas.Graduation), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockRepository) FindAlumniYears(unitId uuid.UUID) ([]graduation_repository.AlumniYear, error) {
+	args := m.Called(unitId)
+	return args.Get(0).([]graduation_repository.AlumniYear), args.Error(1)
+}
+
+type fixture struct {
+	repo         *MockRepository
+	uc           *graduationUseCase
+	unitId       uuid.UUID
+	ixA, viiiA   schemas.Class
+	ani, budi    schemas.ClassEnrollment
+	certificates *mock.Call // Certificate numbers already issued, none by default
+}
+
+// newFixture sets up an SMP in 2025/2026 whose final level 9 class IX A has
+// Ani and Budi still active, next to the level 8 class VIII A.
+func newFixture() *fixture {
+	unitId := uuid.New()
+	f := &fixture{
+		repo:   new(MockRepository),
+		unitId: unitId,
+		ixA:    schemas.Class{Id: uuid.New(), UnitId: unitId, Name: "IX A", Level: 9, AcademicYear: "2025/2026"},
+		viiiA:  schemas.Class{Id: uuid.New(), UnitId: unitId, Name: "VIII A", Level: 8, AcademicYear: "2025/2026"},
+	}
+	f.uc = NewGraduationUseCase(f.repo).(*graduationUseCase)
+	f.uc.now = func() time.Time { return time.Date(2026, 6, 20, 10, 0, 0, 0, time.UTC) }
+	f.ani = enrollment(f.ixA, "Ani")
+	f.budi = enrollment(f.ixA, "Budi")
+
+	f.repo.On("FindClass", unitId, f.ixA.Id).Return(&f.ixA, nil)
+	f.repo.On("FindClass", unitId, f.viiiA.Id).Return(&f.viiiA, nil)
+	f.repo.On("FindClass", unitId, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+	f.repo.On("FindFinalLevel", unitId, "2025/2026").Return(9, nil)
+	f.repo.On("FindActiveEnrollments", f.ixA.Id).Return([]schemas.ClassEnrollment{f.budi, f.ani}, nil)
+	f.certificates = f.repo.On("FindCertificates", unitId, mock.Anything, mock.Anything).Return([]string{}, nil)
+	return f
+}
+
+func enrollment(class schemas.Class, name string) schemas.ClassEnrollment {
+	return schemas.ClassEnrollment{
+		Id:               uuid.New(),
+		StudentProfileId: uuid.New(),
+		ClassId:          class.Id,
+		AcademicYear:     class.AcademicYear,
+		Status:           schemas.EnrollmentStatusActive,
+		StudentProfile:   &schemas.StudentProfile{UserId: uuid.New(), User: &schemas.User{FullName: name}},
+	}
+}
+
+func text(value string) *string { return &value }
+
+// Tests
+
+func TestGraduate_WholeClass(t *testing.T) {
+	f := newFixture()
+	var graduations []schemas.Graduation
+	var closed []schemas.ClassEnrollment
+	f.repo.On("Graduate", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		graduations = args.Get(0).([]schemas.Graduation)
+		closed = args.Get(1).([]schemas.ClassEnrollment)
+	}).Return(nil)
+
+	result, err := f.uc.Graduate(&GraduateRequest{UnitId: f.unitId, ClassId: f.ixA.Id})
+	assert.NoError(t, err)
+	assert.Equal(t, MemberKeep, result.MemberAction)
+	assert.Equal(t, time.Date(2026, 6, 20, 0, 0, 0, 0, time.UTC), result.GraduationDate)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Len(t, result.Graduates, 2)
+	assert.Equal(t, "Ani", result.Graduates[0].StudentName)
+	assert.Equal(t, "IX A", result.Graduates[0].ClassName)
+
+	assert.Len(t, graduations, 2)
+	assert.Equal(t, "2025/2026", graduations[0].AcademicYear)
+	assert.Nil(t, graduations[0].CertificateNumber)
+	for _, enrollment := range closed {
+		assert.Equal(t, schemas.EnrollmentStatusGraduated, enrollment.Status)
+		assert.Equal(t, result.GraduationDate, *enrollment.LeftAt)
+	}
+
+	// Memberships are left alone by default
+	f.repo.AssertNotCalled(t, "FindMembers", mock.Anything, mock.Anything)
+}
+
+func TestGraduate_SelectedStudentsWithCertificates(t *testing.T) {
+	f := newFixture()
+	var graduations []schemas.Graduation
+	f.repo.On("Graduate", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		graduations = args.Get(0).([]schemas.Graduation)
+	}).Return(nil)
+	score := 87.5
+	date := time.Date(2026, 6, 15, 0, 0, 0, 0, time.UTC)
+
+	result, err := f.uc.Graduate(&GraduateRequest{
+		UnitId:   f.unitId,
+		ClassId:  f.ixA.Id,
+		Date:     &date,
+		Students: []GraduateStudent{{ClassEnrollmentId: f.ani.Id, CertificateNumber: text(" DN-01/D-SMP/13/0001 "), FinalScore: &score}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Remaining)
+	assert.Len(t, graduations, 1)
+	assert.Equal(t, f.ani.StudentProfileId, graduations[0].StudentProfileId)
+	assert.Equal(t, "DN-01/D-SMP/13/0001", *graduations[0].CertificateNumber)
+	assert.Equal(t, 87.5, *graduations[0].FinalScore)
+	assert.Equal(t, date, graduations[0].GraduationDate)
+}
+
+func TestGraduate_MemberActions(t *testing.T) {
+	graduate := func(action MemberAction) (*GraduationResult, []schemas.UnitMember) {
+		f := newFixture()
+		member := schemas.UnitMember{Id: uuid.New(), UserId: f.ani.StudentProfile.UserId, UnitId: f.unitId, Role: schemas.UnitMemberRoleAnggota, IsActive: true}
+		f.repo.On("FindMembers", f.unitId, mock.Anything).Return([]schemas.UnitMember{member}, nil)
+		var members []schemas.UnitMember
+		f.repo.On("Graduate", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			members = args.Get(2).([]schemas.UnitMember)
+		}).Return(nil)
+
+		result, err := f.uc.Graduate(&GraduateRequest{UnitId: f.unitId, ClassId: f.ixA.Id, MemberAction: action})
+		assert.NoError(t, err)
+		return result, members
+	}
+
+	result, members := graduate(MemberDowngrade)
+	assert.Equal(t, 1, result.MembersUpdated)
+	assert.Equal(t, schemas.UnitMemberRoleAlumni, members[0].Role)
+	assert.True(t, members[0].IsActive)
+
+	_, members = graduate(MemberDeactivate)
+	assert.Equal(t, schemas.UnitMemberRoleAnggota, members[0].Role)
+	assert.False(t, members[0].IsActive)
+}
+
+func TestGraduate_Refusals(t *testing.T) {
+	f := newFixture()
+
+	_, err := f.uc.Graduate(&GraduateRequest{UnitId: f.unitId, ClassId: f.viiiA.Id})
+	assert.ErrorIs(t, err, ErrNotFinalLevel)
+
+	_, err = f.uc.Graduate(&GraduateRequest{UnitId: f.unitId, ClassId: uuid.New()})
+	assert.ErrorIs(t, err, ErrClassNotFound)
+
+	_, err = f.uc.Graduate(&GraduateRequest{UnitId: f.unitId, ClassId: f.ixA.Id, MemberAction: "remove"})
+	assert.Error(t, err)
+
+	_, err = f.uc.Graduate(&GraduateRequest{UnitId: f.unitId, ClassId: f.ixA.Id, Students: []GraduateStudent{{ClassEnrollmentId: uuid.New()}}})
+	assert.ErrorIs(t, err, ErrEnrollmentNotFound)
+
+	score := 101.0
+	_, err = f.uc.Graduate(&GraduateRequest{UnitId: f.unitId, ClassId: f.ixA.Id, Students: []GraduateStudent{{ClassEnrollmentId: f.ani.Id, FinalScore: &score}}})
+	assert.Error(t, err)
+
+	_, err = f.uc.Graduate(&GraduateRequest{UnitId: f.unitId, ClassId: f.ixA.Id, Students: []GraduateStudent{
+		{ClassEnrollmentId: f.ani.Id, CertificateNumber: text("0001")},
+		{ClassEnrollmentId: f.budi.Id, CertificateNumber: text("0001")},
+	}})
+	assert.ErrorIs(t, err, ErrCertificateExists)
+
+	f.certificates.Return([]string{"0001"}, nil)
+	_, err = f.uc.Graduate(&GraduateRequest{UnitId: f.unitId, ClassId: f.ixA.Id, Students: []GraduateStudent{{ClassEnrollmentId: f.ani.Id, CertificateNumber: text("0001")}}})
+	assert.ErrorIs(t, err, ErrCertificateExists)
+
+	f.repo.AssertNotCalled(t, "Graduate", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGraduate_GraduatedMeanwhile(t *testing.T) {
+	f := newFixture()
+	f.repo.On("Graduate", mock.Anything, mock.Anything, mock.Anything).Return(graduation_repository.ErrAlreadyGraduated).Once()
+
+	_, err := f.uc.Graduate(&GraduateRequest{UnitId: f.unitId, ClassId: f.ixA.Id})
+	assert.ErrorIs(t, err, ErrAlreadyGraduated)
+
+	f.repo.On("Graduate", mock.Anything, mock.Anything, mock.Anything).Return(gorm.ErrDuplicatedKey).Once()
+	_, err = f.uc.Graduate(&GraduateRequest{UnitId: f.unitId, ClassId: f.ixA.Id, Students: []GraduateStudent{{ClassEnrollmentId: f.ani.Id, CertificateNumber: text("0001")}}})
+	assert.ErrorIs(t, err, ErrCertificateExists)
+}
+
+func TestUpdateGraduation(t *testing.T) {
+	f := newFixture()
+	graduation := &schemas.Graduation{
+		Id:               uuid.New(),
+		UnitId:           f.unitId,
+		StudentProfileId: f.ani.StudentProfileId,
+		ClassId:          f.ixA.Id,
+		AcademicYear:     "2025/2026",
+		GraduationDate:   time.Date(2026, 6, 20, 0, 0, 0, 0, time.UTC),
+		StudentProfile:   f.ani.StudentProfile,
+		Class:            &f.ixA,
+	}
+	f.repo.On("FindGraduation", graduation.Id).Return(graduation, nil)
+	f.repo.On("FindGraduation", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+	f.repo.On("UpdateGraduation", graduation).Return(nil)
+
+	entry, err := f.uc.UpdateGraduation(&UpdateGraduationRequest{UnitId: f.unitId, GraduationId: graduation.Id, CertificateNumber: text("0002")})
+	assert.NoError(t, err)
+	assert.Equal(t, "0002", *entry.CertificateNumber)
+	assert.Equal(t, "Ani", entry.StudentName)
+	f.repo.AssertCalled(t, "FindCertificates", f.unitId, []string{"0002"}, &graduation.Id)
+
+	score := 91.5
+	entry, err = f.uc.UpdateGraduation(&UpdateGraduationRequest{UnitId: f.unitId, GraduationId: graduation.Id, FinalScore: &score})
+	assert.NoError(t, err)
+	assert.Equal(t, "0002", *entry.CertificateNumber, "omitted fields are kept")
+	assert.Equal(t, 91.5, *entry.FinalScore)
+
+	_, err = f.uc.UpdateGraduation(&UpdateGraduationRequest{UnitId: f.unitId, GraduationId: graduation.Id, CertificateNumber: text("")})
+	assert.NoError(t, err)
+	assert.Nil(t, graduation.CertificateNumber, "an empty certificate number clears it")
+
+	_, err = f.uc.UpdateGraduation(&UpdateGraduationRequest{UnitId: uuid.New(), GraduationId: graduation.Id})
+	assert.ErrorIs(t, err, ErrGraduationNotFound)
+
+	_, err = f.uc.UpdateGraduation(&UpdateGraduationRequest{UnitId: f.unitId, GraduationId: uuid.New()})
+	assert.ErrorIs(t, err, ErrGraduationNotFound)
+}
+
+func TestListAlumni_Defaults(t *testing.T) {
+	f := newFixture()
+	graduation := schemas.Graduation{Id: uuid.New(), AcademicYear: "2025/2026", StudentProfile: f.ani.StudentProfile, Class: &f.ixA}
+	f.repo.On("FindAlumni", f.unitId, graduation_repository.AlumniFilter{AcademicYear: "2025/2026", Search: "ani", Page: 1, Limit: 10}).
+		Return([]schemas.Graduation{graduation}, int64(1), nil)
+
+	alumni, total, err := f.uc.ListAlumni(f.unitId, graduation_repository.AlumniFilter{AcademicYear: " 2025/2026", Search: "ani "})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, "Ani", alumni[0].StudentName)
+	assert.Equal(t, "IX A", alumni[0].ClassName)
+}
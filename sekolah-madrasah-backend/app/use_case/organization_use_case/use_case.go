@@ -8,21 +8,26 @@ import (
 	"github.com/google/uuid"
 	"sekolah-madrasah/app/repository/org_member_repository"
 	"sekolah-madrasah/app/repository/organization_repository"
+	"sekolah-madrasah/app/repository/role_repository"
+	"sekolah-madrasah/database/schemas"
 	"sekolah-madrasah/pkg/paginate_utils"
 )
 
 type organizationUseCase struct {
 	orgRepo       organization_repository.OrganizationRepository
 	memberRepo    org_member_repository.OrgMemberRepository
+	roleRepo      role_repository.RoleRepository
 }
 
 func NewOrganizationUseCase(
 	orgRepo organization_repository.OrganizationRepository,
 	memberRepo org_member_repository.OrgMemberRepository,
+	roleRepo role_repository.RoleRepository,
 ) OrganizationUseCase {
 	return &organizationUseCase{
 		orgRepo:    orgRepo,
 		memberRepo: memberRepo,
+		roleRepo:   roleRepo,
 	}
 }
 
@@ -182,6 +187,9 @@ func (u *organizationUseCase) AddMember(ctx context.Context, orgId uuid.UUID, re
 	if code == http.StatusOK && existingMember.Id != uuid.Nil {
 		return OrganizationMember{}, http.StatusConflict, errors.New("user is already a member of this organization")
 	}
+	if code, err := u.checkMemberRole(ctx, orgId, req.RoleId); err != nil {
+		return OrganizationMember{}, code, err
+	}
 
 	newMember := org_member_repository.OrganizationMember{
 		UserId:         req.UserId,
@@ -207,6 +215,11 @@ func (u *organizationUseCase) UpdateMember(ctx context.Context, orgId uuid.UUID,
 	if err != nil {
 		return code, err
 	}
+	if req.RoleId != uuid.Nil {
+		if code, err := u.checkMemberRole(ctx, orgId, req.RoleId); err != nil {
+			return code, err
+		}
+	}
 
 	updateData := org_member_repository.OrganizationMember{
 		RoleId:   req.RoleId,
@@ -219,6 +232,26 @@ func (u *organizationUseCase) UpdateMember(ctx context.Context, orgId uuid.UUID,
 	}, updateData)
 }
 
+// checkMemberRole refuses roles a member of the organization cannot hold: the
+// roles of other organizations and the global Super Admin role, which would
+// grant every permission on the whole platform.
+func (u *organizationUseCase) checkMemberRole(ctx context.Context, orgId, roleId uuid.UUID) (int, error) {
+	role, code, err := u.roleRepo.GetRole(ctx, role_repository.RoleFilter{Id: &roleId})
+	if err != nil {
+		if code == http.StatusNotFound {
+			return http.StatusBadRequest, errors.New("role not found")
+		}
+		return code, err
+	}
+	if role.OrganizationId != nil && *role.OrganizationId != orgId {
+		return http.StatusBadRequest, errors.New("role not found")
+	}
+	if role.OrganizationId == nil && role.Name == schemas.SuperAdminRoleName {
+		return http.StatusForbidden, errors.New("the Super Admin role cannot be given to organization members")
+	}
+	return http.StatusOK, nil
+}
+
 func (u *organizationUseCase) RemoveMember(ctx context.Context, orgId uuid.UUID, userId uuid.UUID) (int, error) {
 	_, code, err := u.memberRepo.GetMember(ctx, org_member_repository.OrgMemberFilter{
 		UserId:         &userId,
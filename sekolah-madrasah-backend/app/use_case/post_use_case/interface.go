@@ -19,8 +19,14 @@ type PostUseCase interface {
 	// Comments
 	GetComments(ctx context.Context, postId uuid.UUID, paginate *paginate_utils.PaginateData) ([]PostCommentDTO, int, error)
 	CreateComment(ctx context.Context, dto CreateCommentDTO, authorId uuid.UUID) (PostCommentDTO, int, error)
-	DeleteComment(ctx context.Context, commentId uuid.UUID, userId uuid.UUID) (int, error)
+	// DeleteComment lets the author remove their comment, and moderators holding posts.delete any comment.
+	DeleteComment(ctx context.Context, postId, commentId uuid.UUID, userId uuid.UUID) (int, error)
 
 	// Poll
 	VotePoll(ctx context.Context, dto VotePollDTO, userId uuid.UUID) (int, error)
 }
+
+// PermissionChecker tells whether a user may moderate other people's comments.
+type PermissionChecker interface {
+	HasPermission(userID string, permission string) bool
+}
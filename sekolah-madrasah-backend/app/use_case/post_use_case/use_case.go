@@ -12,15 +12,20 @@ import (
 	"github.com/google/uuid"
 )
 
+// moderatePermission lets a user delete comments written by others.
+const moderatePermission = "posts.delete"
+
 type postUseCase struct {
-	postRepo post_repository.PostRepository
-	userRepo user_repository.UserRepository
+	postRepo    post_repository.PostRepository
+	userRepo    user_repository.UserRepository
+	permissions PermissionChecker
 }
 
-func NewPostUseCase(postRepo post_repository.PostRepository, userRepo user_repository.UserRepository) PostUseCase {
+func NewPostUseCase(postRepo post_repository.PostRepository, userRepo user_repository.UserRepository, permissions PermissionChecker) PostUseCase {
 	return &postUseCase{
-		postRepo: postRepo,
-		userRepo: userRepo,
+		postRepo:    postRepo,
+		userRepo:    userRepo,
+		permissions: permissions,
 	}
 }
 
@@ -257,17 +262,18 @@ func (u *postUseCase) CreateComment(ctx context.Context, dto CreateCommentDTO, a
 	return result, code, nil
 }
 
-func (u *postUseCase) DeleteComment(ctx context.Context, commentId uuid.UUID, userId uuid.UUID) (int, error) {
+func (u *postUseCase) DeleteComment(ctx context.Context, postId, commentId uuid.UUID, userId uuid.UUID) (int, error) {
 	// Get comment first
 	comment, code, err := u.postRepo.GetComment(ctx, post_repository.PostCommentFilter{
-		Id: &commentId,
+		Id:     &commentId,
+		PostId: &postId,
 	})
 	if err != nil {
 		return code, err
 	}
 
-	// Only author can delete
-	if comment.AuthorId != userId {
+	// Only the author or a moderator can delete
+	if comment.AuthorId != userId && (u.permissions == nil || !u.permissions.HasPermission(userId.String(), moderatePermission)) {
 		return http.StatusForbidden, errors.New("you can only delete your own comments")
 	}
 
@@ -291,6 +297,18 @@ func (u *postUseCase) DeleteComment(ctx context.Context, commentId uuid.UUID, us
 // ========== Poll ==========
 
 func (u *postUseCase) VotePoll(ctx context.Context, dto VotePollDTO, userId uuid.UUID) (int, error) {
+	// The option must belong to the poll being voted on
+	options, code, err := u.postRepo.GetPollOptions(ctx, post_repository.PostPollOptionFilter{
+		Id:     &dto.OptionId,
+		PostId: &dto.PostId,
+	})
+	if err != nil {
+		return code, err
+	}
+	if len(options) == 0 {
+		return http.StatusNotFound, errors.New("poll option not found")
+	}
+
 	// Check if already voted
 	existingVote, _ := u.postRepo.GetUserVoteForPost(ctx, dto.PostId, userId)
 	if existingVote != nil {
@@ -308,7 +326,7 @@ func (u *postUseCase) VotePoll(ctx context.Context, dto VotePollDTO, userId uuid
 		UserId:   userId,
 	}
 
-	_, code, err := u.postRepo.CreatePollVote(ctx, vote)
+	_, code, err = u.postRepo.CreatePollVote(ctx, vote)
 	if err != nil {
 		return code, err
 	}
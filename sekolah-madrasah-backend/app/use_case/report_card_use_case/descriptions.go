@@ -0,0 +1,90 @@
+package report_card_use_case
+
+import (
+	"strings"
+
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+)
+
+const (
+	placeholderName       = "{nama}"
+	placeholderCompetency = "{kompetensi}"
+)
+
+// describe writes a subject's competency description (capaian kompetensi) in
+// the Kurikulum Merdeka style: the competencies the student mastered best,
+// then those needing help. Each competency scores the average of its
+// assessments out of 100; assessments without a competency are left out, and
+// with no competency assessed the description stays empty.
+func describe(template *schemas.ReportCardTemplate, studentName string, assessments []schemas.Assessment, scores map[uuid.UUID]float64) string {
+	var order []string
+	totals := map[string]float64{}
+	counts := map[string]int{}
+	for _, assessment := range assessments {
+		score, ok := scores[assessment.Id]
+		if !ok || assessment.Competency == nil || assessment.MaxScore <= 0 {
+			continue
+		}
+		competency := strings.TrimSpace(*assessment.Competency)
+		if competency == "" {
+			continue
+		}
+		if counts[competency] == 0 {
+			order = append(order, competency)
+		}
+		totals[competency] += score / assessment.MaxScore * 100
+		counts[competency]++
+	}
+	if len(order) == 0 {
+		return ""
+	}
+
+	averages := make(map[string]float64, len(order))
+	highest, lowest := -1.0, 101.0
+	for _, competency := range order {
+		average := totals[competency] / float64(counts[competency])
+		averages[competency] = average
+		if average > highest {
+			highest = average
+		}
+		if average < lowest {
+			lowest = average
+		}
+	}
+
+	var best, weakest []string
+	for _, competency := range order {
+		switch averages[competency] {
+		case highest:
+			best = append(best, competency)
+		case lowest:
+			weakest = append(weakest, competency)
+		}
+	}
+
+	description := fill(template.DescriptionHigh, studentName, best)
+	if len(weakest) > 0 {
+		description += " " + fill(template.DescriptionLow, studentName, weakest)
+	}
+	return description
+}
+
+func fill(text, studentName string, competencies []string) string {
+	text = strings.ReplaceAll(text, placeholderName, studentName)
+	return strings.ReplaceAll(text, placeholderCompetency, joinList(competencies))
+}
+
+// joinList joins items the Indonesian way: "a", "a dan b", "a, b, dan c".
+func joinList(items []string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	case 2:
+		return items[0] + " dan " + items[1]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + ", dan " + items[len(items)-1]
+}
@@ -0,0 +1,245 @@
+package report_card_use_case
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"sekolah-madrasah/database/schemas"
+	"sekolah-madrasah/pkg/pdf_utils"
+)
+
+var monthNames = [...]string{
+	"Januari", "Februari", "Maret", "April", "Mei", "Juni",
+	"Juli", "Agustus", "September", "Oktober", "November", "Desember",
+}
+
+const (
+	margin     = 50.0
+	bodySize   = 10.0
+	lineHeight = 13.0
+	cellPad    = 4.0
+)
+
+// reportPage writes report card content top to bottom, moving on to a new
+// page when the next block does not fit.
+type reportPage struct {
+	doc *pdf_utils.Document
+	y   float64
+}
+
+func (p *reportPage) width() float64 {
+	return p.doc.Size().Width - 2*margin
+}
+
+// ensure starts a new page unless height more points fit on this one.
+func (p *reportPage) ensure(height float64) {
+	if p.y+height > p.doc.Size().Height-margin {
+		p.doc.AddPage()
+		p.y = margin
+	}
+}
+
+func (p *reportPage) text(font pdf_utils.Font, size float64, align pdf_utils.Align, text string) {
+	x := margin
+	switch align {
+	case pdf_utils.AlignCenter:
+		x = p.doc.Size().Width / 2
+	case pdf_utils.AlignRight:
+		x = p.doc.Size().Width - margin
+	}
+	for _, line := range pdf_utils.Wrap(font, size, p.width(), text) {
+		p.ensure(size + 3)
+		p.y += size + 3
+		p.doc.Text(x, p.y, font, size, align, line)
+	}
+}
+
+func (p *reportPage) gap(height float64) {
+	p.y += height
+}
+
+// table draws a header and rows of wrapped cells with the given column
+// widths. A row is never split across pages.
+func (p *reportPage) table(widths []float64, header []string, rows [][]string) {
+	draw := func(cells []string, font pdf_utils.Font) {
+		wrapped := make([][]string, len(cells))
+		lines := 1
+		for i, cell := range cells {
+			wrapped[i] = pdf_utils.Wrap(font, bodySize, widths[i]-2*cellPad, cell)
+			if len(wrapped[i]) > lines {
+				lines = len(wrapped[i])
+			}
+		}
+		height := float64(lines)*lineHeight + 2*cellPad
+		p.ensure(height)
+
+		x := margin
+		for i, cell := range wrapped {
+			p.doc.Rect(x, p.y, widths[i], height)
+			for j, line := range cell {
+				p.doc.Text(x+cellPad, p.y+cellPad+float64(j+1)*lineHeight-3, font, bodySize, pdf_utils.AlignLeft, line)
+			}
+			x += widths[i]
+		}
+		p.y += height
+	}
+
+	draw(header, pdf_utils.Bold)
+	for _, row := range rows {
+		draw(row, pdf_utils.Regular)
+	}
+}
+
+// WriteReportCardPDF renders the report card with its unit's template.
+func WriteReportCardPDF(w io.Writer, printable *Printable) error {
+	card, template := printable.Card, printable.Template
+	size, ok := pdf_utils.PageSizes[template.PaperSize]
+	if !ok {
+		size = pdf_utils.PageA4
+	}
+	doc := pdf_utils.New(size)
+	doc.AddPage()
+	page := &reportPage{doc: doc, y: margin - 10}
+
+	// Letterhead
+	page.text(pdf_utils.Bold, 14, pdf_utils.AlignCenter, printable.SchoolName)
+	if template.HeaderLines != nil {
+		for _, line := range strings.Split(*template.HeaderLines, "\n") {
+			page.text(pdf_utils.Regular, 9, pdf_utils.AlignCenter, line)
+		}
+	}
+	page.gap(6)
+	doc.Line(margin, page.y, size.Width-margin, page.y)
+	page.gap(10)
+	page.text(pdf_utils.Bold, 12, pdf_utils.AlignCenter, template.Title)
+	if card.Status == schemas.ReportCardDraft {
+		page.text(pdf_utils.Bold, 10, pdf_utils.AlignCenter, "DRAF - BELUM FINAL")
+	}
+	page.gap(8)
+
+	// Student identity
+	identity := [][2]string{
+		{"Nama Peserta Didik", card.StudentName},
+		{"NIS / NISN", orDash(card.NIS) + " / " + orDash(card.NISN)},
+		{"Kelas", card.ClassName},
+		{"Semester", semesterName(card.Semester)},
+		{"Tahun Pelajaran", card.AcademicYear},
+	}
+	for _, line := range identity {
+		page.ensure(lineHeight)
+		page.y += lineHeight
+		doc.Text(margin, page.y, pdf_utils.Regular, bodySize, pdf_utils.AlignLeft, line[0])
+		doc.Text(margin+120, page.y, pdf_utils.Regular, bodySize, pdf_utils.AlignLeft, ": "+line[1])
+	}
+	page.gap(12)
+
+	// Grades, with the passing score and predicate when the unit sets them
+	width := page.width()
+	thresholds := false
+	for _, subject := range card.Subjects {
+		thresholds = thresholds || subject.MinScore != nil
+	}
+	rows := make([][]string, 0, len(card.Subjects))
+	for i, subject := range card.Subjects {
+		if thresholds {
+			rows = append(rows, []string{strconv.Itoa(i + 1), subject.SubjectName, number(subject.MinScore), number(subject.Grade), orDash(subject.Predicate), subject.Description})
+		} else {
+			rows = append(rows, []string{strconv.Itoa(i + 1), subject.SubjectName, number(subject.Grade), subject.Description})
+		}
+	}
+	if thresholds {
+		page.table([]float64{25, 110, 35, 50, 50, width - 270}, []string{"No", "Mata Pelajaran", "KKM", "Nilai Akhir", "Predikat", "Capaian Kompetensi"}, rows)
+	} else {
+		page.table([]float64{25, 130, 50, width - 205}, []string{"No", "Mata Pelajaran", "Nilai Akhir", "Capaian Kompetensi"}, rows)
+	}
+	page.gap(12)
+
+	// Extracurriculars
+	if len(card.Activities) > 0 {
+		rows = make([][]string, 0, len(card.Activities))
+		for i, activity := range card.Activities {
+			rows = append(rows, []string{strconv.Itoa(i + 1), activity.ActivityName, orDash(activity.Note)})
+		}
+		page.table([]float64{25, 180, width - 205}, []string{"No", "Ekstrakurikuler", "Keterangan"}, rows)
+		page.gap(12)
+	}
+
+	// Attendance
+	page.table([]float64{180, 80}, []string{"Ketidakhadiran", "Jumlah"}, [][]string{
+		{"Sakit", fmt.Sprintf("%d hari", card.Attendance.Sick)},
+		{"Izin", fmt.Sprintf("%d hari", card.Attendance.Excused)},
+		{"Tanpa Keterangan", fmt.Sprintf("%d hari", card.Attendance.Absent)},
+	})
+	page.gap(12)
+
+	// Homeroom note
+	page.table([]float64{width}, []string{"Catatan Wali Kelas"}, [][]string{{orDash(card.HomeroomNote)}})
+	page.gap(20)
+
+	// Signatures: parent and homeroom teacher side by side, principal below
+	page.ensure(170)
+	left, right := margin+80, size.Width-margin-80
+	place := ""
+	if template.Place != nil && *template.Place != "" {
+		place = *template.Place + ", "
+	}
+	y := page.y
+	doc.Text(right, y, pdf_utils.Regular, bodySize, pdf_utils.AlignCenter, place+formatDate(printable.Date))
+	y += lineHeight
+	doc.Text(left, y, pdf_utils.Regular, bodySize, pdf_utils.AlignCenter, "Orang Tua/Wali")
+	doc.Text(right, y, pdf_utils.Regular, bodySize, pdf_utils.AlignCenter, "Wali Kelas")
+	y += 60
+	doc.Text(left, y, pdf_utils.Regular, bodySize, pdf_utils.AlignCenter, "(.........................................)")
+	doc.Text(right, y, pdf_utils.Bold, bodySize, pdf_utils.AlignCenter, orDash(&card.HomeroomTeacher))
+	if card.HomeroomNip != nil {
+		doc.Text(right, y+lineHeight, pdf_utils.Regular, bodySize, pdf_utils.AlignCenter, "NIP. "+*card.HomeroomNip)
+	}
+	if template.PrincipalName != nil {
+		y += 2 * lineHeight
+		doc.Text(size.Width/2, y, pdf_utils.Regular, bodySize, pdf_utils.AlignCenter, "Mengetahui,")
+		doc.Text(size.Width/2, y+lineHeight, pdf_utils.Regular, bodySize, pdf_utils.AlignCenter, "Kepala Sekolah")
+		y += lineHeight + 60
+		doc.Text(size.Width/2, y, pdf_utils.Bold, bodySize, pdf_utils.AlignCenter, *template.PrincipalName)
+		if template.PrincipalNip != nil {
+			doc.Text(size.Width/2, y+lineHeight, pdf_utils.Regular, bodySize, pdf_utils.AlignCenter, "NIP. "+*template.PrincipalNip)
+		}
+	}
+	page.y = y + 2*lineHeight
+
+	if template.Footer != nil && *template.Footer != "" {
+		page.gap(10)
+		page.text(pdf_utils.Regular, 8, pdf_utils.AlignCenter, *template.Footer)
+	}
+
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+func semesterName(semester int) string {
+	if semester == 2 {
+		return "2 (Genap)"
+	}
+	return "1 (Ganjil)"
+}
+
+// formatDate writes a date the Indonesian way, "17 Agustus 2025".
+func formatDate(date time.Time) string {
+	return fmt.Sprintf("%d %s %d", date.Day(), monthNames[date.Month()-1], date.Year())
+}
+
+func number(value *float64) string {
+	if value == nil {
+		return "-"
+	}
+	return strconv.FormatFloat(*value, 'f', -1, 64)
+}
+
+func orDash(value *string) string {
+	if value == nil || strings.TrimSpace(*value) == "" {
+		return "-"
+	}
+	return *value
+}
@@ -0,0 +1,758 @@
+package report_card_use_case
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"sekolah-madrasah/app/repository/report_card_repository"
+	"sekolah-madrasah/app/use_case/gradebook_use_case"
+	"sekolah-madrasah/database/schemas"
+	"sekolah-madrasah/pkg/pdf_utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrEnrollmentNotFound = errors.New("class enrollment not found in this unit")
+	ErrClassNotFound      = errors.New("class not found in this unit")
+	ErrNotHomeroom        = errors.New("only the class's homeroom teacher or unit admins may prepare its report cards")
+	ErrNotPublisher       = errors.New("only unit owners and admins may publish report cards")
+	ErrNotDraft           = errors.New("report card is no longer a draft, reopen it first")
+	ErrNotFinalized       = errors.New("report card has to be finalized first")
+	ErrPublished          = errors.New("report card has been published and can no longer change")
+	ErrChangedMeanwhile   = errors.New("report card was changed by someone else meanwhile, reload it and try again")
+	ErrNotPublishedYet    = errors.New("report card has not been published yet")
+)
+
+type ReportCardUseCase interface {
+	GetTemplate(unitId uuid.UUID) (*schemas.ReportCardTemplate, error)
+	UpdateTemplate(req *TemplateRequest) (*schemas.ReportCardTemplate, error)
+	// ListClass lists the report card status of every student of a class.
+	ListClass(unitId, classId uuid.UUID, semester int) (*ClassReportCards, error)
+	// Get returns a student's report card: computed from the gradebook while a
+	// draft, as frozen once finalized. Students and parents only see their own
+	// or their child's, once published.
+	Get(unitId, enrollmentId uuid.UUID, semester int, viewer Editor) (*ReportCardView, error)
+	// UpdateDraft stores the homeroom teacher's notes on a draft.
+	UpdateDraft(req *DraftRequest) (*ReportCardView, error)
+	// Finalize freezes the results and locks the class's grades for the semester.
+	Finalize(unitId, enrollmentId uuid.UUID, semester int, editor Editor) (*ReportCardView, error)
+	// Reopen turns a finalized report card back into a draft.
+	Reopen(unitId, enrollmentId uuid.UUID, semester int, editor Editor) (*ReportCardView, error)
+	// Publish hands a finalized report card out; it cannot change afterwards.
+	Publish(unitId, enrollmentId uuid.UUID, semester int, editor Editor) (*ReportCardView, error)
+	// GetPrintable gathers what the PDF of a report card needs.
+	GetPrintable(unitId, enrollmentId uuid.UUID, semester int, viewer Editor) (*Printable, error)
+}
+
+// Editor is the user preparing, publishing or viewing report cards and their
+// role in the unit.
+type Editor struct {
+	UserId uuid.UUID
+	Role   schemas.UnitMemberRole
+}
+
+type TemplateRequest struct {
+	UnitId          uuid.UUID
+	Title           *string
+	SchoolName      *string
+	HeaderLines     *string
+	PaperSize       *string
+	Place           *string
+	PrincipalName   *string
+	PrincipalNip    *string
+	DescriptionHigh *string
+	DescriptionLow  *string
+	Footer          *string
+}
+
+type DraftRequest struct {
+	UnitId        uuid.UUID
+	EnrollmentId  uuid.UUID
+	Semester      int
+	HomeroomNote  *string
+	ActivityNotes map[uuid.UUID]*string // Remarks per activity the student takes part in
+	Editor        Editor
+}
+
+type SubjectResult struct {
+	SubjectId   uuid.UUID `json:"subject_id"`
+	SubjectName string    `json:"subject_name"`
+	Grade       *float64  `json:"grade"`     // Null without any score
+	MinScore    *float64  `json:"min_score"` // Passing score (KKM/KKTP), null when the unit set none
+	Predicate   *string   `json:"predicate"` // A to D, null without a passing score or grade
+	Description string    `json:"description"`
+}
+
+type ActivityResult struct {
+	ActivityId   uuid.UUID `json:"activity_id"`
+	ActivityName string    `json:"activity_name"`
+	Note         *string   `json:"note"`
+}
+
+// AttendanceTotals are the school days missed in the semester.
+type AttendanceTotals struct {
+	Sick    int `json:"sick"`
+	Excused int `json:"excused"`
+	Absent  int `json:"absent"`
+}
+
+type ReportCardView struct {
+	Id                *uuid.UUID               `json:"id"` // Null until anything was stored
+	ClassEnrollmentId uuid.UUID                `json:"class_enrollment_id"`
+	StudentProfileId  uuid.UUID                `json:"student_profile_id"`
+	StudentName       string                   `json:"student_name"`
+	NIS               *string                  `json:"nis"`
+	NISN              *string                  `json:"nisn"`
+	ClassId           uuid.UUID                `json:"class_id"`
+	ClassName         string                   `json:"class_name"`
+	Level             int                      `json:"level"`
+	HomeroomTeacher   string                   `json:"homeroom_teacher"`
+	HomeroomNip       *string                  `json:"homeroom_nip"`
+	AcademicYear      string                   `json:"academic_year"`
+	Semester          int                      `json:"semester"`
+	Status            schemas.ReportCardStatus `json:"status"`
+	Subjects          []SubjectResult          `json:"subjects"`
+	Activities        []ActivityResult         `json:"activities"`
+	Attendance        AttendanceTotals         `json:"attendance"`
+	HomeroomNote      *string                  `json:"homeroom_note"`
+	FinalizedAt       *time.Time               `json:"finalized_at"`
+	PublishedAt       *time.Time               `json:"published_at"`
+}
+
+type ReportCardSummary struct {
+	ClassEnrollmentId uuid.UUID                `json:"class_enrollment_id"`
+	StudentName       string                   `json:"student_name"`
+	NIS               *string                  `json:"nis"`
+	Status            schemas.ReportCardStatus `json:"status"`
+	FinalizedAt       *time.Time               `json:"finalized_at"`
+	PublishedAt       *time.Time               `json:"published_at"`
+}
+
+type ClassReportCards struct {
+	ClassId      uuid.UUID           `json:"class_id"`
+	AcademicYear string              `json:"academic_year"`
+	Semester     int                 `json:"semester"`
+	Students     []ReportCardSummary `json:"students"`
+}
+
+// Printable is a report card with the unit's template, ready to be rendered.
+type Printable struct {
+	Card       *ReportCardView
+	Template   *schemas.ReportCardTemplate
+	SchoolName string
+	Date       time.Time // Date of signing
+}
+
+type reportCardUseCase struct {
+	repo report_card_repository.ReportCardRepository
+	now  func() time.Time
+}
+
+func NewReportCardUseCase(repo report_card_repository.ReportCardRepository) ReportCardUseCase {
+	return &reportCardUseCase{repo: repo, now: time.Now}
+}
+
+func (uc *reportCardUseCase) GetTemplate(unitId uuid.UUID) (*schemas.ReportCardTemplate, error) {
+	template, err := uc.repo.FindTemplate(unitId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return schemas.DefaultReportCardTemplate(unitId), nil
+	}
+	return template, err
+}
+
+func (uc *reportCardUseCase) UpdateTemplate(req *TemplateRequest) (*schemas.ReportCardTemplate, error) {
+	template, err := uc.GetTemplate(req.UnitId)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Title != nil {
+		template.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.SchoolName != nil {
+		template.SchoolName = req.SchoolName
+	}
+	if req.HeaderLines != nil {
+		template.HeaderLines = req.HeaderLines
+	}
+	if req.PaperSize != nil {
+		template.PaperSize = strings.ToUpper(*req.PaperSize)
+	}
+	if req.Place != nil {
+		template.Place = req.Place
+	}
+	if req.PrincipalName != nil {
+		template.PrincipalName = req.PrincipalName
+	}
+	if req.PrincipalNip != nil {
+		template.PrincipalNip = req.PrincipalNip
+	}
+	if req.DescriptionHigh != nil {
+		template.DescriptionHigh = *req.DescriptionHigh
+	}
+	if req.DescriptionLow != nil {
+		template.DescriptionLow = *req.DescriptionLow
+	}
+	if req.Footer != nil {
+		template.Footer = req.Footer
+	}
+
+	if template.Title == "" {
+		return nil, errors.New("title is required")
+	}
+	if _, ok := pdf_utils.PageSizes[template.PaperSize]; !ok {
+		return nil, errors.New("paper_size must be A4 or F4")
+	}
+	if !strings.Contains(template.DescriptionHigh, placeholderCompetency) || !strings.Contains(template.DescriptionLow, placeholderCompetency) {
+		return nil, fmt.Errorf("descriptions must mention %s where the competencies go", placeholderCompetency)
+	}
+	return uc.repo.SaveTemplate(template)
+}
+
+func (uc *reportCardUseCase) ListClass(unitId, classId uuid.UUID, semester int) (*ClassReportCards, error) {
+	class, err := uc.repo.FindClass(classId)
+	if err != nil || class.UnitId != unitId {
+		return nil, ErrClassNotFound
+	}
+	settings, err := uc.settings(unitId)
+	if err != nil {
+		return nil, err
+	}
+	if semester, err = term(semester, settings); err != nil {
+		return nil, err
+	}
+
+	enrollments, err := uc.repo.FindEnrollments(class.Id)
+	if err != nil {
+		return nil, err
+	}
+	cards, err := uc.repo.FindClassReportCards(class.Id, class.AcademicYear, semester)
+	if err != nil {
+		return nil, err
+	}
+	stored := make(map[uuid.UUID]schemas.ReportCard, len(cards))
+	for _, card := range cards {
+		stored[card.ClassEnrollmentId] = card
+	}
+
+	list := &ClassReportCards{ClassId: class.Id, AcademicYear: class.AcademicYear, Semester: semester, Students: []ReportCardSummary{}}
+	for _, enrollment := range enrollments {
+		card, ok := stored[enrollment.Id]
+		if !ok && enrollment.LeftAt != nil {
+			continue
+		}
+		summary := ReportCardSummary{ClassEnrollmentId: enrollment.Id, Status: schemas.ReportCardDraft}
+		summary.StudentName, summary.NIS, _ = student(&enrollment)
+		if ok {
+			summary.Status = card.Status
+			summary.FinalizedAt = card.FinalizedAt
+			summary.PublishedAt = card.PublishedAt
+		}
+		list.Students = append(list.Students, summary)
+	}
+
+	sort.SliceStable(list.Students, func(i, j int) bool {
+		return list.Students[i].StudentName < list.Students[j].StudentName
+	})
+	return list, nil
+}
+
+func (uc *reportCardUseCase) Get(unitId, enrollmentId uuid.UUID, semester int, viewer Editor) (*ReportCardView, error) {
+	subject, err := uc.load(unitId, enrollmentId, semester)
+	if err != nil {
+		return nil, err
+	}
+	if err := canView(subject, viewer); err != nil {
+		return nil, err
+	}
+	return uc.view(subject)
+}
+
+func (uc *reportCardUseCase) UpdateDraft(req *DraftRequest) (*ReportCardView, error) {
+	subject, err := uc.load(req.UnitId, req.EnrollmentId, req.Semester)
+	if err != nil {
+		return nil, err
+	}
+	if !uc.canPrepare(subject.class, req.Editor) {
+		return nil, ErrNotHomeroom
+	}
+	if subject.card.Status != schemas.ReportCardDraft {
+		return nil, ErrNotDraft
+	}
+
+	activities, err := uc.activities(subject)
+	if err != nil {
+		return nil, err
+	}
+	taking := map[uuid.UUID]bool{}
+	for _, activity := range activities {
+		taking[activity.ActivityId] = true
+	}
+	for activityId := range req.ActivityNotes {
+		if !taking[activityId] {
+			return nil, fmt.Errorf("the student does not take part in activity %s", activityId)
+		}
+	}
+
+	if req.HomeroomNote != nil {
+		subject.card.HomeroomNote = req.HomeroomNote
+	}
+	subject.card.Activities = subject.card.Activities[:0]
+	for _, activity := range activities {
+		if note, ok := req.ActivityNotes[activity.ActivityId]; ok {
+			activity.Note = note
+		}
+		subject.card.Activities = append(subject.card.Activities, schemas.ReportCardActivity{
+			ActivityId: activity.ActivityId, ActivityName: activity.ActivityName, Note: activity.Note,
+		})
+	}
+	if err := uc.save(subject.card, schemas.ReportCardDraft); err != nil {
+		return nil, err
+	}
+	subject.stored = true
+	return uc.view(subject)
+}
+
+func (uc *reportCardUseCase) Finalize(unitId, enrollmentId uuid.UUID, semester int, editor Editor) (*ReportCardView, error) {
+	subject, err := uc.load(unitId, enrollmentId, semester)
+	if err != nil {
+		return nil, err
+	}
+	if !uc.canPrepare(subject.class, editor) {
+		return nil, ErrNotHomeroom
+	}
+	if subject.card.Status != schemas.ReportCardDraft {
+		return nil, ErrNotDraft
+	}
+
+	view, err := uc.view(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	card := subject.card
+	card.Subjects = make([]schemas.ReportCardSubject, 0, len(view.Subjects))
+	for i, result := range view.Subjects {
+		card.Subjects = append(card.Subjects, schemas.ReportCardSubject{
+			SubjectId: result.SubjectId, SubjectName: result.SubjectName, Position: i + 1,
+			Grade: result.Grade, MinScore: result.MinScore, Predicate: result.Predicate, Description: result.Description,
+		})
+	}
+	card.Activities = make([]schemas.ReportCardActivity, 0, len(view.Activities))
+	for _, activity := range view.Activities {
+		card.Activities = append(card.Activities, schemas.ReportCardActivity{
+			ActivityId: activity.ActivityId, ActivityName: activity.ActivityName, Note: activity.Note,
+		})
+	}
+	card.Sick = view.Attendance.Sick
+	card.Excused = view.Attendance.Excused
+	card.Absent = view.Attendance.Absent
+	now := uc.now()
+	card.Status = schemas.ReportCardFinalized
+	card.FinalizedAt = &now
+	card.FinalizedBy = &editor.UserId
+
+	if err := uc.save(card, schemas.ReportCardDraft); err != nil {
+		return nil, err
+	}
+	subject.stored = true
+	return uc.view(subject)
+}
+
+func (uc *reportCardUseCase) Reopen(unitId, enrollmentId uuid.UUID, semester int, editor Editor) (*ReportCardView, error) {
+	subject, err := uc.load(unitId, enrollmentId, semester)
+	if err != nil {
+		return nil, err
+	}
+	if !uc.canPrepare(subject.class, editor) {
+		return nil, ErrNotHomeroom
+	}
+	switch subject.card.Status {
+	case schemas.ReportCardPublished:
+		return nil, ErrPublished
+	case schemas.ReportCardDraft:
+		return nil, ErrNotFinalized
+	}
+
+	// The activity remarks stay with the draft; the rest is computed again
+	card := subject.card
+	card.Status = schemas.ReportCardDraft
+	card.Subjects = nil
+	card.Sick, card.Excused, card.Absent = 0, 0, 0
+	card.FinalizedAt = nil
+	card.FinalizedBy = nil
+	if err := uc.save(card, schemas.ReportCardFinalized); err != nil {
+		return nil, err
+	}
+	return uc.view(subject)
+}
+
+func (uc *reportCardUseCase) Publish(unitId, enrollmentId uuid.UUID, semester int, editor Editor) (*ReportCardView, error) {
+	subject, err := uc.load(unitId, enrollmentId, semester)
+	if err != nil {
+		return nil, err
+	}
+	switch editor.Role {
+	case schemas.UnitMemberRoleOwner, schemas.UnitMemberRoleAdmin, "":
+	default:
+		return nil, ErrNotPublisher
+	}
+	switch subject.card.Status {
+	case schemas.ReportCardPublished:
+		return nil, ErrPublished
+	case schemas.ReportCardDraft:
+		return nil, ErrNotFinalized
+	}
+
+	now := uc.now()
+	subject.card.Status = schemas.ReportCardPublished
+	subject.card.PublishedAt = &now
+	subject.card.PublishedBy = &editor.UserId
+	if err := uc.save(subject.card, schemas.ReportCardFinalized); err != nil {
+		return nil, err
+	}
+	return uc.view(subject)
+}
+
+func (uc *reportCardUseCase) GetPrintable(unitId, enrollmentId uuid.UUID, semester int, viewer Editor) (*Printable, error) {
+	subject, err := uc.load(unitId, enrollmentId, semester)
+	if err != nil {
+		return nil, err
+	}
+	if err := canView(subject, viewer); err != nil {
+		return nil, err
+	}
+	view, err := uc.view(subject)
+	if err != nil {
+		return nil, err
+	}
+	template, err := uc.GetTemplate(unitId)
+	if err != nil {
+		return nil, err
+	}
+
+	printable := &Printable{Card: view, Template: template, Date: uc.now()}
+	if view.PublishedAt != nil {
+		printable.Date = *view.PublishedAt
+	} else if view.FinalizedAt != nil {
+		printable.Date = *view.FinalizedAt
+	}
+	if template.SchoolName != nil && *template.SchoolName != "" {
+		printable.SchoolName = *template.SchoolName
+	} else if unit, err := uc.repo.FindUnit(unitId); err == nil {
+		printable.SchoolName = unit.Name
+	}
+	return printable, nil
+}
+
+// reportSubject is a student's enrollment, class and report card for a
+// semester; the card is a new draft when none was stored yet.
+type reportSubject struct {
+	enrollment *schemas.ClassEnrollment
+	class      *schemas.Class
+	settings   *schemas.UnitSettings
+	card       *schemas.ReportCard
+	stored     bool
+}
+
+func (uc *reportCardUseCase) load(unitId, enrollmentId uuid.UUID, semester int) (*reportSubject, error) {
+	enrollment, err := uc.repo.FindEnrollment(enrollmentId)
+	if err != nil {
+		return nil, ErrEnrollmentNotFound
+	}
+	class, err := uc.repo.FindClass(enrollment.ClassId)
+	if err != nil || class.UnitId != unitId {
+		return nil, ErrEnrollmentNotFound
+	}
+	settings, err := uc.settings(unitId)
+	if err != nil {
+		return nil, err
+	}
+	if semester, err = term(semester, settings); err != nil {
+		return nil, err
+	}
+
+	subject := &reportSubject{enrollment: enrollment, class: class, settings: settings}
+	card, err := uc.repo.FindReportCard(enrollment.Id, semester)
+	switch {
+	case err == nil:
+		subject.card = card
+		subject.stored = true
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		subject.card = &schemas.ReportCard{
+			UnitId:            unitId,
+			ClassEnrollmentId: enrollment.Id,
+			ClassId:           class.Id,
+			AcademicYear:      enrollment.AcademicYear,
+			Semester:          semester,
+			Status:            schemas.ReportCardDraft,
+		}
+	default:
+		return nil, err
+	}
+	return subject, nil
+}
+
+// view shows the frozen results of a finalized report card, or computes
+// those of a draft.
+func (uc *reportCardUseCase) view(subject *reportSubject) (*ReportCardView, error) {
+	card := subject.card
+	view := &ReportCardView{
+		ClassEnrollmentId: subject.enrollment.Id,
+		StudentProfileId:  subject.enrollment.StudentProfileId,
+		ClassId:           subject.class.Id,
+		ClassName:         subject.class.Name,
+		Level:             subject.class.Level,
+		AcademicYear:      card.AcademicYear,
+		Semester:          card.Semester,
+		Status:            card.Status,
+		HomeroomNote:      card.HomeroomNote,
+		FinalizedAt:       card.FinalizedAt,
+		PublishedAt:       card.PublishedAt,
+	}
+	if subject.stored {
+		id := card.Id
+		view.Id = &id
+	}
+	view.StudentName, view.NIS, view.NISN = student(subject.enrollment)
+	if homeroom := subject.class.HomeroomTeacher; homeroom != nil {
+		view.HomeroomNip = homeroom.NIP
+		if homeroom.User != nil {
+			view.HomeroomTeacher = homeroom.User.FullName
+		}
+	}
+
+	if card.Status != schemas.ReportCardDraft {
+		view.Subjects = make([]SubjectResult, 0, len(card.Subjects))
+		for _, result := range card.Subjects {
+			view.Subjects = append(view.Subjects, SubjectResult{
+				SubjectId: result.SubjectId, SubjectName: result.SubjectName, Grade: result.Grade,
+				MinScore: result.MinScore, Predicate: result.Predicate, Description: result.Description,
+			})
+		}
+		view.Activities = make([]ActivityResult, 0, len(card.Activities))
+		for _, activity := range card.Activities {
+			view.Activities = append(view.Activities, ActivityResult{
+				ActivityId: activity.ActivityId, ActivityName: activity.ActivityName, Note: activity.Note,
+			})
+		}
+		view.Attendance = AttendanceTotals{Sick: card.Sick, Excused: card.Excused, Absent: card.Absent}
+		return view, nil
+	}
+
+	var err error
+	if view.Subjects, err = uc.subjects(subject, view.StudentName); err != nil {
+		return nil, err
+	}
+	if view.Activities, err = uc.activities(subject); err != nil {
+		return nil, err
+	}
+	if view.Attendance, err = uc.attendance(subject); err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
+// subjects computes the final grade, predicate and competency description of
+// every subject the class takes or was assessed in during the semester.
+func (uc *reportCardUseCase) subjects(subject *reportSubject, studentName string) ([]SubjectResult, error) {
+	card := subject.card
+	classSubjects, err := uc.repo.FindClassSubjects(subject.class.Id)
+	if err != nil {
+		return nil, err
+	}
+	assessments, err := uc.repo.FindAssessments(subject.class.Id, card.AcademicYear, card.Semester)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, 0, len(assessments))
+	for _, assessment := range assessments {
+		ids = append(ids, assessment.Id)
+	}
+	scores, err := uc.repo.FindScores(subject.enrollment.Id, ids)
+	if err != nil {
+		return nil, err
+	}
+	entered := make(map[uuid.UUID]float64, len(scores))
+	for _, score := range scores {
+		entered[score.AssessmentId] = score.Score
+	}
+	template, err := uc.GetTemplate(subject.class.UnitId)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := uc.repo.FindThresholds(subject.class.UnitId)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[uuid.UUID]string{}
+	perSubject := map[uuid.UUID][]schemas.Assessment{}
+	for _, classSubject := range classSubjects {
+		if classSubject.Subject != nil {
+			names[classSubject.SubjectId] = classSubject.Subject.Name
+		}
+	}
+	for _, assessment := range assessments {
+		perSubject[assessment.SubjectId] = append(perSubject[assessment.SubjectId], assessment)
+		if _, ok := names[assessment.SubjectId]; !ok && assessment.Subject != nil {
+			names[assessment.SubjectId] = assessment.Subject.Name
+		}
+	}
+
+	rounding := gradebook_use_case.RoundingFrom(subject.settings)
+	results := make([]SubjectResult, 0, len(names))
+	for subjectId, name := range names {
+		result := SubjectResult{
+			SubjectId:   subjectId,
+			SubjectName: name,
+			Grade:       gradebook_use_case.WeightedAverage(perSubject[subjectId], entered, rounding),
+			Description: describe(template, studentName, perSubject[subjectId], entered),
+		}
+		if rule := gradebook_use_case.ThresholdFor(rules, subjectId, subject.class.Level); rule != nil {
+			minScore := rule.MinScore
+			result.MinScore = &minScore
+			result.Predicate, _ = gradebook_use_case.Mark(rule, result.Grade)
+		}
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].SubjectName < results[j].SubjectName
+	})
+	return results, nil
+}
+
+// activities lists the extracurriculars the student takes part in, with the
+// remarks stored on the draft. One-off events do not go on the rapor.
+func (uc *reportCardUseCase) activities(subject *reportSubject) ([]ActivityResult, error) {
+	participations, err := uc.repo.FindActivities(subject.class.UnitId, subject.enrollment.StudentProfileId)
+	if err != nil {
+		return nil, err
+	}
+	notes := map[uuid.UUID]*string{}
+	for _, activity := range subject.card.Activities {
+		notes[activity.ActivityId] = activity.Note
+	}
+
+	results := []ActivityResult{}
+	for _, participation := range participations {
+		activity := participation.Activity
+		if activity == nil || !activity.IsActive || activity.Type == "event" {
+			continue
+		}
+		results = append(results, ActivityResult{
+			ActivityId: activity.Id, ActivityName: activity.Name, Note: notes[activity.Id],
+		})
+	}
+	return results, nil
+}
+
+// attendance totals the days missed within the semester's dates from the unit
+// settings; without them the whole enrollment counts.
+func (uc *reportCardUseCase) attendance(subject *reportSubject) (AttendanceTotals, error) {
+	from, to := subject.settings.Semester1Start, subject.settings.Semester1End
+	if subject.card.Semester == 2 {
+		from, to = subject.settings.Semester2Start, subject.settings.Semester2End
+	}
+	counts, err := uc.repo.CountAttendance(subject.enrollment.Id, from, to)
+	if err != nil {
+		return AttendanceTotals{}, err
+	}
+
+	var totals AttendanceTotals
+	for _, count := range counts {
+		switch count.Status {
+		case schemas.AttendanceSick:
+			totals.Sick += count.Count
+		case schemas.AttendanceExcused:
+			totals.Excused += count.Count
+		case schemas.AttendanceAbsent:
+			totals.Absent += count.Count
+		}
+	}
+	return totals, nil
+}
+
+// canPrepare allows unit owners and admins and the class's homeroom teacher.
+func (uc *reportCardUseCase) canPrepare(class *schemas.Class, editor Editor) bool {
+	switch editor.Role {
+	case schemas.UnitMemberRoleOwner, schemas.UnitMemberRoleAdmin, "":
+		return true
+	}
+	teacher, err := uc.repo.FindTeacherByUser(class.UnitId, editor.UserId)
+	if err != nil {
+		return false
+	}
+	return class.HomeroomTeacherId != nil && *class.HomeroomTeacherId == teacher.Id
+}
+
+// save stores the card as long as nobody moved it out of the status it was
+// loaded in, so two requests cannot both finalize, reopen or publish it.
+func (uc *reportCardUseCase) save(card *schemas.ReportCard, from schemas.ReportCardStatus) error {
+	_, err := uc.repo.SaveReportCard(card, from)
+	if errors.Is(err, report_card_repository.ErrStatusChanged) {
+		return ErrChangedMeanwhile
+	}
+	return err
+}
+
+// canView limits students to their own report cards and parents to their
+// child's, and both to published ones; other roles see every card of the unit.
+func canView(subject *reportSubject, viewer Editor) error {
+	profile := subject.enrollment.StudentProfile
+	switch viewer.Role {
+	case schemas.UnitMemberRoleAnggota:
+		if profile == nil || profile.UserId != viewer.UserId {
+			return ErrEnrollmentNotFound
+		}
+	case schemas.UnitMemberRoleParent:
+		if profile == nil || profile.ParentUserId == nil || *profile.ParentUserId != viewer.UserId {
+			return ErrEnrollmentNotFound
+		}
+	default:
+		return nil
+	}
+	if subject.card.Status != schemas.ReportCardPublished {
+		return ErrNotPublishedYet
+	}
+	return nil
+}
+
+// settings returns the unit's settings, or the defaults if they were never saved.
+func (uc *reportCardUseCase) settings(unitId uuid.UUID) (*schemas.UnitSettings, error) {
+	settings, err := uc.repo.FindSettings(unitId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &schemas.UnitSettings{UnitId: unitId, CurrentSemester: 1, GradeRounding: schemas.GradeRoundHalfUp}, nil
+	}
+	return settings, err
+}
+
+// term defaults the semester to the unit's current one.
+func term(semester int, settings *schemas.UnitSettings) (int, error) {
+	if semester == 0 {
+		semester = settings.CurrentSemester
+	}
+	if semester == 0 {
+		semester = 1
+	}
+	if semester != 1 && semester != 2 {
+		return 0, errors.New("semester must be 1 or 2")
+	}
+	return semester, nil
+}
+
+func student(enrollment *schemas.ClassEnrollment) (string, *string, *string) {
+	if enrollment.StudentProfile == nil {
+		return "", nil, nil
+	}
+	name := ""
+	if enrollment.StudentProfile.User != nil {
+		name = enrollment.StudentProfile.User.FullName
+	}
+	return name, enrollment.StudentProfile.NIS, enrollment.StudentProfile.NISN
+}
@@ -0,0 +1,439 @@
+package report_card_use_case
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"sekolah-madrasah/app/repository/report_card_repository"
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockRepository is a mock implementation of ReportCardRepository
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) FindReportCard(enrollmentId uuid.UUID, semester int) (*schemas.ReportCard, error) {
+	args := m.Called(enrollmentId, semester)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.ReportCard), args.Error(1)
+}
+
+func (m *MockRepository) FindClassReportCards(classId uuid.UUID, academicYear string, semester int) ([]schemas.ReportCard, error) {
+	args := m.Called(classId, academicYear, semester)
+	return args.Get(0).([]schemas.ReportCard), args.Error(1)
+}
+
+func (m *MockRepository) SaveReportCard(card *schemas.ReportCard, from schemas.ReportCardStatus) (*schemas.ReportCard, error) {
+	args := m.Called(card, from)
+	return card, args.Error(0)
+}
+
+func (m *MockRepository) FindTemplate(unitId uuid.UUID) (*schemas.ReportCardTemplate, error) {
+	args := m.Called(unitId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.ReportCardTemplate), args.Error(1)
+}
+
+func (m *MockRepository) SaveTemplate(template *schemas.ReportCardTemplate) (*schemas.ReportCardTemplate, error) {
+	args := m.Called(template)
+	return template, args.Error(0)
+}
+
+func (m *MockRepository) FindUnit(id uuid.UUID) (*schemas.Unit, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.Unit), args.Error(1)
+}
+
+func (m *MockRepository) FindSettings(unitId uuid.UUID) (*schemas.UnitSettings, error) {
+	args := m.Called(unitId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.UnitSettings), args.Error(1)
+}
+
+func (m *MockRepository) FindClass(id uuid.UUID) (*schemas.Class, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.Class), args.Error(1)
+}
+
+func (m *MockRepository) FindEnrollment(id uuid.UUID) (*schemas.ClassEnrollment, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.ClassEnrollment), args.Error(1)
+}
+
+func (m *MockRepository) FindEnrollments(classId uuid.UUID) ([]schemas.ClassEnrollment, error) {
+	args := m.Called(classId)
+	return args.Get(0).([]schemas.ClassEnrollment), args.Error(1)
+}
+
+func (m *MockRepository) FindTeacherByUser(unitId, userId uuid.UUID) (*schemas.TeacherProfile, error) {
+	args := m.Called(unitId, userId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.TeacherProfile), args.Error(1)
+}
+
+func (m *MockRepository) FindClassSubjects(classId uuid.UUID) ([]schemas.ClassSubject, error) {
+	args := m.Called(classId)
+	return args.Get(0).([]schemas.ClassSubject), args.Error(1)
+}
+
+func (m *MockRepository) FindAssessments(classId uuid.UUID, academicYear string, semester int) ([]schemas.Assessment, error) {
+	args := m.Called(classId, academicYear, semester)
+	return args.Get(0).([]schemas.Assessment), args.Error(1)
+}
+
+func (m *MockRepository) FindScores(enrollmentId uuid.UUID, assessmentIds []uuid.UUID) ([]schemas.AssessmentScore, error) {
+	args := m.Called(enrollmentId, assessmentIds)
+	return args.Get(0).([]schemas.AssessmentScore), args.Error(1)
+}
+
+func (m *MockRepository) FindThresholds(unitId uuid.UUID) ([]schemas.GradeThreshold, error) {
+	args := m.Called(unitId)
+	return args.Get(0).([]schemas.GradeThreshold), args.Error(1)
+}
+
+func (m *MockRepository) CountAttendance(enrollmentId uuid.UUID, from, to *time.Time) ([]report_card_repository.AttendanceCount, error) {
+	args := m.Called(enrollmentId, from, to)
+	return args.Get(0).([]report_card_repository.AttendanceCount), args.Error(1)
+}
+
+func (m *MockRepository) FindActivities(unitId, studentProfileId uuid.UUID) ([]schemas.ActivityStudent, error) {
+	args := m.Called(unitId, studentProfileId)
+	return args.Get(0).([]schemas.ActivityStudent), args.Error(1)
+}
+
+type fixture struct {
+	repo       *MockRepository
+	uc         *reportCardUseCase
+	unitId     uuid.UUID
+	class      *schemas.Class
+	homeroom   *schemas.TeacherProfile
+	enrollment *schemas.ClassEnrollment
+	math       *schemas.Subject
+	pramuka    *schemas.Activity
+	thresholds *mock.Call // The unit's passing thresholds, none by default
+	now        time.Time
+}
+
+// newFixture sets up Ani in VII A with two maths assessments on different
+// competencies, a few missed days, scouts and a one-off event, and no report
+// card stored yet.
+func newFixture() *fixture {
+	unitId := uuid.New()
+	f := &fixture{
+		repo:     new(MockRepository),
+		unitId:   unitId,
+		homeroom: &schemas.TeacherProfile{Id: uuid.New(), UnitId: unitId, UserId: uuid.New(), User: &schemas.User{FullName: "Bu Sari"}},
+		math:     &schemas.Subject{Id: uuid.New(), UnitId: unitId, Name: "Matematika"},
+		pramuka:  &schemas.Activity{Id: uuid.New(), UnitId: unitId, Name: "Pramuka", Type: "ekstrakurikuler", IsActive: true},
+		now:      time.Date(2025, 12, 19, 10, 0, 0, 0, time.UTC),
+	}
+	f.uc = NewReportCardUseCase(f.repo).(*reportCardUseCase)
+	f.uc.now = func() time.Time { return f.now }
+	f.class = &schemas.Class{Id: uuid.New(), UnitId: unitId, Name: "VII A", Level: 7, AcademicYear: "2025/2026", HomeroomTeacherId: &f.homeroom.Id, HomeroomTeacher: f.homeroom}
+	f.enrollment = &schemas.ClassEnrollment{
+		Id: uuid.New(), ClassId: f.class.Id, StudentProfileId: uuid.New(), AcademicYear: "2025/2026",
+		StudentProfile: &schemas.StudentProfile{User: &schemas.User{FullName: "Ani"}},
+	}
+
+	integers := "operasi bilangan bulat"
+	fractions := "pecahan"
+	daily := schemas.Assessment{Id: uuid.New(), SubjectId: f.math.Id, Type: schemas.AssessmentDaily, Weight: 1, MaxScore: 50, Competency: &integers, Subject: f.math}
+	task := schemas.Assessment{Id: uuid.New(), SubjectId: f.math.Id, Type: schemas.AssessmentAssignment, Weight: 1, MaxScore: 100, Competency: &fractions, Subject: f.math}
+	semester1End := day("2025-12-20")
+
+	f.repo.On("FindEnrollment", f.enrollment.Id).Return(f.enrollment, nil)
+	f.repo.On("FindClass", f.class.Id).Return(f.class, nil)
+	f.repo.On("FindSettings", unitId).Return(&schemas.UnitSettings{UnitId: unitId, CurrentSemester: 1, GradeRounding: schemas.GradeRoundHalfUp, Semester1End: &semester1End}, nil)
+	f.repo.On("FindTemplate", unitId).Return(nil, gorm.ErrRecordNotFound)
+	f.repo.On("FindUnit", unitId).Return(&schemas.Unit{Id: unitId, Name: "SMP Al Falah"}, nil)
+	f.repo.On("FindTeacherByUser", unitId, f.homeroom.UserId).Return(f.homeroom, nil)
+	f.repo.On("FindClassSubjects", f.class.Id).Return([]schemas.ClassSubject{{ClassId: f.class.Id, SubjectId: f.math.Id, Subject: f.math}}, nil)
+	f.repo.On("FindAssessments", f.class.Id, "2025/2026", 1).Return([]schemas.Assessment{daily, task}, nil)
+	f.repo.On("FindScores", f.enrollment.Id, []uuid.UUID{daily.Id, task.Id}).Return([]schemas.AssessmentScore{
+		{AssessmentId: daily.Id, ClassEnrollmentId: f.enrollment.Id, Score: 45},
+		{AssessmentId: task.Id, ClassEnrollmentId: f.enrollment.Id, Score: 70},
+	}, nil)
+	f.thresholds = f.repo.On("FindThresholds", unitId).Return([]schemas.GradeThreshold{}, nil)
+	f.repo.On("CountAttendance", f.enrollment.Id, (*time.Time)(nil), &semester1End).Return([]report_card_repository.AttendanceCount{
+		{Status: schemas.AttendancePresent, Count: 80},
+		{Status: schemas.AttendanceLate, Count: 2},
+		{Status: schemas.AttendanceSick, Count: 3},
+		{Status: schemas.AttendanceAbsent, Count: 1},
+	}, nil)
+	f.repo.On("FindActivities", unitId, f.enrollment.StudentProfileId).Return([]schemas.ActivityStudent{
+		{ActivityId: f.pramuka.Id, Activity: f.pramuka},
+		{Activity: &schemas.Activity{Id: uuid.New(), Name: "Lomba 17 Agustus", Type: "event", IsActive: true}},
+	}, nil)
+	return f
+}
+
+// stored makes the report card found in the given state.
+func (f *fixture) stored(status schemas.ReportCardStatus) *schemas.ReportCard {
+	card := &schemas.ReportCard{
+		Id: uuid.New(), UnitId: f.unitId, ClassEnrollmentId: f.enrollment.Id, ClassId: f.class.Id,
+		AcademicYear: "2025/2026", Semester: 1, Status: status,
+	}
+	f.repo.On("FindReportCard", f.enrollment.Id, 1).Return(card, nil)
+	return card
+}
+
+func day(value string) time.Time {
+	date, _ := time.Parse("2006-01-02", value)
+	return date
+}
+
+var admin = Editor{UserId: uuid.New(), Role: schemas.UnitMemberRoleAdmin}
+
+// Tests
+
+func TestGet_DraftComputedFromGradebook(t *testing.T) {
+	f := newFixture()
+	f.repo.On("FindReportCard", f.enrollment.Id, 1).Return(nil, gorm.ErrRecordNotFound)
+
+	card, err := f.uc.Get(f.unitId, f.enrollment.Id, 0, admin)
+	assert.NoError(t, err)
+	assert.Nil(t, card.Id)
+	assert.Equal(t, schemas.ReportCardDraft, card.Status)
+	assert.Equal(t, "Bu Sari", card.HomeroomTeacher)
+
+	// (90 + 70) / 2 = 80
+	assert.Len(t, card.Subjects, 1)
+	assert.Equal(t, 80.0, *card.Subjects[0].Grade)
+	assert.Equal(t, "Ananda Ani menunjukkan penguasaan yang baik dalam operasi bilangan bulat. Ananda Ani perlu bantuan dalam pecahan.", card.Subjects[0].Description)
+
+	assert.Equal(t, AttendanceTotals{Sick: 3, Excused: 0, Absent: 1}, card.Attendance)
+	assert.Equal(t, []ActivityResult{{ActivityId: f.pramuka.Id, ActivityName: "Pramuka"}}, card.Activities)
+}
+
+func TestGet_PredicateFromThreshold(t *testing.T) {
+	f := newFixture()
+	f.repo.On("FindReportCard", f.enrollment.Id, 1).Return(nil, gorm.ErrRecordNotFound)
+	level := 7
+	f.thresholds.Return([]schemas.GradeThreshold{
+		{UnitId: f.unitId, MinScore: 70, PredicateA: 90, PredicateB: 80, PredicateC: 70},
+		{UnitId: f.unitId, SubjectId: &f.math.Id, Level: &level, MinScore: 75, PredicateA: 92, PredicateB: 84, PredicateC: 75},
+	}, nil)
+
+	card, err := f.uc.Get(f.unitId, f.enrollment.Id, 1, admin)
+	assert.NoError(t, err)
+	// The maths rule for level 7 applies: 80 is a C against 84 for a B
+	assert.Equal(t, 75.0, *card.Subjects[0].MinScore)
+	assert.Equal(t, "C", *card.Subjects[0].Predicate)
+
+	printable, err := f.uc.GetPrintable(f.unitId, f.enrollment.Id, 1, admin)
+	assert.NoError(t, err)
+	var out bytes.Buffer
+	assert.NoError(t, WriteReportCardPDF(&out, printable))
+	assert.Contains(t, out.String(), "(Predikat)")
+}
+
+func TestFinalize_FreezesResults(t *testing.T) {
+	f := newFixture()
+	f.repo.On("FindReportCard", f.enrollment.Id, 1).Return(nil, gorm.ErrRecordNotFound)
+	f.repo.On("SaveReportCard", mock.Anything, mock.Anything).Return(nil)
+
+	homeroom := Editor{UserId: f.homeroom.UserId, Role: schemas.UnitMemberRoleStaff}
+	card, err := f.uc.Finalize(f.unitId, f.enrollment.Id, 1, homeroom)
+	assert.NoError(t, err)
+	assert.Equal(t, schemas.ReportCardFinalized, card.Status)
+	assert.Equal(t, &f.now, card.FinalizedAt)
+	assert.NotNil(t, card.Id)
+
+	saved := f.repo.Calls[len(f.repo.Calls)-1].Arguments.Get(0).(*schemas.ReportCard)
+	assert.Equal(t, schemas.ReportCardFinalized, saved.Status)
+	assert.Equal(t, 3, saved.Sick)
+	assert.Len(t, saved.Subjects, 1)
+	assert.Equal(t, 1, saved.Subjects[0].Position)
+	assert.Equal(t, "Matematika", saved.Subjects[0].SubjectName)
+	assert.Len(t, saved.Activities, 1)
+}
+
+func TestLifecycle_Refusals(t *testing.T) {
+	f := newFixture()
+	other := Editor{UserId: uuid.New(), Role: schemas.UnitMemberRoleStaff}
+	f.repo.On("FindTeacherByUser", f.unitId, other.UserId).Return(&schemas.TeacherProfile{Id: uuid.New()}, nil)
+	homeroom := Editor{UserId: f.homeroom.UserId, Role: schemas.UnitMemberRoleStaff}
+	card := f.stored(schemas.ReportCardDraft)
+
+	_, err := f.uc.Finalize(f.unitId, f.enrollment.Id, 1, other)
+	assert.ErrorIs(t, err, ErrNotHomeroom)
+	_, err = f.uc.Publish(f.unitId, f.enrollment.Id, 1, admin)
+	assert.ErrorIs(t, err, ErrNotFinalized)
+	_, err = f.uc.Reopen(f.unitId, f.enrollment.Id, 1, admin)
+	assert.ErrorIs(t, err, ErrNotFinalized)
+
+	card.Status = schemas.ReportCardFinalized
+	_, err = f.uc.UpdateDraft(&DraftRequest{UnitId: f.unitId, EnrollmentId: f.enrollment.Id, Semester: 1, Editor: homeroom})
+	assert.ErrorIs(t, err, ErrNotDraft)
+	_, err = f.uc.Publish(f.unitId, f.enrollment.Id, 1, homeroom)
+	assert.ErrorIs(t, err, ErrNotPublisher)
+
+	card.Status = schemas.ReportCardPublished
+	_, err = f.uc.Reopen(f.unitId, f.enrollment.Id, 1, admin)
+	assert.ErrorIs(t, err, ErrPublished)
+	_, err = f.uc.Publish(f.unitId, f.enrollment.Id, 1, admin)
+	assert.ErrorIs(t, err, ErrPublished)
+
+	f.repo.AssertNotCalled(t, "SaveReportCard", mock.Anything, mock.Anything)
+}
+
+func TestPublishAndReopen(t *testing.T) {
+	f := newFixture()
+	f.repo.On("SaveReportCard", mock.Anything, mock.Anything).Return(nil)
+	card := f.stored(schemas.ReportCardFinalized)
+	card.Subjects = []schemas.ReportCardSubject{{SubjectName: "Matematika", Position: 1}}
+	finalizedAt := f.now.AddDate(0, 0, -1)
+	card.FinalizedAt = &finalizedAt
+
+	view, err := f.uc.Reopen(f.unitId, f.enrollment.Id, 1, admin)
+	assert.NoError(t, err)
+	assert.Equal(t, schemas.ReportCardDraft, view.Status)
+	assert.Nil(t, card.Subjects)
+	assert.Nil(t, card.FinalizedAt)
+
+	card.Status = schemas.ReportCardFinalized
+	view, err = f.uc.Publish(f.unitId, f.enrollment.Id, 1, admin)
+	assert.NoError(t, err)
+	assert.Equal(t, schemas.ReportCardPublished, view.Status)
+	assert.Equal(t, &f.now, view.PublishedAt)
+	assert.Equal(t, &admin.UserId, card.PublishedBy)
+}
+
+func TestPublish_ChangedMeanwhile(t *testing.T) {
+	f := newFixture()
+	f.repo.On("SaveReportCard", mock.Anything, schemas.ReportCardFinalized).Return(report_card_repository.ErrStatusChanged)
+	f.stored(schemas.ReportCardFinalized)
+
+	// Reopened or published by someone else since it was loaded
+	_, err := f.uc.Publish(f.unitId, f.enrollment.Id, 1, admin)
+	assert.ErrorIs(t, err, ErrChangedMeanwhile)
+}
+
+func TestGet_StudentsAndParentsSeeOwnPublished(t *testing.T) {
+	f := newFixture()
+	studentId, parentId := uuid.New(), uuid.New()
+	f.enrollment.StudentProfile.UserId = studentId
+	f.enrollment.StudentProfile.ParentUserId = &parentId
+	student := Editor{UserId: studentId, Role: schemas.UnitMemberRoleAnggota}
+	parent := Editor{UserId: parentId, Role: schemas.UnitMemberRoleParent}
+	card := f.stored(schemas.ReportCardFinalized)
+
+	_, err := f.uc.Get(f.unitId, f.enrollment.Id, 1, student)
+	assert.ErrorIs(t, err, ErrNotPublishedYet)
+
+	card.Status = schemas.ReportCardPublished
+	_, err = f.uc.Get(f.unitId, f.enrollment.Id, 1, student)
+	assert.NoError(t, err)
+	_, err = f.uc.GetPrintable(f.unitId, f.enrollment.Id, 1, parent)
+	assert.NoError(t, err)
+
+	// Another student, or a parent of another student, of the same unit
+	_, err = f.uc.Get(f.unitId, f.enrollment.Id, 1, Editor{UserId: uuid.New(), Role: schemas.UnitMemberRoleAnggota})
+	assert.ErrorIs(t, err, ErrEnrollmentNotFound)
+	_, err = f.uc.Get(f.unitId, f.enrollment.Id, 1, Editor{UserId: studentId, Role: schemas.UnitMemberRoleParent})
+	assert.ErrorIs(t, err, ErrEnrollmentNotFound)
+}
+
+func TestUpdateDraft_ActivityNotes(t *testing.T) {
+	f := newFixture()
+	f.repo.On("SaveReportCard", mock.Anything, mock.Anything).Return(nil)
+	f.stored(schemas.ReportCardDraft)
+
+	note := "Aktif mengikuti latihan rutin"
+	homeroomNote := "Pertahankan semangat belajarmu."
+	view, err := f.uc.UpdateDraft(&DraftRequest{
+		UnitId: f.unitId, EnrollmentId: f.enrollment.Id, Semester: 1, Editor: admin,
+		HomeroomNote: &homeroomNote, ActivityNotes: map[uuid.UUID]*string{f.pramuka.Id: &note},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &homeroomNote, view.HomeroomNote)
+	assert.Equal(t, &note, view.Activities[0].Note)
+
+	_, err = f.uc.UpdateDraft(&DraftRequest{
+		UnitId: f.unitId, EnrollmentId: f.enrollment.Id, Semester: 1, Editor: admin,
+		ActivityNotes: map[uuid.UUID]*string{uuid.New(): &note},
+	})
+	assert.Error(t, err)
+}
+
+func TestDescribe(t *testing.T) {
+	template := schemas.DefaultReportCardTemplate(uuid.New())
+	a, b, c := "membaca", "menulis", "berbicara"
+	assessments := []schemas.Assessment{
+		{Id: uuid.New(), MaxScore: 100, Competency: &a},
+		{Id: uuid.New(), MaxScore: 100, Competency: &b},
+		{Id: uuid.New(), MaxScore: 100, Competency: &c},
+		{Id: uuid.New(), MaxScore: 100}, // PAS without a competency
+	}
+	scores := map[uuid.UUID]float64{assessments[0].Id: 90, assessments[1].Id: 90, assessments[2].Id: 60, assessments[3].Id: 10}
+
+	assert.Equal(t, "Ananda Ani menunjukkan penguasaan yang baik dalam membaca dan menulis. Ananda Ani perlu bantuan dalam berbicara.",
+		describe(template, "Ani", assessments, scores))
+
+	// Mastered evenly: nothing singled out as needing help
+	scores[assessments[2].Id] = 90
+	assert.Equal(t, "Ananda Ani menunjukkan penguasaan yang baik dalam membaca, menulis, dan berbicara.",
+		describe(template, "Ani", assessments, scores))
+
+	assert.Equal(t, "", describe(template, "Ani", assessments[3:], scores))
+}
+
+func TestUpdateTemplate_Validation(t *testing.T) {
+	f := newFixture()
+	f.repo.On("SaveTemplate", mock.Anything).Return(nil)
+
+	paper := "f4"
+	template, err := f.uc.UpdateTemplate(&TemplateRequest{UnitId: f.unitId, PaperSize: &paper})
+	assert.NoError(t, err)
+	assert.Equal(t, "F4", template.PaperSize)
+
+	letter := "Letter"
+	_, err = f.uc.UpdateTemplate(&TemplateRequest{UnitId: f.unitId, PaperSize: &letter})
+	assert.Error(t, err)
+	wording := "Ananda {nama} sudah baik."
+	_, err = f.uc.UpdateTemplate(&TemplateRequest{UnitId: f.unitId, DescriptionHigh: &wording})
+	assert.Error(t, err)
+}
+
+func TestWriteReportCardPDF(t *testing.T) {
+	f := newFixture()
+	f.repo.On("FindReportCard", f.enrollment.Id, 1).Return(nil, gorm.ErrRecordNotFound)
+
+	printable, err := f.uc.GetPrintable(f.unitId, f.enrollment.Id, 1, admin)
+	assert.NoError(t, err)
+	assert.Equal(t, "SMP Al Falah", printable.SchoolName)
+
+	var out bytes.Buffer
+	assert.NoError(t, WriteReportCardPDF(&out, printable))
+	pdf := out.String()
+	assert.True(t, strings.HasPrefix(pdf, "%PDF-"))
+	assert.Contains(t, pdf, "(: Ani)")
+	assert.Contains(t, pdf, "(DRAF - BELUM FINAL)")
+	assert.Contains(t, pdf, "(19 Desember 2025)")
+	assert.NotContains(t, pdf, "(Predikat)")
+}
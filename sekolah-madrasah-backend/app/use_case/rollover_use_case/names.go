@@ -0,0 +1,66 @@
+package rollover_use_case
+
+import (
+	"strconv"
+	"strings"
+
+	"sekolah-madrasah/database/schemas"
+)
+
+var romanLevels = [...]string{"", "I", "II", "III", "IV", "V", "VI", "VII", "VIII", "IX", "X", "XI", "XII", "XIII"}
+
+// promoteName proposes the name of a class one level up by raising the level
+// written in it, in Roman or Arabic numerals: "VII A" becomes "VIII A", "7B"
+// becomes "8B". A name without its level stays as it is.
+func promoteName(name string, level int) string {
+	if level < 1 || level >= len(romanLevels)-1 {
+		return name
+	}
+	digits := strconv.Itoa(level)
+	fields := strings.Fields(name)
+	for i, field := range fields {
+		if strings.ToUpper(field) == romanLevels[level] {
+			fields[i] = romanLevels[level+1]
+			return strings.Join(fields, " ")
+		}
+		if strings.HasPrefix(field, digits) && (len(field) == len(digits) || field[len(digits)] < '0' || field[len(digits)] > '9') {
+			fields[i] = strconv.Itoa(level+1) + field[len(digits):]
+			return strings.Join(fields, " ")
+		}
+	}
+	return name
+}
+
+// nextYear follows an academic year such as "2025/2026" with "2026/2027", or
+// returns "" when it is written otherwise.
+func nextYear(academicYear string) string {
+	parts := strings.Split(academicYear, "/")
+	if len(parts) != 2 {
+		return ""
+	}
+	first, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return ""
+	}
+	second, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil || second != first+1 {
+		return ""
+	}
+	return strconv.Itoa(second) + "/" + strconv.Itoa(second+1)
+}
+
+// nameKey compares class names regardless of case and spacing.
+func nameKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+func studentName(enrollment schemas.ClassEnrollment) (string, *string) {
+	if enrollment.StudentProfile == nil {
+		return "", nil
+	}
+	name := ""
+	if enrollment.StudentProfile.User != nil {
+		name = enrollment.StudentProfile.User.FullName
+	}
+	return name, enrollment.StudentProfile.NIS
+}
@@ -0,0 +1,471 @@
+package rollover_use_case
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"sekolah-madrasah/app/repository/rollover_repository"
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNoClasses          = errors.New("the unit has no classes in this academic year")
+	ErrEnrollmentNotFound = errors.New("enrollment is not an active student of this academic year")
+	ErrTargetNotFound     = errors.New("target class is neither a class of this academic year nor one of the next")
+	ErrAlreadyRolledOver  = errors.New("some students were moved on meanwhile, preview the rollover again")
+)
+
+// PromotionAction is what happens to a student at the rollover.
+type PromotionAction string
+
+const (
+	ActionPromote  PromotionAction = "promote"  // Naik kelas, into the clone of their class by default
+	ActionRetain   PromotionAction = "retain"   // Tinggal kelas, into a class of the same level
+	ActionGraduate PromotionAction = "graduate" // Final level, left in place for graduation
+	ActionSkip     PromotionAction = "skip"     // Left in place, e.g. moving away
+)
+
+func (a PromotionAction) IsValid() bool {
+	switch a {
+	case ActionPromote, ActionRetain, ActionGraduate, ActionSkip:
+		return true
+	}
+	return false
+}
+
+type RolloverUseCase interface {
+	// Preview proposes the next year's classes and where each student goes,
+	// with the request's overrides applied, without saving anything.
+	Preview(req *RolloverRequest) (*RolloverPlan, error)
+	// Apply carries the plan out in one transaction: it creates the classes,
+	// closes the old enrollments as promoted or retained and enrolls the
+	// students in their new classes.
+	Apply(req *RolloverRequest) (*RolloverPlan, error)
+}
+
+type RolloverRequest struct {
+	UnitId     uuid.UUID
+	FromYear   string               // Defaults to the unit's current academic year
+	ToYear     string               // Defaults to the year after FromYear
+	FinalLevel int                  // Defaults to the highest level among FromYear's classes
+	Date       *time.Time           // When old enrollments end and new ones start, defaults to today
+	ClassNames map[uuid.UUID]string // Name of the class cloned from a FromYear class, instead of the proposed one
+	Overrides  []Override
+}
+
+// Override changes what happens to one student.
+type Override struct {
+	ClassEnrollmentId uuid.UUID
+	Action            PromotionAction
+	// ToClassId is the class to join: a FromYear class to join its clone, or a
+	// class already created for ToYear. Defaults to the clone of the student's
+	// class when promoted, and to the new class named like it when retained.
+	ToClassId *uuid.UUID
+}
+
+// ClassPlan is a class of the new year, cloned from one of the old.
+type ClassPlan struct {
+	SourceClassId uuid.UUID `json:"source_class_id"`
+	SourceName    string    `json:"source_name"`
+	ClassId       uuid.UUID `json:"class_id"` // The existing class when reused, otherwise the ID it is created with
+	Name          string    `json:"name"`
+	Level         int       `json:"level"`
+	Existing      bool      `json:"existing"` // A class of the new year with this name is reused rather than created
+	Students      int       `json:"students"` // Students going into it
+}
+
+type StudentPlan struct {
+	ClassEnrollmentId uuid.UUID       `json:"class_enrollment_id"`
+	StudentProfileId  uuid.UUID       `json:"student_profile_id"`
+	StudentName       string          `json:"student_name"`
+	NIS               *string         `json:"nis"`
+	FromClassId       uuid.UUID       `json:"from_class_id"`
+	FromClassName     string          `json:"from_class_name"`
+	FromLevel         int             `json:"from_level"`
+	Action            PromotionAction `json:"action"`
+	ToClassId         *uuid.UUID      `json:"to_class_id"` // Null when graduating or skipped
+	ToClassName       string          `json:"to_class_name"`
+	Note              string          `json:"note,omitempty"` // Why a student was skipped
+}
+
+type RolloverPlan struct {
+	FromYear   string        `json:"from_year"`
+	ToYear     string        `json:"to_year"`
+	FinalLevel int           `json:"final_level"`
+	Date       time.Time     `json:"date"`
+	Applied    bool          `json:"applied"`
+	Classes    []ClassPlan   `json:"classes"`
+	Students   []StudentPlan `json:"students"`
+	Promoted   int           `json:"promoted"`
+	Retained   int           `json:"retained"`
+	Graduating int           `json:"graduating"`
+	Skipped    int           `json:"skipped"`
+}
+
+type rolloverUseCase struct {
+	repo rollover_repository.RolloverRepository
+	now  func() time.Time
+}
+
+func NewRolloverUseCase(repo rollover_repository.RolloverRepository) RolloverUseCase {
+	return &rolloverUseCase{repo: repo, now: time.Now}
+}
+
+// destination is a class of the new year a student can be placed in.
+type destination struct {
+	id    uuid.UUID
+	name  string
+	level int
+	plan  *ClassPlan // Nil for a class created beforehand and not cloned
+}
+
+// proposal is a plan with what it takes to carry it out.
+type proposal struct {
+	plan        *RolloverPlan
+	sources     map[uuid.UUID]schemas.Class
+	enrollments map[uuid.UUID]schemas.ClassEnrollment
+}
+
+func (uc *rolloverUseCase) Preview(req *RolloverRequest) (*RolloverPlan, error) {
+	proposal, err := uc.build(req)
+	if err != nil {
+		return nil, err
+	}
+	return proposal.plan, nil
+}
+
+func (uc *rolloverUseCase) Apply(req *RolloverRequest) (*RolloverPlan, error) {
+	proposal, err := uc.build(req)
+	if err != nil {
+		return nil, err
+	}
+	plan := proposal.plan
+
+	classes := make([]schemas.Class, 0, len(plan.Classes))
+	for _, class := range plan.Classes {
+		if class.Existing {
+			continue
+		}
+		source := proposal.sources[class.SourceClassId]
+		classes = append(classes, schemas.Class{
+			Id:                class.ClassId,
+			UnitId:            req.UnitId,
+			Name:              class.Name,
+			Level:             class.Level,
+			AcademicYear:      plan.ToYear,
+			HomeroomTeacherId: source.HomeroomTeacherId,
+			Capacity:          source.Capacity,
+			IsActive:          true,
+		})
+	}
+
+	var closed, opened []schemas.ClassEnrollment
+	for _, student := range plan.Students {
+		if student.ToClassId == nil {
+			continue
+		}
+		old := proposal.enrollments[student.ClassEnrollmentId]
+		leftAt := plan.Date
+		old.LeftAt = &leftAt
+		var note string
+		if student.Action == ActionRetain {
+			old.Status = schemas.EnrollmentStatusRetained
+			note = fmt.Sprintf("Retained in %s for %s", student.ToClassName, plan.ToYear)
+		} else {
+			old.Status = schemas.EnrollmentStatusPromoted
+			note = fmt.Sprintf("Promoted to %s for %s", student.ToClassName, plan.ToYear)
+		}
+		old.Notes = &note
+		closed = append(closed, old)
+
+		opened = append(opened, schemas.ClassEnrollment{
+			Id:               uuid.New(),
+			StudentProfileId: student.StudentProfileId,
+			ClassId:          *student.ToClassId,
+			AcademicYear:     plan.ToYear,
+			Status:           schemas.EnrollmentStatusActive,
+			EnrolledAt:       plan.Date,
+		})
+	}
+
+	if err := uc.repo.Apply(classes, closed, opened); err != nil {
+		if errors.Is(err, rollover_repository.ErrEnrollmentClosed) {
+			return nil, ErrAlreadyRolledOver
+		}
+		return nil, err
+	}
+	plan.Applied = true
+	return plan, nil
+}
+
+// build works out the new year's classes and each student's destination.
+func (uc *rolloverUseCase) build(req *RolloverRequest) (*proposal, error) {
+	settings, err := uc.settings(req.UnitId)
+	if err != nil {
+		return nil, err
+	}
+	fromYear := strings.TrimSpace(req.FromYear)
+	if fromYear == "" {
+		fromYear = settings.AcademicYear
+	}
+	if fromYear == "" {
+		return nil, errors.New("from_year is required until the unit's current academic year is set")
+	}
+	toYear := strings.TrimSpace(req.ToYear)
+	if toYear == "" {
+		toYear = nextYear(fromYear)
+	}
+	if toYear == "" {
+		return nil, fmt.Errorf("to_year is required when from_year %q is not like 2025/2026", fromYear)
+	}
+	if toYear == fromYear {
+		return nil, errors.New("to_year must differ from from_year")
+	}
+
+	sources, err := uc.repo.FindClasses(req.UnitId, fromYear)
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return nil, ErrNoClasses
+	}
+	finalLevel := req.FinalLevel
+	if finalLevel == 0 {
+		for _, class := range sources {
+			if class.Level > finalLevel {
+				finalLevel = class.Level
+			}
+		}
+	}
+	if finalLevel < 1 || finalLevel > 12 {
+		return nil, errors.New("final_level must be between 1 and 12")
+	}
+	date := uc.now()
+	if req.Date != nil {
+		date = *req.Date
+	}
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+
+	plan := &RolloverPlan{
+		FromYear:   fromYear,
+		ToYear:     toYear,
+		FinalLevel: finalLevel,
+		Date:       date,
+		Classes:    []ClassPlan{},
+		Students:   []StudentPlan{},
+	}
+	result := &proposal{
+		plan:        plan,
+		sources:     make(map[uuid.UUID]schemas.Class, len(sources)),
+		enrollments: map[uuid.UUID]schemas.ClassEnrollment{},
+	}
+	for _, class := range sources {
+		result.sources[class.Id] = class
+	}
+
+	destinations, byName, err := uc.classes(req, plan, sources)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.students(req, result, destinations, byName); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// classes proposes a clone at the next level of every class below the final
+// level, reusing a class of the new year that already has the name. It
+// returns every class students can go to, by the ID a request may name it
+// with and by name.
+func (uc *rolloverUseCase) classes(req *RolloverRequest, plan *RolloverPlan, sources []schemas.Class) (map[uuid.UUID]*destination, map[string]*destination, error) {
+	existing, err := uc.repo.FindClasses(req.UnitId, plan.ToYear)
+	if err != nil {
+		return nil, nil, err
+	}
+	destinations := map[uuid.UUID]*destination{}
+	byName := map[string]*destination{}
+	for _, class := range existing {
+		target := &destination{id: class.Id, name: class.Name, level: class.Level}
+		destinations[class.Id] = target
+		byName[nameKey(class.Name)] = target
+	}
+
+	sourceIds := make(map[uuid.UUID]bool, len(sources))
+	for _, source := range sources {
+		sourceIds[source.Id] = true
+	}
+	for id := range req.ClassNames {
+		if !sourceIds[id] {
+			return nil, nil, fmt.Errorf("class %s in class_names is not a class of %s", id, plan.FromYear)
+		}
+	}
+
+	cloned := map[string]bool{}
+	for _, source := range sources {
+		if source.Level >= plan.FinalLevel {
+			continue
+		}
+		name := promoteName(source.Name, source.Level)
+		if custom, ok := req.ClassNames[source.Id]; ok {
+			name = strings.TrimSpace(custom)
+		}
+		if name == "" || len(name) > 50 {
+			return nil, nil, fmt.Errorf("the name of the class following %s must be 1 to 50 characters", source.Name)
+		}
+		if cloned[nameKey(name)] {
+			return nil, nil, fmt.Errorf("two classes of %s would be named %s", plan.ToYear, name)
+		}
+		cloned[nameKey(name)] = true
+
+		class := ClassPlan{SourceClassId: source.Id, SourceName: source.Name, Name: name, Level: source.Level + 1}
+		if target, ok := byName[nameKey(name)]; ok {
+			if target.level != class.Level {
+				return nil, nil, fmt.Errorf("%s of %s is level %d, not %d", target.name, plan.ToYear, target.level, class.Level)
+			}
+			class.ClassId, class.Name, class.Existing = target.id, target.name, true
+		} else {
+			class.ClassId = uuid.New()
+		}
+		plan.Classes = append(plan.Classes, class)
+	}
+
+	for i := range plan.Classes {
+		class := &plan.Classes[i]
+		target := byName[nameKey(class.Name)]
+		if target == nil {
+			target = &destination{id: class.ClassId, name: class.Name, level: class.Level}
+			byName[nameKey(class.Name)] = target
+			destinations[class.ClassId] = target
+		}
+		target.plan = class
+		destinations[class.SourceClassId] = target
+	}
+	return destinations, byName, nil
+}
+
+// students decides where each active student of the old year goes: up into
+// the clone of their class, or left for graduation at the final level,
+// unless overridden. Students already enrolled in the new year are skipped.
+func (uc *rolloverUseCase) students(req *RolloverRequest, result *proposal, destinations map[uuid.UUID]*destination, byName map[string]*destination) error {
+	plan := result.plan
+	enrollments, err := uc.repo.FindActiveEnrollments(req.UnitId, plan.FromYear)
+	if err != nil {
+		return err
+	}
+	already, err := uc.repo.FindActiveEnrollments(req.UnitId, plan.ToYear)
+	if err != nil {
+		return err
+	}
+	enrolled := make(map[uuid.UUID]bool, len(already))
+	for _, enrollment := range already {
+		enrolled[enrollment.StudentProfileId] = true
+	}
+	for _, enrollment := range enrollments {
+		result.enrollments[enrollment.Id] = enrollment
+	}
+
+	overrides := make(map[uuid.UUID]Override, len(req.Overrides))
+	for _, override := range req.Overrides {
+		if _, ok := result.enrollments[override.ClassEnrollmentId]; !ok {
+			return fmt.Errorf("%w: %s", ErrEnrollmentNotFound, override.ClassEnrollmentId)
+		}
+		if !override.Action.IsValid() {
+			return fmt.Errorf("invalid action %q", override.Action)
+		}
+		if _, ok := overrides[override.ClassEnrollmentId]; ok {
+			return fmt.Errorf("enrollment %s is overridden twice", override.ClassEnrollmentId)
+		}
+		overrides[override.ClassEnrollmentId] = override
+	}
+
+	for _, enrollment := range enrollments {
+		class := result.sources[enrollment.ClassId]
+		student := StudentPlan{
+			ClassEnrollmentId: enrollment.Id,
+			StudentProfileId:  enrollment.StudentProfileId,
+			FromClassId:       class.Id,
+			FromClassName:     class.Name,
+			FromLevel:         class.Level,
+			Action:            ActionPromote,
+		}
+		student.StudentName, student.NIS = studentName(enrollment)
+		if class.Level >= plan.FinalLevel {
+			student.Action = ActionGraduate
+		}
+		override, overridden := overrides[enrollment.Id]
+		if overridden {
+			student.Action = override.Action
+		}
+		if enrolled[enrollment.StudentProfileId] {
+			if overridden && (override.Action == ActionPromote || override.Action == ActionRetain) {
+				return fmt.Errorf("%s is already enrolled in a class of %s", student.StudentName, plan.ToYear)
+			}
+			student.Action, student.Note = ActionSkip, "already enrolled in "+plan.ToYear
+		}
+
+		var target *destination
+		switch {
+		case student.Action != ActionPromote && student.Action != ActionRetain:
+		case overridden && override.ToClassId != nil:
+			target = destinations[*override.ToClassId]
+			if target == nil {
+				return fmt.Errorf("%w: %s", ErrTargetNotFound, *override.ToClassId)
+			}
+		case student.Action == ActionPromote:
+			target = destinations[class.Id]
+			if target == nil {
+				return fmt.Errorf("%s is in the final level; retain them, leave them to graduate or give to_class_id", student.StudentName)
+			}
+		default:
+			target = byName[nameKey(class.Name)]
+			if target == nil || target.level != class.Level {
+				return fmt.Errorf("no level %d class of %s is named %s for %s to repeat the year in; give to_class_id", class.Level, plan.ToYear, class.Name, student.StudentName)
+			}
+		}
+		if target != nil {
+			id := target.id
+			student.ToClassId, student.ToClassName = &id, target.name
+			if target.plan != nil {
+				target.plan.Students++
+			}
+		}
+
+		switch student.Action {
+		case ActionPromote:
+			plan.Promoted++
+		case ActionRetain:
+			plan.Retained++
+		case ActionGraduate:
+			plan.Graduating++
+		default:
+			plan.Skipped++
+		}
+		plan.Students = append(plan.Students, student)
+	}
+
+	sort.SliceStable(plan.Students, func(i, j int) bool {
+		a, b := plan.Students[i], plan.Students[j]
+		if a.FromLevel != b.FromLevel {
+			return a.FromLevel < b.FromLevel
+		}
+		if a.FromClassName != b.FromClassName {
+			return a.FromClassName < b.FromClassName
+		}
+		return a.StudentName < b.StudentName
+	})
+	return nil
+}
+
+func (uc *rolloverUseCase) settings(unitId uuid.UUID) (*schemas.UnitSettings, error) {
+	settings, err := uc.repo.FindSettings(unitId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &schemas.UnitSettings{UnitId: unitId}, nil
+	}
+	return settings, err
+}
@@ -0,0 +1,293 @@
+package rollover_use_case
+
+import (
+	"testing"
+	"time"
+
+	"sekolah-madrasah/app/repository/rollover_repository"
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRepository is a mock implementation of RolloverRepository
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) FindSettings(unitId uuid.UUID) (*schemas.UnitSettings, error) {
+	args := m.Called(unitId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.UnitSettings), args.Error(1)
+}
+
+func (m *MockRepository) FindClasses(unitId uuid.UUID, academicYear string) ([]schemas.Class, error) {
+	args := m.Called(unitId, academicYear)
+	return args.Get(0).([]schemas.Class), args.Error(1)
+}
+
+func (m *MockRepository) FindActiveEnrollments(unitId uuid.UUID, academicYear string) ([]schemas.ClassEnrollment, error) {
+	args := m.Called(unitId, academicYear)
+	return args.Get(0).([]schemas.ClassEnrollment), args.Error(1)
+}
+
+func (m *MockRepository) Apply(classes []schemas.Class, closed []schemas.ClassEnrollment, opened []schemas.ClassEnrollment) error {
+	args := m.Called(classes, closed, opened)
+	return args.Error(0)
+}
+
+type fixture struct {
+	repo             *MockRepository
+	uc               *rolloverUseCase
+	unitId           uuid.UUID
+	viiA, viiB, ixA  schemas.Class
+	ani, budi, citra schemas.ClassEnrollment
+	nextClasses      *mock.Call // Classes already created for 2026/2027, none by default
+	nextEnrollments  *mock.Call // Students already enrolled in 2026/2027, none by default
+}
+
+// newFixture sets up an SMP in 2025/2026 with two level 7 classes and the
+// final level 9 class, Ani and Budi in VII A and Citra in IX A.
+func newFixture() *fixture {
+	unitId := uuid.New()
+	homeroom := uuid.New()
+	f := &fixture{
+		repo:   new(MockRepository),
+		unitId: unitId,
+		viiA:   schemas.Class{Id: uuid.New(), UnitId: unitId, Name: "VII A", Level: 7, AcademicYear: "2025/2026", HomeroomTeacherId: &homeroom, Capacity: 32},
+		viiB:   schemas.Class{Id: uuid.New(), UnitId: unitId, Name: "VII B", Level: 7, AcademicYear: "2025/2026", Capacity: 30},
+		ixA:    schemas.Class{Id: uuid.New(), UnitId: unitId, Name: "IX A", Level: 9, AcademicYear: "2025/2026", Capacity: 30},
+	}
+	f.uc = NewRolloverUseCase(f.repo).(*rolloverUseCase)
+	f.uc.now = func() time.Time { return time.Date(2026, 6, 27, 9, 30, 0, 0, time.UTC) }
+	f.ani = enrollment(f.viiA, "Ani")
+	f.budi = enrollment(f.viiA, "Budi")
+	f.citra = enrollment(f.ixA, "Citra")
+
+	f.repo.On("FindSettings", unitId).Return(&schemas.UnitSettings{UnitId: unitId, AcademicYear: "2025/2026"}, nil)
+	f.repo.On("FindClasses", unitId, "2025/2026").Return([]schemas.Class{f.viiA, f.viiB, f.ixA}, nil)
+	f.nextClasses = f.repo.On("FindClasses", unitId, "2026/2027").Return([]schemas.Class{}, nil)
+	f.repo.On("FindActiveEnrollments", unitId, "2025/2026").Return([]schemas.ClassEnrollment{f.citra, f.budi, f.ani}, nil)
+	f.nextEnrollments = f.repo.On("FindActiveEnrollments", unitId, "2026/2027").Return([]schemas.ClassEnrollment{}, nil)
+	return f
+}
+
+func enrollment(class schemas.Class, name string) schemas.ClassEnrollment {
+	return schemas.ClassEnrollment{
+		Id:               uuid.New(),
+		StudentProfileId: uuid.New(),
+		ClassId:          class.Id,
+		AcademicYear:     class.AcademicYear,
+		Status:           schemas.EnrollmentStatusActive,
+		StudentProfile:   &schemas.StudentProfile{User: &schemas.User{FullName: name}},
+	}
+}
+
+func (f *fixture) request(overrides ...Override) *RolloverRequest {
+	return &RolloverRequest{UnitId: f.unitId, Overrides: overrides}
+}
+
+// Tests
+
+func TestPreview_ProposesClonesAndPromotions(t *testing.T) {
+	f := newFixture()
+
+	plan, err := f.uc.Preview(f.request())
+	assert.NoError(t, err)
+	assert.Equal(t, "2026/2027", plan.ToYear)
+	assert.Equal(t, 9, plan.FinalLevel)
+	assert.Equal(t, time.Date(2026, 6, 27, 0, 0, 0, 0, time.UTC), plan.Date)
+	assert.False(t, plan.Applied)
+
+	// The final level class is not cloned
+	assert.Len(t, plan.Classes, 2)
+	assert.Equal(t, "VIII A", plan.Classes[0].Name)
+	assert.Equal(t, 8, plan.Classes[0].Level)
+	assert.Equal(t, 2, plan.Classes[0].Students)
+	assert.Equal(t, "VIII B", plan.Classes[1].Name)
+	assert.Equal(t, 0, plan.Classes[1].Students)
+
+	// Sorted by level, class and name
+	assert.Len(t, plan.Students, 3)
+	assert.Equal(t, "Ani", plan.Students[0].StudentName)
+	assert.Equal(t, ActionPromote, plan.Students[0].Action)
+	assert.Equal(t, plan.Classes[0].ClassId, *plan.Students[0].ToClassId)
+	assert.Equal(t, "Citra", plan.Students[2].StudentName)
+	assert.Equal(t, ActionGraduate, plan.Students[2].Action)
+	assert.Nil(t, plan.Students[2].ToClassId)
+	assert.Equal(t, 2, plan.Promoted)
+	assert.Equal(t, 1, plan.Graduating)
+	f.repo.AssertNotCalled(t, "Apply", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPreview_Overrides(t *testing.T) {
+	f := newFixture()
+	retained := schemas.Class{Id: uuid.New(), UnitId: f.unitId, Name: "VII A", Level: 7, AcademicYear: "2026/2027"}
+	f.nextClasses.Return([]schemas.Class{retained}, nil)
+
+	plan, err := f.uc.Preview(f.request(
+		Override{ClassEnrollmentId: f.ani.Id, Action: ActionRetain},
+		Override{ClassEnrollmentId: f.budi.Id, Action: ActionPromote, ToClassId: &f.viiB.Id},
+	))
+	assert.NoError(t, err)
+
+	// Ani repeats in the VII A already created for the new year; Budi joins the clone of VII B
+	ani, budi := plan.Students[0], plan.Students[1]
+	assert.Equal(t, ActionRetain, ani.Action)
+	assert.Equal(t, retained.Id, *ani.ToClassId)
+	assert.Equal(t, "VIII B", budi.ToClassName)
+	assert.Equal(t, 0, plan.Classes[0].Students)
+	assert.Equal(t, 1, plan.Classes[1].Students)
+	assert.Equal(t, 1, plan.Retained)
+}
+
+func TestPreview_Refusals(t *testing.T) {
+	f := newFixture()
+
+	// No level 7 class in the new year for Ani to repeat in
+	_, err := f.uc.Preview(f.request(Override{ClassEnrollmentId: f.ani.Id, Action: ActionRetain}))
+	assert.Error(t, err)
+
+	// Citra is in the final level and has no class to go up into
+	_, err = f.uc.Preview(f.request(Override{ClassEnrollmentId: f.citra.Id, Action: ActionPromote}))
+	assert.Error(t, err)
+
+	_, err = f.uc.Preview(f.request(Override{ClassEnrollmentId: uuid.New(), Action: ActionSkip}))
+	assert.ErrorIs(t, err, ErrEnrollmentNotFound)
+
+	unknown := uuid.New()
+	_, err = f.uc.Preview(f.request(Override{ClassEnrollmentId: f.ani.Id, Action: ActionPromote, ToClassId: &unknown}))
+	assert.ErrorIs(t, err, ErrTargetNotFound)
+
+	_, err = f.uc.Preview(f.request(Override{ClassEnrollmentId: f.ani.Id, Action: "naik"}))
+	assert.Error(t, err)
+
+	req := f.request()
+	req.ClassNames = map[uuid.UUID]string{f.viiB.Id: "VIII A"}
+	_, err = f.uc.Preview(req)
+	assert.Error(t, err)
+
+	req = f.request()
+	req.ToYear = "2025/2026"
+	_, err = f.uc.Preview(req)
+	assert.Error(t, err)
+}
+
+func TestPreview_ReusesExistingClasses(t *testing.T) {
+	f := newFixture()
+	existing := schemas.Class{Id: uuid.New(), UnitId: f.unitId, Name: "viii  a", Level: 8, AcademicYear: "2026/2027"}
+	f.nextClasses.Return([]schemas.Class{existing}, nil)
+
+	plan, err := f.uc.Preview(f.request())
+	assert.NoError(t, err)
+	assert.True(t, plan.Classes[0].Existing)
+	assert.Equal(t, existing.Id, plan.Classes[0].ClassId)
+	assert.Equal(t, existing.Id, *plan.Students[0].ToClassId)
+
+	existing.Level = 9
+	f.nextClasses.Return([]schemas.Class{existing}, nil)
+	_, err = f.uc.Preview(f.request())
+	assert.Error(t, err)
+}
+
+func TestPreview_AlreadyEnrolledSkipped(t *testing.T) {
+	f := newFixture()
+	f.nextEnrollments.Return([]schemas.ClassEnrollment{{StudentProfileId: f.budi.StudentProfileId}}, nil)
+
+	plan, err := f.uc.Preview(f.request())
+	assert.NoError(t, err)
+	assert.Equal(t, ActionSkip, plan.Students[1].Action)
+	assert.Nil(t, plan.Students[1].ToClassId)
+	assert.Equal(t, 1, plan.Skipped)
+
+	_, err = f.uc.Preview(f.request(Override{ClassEnrollmentId: f.budi.Id, Action: ActionPromote}))
+	assert.Error(t, err)
+}
+
+func TestApply_OneTransaction(t *testing.T) {
+	f := newFixture()
+	f.repo.On("Apply", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	req := f.request(Override{ClassEnrollmentId: f.budi.Id, Action: ActionSkip})
+	req.ClassNames = map[uuid.UUID]string{f.viiB.Id: " VIII Al-Fatih "}
+	plan, err := f.uc.Apply(req)
+	assert.NoError(t, err)
+	assert.True(t, plan.Applied)
+	f.repo.AssertNumberOfCalls(t, "Apply", 1)
+
+	args := f.repo.Calls[len(f.repo.Calls)-1].Arguments
+	classes := args.Get(0).([]schemas.Class)
+	assert.Len(t, classes, 2)
+	assert.Equal(t, "VIII A", classes[0].Name)
+	assert.Equal(t, "2026/2027", classes[0].AcademicYear)
+	assert.Equal(t, f.viiA.HomeroomTeacherId, classes[0].HomeroomTeacherId)
+	assert.Equal(t, 32, classes[0].Capacity)
+	assert.Equal(t, "VIII Al-Fatih", classes[1].Name)
+
+	// Only Ani moves: Budi is skipped and Citra is left for graduation
+	closed := args.Get(1).([]schemas.ClassEnrollment)
+	assert.Len(t, closed, 1)
+	assert.Equal(t, f.ani.Id, closed[0].Id)
+	assert.Equal(t, schemas.EnrollmentStatusPromoted, closed[0].Status)
+	assert.Equal(t, time.Date(2026, 6, 27, 0, 0, 0, 0, time.UTC), *closed[0].LeftAt)
+	assert.Equal(t, "Promoted to VIII A for 2026/2027", *closed[0].Notes)
+
+	opened := args.Get(2).([]schemas.ClassEnrollment)
+	assert.Len(t, opened, 1)
+	assert.Equal(t, f.ani.StudentProfileId, opened[0].StudentProfileId)
+	assert.Equal(t, classes[0].Id, opened[0].ClassId)
+	assert.Equal(t, "2026/2027", opened[0].AcademicYear)
+	assert.Equal(t, schemas.EnrollmentStatusActive, opened[0].Status)
+}
+
+func TestApply_AlreadyRolledOver(t *testing.T) {
+	f := newFixture()
+	// A concurrent rollover closed the enrollments first
+	f.repo.On("Apply", mock.Anything, mock.Anything, mock.Anything).Return(rollover_repository.ErrEnrollmentClosed)
+
+	plan, err := f.uc.Apply(f.request())
+
+	assert.ErrorIs(t, err, ErrAlreadyRolledOver)
+	assert.Nil(t, plan)
+}
+
+func TestApply_NothingSavedOnRefusal(t *testing.T) {
+	f := newFixture()
+
+	// The year after one written otherwise must be given
+	req := f.request()
+	req.FromYear = "2025-2026"
+	_, err := f.uc.Apply(req)
+	assert.Error(t, err)
+
+	_, err = f.uc.Apply(f.request(Override{ClassEnrollmentId: f.citra.Id, Action: ActionPromote}))
+	assert.Error(t, err)
+	f.repo.AssertNotCalled(t, "Apply", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPromoteName(t *testing.T) {
+	tests := []struct {
+		name  string
+		level int
+		want  string
+	}{
+		{"VII A", 7, "VIII A"},
+		{"X IPA 1", 10, "XI IPA 1"},
+		{"7B", 7, "8B"},
+		{"Kelas 4 Umar", 4, "Kelas 5 Umar"},
+		{"iv a", 4, "V a"},
+		{"Al-Fatih", 8, "Al-Fatih"},
+		{"71", 7, "71"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, promoteName(tt.name, tt.level), tt.name)
+	}
+
+	assert.Equal(t, "2026/2027", nextYear("2025/2026"))
+	assert.Equal(t, "", nextYear("2025-2026"))
+	assert.Equal(t, "", nextYear("2025/2027"))
+}
@@ -0,0 +1,27 @@
+package sso_use_case
+
+import (
+	"context"
+
+	"sekolah-madrasah/app/use_case/auth_use_case"
+	"sekolah-madrasah/pkg/auth_utils"
+
+	"github.com/google/uuid"
+)
+
+type SsoUseCase interface {
+	GetProvider(ctx context.Context, actor auth_utils.AuthClaim, organizationId uuid.UUID) (SsoProvider, int, error)
+	SaveProvider(ctx context.Context, actor auth_utils.AuthClaim, organizationId uuid.UUID, req SaveProviderRequest) (SsoProvider, int, error)
+	DeleteProvider(ctx context.Context, actor auth_utils.AuthClaim, organizationId uuid.UUID) (int, error)
+
+	// StartLogin returns the identity provider URL to send the browser to.
+	StartLogin(ctx context.Context, organizationId uuid.UUID) (Authorization, int, error)
+	// CompleteLogin redeems the code the provider redirected back with.
+	CompleteLogin(ctx context.Context, req CallbackRequest) (auth_use_case.LoginResponse, int, error)
+}
+
+// ExternalLoginCompleter issues the session once the provider has identified the
+// user. auth_use_case.AuthUseCase satisfies it.
+type ExternalLoginCompleter interface {
+	CompleteExternalLogin(ctx context.Context, req auth_use_case.ExternalLoginRequest) (auth_use_case.LoginResponse, int, error)
+}
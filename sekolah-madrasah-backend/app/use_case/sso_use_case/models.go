@@ -0,0 +1,57 @@
+package sso_use_case
+
+import (
+	"time"
+
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+)
+
+type SsoProvider struct {
+	Id                uuid.UUID
+	OrganizationId    uuid.UUID
+	Issuer            string
+	ClientId          string
+	HasClientSecret   bool
+	RedirectUri       string
+	Scopes            []string
+	GroupsClaim       string
+	GroupRoleMappings []GroupRoleMapping
+	AllowProvisioning bool
+	IsEnabled         bool
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// GroupRoleMapping gives members of an IdP group a role in one unit.
+type GroupRoleMapping struct {
+	Group  string
+	UnitId uuid.UUID
+	Role   schemas.UnitMemberRole
+}
+
+type SaveProviderRequest struct {
+	Issuer   string
+	ClientId string
+	// ClientSecret may be left empty to keep the stored secret, or for public clients
+	ClientSecret      string
+	RedirectUri       string
+	Scopes            []string
+	GroupsClaim       string
+	GroupRoleMappings []GroupRoleMapping
+	AllowProvisioning bool
+	IsEnabled         bool
+}
+
+type Authorization struct {
+	AuthorizationUrl string
+	ExpiresAt        time.Time
+}
+
+type CallbackRequest struct {
+	State     string
+	Code      string
+	UserAgent string
+	IpAddress string
+}
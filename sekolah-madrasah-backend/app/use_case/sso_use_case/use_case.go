@@ -0,0 +1,557 @@
+package sso_use_case
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"sekolah-madrasah/app/repository/organization_repository"
+	"sekolah-madrasah/app/repository/sso_repository"
+	"sekolah-madrasah/app/repository/unit_member_repository"
+	"sekolah-madrasah/app/repository/unit_repository"
+	"sekolah-madrasah/app/repository/user_repository"
+	"sekolah-madrasah/app/use_case/auth_use_case"
+	"sekolah-madrasah/config"
+	"sekolah-madrasah/database/schemas"
+	"sekolah-madrasah/pkg/auth_utils"
+	"sekolah-madrasah/pkg/crypto_utils"
+	"sekolah-madrasah/pkg/oidc_utils"
+
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// LoginStateDuration is how long the user has to finish signing in at the provider
+	LoginStateDuration = 10 * time.Minute
+
+	defaultGroupsClaim = "groups"
+)
+
+var (
+	defaultScopes = []string{"openid", "email", "profile"}
+
+	errNotConfigured = errors.New("single sign-on is not configured for this organization")
+
+	// mappableRoles ranks the unit roles an IdP group may grant; owner is only
+	// ever assigned by hand
+	mappableRoles = map[schemas.UnitMemberRole]int{
+		schemas.UnitMemberRoleAdmin:    5,
+		schemas.UnitMemberRolePengurus: 4,
+		schemas.UnitMemberRoleStaff:    3,
+		schemas.UnitMemberRoleParent:   2,
+		schemas.UnitMemberRoleAnggota:  1,
+	}
+)
+
+type ssoUseCase struct {
+	ssoRepo        sso_repository.SsoRepository
+	orgRepo        organization_repository.OrganizationRepository
+	unitRepo       unit_repository.UnitRepository
+	unitMemberRepo unit_member_repository.UnitMemberRepository
+	userRepo       user_repository.UserRepository
+	loginCompleter ExternalLoginCompleter
+	oidcClient     *oidc_utils.Client
+}
+
+func NewSsoUseCase(
+	ssoRepo sso_repository.SsoRepository,
+	orgRepo organization_repository.OrganizationRepository,
+	unitRepo unit_repository.UnitRepository,
+	unitMemberRepo unit_member_repository.UnitMemberRepository,
+	userRepo user_repository.UserRepository,
+	loginCompleter ExternalLoginCompleter,
+	oidcClient *oidc_utils.Client,
+) SsoUseCase {
+	return &ssoUseCase{
+		ssoRepo:        ssoRepo,
+		orgRepo:        orgRepo,
+		unitRepo:       unitRepo,
+		unitMemberRepo: unitMemberRepo,
+		userRepo:       userRepo,
+		loginCompleter: loginCompleter,
+		oidcClient:     oidcClient,
+	}
+}
+
+func (u *ssoUseCase) toProvider(p sso_repository.SsoProvider) SsoProvider {
+	mappings := make([]GroupRoleMapping, len(p.GroupRoleMappings))
+	for i, m := range p.GroupRoleMappings {
+		mappings[i] = GroupRoleMapping{Group: m.Group, UnitId: m.UnitId, Role: m.Role}
+	}
+
+	return SsoProvider{
+		Id:                p.Id,
+		OrganizationId:    p.OrganizationId,
+		Issuer:            p.Issuer,
+		ClientId:          p.ClientId,
+		HasClientSecret:   p.ClientSecret != "",
+		RedirectUri:       p.RedirectUri,
+		Scopes:            p.Scopes,
+		GroupsClaim:       p.GroupsClaim,
+		GroupRoleMappings: mappings,
+		AllowProvisioning: p.AllowProvisioning,
+		IsEnabled:         p.IsEnabled,
+		CreatedAt:         p.CreatedAt,
+		UpdatedAt:         p.UpdatedAt,
+	}
+}
+
+func hashState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+// authorizeManager lets super admins, the owner and members of the organization
+// manage its provider. Route permissions decide which members may.
+func (u *ssoUseCase) authorizeManager(ctx context.Context, actor auth_utils.AuthClaim, organizationId uuid.UUID) (int, error) {
+	org, code, err := u.orgRepo.GetOrganization(ctx, organization_repository.OrganizationFilter{Id: &organizationId})
+	if err != nil {
+		return code, err
+	}
+	if org.OwnerId == actor.UserID {
+		return http.StatusOK, nil
+	}
+
+	user, code, err := u.userRepo.GetUser(ctx, user_repository.UserFilter{Id: &actor.UserID})
+	if err != nil {
+		return code, err
+	}
+	if user.IsSuperAdmin {
+		return http.StatusOK, nil
+	}
+
+	isMember, err := u.userRepo.IsOrganizationMember(ctx, actor.UserID, organizationId)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if !isMember {
+		return http.StatusForbidden, errors.New("you are not a member of this organization")
+	}
+
+	return http.StatusOK, nil
+}
+
+// validateProviderUrl requires https, except for loopback addresses used in
+// development and tests.
+func validateProviderUrl(field, value string) error {
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("%s must be an absolute URL", field)
+	}
+	if parsed.Scheme == "https" {
+		return nil
+	}
+
+	host := parsed.Hostname()
+	if ip := net.ParseIP(host); parsed.Scheme == "http" && (host == "localhost" || (ip != nil && ip.IsLoopback())) {
+		return nil
+	}
+	return fmt.Errorf("%s must use https", field)
+}
+
+func (u *ssoUseCase) GetProvider(ctx context.Context, actor auth_utils.AuthClaim, organizationId uuid.UUID) (SsoProvider, int, error) {
+	if code, err := u.authorizeManager(ctx, actor, organizationId); err != nil {
+		return SsoProvider{}, code, err
+	}
+
+	provider, code, err := u.ssoRepo.GetProvider(ctx, sso_repository.SsoProviderFilter{OrganizationId: &organizationId})
+	if err != nil {
+		if code == http.StatusNotFound {
+			return SsoProvider{}, code, errNotConfigured
+		}
+		return SsoProvider{}, code, err
+	}
+
+	return u.toProvider(provider), http.StatusOK, nil
+}
+
+func (u *ssoUseCase) SaveProvider(ctx context.Context, actor auth_utils.AuthClaim, organizationId uuid.UUID, req SaveProviderRequest) (SsoProvider, int, error) {
+	if code, err := u.authorizeManager(ctx, actor, organizationId); err != nil {
+		return SsoProvider{}, code, err
+	}
+
+	req.Issuer = strings.TrimSuffix(strings.TrimSpace(req.Issuer), "/")
+	req.ClientId = strings.TrimSpace(req.ClientId)
+	if err := validateProviderUrl("issuer", req.Issuer); err != nil {
+		return SsoProvider{}, http.StatusBadRequest, err
+	}
+	if err := validateProviderUrl("redirect_uri", req.RedirectUri); err != nil {
+		return SsoProvider{}, http.StatusBadRequest, err
+	}
+	if req.ClientId == "" {
+		return SsoProvider{}, http.StatusBadRequest, errors.New("client_id is required")
+	}
+
+	scopes := req.Scopes
+	if len(scopes) == 0 {
+		scopes = defaultScopes
+	}
+	hasOpenId := false
+	for _, scope := range scopes {
+		if scope == "openid" {
+			hasOpenId = true
+		}
+	}
+	if !hasOpenId {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+
+	groupsClaim := strings.TrimSpace(req.GroupsClaim)
+	if groupsClaim == "" {
+		groupsClaim = defaultGroupsClaim
+	}
+
+	mappings := make([]sso_repository.GroupRoleMapping, 0, len(req.GroupRoleMappings))
+	for _, m := range req.GroupRoleMappings {
+		if strings.TrimSpace(m.Group) == "" {
+			return SsoProvider{}, http.StatusBadRequest, errors.New("every group mapping needs a group")
+		}
+		if _, ok := mappableRoles[m.Role]; !ok {
+			return SsoProvider{}, http.StatusBadRequest, fmt.Errorf("role %q cannot be granted by a group mapping", m.Role)
+		}
+		unitId := m.UnitId
+		if _, code, err := u.unitRepo.GetUnit(ctx, unit_repository.UnitFilter{Id: &unitId, OrganizationId: &organizationId}); err != nil {
+			if code == http.StatusNotFound {
+				return SsoProvider{}, http.StatusBadRequest, fmt.Errorf("unit %s does not belong to this organization", unitId)
+			}
+			return SsoProvider{}, code, err
+		}
+		mappings = append(mappings, sso_repository.GroupRoleMapping{Group: m.Group, UnitId: unitId, Role: m.Role})
+	}
+
+	// Catch a mistyped issuer now rather than at the first sign-in
+	if _, err := u.oidcClient.Discover(ctx, req.Issuer); err != nil {
+		return SsoProvider{}, http.StatusBadRequest, fmt.Errorf("issuer could not be reached: %v", err)
+	}
+
+	var encryptedSecret string
+	if req.ClientSecret != "" {
+		encrypted, err := crypto_utils.Encrypt([]byte(req.ClientSecret), config.APP.Security.AesKey)
+		if err != nil {
+			return SsoProvider{}, http.StatusInternalServerError, err
+		}
+		encryptedSecret = encrypted
+	}
+
+	provider, code, err := u.ssoRepo.SaveProvider(ctx, sso_repository.SsoProvider{
+		OrganizationId:    organizationId,
+		Issuer:            req.Issuer,
+		ClientId:          req.ClientId,
+		ClientSecret:      encryptedSecret,
+		RedirectUri:       req.RedirectUri,
+		Scopes:            scopes,
+		GroupsClaim:       groupsClaim,
+		GroupRoleMappings: mappings,
+		AllowProvisioning: req.AllowProvisioning,
+		IsEnabled:         req.IsEnabled,
+	})
+	if err != nil {
+		return SsoProvider{}, code, err
+	}
+
+	return u.toProvider(provider), http.StatusOK, nil
+}
+
+func (u *ssoUseCase) DeleteProvider(ctx context.Context, actor auth_utils.AuthClaim, organizationId uuid.UUID) (int, error) {
+	if code, err := u.authorizeManager(ctx, actor, organizationId); err != nil {
+		return code, err
+	}
+
+	code, err := u.ssoRepo.DeleteProvider(ctx, sso_repository.SsoProviderFilter{OrganizationId: &organizationId})
+	if err != nil && code == http.StatusNotFound {
+		return code, errNotConfigured
+	}
+	return code, err
+}
+
+func (u *ssoUseCase) enabledProvider(ctx context.Context, organizationId uuid.UUID) (sso_repository.SsoProvider, int, error) {
+	isEnabled := true
+	provider, code, err := u.ssoRepo.GetProvider(ctx, sso_repository.SsoProviderFilter{
+		OrganizationId: &organizationId,
+		IsEnabled:      &isEnabled,
+	})
+	if err != nil {
+		if code == http.StatusNotFound {
+			return sso_repository.SsoProvider{}, code, errNotConfigured
+		}
+		return sso_repository.SsoProvider{}, code, err
+	}
+	return provider, http.StatusOK, nil
+}
+
+func (u *ssoUseCase) StartLogin(ctx context.Context, organizationId uuid.UUID) (Authorization, int, error) {
+	provider, code, err := u.enabledProvider(ctx, organizationId)
+	if err != nil {
+		return Authorization{}, code, err
+	}
+
+	metadata, err := u.oidcClient.Discover(ctx, provider.Issuer)
+	if err != nil {
+		log.Errorf("OIDC discovery failed for organization %s: %v", organizationId, err)
+		return Authorization{}, http.StatusBadGateway, errors.New("identity provider is unavailable")
+	}
+
+	state, err := oidc_utils.RandomString(32)
+	if err != nil {
+		return Authorization{}, http.StatusInternalServerError, err
+	}
+	nonce, err := oidc_utils.RandomString(32)
+	if err != nil {
+		return Authorization{}, http.StatusInternalServerError, err
+	}
+	verifier, err := oidc_utils.RandomString(48)
+	if err != nil {
+		return Authorization{}, http.StatusInternalServerError, err
+	}
+
+	expiresAt := time.Now().Add(LoginStateDuration)
+	if code, err := u.ssoRepo.CreateLoginState(ctx, sso_repository.SsoLoginState{
+		StateHash:      hashState(state),
+		OrganizationId: organizationId,
+		CodeVerifier:   verifier,
+		Nonce:          nonce,
+		ExpiresAt:      expiresAt,
+	}); err != nil {
+		return Authorization{}, code, err
+	}
+
+	return Authorization{
+		AuthorizationUrl: oidc_utils.AuthorizationUrl(metadata, oidc_utils.AuthorizationParams{
+			ClientId:      provider.ClientId,
+			RedirectUri:   provider.RedirectUri,
+			Scopes:        provider.Scopes,
+			State:         state,
+			Nonce:         nonce,
+			CodeChallenge: oidc_utils.CodeChallenge(verifier),
+		}),
+		ExpiresAt: expiresAt,
+	}, http.StatusOK, nil
+}
+
+func (u *ssoUseCase) CompleteLogin(ctx context.Context, req CallbackRequest) (auth_use_case.LoginResponse, int, error) {
+	if req.State == "" || req.Code == "" {
+		return auth_use_case.LoginResponse{}, http.StatusBadRequest, errors.New("state and code are required")
+	}
+
+	state, code, err := u.ssoRepo.ConsumeLoginState(ctx, hashState(req.State))
+	if err != nil {
+		return auth_use_case.LoginResponse{}, code, err
+	}
+
+	provider, code, err := u.enabledProvider(ctx, state.OrganizationId)
+	if err != nil {
+		return auth_use_case.LoginResponse{}, code, err
+	}
+
+	claims, code, err := u.redeemCode(ctx, provider, state, req.Code)
+	if err != nil {
+		return auth_use_case.LoginResponse{}, code, err
+	}
+
+	user, identity, code, err := u.resolveUser(ctx, provider, claims)
+	if err != nil {
+		return auth_use_case.LoginResponse{}, code, err
+	}
+
+	if claims.EmailVerified && user.EmailVerifiedAt == nil && strings.EqualFold(claims.Email, user.Email) {
+		if _, err := u.userRepo.MarkEmailVerified(ctx, user_repository.UserFilter{Id: &user.Id}); err != nil {
+			log.Errorf("failed to mark email verified for user %s: %v", user.Id, err)
+		}
+	}
+
+	u.syncGroupRoles(ctx, provider, user.Id, claims.StringList(provider.GroupsClaim))
+
+	if _, err := u.ssoRepo.TouchIdentity(ctx, identity.Id, time.Now()); err != nil {
+		log.Errorf("failed to record sign-in for identity %s: %v", identity.Id, err)
+	}
+
+	return u.loginCompleter.CompleteExternalLogin(ctx, auth_use_case.ExternalLoginRequest{
+		UserId:    user.Id,
+		UserAgent: req.UserAgent,
+		IpAddress: req.IpAddress,
+	})
+}
+
+func (u *ssoUseCase) redeemCode(ctx context.Context, provider sso_repository.SsoProvider, state sso_repository.SsoLoginState, authCode string) (oidc_utils.IdTokenClaims, int, error) {
+	var clientSecret string
+	if provider.ClientSecret != "" {
+		secret, err := crypto_utils.Decrypt(provider.ClientSecret, config.APP.Security.AesKey)
+		if err != nil {
+			return oidc_utils.IdTokenClaims{}, http.StatusInternalServerError, err
+		}
+		clientSecret = string(secret)
+	}
+
+	metadata, err := u.oidcClient.Discover(ctx, provider.Issuer)
+	if err != nil {
+		log.Errorf("OIDC discovery failed for organization %s: %v", provider.OrganizationId, err)
+		return oidc_utils.IdTokenClaims{}, http.StatusBadGateway, errors.New("identity provider is unavailable")
+	}
+
+	token, err := u.oidcClient.Exchange(ctx, metadata, oidc_utils.ExchangeParams{
+		ClientId:     provider.ClientId,
+		ClientSecret: clientSecret,
+		RedirectUri:  provider.RedirectUri,
+		Code:         authCode,
+		CodeVerifier: state.CodeVerifier,
+	})
+	if err != nil {
+		log.Errorf("OIDC code exchange failed for organization %s: %v", provider.OrganizationId, err)
+		return oidc_utils.IdTokenClaims{}, http.StatusUnauthorized, errors.New("sign-in with the identity provider failed")
+	}
+
+	claims, err := u.oidcClient.VerifyIdToken(ctx, metadata, token.IdToken, provider.ClientId, state.Nonce)
+	if err != nil {
+		log.Errorf("OIDC ID token rejected for organization %s: %v", provider.OrganizationId, err)
+		return oidc_utils.IdTokenClaims{}, http.StatusUnauthorized, errors.New("sign-in with the identity provider failed")
+	}
+
+	return claims, http.StatusOK, nil
+}
+
+// resolveUser finds the account for the IdP subject. Unknown subjects are linked
+// to an existing member with the same verified email, or provisioned when the
+// organization allows it.
+func (u *ssoUseCase) resolveUser(ctx context.Context, provider sso_repository.SsoProvider, claims oidc_utils.IdTokenClaims) (user_repository.User, sso_repository.UserIdentity, int, error) {
+	identity, code, err := u.ssoRepo.GetIdentity(ctx, sso_repository.UserIdentityFilter{
+		Issuer:  &provider.Issuer,
+		Subject: &claims.Subject,
+	})
+	if err == nil {
+		user, code, err := u.userRepo.GetUser(ctx, user_repository.UserFilter{Id: &identity.UserId})
+		return user, identity, code, err
+	}
+	if code != http.StatusNotFound {
+		return user_repository.User{}, sso_repository.UserIdentity{}, code, err
+	}
+
+	email := strings.TrimSpace(claims.Email)
+	if email == "" || !claims.EmailVerified {
+		return user_repository.User{}, sso_repository.UserIdentity{}, http.StatusForbidden,
+			errors.New("the identity provider did not supply a verified email address")
+	}
+
+	user, code, err := u.userRepo.GetUser(ctx, user_repository.UserFilter{Email: &email})
+	switch {
+	case err == nil:
+		// Linking is limited to the organization's own members so one
+		// organization's provider cannot take over accounts elsewhere
+		if user.IsSuperAdmin {
+			return user_repository.User{}, sso_repository.UserIdentity{}, http.StatusForbidden,
+				errors.New("super admin accounts cannot sign in with single sign-on")
+		}
+		isMember, err := u.userRepo.IsOrganizationMember(ctx, user.Id, provider.OrganizationId)
+		if err != nil {
+			return user_repository.User{}, sso_repository.UserIdentity{}, http.StatusInternalServerError, err
+		}
+		if !isMember {
+			return user_repository.User{}, sso_repository.UserIdentity{}, http.StatusForbidden,
+				errors.New("this account is not a member of the organization; sign in with your password")
+		}
+	case code == http.StatusNotFound:
+		if !provider.AllowProvisioning {
+			return user_repository.User{}, sso_repository.UserIdentity{}, http.StatusForbidden,
+				errors.New("no account exists for this email address; ask an administrator to invite you")
+		}
+		user, code, err = u.provisionUser(ctx, email, claims.Name)
+		if err != nil {
+			return user_repository.User{}, sso_repository.UserIdentity{}, code, err
+		}
+	default:
+		return user_repository.User{}, sso_repository.UserIdentity{}, code, err
+	}
+
+	identity, code, err = u.ssoRepo.CreateIdentity(ctx, sso_repository.UserIdentity{
+		UserId:         user.Id,
+		OrganizationId: provider.OrganizationId,
+		Issuer:         provider.Issuer,
+		Subject:        claims.Subject,
+		Email:          email,
+	})
+	if err != nil {
+		return user_repository.User{}, sso_repository.UserIdentity{}, code, err
+	}
+
+	return user, identity, http.StatusOK, nil
+}
+
+// provisionUser creates an account for a first-time single sign-on user. The
+// random password is never shown; the user can set one through password reset.
+func (u *ssoUseCase) provisionUser(ctx context.Context, email, name string) (user_repository.User, int, error) {
+	password, err := oidc_utils.RandomString(32)
+	if err != nil {
+		return user_repository.User{}, http.StatusInternalServerError, err
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return user_repository.User{}, http.StatusInternalServerError, err
+	}
+
+	if strings.TrimSpace(name) == "" {
+		name = email
+	}
+	now := time.Now()
+
+	return u.userRepo.CreateUser(ctx, user_repository.User{
+		Email:           email,
+		Password:        string(hashed),
+		FullName:        name,
+		IsActive:        true,
+		EmailVerifiedAt: &now,
+	})
+}
+
+// syncGroupRoles gives the user the highest role any of their groups maps to
+// in each mapped unit. Memberships in unmapped units and owners are left alone.
+func (u *ssoUseCase) syncGroupRoles(ctx context.Context, provider sso_repository.SsoProvider, userId uuid.UUID, groups []string) {
+	inGroup := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		inGroup[g] = true
+	}
+
+	roles := make(map[uuid.UUID]schemas.UnitMemberRole)
+	for _, m := range provider.GroupRoleMappings {
+		if !inGroup[m.Group] {
+			continue
+		}
+		if current, ok := roles[m.UnitId]; !ok || mappableRoles[m.Role] > mappableRoles[current] {
+			roles[m.UnitId] = m.Role
+		}
+	}
+
+	for unitId, role := range roles {
+		unitId := unitId
+		member, code, err := u.unitMemberRepo.GetMember(ctx, unit_member_repository.UnitMemberFilter{
+			UserId: &userId,
+			UnitId: &unitId,
+		})
+		switch {
+		case err == nil:
+			if member.Role == schemas.UnitMemberRoleOwner || (member.Role == role && member.IsActive) {
+				continue
+			}
+			_, err = u.unitMemberRepo.UpdateMember(ctx, unit_member_repository.UnitMemberFilter{Id: &member.Id},
+				unit_member_repository.UnitMember{Role: role, IsActive: true})
+		case code == http.StatusNotFound:
+			_, _, err = u.unitMemberRepo.AddMember(ctx, unit_member_repository.UnitMember{
+				UserId:   userId,
+				UnitId:   unitId,
+				Role:     role,
+				IsActive: true,
+				JoinedAt: time.Now(),
+			})
+		}
+		if err != nil {
+			log.Errorf("failed to sync unit %s role for user %s: %v", unitId, userId, err)
+		}
+	}
+}
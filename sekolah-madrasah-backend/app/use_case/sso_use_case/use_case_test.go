@@ -0,0 +1,404 @@
+package sso_use_case
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"sekolah-madrasah/app/repository/organization_repository"
+	"sekolah-madrasah/app/repository/sso_repository"
+	"sekolah-madrasah/app/repository/unit_member_repository"
+	"sekolah-madrasah/app/repository/unit_repository"
+	"sekolah-madrasah/app/repository/user_repository"
+	"sekolah-madrasah/app/use_case/auth_use_case"
+	"sekolah-madrasah/config"
+	"sekolah-madrasah/database/schemas"
+	"sekolah-madrasah/pkg/auth_utils"
+	"sekolah-madrasah/pkg/oidc_utils"
+	"sekolah-madrasah/pkg/oidc_utils/oidctest"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type MockSsoRepository struct {
+	provider   *sso_repository.SsoProvider
+	states     map[string]sso_repository.SsoLoginState
+	identities []sso_repository.UserIdentity
+}
+
+func NewMockSsoRepository() *MockSsoRepository {
+	return &MockSsoRepository{states: make(map[string]sso_repository.SsoLoginState)}
+}
+
+func (m *MockSsoRepository) GetProvider(ctx context.Context, filter sso_repository.SsoProviderFilter) (sso_repository.SsoProvider, int, error) {
+	if m.provider == nil || (filter.IsEnabled != nil && m.provider.IsEnabled != *filter.IsEnabled) {
+		return sso_repository.SsoProvider{}, http.StatusNotFound, gorm.ErrRecordNotFound
+	}
+	return *m.provider, http.StatusOK, nil
+}
+
+func (m *MockSsoRepository) SaveProvider(ctx context.Context, provider sso_repository.SsoProvider) (sso_repository.SsoProvider, int, error) {
+	if provider.ClientSecret == "" && m.provider != nil {
+		provider.ClientSecret = m.provider.ClientSecret
+	}
+	provider.Id = uuid.New()
+	m.provider = &provider
+	return provider, http.StatusOK, nil
+}
+
+func (m *MockSsoRepository) DeleteProvider(ctx context.Context, filter sso_repository.SsoProviderFilter) (int, error) {
+	m.provider = nil
+	return http.StatusOK, nil
+}
+
+func (m *MockSsoRepository) CreateLoginState(ctx context.Context, state sso_repository.SsoLoginState) (int, error) {
+	m.states[state.StateHash] = state
+	return http.StatusCreated, nil
+}
+
+func (m *MockSsoRepository) ConsumeLoginState(ctx context.Context, stateHash string) (sso_repository.SsoLoginState, int, error) {
+	state, ok := m.states[stateHash]
+	if !ok || state.UsedAt != nil || !state.ExpiresAt.After(time.Now()) {
+		return sso_repository.SsoLoginState{}, http.StatusBadRequest, gorm.ErrRecordNotFound
+	}
+	now := time.Now()
+	state.UsedAt = &now
+	m.states[stateHash] = state
+	return state, http.StatusOK, nil
+}
+
+func (m *MockSsoRepository) GetIdentity(ctx context.Context, filter sso_repository.UserIdentityFilter) (sso_repository.UserIdentity, int, error) {
+	for _, identity := range m.identities {
+		if identity.Issuer == *filter.Issuer && identity.Subject == *filter.Subject {
+			return identity, http.StatusOK, nil
+		}
+	}
+	return sso_repository.UserIdentity{}, http.StatusNotFound, gorm.ErrRecordNotFound
+}
+
+func (m *MockSsoRepository) CreateIdentity(ctx context.Context, identity sso_repository.UserIdentity) (sso_repository.UserIdentity, int, error) {
+	identity.Id = uuid.New()
+	m.identities = append(m.identities, identity)
+	return identity, http.StatusCreated, nil
+}
+
+func (m *MockSsoRepository) TouchIdentity(ctx context.Context, id uuid.UUID, loginAt time.Time) (int, error) {
+	return http.StatusOK, nil
+}
+
+type MockOrganizationRepository struct {
+	organization_repository.OrganizationRepository
+	org organization_repository.Organization
+}
+
+func (m *MockOrganizationRepository) GetOrganization(ctx context.Context, filter organization_repository.OrganizationFilter) (organization_repository.Organization, int, error) {
+	if *filter.Id != m.org.Id {
+		return organization_repository.Organization{}, http.StatusNotFound, gorm.ErrRecordNotFound
+	}
+	return m.org, http.StatusOK, nil
+}
+
+type MockUnitRepository struct {
+	unit_repository.UnitRepository
+	units map[uuid.UUID]uuid.UUID
+}
+
+func (m *MockUnitRepository) GetUnit(ctx context.Context, filter unit_repository.UnitFilter) (unit_repository.Unit, int, error) {
+	if orgId, ok := m.units[*filter.Id]; ok && orgId == *filter.OrganizationId {
+		return unit_repository.Unit{Id: *filter.Id, OrganizationId: orgId}, http.StatusOK, nil
+	}
+	return unit_repository.Unit{}, http.StatusNotFound, gorm.ErrRecordNotFound
+}
+
+type MockUnitMemberRepository struct {
+	unit_member_repository.UnitMemberRepository
+	members []unit_member_repository.UnitMember
+}
+
+func (m *MockUnitMemberRepository) GetMember(ctx context.Context, filter unit_member_repository.UnitMemberFilter) (unit_member_repository.UnitMember, int, error) {
+	for _, member := range m.members {
+		if member.UserId == *filter.UserId && member.UnitId == *filter.UnitId {
+			return member, http.StatusOK, nil
+		}
+	}
+	return unit_member_repository.UnitMember{}, http.StatusNotFound, gorm.ErrRecordNotFound
+}
+
+func (m *MockUnitMemberRepository) AddMember(ctx context.Context, member unit_member_repository.UnitMember) (unit_member_repository.UnitMember, int, error) {
+	member.Id = uuid.New()
+	m.members = append(m.members, member)
+	return member, http.StatusCreated, nil
+}
+
+func (m *MockUnitMemberRepository) UpdateMember(ctx context.Context, filter unit_member_repository.UnitMemberFilter, member unit_member_repository.UnitMember) (int, error) {
+	for i := range m.members {
+		if m.members[i].Id == *filter.Id {
+			m.members[i].Role = member.Role
+			m.members[i].IsActive = member.IsActive
+			return http.StatusOK, nil
+		}
+	}
+	return http.StatusNotFound, gorm.ErrRecordNotFound
+}
+
+type MockUserRepository struct {
+	user_repository.UserRepository
+	users   []user_repository.User
+	members map[uuid.UUID]bool
+}
+
+func (m *MockUserRepository) GetUser(ctx context.Context, filter user_repository.UserFilter) (user_repository.User, int, error) {
+	for _, user := range m.users {
+		if (filter.Id != nil && user.Id == *filter.Id) || (filter.Email != nil && user.Email == *filter.Email) {
+			return user, http.StatusOK, nil
+		}
+	}
+	return user_repository.User{}, http.StatusNotFound, gorm.ErrRecordNotFound
+}
+
+func (m *MockUserRepository) CreateUser(ctx context.Context, user user_repository.User) (user_repository.User, int, error) {
+	user.Id = uuid.New()
+	m.users = append(m.users, user)
+	return user, http.StatusCreated, nil
+}
+
+func (m *MockUserRepository) MarkEmailVerified(ctx context.Context, filter user_repository.UserFilter) (int, error) {
+	return http.StatusOK, nil
+}
+
+func (m *MockUserRepository) IsOrganizationMember(ctx context.Context, userId, organizationId uuid.UUID) (bool, error) {
+	return m.members[userId], nil
+}
+
+type fakeLoginCompleter struct {
+	userIds []uuid.UUID
+}
+
+func (f *fakeLoginCompleter) CompleteExternalLogin(ctx context.Context, req auth_use_case.ExternalLoginRequest) (auth_use_case.LoginResponse, int, error) {
+	f.userIds = append(f.userIds, req.UserId)
+	return auth_use_case.LoginResponse{AccessToken: "access", User: auth_use_case.UserInfo{Id: req.UserId}}, http.StatusOK, nil
+}
+
+type fixture struct {
+	useCase    SsoUseCase
+	issuer     *oidctest.Issuer
+	ssoRepo    *MockSsoRepository
+	userRepo   *MockUserRepository
+	memberRepo *MockUnitMemberRepository
+	completer  *fakeLoginCompleter
+	orgId      uuid.UUID
+	unitId     uuid.UUID
+	owner      auth_utils.AuthClaim
+}
+
+func newFixture(t *testing.T, allowProvisioning bool) fixture {
+	t.Helper()
+
+	previous := config.APP.Security.AesKey
+	config.APP.Security.AesKey = "test-aes-key"
+	t.Cleanup(func() { config.APP.Security.AesKey = previous })
+
+	issuer := oidctest.NewIssuer("sekolah", "s3cret")
+	t.Cleanup(issuer.Close)
+
+	orgId, unitId, ownerId := uuid.New(), uuid.New(), uuid.New()
+	f := fixture{
+		issuer:     issuer,
+		ssoRepo:    NewMockSsoRepository(),
+		userRepo:   &MockUserRepository{users: []user_repository.User{{Id: ownerId, Email: "owner@example.com", IsActive: true}}, members: map[uuid.UUID]bool{}},
+		memberRepo: &MockUnitMemberRepository{},
+		completer:  &fakeLoginCompleter{},
+		orgId:      orgId,
+		unitId:     unitId,
+		owner:      auth_utils.AuthClaim{UserID: ownerId},
+	}
+	f.useCase = NewSsoUseCase(
+		f.ssoRepo,
+		&MockOrganizationRepository{org: organization_repository.Organization{Id: orgId, OwnerId: ownerId}},
+		&MockUnitRepository{units: map[uuid.UUID]uuid.UUID{unitId: orgId}},
+		f.memberRepo,
+		f.userRepo,
+		f.completer,
+		oidc_utils.NewClient(nil),
+	)
+
+	_, code, err := f.useCase.SaveProvider(context.Background(), f.owner, orgId, SaveProviderRequest{
+		Issuer:       issuer.URL,
+		ClientId:     "sekolah",
+		ClientSecret: "s3cret",
+		RedirectUri:  "http://localhost:3000/sso/callback",
+		GroupRoleMappings: []GroupRoleMapping{
+			{Group: "guru", UnitId: unitId, Role: schemas.UnitMemberRoleStaff},
+			{Group: "kepala-sekolah", UnitId: unitId, Role: schemas.UnitMemberRoleAdmin},
+		},
+		AllowProvisioning: allowProvisioning,
+		IsEnabled:         true,
+	})
+	if err != nil {
+		t.Fatalf("SaveProvider failed: %d %v", code, err)
+	}
+
+	return f
+}
+
+// signIn runs the browser part of the flow against the mock issuer and returns
+// the callback parameters.
+func (f fixture) signIn(t *testing.T, claims map[string]interface{}) CallbackRequest {
+	t.Helper()
+	f.issuer.SetClaims(claims)
+
+	authorization, code, err := f.useCase.StartLogin(context.Background(), f.orgId)
+	if err != nil {
+		t.Fatalf("StartLogin failed: %d %v", code, err)
+	}
+
+	authCode, state, err := f.issuer.Authorize(authorization.AuthorizationUrl)
+	if err != nil {
+		t.Fatalf("Authorize failed: %v", err)
+	}
+
+	return CallbackRequest{State: state, Code: authCode, IpAddress: "127.0.0.1"}
+}
+
+func TestSaveProvider_StoresSecretEncrypted(t *testing.T) {
+	f := newFixture(t, false)
+
+	stored := f.ssoRepo.provider
+	if stored.ClientSecret == "" || strings.Contains(stored.ClientSecret, "s3cret") {
+		t.Errorf("expected the client secret to be encrypted, got %q", stored.ClientSecret)
+	}
+	if stored.Scopes[0] != "openid" {
+		t.Errorf("expected default scopes, got %v", stored.Scopes)
+	}
+
+	_, code, err := f.useCase.SaveProvider(context.Background(), f.owner, f.orgId, SaveProviderRequest{
+		Issuer:            f.issuer.URL,
+		ClientId:          "sekolah",
+		RedirectUri:       "http://localhost:3000/sso/callback",
+		GroupRoleMappings: []GroupRoleMapping{{Group: "guru", UnitId: uuid.New(), Role: schemas.UnitMemberRoleStaff}},
+	})
+	if err == nil || code != http.StatusBadRequest {
+		t.Errorf("expected a unit outside the organization to be rejected, got %d %v", code, err)
+	}
+}
+
+func TestStartLogin_UsesPkce(t *testing.T) {
+	f := newFixture(t, false)
+
+	authorization, _, err := f.useCase.StartLogin(context.Background(), f.orgId)
+	if err != nil {
+		t.Fatalf("StartLogin failed: %v", err)
+	}
+
+	parsed, err := url.Parse(authorization.AuthorizationUrl)
+	if err != nil {
+		t.Fatalf("invalid authorization URL: %v", err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Errorf("expected a S256 PKCE challenge, got %v", query)
+	}
+	for _, state := range f.ssoRepo.states {
+		if query.Get("code_challenge") != oidc_utils.CodeChallenge(state.CodeVerifier) {
+			t.Error("challenge does not match the stored verifier")
+		}
+		if query.Get("state") == state.StateHash {
+			t.Error("expected only the state hash to be stored")
+		}
+	}
+}
+
+func TestCompleteLogin_ProvisionsAndMapsGroups(t *testing.T) {
+	f := newFixture(t, true)
+	ctx := context.Background()
+
+	req := f.signIn(t, map[string]interface{}{
+		"sub":            "idp-guru-1",
+		"email":          "guru@example.com",
+		"email_verified": true,
+		"name":           "Bu Guru",
+		"groups":         []string{"guru", "kepala-sekolah", "unmapped"},
+	})
+	result, code, err := f.useCase.CompleteLogin(ctx, req)
+	if err != nil {
+		t.Fatalf("CompleteLogin failed: %d %v", code, err)
+	}
+
+	user, _, err := f.userRepo.GetUser(ctx, user_repository.UserFilter{Email: strPtr("guru@example.com")})
+	if err != nil {
+		t.Fatalf("expected the user to be provisioned: %v", err)
+	}
+	if result.User.Id != user.Id || user.FullName != "Bu Guru" || user.EmailVerifiedAt == nil {
+		t.Errorf("unexpected provisioned user %+v", user)
+	}
+	if len(f.memberRepo.members) != 1 || f.memberRepo.members[0].Role != schemas.UnitMemberRoleAdmin {
+		t.Errorf("expected one admin membership from the highest mapped group, got %+v", f.memberRepo.members)
+	}
+
+	// The state is single use
+	if _, code, err := f.useCase.CompleteLogin(ctx, req); err == nil || code != http.StatusBadRequest {
+		t.Errorf("expected a replayed state to be rejected, got %d %v", code, err)
+	}
+
+	// A later sign-in matches on the subject and applies group changes
+	req = f.signIn(t, map[string]interface{}{
+		"sub":    "idp-guru-1",
+		"email":  "changed@example.com",
+		"groups": []string{"guru"},
+	})
+	if _, _, err := f.useCase.CompleteLogin(ctx, req); err != nil {
+		t.Fatalf("second CompleteLogin failed: %v", err)
+	}
+	if len(f.userRepo.users) != 2 || f.completer.userIds[1] != user.Id {
+		t.Errorf("expected the existing identity to be reused, got %d users", len(f.userRepo.users))
+	}
+	if f.memberRepo.members[0].Role != schemas.UnitMemberRoleStaff {
+		t.Errorf("expected the role to follow the IdP groups, got %s", f.memberRepo.members[0].Role)
+	}
+}
+
+func TestCompleteLogin_LinksExistingMemberByEmail(t *testing.T) {
+	f := newFixture(t, false)
+	ctx := context.Background()
+
+	member := user_repository.User{Id: uuid.New(), Email: "staf@example.com", IsActive: true}
+	outsider := user_repository.User{Id: uuid.New(), Email: "other@example.com", IsActive: true}
+	f.userRepo.users = append(f.userRepo.users, member, outsider)
+	f.userRepo.members[member.Id] = true
+
+	req := f.signIn(t, map[string]interface{}{"sub": "idp-staf", "email": "staf@example.com", "email_verified": true})
+	if _, code, err := f.useCase.CompleteLogin(ctx, req); err != nil {
+		t.Fatalf("CompleteLogin failed: %d %v", code, err)
+	}
+	if len(f.ssoRepo.identities) != 1 || f.ssoRepo.identities[0].UserId != member.Id {
+		t.Errorf("expected the member to be linked, got %+v", f.ssoRepo.identities)
+	}
+
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+	}{
+		{"not a member", map[string]interface{}{"sub": "idp-other", "email": "other@example.com", "email_verified": true}},
+		{"unverified email", map[string]interface{}{"sub": "idp-unverified", "email": "staf@example.com"}},
+		{"unknown without provisioning", map[string]interface{}{"sub": "idp-new", "email": "new@example.com", "email_verified": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, code, err := f.useCase.CompleteLogin(ctx, f.signIn(t, tt.claims))
+			if err == nil || code != http.StatusForbidden {
+				t.Errorf("expected 403, got %d %v", code, err)
+			}
+		})
+	}
+	if len(f.ssoRepo.identities) != 1 {
+		t.Errorf("expected no further identities, got %d", len(f.ssoRepo.identities))
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,46 @@
+package staff_attendance_use_case
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+var recapColumns = []string{
+	"Nama", "NIP", "Status Kepegawaian", "Peran", "Hari Hadir", "Hari Terlambat", "Menit Terlambat",
+	"Hari Pulang Cepat", "Menit Pulang Cepat", "Tanpa Absen Pulang",
+}
+
+// WriteRecapCSV writes the monthly recap as CSV for the payroll spreadsheet,
+// one line per person.
+func WriteRecapCSV(w io.Writer, recap *MonthlyRecap) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(recapColumns); err != nil {
+		return err
+	}
+
+	for _, line := range recap.Staff {
+		nip := ""
+		if line.NIP != nil {
+			nip = *line.NIP
+		}
+		err := out.Write([]string{
+			line.Name,
+			nip,
+			line.EmploymentStatus,
+			string(line.Role),
+			strconv.Itoa(line.DaysPresent),
+			strconv.Itoa(line.LateDays),
+			strconv.Itoa(line.LateMinutes),
+			strconv.Itoa(line.EarlyLeaveDays),
+			strconv.Itoa(line.EarlyLeaveMinutes),
+			strconv.Itoa(line.MissingCheckOut),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,381 @@
+package staff_attendance_use_case
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"sekolah-madrasah/app/repository/staff_attendance_repository"
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	clockFormat     = "15:04"
+	monthFormat     = "2006-01"
+	defaultTimezone = "Asia/Jakarta"
+)
+
+var (
+	ErrNotStaff           = errors.New("only teachers and staff of the unit can check in")
+	ErrAlreadyCheckedIn   = errors.New("already checked in today")
+	ErrNotCheckedIn       = errors.New("check in before checking out")
+	ErrAlreadyCheckedOut  = errors.New("already checked out today")
+	ErrInvalidCoordinates = errors.New("latitude and longitude must be given together and be valid coordinates")
+	ErrStaffUserRequired  = errors.New("user_id of the staff member is required when checking in with an API key")
+)
+
+type StaffAttendanceUseCase interface {
+	// CheckIn records the caller's arrival today, noting how late it is
+	CheckIn(req *CheckRequest) (*schemas.StaffAttendance, error)
+	// CheckOut records the caller's departure today, noting how early it is
+	CheckOut(req *CheckRequest) (*schemas.StaffAttendance, error)
+	GetDay(unitId uuid.UUID, date time.Time) ([]schemas.StaffAttendance, error)
+	GetOwn(unitId, userId uuid.UUID, month time.Time) ([]schemas.StaffAttendance, error)
+	// GetMonthlyRecap totals each teacher's and staff member's month, for payroll
+	GetMonthlyRecap(unitId uuid.UUID, month time.Time) (*MonthlyRecap, error)
+}
+
+// CheckRequest is a check-in or check-out by the calling user. An API key, such
+// as an attendance kiosk, acts for the key's creator and so has to name the
+// staff member in StaffUserId instead. Location and device are optional and
+// only stored.
+type CheckRequest struct {
+	UnitId      uuid.UUID
+	UserId      uuid.UUID
+	ViaApiKey   bool
+	StaffUserId *uuid.UUID
+	Latitude    *float64
+	Longitude   *float64
+	DeviceId    *string
+	Note        *string
+}
+
+type StaffRecap struct {
+	UserId            uuid.UUID              `json:"user_id"`
+	Name              string                 `json:"name"`
+	Role              schemas.UnitMemberRole `json:"role"` // Empty for teachers who are not unit members
+	TeacherProfileId  *uuid.UUID             `json:"teacher_profile_id"`
+	NIP               *string                `json:"nip"`
+	EmploymentStatus  string                 `json:"employment_status"`
+	DaysPresent       int                    `json:"days_present"`
+	LateDays          int                    `json:"late_days"`
+	LateMinutes       int                    `json:"late_minutes"`
+	EarlyLeaveDays    int                    `json:"early_leave_days"`
+	EarlyLeaveMinutes int                    `json:"early_leave_minutes"`
+	MissingCheckOut   int                    `json:"missing_check_out"` // Days checked in without checking out
+}
+
+type MonthlyRecap struct {
+	Month string       `json:"month"`
+	Staff []StaffRecap `json:"staff"`
+}
+
+type staffAttendanceUseCase struct {
+	repo staff_attendance_repository.StaffAttendanceRepository
+	now  func() time.Time
+}
+
+func NewStaffAttendanceUseCase(repo staff_attendance_repository.StaffAttendanceRepository) StaffAttendanceUseCase {
+	return &staffAttendanceUseCase{repo: repo, now: time.Now}
+}
+
+func (uc *staffAttendanceUseCase) CheckIn(req *CheckRequest) (*schemas.StaffAttendance, error) {
+	if err := validateLocation(req); err != nil {
+		return nil, err
+	}
+	userId, err := req.staffUserId()
+	if err != nil {
+		return nil, err
+	}
+	teacherId, err := uc.staff(req.UnitId, userId)
+	if err != nil {
+		return nil, err
+	}
+	hours, err := uc.hours(req.UnitId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := uc.now()
+	date := hours.date(now)
+	if _, err := uc.repo.FindByUserAndDate(req.UnitId, userId, date); err == nil {
+		return nil, ErrAlreadyCheckedIn
+	}
+
+	return uc.repo.Create(&schemas.StaffAttendance{
+		UnitId:           req.UnitId,
+		UserId:           userId,
+		TeacherProfileId: teacherId,
+		Date:             date,
+		CheckInAt:        now,
+		CheckInLatitude:  req.Latitude,
+		CheckInLongitude: req.Longitude,
+		CheckInDevice:    req.DeviceId,
+		LateMinutes:      hours.lateMinutes(now),
+		Note:             req.Note,
+	})
+}
+
+func (uc *staffAttendanceUseCase) CheckOut(req *CheckRequest) (*schemas.StaffAttendance, error) {
+	if err := validateLocation(req); err != nil {
+		return nil, err
+	}
+	userId, err := req.staffUserId()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := uc.staff(req.UnitId, userId); err != nil {
+		return nil, err
+	}
+	hours, err := uc.hours(req.UnitId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := uc.now()
+	record, err := uc.repo.FindByUserAndDate(req.UnitId, userId, hours.date(now))
+	if err != nil {
+		return nil, ErrNotCheckedIn
+	}
+	if record.CheckOutAt != nil {
+		return nil, ErrAlreadyCheckedOut
+	}
+
+	record.CheckOutAt = &now
+	record.CheckOutLatitude = req.Latitude
+	record.CheckOutLongitude = req.Longitude
+	record.CheckOutDevice = req.DeviceId
+	record.EarlyLeaveMinutes = hours.earlyLeaveMinutes(now)
+	if req.Note != nil {
+		record.Note = req.Note
+	}
+	return uc.repo.Update(record)
+}
+
+func (uc *staffAttendanceUseCase) GetDay(unitId uuid.UUID, date time.Time) ([]schemas.StaffAttendance, error) {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	return uc.repo.FindByDates(unitId, nil, day, day)
+}
+
+func (uc *staffAttendanceUseCase) GetOwn(unitId, userId uuid.UUID, month time.Time) ([]schemas.StaffAttendance, error) {
+	from, to := monthRange(month)
+	return uc.repo.FindByDates(unitId, &userId, from, to)
+}
+
+func (uc *staffAttendanceUseCase) GetMonthlyRecap(unitId uuid.UUID, month time.Time) (*MonthlyRecap, error) {
+	from, to := monthRange(month)
+
+	members, err := uc.repo.FindStaff(unitId)
+	if err != nil {
+		return nil, err
+	}
+	teachers, err := uc.repo.FindTeachers(unitId)
+	if err != nil {
+		return nil, err
+	}
+	records, err := uc.repo.FindByDates(unitId, nil, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// One line per member of staff, per teacher, and per anyone else who
+	// checked in that month
+	lines := map[uuid.UUID]*StaffRecap{}
+	line := func(userId uuid.UUID, user *schemas.User) *StaffRecap {
+		if lines[userId] == nil {
+			lines[userId] = &StaffRecap{UserId: userId}
+		}
+		if user != nil && lines[userId].Name == "" {
+			lines[userId].Name = user.FullName
+		}
+		return lines[userId]
+	}
+	for _, member := range members {
+		line(member.UserId, member.User).Role = member.Role
+	}
+	for _, teacher := range teachers {
+		recap := line(teacher.UserId, teacher.User)
+		teacherId := teacher.Id
+		recap.TeacherProfileId = &teacherId
+		recap.NIP = teacher.NIP
+		recap.EmploymentStatus = teacher.EmploymentStatus
+	}
+	for _, record := range records {
+		recap := line(record.UserId, record.User)
+		recap.DaysPresent++
+		if record.LateMinutes > 0 {
+			recap.LateDays++
+			recap.LateMinutes += record.LateMinutes
+		}
+		if record.EarlyLeaveMinutes > 0 {
+			recap.EarlyLeaveDays++
+			recap.EarlyLeaveMinutes += record.EarlyLeaveMinutes
+		}
+		if record.CheckOutAt == nil {
+			recap.MissingCheckOut++
+		}
+	}
+
+	recap := &MonthlyRecap{Month: from.Format(monthFormat), Staff: make([]StaffRecap, 0, len(lines))}
+	for _, line := range lines {
+		recap.Staff = append(recap.Staff, *line)
+	}
+	sort.Slice(recap.Staff, func(i, j int) bool {
+		if recap.Staff[i].Name != recap.Staff[j].Name {
+			return recap.Staff[i].Name < recap.Staff[j].Name
+		}
+		return recap.Staff[i].UserId.String() < recap.Staff[j].UserId.String()
+	})
+	return recap, nil
+}
+
+// staffUserId is who the check is for: the caller, or the staff member an API
+// key names, never the key's creator.
+func (req *CheckRequest) staffUserId() (uuid.UUID, error) {
+	if !req.ViaApiKey {
+		return req.UserId, nil
+	}
+	if req.StaffUserId == nil || *req.StaffUserId == uuid.Nil {
+		return uuid.Nil, ErrStaffUserRequired
+	}
+	return *req.StaffUserId, nil
+}
+
+// staff checks that the user may check in to the unit: an active owner, admin
+// or staff member, or a teacher of the unit. It returns the teacher profile ID
+// for teachers.
+func (uc *staffAttendanceUseCase) staff(unitId, userId uuid.UUID) (*uuid.UUID, error) {
+	if userId == uuid.Nil {
+		return nil, ErrNotStaff
+	}
+	if teacher, err := uc.repo.FindTeacherByUser(unitId, userId); err == nil {
+		return &teacher.Id, nil
+	}
+
+	member, err := uc.repo.FindMember(unitId, userId)
+	if err != nil {
+		return nil, ErrNotStaff
+	}
+	switch member.Role {
+	case schemas.UnitMemberRoleOwner, schemas.UnitMemberRoleAdmin, schemas.UnitMemberRoleStaff:
+		return nil, nil
+	}
+	return nil, ErrNotStaff
+}
+
+// schoolHours is the unit's working day in its own timezone.
+type schoolHours struct {
+	location  *time.Location
+	start     time.Duration // Since midnight
+	end       time.Duration
+	tolerance time.Duration
+}
+
+func (uc *staffAttendanceUseCase) hours(unitId uuid.UUID) (*schoolHours, error) {
+	settings, err := uc.repo.FindSettings(unitId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		settings = &schemas.UnitSettings{
+			UnitId:           unitId,
+			PeriodDuration:   40,
+			StartTime:        "07:00",
+			TotalPeriods:     9,
+			BreakAfterPeriod: 3,
+			BreakDuration:    15,
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return hoursFrom(settings)
+}
+
+// hoursFrom reads the working day from the unit settings. Without an EndTime
+// the day ends with the last period, laid out as in the timetable.
+func hoursFrom(settings *schemas.UnitSettings) (*schoolHours, error) {
+	name := settings.Timezone
+	if name == "" {
+		name = defaultTimezone
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unit settings have an unknown timezone %q", settings.Timezone)
+	}
+
+	start, err := clock(settings.StartTime)
+	if err != nil {
+		return nil, fmt.Errorf("unit settings have an invalid start time %q", settings.StartTime)
+	}
+
+	end := start + time.Duration(settings.TotalPeriods*settings.PeriodDuration)*time.Minute
+	if settings.BreakAfterPeriod > 0 && settings.BreakAfterPeriod < settings.TotalPeriods {
+		end += time.Duration(settings.BreakDuration) * time.Minute
+	}
+	if settings.EndTime != "" {
+		if end, err = clock(settings.EndTime); err != nil {
+			return nil, fmt.Errorf("unit settings have an invalid end time %q", settings.EndTime)
+		}
+	}
+
+	return &schoolHours{
+		location:  location,
+		start:     start,
+		end:       end,
+		tolerance: time.Duration(settings.LateTolerance) * time.Minute,
+	}, nil
+}
+
+// date is the unit's calendar day at the moment, as stored in date columns.
+func (h *schoolHours) date(moment time.Time) time.Time {
+	local := moment.In(h.location)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func (h *schoolHours) midnight(moment time.Time) time.Time {
+	local := moment.In(h.location)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, h.location)
+}
+
+// lateMinutes counts from the start of the day, but only once the check-in is
+// past the tolerance.
+func (h *schoolHours) lateMinutes(checkIn time.Time) int {
+	late := checkIn.Sub(h.midnight(checkIn).Add(h.start))
+	if late <= h.tolerance {
+		return 0
+	}
+	return int(late / time.Minute)
+}
+
+func (h *schoolHours) earlyLeaveMinutes(checkOut time.Time) int {
+	early := h.midnight(checkOut).Add(h.end).Sub(checkOut)
+	if early <= 0 {
+		return 0
+	}
+	return int(early / time.Minute)
+}
+
+func clock(value string) (time.Duration, error) {
+	parsed, err := time.Parse(clockFormat, value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
+}
+
+func validateLocation(req *CheckRequest) error {
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		return ErrInvalidCoordinates
+	}
+	if req.Latitude != nil && (*req.Latitude < -90 || *req.Latitude > 90 || *req.Longitude < -180 || *req.Longitude > 180) {
+		return ErrInvalidCoordinates
+	}
+	return nil
+}
+
+// monthRange returns the first and last day of the month.
+func monthRange(month time.Time) (time.Time, time.Time) {
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return from, from.AddDate(0, 1, -1)
+}
@@ -0,0 +1,279 @@
+package staff_attendance_use_case
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockRepository is a mock implementation of StaffAttendanceRepository
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) FindByUserAndDate(unitId, userId uuid.UUID, date time.Time) (*schemas.StaffAttendance, error) {
+	args := m.Called(unitId, userId, date)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.StaffAttendance), args.Error(1)
+}
+
+func (m *MockRepository) Create(record *schemas.StaffAttendance) (*schemas.StaffAttendance, error) {
+	args := m.Called(record)
+	return record, args.Error(0)
+}
+
+func (m *MockRepository) Update(record *schemas.StaffAttendance) (*schemas.StaffAttendance, error) {
+	args := m.Called(record)
+	return record, args.Error(0)
+}
+
+func (m *MockRepository) FindByDates(unitId uuid.UUID, userId *uuid.UUID, from, to time.Time) ([]schemas.StaffAttendance, error) {
+	args := m.Called(unitId, userId, from, to)
+	return args.Get(0).([]schemas.StaffAttendance), args.Error(1)
+}
+
+func (m *MockRepository) FindSettings(unitId uuid.UUID) (*schemas.UnitSettings, error) {
+	args := m.Called(unitId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.UnitSettings), args.Error(1)
+}
+
+func (m *MockRepository) FindMember(unitId, userId uuid.UUID) (*schemas.UnitMember, error) {
+	args := m.Called(unitId, userId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.UnitMember), args.Error(1)
+}
+
+func (m *MockRepository) FindStaff(unitId uuid.UUID) ([]schemas.UnitMember, error) {
+	args := m.Called(unitId)
+	return args.Get(0).([]schemas.UnitMember), args.Error(1)
+}
+
+func (m *MockRepository) FindTeacherByUser(unitId, userId uuid.UUID) (*schemas.TeacherProfile, error) {
+	args := m.Called(unitId, userId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.TeacherProfile), args.Error(1)
+}
+
+func (m *MockRepository) FindTeachers(unitId uuid.UUID) ([]schemas.TeacherProfile, error) {
+	args := m.Called(unitId)
+	return args.Get(0).([]schemas.TeacherProfile), args.Error(1)
+}
+
+var wib = time.FixedZone("WIB", 7*60*60)
+
+type fixture struct {
+	repo    *MockRepository
+	uc      *staffAttendanceUseCase
+	unitId  uuid.UUID
+	teacher *schemas.TeacherProfile
+	now     time.Time
+}
+
+// newFixture sets up a unit whose day runs 07:00 to 13:30 WIB with a five
+// minute tolerance, and a teacher of it.
+func newFixture() *fixture {
+	unitId := uuid.New()
+	f := &fixture{
+		repo:    new(MockRepository),
+		unitId:  unitId,
+		teacher: &schemas.TeacherProfile{Id: uuid.New(), UnitId: unitId, UserId: uuid.New()},
+	}
+	f.uc = NewStaffAttendanceUseCase(f.repo).(*staffAttendanceUseCase)
+	f.uc.now = func() time.Time { return f.now }
+
+	f.repo.On("FindSettings", unitId).Return(&schemas.UnitSettings{
+		UnitId: unitId, StartTime: "07:00", EndTime: "13:30", LateTolerance: 5, Timezone: "Asia/Jakarta",
+		PeriodDuration: 40, TotalPeriods: 9,
+	}, nil)
+	f.repo.On("FindTeacherByUser", unitId, f.teacher.UserId).Return(f.teacher, nil)
+	return f
+}
+
+func day(value string) time.Time {
+	date, _ := time.Parse("2006-01-02", value)
+	return date
+}
+
+// Tests
+
+func TestCheckIn_Lateness(t *testing.T) {
+	tests := []struct {
+		name string
+		at   time.Time
+		late int
+	}{
+		{"early", time.Date(2025, 9, 15, 6, 45, 0, 0, wib), 0},
+		{"within tolerance", time.Date(2025, 9, 15, 7, 5, 0, 0, wib), 0},
+		{"late", time.Date(2025, 9, 15, 7, 20, 30, 0, wib), 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture()
+			f.now = tt.at.UTC()
+			f.repo.On("FindByUserAndDate", f.unitId, f.teacher.UserId, day("2025-09-15")).Return(nil, gorm.ErrRecordNotFound)
+			f.repo.On("Create", mock.Anything).Return(nil)
+
+			lat, lng := -6.2, 106.8
+			record, err := f.uc.CheckIn(&CheckRequest{UnitId: f.unitId, UserId: f.teacher.UserId, Latitude: &lat, Longitude: &lng})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.late, record.LateMinutes)
+			assert.Equal(t, day("2025-09-15"), record.Date)
+			assert.Equal(t, &f.teacher.Id, record.TeacherProfileId)
+			assert.Equal(t, &lat, record.CheckInLatitude)
+		})
+	}
+}
+
+func TestCheckIn_UsesUnitCalendarDay(t *testing.T) {
+	f := newFixture()
+	// 23:30 UTC on the 14th is already the morning of the 15th in Jakarta
+	f.now = time.Date(2025, 9, 14, 23, 30, 0, 0, time.UTC)
+	f.repo.On("FindByUserAndDate", f.unitId, f.teacher.UserId, day("2025-09-15")).Return(nil, gorm.ErrRecordNotFound)
+	f.repo.On("Create", mock.Anything).Return(nil)
+
+	record, err := f.uc.CheckIn(&CheckRequest{UnitId: f.unitId, UserId: f.teacher.UserId})
+	assert.NoError(t, err)
+	assert.Equal(t, day("2025-09-15"), record.Date)
+	assert.Equal(t, 0, record.LateMinutes)
+}
+
+func TestCheckIn_Refusals(t *testing.T) {
+	f := newFixture()
+	f.now = time.Date(2025, 9, 15, 6, 50, 0, 0, wib)
+
+	f.repo.On("FindByUserAndDate", f.unitId, f.teacher.UserId, day("2025-09-15")).Return(&schemas.StaffAttendance{}, nil)
+	_, err := f.uc.CheckIn(&CheckRequest{UnitId: f.unitId, UserId: f.teacher.UserId})
+	assert.ErrorIs(t, err, ErrAlreadyCheckedIn)
+
+	lat := 95.0
+	lng := 106.8
+	_, err = f.uc.CheckIn(&CheckRequest{UnitId: f.unitId, UserId: f.teacher.UserId, Latitude: &lat, Longitude: &lng})
+	assert.ErrorIs(t, err, ErrInvalidCoordinates)
+	_, err = f.uc.CheckIn(&CheckRequest{UnitId: f.unitId, UserId: f.teacher.UserId, Longitude: &lng})
+	assert.ErrorIs(t, err, ErrInvalidCoordinates)
+
+	parent := uuid.New()
+	f.repo.On("FindTeacherByUser", f.unitId, parent).Return(nil, gorm.ErrRecordNotFound)
+	f.repo.On("FindMember", f.unitId, parent).Return(&schemas.UnitMember{Role: schemas.UnitMemberRoleParent}, nil)
+	_, err = f.uc.CheckIn(&CheckRequest{UnitId: f.unitId, UserId: parent})
+	assert.ErrorIs(t, err, ErrNotStaff)
+
+	f.repo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestCheckIn_ViaApiKey(t *testing.T) {
+	f := newFixture()
+	f.now = time.Date(2025, 9, 15, 6, 55, 0, 0, wib)
+	f.repo.On("FindByUserAndDate", f.unitId, f.teacher.UserId, day("2025-09-15")).Return(nil, gorm.ErrRecordNotFound)
+	f.repo.On("Create", mock.Anything).Return(nil)
+	keyCreator := uuid.New()
+
+	// Without a staff member the check-in would land on the key's creator
+	_, err := f.uc.CheckIn(&CheckRequest{UnitId: f.unitId, UserId: keyCreator, ViaApiKey: true})
+	assert.ErrorIs(t, err, ErrStaffUserRequired)
+
+	record, err := f.uc.CheckIn(&CheckRequest{UnitId: f.unitId, UserId: keyCreator, ViaApiKey: true, StaffUserId: &f.teacher.UserId})
+	assert.NoError(t, err)
+	assert.Equal(t, f.teacher.UserId, record.UserId)
+
+	// Someone who is not staff of the unit cannot be checked in either
+	outsider := uuid.New()
+	f.repo.On("FindTeacherByUser", f.unitId, outsider).Return(nil, gorm.ErrRecordNotFound)
+	f.repo.On("FindMember", f.unitId, outsider).Return(nil, gorm.ErrRecordNotFound)
+	_, err = f.uc.CheckIn(&CheckRequest{UnitId: f.unitId, UserId: keyCreator, ViaApiKey: true, StaffUserId: &outsider})
+	assert.ErrorIs(t, err, ErrNotStaff)
+}
+
+func TestCheckOut_EarlyLeave(t *testing.T) {
+	f := newFixture()
+	f.now = time.Date(2025, 9, 15, 12, 45, 0, 0, wib)
+	record := &schemas.StaffAttendance{UnitId: f.unitId, UserId: f.teacher.UserId, Date: day("2025-09-15")}
+	f.repo.On("FindByUserAndDate", f.unitId, f.teacher.UserId, day("2025-09-15")).Return(record, nil)
+	f.repo.On("Update", record).Return(nil)
+
+	device := "android-7f3a"
+	saved, err := f.uc.CheckOut(&CheckRequest{UnitId: f.unitId, UserId: f.teacher.UserId, DeviceId: &device})
+	assert.NoError(t, err)
+	assert.Equal(t, 45, saved.EarlyLeaveMinutes)
+	assert.Equal(t, &device, saved.CheckOutDevice)
+
+	_, err = f.uc.CheckOut(&CheckRequest{UnitId: f.unitId, UserId: f.teacher.UserId})
+	assert.ErrorIs(t, err, ErrAlreadyCheckedOut)
+}
+
+func TestCheckOut_WithoutCheckIn(t *testing.T) {
+	f := newFixture()
+	f.now = time.Date(2025, 9, 15, 14, 0, 0, 0, wib)
+	f.repo.On("FindByUserAndDate", f.unitId, f.teacher.UserId, day("2025-09-15")).Return(nil, gorm.ErrRecordNotFound)
+
+	_, err := f.uc.CheckOut(&CheckRequest{UnitId: f.unitId, UserId: f.teacher.UserId})
+	assert.ErrorIs(t, err, ErrNotCheckedIn)
+}
+
+func TestHoursFrom_EndOfLastPeriod(t *testing.T) {
+	hours, err := hoursFrom(&schemas.UnitSettings{
+		StartTime: "07:00", PeriodDuration: 40, TotalPeriods: 9, BreakAfterPeriod: 3, BreakDuration: 15,
+	})
+	assert.NoError(t, err)
+	// 9 x 40 minutes and a 15 minute break after 07:00
+	assert.Equal(t, 13*time.Hour+15*time.Minute, hours.end)
+	assert.Equal(t, "Asia/Jakarta", hours.location.String())
+}
+
+func TestGetMonthlyRecap_ForPayroll(t *testing.T) {
+	f := newFixture()
+	nip := "198001012005011001"
+	f.teacher.NIP = &nip
+	f.teacher.EmploymentStatus = "honorer"
+	f.teacher.User = &schemas.User{FullName: "Ahmad"}
+	clerk := schemas.UnitMember{UserId: uuid.New(), Role: schemas.UnitMemberRoleStaff, User: &schemas.User{FullName: "Budi"}}
+	out := time.Date(2025, 9, 1, 13, 30, 0, 0, wib)
+
+	from, to := day("2025-09-01"), day("2025-09-30")
+	f.repo.On("FindStaff", f.unitId).Return([]schemas.UnitMember{clerk}, nil)
+	f.repo.On("FindTeachers", f.unitId).Return([]schemas.TeacherProfile{*f.teacher}, nil)
+	f.repo.On("FindByDates", f.unitId, (*uuid.UUID)(nil), from, to).Return([]schemas.StaffAttendance{
+		{UserId: f.teacher.UserId, LateMinutes: 12, CheckOutAt: &out},
+		{UserId: f.teacher.UserId, EarlyLeaveMinutes: 30, CheckOutAt: &out},
+		{UserId: f.teacher.UserId},
+	}, nil)
+
+	recap, err := f.uc.GetMonthlyRecap(f.unitId, day("2025-09-10"))
+	assert.NoError(t, err)
+	assert.Equal(t, "2025-09", recap.Month)
+	if !assert.Len(t, recap.Staff, 2) {
+		return
+	}
+
+	ahmad := recap.Staff[0]
+	assert.Equal(t, "Ahmad", ahmad.Name)
+	assert.Equal(t, 3, ahmad.DaysPresent)
+	assert.Equal(t, 1, ahmad.LateDays)
+	assert.Equal(t, 12, ahmad.LateMinutes)
+	assert.Equal(t, 1, ahmad.EarlyLeaveDays)
+	assert.Equal(t, 1, ahmad.MissingCheckOut)
+	assert.Equal(t, 0, recap.Staff[1].DaysPresent)
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteRecapCSV(&buf, recap))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Equal(t, "Ahmad,198001012005011001,honorer,,3,1,12,1,30,1", lines[1])
+}
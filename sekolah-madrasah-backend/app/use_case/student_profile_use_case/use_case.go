@@ -32,7 +32,8 @@ type CreateStudentProfileRequest struct {
 	MotherName     *string
 	GuardianName   *string
 	ParentPhone    *string
-	EnrollmentDate *string // Format: YYYY-MM-DD
+	ParentUserId   *uuid.UUID // Parent's account, may see the published report cards
+	EnrollmentDate *string    // Format: YYYY-MM-DD
 }
 
 type UpdateStudentProfileRequest struct {
@@ -47,6 +48,7 @@ type UpdateStudentProfileRequest struct {
 	MotherName     *string
 	GuardianName   *string
 	ParentPhone    *string
+	ParentUserId   *uuid.UUID
 	EnrollmentDate *string
 }
 
@@ -77,6 +79,7 @@ func (uc *studentProfileUseCase) Create(req *CreateStudentProfileRequest) (*sche
 		MotherName:   req.MotherName,
 		GuardianName: req.GuardianName,
 		ParentPhone:  req.ParentPhone,
+		ParentUserId: req.ParentUserId,
 	}
 
 	if req.BirthDate != nil {
@@ -156,6 +159,9 @@ func (uc *studentProfileUseCase) Update(id uuid.UUID, req *UpdateStudentProfileR
 	if req.ParentPhone != nil {
 		profile.ParentPhone = req.ParentPhone
 	}
+	if req.ParentUserId != nil {
+		profile.ParentUserId = req.ParentUserId
+	}
 	if req.EnrollmentDate != nil {
 		if t, err := time.Parse("2006-01-02", *req.EnrollmentDate); err == nil {
 			profile.EnrollmentDate = &t
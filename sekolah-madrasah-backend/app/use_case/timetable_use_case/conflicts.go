@@ -0,0 +1,245 @@
+package timetable_use_case
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ConflictType string
+
+const (
+	ConflictTeacherBooked      ConflictType = "teacher_double_booked"
+	ConflictClassBooked        ConflictType = "class_double_booked"
+	ConflictRoomBooked         ConflictType = "room_double_booked"
+	ConflictTeacherUnavailable ConflictType = "teacher_unavailable"
+	ConflictTooManyConsecutive ConflictType = "max_consecutive_exceeded"
+)
+
+// Conflict is one rule the timetable breaks, with the entries involved.
+type Conflict struct {
+	Type      ConflictType `json:"type"`
+	DayOfWeek int          `json:"day_of_week"`
+	Period    int          `json:"period"`
+	EntryIds  []uuid.UUID  `json:"entry_ids"`
+	Message   string       `json:"message"`
+}
+
+// ConflictRules are the constraints checked on top of double bookings.
+type ConflictRules struct {
+	Unavailability []schemas.TeacherUnavailability
+	// MaxConsecutive limits the periods a teacher teaches in a row, 0 means no limit
+	MaxConsecutive int
+	// BreakAfterPeriod ends a run of consecutive periods
+	BreakAfterPeriod int
+}
+
+func rulesFor(settings *schemas.UnitSettings, unavailability []schemas.TeacherUnavailability) ConflictRules {
+	return ConflictRules{
+		Unavailability:   unavailability,
+		MaxConsecutive:   settings.MaxConsecutive,
+		BreakAfterPeriod: settings.BreakAfterPeriod,
+	}
+}
+
+var ErrConflict = errors.New("the timetable has conflicts")
+
+// ConflictError carries the conflicts that made a change to the timetable fail.
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	if len(e.Conflicts) == 1 {
+		return e.Conflicts[0].Message
+	}
+	return fmt.Sprintf("%s (and %d more conflicts)", e.Conflicts[0].Message, len(e.Conflicts)-1)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// slotTaken reports a lesson saved concurrently into the entry's slot, caught
+// by the unique index on the class's slots, as the double booking it is.
+func slotTaken(entry *schemas.TimetableEntry, err error) error {
+	if !errors.Is(err, gorm.ErrDuplicatedKey) {
+		return err
+	}
+	return &ConflictError{Conflicts: []Conflict{{ConflictClassBooked, entry.DayOfWeek, entry.Period, []uuid.UUID{entry.Id},
+		"the class already has a lesson at this time"}}}
+}
+
+type slot struct {
+	day, period int
+}
+
+// CheckConflicts reports every double-booked teacher, class or room, every lesson
+// in a teacher's unavailable periods and every run of periods longer than the
+// rules allow. The manual editor and the generator both validate with it.
+func CheckConflicts(entries []schemas.TimetableEntry, rules ConflictRules) []Conflict {
+	sorted := append([]schemas.TimetableEntry(nil), entries...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].DayOfWeek != sorted[j].DayOfWeek {
+			return sorted[i].DayOfWeek < sorted[j].DayOfWeek
+		}
+		if sorted[i].Period != sorted[j].Period {
+			return sorted[i].Period < sorted[j].Period
+		}
+		return sorted[i].Id.String() < sorted[j].Id.String()
+	})
+
+	var conflicts []Conflict
+	conflicts = append(conflicts, doubleBookings(sorted)...)
+
+	for _, entry := range sorted {
+		for _, blocked := range rules.Unavailability {
+			if blocked.TeacherProfileId == entry.TeacherProfileId && blocked.Covers(entry.DayOfWeek, entry.Period) {
+				conflicts = append(conflicts, Conflict{
+					Type:      ConflictTeacherUnavailable,
+					DayOfWeek: entry.DayOfWeek,
+					Period:    entry.Period,
+					EntryIds:  []uuid.UUID{entry.Id},
+					Message:   "the teacher is unavailable in this period",
+				})
+				break
+			}
+		}
+	}
+
+	if rules.MaxConsecutive > 0 {
+		conflicts = append(conflicts, consecutiveRuns(sorted, rules)...)
+	}
+	return conflicts
+}
+
+func doubleBookings(sorted []schemas.TimetableEntry) []Conflict {
+	var conflicts []Conflict
+	for start := 0; start < len(sorted); {
+		end := start
+		current := slot{sorted[start].DayOfWeek, sorted[start].Period}
+		for end < len(sorted) && (slot{sorted[end].DayOfWeek, sorted[end].Period}) == current {
+			end++
+		}
+
+		teachers := map[uuid.UUID][]uuid.UUID{}
+		classes := map[uuid.UUID][]uuid.UUID{}
+		rooms := map[string][]uuid.UUID{}
+		var teacherOrder, classOrder []uuid.UUID
+		var roomOrder []string
+		for _, entry := range sorted[start:end] {
+			if len(teachers[entry.TeacherProfileId]) == 0 {
+				teacherOrder = append(teacherOrder, entry.TeacherProfileId)
+			}
+			teachers[entry.TeacherProfileId] = append(teachers[entry.TeacherProfileId], entry.Id)
+			if len(classes[entry.ClassId]) == 0 {
+				classOrder = append(classOrder, entry.ClassId)
+			}
+			classes[entry.ClassId] = append(classes[entry.ClassId], entry.Id)
+			if entry.Room != nil {
+				if len(rooms[*entry.Room]) == 0 {
+					roomOrder = append(roomOrder, *entry.Room)
+				}
+				rooms[*entry.Room] = append(rooms[*entry.Room], entry.Id)
+			}
+		}
+
+		for _, teacherId := range teacherOrder {
+			if ids := teachers[teacherId]; len(ids) > 1 {
+				conflicts = append(conflicts, Conflict{ConflictTeacherBooked, current.day, current.period, ids,
+					fmt.Sprintf("the teacher is scheduled for %d lessons at once", len(ids))})
+			}
+		}
+		for _, classId := range classOrder {
+			if ids := classes[classId]; len(ids) > 1 {
+				conflicts = append(conflicts, Conflict{ConflictClassBooked, current.day, current.period, ids,
+					fmt.Sprintf("the class has %d lessons at once", len(ids))})
+			}
+		}
+		for _, room := range roomOrder {
+			if ids := rooms[room]; len(ids) > 1 {
+				conflicts = append(conflicts, Conflict{ConflictRoomBooked, current.day, current.period, ids,
+					fmt.Sprintf("room %s is used by %d lessons at once", room, len(ids))})
+			}
+		}
+
+		start = end
+	}
+	return conflicts
+}
+
+// consecutiveRuns reports each run of a teacher's periods on one day that is
+// longer than the limit, once per run.
+func consecutiveRuns(sorted []schemas.TimetableEntry, rules ConflictRules) []Conflict {
+	type teacherDay struct {
+		teacher uuid.UUID
+		day     int
+	}
+	periods := map[teacherDay][]schemas.TimetableEntry{}
+	var order []teacherDay
+	for _, entry := range sorted {
+		key := teacherDay{entry.TeacherProfileId, entry.DayOfWeek}
+		if len(periods[key]) == 0 {
+			order = append(order, key)
+		}
+		periods[key] = append(periods[key], entry)
+	}
+
+	var conflicts []Conflict
+	for _, key := range order {
+		var run []schemas.TimetableEntry
+		flush := func() {
+			if countPeriods(run) > rules.MaxConsecutive {
+				ids := make([]uuid.UUID, 0, len(run))
+				for _, entry := range run {
+					ids = append(ids, entry.Id)
+				}
+				conflicts = append(conflicts, Conflict{ConflictTooManyConsecutive, key.day, run[0].Period, ids,
+					fmt.Sprintf("the teacher teaches %d periods in a row, the limit is %d", countPeriods(run), rules.MaxConsecutive)})
+			}
+			run = nil
+		}
+
+		for _, entry := range periods[key] {
+			if len(run) > 0 {
+				last := run[len(run)-1].Period
+				if entry.Period != last && (entry.Period != last+1 || last == rules.BreakAfterPeriod) {
+					flush()
+				}
+			}
+			run = append(run, entry)
+		}
+		flush()
+	}
+	return conflicts
+}
+
+// countPeriods counts distinct periods, so a double booking is not counted twice.
+func countPeriods(run []schemas.TimetableEntry) int {
+	count := 0
+	for i, entry := range run {
+		if i == 0 || entry.Period != run[i-1].Period {
+			count++
+		}
+	}
+	return count
+}
+
+// involving keeps the conflicts an entry takes part in.
+func involving(conflicts []Conflict, id uuid.UUID) []Conflict {
+	var result []Conflict
+	for _, conflict := range conflicts {
+		for _, entryId := range conflict.EntryIds {
+			if entryId == id {
+				result = append(result, conflict)
+				break
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,618 @@
+package timetable_use_case
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"sekolah-madrasah/app/repository/timetable_repository"
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+	"gorm.io/gorm"
+)
+
+// defaultDays are the school days a generation spreads lessons over when the
+// request names none: Monday to Friday.
+var defaultDays = []int{1, 2, 3, 4, 5}
+
+// A running job records a heartbeat every generationHeartbeat; one silent for
+// staleGenerationAfter is taken to have died with the server running it.
+const (
+	generationHeartbeat  = 30 * time.Second
+	staleGenerationAfter = 5 * generationHeartbeat
+)
+
+type GenerationRequest struct {
+	UnitId       uuid.UUID
+	AcademicYear string      // Defaults to the unit's current academic year
+	Semester     int         // Defaults to the unit's current semester
+	ClassIds     []uuid.UUID // Defaults to every active class of the academic year
+	Days         []int       // Defaults to Monday to Friday
+	RequestedBy  uuid.UUID
+}
+
+type ClassSubjectRequest struct {
+	SubjectId        uuid.UUID
+	TeacherProfileId *uuid.UUID
+	HoursPerWeek     int
+	Room             *string
+}
+
+type UnavailabilityRequest struct {
+	DayOfWeek  int
+	PeriodFrom int
+	PeriodTo   int
+	Reason     *string
+}
+
+// GenerationView is a generation job with its proposal decoded.
+type GenerationView struct {
+	Id           uuid.UUID                         `json:"id"`
+	UnitId       uuid.UUID                         `json:"unit_id"`
+	AcademicYear string                            `json:"academic_year"`
+	Semester     int                               `json:"semester"`
+	ClassIds     []uuid.UUID                       `json:"class_ids"`
+	Days         []int                             `json:"days"`
+	Status       schemas.TimetableGenerationStatus `json:"status"`
+	Entries      []schemas.TimetableEntry          `json:"entries"`
+	Unsatisfied  []Unsatisfied                     `json:"unsatisfied"`
+	Error        *string                           `json:"error"`
+	RequestedBy  uuid.UUID                         `json:"requested_by"`
+	StartedAt    *time.Time                        `json:"started_at"`
+	FinishedAt   *time.Time                        `json:"finished_at"`
+	AppliedAt    *time.Time                        `json:"applied_at"`
+	CreatedAt    time.Time                         `json:"created_at"`
+}
+
+func (uc *timetableUseCase) StartGeneration(req *GenerationRequest) (*GenerationView, error) {
+	settings, err := uc.settings(req.UnitId)
+	if err != nil {
+		return nil, err
+	}
+	academicYear, semester, err := term(req.AcademicYear, req.Semester, settings)
+	if err != nil {
+		return nil, err
+	}
+	if semester != 1 && semester != 2 {
+		return nil, errors.New("semester must be 1 or 2")
+	}
+
+	days := req.Days
+	if len(days) == 0 {
+		days = defaultDays
+	}
+	seenDays := map[int]bool{}
+	for _, day := range days {
+		if day < 1 || day > 7 {
+			return nil, errors.New("days must be between 1 (Monday) and 7 (Sunday)")
+		}
+		if seenDays[day] {
+			return nil, fmt.Errorf("day %d is listed twice", day)
+		}
+		seenDays[day] = true
+	}
+
+	classIds := req.ClassIds
+	if len(classIds) == 0 {
+		classes, err := uc.repo.FindActiveClasses(req.UnitId, academicYear)
+		if err != nil {
+			return nil, err
+		}
+		for _, class := range classes {
+			classIds = append(classIds, class.Id)
+		}
+	} else {
+		for _, classId := range classIds {
+			class, err := uc.repo.FindClass(classId)
+			if err != nil || class.UnitId != req.UnitId {
+				return nil, errors.New("class not found in this unit")
+			}
+			if class.AcademicYear != academicYear {
+				return nil, fmt.Errorf("class %s belongs to academic year %s", class.Name, class.AcademicYear)
+			}
+		}
+	}
+	if len(classIds) == 0 {
+		return nil, errors.New("there are no active classes to generate a timetable for")
+	}
+
+	classIdsJSON, _ := json.Marshal(classIds)
+	daysJSON, _ := json.Marshal(days)
+	generation := &schemas.TimetableGeneration{
+		UnitId:       req.UnitId,
+		AcademicYear: academicYear,
+		Semester:     semester,
+		ClassIds:     string(classIdsJSON),
+		Days:         string(daysJSON),
+		Status:       schemas.TimetableGenerationPending,
+		Entries:      "[]",
+		Unsatisfied:  "[]",
+		RequestedBy:  req.RequestedBy,
+	}
+	if err := uc.repo.CreateGeneration(generation); err != nil {
+		return nil, err
+	}
+
+	// The job works on its own copy so the response below does not race with it
+	job := *generation
+	go uc.runGeneration(&job)
+
+	return toGenerationView(generation)
+}
+
+// runGeneration does the work of a generation job and records its outcome.
+// It runs in the background, so a panic fails the job instead of the server.
+func (uc *timetableUseCase) runGeneration(generation *schemas.TimetableGeneration) {
+	defer func() {
+		if r := recover(); r != nil {
+			uc.failGeneration(generation, fmt.Errorf("generator panicked: %v", r))
+		}
+	}()
+
+	now := time.Now()
+	if err := uc.repo.StartGeneration(generation.Id, now); err != nil {
+		log.Errorf("timetable generation %s: %v", generation.Id, err)
+		return
+	}
+	generation.Status = schemas.TimetableGenerationRunning
+	generation.StartedAt = &now
+	defer uc.keepAlive(generation.Id)()
+
+	result, err := uc.generate(generation)
+	if err != nil {
+		uc.failGeneration(generation, err)
+		return
+	}
+
+	entriesJSON, _ := json.Marshal(result.Entries)
+	unsatisfiedJSON, _ := json.Marshal(result.Unsatisfied)
+	finished := time.Now()
+	generation.Status = schemas.TimetableGenerationCompleted
+	generation.Entries = string(entriesJSON)
+	generation.Unsatisfied = string(unsatisfiedJSON)
+	generation.FinishedAt = &finished
+	if err := uc.repo.FinishGeneration(generation); err != nil {
+		log.Errorf("timetable generation %s: %v", generation.Id, err)
+	}
+}
+
+// keepAlive records heartbeats for a running job until the returned function is
+// called, so that other servers do not take the job for an interrupted one.
+func (uc *timetableUseCase) keepAlive(id uuid.UUID) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(generationHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := uc.repo.HeartbeatGeneration(id); err != nil {
+					log.Errorf("timetable generation %s: %v", id, err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// FailInterruptedGenerations fails the pending and running jobs that have not
+// shown a sign of life for staleGenerationAfter; the server running them died
+// and they would never finish. Jobs other servers are still running are left
+// alone.
+func (uc *timetableUseCase) FailInterruptedGenerations() error {
+	failed, err := uc.repo.FailStaleGenerations("interrupted by a server restart, start the generation again", time.Now().Add(-staleGenerationAfter))
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		log.Warnf("marked %d interrupted timetable generation(s) as failed", failed)
+	}
+	return nil
+}
+
+func (uc *timetableUseCase) failGeneration(generation *schemas.TimetableGeneration, cause error) {
+	message := cause.Error()
+	finished := time.Now()
+	generation.Status = schemas.TimetableGenerationFailed
+	generation.Error = &message
+	generation.FinishedAt = &finished
+	if err := uc.repo.FinishGeneration(generation); err != nil {
+		log.Errorf("timetable generation %s: %v", generation.Id, err)
+	}
+}
+
+// generate collects the generator's input for a job, runs it and validates the
+// proposal with CheckConflicts against the lessons of the other classes.
+func (uc *timetableUseCase) generate(generation *schemas.TimetableGeneration) (GeneratorResult, error) {
+	var classIds []uuid.UUID
+	var days []int
+	if err := json.Unmarshal([]byte(generation.ClassIds), &classIds); err != nil {
+		return GeneratorResult{}, err
+	}
+	if err := json.Unmarshal([]byte(generation.Days), &days); err != nil {
+		return GeneratorResult{}, err
+	}
+
+	settings, err := uc.settings(generation.UnitId)
+	if err != nil {
+		return GeneratorResult{}, err
+	}
+	unavailability, err := uc.repo.FindUnavailability(generation.UnitId)
+	if err != nil {
+		return GeneratorResult{}, err
+	}
+	classSubjects, err := uc.repo.FindClassSubjects(classIds)
+	if err != nil {
+		return GeneratorResult{}, err
+	}
+	assignments, err := uc.repo.FindSubjectTeachers(generation.UnitId)
+	if err != nil {
+		return GeneratorResult{}, err
+	}
+	current, err := uc.repo.Find(timetable_repository.TimetableFilter{
+		UnitId:       generation.UnitId,
+		AcademicYear: generation.AcademicYear,
+		Semester:     generation.Semester,
+	})
+	if err != nil {
+		return GeneratorResult{}, err
+	}
+
+	regenerated := map[uuid.UUID]bool{}
+	for _, classId := range classIds {
+		regenerated[classId] = true
+	}
+	var fixed []schemas.TimetableEntry
+	for _, entry := range current {
+		if !regenerated[entry.ClassId] {
+			fixed = append(fixed, entry)
+		}
+	}
+
+	demands, issues := lessonDemands(classSubjects, assignments)
+	input := GeneratorInput{
+		UnitId:       generation.UnitId,
+		AcademicYear: generation.AcademicYear,
+		Semester:     generation.Semester,
+		Days:         days,
+		Periods:      settings.TotalPeriods,
+		Rules:        rulesFor(settings, unavailability),
+		Demands:      demands,
+		Fixed:        fixed,
+	}
+	result := Generate(input)
+	result.Unsatisfied = append(issues, result.Unsatisfied...)
+
+	for _, conflict := range validateProposal(fixed, result.Entries, input.Rules) {
+		result.Unsatisfied = append(result.Unsatisfied, Unsatisfied{
+			Type:   UnsatisfiedConflict,
+			Reason: fmt.Sprintf("day %d period %d: %s", conflict.DayOfWeek, conflict.Period, conflict.Message),
+		})
+	}
+	if result.Entries == nil {
+		result.Entries = []schemas.TimetableEntry{}
+	}
+	if result.Unsatisfied == nil {
+		result.Unsatisfied = []Unsatisfied{}
+	}
+	return result, nil
+}
+
+// validateProposal runs the editor's conflict check over the proposal together
+// with the lessons it has to fit around, keeping the conflicts it causes.
+func validateProposal(fixed, proposed []schemas.TimetableEntry, rules ConflictRules) []Conflict {
+	proposedIds := map[uuid.UUID]bool{}
+	for _, entry := range proposed {
+		proposedIds[entry.Id] = true
+	}
+
+	var conflicts []Conflict
+	for _, conflict := range CheckConflicts(append(append([]schemas.TimetableEntry(nil), fixed...), proposed...), rules) {
+		for _, id := range conflict.EntryIds {
+			if proposedIds[id] {
+				conflicts = append(conflicts, conflict)
+				break
+			}
+		}
+	}
+	return conflicts
+}
+
+// lessonDemands turns the classes' subject hours into generator demands. A
+// subject's pengampu is used when it is set; otherwise the subject goes to the
+// least loaded teacher assigned to it, primary teachers first.
+func lessonDemands(classSubjects []schemas.ClassSubject, assignments []schemas.TeacherSubject) ([]LessonDemand, []Unsatisfied) {
+	teachers := map[uuid.UUID][]schemas.TeacherSubject{}
+	for _, assignment := range assignments {
+		teachers[assignment.SubjectId] = append(teachers[assignment.SubjectId], assignment)
+	}
+
+	sorted := append([]schemas.ClassSubject(nil), classSubjects...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].HoursPerWeek != sorted[j].HoursPerWeek {
+			return sorted[i].HoursPerWeek > sorted[j].HoursPerWeek
+		}
+		return sorted[i].Id.String() < sorted[j].Id.String()
+	})
+
+	load := map[uuid.UUID]int{}
+	for _, classSubject := range sorted {
+		if classSubject.TeacherProfileId != nil {
+			load[*classSubject.TeacherProfileId] += classSubject.HoursPerWeek
+		}
+	}
+
+	var demands []LessonDemand
+	var issues []Unsatisfied
+	for _, classSubject := range sorted {
+		if classSubject.HoursPerWeek <= 0 {
+			continue
+		}
+		classId, subjectId := classSubject.ClassId, classSubject.SubjectId
+
+		var teacherId uuid.UUID
+		if classSubject.TeacherProfileId != nil {
+			for _, assignment := range teachers[subjectId] {
+				if assignment.TeacherProfileId == *classSubject.TeacherProfileId {
+					teacherId = assignment.TeacherProfileId
+					break
+				}
+			}
+		} else {
+			var chosen *schemas.TeacherSubject
+			for i, assignment := range teachers[subjectId] {
+				if chosen == nil ||
+					assignment.IsPrimary && !chosen.IsPrimary ||
+					assignment.IsPrimary == chosen.IsPrimary && load[assignment.TeacherProfileId] < load[chosen.TeacherProfileId] {
+					chosen = &teachers[subjectId][i]
+				}
+			}
+			if chosen != nil {
+				teacherId = chosen.TeacherProfileId
+				load[teacherId] += classSubject.HoursPerWeek
+			}
+		}
+
+		if teacherId == uuid.Nil {
+			reason := "no teacher is assigned to the subject"
+			if classSubject.TeacherProfileId != nil {
+				reason = "the class's teacher for the subject is not assigned to it"
+			}
+			issues = append(issues, Unsatisfied{
+				Type:         UnsatisfiedNoTeacher,
+				ClassId:      &classId,
+				SubjectId:    &subjectId,
+				MissingHours: classSubject.HoursPerWeek,
+				Reason:       reason,
+			})
+			continue
+		}
+
+		demands = append(demands, LessonDemand{
+			ClassId:          classId,
+			SubjectId:        subjectId,
+			TeacherProfileId: teacherId,
+			Room:             normalizeRoom(classSubject.Room),
+			Hours:            classSubject.HoursPerWeek,
+		})
+	}
+	return demands, issues
+}
+
+func (uc *timetableUseCase) GetGeneration(id uuid.UUID) (*GenerationView, error) {
+	generation, err := uc.repo.FindGeneration(id)
+	if err != nil {
+		return nil, ErrGenerationNotFound
+	}
+	return toGenerationView(generation)
+}
+
+// ApplyGeneration replaces the timetable of the job's classes with its proposal.
+// The proposal is checked again, as the rest of the timetable may have changed
+// since it was generated.
+func (uc *timetableUseCase) ApplyGeneration(id uuid.UUID) (*GenerationView, error) {
+	generation, err := uc.repo.FindGeneration(id)
+	if err != nil {
+		return nil, ErrGenerationNotFound
+	}
+	if generation.Status != schemas.TimetableGenerationCompleted {
+		return nil, fmt.Errorf("only a completed generation can be applied, this one is %s", generation.Status)
+	}
+
+	view, err := toGenerationView(generation)
+	if err != nil {
+		return nil, err
+	}
+	for _, issue := range view.Unsatisfied {
+		if issue.Type == UnsatisfiedConflict {
+			return nil, errors.New("the generated timetable has conflicts and cannot be applied")
+		}
+	}
+
+	settings, err := uc.settings(generation.UnitId)
+	if err != nil {
+		return nil, err
+	}
+	unavailability, err := uc.repo.FindUnavailability(generation.UnitId)
+	if err != nil {
+		return nil, err
+	}
+	current, err := uc.repo.Find(timetable_repository.TimetableFilter{
+		UnitId:       generation.UnitId,
+		AcademicYear: generation.AcademicYear,
+		Semester:     generation.Semester,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	regenerated := map[uuid.UUID]bool{}
+	for _, classId := range view.ClassIds {
+		regenerated[classId] = true
+	}
+	var kept []schemas.TimetableEntry
+	for _, entry := range current {
+		if !regenerated[entry.ClassId] {
+			kept = append(kept, entry)
+		}
+	}
+	if conflicts := validateProposal(kept, view.Entries, rulesFor(settings, unavailability)); len(conflicts) > 0 {
+		return nil, &ConflictError{Conflicts: conflicts}
+	}
+
+	now := time.Now()
+	generation.Status = schemas.TimetableGenerationApplied
+	generation.AppliedAt = &now
+	if err := uc.repo.ApplyGeneration(generation, view.ClassIds, view.Entries); err != nil {
+		switch {
+		case errors.Is(err, timetable_repository.ErrGenerationChanged):
+			return nil, fmt.Errorf("%w: the generation was applied meanwhile", ErrConflict)
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			return nil, fmt.Errorf("%w: a lesson was added to one of the classes meanwhile, generate the timetable again", ErrConflict)
+		}
+		return nil, err
+	}
+	view.Status = generation.Status
+	view.AppliedAt = generation.AppliedAt
+	return view, nil
+}
+
+func (uc *timetableUseCase) GetClassSubjects(unitId, classId uuid.UUID) ([]schemas.ClassSubject, error) {
+	class, err := uc.repo.FindClass(classId)
+	if err != nil || class.UnitId != unitId {
+		return nil, errors.New("class not found in this unit")
+	}
+	return uc.repo.FindClassSubjects([]uuid.UUID{classId})
+}
+
+func (uc *timetableUseCase) SetClassSubjects(unitId, classId uuid.UUID, subjects []ClassSubjectRequest) ([]schemas.ClassSubject, error) {
+	class, err := uc.repo.FindClass(classId)
+	if err != nil || class.UnitId != unitId {
+		return nil, errors.New("class not found in this unit")
+	}
+
+	rows := make([]schemas.ClassSubject, 0, len(subjects))
+	seen := map[uuid.UUID]bool{}
+	for _, req := range subjects {
+		if seen[req.SubjectId] {
+			return nil, errors.New("a subject can only be listed once per class")
+		}
+		seen[req.SubjectId] = true
+		if req.HoursPerWeek < 1 {
+			return nil, errors.New("hours_per_week must be at least 1")
+		}
+
+		subject, err := uc.repo.FindSubject(req.SubjectId)
+		if err != nil || subject.UnitId != unitId {
+			return nil, errors.New("subject not found in this unit")
+		}
+		if req.TeacherProfileId != nil {
+			teacher, err := uc.repo.FindTeacher(*req.TeacherProfileId)
+			if err != nil || teacher.UnitId != unitId {
+				return nil, errors.New("teacher not found in this unit")
+			}
+			assigned, err := uc.repo.IsTeacherAssigned(teacher.Id, subject.Id)
+			if err != nil {
+				return nil, err
+			}
+			if !assigned {
+				return nil, errors.New("teacher is not assigned to this subject")
+			}
+		}
+
+		rows = append(rows, schemas.ClassSubject{
+			ClassId:          classId,
+			SubjectId:        req.SubjectId,
+			TeacherProfileId: req.TeacherProfileId,
+			HoursPerWeek:     req.HoursPerWeek,
+			Room:             normalizeRoom(req.Room),
+		})
+	}
+
+	if err := uc.repo.ReplaceClassSubjects(classId, rows); err != nil {
+		return nil, err
+	}
+	return uc.repo.FindClassSubjects([]uuid.UUID{classId})
+}
+
+func (uc *timetableUseCase) GetUnavailability(unitId, teacherProfileId uuid.UUID) ([]schemas.TeacherUnavailability, error) {
+	teacher, err := uc.repo.FindTeacher(teacherProfileId)
+	if err != nil || teacher.UnitId != unitId {
+		return nil, errors.New("teacher not found in this unit")
+	}
+	return uc.repo.FindTeacherUnavailability(teacherProfileId)
+}
+
+func (uc *timetableUseCase) SetUnavailability(unitId, teacherProfileId uuid.UUID, periods []UnavailabilityRequest) ([]schemas.TeacherUnavailability, error) {
+	teacher, err := uc.repo.FindTeacher(teacherProfileId)
+	if err != nil || teacher.UnitId != unitId {
+		return nil, errors.New("teacher not found in this unit")
+	}
+	settings, err := uc.settings(unitId)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]schemas.TeacherUnavailability, 0, len(periods))
+	for _, req := range periods {
+		if req.DayOfWeek < 1 || req.DayOfWeek > 7 {
+			return nil, errors.New("day_of_week must be between 1 (Monday) and 7 (Sunday)")
+		}
+		if req.PeriodFrom < 1 || req.PeriodTo < req.PeriodFrom || req.PeriodTo > settings.TotalPeriods {
+			return nil, errors.New("period_from and period_to must be a range within the unit's total periods")
+		}
+		rows = append(rows, schemas.TeacherUnavailability{
+			UnitId:           unitId,
+			TeacherProfileId: teacherProfileId,
+			DayOfWeek:        req.DayOfWeek,
+			PeriodFrom:       req.PeriodFrom,
+			PeriodTo:         req.PeriodTo,
+			Reason:           req.Reason,
+		})
+	}
+
+	if err := uc.repo.ReplaceTeacherUnavailability(teacherProfileId, rows); err != nil {
+		return nil, err
+	}
+	return uc.repo.FindTeacherUnavailability(teacherProfileId)
+}
+
+func toGenerationView(generation *schemas.TimetableGeneration) (*GenerationView, error) {
+	view := &GenerationView{
+		Id:           generation.Id,
+		UnitId:       generation.UnitId,
+		AcademicYear: generation.AcademicYear,
+		Semester:     generation.Semester,
+		Status:       generation.Status,
+		Error:        generation.Error,
+		RequestedBy:  generation.RequestedBy,
+		StartedAt:    generation.StartedAt,
+		FinishedAt:   generation.FinishedAt,
+		AppliedAt:    generation.AppliedAt,
+		CreatedAt:    generation.CreatedAt,
+	}
+	fields := []struct {
+		raw    string
+		target any
+	}{
+		{generation.ClassIds, &view.ClassIds},
+		{generation.Days, &view.Days},
+		{generation.Entries, &view.Entries},
+		{generation.Unsatisfied, &view.Unsatisfied},
+	}
+	for _, field := range fields {
+		if field.raw == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(field.raw), field.target); err != nil {
+			return nil, err
+		}
+	}
+	return view, nil
+}
@@ -0,0 +1,303 @@
+package timetable_use_case
+
+import (
+	"fmt"
+	"sort"
+
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+)
+
+// LessonDemand asks for Hours weekly lessons of a subject in a class, all taught
+// by the same teacher.
+type LessonDemand struct {
+	ClassId          uuid.UUID
+	SubjectId        uuid.UUID
+	TeacherProfileId uuid.UUID
+	Room             *string
+	Hours            int
+}
+
+type GeneratorInput struct {
+	UnitId       uuid.UUID
+	AcademicYear string
+	Semester     int
+	Days         []int
+	Periods      int
+	Rules        ConflictRules
+	Demands      []LessonDemand
+	// Fixed are lessons of classes that are not regenerated; their teachers and
+	// rooms stay booked.
+	Fixed []schemas.TimetableEntry
+}
+
+type UnsatisfiedType string
+
+const (
+	UnsatisfiedNoTeacher       UnsatisfiedType = "no_teacher"
+	UnsatisfiedClassOverloaded UnsatisfiedType = "class_overloaded"
+	UnsatisfiedUnplaced        UnsatisfiedType = "unplaced_hours"
+	UnsatisfiedUneven          UnsatisfiedType = "uneven_spread"
+	UnsatisfiedConflict        UnsatisfiedType = "conflict"
+)
+
+// Unsatisfied is a constraint a generation could not meet.
+type Unsatisfied struct {
+	Type             UnsatisfiedType `json:"type"`
+	ClassId          *uuid.UUID      `json:"class_id,omitempty"`
+	SubjectId        *uuid.UUID      `json:"subject_id,omitempty"`
+	TeacherProfileId *uuid.UUID      `json:"teacher_profile_id,omitempty"`
+	MissingHours     int             `json:"missing_hours,omitempty"`
+	Reason           string          `json:"reason"`
+}
+
+type GeneratorResult struct {
+	Entries     []schemas.TimetableEntry
+	Unsatisfied []Unsatisfied
+}
+
+type classSubjectDay struct {
+	class, subject uuid.UUID
+	day            int
+}
+
+type classDay struct {
+	class uuid.UUID
+	day   int
+}
+
+// generator holds the bookings made so far. Lessons are placed greedily: the
+// busiest teachers go first, and each lesson takes the free slot on the day
+// where its class has the fewest lessons of that subject, then the fewest
+// lessons overall, then the earliest period.
+type generator struct {
+	input GeneratorInput
+
+	classBusy   map[uuid.UUID]map[slot]bool
+	teacherBusy map[uuid.UUID]map[slot]bool
+	roomBusy    map[string]map[slot]bool
+
+	subjectPerDay map[classSubjectDay]int
+	lessonsPerDay map[classDay]int
+}
+
+// Generate lays out the demanded lessons without double-booking a teacher,
+// class or room, outside each teacher's unavailable periods and within the
+// consecutive period limit, reporting what could not be placed.
+func Generate(input GeneratorInput) GeneratorResult {
+	g := &generator{
+		input:         input,
+		classBusy:     map[uuid.UUID]map[slot]bool{},
+		teacherBusy:   map[uuid.UUID]map[slot]bool{},
+		roomBusy:      map[string]map[slot]bool{},
+		subjectPerDay: map[classSubjectDay]int{},
+		lessonsPerDay: map[classDay]int{},
+	}
+	for _, entry := range input.Fixed {
+		g.book(entry)
+	}
+
+	var result GeneratorResult
+	result.Unsatisfied = g.overloadedClasses()
+
+	for _, demand := range g.ordered() {
+		spreadCap := (demand.Hours + len(input.Days) - 1) / len(input.Days)
+		uneven, missing := false, 0
+
+		for lesson := 0; lesson < demand.Hours; lesson++ {
+			best, found, spread := g.bestSlot(demand, spreadCap)
+			if !found {
+				missing++
+				continue
+			}
+			if !spread {
+				uneven = true
+			}
+
+			entry := schemas.TimetableEntry{
+				Id:               uuid.New(),
+				UnitId:           input.UnitId,
+				ClassId:          demand.ClassId,
+				SubjectId:        demand.SubjectId,
+				TeacherProfileId: demand.TeacherProfileId,
+				Room:             demand.Room,
+				AcademicYear:     input.AcademicYear,
+				Semester:         input.Semester,
+				DayOfWeek:        best.day,
+				Period:           best.period,
+			}
+			g.book(entry)
+			result.Entries = append(result.Entries, entry)
+		}
+
+		if missing > 0 {
+			result.Unsatisfied = append(result.Unsatisfied, demandIssue(demand, UnsatisfiedUnplaced, missing,
+				fmt.Sprintf("no free period left where the class, the teacher and the room are all available for %d of %d hours", missing, demand.Hours)))
+		}
+		if uneven {
+			result.Unsatisfied = append(result.Unsatisfied, demandIssue(demand, UnsatisfiedUneven, 0,
+				fmt.Sprintf("the lessons could not be spread to at most %d per day", spreadCap)))
+		}
+	}
+
+	return result
+}
+
+func demandIssue(demand LessonDemand, kind UnsatisfiedType, missing int, reason string) Unsatisfied {
+	return Unsatisfied{
+		Type:             kind,
+		ClassId:          &demand.ClassId,
+		SubjectId:        &demand.SubjectId,
+		TeacherProfileId: &demand.TeacherProfileId,
+		MissingHours:     missing,
+		Reason:           reason,
+	}
+}
+
+// overloadedClasses reports classes asking for more hours than the week has periods.
+func (g *generator) overloadedClasses() []Unsatisfied {
+	hours := map[uuid.UUID]int{}
+	var order []uuid.UUID
+	for _, demand := range g.input.Demands {
+		if _, seen := hours[demand.ClassId]; !seen {
+			order = append(order, demand.ClassId)
+		}
+		hours[demand.ClassId] += demand.Hours
+	}
+
+	available := len(g.input.Days) * g.input.Periods
+	var issues []Unsatisfied
+	for _, classId := range order {
+		if hours[classId] > available {
+			classId := classId
+			issues = append(issues, Unsatisfied{
+				Type:         UnsatisfiedClassOverloaded,
+				ClassId:      &classId,
+				MissingHours: hours[classId] - available,
+				Reason:       fmt.Sprintf("the class needs %d hours a week but only has %d periods", hours[classId], available),
+			})
+		}
+	}
+	return issues
+}
+
+// ordered puts the demands of the busiest teachers first, as they have the
+// fewest options left once other lessons are placed.
+func (g *generator) ordered() []LessonDemand {
+	load := map[uuid.UUID]int{}
+	for _, demand := range g.input.Demands {
+		load[demand.TeacherProfileId] += demand.Hours
+	}
+
+	demands := append([]LessonDemand(nil), g.input.Demands...)
+	sort.SliceStable(demands, func(i, j int) bool {
+		a, b := demands[i], demands[j]
+		if load[a.TeacherProfileId] != load[b.TeacherProfileId] {
+			return load[a.TeacherProfileId] > load[b.TeacherProfileId]
+		}
+		if a.Hours != b.Hours {
+			return a.Hours > b.Hours
+		}
+		if a.ClassId != b.ClassId {
+			return a.ClassId.String() < b.ClassId.String()
+		}
+		return a.SubjectId.String() < b.SubjectId.String()
+	})
+	return demands
+}
+
+// bestSlot returns the preferred free slot for one more lesson of the demand.
+// spread is false when every free slot exceeds the per-day cap.
+func (g *generator) bestSlot(demand LessonDemand, spreadCap int) (best slot, found bool, spread bool) {
+	var bestScore slotScore
+
+	for _, day := range g.input.Days {
+		subjectToday := g.subjectPerDay[classSubjectDay{demand.ClassId, demand.SubjectId, day}]
+		lessonsToday := g.lessonsPerDay[classDay{demand.ClassId, day}]
+		overCap := 0
+		if subjectToday >= spreadCap {
+			overCap = 1
+		}
+
+		for period := 1; period <= g.input.Periods; period++ {
+			candidate := slot{day, period}
+			if !g.free(demand, candidate) {
+				continue
+			}
+
+			current := slotScore{overCap, subjectToday, lessonsToday, period}
+			if !found || current.less(bestScore) {
+				best, bestScore, found = candidate, current, true
+			}
+		}
+	}
+	return best, found, found && bestScore.overCap == 0
+}
+
+// slotScore ranks free slots, lower is better.
+type slotScore struct {
+	overCap, subjectToday, lessonsToday, period int
+}
+
+func (a slotScore) less(b slotScore) bool {
+	if a.overCap != b.overCap {
+		return a.overCap < b.overCap
+	}
+	if a.subjectToday != b.subjectToday {
+		return a.subjectToday < b.subjectToday
+	}
+	if a.lessonsToday != b.lessonsToday {
+		return a.lessonsToday < b.lessonsToday
+	}
+	return a.period < b.period
+}
+
+// free checks the hard constraints for placing the demand in a slot.
+func (g *generator) free(demand LessonDemand, candidate slot) bool {
+	if g.classBusy[demand.ClassId][candidate] || g.teacherBusy[demand.TeacherProfileId][candidate] {
+		return false
+	}
+	if demand.Room != nil && g.roomBusy[*demand.Room][candidate] {
+		return false
+	}
+	for _, blocked := range g.input.Rules.Unavailability {
+		if blocked.TeacherProfileId == demand.TeacherProfileId && blocked.Covers(candidate.day, candidate.period) {
+			return false
+		}
+	}
+	return g.input.Rules.MaxConsecutive == 0 || g.runLength(demand.TeacherProfileId, candidate) <= g.input.Rules.MaxConsecutive
+}
+
+// runLength is how many periods in a row the teacher would teach around the slot.
+func (g *generator) runLength(teacherId uuid.UUID, candidate slot) int {
+	busy := g.teacherBusy[teacherId]
+	breakAfter := g.input.Rules.BreakAfterPeriod
+
+	length := 1
+	for period := candidate.period - 1; period >= 1 && period != breakAfter && busy[slot{candidate.day, period}]; period-- {
+		length++
+	}
+	for period := candidate.period + 1; period-1 != breakAfter && busy[slot{candidate.day, period}]; period++ {
+		length++
+	}
+	return length
+}
+
+func (g *generator) book(entry schemas.TimetableEntry) {
+	booked := slot{entry.DayOfWeek, entry.Period}
+	mark(g.classBusy, entry.ClassId, booked)
+	mark(g.teacherBusy, entry.TeacherProfileId, booked)
+	if entry.Room != nil {
+		mark(g.roomBusy, *entry.Room, booked)
+	}
+	g.subjectPerDay[classSubjectDay{entry.ClassId, entry.SubjectId, entry.DayOfWeek}]++
+	g.lessonsPerDay[classDay{entry.ClassId, entry.DayOfWeek}]++
+}
+
+func mark[K comparable](busy map[K]map[slot]bool, key K, booked slot) {
+	if busy[key] == nil {
+		busy[key] = map[slot]bool{}
+	}
+	busy[key][booked] = true
+}
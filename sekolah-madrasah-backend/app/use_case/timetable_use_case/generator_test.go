@@ -0,0 +1,185 @@
+package timetable_use_case
+
+import (
+	"testing"
+
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func entryAt(classId, teacherId uuid.UUID, room *string, day, period int) schemas.TimetableEntry {
+	return schemas.TimetableEntry{
+		Id: uuid.New(), ClassId: classId, SubjectId: uuid.New(), TeacherProfileId: teacherId,
+		Room: room, DayOfWeek: day, Period: period,
+	}
+}
+
+func conflictTypes(conflicts []Conflict) []ConflictType {
+	types := make([]ConflictType, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		types = append(types, conflict.Type)
+	}
+	return types
+}
+
+func TestCheckConflicts_DoubleBookings(t *testing.T) {
+	classA, classB := uuid.New(), uuid.New()
+	teacher := uuid.New()
+	lab := "Lab IPA"
+
+	conflicts := CheckConflicts([]schemas.TimetableEntry{
+		entryAt(classA, teacher, &lab, 1, 1),
+		entryAt(classB, teacher, &lab, 1, 1),
+		entryAt(classA, uuid.New(), nil, 1, 1),
+		entryAt(classA, uuid.New(), nil, 1, 2),
+	}, ConflictRules{})
+
+	assert.ElementsMatch(t, []ConflictType{ConflictTeacherBooked, ConflictClassBooked, ConflictRoomBooked}, conflictTypes(conflicts))
+	for _, conflict := range conflicts {
+		assert.Equal(t, 1, conflict.DayOfWeek)
+		assert.Equal(t, 1, conflict.Period)
+		assert.Len(t, conflict.EntryIds, 2)
+	}
+}
+
+func TestCheckConflicts_Unavailability(t *testing.T) {
+	teacher := uuid.New()
+	blocked := entryAt(uuid.New(), teacher, nil, 3, 4)
+
+	conflicts := CheckConflicts([]schemas.TimetableEntry{
+		blocked,
+		entryAt(uuid.New(), teacher, nil, 3, 6),
+	}, ConflictRules{Unavailability: []schemas.TeacherUnavailability{
+		{TeacherProfileId: teacher, DayOfWeek: 3, PeriodFrom: 3, PeriodTo: 5},
+	}})
+
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, ConflictTeacherUnavailable, conflicts[0].Type)
+	assert.Equal(t, []uuid.UUID{blocked.Id}, conflicts[0].EntryIds)
+}
+
+func TestCheckConflicts_ConsecutiveRunsEndAtBreak(t *testing.T) {
+	teacher := uuid.New()
+	rules := ConflictRules{MaxConsecutive: 3, BreakAfterPeriod: 3}
+
+	var acrossBreak []schemas.TimetableEntry
+	for period := 1; period <= 6; period++ {
+		acrossBreak = append(acrossBreak, entryAt(uuid.New(), teacher, nil, 1, period))
+	}
+	assert.Empty(t, CheckConflicts(acrossBreak, rules), "the break splits periods 1-6 into two runs of 3")
+
+	tooLong := append(acrossBreak, entryAt(uuid.New(), teacher, nil, 1, 7))
+	conflicts := CheckConflicts(tooLong, rules)
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, ConflictTooManyConsecutive, conflicts[0].Type)
+	assert.Equal(t, 4, conflicts[0].Period)
+	assert.Len(t, conflicts[0].EntryIds, 4)
+}
+
+func generatorInput(demands ...LessonDemand) GeneratorInput {
+	return GeneratorInput{
+		UnitId:       uuid.New(),
+		AcademicYear: "2025/2026",
+		Semester:     1,
+		Days:         []int{1, 2, 3, 4, 5},
+		Periods:      6,
+		Rules:        ConflictRules{MaxConsecutive: 3, BreakAfterPeriod: 3},
+		Demands:      demands,
+	}
+}
+
+func TestGenerate_PlacesWithoutConflicts(t *testing.T) {
+	classA, classB := uuid.New(), uuid.New()
+	math, science := uuid.New(), uuid.New()
+	busyTeacher, otherTeacher := uuid.New(), uuid.New()
+	lab := "Lab IPA"
+
+	input := generatorInput(
+		LessonDemand{ClassId: classA, SubjectId: math, TeacherProfileId: busyTeacher, Hours: 6},
+		LessonDemand{ClassId: classB, SubjectId: math, TeacherProfileId: busyTeacher, Hours: 6},
+		LessonDemand{ClassId: classA, SubjectId: science, TeacherProfileId: otherTeacher, Room: &lab, Hours: 4},
+		LessonDemand{ClassId: classB, SubjectId: science, TeacherProfileId: otherTeacher, Room: &lab, Hours: 4},
+	)
+	input.Rules.Unavailability = []schemas.TeacherUnavailability{
+		{TeacherProfileId: busyTeacher, DayOfWeek: 5, PeriodFrom: 1, PeriodTo: 6},
+	}
+
+	result := Generate(input)
+
+	assert.Empty(t, result.Unsatisfied)
+	assert.Len(t, result.Entries, 20)
+	assert.Empty(t, CheckConflicts(result.Entries, input.Rules))
+	for _, entry := range result.Entries {
+		assert.Equal(t, "2025/2026", entry.AcademicYear)
+		if entry.TeacherProfileId == busyTeacher {
+			assert.NotEqual(t, 5, entry.DayOfWeek, "the teacher is unavailable on Fridays")
+		}
+	}
+}
+
+func TestGenerate_SpreadsSubjectsOverTheWeek(t *testing.T) {
+	classId, subjectId := uuid.New(), uuid.New()
+	result := Generate(generatorInput(
+		LessonDemand{ClassId: classId, SubjectId: subjectId, TeacherProfileId: uuid.New(), Hours: 7},
+	))
+
+	perDay := map[int]int{}
+	for _, entry := range result.Entries {
+		perDay[entry.DayOfWeek]++
+	}
+	assert.Empty(t, result.Unsatisfied)
+	assert.Len(t, perDay, 5)
+	for day, lessons := range perDay {
+		assert.LessOrEqual(t, lessons, 2, "day %d", day)
+	}
+}
+
+func TestGenerate_RespectsFixedLessons(t *testing.T) {
+	teacher := uuid.New()
+	input := generatorInput(LessonDemand{ClassId: uuid.New(), SubjectId: uuid.New(), TeacherProfileId: teacher, Hours: 5})
+	input.Days = []int{1}
+	input.Fixed = []schemas.TimetableEntry{entryAt(uuid.New(), teacher, nil, 1, 1)}
+
+	result := Generate(input)
+
+	for _, entry := range result.Entries {
+		assert.NotEqual(t, 1, entry.Period, "period 1 is taken by a lesson that is kept")
+	}
+	assert.Empty(t, CheckConflicts(append(result.Entries, input.Fixed...), input.Rules))
+}
+
+func TestGenerate_ReportsWhatItCannotPlace(t *testing.T) {
+	classId := uuid.New()
+	input := generatorInput(
+		LessonDemand{ClassId: classId, SubjectId: uuid.New(), TeacherProfileId: uuid.New(), Hours: 20},
+		LessonDemand{ClassId: classId, SubjectId: uuid.New(), TeacherProfileId: uuid.New(), Hours: 12},
+	)
+
+	result := Generate(input)
+
+	missing := map[UnsatisfiedType]int{}
+	for _, issue := range result.Unsatisfied {
+		missing[issue.Type] += issue.MissingHours
+	}
+	assert.Equal(t, 2, missing[UnsatisfiedClassOverloaded], "32 hours do not fit in 30 periods")
+	assert.Equal(t, 2, missing[UnsatisfiedUnplaced])
+	assert.Len(t, result.Entries, 30)
+	assert.Empty(t, CheckConflicts(result.Entries, input.Rules))
+}
+
+func TestGenerate_ReportsUnevenSpread(t *testing.T) {
+	teacher := uuid.New()
+	input := generatorInput(LessonDemand{ClassId: uuid.New(), SubjectId: uuid.New(), TeacherProfileId: teacher, Hours: 4})
+	input.Days = []int{1, 2}
+	input.Rules.Unavailability = []schemas.TeacherUnavailability{
+		{TeacherProfileId: teacher, DayOfWeek: 2, PeriodFrom: 1, PeriodTo: 6},
+	}
+
+	result := Generate(input)
+
+	assert.Len(t, result.Entries, 4)
+	assert.Len(t, result.Unsatisfied, 1)
+	assert.Equal(t, UnsatisfiedUneven, result.Unsatisfied[0].Type)
+}
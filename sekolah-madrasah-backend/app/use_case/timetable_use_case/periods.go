@@ -0,0 +1,63 @@
+package timetable_use_case
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+)
+
+const clockFormat = "15:04"
+
+// PeriodTime is when one period (jam pelajaran) of the school day starts and ends.
+type PeriodTime struct {
+	Period    int    `json:"period"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+}
+
+// defaultSettings mirrors the defaults the unit settings endpoint creates, for
+// units whose settings were never saved.
+func defaultSettings(unitId uuid.UUID) *schemas.UnitSettings {
+	return &schemas.UnitSettings{
+		UnitId:           unitId,
+		PeriodDuration:   40,
+		StartTime:        "07:00",
+		TotalPeriods:     9,
+		BreakAfterPeriod: 3,
+		BreakDuration:    15,
+		CurrentSemester:  1,
+	}
+}
+
+// periodTimes lays out the school day: periods follow each other from StartTime,
+// with the break inserted after period BreakAfterPeriod.
+func periodTimes(settings *schemas.UnitSettings) ([]PeriodTime, error) {
+	start, err := time.Parse(clockFormat, settings.StartTime)
+	if err != nil {
+		return nil, fmt.Errorf("unit settings have an invalid start time %q", settings.StartTime)
+	}
+	if settings.PeriodDuration <= 0 || settings.TotalPeriods <= 0 {
+		return nil, errors.New("unit settings need a period duration and a number of periods")
+	}
+
+	periods := make([]PeriodTime, 0, settings.TotalPeriods)
+	current := start
+	for period := 1; period <= settings.TotalPeriods; period++ {
+		end := current.Add(time.Duration(settings.PeriodDuration) * time.Minute)
+		periods = append(periods, PeriodTime{
+			Period:    period,
+			StartTime: current.Format(clockFormat),
+			EndTime:   end.Format(clockFormat),
+		})
+
+		current = end
+		if period == settings.BreakAfterPeriod {
+			current = current.Add(time.Duration(settings.BreakDuration) * time.Minute)
+		}
+	}
+	return periods, nil
+}
@@ -0,0 +1,386 @@
+package timetable_use_case
+
+import (
+	"errors"
+	"strings"
+
+	"sekolah-madrasah/app/repository/timetable_repository"
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrEntryNotFound      = errors.New("timetable entry not found")
+	ErrGenerationNotFound = errors.New("timetable generation not found")
+)
+
+type TimetableUseCase interface {
+	Create(req *CreateEntryRequest) (*EntryView, error)
+	GetById(id uuid.UUID) (*EntryView, error)
+	// GetTimetable lists a semester's lessons; the by-class, by-teacher and
+	// by-room views are this list narrowed down by the query.
+	GetTimetable(query *TimetableQuery) (*TimetableView, error)
+	Update(id uuid.UUID, req *UpdateEntryRequest) (*EntryView, error)
+	Delete(id uuid.UUID) error
+	GetPeriods(unitId uuid.UUID) ([]PeriodTime, error)
+	// GetConflicts checks a semester's timetable with the rules the editor enforces.
+	GetConflicts(query *TimetableQuery) ([]Conflict, error)
+
+	// StartGeneration queues a generator run and returns at once; the result is
+	// polled with GetGeneration and takes effect with ApplyGeneration.
+	StartGeneration(req *GenerationRequest) (*GenerationView, error)
+	GetGeneration(id uuid.UUID) (*GenerationView, error)
+	ApplyGeneration(id uuid.UUID) (*GenerationView, error)
+	// FailInterruptedGenerations is run at startup for the jobs a server that
+	// stopped did not get to finish.
+	FailInterruptedGenerations() error
+
+	GetClassSubjects(unitId, classId uuid.UUID) ([]schemas.ClassSubject, error)
+	SetClassSubjects(unitId, classId uuid.UUID, subjects []ClassSubjectRequest) ([]schemas.ClassSubject, error)
+	GetUnavailability(unitId, teacherProfileId uuid.UUID) ([]schemas.TeacherUnavailability, error)
+	SetUnavailability(unitId, teacherProfileId uuid.UUID, periods []UnavailabilityRequest) ([]schemas.TeacherUnavailability, error)
+}
+
+type CreateEntryRequest struct {
+	UnitId           uuid.UUID
+	ClassId          uuid.UUID
+	SubjectId        uuid.UUID
+	TeacherProfileId uuid.UUID
+	Room             *string
+	Semester         int // Defaults to the unit's current semester
+	DayOfWeek        int
+	Period           int
+}
+
+type UpdateEntryRequest struct {
+	SubjectId        *uuid.UUID
+	TeacherProfileId *uuid.UUID
+	Room             *string
+	DayOfWeek        *int
+	Period           *int
+}
+
+// TimetableQuery selects a semester of a unit's timetable. AcademicYear and
+// Semester default to the unit's current ones.
+type TimetableQuery struct {
+	UnitId           uuid.UUID
+	AcademicYear     string
+	Semester         int
+	ClassId          *uuid.UUID
+	TeacherProfileId *uuid.UUID
+	Room             *string
+	DayOfWeek        *int
+}
+
+// EntryView is a timetable entry with the clock times of its period.
+type EntryView struct {
+	schemas.TimetableEntry
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+}
+
+type TimetableView struct {
+	AcademicYear string       `json:"academic_year"`
+	Semester     int          `json:"semester"`
+	Periods      []PeriodTime `json:"periods"`
+	Entries      []EntryView  `json:"entries"`
+}
+
+type timetableUseCase struct {
+	repo timetable_repository.TimetableRepository
+}
+
+func NewTimetableUseCase(repo timetable_repository.TimetableRepository) TimetableUseCase {
+	return &timetableUseCase{repo: repo}
+}
+
+func (uc *timetableUseCase) Create(req *CreateEntryRequest) (*EntryView, error) {
+	settings, err := uc.settings(req.UnitId)
+	if err != nil {
+		return nil, err
+	}
+
+	class, err := uc.repo.FindClass(req.ClassId)
+	if err != nil || class.UnitId != req.UnitId {
+		return nil, errors.New("class not found in this unit")
+	}
+
+	semester := req.Semester
+	if semester == 0 {
+		semester = currentSemester(settings)
+	}
+	if semester != 1 && semester != 2 {
+		return nil, errors.New("semester must be 1 or 2")
+	}
+
+	entry := &schemas.TimetableEntry{
+		UnitId:           req.UnitId,
+		ClassId:          class.Id,
+		SubjectId:        req.SubjectId,
+		TeacherProfileId: req.TeacherProfileId,
+		Room:             normalizeRoom(req.Room),
+		AcademicYear:     class.AcademicYear,
+		Semester:         semester,
+		DayOfWeek:        req.DayOfWeek,
+		Period:           req.Period,
+	}
+	// The id is set up front so the conflict check can tell the new lesson apart
+	entry.Id = uuid.New()
+	if err := uc.validate(entry, settings); err != nil {
+		return nil, err
+	}
+
+	if err := uc.repo.Create(entry); err != nil {
+		return nil, slotTaken(entry, err)
+	}
+	return uc.GetById(entry.Id)
+}
+
+func (uc *timetableUseCase) GetById(id uuid.UUID) (*EntryView, error) {
+	entry, err := uc.repo.FindById(id)
+	if err != nil {
+		return nil, ErrEntryNotFound
+	}
+
+	settings, err := uc.settings(entry.UnitId)
+	if err != nil {
+		return nil, err
+	}
+	periods, err := periodTimes(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	view := toEntryView(*entry, periods)
+	return &view, nil
+}
+
+func (uc *timetableUseCase) GetTimetable(query *TimetableQuery) (*TimetableView, error) {
+	settings, err := uc.settings(query.UnitId)
+	if err != nil {
+		return nil, err
+	}
+	periods, err := periodTimes(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	academicYear, semester, err := term(query.AcademicYear, query.Semester, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := uc.repo.Find(timetable_repository.TimetableFilter{
+		UnitId:           query.UnitId,
+		AcademicYear:     academicYear,
+		Semester:         semester,
+		ClassId:          query.ClassId,
+		TeacherProfileId: query.TeacherProfileId,
+		Room:             normalizeRoom(query.Room),
+		DayOfWeek:        query.DayOfWeek,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	view := &TimetableView{
+		AcademicYear: academicYear,
+		Semester:     semester,
+		Periods:      periods,
+		Entries:      make([]EntryView, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		view.Entries = append(view.Entries, toEntryView(entry, periods))
+	}
+	return view, nil
+}
+
+func (uc *timetableUseCase) Update(id uuid.UUID, req *UpdateEntryRequest) (*EntryView, error) {
+	entry, err := uc.repo.FindById(id)
+	if err != nil {
+		return nil, ErrEntryNotFound
+	}
+
+	settings, err := uc.settings(entry.UnitId)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.SubjectId != nil {
+		entry.SubjectId = *req.SubjectId
+	}
+	if req.TeacherProfileId != nil {
+		entry.TeacherProfileId = *req.TeacherProfileId
+	}
+	if req.Room != nil {
+		entry.Room = normalizeRoom(req.Room)
+	}
+	if req.DayOfWeek != nil {
+		entry.DayOfWeek = *req.DayOfWeek
+	}
+	if req.Period != nil {
+		entry.Period = *req.Period
+	}
+	if err := uc.validate(entry, settings); err != nil {
+		return nil, err
+	}
+
+	if err := uc.repo.Update(entry); err != nil {
+		return nil, slotTaken(entry, err)
+	}
+	return uc.GetById(entry.Id)
+}
+
+func (uc *timetableUseCase) Delete(id uuid.UUID) error {
+	if _, err := uc.repo.FindById(id); err != nil {
+		return ErrEntryNotFound
+	}
+	return uc.repo.Delete(id)
+}
+
+func (uc *timetableUseCase) GetConflicts(query *TimetableQuery) ([]Conflict, error) {
+	settings, err := uc.settings(query.UnitId)
+	if err != nil {
+		return nil, err
+	}
+	academicYear, semester, err := term(query.AcademicYear, query.Semester, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := uc.repo.Find(timetable_repository.TimetableFilter{
+		UnitId:       query.UnitId,
+		AcademicYear: academicYear,
+		Semester:     semester,
+	})
+	if err != nil {
+		return nil, err
+	}
+	unavailability, err := uc.repo.FindUnavailability(query.UnitId)
+	if err != nil {
+		return nil, err
+	}
+
+	conflicts := CheckConflicts(entries, rulesFor(settings, unavailability))
+	if conflicts == nil {
+		conflicts = []Conflict{}
+	}
+	return conflicts, nil
+}
+
+func (uc *timetableUseCase) GetPeriods(unitId uuid.UUID) ([]PeriodTime, error) {
+	settings, err := uc.settings(unitId)
+	if err != nil {
+		return nil, err
+	}
+	return periodTimes(settings)
+}
+
+// settings returns the unit's settings, or the defaults if they were never saved.
+func (uc *timetableUseCase) settings(unitId uuid.UUID) (*schemas.UnitSettings, error) {
+	settings, err := uc.repo.FindSettings(unitId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return defaultSettings(unitId), nil
+	}
+	return settings, err
+}
+
+// validate checks that the lesson fits the school day, that subject and teacher
+// belong to the entry's unit and go together, and that the semester's timetable
+// has no conflict involving it once the entry is in place.
+func (uc *timetableUseCase) validate(entry *schemas.TimetableEntry, settings *schemas.UnitSettings) error {
+	if entry.DayOfWeek < 1 || entry.DayOfWeek > 7 {
+		return errors.New("day_of_week must be between 1 (Monday) and 7 (Sunday)")
+	}
+	if entry.Period < 1 || entry.Period > settings.TotalPeriods {
+		return errors.New("period must be between 1 and the unit's total periods")
+	}
+
+	subject, err := uc.repo.FindSubject(entry.SubjectId)
+	if err != nil || subject.UnitId != entry.UnitId {
+		return errors.New("subject not found in this unit")
+	}
+	teacher, err := uc.repo.FindTeacher(entry.TeacherProfileId)
+	if err != nil || teacher.UnitId != entry.UnitId {
+		return errors.New("teacher not found in this unit")
+	}
+	assigned, err := uc.repo.IsTeacherAssigned(entry.TeacherProfileId, entry.SubjectId)
+	if err != nil {
+		return err
+	}
+	if !assigned {
+		return errors.New("teacher is not assigned to this subject")
+	}
+
+	entries, err := uc.repo.Find(timetable_repository.TimetableFilter{
+		UnitId:       entry.UnitId,
+		AcademicYear: entry.AcademicYear,
+		Semester:     entry.Semester,
+	})
+	if err != nil {
+		return err
+	}
+	unavailability, err := uc.repo.FindUnavailability(entry.UnitId)
+	if err != nil {
+		return err
+	}
+
+	timetable := make([]schemas.TimetableEntry, 0, len(entries)+1)
+	for _, existing := range entries {
+		if existing.Id != entry.Id {
+			timetable = append(timetable, existing)
+		}
+	}
+	timetable = append(timetable, *entry)
+
+	conflicts := involving(CheckConflicts(timetable, rulesFor(settings, unavailability)), entry.Id)
+	if len(conflicts) > 0 {
+		return &ConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
+
+// term fills in the unit's current academic year and semester where they are not given.
+func term(academicYear string, semester int, settings *schemas.UnitSettings) (string, int, error) {
+	if academicYear == "" {
+		academicYear = settings.AcademicYear
+	}
+	if academicYear == "" {
+		return "", 0, errors.New("academic_year is required until the unit's current academic year is set")
+	}
+	if semester == 0 {
+		semester = currentSemester(settings)
+	}
+	return academicYear, semester, nil
+}
+
+func currentSemester(settings *schemas.UnitSettings) int {
+	if settings.CurrentSemester == 0 {
+		return 1
+	}
+	return settings.CurrentSemester
+}
+
+func normalizeRoom(room *string) *string {
+	if room == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*room)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
+
+// toEntryView adds the period's clock times. Entries left beyond the last period
+// after the unit shortened its day keep empty times.
+func toEntryView(entry schemas.TimetableEntry, periods []PeriodTime) EntryView {
+	view := EntryView{TimetableEntry: entry}
+	if entry.Period >= 1 && entry.Period <= len(periods) {
+		view.StartTime = periods[entry.Period-1].StartTime
+		view.EndTime = periods[entry.Period-1].EndTime
+	}
+	return view
+}
@@ -0,0 +1,555 @@
+package timetable_use_case
+
+import (
+	"testing"
+	"time"
+
+	"sekolah-madrasah/app/repository/timetable_repository"
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockRepository is a mock implementation of TimetableRepository
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Create(entry *schemas.TimetableEntry) error {
+	args := m.Called(entry)
+	if entry.Id == uuid.Nil {
+		entry.Id = uuid.New()
+	}
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindById(id uuid.UUID) (*schemas.TimetableEntry, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.TimetableEntry), args.Error(1)
+}
+
+func (m *MockRepository) Find(filter timetable_repository.TimetableFilter) ([]schemas.TimetableEntry, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]schemas.TimetableEntry), args.Error(1)
+}
+
+func (m *MockRepository) Update(entry *schemas.TimetableEntry) error {
+	args := m.Called(entry)
+	return args.Error(0)
+}
+
+func (m *MockRepository) Delete(id uuid.UUID) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindSettings(unitId uuid.UUID) (*schemas.UnitSettings, error) {
+	args := m.Called(unitId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.UnitSettings), args.Error(1)
+}
+
+func (m *MockRepository) FindClass(id uuid.UUID) (*schemas.Class, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.Class), args.Error(1)
+}
+
+func (m *MockRepository) FindSubject(id uuid.UUID) (*schemas.Subject, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.Subject), args.Error(1)
+}
+
+func (m *MockRepository) FindTeacher(id uuid.UUID) (*schemas.TeacherProfile, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.TeacherProfile), args.Error(1)
+}
+
+func (m *MockRepository) IsTeacherAssigned(teacherProfileId, subjectId uuid.UUID) (bool, error) {
+	args := m.Called(teacherProfileId, subjectId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) FindUnavailability(unitId uuid.UUID) ([]schemas.TeacherUnavailability, error) {
+	args := m.Called(unitId)
+	return args.Get(0).([]schemas.TeacherUnavailability), args.Error(1)
+}
+
+func (m *MockRepository) FindTeacherUnavailability(teacherProfileId uuid.UUID) ([]schemas.TeacherUnavailability, error) {
+	args := m.Called(teacherProfileId)
+	return args.Get(0).([]schemas.TeacherUnavailability), args.Error(1)
+}
+
+func (m *MockRepository) ReplaceTeacherUnavailability(teacherProfileId uuid.UUID, periods []schemas.TeacherUnavailability) error {
+	args := m.Called(teacherProfileId, periods)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindClassSubjects(classIds []uuid.UUID) ([]schemas.ClassSubject, error) {
+	args := m.Called(classIds)
+	return args.Get(0).([]schemas.ClassSubject), args.Error(1)
+}
+
+func (m *MockRepository) ReplaceClassSubjects(classId uuid.UUID, subjects []schemas.ClassSubject) error {
+	args := m.Called(classId, subjects)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindActiveClasses(unitId uuid.UUID, academicYear string) ([]schemas.Class, error) {
+	args := m.Called(unitId, academicYear)
+	return args.Get(0).([]schemas.Class), args.Error(1)
+}
+
+func (m *MockRepository) FindSubjectTeachers(unitId uuid.UUID) ([]schemas.TeacherSubject, error) {
+	args := m.Called(unitId)
+	return args.Get(0).([]schemas.TeacherSubject), args.Error(1)
+}
+
+func (m *MockRepository) CreateGeneration(generation *schemas.TimetableGeneration) error {
+	args := m.Called(generation)
+	if generation.Id == uuid.Nil {
+		generation.Id = uuid.New()
+	}
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindGeneration(id uuid.UUID) (*schemas.TimetableGeneration, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schemas.TimetableGeneration), args.Error(1)
+}
+
+func (m *MockRepository) StartGeneration(id uuid.UUID, startedAt time.Time) error {
+	args := m.Called(id, startedAt)
+	return args.Error(0)
+}
+
+func (m *MockRepository) HeartbeatGeneration(id uuid.UUID) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FinishGeneration(generation *schemas.TimetableGeneration) error {
+	args := m.Called(generation)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FailStaleGenerations(message string, aliveAfter time.Time) (int64, error) {
+	args := m.Called(message, aliveAfter)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) ApplyGeneration(generation *schemas.TimetableGeneration, classIds []uuid.UUID, entries []schemas.TimetableEntry) error {
+	args := m.Called(generation, classIds, entries)
+	return args.Error(0)
+}
+
+type fixture struct {
+	repo    *MockRepository
+	uc      TimetableUseCase
+	unitId  uuid.UUID
+	class   *schemas.Class
+	subject *schemas.Subject
+	teacher *schemas.TeacherProfile
+}
+
+func newFixture() fixture {
+	unitId := uuid.New()
+	f := fixture{
+		repo:    new(MockRepository),
+		unitId:  unitId,
+		class:   &schemas.Class{Id: uuid.New(), UnitId: unitId, AcademicYear: "2025/2026"},
+		subject: &schemas.Subject{Id: uuid.New(), UnitId: unitId},
+		teacher: &schemas.TeacherProfile{Id: uuid.New(), UnitId: unitId},
+	}
+	f.uc = NewTimetableUseCase(f.repo)
+
+	f.repo.On("FindSettings", unitId).Return(&schemas.UnitSettings{
+		UnitId:           unitId,
+		PeriodDuration:   35,
+		StartTime:        "07:15",
+		TotalPeriods:     8,
+		BreakAfterPeriod: 4,
+		BreakDuration:    20,
+		AcademicYear:     "2025/2026",
+		CurrentSemester:  2,
+		MaxConsecutive:   3,
+	}, nil)
+	f.repo.On("FindClass", f.class.Id).Return(f.class, nil)
+	f.repo.On("FindSubject", f.subject.Id).Return(f.subject, nil)
+	f.repo.On("FindTeacher", f.teacher.Id).Return(f.teacher, nil)
+	f.repo.On("FindUnavailability", unitId).Return([]schemas.TeacherUnavailability{}, nil)
+	return f
+}
+
+// term is the filter the fixture's semester timetable is loaded with.
+func (f fixture) term() timetable_repository.TimetableFilter {
+	return timetable_repository.TimetableFilter{UnitId: f.unitId, AcademicYear: "2025/2026", Semester: 2}
+}
+
+// lesson is a stored lesson of the fixture's semester.
+func (f fixture) lesson(classId, teacherId uuid.UUID, day, period int) schemas.TimetableEntry {
+	return schemas.TimetableEntry{
+		Id: uuid.New(), UnitId: f.unitId, ClassId: classId, SubjectId: f.subject.Id, TeacherProfileId: teacherId,
+		AcademicYear: "2025/2026", Semester: 2, DayOfWeek: day, Period: period,
+	}
+}
+
+func (f fixture) request(day, period int) *CreateEntryRequest {
+	return &CreateEntryRequest{
+		UnitId:           f.unitId,
+		ClassId:          f.class.Id,
+		SubjectId:        f.subject.Id,
+		TeacherProfileId: f.teacher.Id,
+		DayOfWeek:        day,
+		Period:           period,
+	}
+}
+
+// Tests
+
+func TestPeriodTimes_InsertsBreak(t *testing.T) {
+	periods, err := periodTimes(&schemas.UnitSettings{
+		PeriodDuration:   40,
+		StartTime:        "07:00",
+		TotalPeriods:     5,
+		BreakAfterPeriod: 3,
+		BreakDuration:    15,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []PeriodTime{
+		{Period: 1, StartTime: "07:00", EndTime: "07:40"},
+		{Period: 2, StartTime: "07:40", EndTime: "08:20"},
+		{Period: 3, StartTime: "08:20", EndTime: "09:00"},
+		{Period: 4, StartTime: "09:15", EndTime: "09:55"},
+		{Period: 5, StartTime: "09:55", EndTime: "10:35"},
+	}, periods)
+}
+
+func TestPeriodTimes_InvalidSettings(t *testing.T) {
+	_, err := periodTimes(&schemas.UnitSettings{StartTime: "7 pagi", PeriodDuration: 40, TotalPeriods: 8})
+	assert.Error(t, err)
+
+	_, err = periodTimes(&schemas.UnitSettings{StartTime: "07:00", TotalPeriods: 8})
+	assert.Error(t, err)
+}
+
+func TestGetPeriods_DefaultsWithoutSettings(t *testing.T) {
+	repo := new(MockRepository)
+	uc := NewTimetableUseCase(repo)
+	unitId := uuid.New()
+	repo.On("FindSettings", unitId).Return(nil, gorm.ErrRecordNotFound)
+
+	periods, err := uc.GetPeriods(unitId)
+
+	assert.NoError(t, err)
+	assert.Len(t, periods, 9)
+	assert.Equal(t, PeriodTime{Period: 4, StartTime: "09:15", EndTime: "09:55"}, periods[3])
+}
+
+func TestCreate_Success(t *testing.T) {
+	f := newFixture()
+	f.repo.On("IsTeacherAssigned", f.teacher.Id, f.subject.Id).Return(true, nil)
+	f.repo.On("Find", f.term()).Return([]schemas.TimetableEntry{}, nil)
+	var created *schemas.TimetableEntry
+	f.repo.On("Create", mock.AnythingOfType("*schemas.TimetableEntry")).Return(nil).
+		Run(func(args mock.Arguments) { created = args.Get(0).(*schemas.TimetableEntry) })
+	f.repo.On("FindById", mock.AnythingOfType("uuid.UUID")).Return(&schemas.TimetableEntry{
+		UnitId: f.unitId, ClassId: f.class.Id, AcademicYear: "2025/2026", Semester: 2, DayOfWeek: 1, Period: 5,
+	}, nil)
+
+	room := "  Lab IPA "
+	req := f.request(1, 5)
+	req.Room = &room
+	view, err := f.uc.Create(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "09:55", view.StartTime)
+	assert.Equal(t, "10:30", view.EndTime)
+
+	assert.Equal(t, "2025/2026", created.AcademicYear, "academic year comes from the class")
+	assert.Equal(t, 2, created.Semester, "semester defaults to the unit's current semester")
+	assert.Equal(t, "Lab IPA", *created.Room)
+}
+
+func TestCreate_SlotTaken(t *testing.T) {
+	f := newFixture()
+	f.repo.On("IsTeacherAssigned", f.teacher.Id, f.subject.Id).Return(true, nil)
+	f.repo.On("Find", f.term()).Return([]schemas.TimetableEntry{f.lesson(f.class.Id, uuid.New(), 3, 2)}, nil)
+
+	_, err := f.uc.Create(f.request(3, 2))
+
+	assert.ErrorIs(t, err, ErrConflict)
+	var conflictErr *ConflictError
+	assert.ErrorAs(t, err, &conflictErr)
+	assert.Equal(t, ConflictClassBooked, conflictErr.Conflicts[0].Type)
+	f.repo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestCreate_SlotTakenConcurrently(t *testing.T) {
+	f := newFixture()
+	f.repo.On("IsTeacherAssigned", f.teacher.Id, f.subject.Id).Return(true, nil)
+	f.repo.On("Find", f.term()).Return([]schemas.TimetableEntry{}, nil)
+	// Another lesson was saved into the slot after the conflict check
+	f.repo.On("Create", mock.AnythingOfType("*schemas.TimetableEntry")).Return(gorm.ErrDuplicatedKey)
+
+	_, err := f.uc.Create(f.request(3, 2))
+
+	assert.ErrorIs(t, err, ErrConflict)
+	var conflictErr *ConflictError
+	assert.ErrorAs(t, err, &conflictErr)
+	assert.Equal(t, ConflictClassBooked, conflictErr.Conflicts[0].Type)
+}
+
+func TestCreate_TeacherConflicts(t *testing.T) {
+	f := newFixture()
+	f.repo.On("IsTeacherAssigned", f.teacher.Id, f.subject.Id).Return(true, nil)
+	otherClass := uuid.New()
+	f.repo.On("Find", f.term()).Return([]schemas.TimetableEntry{
+		f.lesson(otherClass, f.teacher.Id, 1, 1),
+		f.lesson(otherClass, f.teacher.Id, 1, 2),
+		f.lesson(otherClass, f.teacher.Id, 1, 3),
+		f.lesson(otherClass, f.teacher.Id, 2, 1),
+	}, nil)
+
+	_, err := f.uc.Create(f.request(2, 1))
+	var conflictErr *ConflictError
+	assert.ErrorAs(t, err, &conflictErr)
+	assert.Equal(t, ConflictTeacherBooked, conflictErr.Conflicts[0].Type)
+
+	_, err = f.uc.Create(f.request(1, 4))
+	assert.ErrorAs(t, err, &conflictErr)
+	assert.Equal(t, ConflictTooManyConsecutive, conflictErr.Conflicts[0].Type, "the limit is 3 periods in a row")
+
+	f.repo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestCreate_TeacherNotAssigned(t *testing.T) {
+	f := newFixture()
+	f.repo.On("IsTeacherAssigned", f.teacher.Id, f.subject.Id).Return(false, nil)
+
+	_, err := f.uc.Create(f.request(1, 1))
+
+	assert.EqualError(t, err, "teacher is not assigned to this subject")
+}
+
+func TestCreate_Refusals(t *testing.T) {
+	f := newFixture()
+	otherClass := &schemas.Class{Id: uuid.New(), UnitId: uuid.New()}
+	f.repo.On("FindClass", otherClass.Id).Return(otherClass, nil)
+
+	_, err := f.uc.Create(f.request(8, 1))
+	assert.Error(t, err, "day outside the week")
+
+	_, err = f.uc.Create(f.request(1, 9))
+	assert.Error(t, err, "period beyond the unit's school day")
+
+	req := f.request(1, 1)
+	req.Semester = 3
+	_, err = f.uc.Create(req)
+	assert.Error(t, err)
+
+	req = f.request(1, 1)
+	req.ClassId = otherClass.Id
+	_, err = f.uc.Create(req)
+	assert.EqualError(t, err, "class not found in this unit")
+
+	f.repo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestUpdate_KeepsOwnSlot(t *testing.T) {
+	f := newFixture()
+	entry := &schemas.TimetableEntry{
+		Id: uuid.New(), UnitId: f.unitId, ClassId: f.class.Id, SubjectId: f.subject.Id, TeacherProfileId: f.teacher.Id,
+		AcademicYear: "2025/2026", Semester: 2, DayOfWeek: 2, Period: 1,
+	}
+	f.repo.On("FindById", entry.Id).Return(entry, nil)
+	f.repo.On("IsTeacherAssigned", f.teacher.Id, f.subject.Id).Return(true, nil)
+	stored := *entry
+	f.repo.On("Find", f.term()).Return([]schemas.TimetableEntry{stored}, nil)
+	f.repo.On("Update", entry).Return(nil)
+
+	room := "R-101"
+	_, err := f.uc.Update(entry.Id, &UpdateEntryRequest{Room: &room})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "R-101", *entry.Room)
+	f.repo.AssertCalled(t, "Update", entry)
+}
+
+func TestGetTimetable_UsesCurrentTerm(t *testing.T) {
+	f := newFixture()
+	f.repo.On("Find", timetable_repository.TimetableFilter{
+		UnitId:           f.unitId,
+		AcademicYear:     "2025/2026",
+		Semester:         2,
+		TeacherProfileId: &f.teacher.Id,
+	}).Return([]schemas.TimetableEntry{
+		{DayOfWeek: 1, Period: 1},
+		{DayOfWeek: 1, Period: 12},
+	}, nil)
+
+	view, err := f.uc.GetTimetable(&TimetableQuery{UnitId: f.unitId, TeacherProfileId: &f.teacher.Id})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "2025/2026", view.AcademicYear)
+	assert.Equal(t, 2, view.Semester)
+	assert.Len(t, view.Periods, 8)
+	assert.Equal(t, "07:15", view.Entries[0].StartTime)
+	assert.Empty(t, view.Entries[1].StartTime, "periods beyond the school day have no times")
+}
+
+func TestDelete_NotFound(t *testing.T) {
+	repo := new(MockRepository)
+	uc := NewTimetableUseCase(repo)
+	id := uuid.New()
+	repo.On("FindById", id).Return(nil, gorm.ErrRecordNotFound)
+
+	err := uc.Delete(id)
+
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+	repo.AssertNotCalled(t, "Delete", id)
+}
+
+func TestRunGeneration_CompletesWithUnsatisfied(t *testing.T) {
+	f := newFixture()
+	unassigned := &schemas.Subject{Id: uuid.New(), UnitId: f.unitId}
+	otherClass := uuid.New()
+	kept := f.lesson(otherClass, f.teacher.Id, 1, 1)
+	generation := &schemas.TimetableGeneration{
+		Id: uuid.New(), UnitId: f.unitId, AcademicYear: "2025/2026", Semester: 2,
+		ClassIds: `["` + f.class.Id.String() + `"]`, Days: `[1,2,3,4,5]`,
+	}
+
+	f.repo.On("FindClassSubjects", []uuid.UUID{f.class.Id}).Return([]schemas.ClassSubject{
+		{Id: uuid.New(), ClassId: f.class.Id, SubjectId: f.subject.Id, HoursPerWeek: 4},
+		{Id: uuid.New(), ClassId: f.class.Id, SubjectId: unassigned.Id, HoursPerWeek: 2},
+	}, nil)
+	f.repo.On("FindSubjectTeachers", f.unitId).Return([]schemas.TeacherSubject{
+		{TeacherProfileId: f.teacher.Id, SubjectId: f.subject.Id, IsPrimary: true},
+	}, nil)
+	f.repo.On("Find", f.term()).Return([]schemas.TimetableEntry{kept, f.lesson(f.class.Id, f.teacher.Id, 2, 2)}, nil)
+	f.repo.On("StartGeneration", generation.Id, mock.Anything).Return(nil)
+	f.repo.On("FinishGeneration", generation).Return(nil)
+
+	f.uc.(*timetableUseCase).runGeneration(generation)
+
+	assert.Equal(t, schemas.TimetableGenerationCompleted, generation.Status)
+	view, err := toGenerationView(generation)
+	assert.NoError(t, err)
+	assert.Len(t, view.Entries, 4)
+	for _, entry := range view.Entries {
+		assert.Equal(t, f.teacher.Id, entry.TeacherProfileId)
+		assert.False(t, entry.DayOfWeek == 1 && entry.Period == 1, "the teacher teaches the other class then")
+	}
+	assert.Len(t, view.Unsatisfied, 1)
+	assert.Equal(t, UnsatisfiedNoTeacher, view.Unsatisfied[0].Type)
+	assert.Equal(t, unassigned.Id, *view.Unsatisfied[0].SubjectId)
+}
+
+func TestRunGeneration_RecordsFailure(t *testing.T) {
+	f := newFixture()
+	generation := &schemas.TimetableGeneration{Id: uuid.New(), UnitId: f.unitId, ClassIds: "not json", Days: "[]"}
+	f.repo.On("StartGeneration", generation.Id, mock.Anything).Return(nil)
+	f.repo.On("FinishGeneration", generation).Return(nil)
+
+	f.uc.(*timetableUseCase).runGeneration(generation)
+
+	assert.Equal(t, schemas.TimetableGenerationFailed, generation.Status)
+	assert.NotNil(t, generation.Error)
+	assert.NotNil(t, generation.FinishedAt)
+}
+
+func TestApplyGeneration_RechecksCurrentTimetable(t *testing.T) {
+	f := newFixture()
+	proposed := f.lesson(f.class.Id, f.teacher.Id, 1, 1)
+	generation := &schemas.TimetableGeneration{
+		Id: uuid.New(), UnitId: f.unitId, AcademicYear: "2025/2026", Semester: 2,
+		Status:   schemas.TimetableGenerationCompleted,
+		ClassIds: `["` + f.class.Id.String() + `"]`, Days: `[1]`,
+		Entries:     `[{"id":"` + proposed.Id.String() + `","unit_id":"` + f.unitId.String() + `","class_id":"` + f.class.Id.String() + `","subject_id":"` + f.subject.Id.String() + `","teacher_profile_id":"` + f.teacher.Id.String() + `","academic_year":"2025/2026","semester":2,"day_of_week":1,"period":1}]`,
+		Unsatisfied: `[]`,
+	}
+	f.repo.On("FindGeneration", generation.Id).Return(generation, nil)
+	// Since the generation ran, the teacher was booked in another class at the same time
+	f.repo.On("Find", f.term()).Return([]schemas.TimetableEntry{
+		f.lesson(f.class.Id, uuid.New(), 1, 1),
+		f.lesson(uuid.New(), f.teacher.Id, 1, 1),
+	}, nil)
+
+	_, err := f.uc.ApplyGeneration(generation.Id)
+
+	var conflictErr *ConflictError
+	assert.ErrorAs(t, err, &conflictErr)
+	assert.Equal(t, []ConflictType{ConflictTeacherBooked}, conflictTypes(conflictErr.Conflicts), "the regenerated class's own lessons are replaced")
+	f.repo.AssertNotCalled(t, "ApplyGeneration", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestApplyGeneration_AppliedMeanwhile(t *testing.T) {
+	f := newFixture()
+	generation := &schemas.TimetableGeneration{
+		Id: uuid.New(), UnitId: f.unitId, AcademicYear: "2025/2026", Semester: 2,
+		Status:   schemas.TimetableGenerationCompleted,
+		ClassIds: `["` + f.class.Id.String() + `"]`, Days: `[1]`, Entries: `[]`, Unsatisfied: `[]`,
+	}
+	f.repo.On("FindGeneration", generation.Id).Return(generation, nil)
+	f.repo.On("Find", f.term()).Return([]schemas.TimetableEntry{}, nil)
+	// Another request applied it between loading and saving
+	f.repo.On("ApplyGeneration", generation, mock.Anything, mock.Anything).Return(timetable_repository.ErrGenerationChanged)
+
+	_, err := f.uc.ApplyGeneration(generation.Id)
+
+	assert.ErrorIs(t, err, ErrConflict)
+}
+
+func TestRunGeneration_FailedMeanwhile(t *testing.T) {
+	f := newFixture()
+	generation := &schemas.TimetableGeneration{Id: uuid.New(), UnitId: f.unitId, Status: schemas.TimetableGenerationPending}
+	f.repo.On("StartGeneration", generation.Id, mock.Anything).Return(timetable_repository.ErrGenerationChanged)
+
+	f.uc.(*timetableUseCase).runGeneration(generation)
+
+	assert.Equal(t, schemas.TimetableGenerationPending, generation.Status)
+	f.repo.AssertNotCalled(t, "FinishGeneration", mock.Anything)
+}
+
+func TestFailInterruptedGenerations_OnlyStaleOnes(t *testing.T) {
+	f := newFixture()
+	f.repo.On("FailStaleGenerations", mock.Anything, mock.Anything).Return(int64(2), nil)
+
+	before := time.Now()
+	assert.NoError(t, f.uc.FailInterruptedGenerations())
+	after := time.Now()
+
+	aliveAfter := f.repo.Calls[len(f.repo.Calls)-1].Arguments.Get(1).(time.Time)
+	assert.WithinRange(t, aliveAfter, before.Add(-staleGenerationAfter), after.Add(-staleGenerationAfter))
+}
+
+func TestApplyGeneration_OnlyCompleted(t *testing.T) {
+	f := newFixture()
+	generation := &schemas.TimetableGeneration{Id: uuid.New(), UnitId: f.unitId, Status: schemas.TimetableGenerationRunning}
+	f.repo.On("FindGeneration", generation.Id).Return(generation, nil)
+
+	_, err := f.uc.ApplyGeneration(generation.Id)
+
+	assert.EqualError(t, err, "only a completed generation can be applied, this one is running")
+}
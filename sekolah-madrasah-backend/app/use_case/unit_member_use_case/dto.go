@@ -19,6 +19,11 @@ type UnitMember struct {
 	CreatedAt time.Time              `json:"created_at"`
 	UpdatedAt time.Time              `json:"updated_at"`
 
+	ApprovalStatus  schemas.UnitMemberApprovalStatus `json:"approval_status"`
+	ReviewedBy      *uuid.UUID                       `json:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time                       `json:"reviewed_at,omitempty"`
+	RejectionReason string                           `json:"rejection_reason,omitempty"`
+
 	User *UserInfo `json:"user,omitempty"`
 	Unit *UnitInfo `json:"unit,omitempty"`
 }
@@ -51,4 +56,6 @@ type UnitMemberFilter struct {
 	UserId   *uuid.UUID
 	Role     *schemas.UnitMemberRole
 	IsActive *bool
+
+	ApprovalStatus *schemas.UnitMemberApprovalStatus
 }
@@ -14,4 +14,9 @@ type UnitMemberUseCase interface {
 	AddMember(ctx context.Context, perumahanId uuid.UUID, req AddMemberRequest, invitedBy *uuid.UUID) (UnitMember, int, error)
 	UpdateMember(ctx context.Context, perumahanId, memberId uuid.UUID, req UpdateMemberRequest) (UnitMember, int, error)
 	RemoveMember(ctx context.Context, perumahanId, memberId uuid.UUID) (int, error)
+
+	// ApproveMember and RejectMember decide a pending sign-up. Only pending
+	// members can be reviewed, and a decision is final.
+	ApproveMember(ctx context.Context, unitId, memberId, reviewerId uuid.UUID) (UnitMember, int, error)
+	RejectMember(ctx context.Context, unitId, memberId, reviewerId uuid.UUID, reason string) (UnitMember, int, error)
 }
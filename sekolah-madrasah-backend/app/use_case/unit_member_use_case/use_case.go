@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"strings"
+	"time"
 
 	"sekolah-madrasah/app/repository/unit_member_repository"
 	"sekolah-madrasah/database/schemas"
@@ -31,6 +33,11 @@ func (u *perumahanMemberUseCase) toMember(m unit_member_repository.UnitMember) U
 		InvitedBy: m.InvitedBy,
 		CreatedAt: m.CreatedAt,
 		UpdatedAt: m.UpdatedAt,
+
+		ApprovalStatus:  m.ApprovalStatus,
+		ReviewedBy:      m.ReviewedBy,
+		ReviewedAt:      m.ReviewedAt,
+		RejectionReason: m.RejectionReason,
 	}
 
 	if m.User != nil {
@@ -70,6 +77,8 @@ func (u *perumahanMemberUseCase) GetMembers(ctx context.Context, perumahanId uui
 		UserId:   filter.UserId,
 		Role:     filter.Role,
 		IsActive: filter.IsActive,
+
+		ApprovalStatus: filter.ApprovalStatus,
 	}
 
 	members, code, err := u.memberRepo.GetMembers(ctx, repoFilter, paginate)
@@ -100,12 +109,18 @@ func (u *perumahanMemberUseCase) AddMember(ctx context.Context, perumahanId uuid
 		req.Role = schemas.UnitMemberRoleStaff
 	}
 
+	// Members added by an admin need no further review
+	now := time.Now()
 	newMember := unit_member_repository.UnitMember{
 		UserId:    req.UserId,
 		UnitId:  perumahanId,
 		Role:      req.Role,
 		IsActive:  true,
 		InvitedBy: invitedBy,
+
+		ApprovalStatus: schemas.UnitMemberApprovalApproved,
+		ReviewedBy:     invitedBy,
+		ReviewedAt:     &now,
 	}
 
 	createdMember, code, err := u.memberRepo.AddMember(ctx, newMember)
@@ -165,3 +180,55 @@ func (u *perumahanMemberUseCase) RemoveMember(ctx context.Context, perumahanId,
 
 	return u.memberRepo.RemoveMember(ctx, unit_member_repository.UnitMemberFilter{Id: &memberId})
 }
+
+func (u *perumahanMemberUseCase) ApproveMember(ctx context.Context, unitId, memberId, reviewerId uuid.UUID) (UnitMember, int, error) {
+	return u.reviewMember(ctx, unitId, memberId, unit_member_repository.MemberReview{
+		Status:     schemas.UnitMemberApprovalApproved,
+		ReviewedBy: reviewerId,
+	})
+}
+
+func (u *perumahanMemberUseCase) RejectMember(ctx context.Context, unitId, memberId, reviewerId uuid.UUID, reason string) (UnitMember, int, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return UnitMember{}, http.StatusBadRequest, errors.New("a reason is required to reject a sign-up")
+	}
+
+	return u.reviewMember(ctx, unitId, memberId, unit_member_repository.MemberReview{
+		Status:          schemas.UnitMemberApprovalRejected,
+		ReviewedBy:      reviewerId,
+		RejectionReason: reason,
+	})
+}
+
+func (u *perumahanMemberUseCase) reviewMember(ctx context.Context, unitId, memberId uuid.UUID, review unit_member_repository.MemberReview) (UnitMember, int, error) {
+	member, code, err := u.memberRepo.GetMember(ctx, unit_member_repository.UnitMemberFilter{
+		Id:     &memberId,
+		UnitId: &unitId,
+	})
+	if err != nil {
+		return UnitMember{}, code, err
+	}
+	if member.ApprovalStatus != schemas.UnitMemberApprovalPending {
+		return UnitMember{}, http.StatusConflict, errors.New("this sign-up has already been reviewed")
+	}
+	if member.UserId == review.ReviewedBy {
+		return UnitMember{}, http.StatusForbidden, errors.New("you cannot review your own sign-up")
+	}
+
+	code, err = u.memberRepo.ReviewMember(ctx, unit_member_repository.UnitMemberFilter{Id: &memberId}, review)
+	if err != nil {
+		if code == http.StatusNotFound {
+			// Another admin decided it between the read and the update
+			return UnitMember{}, http.StatusConflict, errors.New("this sign-up has already been reviewed")
+		}
+		return UnitMember{}, code, err
+	}
+
+	reviewed, code, err := u.memberRepo.GetMember(ctx, unit_member_repository.UnitMemberFilter{Id: &memberId})
+	if err != nil {
+		return UnitMember{}, code, err
+	}
+
+	return u.toMember(reviewed), http.StatusOK, nil
+}
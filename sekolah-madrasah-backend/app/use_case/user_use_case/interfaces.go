@@ -14,3 +14,8 @@ type UserUseCase interface {
 	UpdateUser(ctx context.Context, id uuid.UUID, req UpdateUserRequest) (User, int, error)
 	DeleteUser(ctx context.Context, id uuid.UUID) (int, error)
 }
+
+// EmailVerifier sends a verification link to a user whose email is unverified.
+type EmailVerifier interface {
+	SendEmailVerification(ctx context.Context, userId uuid.UUID) (int, error)
+}
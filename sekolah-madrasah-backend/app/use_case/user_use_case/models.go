@@ -7,17 +7,19 @@ import (
 )
 
 type User struct {
-	Id              uuid.UUID
-	Email           string
-	FullName        string
-	Phone           string
-	Avatar          string
-	IsSuperAdmin    bool
-	IsActive        bool
-	EmailVerifiedAt *time.Time
-	LastLoginAt     *time.Time
-	CreatedAt       time.Time
-	UpdatedAt       time.Time
+	Id                 uuid.UUID
+	Email              string
+	FullName           string
+	Phone              string
+	Avatar             string
+	IsSuperAdmin       bool
+	IsActive           bool
+	MustChangePassword bool
+	EmailVerifiedAt    *time.Time
+	LastLoginAt        *time.Time
+	LockedUntil        *time.Time
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
 }
 
 type CreateUserRequest struct {
@@ -25,6 +27,8 @@ type CreateUserRequest struct {
 	Password string
 	FullName string
 	Phone    string
+	// MustChangePassword forces the user to pick a new password on first login
+	MustChangePassword bool
 }
 
 type UpdateUserRequest struct {
@@ -9,30 +9,34 @@ import (
 	"sekolah-madrasah/pkg/paginate_utils"
 
 	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type userUseCase struct {
-	userRepo user_repository.UserRepository
+	userRepo      user_repository.UserRepository
+	emailVerifier EmailVerifier
 }
 
-func NewUserUseCase(userRepo user_repository.UserRepository) UserUseCase {
-	return &userUseCase{userRepo: userRepo}
+func NewUserUseCase(userRepo user_repository.UserRepository, emailVerifier EmailVerifier) UserUseCase {
+	return &userUseCase{userRepo: userRepo, emailVerifier: emailVerifier}
 }
 
 func (u *userUseCase) toUser(repoUser user_repository.User) User {
 	return User{
-		Id:              repoUser.Id,
-		Email:           repoUser.Email,
-		FullName:        repoUser.FullName,
-		Phone:           repoUser.Phone,
-		Avatar:          repoUser.Avatar,
-		IsSuperAdmin:    repoUser.IsSuperAdmin,
-		IsActive:        repoUser.IsActive,
-		EmailVerifiedAt: repoUser.EmailVerifiedAt,
-		LastLoginAt:     repoUser.LastLoginAt,
-		CreatedAt:       repoUser.CreatedAt,
-		UpdatedAt:       repoUser.UpdatedAt,
+		Id:                 repoUser.Id,
+		Email:              repoUser.Email,
+		FullName:           repoUser.FullName,
+		Phone:              repoUser.Phone,
+		Avatar:             repoUser.Avatar,
+		IsSuperAdmin:       repoUser.IsSuperAdmin,
+		IsActive:           repoUser.IsActive,
+		MustChangePassword: repoUser.MustChangePassword,
+		EmailVerifiedAt:    repoUser.EmailVerifiedAt,
+		LastLoginAt:        repoUser.LastLoginAt,
+		LockedUntil:        repoUser.LockedUntil,
+		CreatedAt:          repoUser.CreatedAt,
+		UpdatedAt:          repoUser.UpdatedAt,
 	}
 }
 
@@ -83,11 +87,12 @@ func (u *userUseCase) CreateUser(ctx context.Context, req CreateUserRequest) (Us
 	}
 
 	newUser := user_repository.User{
-		Email:    req.Email,
-		Password: string(hashedPassword),
-		FullName: req.FullName,
-		Phone:    req.Phone,
-		IsActive: true,
+		Email:              req.Email,
+		Password:           string(hashedPassword),
+		FullName:           req.FullName,
+		Phone:              req.Phone,
+		IsActive:           true,
+		MustChangePassword: req.MustChangePassword,
 	}
 
 	createdUser, code, err := u.userRepo.CreateUser(ctx, newUser)
@@ -106,7 +111,8 @@ func (u *userUseCase) UpdateUser(ctx context.Context, id uuid.UUID, req UpdateUs
 		return User{}, code, err
 	}
 
-	if req.Email != "" && req.Email != existingUser.Email {
+	emailChanged := req.Email != "" && req.Email != existingUser.Email
+	if emailChanged {
 		checkUser, checkCode, _ := u.userRepo.GetUser(ctx, user_repository.UserFilter{
 			Email: &req.Email,
 		})
@@ -116,17 +122,26 @@ func (u *userUseCase) UpdateUser(ctx context.Context, id uuid.UUID, req UpdateUs
 	}
 
 	updateData := user_repository.User{
-		Email:    req.Email,
 		FullName: req.FullName,
 		Phone:    req.Phone,
 		Avatar:   req.Avatar,
 	}
+	// Only pass the email on a real change, since that also clears the verification
+	if emailChanged {
+		updateData.Email = req.Email
+	}
 
 	code, err = u.userRepo.UpdateUser(ctx, user_repository.UserFilter{Id: &id}, updateData)
 	if err != nil {
 		return User{}, code, err
 	}
 
+	if emailChanged && u.emailVerifier != nil {
+		if _, err := u.emailVerifier.SendEmailVerification(ctx, id); err != nil {
+			log.Errorf("failed to send verification email to user %s: %v", id, err)
+		}
+	}
+
 	updatedUser, code, err := u.userRepo.GetUser(ctx, user_repository.UserFilter{Id: &id})
 	if err != nil {
 		return User{}, code, err
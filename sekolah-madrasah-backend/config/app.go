@@ -16,6 +16,19 @@ type Rest struct {
 	JWTSecret       string
 	XAuthCron       string
 	RestDebugMode   bool
+	// FrontendURL is the base of links sent to users, e.g. password reset links
+	FrontendURL string
+
+	// JWTKeysDir holds <kid>.pem keys; private RSA or Ed25519 keys can sign, public
+	// keys only verify tokens issued before a rotation
+	JWTKeysDir string
+	// JWTSigningKeyId picks the key in JWTKeysDir that signs new tokens; empty keeps HS256 with JWTSecret
+	JWTSigningKeyId string
+	// JWTAcceptHS256 keeps tokens signed with JWTSecret valid after switching to JWTSigningKeyId
+	JWTAcceptHS256 bool
+	// JWTIssuer and JWTAudience are put in the iss and aud claims and required on every token
+	JWTIssuer   string
+	JWTAudience string
 }
 
 func (r *Rest) GetOrigin() []string {
@@ -84,9 +97,43 @@ type AppConfig struct {
 	// Bucket        BucketConfig
 	Elasticsearch ElasticsearchConfig
 	Security      Security
+	Mail          MailConfig
+	RateLimit     RateLimitConfig
+}
+
+type RateLimitConfig struct {
+	// Store is "memory" for a per-process limiter or "postgres" to share counts between replicas
+	Store string
+	// DefaultPerMinute applies to each /api/v1 route group without an entry in Groups; 0 disables it
+	DefaultPerMinute int
+	// Groups overrides DefaultPerMinute per route group, e.g. {"auth": 60}
+	Groups map[string]int
+}
+
+type MailConfig struct {
+	// Driver is "smtp" to deliver mail or "file" to write messages to FileDir
+	Driver   string
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	FileDir  string
 }
 type Security struct {
 	AesKey string
+	// RequireSuperAdminTwoFactor makes super admins enroll in TOTP before using the API
+	RequireSuperAdminTwoFactor bool
+	// TwoFactorIssuer is the account label shown in authenticator apps
+	TwoFactorIssuer string
+	// LoginMaxAttempts wrong passwords lock an account for LoginLockoutMinutes; 0 disables lockout
+	LoginMaxAttempts    int
+	LoginLockoutMinutes int
+	// LoginDelayAfter failures start progressively longer waits between attempts; 0 disables them
+	LoginDelayAfter int
+	// LoginMaxAttemptsPerIP failures within LoginAttemptWindowMinutes block an IP address; 0 disables it
+	LoginMaxAttemptsPerIP     int
+	LoginAttemptWindowMinutes int
 }
 
 var APP AppConfig
@@ -3,6 +3,9 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+
+	"github.com/labstack/gommon/log"
 )
 
 // Init initializes application configuration
@@ -52,3 +55,25 @@ func getEnvAsFloat(key string, defaultValue float64) float64 {
 	}
 	return defaultValue
 }
+
+// getEnvAsIntMap parses "name=value" pairs separated by commas, e.g. "auth=60,users=300"
+func getEnvAsIntMap(key string, defaultValue map[string]int) map[string]int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	result := make(map[string]int)
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, raw, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		intValue, err := strconv.Atoi(strings.TrimSpace(raw))
+		if !ok || err != nil {
+			log.Fatalf("invalid %s entry %q, expected name=number", key, pair)
+		}
+		result[strings.TrimSpace(name)] = intValue
+	}
+	return result
+}
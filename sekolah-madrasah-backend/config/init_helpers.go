@@ -29,9 +29,15 @@ func newAppConfig() *AppConfig {
 			Origin:          getEnv("REST_CORS_ORIGIN", "*"),
 			RestDebugMode:   getEnvAsBool("REST_DEBUG_MODE", true),
 			JWTSecret:       getEnv("REST_SECRET", ""),
+			JWTKeysDir:      getEnv("JWT_KEYS_DIR", ""),
+			JWTSigningKeyId: getEnv("JWT_SIGNING_KEY_ID", ""),
+			JWTAcceptHS256:  getEnvAsBool("JWT_ACCEPT_HS256", false),
+			JWTIssuer:       getEnv("JWT_ISSUER", "sekolah-madrasah"),
+			JWTAudience:     getEnv("JWT_AUDIENCE", "sekolah-madrasah"),
 			XAuthCron:       getEnv("X_AUTH_CRON", ""),
 			SwaggerUser:     getEnv("SWAGGER_USER", ""),
 			SwaggerPassword: getEnv("SWAGGER_PASSWORD", ""),
+			FrontendURL:     getEnv("FRONTEND_URL", "http://localhost:3000"),
 		},
 		MainDB: DBConfig{
 			Id:       MainDB,
@@ -75,7 +81,28 @@ func newAppConfig() *AppConfig {
 			CertPath:    getEnv("ELASTIC_CERT_PATH", ""),
 		},
 		Security: Security{
-			AesKey: getEnv("AES_KEY", "default-aes-key-32-chars-long!!"),
+			AesKey:                     getEnv("AES_KEY", "default-aes-key-32-chars-long!!"),
+			RequireSuperAdminTwoFactor: getEnvAsBool("SUPER_ADMIN_REQUIRE_2FA", false),
+			TwoFactorIssuer:            getEnv("TWO_FACTOR_ISSUER", "Sekolah Madrasah"),
+			LoginMaxAttempts:           getEnvAsInt("LOGIN_MAX_ATTEMPTS", 5),
+			LoginLockoutMinutes:        getEnvAsInt("LOGIN_LOCKOUT_MINUTES", 15),
+			LoginDelayAfter:            getEnvAsInt("LOGIN_DELAY_AFTER", 3),
+			LoginMaxAttemptsPerIP:      getEnvAsInt("LOGIN_MAX_ATTEMPTS_PER_IP", 20),
+			LoginAttemptWindowMinutes:  getEnvAsInt("LOGIN_ATTEMPT_WINDOW_MINUTES", 15),
+		},
+		Mail: MailConfig{
+			Driver:   getEnv("MAIL_DRIVER", "file"),
+			Host:     getEnv("SMTP_HOST", ""),
+			Port:     getEnvAsInt("SMTP_PORT", 587),
+			Username: getEnv("SMTP_USERNAME", ""),
+			Password: getEnv("SMTP_PASSWORD", ""),
+			From:     getEnv("MAIL_FROM", "no-reply@localhost"),
+			FileDir:  getEnv("MAIL_FILE_DIR", "storage/mail"),
+		},
+		RateLimit: RateLimitConfig{
+			Store:            getEnv("RATE_LIMIT_STORE", "memory"),
+			DefaultPerMinute: getEnvAsInt("RATE_LIMIT_DEFAULT_PER_MINUTE", 600),
+			Groups:           getEnvAsIntMap("RATE_LIMIT_GROUPS", map[string]int{"auth": 60}),
 		},
 	}
 }
@@ -89,6 +116,33 @@ func validateOperationalConfig(app *AppConfig) {
 		log.Info("Elasticsearch disabled, skipping validation")
 	}
 	validateAPMConfig(app)
+	validateMailConfig(app)
+	validateRateLimitConfig(app)
+}
+
+func validateMailConfig(app *AppConfig) {
+	switch app.Mail.Driver {
+	case "smtp":
+		if app.Mail.Host == "" {
+			log.Fatal("MAIL_DRIVER is smtp but SMTP_HOST is not set")
+		}
+		log.Infof("✅ Mail delivery via SMTP (%s:%d)", app.Mail.Host, app.Mail.Port)
+	case "file":
+		log.Warnf("⚠️  Mail is written to %s instead of being delivered", app.Mail.FileDir)
+	default:
+		log.Fatalf("unknown MAIL_DRIVER %q, use smtp or file", app.Mail.Driver)
+	}
+}
+
+func validateRateLimitConfig(app *AppConfig) {
+	switch app.RateLimit.Store {
+	case "postgres":
+		log.Info("✅ Rate limits shared through PostgreSQL")
+	case "memory":
+		log.Warn("⚠️  Rate limits are kept in memory and are not shared between replicas")
+	default:
+		log.Fatalf("unknown RATE_LIMIT_STORE %q, use memory or postgres", app.RateLimit.Store)
+	}
 }
 
 // func validateBrokerConfig(app *AppConfig) {
@@ -190,8 +244,13 @@ func validateAPMConfig(app *AppConfig) {
 }
 
 func validateRestAndSecurity(app *AppConfig) {
-	if app.Rest.JWTSecret == "" {
-		log.Fatal("you neet to set REST_SECRET to start rest server")
+	if app.Rest.JWTSigningKeyId == "" || app.Rest.JWTAcceptHS256 {
+		if app.Rest.JWTSecret == "" {
+			log.Fatal("you neet to set REST_SECRET to start rest server")
+		}
+	}
+	if app.Rest.JWTSigningKeyId != "" && app.Rest.JWTKeysDir == "" {
+		log.Fatal("JWT_SIGNING_KEY_ID is set but JWT_KEYS_DIR is not")
 	}
 	if app.Security.AesKey == "" {
 		log.Fatal("you need to set AES_KEY env")
@@ -25,6 +25,8 @@ func NewDBConnection(cfg config.DBConfig) (*gorm.DB, error) {
 	}
 	gcfg := &gorm.Config{
 		Logger: apmLogger,
+		// Unique violations surface as gorm.ErrDuplicatedKey
+		TranslateError: true,
 	}
 
 	db, err := openDatabase(cfg, gcfg)
@@ -83,6 +85,7 @@ func handleMigration(db *gorm.DB, cfg config.DBConfig) {
 	case config.MainDB:
 		{
 			log.Info("Main Database is migrating")
+			dropReplacedIndexes(db)
 			if err := db.AutoMigrate(
 				// Core modules
 				&schemas.User{},
@@ -91,6 +94,20 @@ func handleMigration(db *gorm.DB, cfg config.DBConfig) {
 				&schemas.Permission{},
 				&schemas.RolePermission{},
 				&schemas.OrganizationMember{},
+				// Auth sessions
+				&schemas.RefreshToken{},
+				&schemas.RevokedAccessToken{},
+				&schemas.UserSession{},
+				&schemas.PasswordResetToken{},
+				&schemas.UserTwoFactor{},
+				&schemas.TwoFactorRecoveryCode{},
+				&schemas.LoginAttempt{},
+				&schemas.AuditLog{},
+				&schemas.RateLimitCounter{},
+				&schemas.ApiKey{},
+				&schemas.SsoProvider{},
+				&schemas.SsoLoginState{},
+				&schemas.UserIdentity{},
 				// Unit management
 				&schemas.Unit{},
 				&schemas.UnitMember{},
@@ -104,6 +121,27 @@ func handleMigration(db *gorm.DB, cfg config.DBConfig) {
 				// Subjects
 				&schemas.Subject{},
 				&schemas.TeacherSubject{},
+				// Timetable
+				&schemas.TimetableEntry{},
+				&schemas.ClassSubject{},
+				&schemas.TeacherUnavailability{},
+				&schemas.TimetableGeneration{},
+				// Attendance
+				&schemas.DailyAttendance{},
+				&schemas.LessonJournal{},
+				&schemas.LessonAttendance{},
+				&schemas.StaffAttendance{},
+				// Grades
+				&schemas.Assessment{},
+				&schemas.AssessmentScore{},
+				&schemas.GradeThreshold{},
+				// Report Cards
+				&schemas.ReportCard{},
+				&schemas.ReportCardSubject{},
+				&schemas.ReportCardActivity{},
+				&schemas.ReportCardTemplate{},
+				// Graduation / Alumni
+				&schemas.Graduation{},
 				// Activities
 				&schemas.Activity{},
 				&schemas.ActivityTeacher{},
@@ -116,6 +154,11 @@ func handleMigration(db *gorm.DB, cfg config.DBConfig) {
 			); err != nil {
 				log.Error("migrate error : ", err)
 			}
+			for _, statement := range expressionIndexes {
+				if err := db.Exec(statement).Error; err != nil {
+					log.Error("create index error : ", err)
+				}
+			}
 			log.Info("Main Database migrate successfully")
 
 			runSeeders(db)
@@ -124,10 +167,45 @@ func handleMigration(db *gorm.DB, cfg config.DBConfig) {
 	}
 }
 
+// replacedIndexes maps indexes whose definition changed to a fragment only the
+// current definition has. Migrations keep an existing index of the same name,
+// so an older one is dropped for it to be recreated.
+var replacedIndexes = map[string]string{
+	"idx_timetable_class_slot":  "WHERE",
+	"idx_grade_threshold_scope": "COALESCE",
+}
+
+// expressionIndexes cannot be declared in struct tags and are created after AutoMigrate.
+var expressionIndexes = []string{
+	// NULLs never collide in a unique index, so a rule for every subject or
+	// every level is compared as the nil UUID or level 0
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_grade_threshold_scope ON grade_thresholds
+		(unit_id, COALESCE(subject_id, '00000000-0000-0000-0000-000000000000'::uuid), COALESCE(level, 0))`,
+}
+
+func dropReplacedIndexes(db *gorm.DB) {
+	for name, fragment := range replacedIndexes {
+		var count int64
+		err := db.Raw("SELECT COUNT(*) FROM pg_indexes WHERE indexname = ? AND indexdef NOT LIKE ?", name, "%"+fragment+"%").
+			Scan(&count).Error
+		if err != nil {
+			log.Error("index check error : ", err)
+			continue
+		}
+		if count == 0 {
+			continue
+		}
+		if err := db.Exec("DROP INDEX IF EXISTS " + name).Error; err != nil {
+			log.Error("drop index error : ", err)
+		}
+	}
+}
+
 func runSeeders(db *gorm.DB) {
 	log.Info("🌱 Running database seeders...")
 
 	permissions := getCorePermissions()
+	var created []string
 	for _, p := range permissions {
 		var existing schemas.Permission
 		if db.Where("name = ?", p.name).First(&existing).Error != nil {
@@ -138,6 +216,7 @@ func runSeeders(db *gorm.DB) {
 				Description: p.description,
 			}
 			if err := db.Create(&permission).Error; err == nil {
+				created = append(created, p.name)
 				log.Infof("✅ Created permission: %s", p.name)
 			}
 		}
@@ -145,32 +224,47 @@ func runSeeders(db *gorm.DB) {
 
 	roles := getDefaultRoles()
 	for _, r := range roles {
-		var existing schemas.Role
-		if db.Where("name = ?", r.name).First(&existing).Error != nil {
-			role := schemas.Role{
+		var role schemas.Role
+		newRole := false
+		if db.Where("name = ?", r.name).First(&role).Error != nil {
+			role = schemas.Role{
 				Name:        r.name,
 				Description: r.description,
 			}
-			if err := db.Create(&role).Error; err == nil {
-				log.Infof("✅ Created role: %s", r.name)
-
-				if r.allPermissions {
-					var allPerms []schemas.Permission
-					db.Find(&allPerms)
-					for _, perm := range allPerms {
-						db.Create(&schemas.RolePermission{RoleId: role.Id, PermissionId: perm.Id})
-					}
-					log.Infof("   → Assigned ALL permissions to %s", r.name)
-				} else {
-					for _, permName := range r.permissions {
-						var perm schemas.Permission
-						if db.Where("name = ?", permName).First(&perm).Error == nil {
-							db.Create(&schemas.RolePermission{RoleId: role.Id, PermissionId: perm.Id})
-						}
-					}
-					log.Infof("   → Assigned %d permissions to %s", len(r.permissions), r.name)
-				}
+			if err := db.Create(&role).Error; err != nil {
+				continue
 			}
+			newRole = true
+			log.Infof("✅ Created role: %s", r.name)
+		}
+
+		// A new role gets all its defaults; a role seeded earlier picks up only
+		// the permissions created in this run, so permissions an operator removed
+		// from it stay removed
+		query := db.Model(&schemas.Permission{})
+		if !r.allPermissions {
+			query = query.Where("name IN ?", r.permissions)
+		}
+		if !newRole {
+			if len(created) == 0 {
+				continue
+			}
+			query = query.Where("name IN ?", created)
+		}
+		var perms []schemas.Permission
+		query.Find(&perms)
+		assigned := 0
+		for _, perm := range perms {
+			var existing schemas.RolePermission
+			if db.Where("role_id = ? AND permission_id = ?", role.Id, perm.Id).First(&existing).Error == nil {
+				continue
+			}
+			if db.Create(&schemas.RolePermission{RoleId: role.Id, PermissionId: perm.Id}).Error == nil {
+				assigned++
+			}
+		}
+		if assigned > 0 {
+			log.Infof("   → Assigned %d permissions to %s", assigned, r.name)
 		}
 	}
 
@@ -199,6 +293,18 @@ func getCorePermissions() []permissionData {
 		{"units", "Unit"},
 		{"unit_members", "Unit Member"},
 		{"posts", "Post"},
+		{"teachers", "Teacher"},
+		{"students", "Student"},
+		{"classes", "Class"},
+		{"class_enrollments", "Class Enrollment"},
+		{"subjects", "Subject"},
+		{"timetables", "Timetable"},
+		{"attendances", "Attendance"},
+		{"staff_attendances", "Staff Attendance"},
+		{"grades", "Grade"},
+		{"report_cards", "Report Card"},
+		{"activities", "Activity"},
+		{"api_keys", "API Key"},
 	}
 	actions := []struct{ action, description string }{
 		{"create", "Create"}, {"read", "Read"}, {"update", "Update"},
@@ -227,18 +333,34 @@ type roleData struct {
 
 func getDefaultRoles() []roleData {
 	return []roleData{
-		{"Super Admin", "Full system access", nil, true},
+		{schemas.SuperAdminRoleName, "Full system access", nil, true},
 		{"Admin", "Organization administrator", []string{
 			"organizations.create", "organizations.read", "organizations.update", "organizations.delete", "organizations.list",
 			"units.create", "units.read", "units.update", "units.delete", "units.list",
 			"unit_members.create", "unit_members.read", "unit_members.update", "unit_members.delete", "unit_members.list",
 			"users.read", "users.list", "roles.read", "roles.list", "permissions.read", "permissions.list",
 			"posts.create", "posts.read", "posts.update", "posts.delete", "posts.list",
+			"teachers.create", "teachers.read", "teachers.update", "teachers.delete", "teachers.list",
+			"students.create", "students.read", "students.update", "students.delete", "students.list",
+			"classes.create", "classes.read", "classes.update", "classes.delete", "classes.list",
+			"class_enrollments.create", "class_enrollments.read", "class_enrollments.update", "class_enrollments.delete", "class_enrollments.list",
+			"subjects.create", "subjects.read", "subjects.update", "subjects.delete", "subjects.list",
+			"timetables.create", "timetables.read", "timetables.update", "timetables.delete", "timetables.list",
+			"attendances.create", "attendances.read", "attendances.update", "attendances.delete", "attendances.list",
+			"staff_attendances.create", "staff_attendances.read", "staff_attendances.update", "staff_attendances.delete", "staff_attendances.list",
+			"grades.create", "grades.read", "grades.update", "grades.delete", "grades.list",
+			"report_cards.create", "report_cards.read", "report_cards.update", "report_cards.delete", "report_cards.list",
+			"activities.create", "activities.read", "activities.update", "activities.delete", "activities.list",
+			"api_keys.create", "api_keys.read", "api_keys.delete", "api_keys.list",
 		}, false},
 		{"Member", "Basic member with read access", []string{
 			"organizations.read",
 			"units.read", "units.list",
 			"posts.read", "posts.list",
+			"classes.read", "classes.list",
+			"subjects.read", "subjects.list",
+			"timetables.read", "timetables.list",
+			"activities.read", "activities.list",
 		}, false},
 	}
 }
@@ -0,0 +1,46 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ApiKey is an organization-scoped credential for machine integrations such as
+// attendance devices. Only the SHA-256 of the key is stored; Prefix is the
+// non-secret start of the key that identifies it in the UI. Scopes is a JSON
+// array of permission names.
+type ApiKey struct {
+	Id             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	OrganizationId uuid.UUID  `gorm:"type:uuid;not null;index" json:"organization_id"`
+	Name           string     `gorm:"type:varchar(100);not null" json:"name"`
+	Prefix         string     `gorm:"type:varchar(20);not null;uniqueIndex" json:"prefix"`
+	KeyHash        string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	Scopes         string     `gorm:"type:jsonb;not null;default:'[]'" json:"scopes"`
+	CreatedBy      uuid.UUID  `gorm:"type:uuid;not null" json:"created_by"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	LastUsedAt     *time.Time `json:"last_used_at"`
+	LastUsedIp     string     `gorm:"type:varchar(45)" json:"last_used_ip"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	Organization *Organization `gorm:"foreignKey:OrganizationId" json:"organization,omitempty"`
+}
+
+func (ApiKey) TableName() string { return "api_keys" }
+
+func (ak *ApiKey) BeforeCreate(tx *gorm.DB) (err error) {
+	if ak.Id == uuid.Nil {
+		ak.Id = uuid.New()
+	}
+	ak.CreatedAt = time.Now()
+	ak.UpdatedAt = time.Now()
+	return
+}
+
+func (ak *ApiKey) BeforeUpdate(tx *gorm.DB) (err error) {
+	ak.UpdatedAt = time.Now()
+	return
+}
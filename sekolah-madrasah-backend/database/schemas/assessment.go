@@ -0,0 +1,109 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AssessmentType is the kind of assessment (jenis penilaian)
+type AssessmentType string
+
+const (
+	AssessmentDaily      AssessmentType = "harian" // Ulangan harian
+	AssessmentAssignment AssessmentType = "tugas"
+	AssessmentMidterm    AssessmentType = "pts" // Penilaian Tengah Semester
+	AssessmentFinal      AssessmentType = "pas" // Penilaian Akhir Semester
+)
+
+// AssessmentTypes lists every type in report order.
+var AssessmentTypes = []AssessmentType{
+	AssessmentDaily, AssessmentAssignment, AssessmentMidterm, AssessmentFinal,
+}
+
+// IsValid reports whether the type is one of the known types
+func (t AssessmentType) IsValid() bool {
+	for _, known := range AssessmentTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Grade rounding policies for UnitSettings.GradeRounding
+const (
+	GradeRoundHalfUp = "half_up" // 82.5 -> 83
+	GradeRoundDown   = "down"    // 82.9 -> 82
+	GradeRoundUp     = "up"      // 82.1 -> 83
+)
+
+// Assessment is one graded piece of work for a class in a subject and
+// semester. Weights are relative to the other assessments of the same class,
+// subject and semester.
+type Assessment struct {
+	Id           uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	UnitId       uuid.UUID      `gorm:"type:uuid;not null;index" json:"unit_id"`
+	ClassId      uuid.UUID      `gorm:"type:uuid;not null;index:idx_assessment_class_subject_term" json:"class_id"`
+	SubjectId    uuid.UUID      `gorm:"type:uuid;not null;index:idx_assessment_class_subject_term" json:"subject_id"`
+	AcademicYear string         `gorm:"type:varchar(20);not null;index:idx_assessment_class_subject_term" json:"academic_year"` // "2025/2026"
+	Semester     int            `gorm:"not null;index:idx_assessment_class_subject_term" json:"semester"`                       // 1 or 2
+	Type         AssessmentType `gorm:"type:varchar(20);not null" json:"type"`
+	Name         string         `gorm:"type:varchar(100);not null" json:"name"` // "UH 1 Bilangan Bulat"
+	Competency   *string        `gorm:"type:varchar(255)" json:"competency"`    // Tujuan pembelajaran assessed, used in rapor descriptions
+	Date         *time.Time     `gorm:"type:date" json:"date"`
+	Weight       float64        `gorm:"type:numeric(6,2);not null" json:"weight"`
+	MaxScore     float64        `gorm:"type:numeric(6,2);not null;default:100" json:"max_score"`
+	CreatedBy    uuid.UUID      `gorm:"type:uuid;not null" json:"created_by"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Class   *Class   `gorm:"foreignKey:ClassId" json:"class,omitempty"`
+	Subject *Subject `gorm:"foreignKey:SubjectId" json:"subject,omitempty"`
+}
+
+func (Assessment) TableName() string { return "assessments" }
+
+func (a *Assessment) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.Id == uuid.Nil {
+		a.Id = uuid.New()
+	}
+	a.CreatedAt = time.Now()
+	a.UpdatedAt = time.Now()
+	return
+}
+
+func (a *Assessment) BeforeUpdate(tx *gorm.DB) (err error) {
+	a.UpdatedAt = time.Now()
+	return
+}
+
+// AssessmentScore is a student's score in an assessment. Version goes up with
+// every change so two teachers editing the same sheet cannot overwrite each
+// other unknowingly.
+type AssessmentScore struct {
+	Id                uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	AssessmentId      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_assessment_score_student" json:"assessment_id"`
+	ClassEnrollmentId uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_assessment_score_student;index" json:"class_enrollment_id"`
+	Score             float64   `gorm:"type:numeric(6,2);not null" json:"score"`
+	Note              *string   `gorm:"type:text" json:"note"`
+	Version           int       `gorm:"not null;default:1" json:"version"`
+	RecordedBy        uuid.UUID `gorm:"type:uuid;not null" json:"recorded_by"` // User who last changed it
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+
+	Assessment *Assessment `gorm:"foreignKey:AssessmentId" json:"assessment,omitempty"`
+}
+
+func (AssessmentScore) TableName() string { return "assessment_scores" }
+
+func (s *AssessmentScore) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.Id == uuid.Nil {
+		s.Id = uuid.New()
+	}
+	s.CreatedAt = time.Now()
+	s.UpdatedAt = time.Now()
+	return
+}
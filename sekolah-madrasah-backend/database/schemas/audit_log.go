@@ -0,0 +1,30 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AuditLog is an append-only record of a security-relevant action. ActorId is who
+// did it (nil for the system) and UserId is the account it affected.
+type AuditLog struct {
+	Id        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	ActorId   *uuid.UUID `gorm:"type:uuid;index" json:"actor_id"`
+	UserId    *uuid.UUID `gorm:"type:uuid;index" json:"user_id"`
+	Action    string     `gorm:"type:varchar(100);not null;index" json:"action"`
+	IpAddress string     `gorm:"type:varchar(45)" json:"ip_address"`
+	Details   string     `gorm:"type:jsonb;default:'{}'" json:"details"`
+	CreatedAt time.Time  `gorm:"index" json:"created_at"`
+}
+
+func (AuditLog) TableName() string { return "audit_logs" }
+
+func (al *AuditLog) BeforeCreate(tx *gorm.DB) (err error) {
+	if al.Id == uuid.Nil {
+		al.Id = uuid.New()
+	}
+	al.CreatedAt = time.Now()
+	return
+}
@@ -15,6 +15,8 @@ const (
 	EnrollmentStatusGraduated   ClassEnrollmentStatus = "graduated"
 	EnrollmentStatusTransferred ClassEnrollmentStatus = "transferred"
 	EnrollmentStatusDropped     ClassEnrollmentStatus = "dropped"
+	EnrollmentStatusPromoted    ClassEnrollmentStatus = "promoted" // Naik kelas at the year's rollover
+	EnrollmentStatusRetained    ClassEnrollmentStatus = "retained" // Tinggal kelas at the year's rollover
 )
 
 // ClassEnrollment represents the relationship between students and classes.
@@ -24,7 +26,7 @@ type ClassEnrollment struct {
 	StudentProfileId uuid.UUID             `gorm:"type:uuid;not null;index" json:"student_profile_id"` // FK to student_profiles
 	ClassId          uuid.UUID             `gorm:"type:uuid;not null;index" json:"class_id"`           // FK to classes
 	AcademicYear     string                `gorm:"type:varchar(20);not null" json:"academic_year"`     // "2025/2026"
-	Status           ClassEnrollmentStatus `gorm:"type:varchar(20);default:'active'" json:"status"`    // active/graduated/transferred
+	Status           ClassEnrollmentStatus `gorm:"type:varchar(20);default:'active'" json:"status"`    // active/graduated/transferred/promoted/retained
 	EnrolledAt       time.Time             `gorm:"type:date;not null" json:"enrolled_at"`              // Tanggal masuk kelas
 	LeftAt           *time.Time            `gorm:"type:date" json:"left_at"`                           // Tanggal keluar (nullable)
 	Notes            *string               `gorm:"type:text" json:"notes"`                             // Catatan
@@ -0,0 +1,42 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ClassSubject is a subject on a class's curriculum with its weekly hours
+// (jam pelajaran per minggu), the input of the timetable generator.
+type ClassSubject struct {
+	Id               uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	ClassId          uuid.UUID      `gorm:"type:uuid;not null;index" json:"class_id"`
+	SubjectId        uuid.UUID      `gorm:"type:uuid;not null;index" json:"subject_id"`
+	TeacherProfileId *uuid.UUID     `gorm:"type:uuid;index" json:"teacher_profile_id"` // Pengampu; any teacher of the subject when empty
+	HoursPerWeek     int            `gorm:"not null" json:"hours_per_week"`
+	Room             *string        `gorm:"type:varchar(50)" json:"room"` // Lab or special room the lessons need
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Class          *Class          `gorm:"foreignKey:ClassId" json:"class,omitempty"`
+	Subject        *Subject        `gorm:"foreignKey:SubjectId" json:"subject,omitempty"`
+	TeacherProfile *TeacherProfile `gorm:"foreignKey:TeacherProfileId" json:"teacher_profile,omitempty"`
+}
+
+func (ClassSubject) TableName() string { return "class_subjects" }
+
+func (cs *ClassSubject) BeforeCreate(tx *gorm.DB) (err error) {
+	if cs.Id == uuid.Nil {
+		cs.Id = uuid.New()
+	}
+	cs.CreatedAt = time.Now()
+	cs.UpdatedAt = time.Now()
+	return
+}
+
+func (cs *ClassSubject) BeforeUpdate(tx *gorm.DB) (err error) {
+	cs.UpdatedAt = time.Now()
+	return
+}
@@ -0,0 +1,66 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AttendanceStatus is how a student attended a school day
+type AttendanceStatus string
+
+const (
+	AttendancePresent AttendanceStatus = "hadir"
+	AttendanceSick    AttendanceStatus = "sakit"
+	AttendanceExcused AttendanceStatus = "izin"
+	AttendanceAbsent  AttendanceStatus = "alpa" // Tanpa keterangan
+	AttendanceLate    AttendanceStatus = "terlambat"
+)
+
+// AttendanceStatuses lists every status in report order.
+var AttendanceStatuses = []AttendanceStatus{
+	AttendancePresent, AttendanceSick, AttendanceExcused, AttendanceAbsent, AttendanceLate,
+}
+
+// IsValid reports whether the status is one of the known statuses
+func (s AttendanceStatus) IsValid() bool {
+	for _, status := range AttendanceStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// DailyAttendance is a student's attendance (presensi harian) for one school day,
+// recorded against the class enrollment of that day.
+type DailyAttendance struct {
+	Id                uuid.UUID        `gorm:"type:uuid;primaryKey" json:"id"`
+	ClassEnrollmentId uuid.UUID        `gorm:"type:uuid;not null;uniqueIndex:idx_daily_attendance_enrollment_date" json:"class_enrollment_id"`
+	ClassId           uuid.UUID        `gorm:"type:uuid;not null;index:idx_daily_attendance_class_date" json:"class_id"` // Copied from the enrollment for class reports
+	Date              time.Time        `gorm:"type:date;not null;uniqueIndex:idx_daily_attendance_enrollment_date;index:idx_daily_attendance_class_date" json:"date"`
+	Status            AttendanceStatus `gorm:"type:varchar(20);not null" json:"status"`
+	Note              *string          `gorm:"type:text" json:"note"`
+	RecordedBy        uuid.UUID        `gorm:"type:uuid;not null" json:"recorded_by"` // User who last recorded it
+	CreatedAt         time.Time        `json:"created_at"`
+	UpdatedAt         time.Time        `json:"updated_at"`
+
+	ClassEnrollment *ClassEnrollment `gorm:"foreignKey:ClassEnrollmentId" json:"class_enrollment,omitempty"`
+}
+
+func (DailyAttendance) TableName() string { return "daily_attendances" }
+
+func (a *DailyAttendance) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.Id == uuid.Nil {
+		a.Id = uuid.New()
+	}
+	a.CreatedAt = time.Now()
+	a.UpdatedAt = time.Now()
+	return
+}
+
+func (a *DailyAttendance) BeforeUpdate(tx *gorm.DB) (err error) {
+	a.UpdatedAt = time.Now()
+	return
+}
@@ -0,0 +1,59 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GradeThreshold is a unit's passing score (KKM/KKTP) and predicate scale.
+// A rule may be narrowed to a subject, a level or both; the most specific
+// rule matching a class and subject applies. The unit, subject and level are
+// unique through idx_grade_threshold_scope, an expression index created in
+// the migration so that rules for every subject or level collide too.
+type GradeThreshold struct {
+	Id        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UnitId    uuid.UUID  `gorm:"type:uuid;not null;index" json:"unit_id"`
+	SubjectId *uuid.UUID `gorm:"type:uuid" json:"subject_id"`                 // Null = every subject
+	Level     *int       `json:"level"`                                       // Null = every level
+	MinScore  float64    `gorm:"type:numeric(5,2);not null" json:"min_score"` // KKM/KKTP, out of 100
+	// Lowest average earning each predicate; below PredicateC is D
+	PredicateA float64   `gorm:"type:numeric(5,2);not null" json:"predicate_a"`
+	PredicateB float64   `gorm:"type:numeric(5,2);not null" json:"predicate_b"`
+	PredicateC float64   `gorm:"type:numeric(5,2);not null" json:"predicate_c"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	Subject *Subject `gorm:"foreignKey:SubjectId" json:"subject,omitempty"`
+}
+
+func (GradeThreshold) TableName() string { return "grade_thresholds" }
+
+func (t *GradeThreshold) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.Id == uuid.Nil {
+		t.Id = uuid.New()
+	}
+	t.CreatedAt = time.Now()
+	t.UpdatedAt = time.Now()
+	return
+}
+
+func (t *GradeThreshold) BeforeUpdate(tx *gorm.DB) (err error) {
+	t.UpdatedAt = time.Now()
+	return
+}
+
+// Predicate grades an average out of 100 on the rule's scale.
+func (t *GradeThreshold) Predicate(score float64) string {
+	switch {
+	case score >= t.PredicateA:
+		return "A"
+	case score >= t.PredicateB:
+		return "B"
+	case score >= t.PredicateC:
+		return "C"
+	default:
+		return "D"
+	}
+}
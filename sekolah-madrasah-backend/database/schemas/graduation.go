@@ -0,0 +1,45 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Graduation is a student's alumni record, written when a final-level class
+// graduates (kelulusan). The student's final enrollment is closed as graduated.
+type Graduation struct {
+	Id                uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UnitId            uuid.UUID `gorm:"type:uuid;not null;index;uniqueIndex:idx_graduation_certificate" json:"unit_id"`
+	StudentProfileId  uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"student_profile_id"` // One graduation per student
+	ClassEnrollmentId uuid.UUID `gorm:"type:uuid;not null;index" json:"class_enrollment_id"`      // The final enrollment
+	ClassId           uuid.UUID `gorm:"type:uuid;not null;index" json:"class_id"`                 // Class graduated from
+	AcademicYear      string    `gorm:"type:varchar(20);not null;index" json:"academic_year"`     // Year of graduation, "2025/2026"
+	GraduationDate    time.Time `gorm:"type:date;not null" json:"graduation_date"`                // Tanggal kelulusan
+	// Nomor ijazah, often issued after the graduation itself
+	CertificateNumber *string   `gorm:"type:varchar(50);uniqueIndex:idx_graduation_certificate" json:"certificate_number"`
+	FinalScore        *float64  `gorm:"type:numeric(5,2)" json:"final_score"` // Nilai ujian akhir, out of 100
+	Notes             *string   `gorm:"type:text" json:"notes"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+
+	StudentProfile *StudentProfile `gorm:"foreignKey:StudentProfileId" json:"student_profile,omitempty"`
+	Class          *Class          `gorm:"foreignKey:ClassId" json:"class,omitempty"`
+}
+
+func (Graduation) TableName() string { return "graduations" }
+
+func (g *Graduation) BeforeCreate(tx *gorm.DB) (err error) {
+	if g.Id == uuid.Nil {
+		g.Id = uuid.New()
+	}
+	g.CreatedAt = time.Now()
+	g.UpdatedAt = time.Now()
+	return
+}
+
+func (g *Graduation) BeforeUpdate(tx *gorm.DB) (err error) {
+	g.UpdatedAt = time.Now()
+	return
+}
@@ -0,0 +1,69 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LessonJournal is a teaching journal entry (jurnal mengajar) for one lesson
+// held from the timetable: which slot, on which date, and the topic covered.
+// Subject, class and teacher are copied from the timetable entry so the journal
+// keeps them when the timetable changes later.
+type LessonJournal struct {
+	Id               uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UnitId           uuid.UUID `gorm:"type:uuid;not null;index" json:"unit_id"`
+	TimetableEntryId uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_lesson_journal_entry_date" json:"timetable_entry_id"`
+	Date             time.Time `gorm:"type:date;not null;uniqueIndex:idx_lesson_journal_entry_date;index:idx_lesson_journal_class_date" json:"date"`
+	ClassId          uuid.UUID `gorm:"type:uuid;not null;index:idx_lesson_journal_class_date" json:"class_id"`
+	SubjectId        uuid.UUID `gorm:"type:uuid;not null;index" json:"subject_id"`
+	TeacherProfileId uuid.UUID `gorm:"type:uuid;not null;index" json:"teacher_profile_id"`
+	Period           int       `gorm:"not null" json:"period"`
+	Topic            string    `gorm:"type:varchar(255);not null" json:"topic"` // Materi yang diajarkan
+	Notes            *string   `gorm:"type:text" json:"notes"`
+	RecordedBy       uuid.UUID `gorm:"type:uuid;not null" json:"recorded_by"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+
+	Subject        *Subject        `gorm:"foreignKey:SubjectId" json:"subject,omitempty"`
+	TeacherProfile *TeacherProfile `gorm:"foreignKey:TeacherProfileId" json:"teacher_profile,omitempty"`
+}
+
+func (LessonJournal) TableName() string { return "lesson_journals" }
+
+func (j *LessonJournal) BeforeCreate(tx *gorm.DB) (err error) {
+	if j.Id == uuid.Nil {
+		j.Id = uuid.New()
+	}
+	j.CreatedAt = time.Now()
+	j.UpdatedAt = time.Now()
+	return
+}
+
+func (j *LessonJournal) BeforeUpdate(tx *gorm.DB) (err error) {
+	j.UpdatedAt = time.Now()
+	return
+}
+
+// LessonAttendance is a student's attendance in one lesson.
+type LessonAttendance struct {
+	Id                uuid.UUID        `gorm:"type:uuid;primaryKey" json:"id"`
+	LessonJournalId   uuid.UUID        `gorm:"type:uuid;not null;uniqueIndex:idx_lesson_attendance_student" json:"lesson_journal_id"`
+	ClassEnrollmentId uuid.UUID        `gorm:"type:uuid;not null;uniqueIndex:idx_lesson_attendance_student;index" json:"class_enrollment_id"`
+	Status            AttendanceStatus `gorm:"type:varchar(20);not null" json:"status"`
+	Note              *string          `gorm:"type:text" json:"note"`
+	CreatedAt         time.Time        `json:"created_at"`
+
+	LessonJournal *LessonJournal `gorm:"foreignKey:LessonJournalId" json:"lesson_journal,omitempty"`
+}
+
+func (LessonAttendance) TableName() string { return "lesson_attendances" }
+
+func (a *LessonAttendance) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.Id == uuid.Nil {
+		a.Id = uuid.New()
+	}
+	a.CreatedAt = time.Now()
+	return
+}
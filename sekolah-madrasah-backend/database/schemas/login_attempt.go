@@ -0,0 +1,29 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LoginAttempt records every password login so failures can be counted per email
+// and per IP address, including emails that do not belong to an account.
+type LoginAttempt struct {
+	Id        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	Email     string     `gorm:"type:varchar(255);not null;index" json:"email"`
+	IpAddress string     `gorm:"type:varchar(45);index" json:"ip_address"`
+	UserId    *uuid.UUID `gorm:"type:uuid;index" json:"user_id"`
+	Success   bool       `gorm:"not null;default:false" json:"success"`
+	CreatedAt time.Time  `gorm:"index" json:"created_at"`
+}
+
+func (LoginAttempt) TableName() string { return "login_attempts" }
+
+func (la *LoginAttempt) BeforeCreate(tx *gorm.DB) (err error) {
+	if la.Id == uuid.Nil {
+		la.Id = uuid.New()
+	}
+	la.CreatedAt = time.Now()
+	return
+}
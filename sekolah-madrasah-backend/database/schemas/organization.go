@@ -11,6 +11,9 @@ import (
 // Conceptually: Organization = Yayasan (Foundation)
 // Example: "Yayasan Pendidikan Islam" (code: YPI-001)
 // Contains multiple Schools/Units (stored in units table)
+// Settings is a JSON object of per-organization options:
+//   - require_email_verification (bool): unverified members cannot log in
+//   - require_two_factor (bool): admins must use two-factor authentication
 type Organization struct {
 	Id          uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
 	OwnerId     uuid.UUID      `gorm:"type:uuid;not null;index" json:"owner_id"`
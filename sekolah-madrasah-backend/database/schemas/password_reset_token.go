@@ -0,0 +1,31 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PasswordResetToken stores only the SHA-256 of the token mailed to the user.
+type PasswordResetToken struct {
+	Id        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserId    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	TokenHash string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	IpAddress string     `gorm:"type:varchar(45)" json:"ip_address"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	User *User `gorm:"foreignKey:UserId" json:"user,omitempty"`
+}
+
+func (PasswordResetToken) TableName() string { return "password_reset_tokens" }
+
+func (prt *PasswordResetToken) BeforeCreate(tx *gorm.DB) (err error) {
+	if prt.Id == uuid.Nil {
+		prt.Id = uuid.New()
+	}
+	prt.CreatedAt = time.Now()
+	return
+}
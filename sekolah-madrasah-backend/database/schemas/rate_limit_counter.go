@@ -0,0 +1,14 @@
+package schemas
+
+import "time"
+
+// RateLimitCounter counts requests for one rate-limit key in one fixed window.
+// Rows are disposable and are deleted once ExpiresAt has passed.
+type RateLimitCounter struct {
+	Key         string    `gorm:"type:varchar(255);primaryKey" json:"key"`
+	WindowStart time.Time `gorm:"primaryKey" json:"window_start"`
+	Count       int       `gorm:"not null;default:0" json:"count"`
+	ExpiresAt   time.Time `gorm:"not null;index" json:"expires_at"`
+}
+
+func (RateLimitCounter) TableName() string { return "rate_limit_counters" }
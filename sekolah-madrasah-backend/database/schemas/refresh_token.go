@@ -0,0 +1,58 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RefreshToken is a server-side record of an issued refresh token. Tokens
+// rotated from the same login share a FamilyId, which also identifies the session.
+type RefreshToken struct {
+	Id              uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"` // jti claim
+	UserId          uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	FamilyId        uuid.UUID  `gorm:"type:uuid;not null;index" json:"family_id"`
+	AccessTokenId   uuid.UUID  `gorm:"type:uuid;not null" json:"access_token_id"`
+	AccessExpiresAt time.Time  `gorm:"not null" json:"access_expires_at"`
+	ExpiresAt       time.Time  `gorm:"not null;index" json:"expires_at"`
+	UsedAt          *time.Time `json:"used_at"`
+	RevokedAt       *time.Time `json:"revoked_at"`
+	UserAgent       string     `gorm:"type:varchar(255)" json:"user_agent"`
+	IpAddress       string     `gorm:"type:varchar(45)" json:"ip_address"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	User *User `gorm:"foreignKey:UserId" json:"user,omitempty"`
+}
+
+func (RefreshToken) TableName() string { return "refresh_tokens" }
+
+func (rt *RefreshToken) BeforeCreate(tx *gorm.DB) (err error) {
+	if rt.Id == uuid.Nil {
+		rt.Id = uuid.New()
+	}
+	rt.CreatedAt = time.Now()
+	rt.UpdatedAt = time.Now()
+	return
+}
+
+func (rt *RefreshToken) BeforeUpdate(tx *gorm.DB) (err error) {
+	rt.UpdatedAt = time.Now()
+	return
+}
+
+// RevokedAccessToken denies an access token by jti until it would have expired anyway.
+type RevokedAccessToken struct {
+	Jti       uuid.UUID `gorm:"type:uuid;primaryKey" json:"jti"`
+	UserId    uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+func (RevokedAccessToken) TableName() string { return "revoked_access_tokens" }
+
+func (rat *RevokedAccessToken) BeforeCreate(tx *gorm.DB) (err error) {
+	rat.RevokedAt = time.Now()
+	return
+}
@@ -0,0 +1,152 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReportCardStatus is where a report card (rapor) is in its lifecycle
+type ReportCardStatus string
+
+const (
+	ReportCardDraft     ReportCardStatus = "draft"     // Computed live from the gradebook
+	ReportCardFinalized ReportCardStatus = "finalized" // Results frozen, grades locked; may be reopened
+	ReportCardPublished ReportCardStatus = "published" // Handed out to the student, cannot change
+)
+
+// ReportCard is a student's rapor for one semester of a class enrollment.
+// While a draft only the homeroom notes are stored; finalizing copies the
+// results into it so the rapor no longer follows the gradebook.
+type ReportCard struct {
+	Id                uuid.UUID        `gorm:"type:uuid;primaryKey" json:"id"`
+	UnitId            uuid.UUID        `gorm:"type:uuid;not null;index" json:"unit_id"`
+	ClassEnrollmentId uuid.UUID        `gorm:"type:uuid;not null;uniqueIndex:idx_report_card_enrollment_semester" json:"class_enrollment_id"`
+	ClassId           uuid.UUID        `gorm:"type:uuid;not null;index:idx_report_card_class_term" json:"class_id"` // Copied from the enrollment for class listings
+	AcademicYear      string           `gorm:"type:varchar(20);not null;index:idx_report_card_class_term" json:"academic_year"`
+	Semester          int              `gorm:"not null;uniqueIndex:idx_report_card_enrollment_semester;index:idx_report_card_class_term" json:"semester"`
+	Status            ReportCardStatus `gorm:"type:varchar(20);not null;default:'draft'" json:"status"`
+	HomeroomNote      *string          `gorm:"type:text" json:"homeroom_note"` // Catatan wali kelas
+	// Attendance totals, frozen when finalized
+	Sick    int `gorm:"not null;default:0" json:"sick"`
+	Excused int `gorm:"not null;default:0" json:"excused"`
+	Absent  int `gorm:"not null;default:0" json:"absent"`
+
+	FinalizedAt *time.Time `json:"finalized_at"`
+	FinalizedBy *uuid.UUID `gorm:"type:uuid" json:"finalized_by"`
+	PublishedAt *time.Time `json:"published_at"`
+	PublishedBy *uuid.UUID `gorm:"type:uuid" json:"published_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	Subjects        []ReportCardSubject  `gorm:"foreignKey:ReportCardId" json:"subjects,omitempty"`
+	Activities      []ReportCardActivity `gorm:"foreignKey:ReportCardId" json:"activities,omitempty"`
+	ClassEnrollment *ClassEnrollment     `gorm:"foreignKey:ClassEnrollmentId" json:"class_enrollment,omitempty"`
+}
+
+func (ReportCard) TableName() string { return "report_cards" }
+
+func (r *ReportCard) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.Id == uuid.Nil {
+		r.Id = uuid.New()
+	}
+	r.CreatedAt = time.Now()
+	r.UpdatedAt = time.Now()
+	return
+}
+
+func (r *ReportCard) BeforeUpdate(tx *gorm.DB) (err error) {
+	r.UpdatedAt = time.Now()
+	return
+}
+
+// ReportCardSubject is a subject's final grade and competency description as
+// frozen on a finalized report card.
+type ReportCardSubject struct {
+	Id           uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	ReportCardId uuid.UUID `gorm:"type:uuid;not null;index" json:"report_card_id"`
+	SubjectId    uuid.UUID `gorm:"type:uuid;not null" json:"subject_id"`
+	SubjectName  string    `gorm:"type:varchar(100);not null" json:"subject_name"` // As it was when finalized
+	Position     int       `gorm:"not null" json:"position"`                       // Order on the rapor
+	Grade        *float64  `gorm:"type:numeric(6,2)" json:"grade"`                 // Null without any score
+	MinScore     *float64  `gorm:"type:numeric(5,2)" json:"min_score"`             // KKM/KKTP applied, null without one
+	Predicate    *string   `gorm:"type:varchar(1)" json:"predicate"`               // A to D, null without a threshold or grade
+	Description  string    `gorm:"type:text" json:"description"`                   // Capaian kompetensi
+}
+
+func (ReportCardSubject) TableName() string { return "report_card_subjects" }
+
+func (s *ReportCardSubject) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.Id == uuid.Nil {
+		s.Id = uuid.New()
+	}
+	return
+}
+
+// ReportCardActivity is an extracurricular the student took part in, with
+// the homeroom teacher's remark on it.
+type ReportCardActivity struct {
+	Id           uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	ReportCardId uuid.UUID `gorm:"type:uuid;not null;index;uniqueIndex:idx_report_card_activity" json:"report_card_id"`
+	ActivityId   uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_report_card_activity" json:"activity_id"`
+	ActivityName string    `gorm:"type:varchar(100);not null" json:"activity_name"`
+	Note         *string   `gorm:"type:text" json:"note"` // "Sangat baik, aktif dalam latihan rutin"
+}
+
+func (ReportCardActivity) TableName() string { return "report_card_activities" }
+
+func (a *ReportCardActivity) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.Id == uuid.Nil {
+		a.Id = uuid.New()
+	}
+	return
+}
+
+// ReportCardTemplate is how a unit's rapor is laid out and worded. A unit
+// without one gets the defaults below.
+type ReportCardTemplate struct {
+	Id            uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UnitId        uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"unit_id"`
+	Title         string    `gorm:"type:varchar(100);not null;default:'LAPORAN HASIL BELAJAR'" json:"title"`
+	SchoolName    *string   `gorm:"type:varchar(255)" json:"school_name"`                     // Defaults to the unit's name
+	HeaderLines   *string   `gorm:"type:text" json:"header_lines"`                            // Address and contacts, one per line
+	PaperSize     string    `gorm:"type:varchar(10);not null;default:'A4'" json:"paper_size"` // A4 or F4
+	Place         *string   `gorm:"type:varchar(100)" json:"place"`                           // City written before the date of signing
+	PrincipalName *string   `gorm:"type:varchar(100)" json:"principal_name"`
+	PrincipalNip  *string   `gorm:"type:varchar(30)" json:"principal_nip"`
+	// Competency descriptions; {nama} is replaced with the student's name and
+	// {kompetensi} with the competencies concerned
+	DescriptionHigh string    `gorm:"type:text;not null" json:"description_high"`
+	DescriptionLow  string    `gorm:"type:text;not null" json:"description_low"`
+	Footer          *string   `gorm:"type:text" json:"footer"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func (ReportCardTemplate) TableName() string { return "report_card_templates" }
+
+func (t *ReportCardTemplate) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.Id == uuid.Nil {
+		t.Id = uuid.New()
+	}
+	t.CreatedAt = time.Now()
+	t.UpdatedAt = time.Now()
+	return
+}
+
+func (t *ReportCardTemplate) BeforeUpdate(tx *gorm.DB) (err error) {
+	t.UpdatedAt = time.Now()
+	return
+}
+
+// DefaultReportCardTemplate is the template of a unit that has not made its own.
+func DefaultReportCardTemplate(unitId uuid.UUID) *ReportCardTemplate {
+	return &ReportCardTemplate{
+		UnitId:          unitId,
+		Title:           "LAPORAN HASIL BELAJAR",
+		PaperSize:       "A4",
+		DescriptionHigh: "Ananda {nama} menunjukkan penguasaan yang baik dalam {kompetensi}.",
+		DescriptionLow:  "Ananda {nama} perlu bantuan dalam {kompetensi}.",
+	}
+}
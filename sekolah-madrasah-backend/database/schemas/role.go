@@ -7,6 +7,10 @@ import (
 	"gorm.io/gorm"
 )
 
+// SuperAdminRoleName is the seeded global role holding every permission. It is
+// never given out through organization membership.
+const SuperAdminRoleName = "Super Admin"
+
 type Role struct {
 	Id             uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
 	OrganizationId *uuid.UUID     `gorm:"type:uuid;index" json:"organization_id"`
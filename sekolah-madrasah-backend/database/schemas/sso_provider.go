@@ -0,0 +1,94 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SsoProvider is an organization's OpenID Connect identity provider. The client
+// secret is stored AES-GCM encrypted. GroupRoleMappings is a JSON array of
+// {"group", "unit_id", "role"} objects that grant a UnitMemberRole in a unit to
+// members of an IdP group.
+type SsoProvider struct {
+	Id                uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	OrganizationId    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"organization_id"`
+	Issuer            string    `gorm:"type:varchar(500);not null" json:"issuer"`
+	ClientId          string    `gorm:"type:varchar(255);not null" json:"client_id"`
+	ClientSecret      string    `gorm:"type:text" json:"-"`
+	RedirectUri       string    `gorm:"type:varchar(500);not null" json:"redirect_uri"`
+	Scopes            string    `gorm:"type:varchar(255);not null;default:'openid email profile'" json:"scopes"`
+	GroupsClaim       string    `gorm:"type:varchar(100);not null;default:'groups'" json:"groups_claim"`
+	GroupRoleMappings string    `gorm:"type:jsonb;not null;default:'[]'" json:"group_role_mappings"`
+	AllowProvisioning bool      `gorm:"default:false" json:"allow_provisioning"`
+	IsEnabled         bool      `gorm:"default:true" json:"is_enabled"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+
+	Organization *Organization `gorm:"foreignKey:OrganizationId" json:"organization,omitempty"`
+}
+
+func (SsoProvider) TableName() string { return "sso_providers" }
+
+func (sp *SsoProvider) BeforeCreate(tx *gorm.DB) (err error) {
+	if sp.Id == uuid.Nil {
+		sp.Id = uuid.New()
+	}
+	sp.CreatedAt = time.Now()
+	sp.UpdatedAt = time.Now()
+	return
+}
+
+func (sp *SsoProvider) BeforeUpdate(tx *gorm.DB) (err error) {
+	sp.UpdatedAt = time.Now()
+	return
+}
+
+// SsoLoginState is a pending authorization request. StateHash is the SHA-256 of
+// the state parameter; the PKCE verifier and nonce never leave the server.
+type SsoLoginState struct {
+	Id             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	StateHash      string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	OrganizationId uuid.UUID  `gorm:"type:uuid;not null;index" json:"organization_id"`
+	CodeVerifier   string     `gorm:"type:varchar(128);not null" json:"-"`
+	Nonce          string     `gorm:"type:varchar(128);not null" json:"-"`
+	ExpiresAt      time.Time  `gorm:"not null;index" json:"expires_at"`
+	UsedAt         *time.Time `json:"used_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (SsoLoginState) TableName() string { return "sso_login_states" }
+
+func (s *SsoLoginState) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.Id == uuid.Nil {
+		s.Id = uuid.New()
+	}
+	s.CreatedAt = time.Now()
+	return
+}
+
+// UserIdentity links a user to a subject at an identity provider, so later
+// sign-ins match on the stable subject rather than the email address.
+type UserIdentity struct {
+	Id             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserId         uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	OrganizationId uuid.UUID  `gorm:"type:uuid;not null;index" json:"organization_id"`
+	Issuer         string     `gorm:"type:varchar(500);not null;uniqueIndex:idx_user_identity_subject" json:"issuer"`
+	Subject        string     `gorm:"type:varchar(255);not null;uniqueIndex:idx_user_identity_subject" json:"subject"`
+	Email          string     `gorm:"type:varchar(255)" json:"email"`
+	LastLoginAt    *time.Time `json:"last_login_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+
+	User *User `gorm:"foreignKey:UserId" json:"user,omitempty"`
+}
+
+func (UserIdentity) TableName() string { return "user_identities" }
+
+func (ui *UserIdentity) BeforeCreate(tx *gorm.DB) (err error) {
+	if ui.Id == uuid.Nil {
+		ui.Id = uuid.New()
+	}
+	ui.CreatedAt = time.Now()
+	return
+}
@@ -0,0 +1,55 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// StaffAttendance is a teacher's or staff member's attendance (presensi
+// guru/pegawai) for one day: when they checked in and out, and from where.
+// Lateness and early leave are worked out from the unit settings at the time
+// of checking in and out, so later changes to the school hours keep the history.
+type StaffAttendance struct {
+	Id               uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UnitId           uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_staff_attendance_user_date;index:idx_staff_attendance_unit_date" json:"unit_id"`
+	UserId           uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_staff_attendance_user_date" json:"user_id"`
+	TeacherProfileId *uuid.UUID `gorm:"type:uuid;index" json:"teacher_profile_id"` // Set for teachers
+	Date             time.Time  `gorm:"type:date;not null;uniqueIndex:idx_staff_attendance_user_date;index:idx_staff_attendance_unit_date" json:"date"`
+
+	CheckInAt        time.Time `gorm:"not null" json:"check_in_at"`
+	CheckInLatitude  *float64  `json:"check_in_latitude"`
+	CheckInLongitude *float64  `json:"check_in_longitude"`
+	CheckInDevice    *string   `gorm:"type:varchar(100)" json:"check_in_device"`
+	LateMinutes      int       `gorm:"not null;default:0" json:"late_minutes"` // 0 when on time
+
+	CheckOutAt        *time.Time `json:"check_out_at"`
+	CheckOutLatitude  *float64   `json:"check_out_latitude"`
+	CheckOutLongitude *float64   `json:"check_out_longitude"`
+	CheckOutDevice    *string    `gorm:"type:varchar(100)" json:"check_out_device"`
+	EarlyLeaveMinutes int        `gorm:"not null;default:0" json:"early_leave_minutes"` // 0 when leaving at or after the end of the day
+
+	Note      *string   `gorm:"type:text" json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	User           *User           `gorm:"foreignKey:UserId" json:"user,omitempty"`
+	TeacherProfile *TeacherProfile `gorm:"foreignKey:TeacherProfileId" json:"teacher_profile,omitempty"`
+}
+
+func (StaffAttendance) TableName() string { return "staff_attendances" }
+
+func (a *StaffAttendance) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.Id == uuid.Nil {
+		a.Id = uuid.New()
+	}
+	a.CreatedAt = time.Now()
+	a.UpdatedAt = time.Now()
+	return
+}
+
+func (a *StaffAttendance) BeforeUpdate(tx *gorm.DB) (err error) {
+	a.UpdatedAt = time.Now()
+	return
+}
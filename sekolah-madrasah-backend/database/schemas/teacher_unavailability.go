@@ -0,0 +1,36 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TeacherUnavailability blocks a range of periods on one weekday in which the
+// teacher cannot be scheduled, e.g. because they also teach at another school.
+type TeacherUnavailability struct {
+	Id               uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UnitId           uuid.UUID `gorm:"type:uuid;not null;index" json:"unit_id"`
+	TeacherProfileId uuid.UUID `gorm:"type:uuid;not null;index" json:"teacher_profile_id"`
+	DayOfWeek        int       `gorm:"not null" json:"day_of_week"` // 1 = Senin ... 7 = Minggu
+	PeriodFrom       int       `gorm:"not null" json:"period_from"`
+	PeriodTo         int       `gorm:"not null" json:"period_to"` // Inclusive
+	Reason           *string   `gorm:"type:varchar(255)" json:"reason"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+func (TeacherUnavailability) TableName() string { return "teacher_unavailabilities" }
+
+func (tu *TeacherUnavailability) BeforeCreate(tx *gorm.DB) (err error) {
+	if tu.Id == uuid.Nil {
+		tu.Id = uuid.New()
+	}
+	tu.CreatedAt = time.Now()
+	return
+}
+
+// Covers reports whether the teacher is unavailable in the given slot.
+func (tu TeacherUnavailability) Covers(dayOfWeek, period int) bool {
+	return tu.DayOfWeek == dayOfWeek && period >= tu.PeriodFrom && period <= tu.PeriodTo
+}
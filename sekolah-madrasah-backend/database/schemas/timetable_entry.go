@@ -0,0 +1,48 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TimetableEntry places one lesson in a class's weekly timetable (jadwal pelajaran):
+// which subject is taught by which teacher in a given day and period.
+// Period start and end times are derived from the unit's UnitSettings.
+// A class has at most one lesson per slot; deleted entries do not count.
+type TimetableEntry struct {
+	Id               uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	UnitId           uuid.UUID      `gorm:"type:uuid;not null;index" json:"unit_id"`
+	ClassId          uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_timetable_class_slot,where:deleted_at IS NULL" json:"class_id"`
+	SubjectId        uuid.UUID      `gorm:"type:uuid;not null;index" json:"subject_id"`
+	TeacherProfileId uuid.UUID      `gorm:"type:uuid;not null;index" json:"teacher_profile_id"`
+	Room             *string        `gorm:"type:varchar(50);index" json:"room"`                                                  // Ruang kelas/lab
+	AcademicYear     string         `gorm:"type:varchar(20);not null;uniqueIndex:idx_timetable_class_slot" json:"academic_year"` // "2025/2026"
+	Semester         int            `gorm:"not null;uniqueIndex:idx_timetable_class_slot" json:"semester"`                       // 1 or 2
+	DayOfWeek        int            `gorm:"not null;uniqueIndex:idx_timetable_class_slot" json:"day_of_week"`                    // 1 = Senin ... 7 = Minggu
+	Period           int            `gorm:"not null;uniqueIndex:idx_timetable_class_slot" json:"period"`                         // Jam ke-
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Class          *Class          `gorm:"foreignKey:ClassId" json:"class,omitempty"`
+	Subject        *Subject        `gorm:"foreignKey:SubjectId" json:"subject,omitempty"`
+	TeacherProfile *TeacherProfile `gorm:"foreignKey:TeacherProfileId" json:"teacher_profile,omitempty"`
+}
+
+func (TimetableEntry) TableName() string { return "timetable_entries" }
+
+func (e *TimetableEntry) BeforeCreate(tx *gorm.DB) (err error) {
+	if e.Id == uuid.Nil {
+		e.Id = uuid.New()
+	}
+	e.CreatedAt = time.Now()
+	e.UpdatedAt = time.Now()
+	return
+}
+
+func (e *TimetableEntry) BeforeUpdate(tx *gorm.DB) (err error) {
+	e.UpdatedAt = time.Now()
+	return
+}
@@ -0,0 +1,59 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TimetableGenerationStatus string
+
+const (
+	TimetableGenerationPending   TimetableGenerationStatus = "pending"
+	TimetableGenerationRunning   TimetableGenerationStatus = "running"
+	TimetableGenerationCompleted TimetableGenerationStatus = "completed"
+	TimetableGenerationFailed    TimetableGenerationStatus = "failed"
+	TimetableGenerationApplied   TimetableGenerationStatus = "applied"
+)
+
+// TimetableGeneration is a background run of the timetable generator. The
+// proposed timetable is kept here until someone applies it.
+type TimetableGeneration struct {
+	Id           uuid.UUID                 `gorm:"type:uuid;primaryKey" json:"id"`
+	UnitId       uuid.UUID                 `gorm:"type:uuid;not null;index" json:"unit_id"`
+	AcademicYear string                    `gorm:"type:varchar(20);not null" json:"academic_year"`
+	Semester     int                       `gorm:"not null" json:"semester"`
+	ClassIds     string                    `gorm:"type:jsonb;not null;default:'[]'" json:"class_ids"` // Classes whose timetable is regenerated
+	Days         string                    `gorm:"type:jsonb;not null;default:'[]'" json:"days"`      // School days lessons are spread over
+	Status       TimetableGenerationStatus `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	Entries      string                    `gorm:"type:jsonb;not null;default:'[]'" json:"entries"`     // Proposed TimetableEntry rows
+	Unsatisfied  string                    `gorm:"type:jsonb;not null;default:'[]'" json:"unsatisfied"` // Constraints the generator could not meet
+	Error        *string                   `gorm:"type:text" json:"error"`
+	RequestedBy  uuid.UUID                 `gorm:"type:uuid;not null" json:"requested_by"`
+	StartedAt    *time.Time                `json:"started_at"`
+	HeartbeatAt  *time.Time                `json:"heartbeat_at"` // Last sign of life of the server running the job
+	FinishedAt   *time.Time                `json:"finished_at"`
+	AppliedAt    *time.Time                `json:"applied_at"`
+	CreatedAt    time.Time                 `json:"created_at"`
+	UpdatedAt    time.Time                 `json:"updated_at"`
+}
+
+func (TimetableGeneration) TableName() string { return "timetable_generations" }
+
+func (g *TimetableGeneration) BeforeCreate(tx *gorm.DB) (err error) {
+	if g.Id == uuid.Nil {
+		g.Id = uuid.New()
+	}
+	if g.Status == "" {
+		g.Status = TimetableGenerationPending
+	}
+	g.CreatedAt = time.Now()
+	g.UpdatedAt = time.Now()
+	return
+}
+
+func (g *TimetableGeneration) BeforeUpdate(tx *gorm.DB) (err error) {
+	g.UpdatedAt = time.Now()
+	return
+}
@@ -0,0 +1,56 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserTwoFactor holds a user's TOTP secret, encrypted with the AES key from config.
+// The row exists from enrollment on; only ConfirmedAt makes it active.
+type UserTwoFactor struct {
+	UserId      uuid.UUID  `gorm:"type:uuid;primaryKey" json:"user_id"`
+	Secret      string     `gorm:"type:text;not null" json:"-"`
+	ConfirmedAt *time.Time `json:"confirmed_at"`
+	// LastUsedStep is the newest TOTP time step accepted, so a code cannot be replayed
+	LastUsedStep int64     `gorm:"not null;default:0" json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	User *User `gorm:"foreignKey:UserId" json:"user,omitempty"`
+}
+
+func (UserTwoFactor) TableName() string { return "user_two_factors" }
+
+func (tf *UserTwoFactor) BeforeCreate(tx *gorm.DB) (err error) {
+	tf.CreatedAt = time.Now()
+	tf.UpdatedAt = time.Now()
+	return
+}
+
+func (tf *UserTwoFactor) BeforeUpdate(tx *gorm.DB) (err error) {
+	tf.UpdatedAt = time.Now()
+	return
+}
+
+// TwoFactorRecoveryCode stores only the SHA-256 of a single-use recovery code.
+type TwoFactorRecoveryCode struct {
+	Id        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserId    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	User *User `gorm:"foreignKey:UserId" json:"user,omitempty"`
+}
+
+func (TwoFactorRecoveryCode) TableName() string { return "two_factor_recovery_codes" }
+
+func (rc *TwoFactorRecoveryCode) BeforeCreate(tx *gorm.DB) (err error) {
+	if rc.Id == uuid.Nil {
+		rc.Id = uuid.New()
+	}
+	rc.CreatedAt = time.Now()
+	return
+}
@@ -17,6 +17,17 @@ const (
 	UnitMemberRoleStaff    UnitMemberRole = "staff"
 	UnitMemberRoleParent   UnitMemberRole = "parent"
 	UnitMemberRoleAnggota  UnitMemberRole = "anggota" // siswa/member
+	UnitMemberRoleAlumni   UnitMemberRole = "alumni"  // Graduated student
+)
+
+// UnitMemberApprovalStatus tracks a sign-up through review by the unit's admins.
+// Members added by an admin are approved straight away.
+type UnitMemberApprovalStatus string
+
+const (
+	UnitMemberApprovalPending  UnitMemberApprovalStatus = "pending"
+	UnitMemberApprovalApproved UnitMemberApprovalStatus = "approved"
+	UnitMemberApprovalRejected UnitMemberApprovalStatus = "rejected"
 )
 
 type UnitMember struct {
@@ -27,6 +38,12 @@ type UnitMember struct {
 	IsActive  bool           `gorm:"default:true" json:"is_active"`
 	JoinedAt  time.Time      `json:"joined_at"`
 	InvitedBy *uuid.UUID     `gorm:"type:uuid" json:"invited_by"`
+
+	ApprovalStatus  UnitMemberApprovalStatus `gorm:"type:varchar(20);not null;default:'approved';index" json:"approval_status"`
+	ReviewedBy      *uuid.UUID               `gorm:"type:uuid" json:"reviewed_by"`
+	ReviewedAt      *time.Time               `json:"reviewed_at"`
+	RejectionReason string                   `gorm:"type:text" json:"rejection_reason"`
+
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
@@ -42,6 +59,9 @@ func (sm *UnitMember) BeforeCreate(tx *gorm.DB) (err error) {
 	if sm.Id == uuid.Nil {
 		sm.Id = uuid.New()
 	}
+	if sm.ApprovalStatus == "" {
+		sm.ApprovalStatus = UnitMemberApprovalApproved
+	}
 	sm.JoinedAt = time.Now()
 	sm.CreatedAt = time.Now()
 	sm.UpdatedAt = time.Now()
@@ -16,6 +16,16 @@ type UnitSettings struct {
 	TotalPeriods     int       `gorm:"type:int;default:9"`               // Total periods per day
 	BreakAfterPeriod int       `gorm:"type:int;default:3"`               // Break after period n
 	BreakDuration    int       `gorm:"type:int;default:15"`              // Break duration (minutes)
+	MaxConsecutive   int       `gorm:"type:int;default:0"`               // Max periods a teacher teaches in a row, 0 = no limit
+
+	// Staff Attendance Settings
+	EndTime       string `gorm:"type:varchar(10)"`                        // Jam pulang, empty = end of the last period
+	LateTolerance int    `gorm:"type:int;default:0"`                      // Minutes after StartTime a check-in still counts as on time
+	Timezone      string `gorm:"type:varchar(40);default:'Asia/Jakarta'"` // WIB, WITA (Asia/Makassar) or WIT (Asia/Jayapura)
+
+	// Grade Settings
+	GradeRounding string `gorm:"type:varchar(20);default:'half_up'"` // How averages are rounded: half_up, down or up
+	GradeDecimals int    `gorm:"type:int;default:0"`                 // Decimals kept when rounding averages
 
 	// Semester Settings
 	AcademicYear    string     `gorm:"type:varchar(20)"`   // "2025/2026"
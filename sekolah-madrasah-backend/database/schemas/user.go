@@ -8,19 +8,26 @@ import (
 )
 
 type User struct {
-	Id              uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
-	Email           string         `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"`
-	Password        string         `gorm:"type:varchar(255);not null" json:"-"`
-	FullName        string         `gorm:"type:varchar(100)" json:"full_name"`
-	Phone           string         `gorm:"type:varchar(20)" json:"phone"`
-	Avatar          string         `gorm:"type:varchar(500)" json:"avatar"`
-	IsSuperAdmin    bool           `gorm:"default:false" json:"is_super_admin"`
-	IsActive        bool           `gorm:"default:true" json:"is_active"`
-	EmailVerifiedAt *time.Time     `json:"email_verified_at"`
-	LastLoginAt     *time.Time     `json:"last_login_at"`
-	CreatedAt       time.Time      `json:"created_at"`
-	UpdatedAt       time.Time      `json:"updated_at"`
-	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+	Id           uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	Email        string    `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"`
+	Password     string    `gorm:"type:varchar(255);not null" json:"-"`
+	FullName     string    `gorm:"type:varchar(100)" json:"full_name"`
+	Phone        string    `gorm:"type:varchar(20)" json:"phone"`
+	Avatar       string    `gorm:"type:varchar(500)" json:"avatar"`
+	IsSuperAdmin bool      `gorm:"default:false" json:"is_super_admin"`
+	IsActive     bool      `gorm:"default:true" json:"is_active"`
+	// MustChangePassword is set on accounts created with a generated password
+	MustChangePassword bool       `gorm:"default:false" json:"must_change_password"`
+	PasswordChangedAt  *time.Time `json:"password_changed_at"`
+	EmailVerifiedAt    *time.Time `json:"email_verified_at"`
+	LastLoginAt        *time.Time `json:"last_login_at"`
+	// FailedLoginAttempts counts wrong passwords since the last successful login or unlock
+	FailedLoginAttempts int            `gorm:"default:0" json:"failed_login_attempts"`
+	LastFailedLoginAt   *time.Time     `json:"last_failed_login_at"`
+	LockedUntil         *time.Time     `json:"locked_until"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (User) TableName() string { return "users" }
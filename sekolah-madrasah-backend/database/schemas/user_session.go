@@ -0,0 +1,41 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserSession is one signed-in device. Its Id is the FamilyId shared by the
+// refresh tokens rotated from that login.
+type UserSession struct {
+	Id         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserId     uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Device     string     `gorm:"type:varchar(100)" json:"device"`
+	UserAgent  string     `gorm:"type:varchar(255)" json:"user_agent"`
+	IpAddress  string     `gorm:"type:varchar(45)" json:"ip_address"`
+	LastSeenAt time.Time  `gorm:"not null" json:"last_seen_at"`
+	ExpiresAt  time.Time  `gorm:"not null;index" json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	User *User `gorm:"foreignKey:UserId" json:"user,omitempty"`
+}
+
+func (UserSession) TableName() string { return "user_sessions" }
+
+func (us *UserSession) BeforeCreate(tx *gorm.DB) (err error) {
+	if us.Id == uuid.Nil {
+		us.Id = uuid.New()
+	}
+	us.CreatedAt = time.Now()
+	us.UpdatedAt = time.Now()
+	return
+}
+
+func (us *UserSession) BeforeUpdate(tx *gorm.DB) (err error) {
+	us.UpdatedAt = time.Now()
+	return
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	_ "time/tzdata" // Unit timezones must resolve on hosts without zoneinfo
 
 	"sekolah-madrasah/config"
 	"sekolah-madrasah/routes"
@@ -3,6 +3,10 @@ package auth_utils
 import (
 	"context"
 	"encoding/base64"
+
+	"sekolah-madrasah/database/schemas"
+
+	"github.com/google/uuid"
 )
 
 // ctxKey is a private type to avoid key collisions in context.
@@ -42,3 +46,25 @@ func BasicAuth(username, password string) string {
 	auth := username + ":" + password
 	return base64.StdEncoding.EncodeToString([]byte(auth))
 }
+
+const ctxUnitAccessKey ctxKey = "unit_access"
+
+// UnitAccess is the caller's effective membership in the unit addressed by the current request.
+type UnitAccess struct {
+	UnitId uuid.UUID
+	Role   schemas.UnitMemberRole
+}
+
+// WithUnitAccess returns a new context carrying the resolved unit access.
+func WithUnitAccess(ctx context.Context, access UnitAccess) context.Context {
+	return context.WithValue(ctx, ctxUnitAccessKey, access)
+}
+
+// GetUnitAccess extracts UnitAccess from the context, reporting whether it was set.
+func GetUnitAccess(ctx context.Context) (UnitAccess, bool) {
+	if ctx == nil {
+		return UnitAccess{}, false
+	}
+	access, ok := ctx.Value(ctxUnitAccessKey).(UnitAccess)
+	return access, ok
+}
@@ -0,0 +1,181 @@
+package auth_utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// minRSAKeyBits rejects RSA keys too short to be trusted for signing
+const minRSAKeyBits = 2048
+
+type jwtKey struct {
+	method jwt.SigningMethod
+	public crypto.PublicKey
+	// private is nil for keys kept only to verify tokens issued before a rotation
+	private crypto.PrivateKey
+}
+
+// KeySet holds the asymmetric keys tokens are signed and verified with. Each key
+// is addressed by the kid header, so tokens signed by a retired key stay valid
+// until they expire as long as its public key remains in the set.
+type KeySet struct {
+	signingKeyId string
+	keys         map[string]jwtKey
+}
+
+var keySet *KeySet
+
+// SetKeySet switches signing to the set's signing key. Without a key set tokens
+// are signed with HS256 and REST_SECRET.
+func SetKeySet(set *KeySet) {
+	keySet = set
+}
+
+// LoadKeySet reads every <kid>.pem file in dir. Files may hold an RSA or Ed25519
+// private key, or only a public key for a key that no longer signs.
+// signingKeyId names the key that signs new tokens and must be a private key.
+func LoadKeySet(dir, signingKeyId string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	set := &KeySet{signingKeyId: signingKeyId, keys: make(map[string]jwtKey)}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := parseKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		set.keys[strings.TrimSuffix(filepath.Base(path), ".pem")] = key
+	}
+
+	signing, ok := set.keys[signingKeyId]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q not found in %s", signingKeyId, dir)
+	}
+	if signing.private == nil {
+		return nil, fmt.Errorf("signing key %q has no private key", signingKeyId)
+	}
+
+	return set, nil
+}
+
+func parseKey(data []byte) (jwtKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return jwtKey{}, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return jwtKey{}, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return jwtKey{}, err
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < minRSAKeyBits {
+			return jwtKey{}, fmt.Errorf("RSA keys must have at least %d bits", minRSAKeyBits)
+		}
+		return jwtKey{method: jwt.SigningMethodRS256, public: &key.PublicKey, private: key}, nil
+	case *rsa.PublicKey:
+		if key.N.BitLen() < minRSAKeyBits {
+			return jwtKey{}, fmt.Errorf("RSA keys must have at least %d bits", minRSAKeyBits)
+		}
+		return jwtKey{method: jwt.SigningMethodRS256, public: key}, nil
+	case ed25519.PrivateKey:
+		return jwtKey{method: jwt.SigningMethodEdDSA, public: key.Public(), private: key}, nil
+	case ed25519.PublicKey:
+		return jwtKey{method: jwt.SigningMethodEdDSA, public: key}, nil
+	default:
+		return jwtKey{}, fmt.Errorf("unsupported key type %T, use RSA or Ed25519", parsed)
+	}
+}
+
+// verificationKey returns the public key for a token's kid, provided the token
+// was signed with the algorithm that key is meant for.
+func (s *KeySet) verificationKey(token *jwt.Token) (crypto.PublicKey, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if key.method.Alg() != token.Method.Alg() {
+		return nil, fmt.Errorf("signing key %q does not use %s", kid, token.Method.Alg())
+	}
+	return key.public, nil
+}
+
+type Jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// N and E are set for RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Crv and X are set for Ed25519 keys
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type Jwks struct {
+	Keys []Jwk `json:"keys"`
+}
+
+// PublicJwks lists the public half of every key tokens may be signed with. The
+// HS256 secret is never published, so the list is empty without a key set.
+func PublicJwks() Jwks {
+	jwks := Jwks{Keys: []Jwk{}}
+	if keySet == nil {
+		return jwks
+	}
+
+	for kid, key := range keySet.keys {
+		jwk := Jwk{Kid: kid, Use: "sig", Alg: key.method.Alg()}
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].Kid < jwks.Keys[j].Kid })
+	return jwks
+}
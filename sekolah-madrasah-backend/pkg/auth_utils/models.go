@@ -12,6 +12,36 @@ type AuthClaim struct {
 	Exp    int64     `json:"exp" default:"1h" order:"10"`
 	Level  rbac.Role
 
+	// TokenType is either TokenTypeAccess or TokenTypeRefresh
+	TokenType string `json:"token_type"`
+	// SessionID identifies the refresh token family the token was issued for
+	SessionID uuid.UUID `json:"sid"`
+	// MustChangePassword restricts the token to the change-password endpoint
+	MustChangePassword bool `json:"mcp,omitempty"`
+	// TwoFactorSetup restricts the token to two-factor enrollment
+	TwoFactorSetup bool `json:"tfs,omitempty"`
+	// Email is the address an email verification token confirms
+	Email string `json:"email,omitempty"`
+	// ImpersonatorID is the super admin acting as UserID; nil for ordinary tokens
+	ImpersonatorID *uuid.UUID `json:"act_id,omitempty"`
+	// ImpersonationWrites lets an impersonation token call state-changing endpoints
+	ImpersonationWrites bool `json:"imw,omitempty"`
+
+	// ApiKeyID, OrganizationID and Scopes are only set for TokenTypeApiKey, when the
+	// request authenticated with an organization API key instead of a JWT
+	ApiKeyID       uuid.UUID `json:"-"`
+	OrganizationID uuid.UUID `json:"-"`
+	Scopes         []string  `json:"-"`
+
 	jwt.StandardClaims
 }
 
+// IsApiKey reports whether the claims come from an organization API key.
+func (c AuthClaim) IsApiKey() bool {
+	return c.TokenType == TokenTypeApiKey
+}
+
+// IsImpersonated reports whether a super admin is acting as UserID.
+func (c AuthClaim) IsImpersonated() bool {
+	return c.ImpersonatorID != nil
+}
@@ -10,13 +10,25 @@ import (
 	"sekolah-madrasah/config"
 )
 
+// ParseToken verifies the signature with the key named by the kid header, or
+// with REST_SECRET for HS256 tokens when no key set is loaded or
+// JWTAcceptHS256 keeps them valid during the switch to asymmetric keys.
 func ParseToken(token string) (*jwt.Token, error) {
 	return jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			//nil secret key
+		switch token.Method.(type) {
+		case *jwt.SigningMethodHMAC:
+			if keySet != nil && !config.APP.Rest.JWTAcceptHS256 {
+				return nil, errors.New("HS256 tokens are no longer accepted")
+			}
+			return []byte(config.APP.Rest.JWTSecret), nil
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodEd25519:
+			if keySet == nil {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return keySet.verificationKey(token)
+		default:
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(config.APP.Rest.JWTSecret), nil
 	})
 }
 
@@ -36,6 +48,46 @@ func ValidateToken(signedToken string) (claims *AuthClaim, err error) {
 		}
 	}
 
+	if tokenType, ok := parsedData["token_type"].(string); ok {
+		claims.TokenType = tokenType
+	}
+
+	if mcp, ok := parsedData["mcp"].(bool); ok {
+		claims.MustChangePassword = mcp
+	}
+
+	if tfs, ok := parsedData["tfs"].(bool); ok {
+		claims.TwoFactorSetup = tfs
+	}
+
+	if email, ok := parsedData["email"].(string); ok {
+		claims.Email = email
+	}
+
+	if actorID, ok := parsedData["act_id"].(string); ok {
+		var id uuid.UUID
+		id, err = uuid.Parse(actorID)
+		if err != nil {
+			return
+		}
+		claims.ImpersonatorID = &id
+	}
+
+	if imw, ok := parsedData["imw"].(bool); ok {
+		claims.ImpersonationWrites = imw
+	}
+
+	if jti, ok := parsedData["jti"].(string); ok {
+		claims.Id = jti
+	}
+
+	if sid, ok := parsedData["sid"].(string); ok {
+		claims.SessionID, err = uuid.Parse(sid)
+		if err != nil {
+			return
+		}
+	}
+
 	// Handle expiration
 	if exp, ok := parsedData["exp"].(float64); ok {
 		claims.Exp = int64(exp)
@@ -58,11 +110,40 @@ func getParsedMapClaims(signedToken string) (jwt.MapClaims, error) {
 	if !ok || !parsed.Valid {
 		return nil, errors.New("couldn't parse claims")
 	}
+
+	if issuer := config.APP.Rest.JWTIssuer; issuer != "" && !parsedData.VerifyIssuer(issuer, true) {
+		return nil, errors.New("invalid token issuer")
+	}
+	if audience := config.APP.Rest.JWTAudience; audience != "" && !parsedData.VerifyAudience(audience, true) {
+		return nil, errors.New("invalid token audience")
+	}
 	return parsedData, nil
 }
 
+const (
+	TokenTypeAccess            = "access"
+	TokenTypeRefresh           = "refresh"
+	TokenTypeEmailVerification = "email_verification"
+	// TokenTypeTwoFactorChallenge proves the password step of a two-factor login
+	TokenTypeTwoFactorChallenge = "2fa_challenge"
+	// TokenTypeApiKey marks claims built from an organization API key; it is never signed
+	TokenTypeApiKey = "api_key"
+)
+
 type TokenParams struct {
-	UserID uuid.UUID
+	UserID    uuid.UUID
+	TokenType string
+	// TokenID becomes the jti claim; a random one is used when empty
+	TokenID   uuid.UUID
+	SessionID uuid.UUID
+
+	MustChangePassword bool
+	TwoFactorSetup     bool
+	Email              string
+
+	// ImpersonatorID marks an access token a super admin uses to act as UserID
+	ImpersonatorID      *uuid.UUID
+	ImpersonationWrites bool
 }
 
 func GenerateToken(params TokenParams, duration time.Duration) (string, error) {
@@ -75,19 +156,49 @@ func GenerateTokenWithExpTimestamp(params TokenParams, expiresAt int64) (string,
 		return "", errors.New("user_id is required")
 	}
 
+	tokenType := params.TokenType
+	if tokenType == "" {
+		tokenType = TokenTypeAccess
+	}
+
+	tokenID := params.TokenID
+	if tokenID == uuid.Nil {
+		tokenID = uuid.New()
+	}
+
 	claims := &AuthClaim{
-		UserID: params.UserID,
-		Exp:    expiresAt,
+		UserID:    params.UserID,
+		Exp:       expiresAt,
+		TokenType: tokenType,
+		SessionID: params.SessionID,
+
+		MustChangePassword: params.MustChangePassword,
+		TwoFactorSetup:     params.TwoFactorSetup,
+		Email:              params.Email,
+
+		ImpersonatorID:      params.ImpersonatorID,
+		ImpersonationWrites: params.ImpersonationWrites,
 		StandardClaims: jwt.StandardClaims{
+			Id:        tokenID.String(),
+			Issuer:    config.APP.Rest.JWTIssuer,
+			Audience:  config.APP.Rest.JWTAudience,
+			IssuedAt:  time.Now().Unix(),
 			ExpiresAt: expiresAt,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(config.APP.Rest.JWTSecret))
-	if err != nil {
-		return "", err
+	return signClaims(claims)
+}
+
+// signClaims signs with the key set's signing key and names it in the kid
+// header, falling back to HS256 and REST_SECRET without a key set.
+func signClaims(claims jwt.Claims) (string, error) {
+	if keySet == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(config.APP.Rest.JWTSecret))
 	}
 
-	return tokenString, nil
+	key := keySet.keys[keySet.signingKeyId]
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = keySet.signingKeyId
+	return token.SignedString(key.private)
 }
@@ -0,0 +1,170 @@
+package auth_utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sekolah-madrasah/config"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePrivateKey(t *testing.T, dir, kid string, key interface{}) {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	writePem(t, dir, kid, "PRIVATE KEY", der)
+}
+
+func writePublicKey(t *testing.T, dir, kid string, key interface{}) {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+	writePem(t, dir, kid, "PUBLIC KEY", der)
+}
+
+func writePem(t *testing.T, dir, kid, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600))
+}
+
+func useKeySet(t *testing.T, dir, signingKeyId string) {
+	t.Helper()
+	set, err := LoadKeySet(dir, signingKeyId)
+	require.NoError(t, err)
+
+	previous := keySet
+	SetKeySet(set)
+	t.Cleanup(func() { SetKeySet(previous) })
+}
+
+func withRestConfig(t *testing.T, rest config.Rest) {
+	previous := config.APP.Rest
+	config.APP.Rest = rest
+	t.Cleanup(func() { config.APP.Rest = previous })
+}
+
+func newToken(t *testing.T) string {
+	t.Helper()
+	token, err := GenerateToken(TokenParams{UserID: uuid.New()}, time.Hour)
+	require.NoError(t, err)
+	return token
+}
+
+func TestToken_SignsWithEd25519AndKid(t *testing.T) {
+	withRestConfig(t, config.Rest{JWTIssuer: "issuer", JWTAudience: "api"})
+	_, private, _ := ed25519.GenerateKey(rand.Reader)
+	dir := t.TempDir()
+	writePrivateKey(t, dir, "ed-1", private)
+	useKeySet(t, dir, "ed-1")
+
+	token := newToken(t)
+	parsed, _, err := new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, "EdDSA", parsed.Header["alg"])
+	assert.Equal(t, "ed-1", parsed.Header["kid"])
+	assert.Equal(t, "issuer", parsed.Claims.(jwt.MapClaims)["iss"])
+
+	_, err = ValidateToken(token)
+	assert.NoError(t, err)
+}
+
+func TestToken_RotationKeepsOldTokensValid(t *testing.T) {
+	withRestConfig(t, config.Rest{})
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	writePrivateKey(t, dir, "rsa-1", oldKey)
+	useKeySet(t, dir, "rsa-1")
+	oldToken := newToken(t)
+
+	// Rotate: a new signing key, with only the public half of the old one kept
+	_, newKey, _ := ed25519.GenerateKey(rand.Reader)
+	rotated := t.TempDir()
+	writePrivateKey(t, rotated, "ed-2", newKey)
+	writePublicKey(t, rotated, "rsa-1", &oldKey.PublicKey)
+	useKeySet(t, rotated, "ed-2")
+
+	_, err = ValidateToken(oldToken)
+	assert.NoError(t, err)
+	_, err = ValidateToken(newToken(t))
+	assert.NoError(t, err)
+
+	jwks := PublicJwks()
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, Jwk{Kty: "OKP", Kid: "ed-2", Use: "sig", Alg: "EdDSA", Crv: "Ed25519", X: jwks.Keys[0].X}, jwks.Keys[0])
+	assert.Equal(t, "RSA", jwks.Keys[1].Kty)
+	assert.Equal(t, "RS256", jwks.Keys[1].Alg)
+	assert.Equal(t, "AQAB", jwks.Keys[1].E)
+
+	// Once the old key is dropped its tokens are refused
+	final := t.TempDir()
+	writePrivateKey(t, final, "ed-2", newKey)
+	useKeySet(t, final, "ed-2")
+	_, err = ValidateToken(oldToken)
+	assert.Error(t, err)
+}
+
+func TestToken_RejectsHS256OnceKeysAreLoaded(t *testing.T) {
+	withRestConfig(t, config.Rest{JWTSecret: "secret"})
+	hmacToken := newToken(t)
+
+	_, private, _ := ed25519.GenerateKey(rand.Reader)
+	dir := t.TempDir()
+	writePrivateKey(t, dir, "ed-1", private)
+	useKeySet(t, dir, "ed-1")
+
+	_, err := ValidateToken(hmacToken)
+	assert.Error(t, err)
+
+	config.APP.Rest.JWTAcceptHS256 = true
+	_, err = ValidateToken(hmacToken)
+	assert.NoError(t, err)
+}
+
+func TestToken_ValidatesIssuerAndAudience(t *testing.T) {
+	withRestConfig(t, config.Rest{JWTSecret: "secret", JWTIssuer: "issuer", JWTAudience: "api"})
+	token := newToken(t)
+
+	config.APP.Rest.JWTIssuer = "someone-else"
+	_, err := ValidateToken(token)
+	assert.Error(t, err)
+
+	config.APP.Rest.JWTIssuer = "issuer"
+	config.APP.Rest.JWTAudience = "other-api"
+	_, err = ValidateToken(token)
+	assert.Error(t, err)
+
+	config.APP.Rest.JWTAudience = "api"
+	_, err = ValidateToken(token)
+	assert.NoError(t, err)
+}
+
+func TestLoadKeySet_Refusals(t *testing.T) {
+	_, private, _ := ed25519.GenerateKey(rand.Reader)
+	dir := t.TempDir()
+	writePublicKey(t, dir, "public-only", private.Public())
+
+	_, err := LoadKeySet(dir, "public-only")
+	assert.Error(t, err, "a public key cannot sign")
+
+	_, err = LoadKeySet(dir, "missing")
+	assert.Error(t, err)
+
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	writePrivateKey(t, dir, "weak", weak)
+	_, err = LoadKeySet(dir, "weak")
+	assert.Error(t, err)
+}
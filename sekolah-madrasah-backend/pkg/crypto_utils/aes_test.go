@@ -0,0 +1,20 @@
+package crypto_utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	sealed, err := Encrypt([]byte("JBSWY3DPEHPK3PXP"), "default-aes-key-32-chars-long!!")
+	assert.NoError(t, err)
+	assert.NotContains(t, sealed, "JBSWY3DPEHPK3PXP")
+
+	plain, err := Decrypt(sealed, "default-aes-key-32-chars-long!!")
+	assert.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", string(plain))
+
+	_, err = Decrypt(sealed, "another-key")
+	assert.Error(t, err)
+}
@@ -0,0 +1,121 @@
+package http_middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sekolah-madrasah/pkg/auth_utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeApiKeys map[string]*auth_utils.AuthClaim
+
+func (f fakeApiKeys) AuthenticateApiKey(ctx context.Context, rawKey, ipAddress string) (*auth_utils.AuthClaim, int, error) {
+	claims, ok := f[rawKey]
+	if !ok {
+		return nil, http.StatusUnauthorized, errors.New("invalid API key")
+	}
+	return claims, http.StatusOK, nil
+}
+
+func withApiKeys(t *testing.T, keys fakeApiKeys) {
+	previous := apiKeyAuthenticator
+	SetApiKeyAuthenticator(keys)
+	t.Cleanup(func() { SetApiKeyAuthenticator(previous) })
+}
+
+func performWithApiKey(pattern, path, apiKey string, handlers ...gin.HandlerFunc) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	chain := append([]gin.HandlerFunc{Authentication}, handlers...)
+	chain = append(chain, func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	router.GET(pattern, chain...)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	if apiKey != "" {
+		req.Header.Set(ApiKeyHeader, apiKey)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func apiKeySetup(t *testing.T) (orgId, unitId, otherUnitId uuid.UUID) {
+	orgId, unitId, otherUnitId = uuid.New(), uuid.New(), uuid.New()
+	withResolver(t, &fakeResolver{orgs: map[uuid.UUID]uuid.UUID{unitId: orgId, otherUnitId: uuid.New()}})
+	withApiKeys(t, fakeApiKeys{
+		"smk_valid": {
+			UserID:         uuid.New(),
+			TokenType:      auth_utils.TokenTypeApiKey,
+			ApiKeyID:       uuid.New(),
+			OrganizationID: orgId,
+			Scopes:         []string{"students.list"},
+		},
+	})
+	return orgId, unitId, otherUnitId
+}
+
+func TestApiKey_ScopeAllowedInOwnOrganization(t *testing.T) {
+	_, unitId, _ := apiKeySetup(t)
+
+	w := performWithApiKey("/units/:id/students", "/units/"+unitId.String()+"/students", "smk_valid",
+		RequireUnitAccess, RequirePermission("students.list"))
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestApiKey_MissingScopeForbidden(t *testing.T) {
+	_, unitId, _ := apiKeySetup(t)
+
+	w := performWithApiKey("/units/:id/students", "/units/"+unitId.String()+"/students", "smk_valid",
+		RequireUnitAccess, RequirePermission("students.create"))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestApiKey_OtherOrganizationForbidden(t *testing.T) {
+	_, _, otherUnitId := apiKeySetup(t)
+
+	w := performWithApiKey("/units/:id/students", "/units/"+otherUnitId.String()+"/students", "smk_valid",
+		RequireUnitAccess, RequirePermission("students.list"))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestApiKey_ScopeIgnoredOutsideUnit(t *testing.T) {
+	apiKeySetup(t)
+
+	w := performWithApiKey("/students", "/students", "smk_valid", RequirePermission("students.list"))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestApiKey_InvalidKeyRejected(t *testing.T) {
+	_, unitId, _ := apiKeySetup(t)
+
+	w := performWithApiKey("/units/:id", "/units/"+unitId.String(), "smk_unknown", RequireUnitAccess)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestApiKey_NeverSuperAdmin(t *testing.T) {
+	apiKeySetup(t)
+
+	w := performWithApiKey("/admin", "/admin", "smk_valid", RequireSuperAdmin)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAuthentication_FallsBackToJWT(t *testing.T) {
+	apiKeySetup(t)
+
+	w := performWithApiKey("/me", "/me", "")
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -1,6 +1,7 @@
 package http_middleware
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,6 +14,7 @@ import (
 	"sekolah-madrasah/pkg/request_utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/labstack/gommon/log"
 	"go.elastic.co/apm/v2"
 )
@@ -35,6 +37,46 @@ func CORS(c *gin.Context) {
 	c.Next()
 }
 
+// TokenDenylist reports whether an access token was revoked before its expiry.
+type TokenDenylist interface {
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+var tokenDenylist TokenDenylist
+
+func SetTokenDenylist(denylist TokenDenylist) {
+	tokenDenylist = denylist
+}
+
+// SessionTracker records activity on the session an access token belongs to.
+type SessionTracker interface {
+	TouchSession(ctx context.Context, sessionID uuid.UUID, ipAddress string)
+}
+
+var sessionTracker SessionTracker
+
+func SetSessionTracker(tracker SessionTracker) {
+	sessionTracker = tracker
+}
+
+// passwordChangeRoutes are the only routes a token flagged with MustChangePassword may call.
+var passwordChangeRoutes = map[string]bool{
+	http.MethodPut + " /api/v1/users/me/password": true,
+	http.MethodPost + " /api/v1/auth/logout":      true,
+	http.MethodPost + " /api/v1/auth/logout-all":  true,
+}
+
+// twoFactorSetupRoutes are the only routes a token flagged with TwoFactorSetup may call.
+// The password route stays open so a token carrying both flags can clear them in order.
+var twoFactorSetupRoutes = map[string]bool{
+	http.MethodGet + " /api/v1/users/me/2fa":         true,
+	http.MethodPost + " /api/v1/users/me/2fa/setup":  true,
+	http.MethodPost + " /api/v1/users/me/2fa/enable": true,
+	http.MethodPut + " /api/v1/users/me/password":    true,
+	http.MethodPost + " /api/v1/auth/logout":         true,
+	http.MethodPost + " /api/v1/auth/logout-all":     true,
+}
+
 func JWTAuthentication(c *gin.Context) {
 	tokenString := c.GetHeader("Authorization")
 	if tokenString == "" {
@@ -56,6 +98,49 @@ func JWTAuthentication(c *gin.Context) {
 		c.Abort()
 		return
 	}
+	if claims.TokenType != auth_utils.TokenTypeAccess {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "access token required"})
+		c.Abort()
+		return
+	}
+	if tokenDenylist != nil {
+		revoked, err := tokenDenylist.IsAccessTokenRevoked(c.Request.Context(), claims.Id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify token"})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "token has been revoked"})
+			c.Abort()
+			return
+		}
+	}
+
+	if claims.IsImpersonated() {
+		// Deferred so refused requests are recorded too
+		defer recordImpersonatedRequest(c, claims)
+	}
+
+	route := c.Request.Method + " " + c.FullPath()
+	if claims.MustChangePassword && !passwordChangeRoutes[route] {
+		abortForbidden(c, "password change required")
+		return
+	}
+	if claims.TwoFactorSetup && !twoFactorSetupRoutes[route] {
+		abortForbidden(c, "two-factor enrollment required")
+		return
+	}
+	if claims.IsImpersonated() {
+		if reason := impersonationDenied(claims, c.Request.Method, route); reason != "" {
+			abortForbidden(c, reason)
+			return
+		}
+	}
+
+	if sessionTracker != nil {
+		sessionTracker.TouchSession(c.Request.Context(), claims.SessionID, c.ClientIP())
+	}
 
 	ctx := auth_utils.WithAuthClaim(c.Request.Context(), claims)
 	c.Request = c.Request.WithContext(ctx)
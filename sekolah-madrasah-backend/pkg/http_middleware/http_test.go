@@ -0,0 +1,89 @@
+package http_middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"sekolah-madrasah/pkg/auth_utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDenylist map[string]bool
+
+func (f fakeDenylist) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return f[jti], nil
+}
+
+func withDenylist(t *testing.T, denylist TokenDenylist) {
+	previous := tokenDenylist
+	SetTokenDenylist(denylist)
+	t.Cleanup(func() { SetTokenDenylist(previous) })
+}
+
+func performAuthenticated(token string) *httptest.ResponseRecorder {
+	return performAuthenticatedAt(http.MethodGet, "/me", token)
+}
+
+func performAuthenticatedAt(method, path, token string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	router.GET("/me", JWTAuthentication, handler)
+	router.PUT("/api/v1/users/me/password", JWTAuthentication, handler)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestJWTAuthentication_AcceptsAccessToken(t *testing.T) {
+	withDenylist(t, fakeDenylist{})
+	token, _ := auth_utils.GenerateToken(auth_utils.TokenParams{UserID: uuid.New(), TokenType: auth_utils.TokenTypeAccess}, time.Hour)
+
+	w := performAuthenticated(token)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestJWTAuthentication_RejectsRefreshToken(t *testing.T) {
+	withDenylist(t, fakeDenylist{})
+	token, _ := auth_utils.GenerateToken(auth_utils.TokenParams{UserID: uuid.New(), TokenType: auth_utils.TokenTypeRefresh}, time.Hour)
+
+	w := performAuthenticated(token)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestJWTAuthentication_RejectsRevokedToken(t *testing.T) {
+	jti := uuid.New()
+	withDenylist(t, fakeDenylist{jti.String(): true})
+	token, _ := auth_utils.GenerateToken(auth_utils.TokenParams{UserID: uuid.New(), TokenID: jti}, time.Hour)
+
+	w := performAuthenticated(token)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestJWTAuthentication_MustChangePasswordRestrictsRoutes(t *testing.T) {
+	withDenylist(t, fakeDenylist{})
+	token, _ := auth_utils.GenerateToken(auth_utils.TokenParams{UserID: uuid.New(), MustChangePassword: true}, time.Hour)
+
+	assert.Equal(t, http.StatusForbidden, performAuthenticatedAt(http.MethodGet, "/me", token).Code)
+	assert.Equal(t, http.StatusNoContent, performAuthenticatedAt(http.MethodPut, "/api/v1/users/me/password", token).Code)
+}
+
+func TestJWTAuthentication_TwoFactorSetupRestrictsRoutes(t *testing.T) {
+	withDenylist(t, fakeDenylist{})
+	token, _ := auth_utils.GenerateToken(auth_utils.TokenParams{UserID: uuid.New(), TwoFactorSetup: true}, time.Hour)
+
+	assert.Equal(t, http.StatusForbidden, performAuthenticatedAt(http.MethodGet, "/me", token).Code)
+	assert.Equal(t, http.StatusNoContent, performAuthenticatedAt(http.MethodPut, "/api/v1/users/me/password", token).Code)
+}
@@ -0,0 +1,66 @@
+package http_middleware
+
+import (
+	"context"
+	"net/http"
+
+	"sekolah-madrasah/pkg/auth_utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImpersonationAuditor records every request made with an impersonation token.
+type ImpersonationAuditor interface {
+	RecordImpersonatedRequest(ctx context.Context, claims auth_utils.AuthClaim, method, path string, status int, ipAddress string)
+}
+
+var impersonationAuditor ImpersonationAuditor
+
+func SetImpersonationAuditor(auditor ImpersonationAuditor) {
+	impersonationAuditor = auditor
+}
+
+// impersonationBlockedRoutes stay closed to impersonation tokens even when writes
+// are allowed: they change the user's credentials or hand out access that would
+// outlive the impersonation.
+var impersonationBlockedRoutes = map[string]bool{
+	http.MethodPut + " /api/v1/users/me/password":                    true,
+	http.MethodPost + " /api/v1/users/me/2fa/setup":                  true,
+	http.MethodPost + " /api/v1/users/me/2fa/enable":                 true,
+	http.MethodPost + " /api/v1/users/me/2fa/disable":                true,
+	http.MethodPost + " /api/v1/users/me/2fa/recovery-codes":         true,
+	http.MethodDelete + " /api/v1/users/me/sessions/:id":             true,
+	http.MethodPost + " /api/v1/auth/logout-all":                     true,
+	http.MethodPost + " /api/v1/users/:id/impersonate":               true,
+	http.MethodPost + " /api/v1/organizations/:id/api-keys":          true,
+	http.MethodDelete + " /api/v1/organizations/:id/api-keys/:keyId": true,
+}
+
+// impersonationReadOnlyRoutes are the state-changing routes a read-only
+// impersonation token may still call.
+var impersonationReadOnlyRoutes = map[string]bool{
+	http.MethodPost + " /api/v1/auth/logout": true,
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// impersonationDenied returns why claims may not call route, or "" when they may.
+func impersonationDenied(claims *auth_utils.AuthClaim, method, route string) string {
+	if impersonationBlockedRoutes[route] {
+		return "not available while impersonating"
+	}
+	if isSafeMethod(method) || impersonationReadOnlyRoutes[route] || claims.ImpersonationWrites {
+		return ""
+	}
+	return "impersonation is read-only"
+}
+
+func recordImpersonatedRequest(c *gin.Context, claims *auth_utils.AuthClaim) {
+	if impersonationAuditor == nil {
+		return
+	}
+	impersonationAuditor.RecordImpersonatedRequest(context.WithoutCancel(c.Request.Context()), *claims,
+		c.Request.Method, c.Request.URL.Path, c.Writer.Status(), c.ClientIP())
+}
@@ -0,0 +1,107 @@
+package http_middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"sekolah-madrasah/pkg/auth_utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type impersonatedRequest struct {
+	actor  uuid.UUID
+	user   uuid.UUID
+	route  string
+	status int
+}
+
+type fakeImpersonationAuditor struct {
+	requests []impersonatedRequest
+}
+
+func (f *fakeImpersonationAuditor) RecordImpersonatedRequest(ctx context.Context, claims auth_utils.AuthClaim, method, path string, status int, ipAddress string) {
+	f.requests = append(f.requests, impersonatedRequest{actor: *claims.ImpersonatorID, user: claims.UserID, route: method + " " + path, status: status})
+}
+
+func withImpersonationAuditor(t *testing.T) *fakeImpersonationAuditor {
+	auditor := &fakeImpersonationAuditor{}
+	previous := impersonationAuditor
+	SetImpersonationAuditor(auditor)
+	t.Cleanup(func() { SetImpersonationAuditor(previous) })
+	return auditor
+}
+
+func performImpersonated(method, path, token string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	router.GET("/api/v1/users/me", JWTAuthentication, handler)
+	router.PUT("/api/v1/users/:id", JWTAuthentication, handler)
+	router.PUT("/api/v1/users/me/password", JWTAuthentication, handler)
+	router.POST("/api/v1/auth/logout", JWTAuthentication, handler)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func impersonationToken(t *testing.T, actor, user uuid.UUID, allowWrites bool) string {
+	token, err := auth_utils.GenerateToken(auth_utils.TokenParams{
+		UserID:              user,
+		ImpersonatorID:      &actor,
+		ImpersonationWrites: allowWrites,
+	}, time.Hour)
+	assert.NoError(t, err)
+	return token
+}
+
+func TestJWTAuthentication_ImpersonationIsReadOnlyByDefault(t *testing.T) {
+	withDenylist(t, fakeDenylist{})
+	withImpersonationAuditor(t)
+	token := impersonationToken(t, uuid.New(), uuid.New(), false)
+
+	assert.Equal(t, http.StatusNoContent, performImpersonated(http.MethodGet, "/api/v1/users/me", token).Code)
+	assert.Equal(t, http.StatusForbidden, performImpersonated(http.MethodPut, "/api/v1/users/"+uuid.NewString(), token).Code)
+	assert.Equal(t, http.StatusNoContent, performImpersonated(http.MethodPost, "/api/v1/auth/logout", token).Code)
+}
+
+func TestJWTAuthentication_ImpersonationWritesKeepCredentialsClosed(t *testing.T) {
+	withDenylist(t, fakeDenylist{})
+	withImpersonationAuditor(t)
+	token := impersonationToken(t, uuid.New(), uuid.New(), true)
+
+	assert.Equal(t, http.StatusNoContent, performImpersonated(http.MethodPut, "/api/v1/users/"+uuid.NewString(), token).Code)
+	assert.Equal(t, http.StatusForbidden, performImpersonated(http.MethodPut, "/api/v1/users/me/password", token).Code)
+}
+
+func TestJWTAuthentication_RecordsImpersonatedRequests(t *testing.T) {
+	withDenylist(t, fakeDenylist{})
+	auditor := withImpersonationAuditor(t)
+	actor, user := uuid.New(), uuid.New()
+	token := impersonationToken(t, actor, user, false)
+
+	performImpersonated(http.MethodGet, "/api/v1/users/me", token)
+	performImpersonated(http.MethodPut, "/api/v1/users/me/password", token)
+
+	assert.Equal(t, []impersonatedRequest{
+		{actor: actor, user: user, route: "GET /api/v1/users/me", status: http.StatusNoContent},
+		{actor: actor, user: user, route: "PUT /api/v1/users/me/password", status: http.StatusForbidden},
+	}, auditor.requests)
+}
+
+func TestJWTAuthentication_OrdinaryTokensAreNotRecorded(t *testing.T) {
+	withDenylist(t, fakeDenylist{})
+	auditor := withImpersonationAuditor(t)
+	token, _ := auth_utils.GenerateToken(auth_utils.TokenParams{UserID: uuid.New()}, time.Hour)
+
+	assert.Equal(t, http.StatusNoContent, performImpersonated(http.MethodPut, "/api/v1/users/"+uuid.NewString(), token).Code)
+	assert.Empty(t, auditor.requests)
+}
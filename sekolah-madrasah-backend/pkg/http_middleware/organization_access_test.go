@@ -0,0 +1,165 @@
+package http_middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sekolah-madrasah/pkg/auth_utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeOrganizations struct {
+	owners  map[uuid.UUID]uuid.UUID
+	members map[uuid.UUID][]uuid.UUID
+}
+
+func (f *fakeOrganizations) ResolveOrganizationOwner(ctx context.Context, organizationId uuid.UUID) (uuid.UUID, int, error) {
+	ownerId, ok := f.owners[organizationId]
+	if !ok {
+		return uuid.Nil, http.StatusNotFound, errors.New("organization not found")
+	}
+	return ownerId, http.StatusOK, nil
+}
+
+func (f *fakeOrganizations) ResolveUserOrganizations(ctx context.Context, userId uuid.UUID) ([]uuid.UUID, int, error) {
+	return f.members[userId], http.StatusOK, nil
+}
+
+func withOrganizations(t *testing.T, resolver OrganizationAccessResolver) {
+	previous := organizationAccessResolver
+	SetOrganizationAccessResolver(resolver)
+	t.Cleanup(func() { SetOrganizationAccessResolver(previous) })
+}
+
+func performOrganizationRequest(claims *auth_utils.AuthClaim, organizationId uuid.UUID, handlers ...gin.HandlerFunc) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	chain := []gin.HandlerFunc{func(c *gin.Context) {
+		c.Set("auth", claims)
+		c.Next()
+	}}
+	chain = append(chain, handlers...)
+	chain = append(chain, func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	router.PUT("/organizations/:id", chain...)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/organizations/"+organizationId.String(), nil)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRequireOrganizationAccess_AdminOfOtherOrganizationForbidden(t *testing.T) {
+	userID, orgA, orgB := uuid.New(), uuid.New(), uuid.New()
+	withOrganizations(t, &fakeOrganizations{owners: map[uuid.UUID]uuid.UUID{orgA: uuid.New(), orgB: uuid.New()}})
+	withChecker(t, &fakeChecker{
+		granted: map[string][]string{userID.String(): {"organizations.update"}},
+		organizations: map[uuid.UUID]map[string][]string{
+			orgA: {userID.String(): {"organizations.update"}},
+			orgB: {userID.String(): {"organizations.read"}},
+		},
+	})
+	claims := &auth_utils.AuthClaim{UserID: userID}
+
+	w := performOrganizationRequest(claims, orgB, RequireOrganizationAccess, RequirePermission("organizations.update"))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performOrganizationRequest(claims, orgA, RequireOrganizationAccess, RequirePermission("organizations.update"))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestRequireOrganizationAccess_OwnerAllowed(t *testing.T) {
+	ownerID, orgId := uuid.New(), uuid.New()
+	withOrganizations(t, &fakeOrganizations{owners: map[uuid.UUID]uuid.UUID{orgId: ownerID}})
+	withChecker(t, &fakeChecker{})
+
+	w := performOrganizationRequest(&auth_utils.AuthClaim{UserID: ownerID}, orgId, RequireOrganizationAccess, RequirePermission("organizations.delete"))
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestRequireOrganizationAccess_UnknownOrganization(t *testing.T) {
+	withOrganizations(t, &fakeOrganizations{})
+	withChecker(t, &fakeChecker{})
+
+	w := performOrganizationRequest(&auth_utils.AuthClaim{UserID: uuid.New()}, uuid.New(), RequireOrganizationAccess, RequirePermission("organizations.read"))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRequireOrganizationAccess_ApiKeyOfOtherOrganizationForbidden(t *testing.T) {
+	withOrganizations(t, &fakeOrganizations{})
+	claims := &auth_utils.AuthClaim{
+		UserID:         uuid.New(),
+		TokenType:      auth_utils.TokenTypeApiKey,
+		OrganizationID: uuid.New(),
+		Scopes:         []string{"organizations.read"},
+	}
+
+	w := performOrganizationRequest(claims, uuid.New(), RequireOrganizationAccess, RequirePermission("organizations.read"))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func performUserRequest(userID, targetID uuid.UUID, handlers ...gin.HandlerFunc) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	chain := []gin.HandlerFunc{func(c *gin.Context) {
+		c.Set("auth", &auth_utils.AuthClaim{UserID: userID})
+		c.Next()
+	}}
+	chain = append(chain, handlers...)
+	chain = append(chain, func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	router.POST("/users/:id/unlock", chain...)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/users/"+targetID.String()+"/unlock", nil)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRequireUserAdmin_OnlyAdminsOfTheUsersOrganization(t *testing.T) {
+	adminID, ownerID, targetID, orgA, orgB := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	withOrganizations(t, &fakeOrganizations{
+		owners:  map[uuid.UUID]uuid.UUID{orgA: uuid.New(), orgB: ownerID},
+		members: map[uuid.UUID][]uuid.UUID{targetID: {orgB}},
+	})
+	withChecker(t, &fakeChecker{
+		granted:       map[string][]string{adminID.String(): {"users.update"}},
+		organizations: map[uuid.UUID]map[string][]string{orgA: {adminID.String(): {"users.update"}}},
+	})
+
+	w := performUserRequest(adminID, targetID, RequireUserAdmin("users.update"))
+	assert.Equal(t, http.StatusForbidden, w.Code, "admin of another organization")
+
+	w = performUserRequest(ownerID, targetID, RequireUserAdmin("users.update"))
+	assert.Equal(t, http.StatusNoContent, w.Code, "owner of the user's organization")
+}
+
+func TestRequireUserAdmin_SuperAdmin(t *testing.T) {
+	adminID := uuid.New()
+	withOrganizations(t, &fakeOrganizations{})
+	withChecker(t, &fakeChecker{superAdmins: map[string]bool{adminID.String(): true}})
+
+	w := performUserRequest(adminID, uuid.New(), RequireUserAdmin("users.update"))
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestRequireUserAdmin_SuperAdminTargetOnlyForSuperAdmins(t *testing.T) {
+	ownerID, targetID, orgId := uuid.New(), uuid.New(), uuid.New()
+	withOrganizations(t, &fakeOrganizations{
+		owners:  map[uuid.UUID]uuid.UUID{orgId: ownerID},
+		members: map[uuid.UUID][]uuid.UUID{targetID: {orgId}},
+	})
+	withChecker(t, &fakeChecker{superAdmins: map[string]bool{targetID.String(): true}})
+
+	w := performUserRequest(ownerID, targetID, RequireUserAdmin("users.delete"))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
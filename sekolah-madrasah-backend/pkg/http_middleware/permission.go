@@ -4,12 +4,15 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"sekolah-madrasah/pkg/auth_utils"
 )
 
 type PermissionChecker interface {
 	HasPermission(userID string, permission string) bool
+	HasOrganizationPermission(userID string, organizationId uuid.UUID, permission string) bool
 	GetUserPermissions(userID string) []string
+	IsSuperAdmin(userID string) bool
 }
 
 var permissionChecker PermissionChecker
@@ -18,39 +21,72 @@ func SetPermissionChecker(checker PermissionChecker) {
 	permissionChecker = checker
 }
 
+// ForbiddenResponse is the body of every 403 produced by the authorization middlewares.
+type ForbiddenResponse struct {
+	Error    string   `json:"error"`
+	Required []string `json:"required,omitempty"`
+}
+
+func abortForbidden(c *gin.Context, message string, required ...string) {
+	c.AbortWithStatusJSON(http.StatusForbidden, ForbiddenResponse{
+		Error:    message,
+		Required: required,
+	})
+}
+
+// authClaimFromContext returns the claims stored by JWTAuthentication, aborting with 401 when absent.
+func authClaimFromContext(c *gin.Context) (*auth_utils.AuthClaim, bool) {
+	authData, exists := c.Get("auth")
+	if !exists {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return nil, false
+	}
+
+	claims, ok := authData.(*auth_utils.AuthClaim)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid auth claims"})
+		return nil, false
+	}
+	return claims, true
+}
+
+// hasPermission checks the unit role resolved for the request on unit-scoped
+// routes, the caller's role in the organization on organization-scoped routes
+// and their organization-level permissions everywhere else. Roles held in
+// other organizations never grant anything inside a unit or organization.
+// A missing checker never grants access. API keys are limited to their scopes.
+func hasPermission(c *gin.Context, claims *auth_utils.AuthClaim, permission string) bool {
+	if claims.IsApiKey() {
+		return apiKeyGrants(c, claims, permission)
+	}
+	if _, scoped := c.Get("unit_id"); scoped {
+		return unitRoleGrants(c, permission)
+	}
+	if permissionChecker == nil {
+		return false
+	}
+	if granted, scoped := organizationGrants(c, claims, permission); scoped {
+		return granted
+	}
+	return permissionChecker.HasPermission(claims.UserID.String(), permission)
+}
+
+// RequirePermission allows the request when the caller holds at least one of the given permissions.
 func RequirePermission(permissions ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authData, exists := c.Get("auth")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
-			c.Abort()
-			return
-		}
-
-		claims, ok := authData.(*auth_utils.AuthClaim)
+		claims, ok := authClaimFromContext(c)
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid auth claims"})
-			c.Abort()
-			return
-		}
-
-		if permissionChecker == nil {
-			c.Next()
 			return
 		}
 
 		for _, perm := range permissions {
-			if permissionChecker.HasPermission(claims.UserID.String(), perm) {
+			if hasPermission(c, claims, perm) {
 				c.Next()
 				return
 			}
 		}
 
-		c.JSON(http.StatusForbidden, gin.H{
-			"error":    "insufficient permissions",
-			"required": permissions,
-		})
-		c.Abort()
+		abortForbidden(c, "insufficient permissions", permissions...)
 	}
 }
 
@@ -58,34 +94,17 @@ func RequireAnyPermission(permissions ...string) gin.HandlerFunc {
 	return RequirePermission(permissions...)
 }
 
+// RequireAllPermissions allows the request only when the caller holds every given permission.
 func RequireAllPermissions(permissions ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authData, exists := c.Get("auth")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
-			c.Abort()
-			return
-		}
-
-		claims, ok := authData.(*auth_utils.AuthClaim)
+		claims, ok := authClaimFromContext(c)
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid auth claims"})
-			c.Abort()
-			return
-		}
-
-		if permissionChecker == nil {
-			c.Next()
 			return
 		}
 
 		for _, perm := range permissions {
-			if !permissionChecker.HasPermission(claims.UserID.String(), perm) {
-				c.JSON(http.StatusForbidden, gin.H{
-					"error":   "insufficient permissions",
-					"missing": perm,
-				})
-				c.Abort()
+			if !hasPermission(c, claims, perm) {
+				abortForbidden(c, "insufficient permissions", permissions...)
 				return
 			}
 		}
@@ -94,6 +113,21 @@ func RequireAllPermissions(permissions ...string) gin.HandlerFunc {
 	}
 }
 
+// RequireSuperAdmin allows only platform super admins through.
+func RequireSuperAdmin(c *gin.Context) {
+	claims, ok := authClaimFromContext(c)
+	if !ok {
+		return
+	}
+
+	if claims.IsApiKey() || permissionChecker == nil || !permissionChecker.IsSuperAdmin(claims.UserID.String()) {
+		abortForbidden(c, "super admin access required")
+		return
+	}
+
+	c.Next()
+}
+
 type RoleLevel int
 
 const (
@@ -106,28 +140,14 @@ const (
 
 func RequireRoleLevel(minLevel RoleLevel) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authData, exists := c.Get("auth")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
-			c.Abort()
-			return
-		}
-
-		claims, ok := authData.(*auth_utils.AuthClaim)
+		claims, ok := authClaimFromContext(c)
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid auth claims"})
-			c.Abort()
 			return
 		}
 
 		userLevel := getRoleLevelFromClaim(claims)
 		if userLevel < minLevel {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error":         "insufficient role level",
-				"required":      minLevel,
-				"current_level": userLevel,
-			})
-			c.Abort()
+			abortForbidden(c, "insufficient role level")
 			return
 		}
 
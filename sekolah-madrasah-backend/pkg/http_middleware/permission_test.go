@@ -0,0 +1,150 @@
+package http_middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sekolah-madrasah/pkg/auth_utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeChecker struct {
+	granted       map[string][]string
+	superAdmins   map[string]bool
+	organizations map[uuid.UUID]map[string][]string
+}
+
+func (f *fakeChecker) HasPermission(userID string, permission string) bool {
+	for _, p := range f.granted[userID] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeChecker) HasOrganizationPermission(userID string, organizationId uuid.UUID, permission string) bool {
+	for _, p := range f.organizations[organizationId][userID] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeChecker) GetUserPermissions(userID string) []string {
+	return f.granted[userID]
+}
+
+func (f *fakeChecker) IsSuperAdmin(userID string) bool {
+	return f.superAdmins[userID]
+}
+
+func withChecker(t *testing.T, checker PermissionChecker) {
+	previous := permissionChecker
+	SetPermissionChecker(checker)
+	t.Cleanup(func() { SetPermissionChecker(previous) })
+}
+
+func performRequest(userID *uuid.UUID, handlers ...gin.HandlerFunc) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	chain := []gin.HandlerFunc{func(c *gin.Context) {
+		if userID != nil {
+			c.Set("auth", &auth_utils.AuthClaim{UserID: *userID})
+		}
+		c.Next()
+	}}
+	chain = append(chain, handlers...)
+	chain = append(chain, func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	router.DELETE("/resource", chain...)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/resource", nil)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRequirePermission_Granted(t *testing.T) {
+	userID := uuid.New()
+	withChecker(t, &fakeChecker{granted: map[string][]string{userID.String(): {"units.delete"}}})
+
+	w := performRequest(&userID, RequirePermission("units.delete"))
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestRequirePermission_AnyOfSeveral(t *testing.T) {
+	userID := uuid.New()
+	withChecker(t, &fakeChecker{granted: map[string][]string{userID.String(): {"posts.update"}}})
+
+	w := performRequest(&userID, RequirePermission("posts.delete", "posts.update"))
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestRequirePermission_Forbidden(t *testing.T) {
+	userID := uuid.New()
+	withChecker(t, &fakeChecker{granted: map[string][]string{userID.String(): {"units.read"}}})
+
+	w := performRequest(&userID, RequirePermission("units.delete"))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var body ForbiddenResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "insufficient permissions", body.Error)
+	assert.Equal(t, []string{"units.delete"}, body.Required)
+}
+
+func TestRequirePermission_NoCheckerFailsClosed(t *testing.T) {
+	withChecker(t, nil)
+	userID := uuid.New()
+
+	w := performRequest(&userID, RequirePermission("users.delete"))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRequirePermission_Unauthenticated(t *testing.T) {
+	withChecker(t, &fakeChecker{})
+
+	w := performRequest(nil, RequirePermission("users.delete"))
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRequireAllPermissions_MissingOne(t *testing.T) {
+	userID := uuid.New()
+	withChecker(t, &fakeChecker{granted: map[string][]string{userID.String(): {"roles.update"}}})
+
+	w := performRequest(&userID, RequireAllPermissions("roles.update", "permissions.update"))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var body ForbiddenResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, []string{"roles.update", "permissions.update"}, body.Required)
+}
+
+func TestRequireRoleLevel_UsesSameForbiddenShape(t *testing.T) {
+	userID := uuid.New()
+
+	w := performRequest(&userID, RequireRoleLevel(RoleLevelAdmin))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var body ForbiddenResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "insufficient role level", body.Error)
+}
+
+func TestRequireSuperAdmin(t *testing.T) {
+	admin, member := uuid.New(), uuid.New()
+	withChecker(t, &fakeChecker{superAdmins: map[string]bool{admin.String(): true}})
+
+	assert.Equal(t, http.StatusNoContent, performRequest(&admin, RequireSuperAdmin).Code)
+	assert.Equal(t, http.StatusForbidden, performRequest(&member, RequireSuperAdmin).Code)
+}
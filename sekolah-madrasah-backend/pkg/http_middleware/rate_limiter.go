@@ -1,93 +1,172 @@
 package http_middleware
 
 import (
+	"context"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/labstack/gommon/log"
 )
 
-type RateLimiter struct {
-	requests map[string][]time.Time
-	mu       sync.RWMutex
-	limit    int
-	window   time.Duration
+// RateLimitStore counts requests per key. A store shared between replicas makes
+// every instance enforce the same limit.
+type RateLimitStore interface {
+	// Take records one request for key and reports whether it fits within limit
+	// requests per window.
+	Take(ctx context.Context, key string, limit int, window time.Duration) (RateLimitResult, error)
 }
 
-func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
-	rl := &RateLimiter{
-		requests: make(map[string][]time.Time),
-		limit:    limit,
-		window:   window,
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the window frees up capacity again
+	Reset time.Duration
+}
+
+var (
+	rateLimitStore     RateLimitStore
+	rateLimitStoreOnce sync.Once
+)
+
+// SetRateLimitStore replaces the in-memory store. Call it before serving requests.
+func SetRateLimitStore(store RateLimitStore) {
+	rateLimitStoreOnce.Do(func() {})
+	rateLimitStore = store
+}
+
+func currentRateLimitStore() RateLimitStore {
+	rateLimitStoreOnce.Do(func() {
+		rateLimitStore = NewMemoryRateLimitStore()
+	})
+	return rateLimitStore
+}
+
+// MemoryRateLimitStore keeps a sliding log of request times per key in process
+// memory. Counts are lost on restart and are not shared between replicas.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryRateLimitEntry
+}
+
+type memoryRateLimitEntry struct {
+	times  []time.Time
+	window time.Duration
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	s := &MemoryRateLimitStore{
+		entries: make(map[string]*memoryRateLimitEntry),
 	}
 
-	go rl.cleanup()
+	go s.cleanup(time.Minute)
 
-	return rl
+	return s
 }
 
-func (rl *RateLimiter) cleanup() {
-	ticker := time.NewTicker(rl.window)
+func (s *MemoryRateLimitStore) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
 	for range ticker.C {
-		rl.mu.Lock()
+		s.mu.Lock()
 		now := time.Now()
-		for key, times := range rl.requests {
-			var valid []time.Time
-			for _, t := range times {
-				if now.Sub(t) < rl.window {
-					valid = append(valid, t)
-				}
-			}
-			if len(valid) == 0 {
-				delete(rl.requests, key)
-			} else {
-				rl.requests[key] = valid
+		for key, entry := range s.entries {
+			if len(entry.times) == 0 || now.Sub(entry.times[len(entry.times)-1]) >= entry.window {
+				delete(s.entries, key)
 			}
 		}
-		rl.mu.Unlock()
+		s.mu.Unlock()
 	}
 }
 
-func (rl *RateLimiter) Allow(key string) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit int, window time.Duration) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	now := time.Now()
-	windowStart := now.Add(-rl.window)
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &memoryRateLimitEntry{}
+		s.entries[key] = entry
+	}
+	entry.window = window
 
-	var valid []time.Time
-	for _, t := range rl.requests[key] {
+	windowStart := now.Add(-window)
+	valid := entry.times[:0]
+	for _, t := range entry.times {
 		if t.After(windowStart) {
 			valid = append(valid, t)
 		}
 	}
+	entry.times = valid
 
-	if len(valid) >= rl.limit {
-		rl.requests[key] = valid
-		return false
+	result := RateLimitResult{Limit: limit}
+	if len(entry.times) >= limit {
+		result.Reset = entry.times[0].Add(window).Sub(now)
+		return result, nil
 	}
 
-	rl.requests[key] = append(valid, now)
-	return true
+	entry.times = append(entry.times, now)
+	result.Allowed = true
+	result.Remaining = limit - len(entry.times)
+	result.Reset = entry.times[0].Add(window).Sub(now)
+	return result, nil
 }
 
-func RateLimitMiddleware(limiter *RateLimiter) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		key := c.ClientIP()
+// rateLimitClientKey identifies the caller: the authenticated user when known,
+// otherwise the client IP.
+func rateLimitClientKey(c *gin.Context) string {
+	key := c.ClientIP()
 
-		if authData, exists := c.Get("auth"); exists {
-			if claims, ok := authData.(interface{ GetUserID() string }); ok {
-				key = claims.GetUserID()
-			}
+	if authData, exists := c.Get("auth"); exists {
+		if claims, ok := authData.(interface{ GetUserID() string }); ok {
+			key = claims.GetUserID()
 		}
+	}
+
+	return key
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// enforceRateLimit takes one request from the store and writes the RateLimit-*
+// headers. It aborts with 429 and Retry-After when the limit is exhausted.
+func enforceRateLimit(c *gin.Context, key string, limit int, window time.Duration) bool {
+	result, err := currentRateLimitStore().Take(c.Request.Context(), key, limit, window)
+	if err != nil {
+		// Fail open: an unavailable store should not take the whole API down with it
+		log.Errorf("rate limit store error for %s: %v", key, err)
+		return true
+	}
+
+	c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+	if !result.Allowed {
+		retryAfter := ceilSeconds(result.Reset)
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+			"error":       "rate limit exceeded",
+			"retry_after": retryAfter,
+		})
+		return false
+	}
 
-		if !limiter.Allow(key) {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error":       "rate limit exceeded",
-				"retry_after": limiter.window.Seconds(),
-			})
-			c.Abort()
+	return true
+}
+
+// rateLimitRoute limits each caller separately on every route it is mounted on.
+func rateLimitRoute(limit int, window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := "route:" + c.Request.Method + " " + c.FullPath() + ":" + rateLimitClientKey(c)
+		if !enforceRateLimit(c, key, limit, window) {
 			return
 		}
 
@@ -96,11 +175,33 @@ func RateLimitMiddleware(limiter *RateLimiter) gin.HandlerFunc {
 }
 
 func RateLimit(requestsPerMinute int) gin.HandlerFunc {
-	limiter := NewRateLimiter(requestsPerMinute, time.Minute)
-	return RateLimitMiddleware(limiter)
+	return rateLimitRoute(requestsPerMinute, time.Minute)
 }
 
 func RateLimitPerSecond(requestsPerSecond int) gin.HandlerFunc {
-	limiter := NewRateLimiter(requestsPerSecond, time.Second)
-	return RateLimitMiddleware(limiter)
+	return rateLimitRoute(requestsPerSecond, time.Second)
+}
+
+// RateLimitGroups limits each caller per route group, the first path segment
+// after prefix (e.g. "auth" in /api/v1/auth/login). Groups missing from
+// perMinute get defaultPerMinute; a limit of 0 leaves the group unlimited.
+func RateLimitGroups(prefix string, defaultPerMinute int, perMinute map[string]int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		group := strings.TrimPrefix(c.FullPath(), prefix)
+		group = strings.TrimPrefix(group, "/")
+		if i := strings.Index(group, "/"); i >= 0 {
+			group = group[:i]
+		}
+
+		limit, ok := perMinute[group]
+		if !ok {
+			limit = defaultPerMinute
+		}
+
+		if limit > 0 && !enforceRateLimit(c, "group:"+group+":"+rateLimitClientKey(c), limit, time.Minute) {
+			return
+		}
+
+		c.Next()
+	}
 }
@@ -0,0 +1,105 @@
+package http_middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func withRateLimitStore(t *testing.T, store RateLimitStore) {
+	previous := currentRateLimitStore()
+	SetRateLimitStore(store)
+	t.Cleanup(func() { SetRateLimitStore(previous) })
+}
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(ctx context.Context, key string, limit int, window time.Duration) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("database is down")
+}
+
+func performRateLimited(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestMemoryRateLimitStore_SlidingWindow(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		result, _ := store.Take(ctx, "k", 2, 50*time.Millisecond)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 1-i, result.Remaining)
+	}
+
+	result, _ := store.Take(ctx, "k", 2, 50*time.Millisecond)
+	assert.False(t, result.Allowed)
+	assert.Greater(t, result.Reset, time.Duration(0))
+
+	other, _ := store.Take(ctx, "other", 2, 50*time.Millisecond)
+	assert.True(t, other.Allowed, "keys are limited independently")
+
+	time.Sleep(60 * time.Millisecond)
+	result, _ = store.Take(ctx, "k", 2, 50*time.Millisecond)
+	assert.True(t, result.Allowed, "requests leave the window")
+}
+
+func TestRateLimit_SetsHeadersAndRetryAfter(t *testing.T) {
+	withRateLimitStore(t, NewMemoryRateLimitStore())
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/limited", RateLimit(1), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	w := performRateLimited(router, "/limited")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+
+	w = performRateLimited(router, "/limited")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+}
+
+func TestRateLimitGroups_UsesPerGroupLimits(t *testing.T) {
+	withRateLimitStore(t, NewMemoryRateLimitStore())
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	v1 := router.Group("/api/v1", RateLimitGroups("/api/v1", 5, map[string]int{"auth": 1, "public": 0}))
+	handler := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	v1.GET("/auth/login", handler)
+	v1.GET("/auth/refresh", handler)
+	v1.GET("/users", handler)
+	v1.GET("/public", handler)
+
+	assert.Equal(t, http.StatusNoContent, performRateLimited(router, "/api/v1/auth/login").Code)
+	assert.Equal(t, http.StatusTooManyRequests, performRateLimited(router, "/api/v1/auth/refresh").Code, "routes in a group share its limit")
+
+	w := performRateLimited(router, "/api/v1/users")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "5", w.Header().Get("RateLimit-Limit"), "groups without an entry get the default")
+
+	w = performRateLimited(router, "/api/v1/public")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Header().Get("RateLimit-Limit"), "a zero limit disables limiting")
+}
+
+func TestRateLimit_FailsOpenWhenStoreErrors(t *testing.T) {
+	withRateLimitStore(t, failingRateLimitStore{})
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/limited", RateLimit(1), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	assert.Equal(t, http.StatusNoContent, performRateLimited(router, "/limited").Code)
+	assert.Equal(t, http.StatusNoContent, performRateLimited(router, "/limited").Code)
+}
@@ -0,0 +1,206 @@
+package http_middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"sekolah-madrasah/database/schemas"
+	"sekolah-madrasah/pkg/auth_utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type UnitAccessResolver interface {
+	ResolveUnitRole(ctx context.Context, userId, unitId uuid.UUID) (schemas.UnitMemberRole, int, error)
+	ResolveOwningUnit(ctx context.Context, resource string, id uuid.UUID) (uuid.UUID, int, error)
+	ResolveUnitOrganization(ctx context.Context, unitId uuid.UUID) (uuid.UUID, int, error)
+}
+
+var unitAccessResolver UnitAccessResolver
+
+func SetUnitAccessResolver(resolver UnitAccessResolver) {
+	unitAccessResolver = resolver
+}
+
+// unitScopedParams maps path parameters to the table of the unit-owned resource they identify.
+var unitScopedParams = map[string]string{
+	"memberId":     schemas.UnitMember{}.TableName(),
+	"teacherId":    schemas.TeacherProfile{}.TableName(),
+	"studentId":    schemas.StudentProfile{}.TableName(),
+	"classId":      schemas.Class{}.TableName(),
+	"subjectId":    schemas.Subject{}.TableName(),
+	"activityId":   schemas.Activity{}.TableName(),
+	"enrollmentId": schemas.ClassEnrollment{}.TableName(),
+	"timetableId":  schemas.TimetableEntry{}.TableName(),
+	"generationId": schemas.TimetableGeneration{}.TableName(),
+	"assessmentId": schemas.Assessment{}.TableName(),
+	"thresholdId":  schemas.GradeThreshold{}.TableName(),
+	"graduationId": schemas.Graduation{}.TableName(),
+}
+
+var unitResources = []string{
+	"unit_members", "teachers", "students", "classes", "class_enrollments", "subjects", "timetables", "attendances", "grades", "report_cards", "activities",
+}
+
+// unitAdminResources are only open to owners and admins; staff may check
+// themselves in and out but not look at each other's attendance.
+var unitAdminResources = append([]string{"staff_attendances"}, unitResources...)
+
+// unitRolePermissions lists what each unit role may do inside its own unit,
+// on top of any permissions granted through organization roles.
+// Staff may also record attendance and grades and prepare report cards; the
+// use cases limit teachers among them to their homeroom class for attendance
+// and report cards, and to the subjects they are assigned for grades.
+// Students and parents may read report cards; the use case limits them to
+// their own or their child's, once published.
+var unitRolePermissions = map[schemas.UnitMemberRole]map[string]struct{}{
+	schemas.UnitMemberRoleOwner:    permissionSet([]string{"units.read", "units.update", "units.delete"}, unitAdminResources, "create", "read", "update", "delete", "list"),
+	schemas.UnitMemberRoleAdmin:    permissionSet([]string{"units.read", "units.update"}, unitAdminResources, "create", "read", "update", "delete", "list"),
+	schemas.UnitMemberRolePengurus: permissionSet([]string{"units.read"}, unitResources, "read", "list"),
+	schemas.UnitMemberRoleStaff:    permissionSet([]string{"units.read", "attendances.create", "attendances.update", "staff_attendances.create", "grades.create", "grades.update", "grades.delete", "report_cards.update"}, unitResources, "read", "list"),
+	schemas.UnitMemberRoleParent:   permissionSet([]string{"units.read", "report_cards.read"}, []string{"classes", "subjects", "timetables", "activities"}, "read", "list"),
+	schemas.UnitMemberRoleAnggota:  permissionSet([]string{"units.read", "report_cards.read"}, []string{"classes", "subjects", "timetables", "activities"}, "read", "list"),
+	schemas.UnitMemberRoleAlumni:   permissionSet([]string{"units.read"}, []string{"activities"}, "read", "list"),
+}
+
+func permissionSet(extra []string, resources []string, actions ...string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, name := range extra {
+		set[name] = struct{}{}
+	}
+	for _, resource := range resources {
+		for _, action := range actions {
+			set[resource+"."+action] = struct{}{}
+		}
+	}
+	return set
+}
+
+// unitRoleGrants reports whether the unit role resolved for this request grants the permission.
+func unitRoleGrants(c *gin.Context, permission string) bool {
+	role, ok := GetUnitRole(c)
+	if !ok {
+		return false
+	}
+	_, granted := unitRolePermissions[role][permission]
+	return granted
+}
+
+// GetUnitRole returns the effective UnitMemberRole set by RequireUnitAccess.
+func GetUnitRole(c *gin.Context) (schemas.UnitMemberRole, bool) {
+	value, exists := c.Get("unit_role")
+	if !exists {
+		return "", false
+	}
+	role, ok := value.(schemas.UnitMemberRole)
+	return role, ok
+}
+
+// RequireUnitAccess authorizes routes addressed as /units/:id/...
+func RequireUnitAccess(c *gin.Context) {
+	unitId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid unit id"})
+		return
+	}
+	authorizeUnit(c, unitId, "")
+}
+
+// RequireUnitAccessVia authorizes routes addressed by the bare ID of a unit-owned resource,
+// such as /activities/:activityId, by resolving the unit that owns it.
+func RequireUnitAccessVia(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		resource, known := unitScopedParams[param]
+		if !known || unitAccessResolver == nil {
+			abortForbidden(c, "you do not have access to this unit")
+			return
+		}
+
+		id, err := uuid.Parse(c.Param(param))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid " + param})
+			return
+		}
+
+		unitId, code, err := unitAccessResolver.ResolveOwningUnit(c.Request.Context(), resource, id)
+		if err != nil {
+			c.AbortWithStatusJSON(code, gin.H{"error": err.Error()})
+			return
+		}
+
+		authorizeUnit(c, unitId, param)
+	}
+}
+
+// resolveCallerUnitRole returns the user's role in the unit. API keys get no role,
+// only their scopes, and are refused outside their own organization.
+func resolveCallerUnitRole(ctx context.Context, claims *auth_utils.AuthClaim, unitId uuid.UUID) (schemas.UnitMemberRole, int, error) {
+	if !claims.IsApiKey() {
+		return unitAccessResolver.ResolveUnitRole(ctx, claims.UserID, unitId)
+	}
+
+	orgId, code, err := unitAccessResolver.ResolveUnitOrganization(ctx, unitId)
+	if err != nil {
+		return "", code, err
+	}
+	if orgId != claims.OrganizationID {
+		return "", http.StatusForbidden, errors.New("you do not have access to this unit")
+	}
+	return "", http.StatusOK, nil
+}
+
+func authorizeUnit(c *gin.Context, unitId uuid.UUID, anchorParam string) {
+	claims, ok := authClaimFromContext(c)
+	if !ok {
+		return
+	}
+
+	if unitAccessResolver == nil {
+		abortForbidden(c, "you do not have access to this unit")
+		return
+	}
+
+	ctx := c.Request.Context()
+	role, code, err := resolveCallerUnitRole(ctx, claims, unitId)
+	if err != nil {
+		if code == http.StatusForbidden {
+			abortForbidden(c, err.Error())
+			return
+		}
+		c.AbortWithStatusJSON(code, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Every other resource named in the path must live in the same unit
+	for _, param := range c.Params {
+		resource, known := unitScopedParams[param.Key]
+		if !known || param.Key == anchorParam {
+			continue
+		}
+		id, err := uuid.Parse(param.Value)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid " + param.Key})
+			return
+		}
+		ownerId, code, err := unitAccessResolver.ResolveOwningUnit(ctx, resource, id)
+		if err != nil {
+			c.AbortWithStatusJSON(code, gin.H{"error": err.Error()})
+			return
+		}
+		if ownerId != unitId {
+			// Answer as not found so other units' IDs cannot be probed
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "resource not found"})
+			return
+		}
+	}
+
+	c.Set("unit_id", unitId)
+	c.Set("unit_role", role)
+	c.Request = c.Request.WithContext(auth_utils.WithUnitAccess(ctx, auth_utils.UnitAccess{
+		UnitId: unitId,
+		Role:   role,
+	}))
+	c.Next()
+}
@@ -0,0 +1,213 @@
+package http_middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sekolah-madrasah/database/schemas"
+	"sekolah-madrasah/pkg/auth_utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeResolver struct {
+	roles  map[uuid.UUID]schemas.UnitMemberRole
+	owners map[uuid.UUID]uuid.UUID
+	orgs   map[uuid.UUID]uuid.UUID
+}
+
+func (f *fakeResolver) ResolveUnitRole(ctx context.Context, userId, unitId uuid.UUID) (schemas.UnitMemberRole, int, error) {
+	role, ok := f.roles[unitId]
+	if !ok {
+		return "", http.StatusForbidden, errors.New("you do not have access to this unit")
+	}
+	return role, http.StatusOK, nil
+}
+
+func (f *fakeResolver) ResolveOwningUnit(ctx context.Context, resource string, id uuid.UUID) (uuid.UUID, int, error) {
+	unitId, ok := f.owners[id]
+	if !ok {
+		return uuid.Nil, http.StatusNotFound, errors.New("resource not found")
+	}
+	return unitId, http.StatusOK, nil
+}
+
+func (f *fakeResolver) ResolveUnitOrganization(ctx context.Context, unitId uuid.UUID) (uuid.UUID, int, error) {
+	orgId, ok := f.orgs[unitId]
+	if !ok {
+		return uuid.Nil, http.StatusNotFound, errors.New("unit not found")
+	}
+	return orgId, http.StatusOK, nil
+}
+
+func withResolver(t *testing.T, resolver UnitAccessResolver) {
+	previous := unitAccessResolver
+	SetUnitAccessResolver(resolver)
+	t.Cleanup(func() { SetUnitAccessResolver(previous) })
+}
+
+func performUnitRequest(pattern, path string, handlers ...gin.HandlerFunc) (*httptest.ResponseRecorder, *auth_utils.UnitAccess) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	userID := uuid.New()
+	var seen *auth_utils.UnitAccess
+	chain := []gin.HandlerFunc{func(c *gin.Context) {
+		c.Set("auth", &auth_utils.AuthClaim{UserID: userID})
+		c.Next()
+	}}
+	chain = append(chain, handlers...)
+	chain = append(chain, func(c *gin.Context) {
+		if access, ok := auth_utils.GetUnitAccess(c.Request.Context()); ok {
+			seen = &access
+		}
+		c.Status(http.StatusNoContent)
+	})
+	router.GET(pattern, chain...)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	router.ServeHTTP(w, req)
+	return w, seen
+}
+
+func TestRequireUnitAccess_MemberAllowed(t *testing.T) {
+	unitId := uuid.New()
+	withResolver(t, &fakeResolver{roles: map[uuid.UUID]schemas.UnitMemberRole{unitId: schemas.UnitMemberRoleStaff}})
+
+	w, access := performUnitRequest("/units/:id", "/units/"+unitId.String(), RequireUnitAccess)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	if assert.NotNil(t, access) {
+		assert.Equal(t, unitId, access.UnitId)
+		assert.Equal(t, schemas.UnitMemberRoleStaff, access.Role)
+	}
+}
+
+func TestRequireUnitAccess_NonMemberForbidden(t *testing.T) {
+	withResolver(t, &fakeResolver{})
+
+	w, _ := performUnitRequest("/units/:id", "/units/"+uuid.New().String(), RequireUnitAccess)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRequireUnitAccess_InvalidUnitId(t *testing.T) {
+	withResolver(t, &fakeResolver{})
+
+	w, _ := performUnitRequest("/units/:id", "/units/not-a-uuid", RequireUnitAccess)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestRequireUnitAccess_NilResolverFailsClosed(t *testing.T) {
+	withResolver(t, nil)
+
+	w, _ := performUnitRequest("/units/:id", "/units/"+uuid.New().String(), RequireUnitAccess)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRequireUnitAccess_ChildFromOtherUnit(t *testing.T) {
+	unitId, otherUnitId, classId := uuid.New(), uuid.New(), uuid.New()
+	withResolver(t, &fakeResolver{
+		roles:  map[uuid.UUID]schemas.UnitMemberRole{unitId: schemas.UnitMemberRoleAdmin},
+		owners: map[uuid.UUID]uuid.UUID{classId: otherUnitId},
+	})
+
+	w, _ := performUnitRequest("/units/:id/classes/:classId", "/units/"+unitId.String()+"/classes/"+classId.String(), RequireUnitAccess)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRequireUnitAccessVia_ResolvesOwningUnit(t *testing.T) {
+	unitId, activityId := uuid.New(), uuid.New()
+	withResolver(t, &fakeResolver{
+		roles:  map[uuid.UUID]schemas.UnitMemberRole{unitId: schemas.UnitMemberRoleAdmin},
+		owners: map[uuid.UUID]uuid.UUID{activityId: unitId},
+	})
+
+	w, access := performUnitRequest("/activities/:activityId", "/activities/"+activityId.String(), RequireUnitAccessVia("activityId"))
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	if assert.NotNil(t, access) {
+		assert.Equal(t, unitId, access.UnitId)
+	}
+}
+
+func TestRequireUnitAccessVia_OtherUnitForbidden(t *testing.T) {
+	activityId := uuid.New()
+	withResolver(t, &fakeResolver{owners: map[uuid.UUID]uuid.UUID{activityId: uuid.New()}})
+
+	w, _ := performUnitRequest("/activities/:activityId", "/activities/"+activityId.String(), RequireUnitAccessVia("activityId"))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRequirePermission_GrantedByUnitRole(t *testing.T) {
+	unitId := uuid.New()
+	withChecker(t, &fakeChecker{})
+	withResolver(t, &fakeResolver{roles: map[uuid.UUID]schemas.UnitMemberRole{unitId: schemas.UnitMemberRoleAdmin}})
+
+	w, _ := performUnitRequest("/units/:id", "/units/"+unitId.String(), RequireUnitAccess, RequirePermission("classes.update"))
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestRequirePermission_UnitRoleDoesNotGrantWrites(t *testing.T) {
+	unitId := uuid.New()
+	withChecker(t, &fakeChecker{})
+	withResolver(t, &fakeResolver{roles: map[uuid.UUID]schemas.UnitMemberRole{unitId: schemas.UnitMemberRoleParent}})
+
+	w, _ := performUnitRequest("/units/:id", "/units/"+unitId.String(), RequireUnitAccess, RequirePermission("classes.update"))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRequirePermission_OrgRoleDoesNotGrantInsideUnit(t *testing.T) {
+	unitId := uuid.New()
+	// An admin of another organization, only a plain member of this unit
+	withChecker(t, &adminEverywhere{})
+	withResolver(t, &fakeResolver{roles: map[uuid.UUID]schemas.UnitMemberRole{unitId: schemas.UnitMemberRoleAnggota}})
+
+	for _, permission := range []string{"units.update", "unit_members.delete", "teachers.delete"} {
+		w, _ := performUnitRequest("/units/:id", "/units/"+unitId.String(), RequireUnitAccess, RequirePermission(permission))
+		assert.Equal(t, http.StatusForbidden, w.Code, permission)
+	}
+}
+
+func TestRequirePermission_OwnerMayDeleteUnit(t *testing.T) {
+	unitId := uuid.New()
+	withChecker(t, &fakeChecker{})
+	withResolver(t, &fakeResolver{roles: map[uuid.UUID]schemas.UnitMemberRole{unitId: schemas.UnitMemberRoleOwner}})
+
+	w, _ := performUnitRequest("/units/:id", "/units/"+unitId.String(), RequireUnitAccess, RequirePermission("units.delete"))
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+// adminEverywhere holds every organization-level permission.
+type adminEverywhere struct{}
+
+func (adminEverywhere) HasPermission(userID string, permission string) bool { return true }
+func (adminEverywhere) HasOrganizationPermission(userID string, organizationId uuid.UUID, permission string) bool {
+	return true
+}
+func (adminEverywhere) GetUserPermissions(userID string) []string { return nil }
+func (adminEverywhere) IsSuperAdmin(userID string) bool           { return false }
+
+func TestRequirePermission_ParentsReadReportCards(t *testing.T) {
+	unitId := uuid.New()
+	withChecker(t, &fakeChecker{})
+	withResolver(t, &fakeResolver{roles: map[uuid.UUID]schemas.UnitMemberRole{unitId: schemas.UnitMemberRoleParent}})
+
+	w, _ := performUnitRequest("/units/:id", "/units/"+unitId.String(), RequireUnitAccess, RequirePermission("report_cards.read"))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w, _ = performUnitRequest("/units/:id", "/units/"+unitId.String(), RequireUnitAccess, RequirePermission("report_cards.list"))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
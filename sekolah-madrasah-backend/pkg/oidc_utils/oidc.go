@@ -0,0 +1,447 @@
+package oidc_utils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	// MetadataTTL is how long discovery documents and signing keys are cached
+	MetadataTTL = time.Hour
+
+	maxResponseBytes = 1 << 20
+)
+
+var ErrInvalidIdToken = errors.New("invalid ID token")
+
+// ProviderMetadata is the part of the discovery document the authorization code
+// flow needs.
+type ProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+type AuthorizationParams struct {
+	ClientId      string
+	RedirectUri   string
+	Scopes        []string
+	State         string
+	Nonce         string
+	CodeChallenge string
+}
+
+type ExchangeParams struct {
+	ClientId     string
+	ClientSecret string
+	RedirectUri  string
+	Code         string
+	CodeVerifier string
+}
+
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	IdToken     string `json:"id_token"`
+	TokenType   string `json:"token_type"`
+}
+
+// IdTokenClaims holds the verified claims of an ID token. Raw keeps every claim
+// so provider-specific ones such as groups can be read.
+type IdTokenClaims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Raw           map[string]interface{}
+}
+
+// StringList reads a claim that is either a string or an array of strings.
+func (c IdTokenClaims) StringList(name string) []string {
+	switch v := c.Raw[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+type cachedMetadata struct {
+	metadata  ProviderMetadata
+	fetchedAt time.Time
+}
+
+type cachedKeys struct {
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+// Client talks to OpenID providers. It caches discovery documents and JWKS per
+// issuer and is safe for concurrent use.
+type Client struct {
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	metadata map[string]cachedMetadata
+	keys     map[string]cachedKeys
+}
+
+func NewClient(httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Client{
+		httpClient: httpClient,
+		metadata:   make(map[string]cachedMetadata),
+		keys:       make(map[string]cachedKeys),
+	}
+}
+
+// Discover fetches the provider's /.well-known/openid-configuration.
+func (c *Client) Discover(ctx context.Context, issuer string) (ProviderMetadata, error) {
+	issuer = strings.TrimSuffix(issuer, "/")
+
+	c.mu.Lock()
+	cached, ok := c.metadata[issuer]
+	c.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < MetadataTTL {
+		return cached.metadata, nil
+	}
+
+	var metadata ProviderMetadata
+	if err := c.getJSON(ctx, issuer+"/.well-known/openid-configuration", &metadata); err != nil {
+		return ProviderMetadata{}, fmt.Errorf("discovery failed: %w", err)
+	}
+	if strings.TrimSuffix(metadata.Issuer, "/") != issuer {
+		return ProviderMetadata{}, fmt.Errorf("discovery returned issuer %q, expected %q", metadata.Issuer, issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JwksUri == "" {
+		return ProviderMetadata{}, errors.New("discovery document is missing endpoints")
+	}
+
+	c.mu.Lock()
+	c.metadata[issuer] = cachedMetadata{metadata: metadata, fetchedAt: time.Now()}
+	c.mu.Unlock()
+
+	return metadata, nil
+}
+
+// AuthorizationUrl builds the URL the browser is sent to, using PKCE with S256.
+func AuthorizationUrl(metadata ProviderMetadata, params AuthorizationParams) string {
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", params.ClientId)
+	query.Set("redirect_uri", params.RedirectUri)
+	query.Set("scope", strings.Join(params.Scopes, " "))
+	query.Set("state", params.State)
+	query.Set("nonce", params.Nonce)
+	query.Set("code_challenge", params.CodeChallenge)
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return metadata.AuthorizationEndpoint + separator + query.Encode()
+}
+
+// Exchange redeems an authorization code at the token endpoint.
+func (c *Client) Exchange(ctx context.Context, metadata ProviderMetadata, params ExchangeParams) (TokenResponse, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", params.Code)
+	form.Set("redirect_uri", params.RedirectUri)
+	form.Set("code_verifier", params.CodeVerifier)
+	form.Set("client_id", params.ClientId)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if params.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(params.ClientId), url.QueryEscape(params.ClientSecret))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var oauthErr struct {
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+		}
+		_ = json.Unmarshal(body, &oauthErr)
+		if oauthErr.Error != "" {
+			return TokenResponse{}, fmt.Errorf("token endpoint returned %s: %s", oauthErr.Error, oauthErr.ErrorDescription)
+		}
+		return TokenResponse{}, fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var token TokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return TokenResponse{}, err
+	}
+	if token.IdToken == "" {
+		return TokenResponse{}, errors.New("token response has no id_token")
+	}
+
+	return token, nil
+}
+
+// VerifyIdToken checks the signature against the provider's JWKS and validates
+// iss, aud, exp and nonce.
+func (c *Client) VerifyIdToken(ctx context.Context, metadata ProviderMetadata, rawToken, clientId, nonce string) (IdTokenClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := c.signingKey(ctx, metadata.JwksUri, kid)
+		if err != nil {
+			return nil, err
+		}
+
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA:
+			if _, ok := key.(*rsa.PublicKey); !ok {
+				return nil, errors.New("key type does not match algorithm")
+			}
+		case *jwt.SigningMethodECDSA:
+			if _, ok := key.(*ecdsa.PublicKey); !ok {
+				return nil, errors.New("key type does not match algorithm")
+			}
+		default:
+			return nil, fmt.Errorf("unsupported signing algorithm %v", token.Header["alg"])
+		}
+		return key, nil
+	})
+	if err != nil {
+		return IdTokenClaims{}, fmt.Errorf("%w: %v", ErrInvalidIdToken, err)
+	}
+
+	if iss, _ := claims["iss"].(string); strings.TrimSuffix(iss, "/") != strings.TrimSuffix(metadata.Issuer, "/") {
+		return IdTokenClaims{}, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIdToken, iss)
+	}
+	if _, ok := claims["exp"]; !ok {
+		return IdTokenClaims{}, fmt.Errorf("%w: missing exp", ErrInvalidIdToken)
+	}
+
+	result := IdTokenClaims{Raw: claims}
+	audiences := result.StringList("aud")
+	if !contains(audiences, clientId) {
+		return IdTokenClaims{}, fmt.Errorf("%w: token is not intended for this client", ErrInvalidIdToken)
+	}
+	if azp, ok := claims["azp"].(string); ok && len(audiences) > 1 && azp != clientId {
+		return IdTokenClaims{}, fmt.Errorf("%w: unexpected authorized party", ErrInvalidIdToken)
+	}
+	if tokenNonce, _ := claims["nonce"].(string); nonce != "" && tokenNonce != nonce {
+		return IdTokenClaims{}, fmt.Errorf("%w: nonce mismatch", ErrInvalidIdToken)
+	}
+
+	result.Subject, _ = claims["sub"].(string)
+	result.Email, _ = claims["email"].(string)
+	result.Name, _ = claims["name"].(string)
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		result.EmailVerified = v
+	case string:
+		result.EmailVerified = v == "true"
+	}
+	if result.Subject == "" {
+		return IdTokenClaims{}, fmt.Errorf("%w: missing sub", ErrInvalidIdToken)
+	}
+
+	return result, nil
+}
+
+// signingKey looks up kid in the cached JWKS, refetching once when the key is
+// unknown so provider key rotation is picked up without a restart.
+func (c *Client) signingKey(ctx context.Context, jwksUri, kid string) (interface{}, error) {
+	c.mu.Lock()
+	cached, ok := c.keys[jwksUri]
+	c.mu.Unlock()
+
+	if ok && time.Since(cached.fetchedAt) < MetadataTTL {
+		if key := pickKey(cached.keys, kid); key != nil {
+			return key, nil
+		}
+	}
+
+	keys, err := c.fetchKeys(ctx, jwksUri)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.keys[jwksUri] = cachedKeys{keys: keys, fetchedAt: time.Now()}
+	c.mu.Unlock()
+
+	if key := pickKey(keys, kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no signing key found for kid %q", kid)
+}
+
+// pickKey returns the key with kid, or the only key when the token names none.
+func pickKey(keys map[string]interface{}, kid string) interface{} {
+	if kid != "" {
+		return keys[kid]
+	}
+	if len(keys) == 1 {
+		for _, key := range keys {
+			return key
+		}
+	}
+	return nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (c *Client) fetchKeys(ctx context.Context, jwksUri string) (map[string]interface{}, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := c.getJSON(ctx, jwksUri, &set); err != nil {
+		return nil, fmt.Errorf("fetching JWKS failed: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := parseJwk(jwk)
+		if err != nil {
+			// Providers may publish key types we do not use
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
+
+func parseJwk(jwk jsonWebKey) (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+}
+
+func (c *Client) getJSON(ctx context.Context, target string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", target, resp.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(out)
+}
+
+// RandomString returns n random bytes encoded as base64url, for state, nonce
+// and PKCE verifiers.
+func RandomString(n int) (string, error) {
+	raw := make([]byte, n)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// CodeChallenge derives the S256 PKCE challenge from a verifier.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func contains(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
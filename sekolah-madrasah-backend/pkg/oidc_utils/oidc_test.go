@@ -0,0 +1,112 @@
+package oidc_utils
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"sekolah-madrasah/pkg/oidc_utils/oidctest"
+)
+
+func authorize(t *testing.T, issuer *oidctest.Issuer, metadata ProviderMetadata, clientId, verifier, nonce string) string {
+	t.Helper()
+	authUrl := AuthorizationUrl(metadata, AuthorizationParams{
+		ClientId:      clientId,
+		RedirectUri:   "http://localhost/callback",
+		Scopes:        []string{"openid", "email"},
+		State:         "state-1",
+		Nonce:         nonce,
+		CodeChallenge: CodeChallenge(verifier),
+	})
+	code, state, err := issuer.Authorize(authUrl)
+	if err != nil {
+		t.Fatalf("Authorize failed: %v", err)
+	}
+	if state != "state-1" {
+		t.Fatalf("expected state to round-trip, got %q", state)
+	}
+	return code
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	issuer := oidctest.NewIssuer("client-1", "secret-1")
+	defer issuer.Close()
+	issuer.SetClaims(map[string]interface{}{
+		"sub":            "user-123",
+		"email":          "guru@example.com",
+		"email_verified": true,
+		"groups":         []string{"guru", "staf-tu"},
+	})
+
+	client := NewClient(nil)
+	ctx := context.Background()
+
+	metadata, err := client.Discover(ctx, issuer.URL+"/")
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+
+	verifier, _ := RandomString(32)
+	code := authorize(t, issuer, metadata, "client-1", verifier, "nonce-1")
+
+	token, err := client.Exchange(ctx, metadata, ExchangeParams{
+		ClientId:     "client-1",
+		ClientSecret: "secret-1",
+		RedirectUri:  "http://localhost/callback",
+		Code:         code,
+		CodeVerifier: verifier,
+	})
+	if err != nil {
+		t.Fatalf("Exchange failed: %v", err)
+	}
+
+	claims, err := client.VerifyIdToken(ctx, metadata, token.IdToken, "client-1", "nonce-1")
+	if err != nil {
+		t.Fatalf("VerifyIdToken failed: %v", err)
+	}
+	if claims.Subject != "user-123" || claims.Email != "guru@example.com" || !claims.EmailVerified {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if groups := claims.StringList("groups"); len(groups) != 2 || groups[1] != "staf-tu" {
+		t.Errorf("unexpected groups %v", groups)
+	}
+
+	if _, err := client.VerifyIdToken(ctx, metadata, token.IdToken, "client-1", "other-nonce"); !errors.Is(err, ErrInvalidIdToken) {
+		t.Errorf("expected nonce mismatch to be rejected, got %v", err)
+	}
+	if _, err := client.VerifyIdToken(ctx, metadata, token.IdToken, "client-2", "nonce-1"); !errors.Is(err, ErrInvalidIdToken) {
+		t.Errorf("expected foreign audience to be rejected, got %v", err)
+	}
+	tampered := token.IdToken[:strings.LastIndex(token.IdToken, ".")+1] + "AAAA"
+	if _, err := client.VerifyIdToken(ctx, metadata, tampered, "client-1", "nonce-1"); !errors.Is(err, ErrInvalidIdToken) {
+		t.Errorf("expected a bad signature to be rejected, got %v", err)
+	}
+}
+
+func TestExchange_RejectsWrongVerifier(t *testing.T) {
+	issuer := oidctest.NewIssuer("client-1", "secret-1")
+	defer issuer.Close()
+	issuer.SetClaims(map[string]interface{}{"sub": "user-123"})
+
+	client := NewClient(nil)
+	ctx := context.Background()
+	metadata, err := client.Discover(ctx, issuer.URL)
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+
+	verifier, _ := RandomString(32)
+	code := authorize(t, issuer, metadata, "client-1", verifier, "nonce-1")
+
+	_, err = client.Exchange(ctx, metadata, ExchangeParams{
+		ClientId:     "client-1",
+		ClientSecret: "secret-1",
+		RedirectUri:  "http://localhost/callback",
+		Code:         code,
+		CodeVerifier: "not-the-verifier",
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("expected invalid_grant, got %v", err)
+	}
+}
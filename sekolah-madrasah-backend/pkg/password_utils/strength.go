@@ -0,0 +1,61 @@
+package password_utils
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+const (
+	MinPasswordLength = 8
+	// MaxPasswordLength is bcrypt's input limit; longer passwords are silently truncated
+	MaxPasswordLength = 72
+)
+
+var commonPasswords = map[string]struct{}{
+	"password": {}, "password1": {}, "password123": {}, "12345678": {}, "123456789": {},
+	"1234567890": {}, "qwerty123": {}, "qwertyuiop": {}, "11111111": {}, "00000000": {},
+	"abcd1234": {}, "admin123": {}, "guru123456": {}, "sekolah123": {}, "bismillah": {},
+	"indonesia": {}, "rahasia123": {}, "iloveyou": {},
+}
+
+// ValidateStrength checks a new password against the account password rules.
+// personal holds values such as the email or name that must not appear in it.
+func ValidateStrength(password string, personal ...string) error {
+	if len(password) < MinPasswordLength {
+		return errors.New("password must be at least 8 characters")
+	}
+	if len(password) > MaxPasswordLength {
+		return errors.New("password must be at most 72 bytes")
+	}
+
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	if !hasLetter || !hasDigit {
+		return errors.New("password must contain both letters and numbers")
+	}
+
+	lower := strings.ToLower(password)
+	if _, common := commonPasswords[lower]; common {
+		return errors.New("password is too common")
+	}
+
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if at := strings.Index(value, "@"); at >= 0 {
+			value = value[:at]
+		}
+		if len(value) >= 4 && strings.Contains(lower, value) {
+			return errors.New("password must not contain your name or email")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package password_utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateStrength(t *testing.T) {
+	cases := []struct {
+		name     string
+		password string
+		personal []string
+		valid    bool
+	}{
+		{"strong", "Kelas7b-Ceria", nil, true},
+		{"too short", "ab12", nil, false},
+		{"letters only", "abcdefghij", nil, false},
+		{"digits only", "1234567890", nil, false},
+		{"common", "Password123", nil, false},
+		{"contains email local part", "budi.santoso99", []string{"budi.santoso@example.com"}, false},
+		{"short personal values ignored", "ani2024-kelas", []string{"ani"}, true},
+		{"too long", string(make([]byte, 73)), nil, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateStrength(tc.password, tc.personal...)
+			assert.Equal(t, tc.valid, err == nil, "error: %v", err)
+		})
+	}
+}
+
+func TestGeneratePassword_PassesStrengthRules(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, ValidateStrength(GeneratePassword(12)))
+	}
+}
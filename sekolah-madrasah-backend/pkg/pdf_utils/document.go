@@ -0,0 +1,151 @@
+// Package pdf_utils writes simple text documents as PDF using the standard
+// Helvetica fonts, which every PDF reader has built in, so nothing needs to
+// be embedded.
+package pdf_utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PageSize is a page's width and height in points (1/72 inch).
+type PageSize struct {
+	Width  float64
+	Height float64
+}
+
+var (
+	PageA4 = PageSize{Width: 595.28, Height: 841.89}
+	PageF4 = PageSize{Width: 609.45, Height: 935.43} // 215 x 330 mm, common for rapor
+)
+
+// PageSizes maps the names a template may use to their size.
+var PageSizes = map[string]PageSize{
+	"A4": PageA4,
+	"F4": PageF4,
+}
+
+type Font int
+
+const (
+	Regular Font = iota
+	Bold
+)
+
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignCenter
+	AlignRight
+)
+
+// Document is a PDF being written page by page. Coordinates are in points
+// from the top left corner of the page.
+type Document struct {
+	size  PageSize
+	pages []*bytes.Buffer
+}
+
+func New(size PageSize) *Document {
+	return &Document{size: size}
+}
+
+func (d *Document) Size() PageSize {
+	return d.size
+}
+
+// AddPage starts a new page; drawing goes to the last page added.
+func (d *Document) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+func (d *Document) page() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	return d.pages[len(d.pages)-1]
+}
+
+// Text writes a line of text with its baseline at y. x is the left edge, the
+// centre or the right edge of the text depending on the alignment.
+func (d *Document) Text(x, y float64, font Font, size float64, align Align, text string) {
+	switch align {
+	case AlignCenter:
+		x -= TextWidth(font, size, text) / 2
+	case AlignRight:
+		x -= TextWidth(font, size, text)
+	}
+	fmt.Fprintf(d.page(), "BT /F%d %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font+1, size, x, d.size.Height-y, escape(text))
+}
+
+// Line draws a thin line between two points.
+func (d *Document) Line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(d.page(), "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, d.size.Height-y1, x2, d.size.Height-y2)
+}
+
+// Rect draws the outline of a rectangle whose top left corner is at x, y.
+func (d *Document) Rect(x, y, width, height float64) {
+	fmt.Fprintf(d.page(), "0.5 w %.2f %.2f %.2f %.2f re S\n", x, d.size.Height-y-height, width, height)
+}
+
+// WriteTo writes the document as a PDF file.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	var out bytes.Buffer
+	offsets := []int{}
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// 1 catalog, 2 page tree, 3 and 4 fonts, then a page and its content per page
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, content := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			d.size.Width, d.size.Height, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.WriteTo(w)
+}
+
+// escape encodes the text in WinAnsi, replacing what it cannot show, and
+// escapes the characters that are special inside a PDF string.
+func escape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 32 && r < 127:
+			b.WriteRune(r)
+		case r >= 160 && r <= 255:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
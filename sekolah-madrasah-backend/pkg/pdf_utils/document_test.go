@@ -0,0 +1,61 @@
+package pdf_utils
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteTo_Structure(t *testing.T) {
+	doc := New(PageA4)
+	doc.AddPage()
+	doc.Text(50, 50, Bold, 12, AlignLeft, "Rapor (Semester 1)")
+	doc.AddPage()
+	doc.Text(50, 50, Regular, 10, AlignCenter, "Halaman 2")
+
+	var out bytes.Buffer
+	_, err := doc.WriteTo(&out)
+	assert.NoError(t, err)
+
+	pdf := out.String()
+	assert.True(t, strings.HasPrefix(pdf, "%PDF-1.4"))
+	assert.True(t, strings.HasSuffix(pdf, "%%EOF\n"))
+	assert.Contains(t, pdf, "/Count 2")
+	assert.Contains(t, pdf, `(Rapor \(Semester 1\)) Tj`)
+
+	// Every xref entry points at the object it lists
+	xref := pdf[strings.LastIndex(pdf, "\nxref\n")+1:]
+	entries := strings.Split(xref, "\n")[3:9]
+	for i, entry := range entries {
+		offset, err := strconv.Atoi(entry[:10])
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(pdf[offset:], strconv.Itoa(i+1)+" 0 obj"), "object %d", i+1)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	assert.Equal(t, `a\(b\)\\c`, escape(`a(b)\c`))
+	assert.Equal(t, `Jos\351`, escape("José"))
+	assert.Equal(t, "?", escape("€"))
+}
+
+func TestWrap(t *testing.T) {
+	text := "Menunjukkan penguasaan yang baik dalam operasi bilangan bulat dan pecahan."
+	lines := Wrap(Regular, 10, 150, text)
+	assert.Greater(t, len(lines), 1)
+	for _, line := range lines {
+		assert.LessOrEqual(t, TextWidth(Regular, 10, line), 150.0)
+	}
+	assert.Equal(t, text, strings.Join(lines, " "))
+
+	assert.Equal(t, []string{"satu", "", "dua"}, Wrap(Regular, 10, 150, "satu\n\ndua"))
+	assert.Equal(t, []string{"Supercalifragilistic"}, Wrap(Bold, 12, 20, "Supercalifragilistic"))
+}
+
+func TestTextWidth(t *testing.T) {
+	assert.InDelta(t, 5.56*3, TextWidth(Regular, 10, "000"), 1e-9)
+	assert.Greater(t, TextWidth(Bold, 10, "Rapor"), TextWidth(Regular, 10, "Rapor"))
+}
@@ -5,53 +5,94 @@ import (
 	"log"
 
 	"sekolah-madrasah/app/controller/activity_controller"
+	"sekolah-madrasah/app/controller/api_key_controller"
+	"sekolah-madrasah/app/controller/attendance_controller"
+	"sekolah-madrasah/app/controller/audit_controller"
 	"sekolah-madrasah/app/controller/auth_controller"
 	"sekolah-madrasah/app/controller/class_controller"
 	"sekolah-madrasah/app/controller/class_enrollment_controller"
+	"sekolah-madrasah/app/controller/gradebook_controller"
+	"sekolah-madrasah/app/controller/graduation_controller"
 	"sekolah-madrasah/app/controller/organization_controller"
 	"sekolah-madrasah/app/controller/permission_controller"
 	"sekolah-madrasah/app/controller/post_controller"
+	"sekolah-madrasah/app/controller/report_card_controller"
 	"sekolah-madrasah/app/controller/role_controller"
+	"sekolah-madrasah/app/controller/rollover_controller"
+	"sekolah-madrasah/app/controller/session_controller"
+	"sekolah-madrasah/app/controller/sso_controller"
+	"sekolah-madrasah/app/controller/staff_attendance_controller"
 	"sekolah-madrasah/app/controller/student_profile_controller"
 	"sekolah-madrasah/app/controller/subject_controller"
 	"sekolah-madrasah/app/controller/teacher_profile_controller"
+	"sekolah-madrasah/app/controller/timetable_controller"
+	"sekolah-madrasah/app/controller/two_factor_controller"
 	"sekolah-madrasah/app/controller/unit_controller"
 	"sekolah-madrasah/app/controller/unit_member_controller"
 	"sekolah-madrasah/app/controller/unit_settings_controller"
 	"sekolah-madrasah/app/controller/user_controller"
 	"sekolah-madrasah/app/repository/activity_repository"
+	"sekolah-madrasah/app/repository/api_key_repository"
+	"sekolah-madrasah/app/repository/attendance_repository"
+	"sekolah-madrasah/app/repository/audit_repository"
 	"sekolah-madrasah/app/repository/class_enrollment_repository"
 	"sekolah-madrasah/app/repository/class_repository"
+	"sekolah-madrasah/app/repository/gradebook_repository"
+	"sekolah-madrasah/app/repository/graduation_repository"
+	"sekolah-madrasah/app/repository/login_attempt_repository"
 	"sekolah-madrasah/app/repository/org_member_repository"
 	"sekolah-madrasah/app/repository/organization_repository"
 	"sekolah-madrasah/app/repository/permission_repository"
 	"sekolah-madrasah/app/repository/post_repository"
+	"sekolah-madrasah/app/repository/report_card_repository"
 	"sekolah-madrasah/app/repository/role_repository"
+	"sekolah-madrasah/app/repository/rollover_repository"
+	"sekolah-madrasah/app/repository/sso_repository"
+	"sekolah-madrasah/app/repository/staff_attendance_repository"
 	"sekolah-madrasah/app/repository/student_profile_repository"
 	"sekolah-madrasah/app/repository/subject_repository"
 	"sekolah-madrasah/app/repository/teacher_profile_repository"
+	"sekolah-madrasah/app/repository/timetable_repository"
+	"sekolah-madrasah/app/repository/token_repository"
+	"sekolah-madrasah/app/repository/two_factor_repository"
 	"sekolah-madrasah/app/repository/unit_member_repository"
 	"sekolah-madrasah/app/repository/unit_repository"
 	"sekolah-madrasah/app/repository/user_repository"
+	"sekolah-madrasah/app/service/mail_service"
 	"sekolah-madrasah/app/service/membership_service"
+	"sekolah-madrasah/app/service/permission_service"
+	"sekolah-madrasah/app/service/rate_limit_service"
+	"sekolah-madrasah/app/service/unit_access_service"
 	"sekolah-madrasah/app/use_case/activity_use_case"
+	"sekolah-madrasah/app/use_case/api_key_use_case"
+	"sekolah-madrasah/app/use_case/attendance_use_case"
+	"sekolah-madrasah/app/use_case/audit_use_case"
 	"sekolah-madrasah/app/use_case/auth_use_case"
 	"sekolah-madrasah/app/use_case/class_enrollment_use_case"
 	"sekolah-madrasah/app/use_case/class_use_case"
+	"sekolah-madrasah/app/use_case/gradebook_use_case"
+	"sekolah-madrasah/app/use_case/graduation_use_case"
 	"sekolah-madrasah/app/use_case/organization_use_case"
 	"sekolah-madrasah/app/use_case/permission_use_case"
 	"sekolah-madrasah/app/use_case/post_use_case"
+	"sekolah-madrasah/app/use_case/report_card_use_case"
 	"sekolah-madrasah/app/use_case/role_use_case"
+	"sekolah-madrasah/app/use_case/rollover_use_case"
+	"sekolah-madrasah/app/use_case/sso_use_case"
+	"sekolah-madrasah/app/use_case/staff_attendance_use_case"
 	"sekolah-madrasah/app/use_case/student_profile_use_case"
 	"sekolah-madrasah/app/use_case/subject_use_case"
 	"sekolah-madrasah/app/use_case/teacher_profile_use_case"
+	"sekolah-madrasah/app/use_case/timetable_use_case"
 	"sekolah-madrasah/app/use_case/unit_member_use_case"
 	"sekolah-madrasah/app/use_case/unit_use_case"
 	"sekolah-madrasah/app/use_case/user_use_case"
 	"sekolah-madrasah/config"
 	"sekolah-madrasah/database"
 	_ "sekolah-madrasah/docs" // swagger docs
+	"sekolah-madrasah/pkg/auth_utils"
 	"sekolah-madrasah/pkg/http_middleware"
+	"sekolah-madrasah/pkg/oidc_utils"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -61,6 +102,13 @@ import (
 
 type Container struct {
 	AuthController            auth_controller.AuthController
+	AuthUseCase               auth_use_case.AuthUseCase
+	SessionController         session_controller.SessionController
+	TwoFactorController       two_factor_controller.TwoFactorController
+	AuditController           audit_controller.AuditController
+	SsoController             sso_controller.SsoController
+	ApiKeyController          api_key_controller.ApiKeyController
+	ApiKeyUseCase             api_key_use_case.ApiKeyUseCase
 	UserController            user_controller.UserController
 	RoleController            role_controller.RoleController
 	PermissionController      permission_controller.PermissionController
@@ -74,11 +122,24 @@ type Container struct {
 	ClassController           *class_controller.ClassController
 	ClassEnrollmentController *class_enrollment_controller.ClassEnrollmentController
 	SubjectController         *subject_controller.SubjectController
+	TimetableController       *timetable_controller.TimetableController
+	AttendanceController      *attendance_controller.AttendanceController
+	StaffAttendanceController *staff_attendance_controller.StaffAttendanceController
+	GradebookController       *gradebook_controller.GradebookController
+	ReportCardController      *report_card_controller.ReportCardController
+	RolloverController        *rollover_controller.RolloverController
+	GraduationController      *graduation_controller.GraduationController
 	ActivityController        *activity_controller.ActivityController
+	PermissionService         permission_service.PermissionService
+	UnitAccessService         unit_access_service.UnitAccessService
 }
 
 func NewContainer(db *gorm.DB) *Container {
 	userRepo := user_repository.NewUserRepository(db)
+	tokenRepo := token_repository.NewTokenRepository(db)
+	twoFactorRepo := two_factor_repository.NewTwoFactorRepository(db)
+	loginAttemptRepo := login_attempt_repository.NewLoginAttemptRepository(db)
+	auditRepo := audit_repository.NewAuditRepository(db)
 	roleRepo := role_repository.NewRoleRepository(db)
 	permissionRepo := permission_repository.NewPermissionRepository(db)
 	orgRepo := organization_repository.NewOrganizationRepository(db)
@@ -91,25 +152,59 @@ func NewContainer(db *gorm.DB) *Container {
 	classRepo := class_repository.NewClassRepository(db)
 	classEnrollmentRepo := class_enrollment_repository.NewClassEnrollmentRepository(db)
 	subjectRepo := subject_repository.NewSubjectRepository(db)
+	timetableRepo := timetable_repository.NewTimetableRepository(db)
+	attendanceRepo := attendance_repository.NewAttendanceRepository(db)
+	staffAttendanceRepo := staff_attendance_repository.NewStaffAttendanceRepository(db)
+	gradebookRepo := gradebook_repository.NewGradebookRepository(db)
+	reportCardRepo := report_card_repository.NewReportCardRepository(db)
+	rolloverRepo := rollover_repository.NewRolloverRepository(db)
+	graduationRepo := graduation_repository.NewGraduationRepository(db)
 	activityRepo := activity_repository.NewActivityRepository(db)
+	apiKeyRepo := api_key_repository.NewApiKeyRepository(db)
+	ssoRepo := sso_repository.NewSsoRepository(db)
 
-	authUseCase := auth_use_case.NewAuthUseCase(userRepo)
-	userUseCase := user_use_case.NewUserUseCase(userRepo)
+	mailer, err := mail_service.NewMailer(config.APP.Mail)
+	if err != nil {
+		log.Fatalf("❌ Failed to configure mailer: %v", err)
+	}
+
+	authUseCase := auth_use_case.NewAuthUseCase(userRepo, tokenRepo, twoFactorRepo, loginAttemptRepo, auditRepo, unitRepo, mailer)
+	userUseCase := user_use_case.NewUserUseCase(userRepo, authUseCase)
+	ssoUseCase := sso_use_case.NewSsoUseCase(ssoRepo, orgRepo, unitRepo, unitMemberRepo, userRepo, authUseCase, oidc_utils.NewClient(nil))
+	auditUseCase := audit_use_case.NewAuditUseCase(auditRepo)
 	roleUseCase := role_use_case.NewRoleUseCase(roleRepo, permissionRepo)
 	permissionUseCase := permission_use_case.NewPermissionUseCase(permissionRepo)
-	orgUseCase := organization_use_case.NewOrganizationUseCase(orgRepo, orgMemberRepo)
+	orgUseCase := organization_use_case.NewOrganizationUseCase(orgRepo, orgMemberRepo, roleRepo)
 	unitUseCase := unit_use_case.NewUnitUseCase(unitRepo)
 	unitMemberUseCase := unit_member_use_case.NewUnitMemberUseCase(unitMemberRepo)
-	postUseCase := post_use_case.NewPostUseCase(postRepo, userRepo)
+	permissionService := permission_service.NewPermissionService(db, permission_service.DefaultCacheTTL)
+	postUseCase := post_use_case.NewPostUseCase(postRepo, userRepo, permissionService)
 	teacherProfileUseCase := teacher_profile_use_case.NewTeacherProfileUseCase(teacherProfileRepo)
 	studentProfileUseCase := student_profile_use_case.NewStudentProfileUseCase(studentProfileRepo)
 	classUseCase := class_use_case.NewClassUseCase(classRepo)
 	classEnrollmentUseCase := class_enrollment_use_case.NewClassEnrollmentUseCase(classEnrollmentRepo)
 	subjectUseCase := subject_use_case.NewSubjectUseCase(subjectRepo)
+	timetableUseCase := timetable_use_case.NewTimetableUseCase(timetableRepo)
+	if err := timetableUseCase.FailInterruptedGenerations(); err != nil {
+		log.Printf("⚠️ Failed to clean up interrupted timetable generations: %v", err)
+	}
+	attendanceUseCase := attendance_use_case.NewAttendanceUseCase(attendanceRepo)
+	staffAttendanceUseCase := staff_attendance_use_case.NewStaffAttendanceUseCase(staffAttendanceRepo)
+	gradebookUseCase := gradebook_use_case.NewGradebookUseCase(gradebookRepo)
+	reportCardUseCase := report_card_use_case.NewReportCardUseCase(reportCardRepo)
+	rolloverUseCase := rollover_use_case.NewRolloverUseCase(rolloverRepo)
+	graduationUseCase := graduation_use_case.NewGraduationUseCase(graduationRepo)
 	activityUseCase := activity_use_case.NewActivityUseCase(activityRepo)
 	membershipService := membership_service.NewMembershipService(db)
-
-	authController := auth_controller.NewAuthController(authUseCase)
+	unitAccessService := unit_access_service.NewUnitAccessService(db)
+	apiKeyUseCase := api_key_use_case.NewApiKeyUseCase(apiKeyRepo, orgRepo, orgMemberRepo, permissionRepo, auditRepo, permissionService)
+
+	authController := auth_controller.NewAuthController(authUseCase, ssoUseCase)
+	sessionController := session_controller.NewSessionController(authUseCase)
+	twoFactorController := two_factor_controller.NewTwoFactorController(authUseCase)
+	auditController := audit_controller.NewAuditController(auditUseCase)
+	apiKeyController := api_key_controller.NewApiKeyController(apiKeyUseCase)
+	ssoController := sso_controller.NewSsoController(ssoUseCase)
 	userController := user_controller.NewUserController(userUseCase, membershipService)
 	roleController := role_controller.NewRoleController(roleUseCase)
 	permissionController := permission_controller.NewPermissionController(permissionUseCase)
@@ -123,10 +218,24 @@ func NewContainer(db *gorm.DB) *Container {
 	classCtrl := class_controller.NewClassController(classUseCase)
 	classEnrollmentCtrl := class_enrollment_controller.NewClassEnrollmentController(classEnrollmentUseCase)
 	subjectCtrl := subject_controller.NewSubjectController(subjectUseCase)
+	timetableCtrl := timetable_controller.NewTimetableController(timetableUseCase)
+	attendanceCtrl := attendance_controller.NewAttendanceController(attendanceUseCase)
+	staffAttendanceCtrl := staff_attendance_controller.NewStaffAttendanceController(staffAttendanceUseCase)
+	gradebookCtrl := gradebook_controller.NewGradebookController(gradebookUseCase)
+	reportCardCtrl := report_card_controller.NewReportCardController(reportCardUseCase)
+	rolloverCtrl := rollover_controller.NewRolloverController(rolloverUseCase)
+	graduationCtrl := graduation_controller.NewGraduationController(graduationUseCase)
 	activityCtrl := activity_controller.NewActivityController(activityUseCase)
 
 	return &Container{
 		AuthController:            authController,
+		AuthUseCase:               authUseCase,
+		SessionController:         sessionController,
+		TwoFactorController:       twoFactorController,
+		AuditController:           auditController,
+		SsoController:             ssoController,
+		ApiKeyController:          apiKeyController,
+		ApiKeyUseCase:             apiKeyUseCase,
 		UserController:            userController,
 		RoleController:            roleController,
 		PermissionController:      permissionController,
@@ -140,7 +249,16 @@ func NewContainer(db *gorm.DB) *Container {
 		ClassController:           classCtrl,
 		ClassEnrollmentController: classEnrollmentCtrl,
 		SubjectController:         subjectCtrl,
+		TimetableController:       timetableCtrl,
+		AttendanceController:      attendanceCtrl,
+		StaffAttendanceController: staffAttendanceCtrl,
+		GradebookController:       gradebookCtrl,
+		ReportCardController:      reportCardCtrl,
+		RolloverController:        rolloverCtrl,
+		GraduationController:      graduationCtrl,
 		ActivityController:        activityCtrl,
+		PermissionService:         permissionService,
+		UnitAccessService:         unitAccessService,
 	}
 }
 
@@ -151,16 +269,37 @@ func InitRoutes() *gin.Engine {
 	}
 	log.Println("✅ Main database connected")
 
+	if config.APP.Rest.JWTSigningKeyId != "" {
+		keySet, err := auth_utils.LoadKeySet(config.APP.Rest.JWTKeysDir, config.APP.Rest.JWTSigningKeyId)
+		if err != nil {
+			log.Fatalf("❌ Failed to load JWT signing keys: %v", err)
+		}
+		auth_utils.SetKeySet(keySet)
+		log.Printf("✅ Signing tokens with key %s", config.APP.Rest.JWTSigningKeyId)
+	}
+
 	router := gin.Default()
 
 	router.Use(http_middleware.CORS)
 
 	container := NewContainer(mainDB)
+	http_middleware.SetPermissionChecker(container.PermissionService)
+	http_middleware.SetUnitAccessResolver(container.UnitAccessService)
+	http_middleware.SetOrganizationAccessResolver(container.UnitAccessService)
+	http_middleware.SetTokenDenylist(container.AuthUseCase)
+	http_middleware.SetApiKeyAuthenticator(container.ApiKeyUseCase)
+	http_middleware.SetSessionTracker(container.AuthUseCase)
+	http_middleware.SetImpersonationAuditor(container.AuthUseCase)
+	if config.APP.RateLimit.Store == "postgres" {
+		http_middleware.SetRateLimitStore(rate_limit_service.NewPostgresStore(mainDB))
+	}
 
 	// Swagger docs
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	router.GET("/.well-known/jwks.json", container.AuthController.Jwks)
 
 	v1 := router.Group("/api/v1")
+	v1.Use(http_middleware.RateLimitGroups("/api/v1", config.APP.RateLimit.DefaultPerMinute, config.APP.RateLimit.Groups))
 	{
 		v1.GET("/ping", func(c *gin.Context) {
 			c.JSON(200, gin.H{"message": "pong"})
@@ -168,167 +307,282 @@ func InitRoutes() *gin.Engine {
 
 		auth := v1.Group("/auth")
 		{
-			auth.POST("/login", container.AuthController.Login)
+			auth.POST("/login", http_middleware.RateLimit(30), container.AuthController.Login)
 			auth.POST("/register", container.AuthController.Register)
 			auth.POST("/refresh", container.AuthController.RefreshToken)
+			auth.POST("/2fa/verify", http_middleware.RateLimit(10), container.AuthController.VerifyTwoFactor)
+			auth.GET("/sso/:organizationId/authorize", http_middleware.RateLimit(30), container.AuthController.StartSsoLogin)
+			auth.POST("/sso/callback", http_middleware.RateLimit(30), container.AuthController.SsoCallback)
+			auth.POST("/logout", http_middleware.JWTAuthentication, container.AuthController.Logout)
+			auth.POST("/logout-all", http_middleware.JWTAuthentication, container.AuthController.LogoutAll)
+			auth.POST("/forgot-password", http_middleware.RateLimit(5), container.AuthController.ForgotPassword)
+			auth.POST("/reset-password", http_middleware.RateLimit(10), container.AuthController.ResetPassword)
+			auth.POST("/verify-email", http_middleware.RateLimit(10), container.AuthController.VerifyEmail)
+			auth.POST("/resend-verification", http_middleware.RateLimit(3), container.AuthController.ResendEmailVerification)
 		}
 
 		users := v1.Group("/users")
 		users.Use(http_middleware.JWTAuthentication)
 		{
-			users.GET("", container.UserController.GetUsers)
+			users.GET("", http_middleware.RequireSuperAdmin, container.UserController.GetUsers)
 			users.GET("/me", container.UserController.GetCurrentUser)
 			users.GET("/me/memberships", container.UserController.GetMyMemberships)
-			users.GET("/:id", container.UserController.GetUser)
-			users.POST("", container.UserController.CreateUser)
-			users.PUT("/:id", container.UserController.UpdateUser)
-			users.DELETE("/:id", container.UserController.DeleteUser)
+			users.PUT("/me/password", container.AuthController.ChangePassword)
+			users.GET("/me/sessions", container.SessionController.GetMySessions)
+			users.DELETE("/me/sessions/:id", container.SessionController.RevokeMySession)
+			users.GET("/me/2fa", container.TwoFactorController.GetStatus)
+			users.POST("/me/2fa/setup", container.TwoFactorController.Setup)
+			users.POST("/me/2fa/enable", container.TwoFactorController.Enable)
+			users.POST("/me/2fa/disable", container.TwoFactorController.Disable)
+			users.POST("/me/2fa/recovery-codes", container.TwoFactorController.RegenerateRecoveryCodes)
+			users.GET("/:id/sessions", http_middleware.RequireSuperAdmin, container.SessionController.GetUserSessions)
+			users.DELETE("/:id/sessions/:sessionId", http_middleware.RequireSuperAdmin, container.SessionController.RevokeUserSession)
+			users.DELETE("/:id/2fa", http_middleware.RequireSuperAdmin, container.TwoFactorController.ResetUserTwoFactor)
+			users.POST("/:id/impersonate", http_middleware.RequireSuperAdmin, container.AuthController.Impersonate)
+			users.POST("/:id/unlock", http_middleware.RequireUserAdmin("users.update"), container.AuthController.UnlockUser)
+			users.GET("/:id", http_middleware.RequireUserAdmin("users.read"), container.UserController.GetUser)
+			users.POST("", http_middleware.RequireSuperAdmin, container.UserController.CreateUser)
+			users.PUT("/:id", http_middleware.RequireUserAdmin("users.update"), container.UserController.UpdateUser)
+			users.DELETE("/:id", http_middleware.RequireUserAdmin("users.delete"), container.UserController.DeleteUser)
 		}
 
 		roles := v1.Group("/roles")
 		roles.Use(http_middleware.JWTAuthentication)
 		{
-			roles.GET("", container.RoleController.GetRoles)
-			roles.GET("/:id", container.RoleController.GetRole)
-			roles.POST("", container.RoleController.CreateRole)
-			roles.PUT("/:id", container.RoleController.UpdateRole)
-			roles.DELETE("/:id", container.RoleController.DeleteRole)
+			roles.GET("", http_middleware.RequirePermission("roles.list"), container.RoleController.GetRoles)
+			roles.GET("/:id", http_middleware.RequirePermission("roles.read"), container.RoleController.GetRole)
+			roles.POST("", http_middleware.RequirePermission("roles.create"), container.RoleController.CreateRole)
+			roles.PUT("/:id", http_middleware.RequirePermission("roles.update"), container.RoleController.UpdateRole)
+			roles.DELETE("/:id", http_middleware.RequirePermission("roles.delete"), container.RoleController.DeleteRole)
 		}
 
 		permissions := v1.Group("/permissions")
 		permissions.Use(http_middleware.JWTAuthentication)
 		{
-			permissions.GET("", container.PermissionController.GetPermissions)
-			permissions.GET("/:id", container.PermissionController.GetPermission)
-			permissions.POST("", container.PermissionController.CreatePermission)
-			permissions.DELETE("/:id", container.PermissionController.DeletePermission)
+			permissions.GET("", http_middleware.RequirePermission("permissions.list"), container.PermissionController.GetPermissions)
+			permissions.GET("/:id", http_middleware.RequirePermission("permissions.read"), container.PermissionController.GetPermission)
+			permissions.POST("", http_middleware.RequirePermission("permissions.create"), container.PermissionController.CreatePermission)
+			permissions.DELETE("/:id", http_middleware.RequirePermission("permissions.delete"), container.PermissionController.DeletePermission)
+		}
+
+		auditLogs := v1.Group("/audit-logs")
+		auditLogs.Use(http_middleware.JWTAuthentication)
+		{
+			auditLogs.GET("", http_middleware.RequireSuperAdmin, container.AuditController.GetAuditLogs)
 		}
 
 		organizations := v1.Group("/organizations")
 		organizations.Use(http_middleware.JWTAuthentication)
 		{
-			organizations.GET("", container.OrganizationController.GetOrganizations)
-			organizations.GET("/:id", container.OrganizationController.GetOrganization)
-			organizations.POST("", container.OrganizationController.CreateOrganization)
-			organizations.PUT("/:id", container.OrganizationController.UpdateOrganization)
-			organizations.DELETE("/:id", container.OrganizationController.DeleteOrganization)
-
-			organizations.GET("/:id/members", container.OrganizationController.GetMembers)
-			organizations.POST("/:id/members", container.OrganizationController.AddMember)
-			organizations.PUT("/:id/members/:userId", container.OrganizationController.UpdateMember)
-			organizations.DELETE("/:id/members/:userId", container.OrganizationController.RemoveMember)
+			organizations.GET("", http_middleware.RequirePermission("organizations.list"), container.OrganizationController.GetOrganizations)
+			organizations.GET("/:id", http_middleware.RequireOrganizationAccess, http_middleware.RequirePermission("organizations.read"), container.OrganizationController.GetOrganization)
+			organizations.POST("", http_middleware.RequirePermission("organizations.create"), container.OrganizationController.CreateOrganization)
+			organizations.PUT("/:id", http_middleware.RequireOrganizationAccess, http_middleware.RequirePermission("organizations.update"), container.OrganizationController.UpdateOrganization)
+			organizations.DELETE("/:id", http_middleware.RequireOrganizationAccess, http_middleware.RequirePermission("organizations.delete"), container.OrganizationController.DeleteOrganization)
+
+			organizations.GET("/:id/members", http_middleware.RequireOrganizationAccess, http_middleware.RequirePermission("organizations.read"), container.OrganizationController.GetMembers)
+			organizations.POST("/:id/members", http_middleware.RequireOrganizationAccess, http_middleware.RequirePermission("organizations.update"), container.OrganizationController.AddMember)
+			organizations.PUT("/:id/members/:userId", http_middleware.RequireOrganizationAccess, http_middleware.RequirePermission("organizations.update"), container.OrganizationController.UpdateMember)
+			organizations.DELETE("/:id/members/:userId", http_middleware.RequireOrganizationAccess, http_middleware.RequirePermission("organizations.update"), container.OrganizationController.RemoveMember)
+
+			organizations.GET("/:id/sso", http_middleware.RequireOrganizationAccess, http_middleware.RequirePermission("organizations.update"), container.SsoController.GetProvider)
+			organizations.PUT("/:id/sso", http_middleware.RequireOrganizationAccess, http_middleware.RequirePermission("organizations.update"), container.SsoController.SaveProvider)
+			organizations.DELETE("/:id/sso", http_middleware.RequireOrganizationAccess, http_middleware.RequirePermission("organizations.update"), container.SsoController.DeleteProvider)
+
+			organizations.GET("/:id/api-keys", http_middleware.RequireOrganizationAccess, http_middleware.RequirePermission("api_keys.list"), container.ApiKeyController.GetApiKeys)
+			organizations.POST("/:id/api-keys", http_middleware.RequireOrganizationAccess, http_middleware.RequirePermission("api_keys.create"), container.ApiKeyController.CreateApiKey)
+			organizations.DELETE("/:id/api-keys/:keyId", http_middleware.RequireOrganizationAccess, http_middleware.RequirePermission("api_keys.delete"), container.ApiKeyController.RevokeApiKey)
 		}
 
 		units := v1.Group("/units")
-		units.Use(http_middleware.JWTAuthentication)
+		units.Use(http_middleware.Authentication)
 		{
-			units.GET("", container.UnitController.GetUnits)
-			units.GET("/:id", container.UnitController.GetUnit)
-			units.POST("", container.UnitController.CreateUnit)
-			units.PUT("/:id", container.UnitController.UpdateUnit)
-			units.DELETE("/:id", container.UnitController.DeleteUnit)
-
-			units.GET("/:id/members", container.UnitMemberController.GetMembers)
-			units.GET("/:id/members/:memberId", container.UnitMemberController.GetMember)
-			units.POST("/:id/members", container.UnitMemberController.AddMember)
-			units.PUT("/:id/members/:memberId", container.UnitMemberController.UpdateMember)
-			units.DELETE("/:id/members/:memberId", container.UnitMemberController.RemoveMember)
-
-			units.GET("/:id/settings", container.UnitSettingsController.GetSettings)
-			units.PUT("/:id/settings", container.UnitSettingsController.UpdateSettings)
+			units.GET("", http_middleware.RequirePermission("units.list"), container.UnitController.GetUnits)
+			units.GET("/:id", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("units.read"), container.UnitController.GetUnit)
+			units.POST("", http_middleware.RequirePermission("units.create"), container.UnitController.CreateUnit)
+			units.PUT("/:id", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("units.update"), container.UnitController.UpdateUnit)
+			units.DELETE("/:id", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("units.delete"), container.UnitController.DeleteUnit)
+
+			units.GET("/:id/members", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("unit_members.list"), container.UnitMemberController.GetMembers)
+			units.GET("/:id/members/:memberId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("unit_members.read"), container.UnitMemberController.GetMember)
+			units.POST("/:id/members", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("unit_members.create"), container.UnitMemberController.AddMember)
+			units.PUT("/:id/members/:memberId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("unit_members.update"), container.UnitMemberController.UpdateMember)
+			units.DELETE("/:id/members/:memberId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("unit_members.delete"), container.UnitMemberController.RemoveMember)
+			units.POST("/:id/members/:memberId/approve", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("unit_members.update"), container.UnitMemberController.ApproveMember)
+			units.POST("/:id/members/:memberId/reject", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("unit_members.update"), container.UnitMemberController.RejectMember)
+
+			units.GET("/:id/settings", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("units.read"), container.UnitSettingsController.GetSettings)
+			units.PUT("/:id/settings", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("units.update"), container.UnitSettingsController.UpdateSettings)
 
 			// Teacher profiles
-			units.GET("/:id/teachers", container.TeacherProfileController.GetAll)
-			units.GET("/:id/teachers/:teacherId", container.TeacherProfileController.GetById)
-			units.POST("/:id/teachers", container.TeacherProfileController.Create)
-			units.POST("/:id/teachers/with-user", container.TeacherProfileController.CreateWithUser)
-			units.PUT("/:id/teachers/:teacherId", container.TeacherProfileController.Update)
-			units.DELETE("/:id/teachers/:teacherId", container.TeacherProfileController.Delete)
+			units.GET("/:id/teachers", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("teachers.list"), container.TeacherProfileController.GetAll)
+			units.GET("/:id/teachers/:teacherId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("teachers.read"), container.TeacherProfileController.GetById)
+			units.POST("/:id/teachers", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("teachers.create"), container.TeacherProfileController.Create)
+			units.POST("/:id/teachers/with-user", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("teachers.create"), container.TeacherProfileController.CreateWithUser)
+			units.PUT("/:id/teachers/:teacherId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("teachers.update"), container.TeacherProfileController.Update)
+			units.DELETE("/:id/teachers/:teacherId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("teachers.delete"), container.TeacherProfileController.Delete)
 
 			// Student profiles
-			units.GET("/:id/students", container.StudentProfileController.GetAll)
-			units.GET("/:id/students/:studentId", container.StudentProfileController.GetById)
-			units.POST("/:id/students", container.StudentProfileController.Create)
-			units.POST("/:id/students/with-user", container.StudentProfileController.CreateWithUser)
-			units.PUT("/:id/students/:studentId", container.StudentProfileController.Update)
-			units.DELETE("/:id/students/:studentId", container.StudentProfileController.Delete)
+			units.GET("/:id/students", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("students.list"), container.StudentProfileController.GetAll)
+			units.GET("/:id/students/:studentId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("students.read"), container.StudentProfileController.GetById)
+			units.POST("/:id/students", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("students.create"), container.StudentProfileController.Create)
+			units.POST("/:id/students/with-user", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("students.create"), container.StudentProfileController.CreateWithUser)
+			units.PUT("/:id/students/:studentId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("students.update"), container.StudentProfileController.Update)
+			units.DELETE("/:id/students/:studentId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("students.delete"), container.StudentProfileController.Delete)
 
 			// Classes
-			units.GET("/:id/classes", container.ClassController.GetAll)
-			units.GET("/:id/classes/:classId", container.ClassController.GetById)
-			units.POST("/:id/classes", container.ClassController.Create)
-			units.PUT("/:id/classes/:classId", container.ClassController.Update)
-			units.DELETE("/:id/classes/:classId", container.ClassController.Delete)
+			units.GET("/:id/classes", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("classes.list"), container.ClassController.GetAll)
+			units.GET("/:id/classes/:classId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("classes.read"), container.ClassController.GetById)
+			units.POST("/:id/classes", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("classes.create"), container.ClassController.Create)
+			units.PUT("/:id/classes/:classId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("classes.update"), container.ClassController.Update)
+			units.DELETE("/:id/classes/:classId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("classes.delete"), container.ClassController.Delete)
 
 			// Class enrollments
-			units.GET("/:id/classes/:classId/students", container.ClassEnrollmentController.GetByClass)
-			units.POST("/:id/classes/:classId/enroll", container.ClassEnrollmentController.Enroll)
+			units.GET("/:id/classes/:classId/students", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("class_enrollments.list"), container.ClassEnrollmentController.GetByClass)
+			units.POST("/:id/classes/:classId/enroll", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("class_enrollments.create"), container.ClassEnrollmentController.Enroll)
 
 			// Subjects
-			units.GET("/:id/subjects", container.SubjectController.GetAll)
-			units.GET("/:id/subjects/:subjectId", container.SubjectController.GetById)
-			units.POST("/:id/subjects", container.SubjectController.Create)
-			units.PUT("/:id/subjects/:subjectId", container.SubjectController.Update)
-			units.DELETE("/:id/subjects/:subjectId", container.SubjectController.Delete)
+			units.GET("/:id/subjects", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("subjects.list"), container.SubjectController.GetAll)
+			units.GET("/:id/subjects/:subjectId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("subjects.read"), container.SubjectController.GetById)
+			units.POST("/:id/subjects", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("subjects.create"), container.SubjectController.Create)
+			units.PUT("/:id/subjects/:subjectId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("subjects.update"), container.SubjectController.Update)
+			units.DELETE("/:id/subjects/:subjectId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("subjects.delete"), container.SubjectController.Delete)
+
+			// Timetable (jadwal pelajaran)
+			units.GET("/:id/timetable", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("timetables.list"), container.TimetableController.GetAll)
+			units.GET("/:id/timetable/periods", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("timetables.list"), container.TimetableController.GetPeriods)
+			units.GET("/:id/timetable/conflicts", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("timetables.list"), container.TimetableController.GetConflicts)
+			units.GET("/:id/timetable/classes/:classId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("timetables.list"), container.TimetableController.GetByClass)
+			units.GET("/:id/timetable/teachers/:teacherId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("timetables.list"), container.TimetableController.GetByTeacher)
+			units.GET("/:id/timetable/rooms/:room", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("timetables.list"), container.TimetableController.GetByRoom)
+			units.GET("/:id/timetable/:timetableId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("timetables.read"), container.TimetableController.GetById)
+			units.POST("/:id/timetable", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("timetables.create"), container.TimetableController.Create)
+			units.PUT("/:id/timetable/:timetableId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("timetables.update"), container.TimetableController.Update)
+			units.DELETE("/:id/timetable/:timetableId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("timetables.delete"), container.TimetableController.Delete)
+
+			// Timetable generator and its inputs
+			units.POST("/:id/timetable/generations", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("timetables.create"), container.TimetableController.StartGeneration)
+			units.GET("/:id/timetable/generations/:generationId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("timetables.read"), container.TimetableController.GetGeneration)
+			units.POST("/:id/timetable/generations/:generationId/apply", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("timetables.update"), container.TimetableController.ApplyGeneration)
+			units.GET("/:id/classes/:classId/subject-hours", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("classes.read"), container.TimetableController.GetClassSubjects)
+			units.PUT("/:id/classes/:classId/subject-hours", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("timetables.update"), container.TimetableController.SetClassSubjects)
+			units.GET("/:id/teachers/:teacherId/unavailability", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("teachers.read"), container.TimetableController.GetUnavailability)
+			units.PUT("/:id/teachers/:teacherId/unavailability", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("timetables.update"), container.TimetableController.SetUnavailability)
+
+			// Daily attendance (presensi harian)
+			units.GET("/:id/classes/:classId/attendance", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("attendances.list"), container.AttendanceController.GetClassAttendance)
+			units.PUT("/:id/classes/:classId/attendance", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("attendances.update"), container.AttendanceController.SaveClassAttendance)
+			units.GET("/:id/classes/:classId/attendance/recap", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("attendances.list"), container.AttendanceController.GetClassRecap)
+			units.GET("/:id/attendance/recap", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("attendances.list"), container.AttendanceController.GetUnitRecap)
+			// Lesson attendance and teaching journal (jurnal mengajar)
+			units.GET("/:id/timetable/:timetableId/lessons/:date", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("attendances.read"), container.AttendanceController.GetLesson)
+			units.PUT("/:id/timetable/:timetableId/lessons/:date", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("attendances.update"), container.AttendanceController.SaveLesson)
+			units.GET("/:id/classes/:classId/lessons", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("attendances.list"), container.AttendanceController.GetJournals)
+			units.GET("/:id/classes/:classId/attendance/subjects", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("attendances.list"), container.AttendanceController.GetSubjectRecap)
+			units.GET("/:id/classes/:classId/attendance/reconciliation", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("attendances.list"), container.AttendanceController.GetReconciliation)
+			// Teacher and staff attendance (presensi guru/pegawai); checking in also lets one see one's own record
+			units.POST("/:id/staff-attendance/check-in", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("staff_attendances.create"), container.StaffAttendanceController.CheckIn)
+			units.POST("/:id/staff-attendance/check-out", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("staff_attendances.create"), container.StaffAttendanceController.CheckOut)
+			units.GET("/:id/staff-attendance/me", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("staff_attendances.create"), container.StaffAttendanceController.GetOwn)
+			units.GET("/:id/staff-attendance", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("staff_attendances.list"), container.StaffAttendanceController.GetDay)
+			units.GET("/:id/staff-attendance/recap", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("staff_attendances.list"), container.StaffAttendanceController.GetMonthlyRecap)
+			units.GET("/:id/staff-attendance/recap/export", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("staff_attendances.list"), container.StaffAttendanceController.ExportMonthlyRecap)
+
+			// Gradebook (daftar nilai); only the assigned teacher may enter scores
+			units.GET("/:id/classes/:classId/subjects/:subjectId/assessments", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("grades.list"), container.GradebookController.ListAssessments)
+			units.POST("/:id/classes/:classId/subjects/:subjectId/assessments", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("grades.create"), container.GradebookController.CreateAssessment)
+			units.GET("/:id/classes/:classId/subjects/:subjectId/gradebook", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("grades.list"), container.GradebookController.GetGradebook)
+			units.PUT("/:id/assessments/:assessmentId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("grades.update"), container.GradebookController.UpdateAssessment)
+			units.DELETE("/:id/assessments/:assessmentId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("grades.delete"), container.GradebookController.DeleteAssessment)
+			units.GET("/:id/assessments/:assessmentId/scores", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("grades.read"), container.GradebookController.GetScores)
+			units.PUT("/:id/assessments/:assessmentId/scores", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("grades.update"), container.GradebookController.SaveScores)
+
+			// Passing thresholds (KKM/KKTP) and pass rates
+			units.GET("/:id/grade-thresholds", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("grades.list"), container.GradebookController.ListThresholds)
+			units.POST("/:id/grade-thresholds", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("units.update"), container.GradebookController.CreateThreshold)
+			units.PUT("/:id/grade-thresholds/:thresholdId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("units.update"), container.GradebookController.UpdateThreshold)
+			units.DELETE("/:id/grade-thresholds/:thresholdId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("units.update"), container.GradebookController.DeleteThreshold)
+			units.GET("/:id/classes/:classId/pass-rates", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("grades.list"), container.GradebookController.GetPassRates)
+
+			// Report cards (rapor): draft -> finalized -> published
+			units.GET("/:id/report-card-template", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("units.read"), container.ReportCardController.GetTemplate)
+			units.PUT("/:id/report-card-template", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("units.update"), container.ReportCardController.UpdateTemplate)
+			units.GET("/:id/classes/:classId/report-cards", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("report_cards.list"), container.ReportCardController.ListClass)
+			units.GET("/:id/enrollments/:enrollmentId/report-card", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("report_cards.read"), container.ReportCardController.Get)
+			units.PUT("/:id/enrollments/:enrollmentId/report-card", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("report_cards.update"), container.ReportCardController.UpdateDraft)
+			units.POST("/:id/enrollments/:enrollmentId/report-card/finalize", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("report_cards.update"), container.ReportCardController.Finalize)
+			units.POST("/:id/enrollments/:enrollmentId/report-card/reopen", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("report_cards.update"), container.ReportCardController.Reopen)
+			units.POST("/:id/enrollments/:enrollmentId/report-card/publish", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("report_cards.update"), container.ReportCardController.Publish)
+			units.GET("/:id/enrollments/:enrollmentId/report-card/pdf", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("report_cards.read"), container.ReportCardController.DownloadPDF)
+
+			// Academic year rollover (kenaikan kelas): preview, then apply in one transaction
+			units.POST("/:id/rollover/preview", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("units.update"), container.RolloverController.Preview)
+			units.POST("/:id/rollover", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("units.update"), container.RolloverController.Apply)
+
+			// Graduation (kelulusan) and alumni directory
+			units.POST("/:id/classes/:classId/graduation", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("units.update"), container.GraduationController.Graduate)
+			units.GET("/:id/alumni", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("students.list"), container.GraduationController.ListAlumni)
+			units.GET("/:id/alumni/years", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("students.list"), container.GraduationController.ListAlumniYears)
+			units.PUT("/:id/alumni/:graduationId", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("units.update"), container.GraduationController.UpdateGraduation)
 
 			// Activities
-			units.GET("/:id/activities", container.ActivityController.GetAll)
-			units.POST("/:id/activities", container.ActivityController.Create)
+			units.GET("/:id/activities", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("activities.list"), container.ActivityController.GetAll)
+			units.POST("/:id/activities", http_middleware.RequireUnitAccess, http_middleware.RequirePermission("activities.create"), container.ActivityController.Create)
 		}
 
 		// Class enrollment management (outside unit scope)
 		classEnrollments := v1.Group("/class-enrollments")
-		classEnrollments.Use(http_middleware.JWTAuthentication)
+		classEnrollments.Use(http_middleware.Authentication, http_middleware.RequireUnitAccessVia("enrollmentId"))
 		{
-			classEnrollments.PUT("/:enrollmentId", container.ClassEnrollmentController.UpdateStatus)
-			classEnrollments.POST("/:enrollmentId/transfer", container.ClassEnrollmentController.Transfer)
-			classEnrollments.DELETE("/:enrollmentId", container.ClassEnrollmentController.Remove)
+			classEnrollments.PUT("/:enrollmentId", http_middleware.RequirePermission("class_enrollments.update"), container.ClassEnrollmentController.UpdateStatus)
+			classEnrollments.POST("/:enrollmentId/transfer", http_middleware.RequirePermission("class_enrollments.update"), container.ClassEnrollmentController.Transfer)
+			classEnrollments.DELETE("/:enrollmentId", http_middleware.RequirePermission("class_enrollments.delete"), container.ClassEnrollmentController.Remove)
 		}
 
 		posts := v1.Group("/posts")
 		posts.Use(http_middleware.JWTAuthentication)
 		{
-			posts.GET("", container.PostController.GetPosts)
-			posts.GET("/:id", container.PostController.GetPost)
-			posts.POST("", container.PostController.CreatePost)
-			posts.PUT("/:id", container.PostController.UpdatePost)
-			posts.DELETE("/:id", container.PostController.DeletePost)
-			posts.GET("/:id/comments", container.PostController.GetComments)
-			posts.POST("/:id/comments", container.PostController.CreateComment)
-			posts.DELETE("/:id/comments/:commentId", container.PostController.DeleteComment)
-			posts.POST("/:id/vote", container.PostController.VotePoll)
+			posts.GET("", http_middleware.RequirePermission("posts.list"), container.PostController.GetPosts)
+			posts.GET("/:id", http_middleware.RequirePermission("posts.read"), container.PostController.GetPost)
+			posts.POST("", http_middleware.RequirePermission("posts.create"), container.PostController.CreatePost)
+			posts.PUT("/:id", http_middleware.RequirePermission("posts.update"), container.PostController.UpdatePost)
+			posts.DELETE("/:id", http_middleware.RequirePermission("posts.delete"), container.PostController.DeletePost)
+			posts.GET("/:id/comments", http_middleware.RequirePermission("posts.read"), container.PostController.GetComments)
+			posts.POST("/:id/comments", http_middleware.RequirePermission("posts.read"), container.PostController.CreateComment)
+			posts.DELETE("/:id/comments/:commentId", http_middleware.RequirePermission("posts.read"), container.PostController.DeleteComment)
+			posts.POST("/:id/vote", http_middleware.RequirePermission("posts.read"), container.PostController.VotePoll)
 		}
 
 		// Subject-Teacher assignments
 		subjects := v1.Group("/subjects")
-		subjects.Use(http_middleware.JWTAuthentication)
+		subjects.Use(http_middleware.Authentication, http_middleware.RequireUnitAccessVia("subjectId"))
 		{
-			subjects.POST("/:subjectId/teachers", container.SubjectController.AssignTeacher)
-			subjects.DELETE("/:subjectId/teachers/:teacherId", container.SubjectController.RemoveTeacher)
+			subjects.POST("/:subjectId/teachers", http_middleware.RequirePermission("subjects.update"), container.SubjectController.AssignTeacher)
+			subjects.DELETE("/:subjectId/teachers/:teacherId", http_middleware.RequirePermission("subjects.update"), container.SubjectController.RemoveTeacher)
 		}
 
 		// Teacher subjects (get subjects for a teacher)
 		teachers := v1.Group("/teachers")
-		teachers.Use(http_middleware.JWTAuthentication)
+		teachers.Use(http_middleware.Authentication, http_middleware.RequireUnitAccessVia("teacherId"))
 		{
-			teachers.GET("/:teacherId/subjects", container.SubjectController.GetByTeacher)
+			teachers.GET("/:teacherId/subjects", http_middleware.RequirePermission("subjects.read"), container.SubjectController.GetByTeacher)
 		}
 
 		// Activities management
 		activities := v1.Group("/activities")
-		activities.Use(http_middleware.JWTAuthentication)
+		activities.Use(http_middleware.Authentication, http_middleware.RequireUnitAccessVia("activityId"))
 		{
-			activities.GET("/:activityId", container.ActivityController.GetById)
-			activities.PUT("/:activityId", container.ActivityController.Update)
-			activities.DELETE("/:activityId", container.ActivityController.Delete)
+			activities.GET("/:activityId", http_middleware.RequirePermission("activities.read"), container.ActivityController.GetById)
+			activities.PUT("/:activityId", http_middleware.RequirePermission("activities.update"), container.ActivityController.Update)
+			activities.DELETE("/:activityId", http_middleware.RequirePermission("activities.delete"), container.ActivityController.Delete)
 			// Teacher assignments
-			activities.GET("/:activityId/teachers", container.ActivityController.GetTeachers)
-			activities.POST("/:activityId/teachers", container.ActivityController.AssignTeacher)
-			activities.DELETE("/:activityId/teachers/:teacherId", container.ActivityController.RemoveTeacher)
+			activities.GET("/:activityId/teachers", http_middleware.RequirePermission("activities.read"), container.ActivityController.GetTeachers)
+			activities.POST("/:activityId/teachers", http_middleware.RequirePermission("activities.update"), container.ActivityController.AssignTeacher)
+			activities.DELETE("/:activityId/teachers/:teacherId", http_middleware.RequirePermission("activities.update"), container.ActivityController.RemoveTeacher)
 			// Student enrollments
-			activities.GET("/:activityId/students", container.ActivityController.GetStudents)
-			activities.POST("/:activityId/students", container.ActivityController.EnrollStudent)
-			activities.DELETE("/:activityId/students/:studentId", container.ActivityController.RemoveStudent)
+			activities.GET("/:activityId/students", http_middleware.RequirePermission("activities.read"), container.ActivityController.GetStudents)
+			activities.POST("/:activityId/students", http_middleware.RequirePermission("activities.update"), container.ActivityController.EnrollStudent)
+			activities.DELETE("/:activityId/students/:studentId", http_middleware.RequirePermission("activities.update"), container.ActivityController.RemoveStudent)
 		}
 	}
 